@@ -0,0 +1,67 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Response is the response of a request to a service.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Do sends a request to the service name, with body encoded as JSON unless
+// nil and header given as key, value pairs, e.g. "If-Match", etag. It fails
+// the test when the service cannot be reached.
+func (s *System) Do(method, name, path string, body any, header ...string) Response {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("e2e: encoding the body of %s %s: %v", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL(name)+path, reader)
+	if err != nil {
+		s.t.Fatalf("e2e: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatalf("e2e: %s %s of %s: %v", method, path, name, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("e2e: reading the response to %s %s of %s: %v", method, path, name, err)
+	}
+	return Response{Status: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// JSON sends a request like Do, fails the test unless the response has
+// status want, and decodes its body into out, when not nil.
+func (s *System) JSON(method, name, path string, body any, want int, out any, header ...string) Response {
+	s.t.Helper()
+	resp := s.Do(method, name, path, body, header...)
+	if resp.Status != want {
+		s.t.Fatalf("e2e: %s %s of %s answered %d, want %d: %s", method, path, name, resp.Status, want, resp.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Body, out); err != nil {
+			s.t.Fatalf("e2e: decoding the response to %s %s of %s: %v: %s", method, path, name, err, resp.Body)
+		}
+	}
+	return resp
+}
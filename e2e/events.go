@@ -0,0 +1,111 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Recorder records the events published by a service on its event stream.
+type Recorder struct {
+	t       testing.TB
+	mutex   sync.Mutex
+	events  []events.Event
+	changed chan struct{}
+}
+
+// Record follows the event stream of the service name, restricted to the
+// event type patterns types if any, until the test ends. It returns once
+// the stream is open, so every event published afterwards is recorded.
+func (s *System) Record(name string, types ...string) *Recorder {
+	s.t.Helper()
+	streamURL := s.URL(name) + "/events"
+	if len(types) > 0 {
+		streamURL += "?types=" + url.QueryEscape(strings.Join(types, ","))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		s.t.Fatalf("e2e: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// The stream outlives the timeout of the other requests
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatalf("e2e: following the events of %s: %v", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		s.t.Fatalf("e2e: following the events of %s: %s", name, resp.Status)
+	}
+
+	r := &Recorder{t: s.t, changed: make(chan struct{})}
+	go func() {
+		defer resp.Body.Close()
+		events.ReadSSE(resp.Body, func(message events.Message) error {
+			var event events.Event
+			if err := json.Unmarshal([]byte(message.Data), &event); err == nil {
+				r.add(event)
+			}
+			return nil
+		})
+	}()
+	return r
+}
+
+// add records event and wakes up the waiting callers
+func (r *Recorder) add(event events.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Events returns the events recorded so far, in the order they were published.
+func (r *Recorder) Events() []events.Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+// WaitFor returns the first recorded event of eventType for which match,
+// when not nil, is true, waiting for it to be published and failing the
+// test after a timeout.
+func (r *Recorder) WaitFor(eventType string, match func(events.Event) bool) events.Event {
+	r.t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		r.mutex.Lock()
+		changed := r.changed
+		for _, event := range r.events {
+			if event.Type == eventType && (match == nil || match(event)) {
+				r.mutex.Unlock()
+				return event
+			}
+		}
+		r.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			r.t.Fatalf("e2e: timed out after %v waiting for a %s event, got %d other events", waitTimeout, eventType, len(r.Events()))
+		}
+	}
+}
+
+// Subject matches the events about subject, such as a user or order ID.
+func Subject(subject string) func(events.Event) bool {
+	return func(event events.Event) bool {
+		return event.Subject == subject
+	}
+}
@@ -0,0 +1,123 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events/eventstest"
+)
+
+type user struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Version int64  `json:"version"`
+}
+
+type order struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id"`
+	Status     string `json:"status"`
+}
+
+type customer struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+type document struct {
+	ID   string            `json:"id"`
+	Type string            `json:"type"`
+	Text map[string]string `json:"text"`
+}
+
+func TestCustomerLifecycle(t *testing.T) {
+	s := Start(t, Foundation(), Orders(), Search())
+	userEvents := s.Record("foundation", "user.*")
+	orderEvents := s.Record("orders", "order.*")
+
+	// A new user becomes a customer of the orders service
+	var ada user
+	resp := s.JSON(http.MethodPost, "foundation", "/users", map[string]string{"name": "Ada Lovelace", "email": "ada@example.com"}, http.StatusCreated, &ada)
+	userEvents.WaitFor("user.created", Subject(ada.ID))
+	eventstest.Expect(t, userEvents).ToHave("user.created").
+		WithSubject(ada.ID).
+		WithSource("user-service").
+		WithPayloadField("user.email", "ada@example.com").
+		Once()
+	s.Eventually("the customer in orders", func() bool {
+		var customers []customer
+		s.JSON(http.MethodGet, "orders", "/customers", nil, http.StatusOK, &customers)
+		return containsCustomer(customers, ada.ID)
+	})
+
+	// Their orders are indexed by search along with their name
+	var placed order
+	s.JSON(http.MethodPost, "orders", "/orders", map[string]any{
+		"customer_id": ada.ID,
+		"items":       []map[string]any{{"sku": "BOOK-1", "quantity": 2, "unit_price_cents": 1500}},
+	}, http.StatusCreated, &placed)
+	orderEvents.WaitFor("order.placed", Subject(placed.ID))
+	s.Eventually("the user and order documents in search", func() bool {
+		if s.Do(http.MethodGet, "search", "/documents/user:"+ada.ID, nil).Status != http.StatusOK {
+			return false
+		}
+		resp := s.Do(http.MethodGet, "search", "/documents/order:"+placed.ID, nil)
+		var doc document
+		return resp.Status == http.StatusOK && json.Unmarshal(resp.Body, &doc) == nil && doc.Text["customer"] == ada.Name
+	})
+
+	// Deleting the user cancels their open orders and forgets the customer
+	s.JSON(http.MethodDelete, "foundation", "/users/"+ada.ID, nil, http.StatusNoContent, nil, "If-Match", resp.Header.Get("ETag"))
+	userEvents.WaitFor("user.deleted", Subject(ada.ID))
+	orderEvents.WaitFor("order.cancelled", Subject(placed.ID))
+	eventstest.Expect(t, orderEvents).ToHave("order.cancelled").
+		WithSubject(placed.ID).
+		WithPayloadField("order.cancel_reason", "customer deleted")
+	s.Eventually("the customer removed from orders", func() bool {
+		var customers []customer
+		s.JSON(http.MethodGet, "orders", "/customers", nil, http.StatusOK, &customers)
+		return !containsCustomer(customers, ada.ID)
+	})
+	s.Eventually("the user document removed from search", func() bool {
+		return s.Do(http.MethodGet, "search", "/documents/user:"+ada.ID, nil).Status == http.StatusNotFound
+	})
+}
+
+func TestInvalidOrderPublishesNothing(t *testing.T) {
+	s := Start(t, Foundation(), Orders())
+	orderEvents := s.Record("orders")
+
+	// Orders of unknown customers are rejected before any event
+	s.JSON(http.MethodPost, "orders", "/orders", map[string]any{
+		"customer_id": "nobody",
+		"items":       []map[string]any{{"sku": "BOOK-1", "quantity": 1, "unit_price_cents": 1500}},
+	}, http.StatusUnprocessableEntity, nil)
+
+	var ada user
+	s.JSON(http.MethodPost, "foundation", "/users", map[string]string{"name": "Ada Lovelace", "email": "ada@example.com"}, http.StatusCreated, &ada)
+	s.Eventually("the customer in orders", func() bool {
+		var customers []customer
+		s.JSON(http.MethodGet, "orders", "/customers", nil, http.StatusOK, &customers)
+		return containsCustomer(customers, ada.ID)
+	})
+	var placed order
+	s.JSON(http.MethodPost, "orders", "/orders", map[string]any{
+		"customer_id": ada.ID,
+		"items":       []map[string]any{{"sku": "BOOK-1", "quantity": 1, "unit_price_cents": 1500}},
+	}, http.StatusCreated, &placed)
+
+	// The stream is ordered, so the only event before it is the valid order
+	orderEvents.WaitFor("order.placed", Subject(placed.ID))
+	eventstest.Expect(t, orderEvents).ToHave("*").Once()
+}
+
+func containsCustomer(customers []customer, id string) bool {
+	for _, c := range customers {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+// Package e2e runs the services of the repository together for end-to-end
+// tests: it builds the modules, starts each one on a free port with its
+// event streams wired to the others, drives scenarios over HTTP and records
+// the events they publish. The modules are main packages, so each runs in
+// its own process, as it would in production.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Timeouts of the harness
+const (
+	startTimeout = 30 * time.Second
+	stopTimeout  = 10 * time.Second
+	waitTimeout  = 10 * time.Second
+	pollInterval = 25 * time.Millisecond
+)
+
+// ModulesDir is the directory of the modules, relative to the e2e package.
+var ModulesDir = filepath.Join("..", "modules")
+
+// Service is a module run by the harness.
+type Service struct {
+	// Name is the directory of the module under ModulesDir, e.g. orders.
+	Name string
+	// Env returns the environment of the service, in addition to HOST and
+	// PORT. It is called once every service has its URL, so it may point
+	// the service at the others with System.URL.
+	Env func(s *System) map[string]string
+	// Streams is the number of event streams the service follows. It is
+	// ready once it logged following each of them, so no event is missed.
+	Streams int
+}
+
+// Foundation is the user service, with authentication disabled.
+func Foundation() Service {
+	return Service{
+		Name: "foundation",
+		Env: func(s *System) map[string]string {
+			return map[string]string{"GRPC_PORT": freePort(s.t)}
+		},
+	}
+}
+
+// Orders is the orders service, following the user events of Foundation.
+func Orders() Service {
+	return Service{
+		Name: "orders",
+		Env: func(s *System) map[string]string {
+			return map[string]string{"USER_EVENTS_URL": s.URL("foundation") + "/events"}
+		},
+		Streams: 1,
+	}
+}
+
+// Search is the search service, indexing the events of Foundation and Orders.
+func Search() Service {
+	return Service{
+		Name: "search",
+		Env: func(s *System) map[string]string {
+			return map[string]string{
+				"USER_EVENTS_URL":  s.URL("foundation") + "/events",
+				"ORDER_EVENTS_URL": s.URL("orders") + "/events",
+			}
+		},
+		Streams: 2,
+	}
+}
+
+// System is a set of running services.
+type System struct {
+	t         testing.TB
+	processes map[string]*process
+	order     []string
+	client    *http.Client
+}
+
+// process is a running service
+type process struct {
+	service Service
+	url     string
+	cmd     *exec.Cmd
+	logs    *logBuffer
+	exited  chan struct{}
+}
+
+// Start builds the services and starts them in order, each once the
+// previous one is ready, and stops them when the test ends. Tests using it
+// are skipped with -short.
+func Start(t testing.TB, services ...Service) *System {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end tests build and run the services")
+	}
+
+	s := &System{t: t, processes: make(map[string]*process), client: &http.Client{Timeout: waitTimeout}}
+	t.Cleanup(s.stop)
+
+	binaries := t.TempDir()
+	for _, service := range services {
+		s.processes[service.Name] = &process{
+			service: service,
+			url:     "http://127.0.0.1:" + freePort(t),
+			logs:    &logBuffer{},
+			exited:  make(chan struct{}),
+		}
+		s.order = append(s.order, service.Name)
+	}
+	for _, service := range services {
+		s.start(s.processes[service.Name], build(t, service.Name, binaries))
+	}
+	return s
+}
+
+// URL returns the base URL of the running service name, e.g. http://127.0.0.1:43521.
+func (s *System) URL(name string) string {
+	p, ok := s.processes[name]
+	if !ok {
+		s.t.Fatalf("e2e: service %s is not part of the system", name)
+	}
+	return p.url
+}
+
+// Logs returns the output of the service name so far.
+func (s *System) Logs(name string) string {
+	p, ok := s.processes[name]
+	if !ok {
+		s.t.Fatalf("e2e: service %s is not part of the system", name)
+	}
+	return p.logs.String()
+}
+
+// build compiles the module name into dir and returns the binary
+func build(t testing.TB, name, dir string) string {
+	t.Helper()
+	binary := filepath.Join(dir, name)
+	cmd := exec.Command("go", "build", "-o", binary, ".")
+	cmd.Dir = filepath.Join(ModulesDir, name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("e2e: building %s: %v\n%s", name, err, output)
+	}
+	return binary
+}
+
+// start runs the binary of p and waits until it is healthy and follows its streams
+func (s *System) start(p *process, binary string) {
+	s.t.Helper()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(p.url, "http://"))
+	env := map[string]string{"HOST": host, "PORT": port}
+	if p.service.Env != nil {
+		for key, value := range p.service.Env(s) {
+			env[key] = value
+		}
+	}
+
+	p.cmd = exec.Command(binary)
+	// The services run in an empty directory, away from .env and config files
+	p.cmd.Dir = s.t.TempDir()
+	p.cmd.Env = os.Environ()
+	for key, value := range env {
+		p.cmd.Env = append(p.cmd.Env, key+"="+value)
+	}
+	p.cmd.Stdout = p.logs
+	p.cmd.Stderr = p.logs
+	if err := p.cmd.Start(); err != nil {
+		s.t.Fatalf("e2e: starting %s: %v", p.service.Name, err)
+	}
+	go func() {
+		p.cmd.Wait()
+		close(p.exited)
+	}()
+
+	deadline := time.Now().Add(startTimeout)
+	for !s.ready(p) {
+		select {
+		case <-p.exited:
+			s.t.Fatalf("e2e: %s exited while starting:\n%s", p.service.Name, p.logs)
+		case <-time.After(pollInterval):
+		}
+		if time.Now().After(deadline) {
+			s.t.Fatalf("e2e: %s is not ready after %v:\n%s", p.service.Name, startTimeout, p.logs)
+		}
+	}
+}
+
+// ready reports whether p answers its health check and follows its streams
+func (s *System) ready(p *process) bool {
+	resp, err := s.client.Get(p.url + "/health")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK && strings.Count(p.logs.String(), "Following event stream") >= p.service.Streams
+}
+
+// stop interrupts the services in reverse order, killing the ones not done
+// in time, and prints their logs when the test failed
+func (s *System) stop() {
+	var stopping []*process
+	for i := len(s.order) - 1; i >= 0; i-- {
+		p := s.processes[s.order[i]]
+		if p.cmd != nil && p.cmd.Process != nil {
+			p.cmd.Process.Signal(syscall.SIGINT)
+			stopping = append(stopping, p)
+		}
+	}
+	for _, p := range stopping {
+		select {
+		case <-p.exited:
+		case <-time.After(stopTimeout):
+			p.cmd.Process.Kill()
+			<-p.exited
+		}
+		if s.t.Failed() {
+			s.t.Logf("e2e: logs of %s:\n%s", p.service.Name, p.logs)
+		}
+	}
+}
+
+// Eventually polls condition until it holds, failing the test with what
+// after a timeout. It waits for projections, which the services update
+// asynchronously from events.
+func (s *System) Eventually(what string, condition func() bool) {
+	s.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+	for !condition() {
+		select {
+		case <-ctx.Done():
+			s.t.Fatalf("e2e: timed out after %v waiting for %s", waitTimeout, what)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort(t testing.TB) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("e2e: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+// logBuffer collects the output of a service, written and read concurrently
+type logBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxDays limits the days of one daily metrics request
+const maxDays = 366
+
+// dailyCSVHeader is the header row of the CSV export of the daily metrics
+var dailyCSVHeader = []string{"day", "signups", "deletions", "orders_placed", "orders_cancelled", "revenue_cents"}
+
+// AnalyticsHandler serves the query API of the projection
+type AnalyticsHandler struct {
+	projection *Projection
+	mux        *http.ServeMux
+}
+
+// NewAnalyticsHandler creates the handler of the analytics routes
+func NewAnalyticsHandler(projection *Projection) *AnalyticsHandler {
+	h := &AnalyticsHandler{projection: projection, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /analytics/summary", h.handleSummary)
+	h.mux.HandleFunc("GET /analytics/daily", h.handleDaily)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *AnalyticsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleSummary returns the metrics of all time
+func (h *AnalyticsHandler) handleSummary(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.projection.Summary())
+}
+
+// handleDaily returns the daily metrics between the from and to days, as
+// JSON or, with ?format=csv or Accept: text/csv, as a CSV download
+func (h *AnalyticsHandler) handleDaily(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, err := parseDay(query.Get("from"))
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid from: "+err.Error())
+		return
+	}
+	to, err := parseDay(query.Get("to"))
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid to: "+err.Error())
+		return
+	}
+	if !from.IsZero() && !to.IsZero() {
+		if to.Before(from) {
+			problem.Write(w, r, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+		if to.Sub(from) >= maxDays*24*time.Hour {
+			problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("At most %d days can be requested at once", maxDays))
+			return
+		}
+	}
+
+	metrics := h.projection.Daily(from, to)
+	if query.Get("format") == "csv" || r.Header.Get("Accept") == "text/csv" {
+		writeDailyCSV(w, metrics)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, metrics)
+}
+
+// parseDay parses a YYYY-MM-DD day, or returns the zero time for ""
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.Parse(dayLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("want a day like 2025-01-31, got %q", value)
+	}
+	return day, nil
+}
+
+// writeDailyCSV writes the daily metrics as a CSV attachment
+func writeDailyCSV(w http.ResponseWriter, metrics []DailyMetrics) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="daily-metrics.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write(dailyCSVHeader)
+	for _, m := range metrics {
+		out.Write([]string{
+			m.Day,
+			strconv.Itoa(m.Signups),
+			strconv.Itoa(m.Deletions),
+			strconv.Itoa(m.OrdersPlaced),
+			strconv.Itoa(m.OrdersCancelled),
+			strconv.FormatInt(m.RevenueCents, 10),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		slog.Error("Failed to write CSV", "error", err)
+	}
+}
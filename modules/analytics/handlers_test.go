@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsHandler(t *testing.T) {
+	projection := NewProjection()
+	day := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	projection.Handle(context.Background(), event(t, "1", "user.created", "default", day, user("1", "active")))
+	projection.Handle(context.Background(), event(t, "2", "order.placed", "", day.AddDate(0, 0, 1), order(999)))
+	handler := NewAnalyticsHandler(projection)
+
+	tests := []struct {
+		name       string
+		target     string
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"summary", "/analytics/summary", "", http.StatusOK, `"active_users":1`},
+		{"daily", "/analytics/daily", "", http.StatusOK, `"day":"2025-01-02"`},
+		{"daily range", "/analytics/daily?from=2025-01-01&to=2025-01-01", "", http.StatusOK, `"signups":1`},
+		{"csv", "/analytics/daily?format=csv", "", http.StatusOK, "day,signups,deletions,orders_placed,orders_cancelled,revenue_cents\n2025-01-01,1,0,0,0,0\n2025-01-02,0,0,1,0,999\n"},
+		{"csv by Accept", "/analytics/daily?from=2025-01-02", "text/csv", http.StatusOK, "2025-01-02,0,0,1,0,999\n"},
+		{"invalid day", "/analytics/daily?from=yesterday", "", http.StatusBadRequest, "Invalid from"},
+		{"reversed range", "/analytics/daily?from=2025-01-02&to=2025-01-01", "", http.StatusBadRequest, "before"},
+		{"range too long", "/analytics/daily?from=2020-01-01&to=2025-01-01", "", http.StatusBadRequest, "366 days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got body %q want it to contain %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/analytics/daily?format=csv", nil))
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "daily-metrics.csv") {
+		t.Errorf("got Content-Disposition %q want an attachment", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/analytics/daily", nil))
+	var daily []DailyMetrics
+	if err := json.NewDecoder(rec.Body).Decode(&daily); err != nil || len(daily) != 2 {
+		t.Errorf("got %+v, %v want 2 days", daily, err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestParseStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{"one", "http://localhost:8080/events", 1, false},
+		{"several with spaces", "http://a:8080/events, https://b/events,", 2, false},
+		{"empty", " , ", 0, true},
+		{"no scheme", "localhost:8080/events", 0, true},
+		{"unsupported scheme", "ftp://a/events", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams, err := parseStreams(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStreams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(streams) != tt.want {
+				t.Errorf("got %d streams want %d", len(streams), tt.want)
+			}
+		})
+	}
+}
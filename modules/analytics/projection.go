@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// dayLayout formats the days of the daily metrics
+const dayLayout = "2006-01-02"
+
+// userStatusActive is the status of users counted as active
+const userStatusActive = "active"
+
+// DailyMetrics are the metrics of one UTC day
+type DailyMetrics struct {
+	Day             string `json:"day"`
+	Signups         int    `json:"signups"`
+	Deletions       int    `json:"deletions"`
+	OrdersPlaced    int    `json:"orders_placed"`
+	OrdersCancelled int    `json:"orders_cancelled"`
+	RevenueCents    int64  `json:"revenue_cents"`
+}
+
+// Summary are the metrics of all time
+type Summary struct {
+	Events        int            `json:"events"`
+	EventsByType  map[string]int `json:"events_by_type"`
+	Users         int            `json:"users"`
+	ActiveUsers   int            `json:"active_users"`
+	UsersByStatus map[string]int `json:"users_by_status"`
+	LastEventAt   *time.Time     `json:"last_event_at,omitempty"`
+}
+
+// eventData is the part of the payload of user and order events read by
+// the projection
+type eventData struct {
+	User *struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	} `json:"user"`
+	Order *struct {
+		TotalCents int64 `json:"total_cents"`
+	} `json:"order"`
+}
+
+// Projection maintains aggregate metrics from the events of every service.
+// Each event is counted once, on the UTC day of its time.
+type Projection struct {
+	mutex        sync.RWMutex
+	seen         map[string]bool
+	eventsByType map[string]int
+	userStatus   map[string]string
+	days         map[string]*DailyMetrics
+	lastEventAt  time.Time
+}
+
+// NewProjection creates a projection without events
+func NewProjection() *Projection {
+	return &Projection{
+		seen:         make(map[string]bool),
+		eventsByType: make(map[string]int),
+		userStatus:   make(map[string]string),
+		days:         make(map[string]*DailyMetrics),
+	}
+}
+
+// Handle applies the event, as an events.Handler
+func (p *Projection) Handle(ctx context.Context, event events.Event) error {
+	var data eventData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.seen[event.ID] {
+		return nil
+	}
+	p.seen[event.ID] = true
+	p.eventsByType[event.Type]++
+	if event.Time.After(p.lastEventAt) {
+		p.lastEventAt = event.Time
+	}
+
+	day := p.day(event.Time)
+	if data.User != nil {
+		// Users are keyed by tenant too, as each tenant has its own IDs
+		key := event.Tenant + "/" + data.User.ID
+		switch event.Type {
+		case "user.created":
+			day.Signups++
+			p.userStatus[key] = data.User.Status
+		case "user.deleted":
+			day.Deletions++
+			delete(p.userStatus, key)
+		default:
+			// Users created before the projection started are counted from
+			// their next event
+			p.userStatus[key] = data.User.Status
+		}
+	}
+	if data.Order != nil {
+		switch event.Type {
+		case "order.placed":
+			day.OrdersPlaced++
+			day.RevenueCents += data.Order.TotalCents
+		case "order.cancelled":
+			day.OrdersCancelled++
+			day.RevenueCents -= data.Order.TotalCents
+		}
+	}
+	return nil
+}
+
+// day returns the metrics of the day of t, created when missing
+func (p *Projection) day(t time.Time) *DailyMetrics {
+	key := t.UTC().Format(dayLayout)
+	day, ok := p.days[key]
+	if !ok {
+		day = &DailyMetrics{Day: key}
+		p.days[key] = day
+	}
+	return day
+}
+
+// Summary returns the metrics of all time
+func (p *Projection) Summary() Summary {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	summary := Summary{
+		EventsByType:  make(map[string]int, len(p.eventsByType)),
+		Users:         len(p.userStatus),
+		UsersByStatus: make(map[string]int),
+	}
+	for eventType, n := range p.eventsByType {
+		summary.EventsByType[eventType] = n
+		summary.Events += n
+	}
+	for _, status := range p.userStatus {
+		summary.UsersByStatus[status]++
+	}
+	summary.ActiveUsers = summary.UsersByStatus[userStatusActive]
+	if !p.lastEventAt.IsZero() {
+		last := p.lastEventAt
+		summary.LastEventAt = &last
+	}
+	return summary
+}
+
+// Daily returns the metrics of the days from from to to, inclusive, oldest
+// first. Days without events are included with zero metrics; a zero from or
+// to is the first or last day with events.
+func (p *Projection) Daily(from, to time.Time) []DailyMetrics {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if len(p.days) == 0 && (from.IsZero() || to.IsZero()) {
+		return []DailyMetrics{}
+	}
+	keys := make([]string, 0, len(p.days))
+	for key := range p.days {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if from.IsZero() {
+		from, _ = time.Parse(dayLayout, keys[0])
+	}
+	if to.IsZero() {
+		to, _ = time.Parse(dayLayout, keys[len(keys)-1])
+	}
+
+	metrics := []DailyMetrics{}
+	for day := from.UTC().Truncate(24 * time.Hour); !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format(dayLayout)
+		if m, ok := p.days[key]; ok {
+			metrics = append(metrics, *m)
+		} else {
+			metrics = append(metrics, DailyMetrics{Day: key})
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// event creates an event of eventType at t carrying data
+func event(t *testing.T, id, eventType, tenant string, at time.Time, data interface{}) events.Event {
+	t.Helper()
+	e, err := events.New(id, eventType, "test", "", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Tenant = tenant
+	e.Time = at
+	return e
+}
+
+func user(id, status string) map[string]interface{} {
+	return map[string]interface{}{"user": map[string]string{"id": id, "status": status}}
+}
+
+func order(totalCents int64) map[string]interface{} {
+	return map[string]interface{}{"order": map[string]int64{"total_cents": totalCents}}
+}
+
+func TestProjection(t *testing.T) {
+	projection := NewProjection()
+	day1 := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	day3 := day1.AddDate(0, 0, 2)
+
+	for _, e := range []events.Event{
+		event(t, "1", "user.created", "default", day1, user("1", "pending")),
+		event(t, "2", "user.created", "default", day1, user("2", "pending")),
+		event(t, "3", "user.created", "acme", day1, user("1", "pending")),
+		event(t, "4", "user.activated", "default", day1, user("1", "active")),
+		event(t, "5", "user.suspended", "default", day3, user("9", "suspended")),
+		event(t, "6", "user.deleted", "default", day3, user("2", "deleted")),
+		event(t, "7", "order.placed", "", day3, order(1500)),
+		event(t, "8", "order.placed", "", day3, order(500)),
+		event(t, "9", "order.cancelled", "", day3, order(500)),
+		event(t, "1", "user.created", "default", day1, user("1", "pending")),
+	} {
+		if err := projection.Handle(context.Background(), e); err != nil {
+			t.Fatalf("Handle(%s) error = %v", e.Type, err)
+		}
+	}
+
+	summary := projection.Summary()
+	if summary.Events != 9 || summary.EventsByType["user.created"] != 3 {
+		t.Errorf("got %d events, %d user.created want 9 and 3, the redelivered event counted once", summary.Events, summary.EventsByType["user.created"])
+	}
+	if summary.Users != 3 || summary.ActiveUsers != 1 || summary.UsersByStatus["pending"] != 1 || summary.UsersByStatus["suspended"] != 1 {
+		t.Errorf("got users %d active %d by status %v want 3 users, 1 active", summary.Users, summary.ActiveUsers, summary.UsersByStatus)
+	}
+	if summary.LastEventAt == nil || !summary.LastEventAt.Equal(day3) {
+		t.Errorf("got last event at %v want %v", summary.LastEventAt, day3)
+	}
+
+	daily := projection.Daily(time.Time{}, time.Time{})
+	want := []DailyMetrics{
+		{Day: "2025-01-01", Signups: 3},
+		{Day: "2025-01-02"},
+		{Day: "2025-01-03", Deletions: 1, OrdersPlaced: 2, OrdersCancelled: 1, RevenueCents: 1500},
+	}
+	if len(daily) != len(want) {
+		t.Fatalf("got %+v want %+v", daily, want)
+	}
+	for i := range want {
+		if daily[i] != want[i] {
+			t.Errorf("day %d got %+v want %+v", i, daily[i], want[i])
+		}
+	}
+
+	from := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	if got := projection.Daily(from, from.AddDate(0, 0, 1)); len(got) != 2 || got[0].Signups != 0 || got[1].Signups != 3 {
+		t.Errorf("got %+v want 2024-12-31 empty and 2025-01-01", got)
+	}
+}
+
+func TestProjection_Empty(t *testing.T) {
+	projection := NewProjection()
+	if got := projection.Daily(time.Time{}, time.Time{}); len(got) != 0 {
+		t.Errorf("got %+v want no days", got)
+	}
+	if summary := projection.Summary(); summary.Events != 0 || summary.LastEventAt != nil {
+		t.Errorf("got %+v want an empty summary", summary)
+	}
+	if err := projection.Handle(context.Background(), events.Event{ID: "1", Data: []byte(`[]`)}); err == nil {
+		t.Error("got nil error want a decoding error")
+	}
+}
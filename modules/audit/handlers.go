@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// Page sizes of GET /audit/records
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// recordsPage is the body of GET /audit/records. NextAfter is the after
+// parameter of the next page, absent on the last page.
+type recordsPage struct {
+	Records   []Record `json:"records"`
+	NextAfter int64    `json:"next_after,omitempty"`
+}
+
+// AuditHandler serves the query API of the history
+type AuditHandler struct {
+	store *Store
+	mux   *http.ServeMux
+}
+
+// NewAuditHandler creates the handler of the audit routes
+func NewAuditHandler(store *Store) *AuditHandler {
+	h := &AuditHandler{store: store, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /audit/records", h.handleQuery)
+	h.mux.HandleFunc("GET /audit/records/{sequence}", h.handleGetRecord)
+	h.mux.HandleFunc("GET /audit/verify", h.handleVerify)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *AuditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleQuery returns a page of the records selected by the query parameters
+func (h *AuditHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Fetch one more record to tell whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	page := recordsPage{Records: h.store.Query(filter)}
+	if len(page.Records) > limit {
+		page.Records = page.Records[:limit]
+		page.NextAfter = page.Records[limit-1].Sequence
+	}
+	httpx.WriteJSON(w, http.StatusOK, page)
+}
+
+// handleGetRecord returns one record
+func (h *AuditHandler) handleGetRecord(w http.ResponseWriter, r *http.Request) {
+	sequence, err := strconv.ParseInt(r.PathValue("sequence"), 10, 64)
+	if err != nil || sequence < 1 {
+		problem.Write(w, r, http.StatusBadRequest, "The sequence must be a positive integer")
+		return
+	}
+	records := h.store.Query(Filter{After: sequence - 1, Limit: 1})
+	if len(records) == 0 || records[0].Sequence != sequence {
+		problem.Write(w, r, http.StatusNotFound, fmt.Sprintf("No record %d", sequence))
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, records[0])
+}
+
+// handleVerify checks the hash chain of the history
+func (h *AuditHandler) handleVerify(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Verify(); err != nil {
+		if errors.Is(err, ErrTampered) {
+			problem.Write(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		slog.ErrorContext(r.Context(), "Verification failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{"valid": true, "records": h.store.Len()})
+}
+
+// parseFilter reads the filter of GET /audit/records
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	filter := Filter{
+		Type:    query.Get("type"),
+		Source:  query.Get("source"),
+		Subject: query.Get("subject"),
+		Tenant:  query.Get("tenant"),
+		Actor:   query.Get("actor"),
+		Limit:   defaultLimit,
+	}
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if value := query.Get(bound.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return Filter{}, fmt.Errorf("%s must be an RFC 3339 time, got %q", bound.name, value)
+			}
+			*bound.value = t
+		}
+	}
+	if value := query.Get("after"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			return Filter{}, fmt.Errorf("after must be a non-negative integer, got %q", value)
+		}
+		filter.After = after
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return Filter{}, fmt.Errorf("limit must be between 1 and %d, got %q", maxLimit, value)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditHandler(t *testing.T) {
+	store := NewMemoryStore()
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"1", "2", "3"} {
+		store.Append(testEvent(t, id, "user.created", "admin", day.Add(time.Duration(i)*time.Hour)))
+	}
+	handler := NewAuditHandler(store)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"query", "/audit/records?actor=admin&type=user.*", http.StatusOK, `"sequence":3`},
+		{"first page", "/audit/records?limit=2", http.StatusOK, `"next_after":2`},
+		{"since", "/audit/records?since=2025-01-01T02:00:00Z", http.StatusOK, `"sequence":3`},
+		{"invalid since", "/audit/records?since=today", http.StatusBadRequest, "RFC 3339"},
+		{"invalid limit", "/audit/records?limit=5000", http.StatusBadRequest, "limit"},
+		{"invalid after", "/audit/records?after=-1", http.StatusBadRequest, "after"},
+		{"record", "/audit/records/2", http.StatusOK, `"sequence":2`},
+		{"missing record", "/audit/records/9", http.StatusNotFound, "No record 9"},
+		{"invalid sequence", "/audit/records/zero", http.StatusBadRequest, "positive"},
+		{"verify", "/audit/verify", http.StatusOK, `"records":3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got body %q want it to contain %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/records?after=2&limit=2", nil))
+	var page recordsPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 1 || page.NextAfter != 0 {
+		t.Errorf("got %+v want the last record without a next page", page)
+	}
+
+	store.records[0].Event.Actor = "intruder"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/verify", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("got status %d for a tampered history want %d", rec.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+const (
+	defaultPort         = "8085"
+	defaultHost         = "localhost"
+	defaultEventStreams = "http://localhost:8080/events,http://localhost:8081/events,http://localhost:8083/events"
+	defaultLogFile      = "audit.jsonl"
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	streams, err := parseStreams(config.Getenv("EVENT_STREAMS", defaultEventStreams))
+	if err != nil {
+		fatal("Invalid EVENT_STREAMS", "error", err)
+	}
+
+	// Keep the history in an append-only file
+	logFile := config.Getenv("AUDIT_LOG_FILE", defaultLogFile)
+	store, closer, err := OpenStore(logFile)
+	if err != nil {
+		fatal("Failed to open the audit log", "error", err)
+	}
+	defer closer.Close()
+
+	// Follow every event type of every service
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var header http.Header
+	if token := os.Getenv("EVENTS_TOKEN"); token != "" {
+		header = http.Header{"Authorization": {"Bearer " + token}}
+	}
+	for _, stream := range streams {
+		subscriber := &events.Subscriber{URL: stream, Types: []string{"*"}, Header: header}
+		go subscriber.Run(ctx, store.Handle)
+	}
+
+	// Setup routes
+	mux := http.NewServeMux()
+	mux.Handle("/audit/", NewAuditHandler(store))
+	mux.HandleFunc("/health", healthHandler(store))
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting audit service", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"streams", streams, "log_file", logFile, "records", store.Len())
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Audit service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down audit service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Audit service forced to shutdown", "error", err)
+	}
+	slog.Info("Audit service exited")
+}
+
+// parseStreams parses a comma-separated list of event stream URLs
+func parseStreams(spec string) ([]string, error) {
+	var streams []string
+	for _, stream := range strings.Split(spec, ",") {
+		stream = strings.TrimSpace(stream)
+		if stream == "" {
+			continue
+		}
+		if u, err := url.Parse(stream); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("%q is not an http(s) URL", stream)
+		}
+		streams = append(streams, stream)
+	}
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no event stream")
+	}
+	return streams, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "audit",
+		"endpoints": map[string]string{
+			"records": "/audit/records",
+			"verify":  "/audit/verify",
+			"health":  "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy along with the number of records
+func healthHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "healthy",
+			"records": store.Len(),
+		})
+	}
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ErrTampered reports a history whose hash chain does not verify
+var ErrTampered = errors.New("audit history was tampered with")
+
+// Record is an event as kept in the history. Each record holds the hash of
+// the previous one, so changing or removing a record breaks the chain.
+type Record struct {
+	Sequence   int64        `json:"sequence"`
+	ReceivedAt time.Time    `json:"received_at"`
+	Event      events.Event `json:"event"`
+	PrevHash   string       `json:"prev_hash"`
+	Hash       string       `json:"hash"`
+}
+
+// hash returns the hash of the record, covering every field but Hash
+func (r Record) hash() string {
+	r.Hash = ""
+	encoded, _ := json.Marshal(r)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Store is the append-only history of events, kept in memory for queries and
+// in a JSON Lines file, one record per line, to survive restarts
+type Store struct {
+	mutex   sync.RWMutex
+	file    io.Writer
+	sync    func() error
+	records []Record
+	seen    map[string]bool
+	now     func() time.Time
+}
+
+// OpenStore opens the history kept in the file at path, created when
+// missing. The records already in the file are loaded and their chain
+// verified.
+func OpenStore(path string) (*Store, io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, err := readRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	store := newStore(file, file.Sync)
+	for _, record := range records {
+		store.records = append(store.records, record)
+		store.seen[record.Event.ID] = true
+	}
+	if err := store.Verify(); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return store, file, nil
+}
+
+// NewMemoryStore creates a history kept in memory only
+func NewMemoryStore() *Store {
+	return newStore(io.Discard, func() error { return nil })
+}
+
+func newStore(file io.Writer, sync func() error) *Store {
+	return &Store{
+		file: file,
+		sync: sync,
+		seen: make(map[string]bool),
+		now:  func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// readRecords reads the JSON Lines records of r
+func readRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Handle appends the event to the history, as an events.Handler. Events
+// already recorded are skipped, so redeliveries keep one record.
+func (s *Store) Handle(ctx context.Context, event events.Event) error {
+	_, err := s.Append(event)
+	return err
+}
+
+// Append appends the event to the history and reports whether it did:
+// events already recorded are not appended again
+func (s *Store) Append(event events.Event) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.seen[event.ID] {
+		return false, nil
+	}
+	record := Record{
+		Sequence:   int64(len(s.records)) + 1,
+		ReceivedAt: s.now(),
+		Event:      event,
+	}
+	if len(s.records) > 0 {
+		record.PrevHash = s.records[len(s.records)-1].Hash
+	}
+	record.Hash = record.hash()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return false, fmt.Errorf("writing audit record: %w", err)
+	}
+	if err := s.sync(); err != nil {
+		return false, fmt.Errorf("syncing audit record: %w", err)
+	}
+	s.records = append(s.records, record)
+	s.seen[event.ID] = true
+	return true, nil
+}
+
+// Verify checks the hash chain of the whole history
+func (s *Store) Verify() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	prev := ""
+	for i, record := range s.records {
+		if record.Sequence != int64(i)+1 {
+			return fmt.Errorf("%w: record %d has sequence %d", ErrTampered, i+1, record.Sequence)
+		}
+		if record.PrevHash != prev || record.hash() != record.Hash {
+			return fmt.Errorf("%w: record %d does not match its hash", ErrTampered, record.Sequence)
+		}
+		prev = record.Hash
+	}
+	return nil
+}
+
+// Len returns the number of records
+func (s *Store) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.records)
+}
+
+// Filter selects records of the history. Empty fields match every record.
+type Filter struct {
+	Type    string // event type pattern, see events.Match
+	Source  string
+	Subject string
+	Tenant  string
+	Actor   string
+	Since   time.Time // inclusive, on the event time
+	Until   time.Time // exclusive, on the event time
+	After   int64     // records after this sequence only
+	Limit   int
+}
+
+// matches reports whether the record is selected by f
+func (f Filter) matches(record Record) bool {
+	event := record.Event
+	return record.Sequence > f.After &&
+		(f.Type == "" || events.Match(f.Type, event.Type)) &&
+		(f.Source == "" || event.Source == f.Source) &&
+		(f.Subject == "" || event.Subject == f.Subject) &&
+		(f.Tenant == "" || event.Tenant == f.Tenant) &&
+		(f.Actor == "" || event.Actor == f.Actor) &&
+		(f.Since.IsZero() || !event.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || event.Time.Before(f.Until))
+}
+
+// Query returns the records selected by f in sequence order, at most
+// f.Limit of them when it is positive
+func (s *Store) Query(f Filter) []Record {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	records := []Record{}
+	for _, record := range s.records[min(int(max(f.After, 0)), len(s.records)):] {
+		if f.matches(record) {
+			records = append(records, record)
+			if f.Limit > 0 && len(records) == f.Limit {
+				break
+			}
+		}
+	}
+	return records
+}
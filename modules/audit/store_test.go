@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// testEvent creates an event with the given ID, type and actor
+func testEvent(t *testing.T, id, eventType, actor string, at time.Time) events.Event {
+	t.Helper()
+	event, err := events.New(id, eventType, "user-service", "u-"+id, map[string]string{"id": id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Actor, event.Tenant, event.Time = actor, "default", at
+	return event
+}
+
+func TestStore_AppendAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	store, closer, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	now := time.Now().UTC()
+
+	for _, id := range []string{"1", "2", "1"} {
+		if _, err := store.Append(testEvent(t, id, "user.created", "admin", now)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if store.Len() != 2 {
+		t.Errorf("got %d records want 2, the redelivered event recorded once", store.Len())
+	}
+	closer.Close()
+
+	reopened, closer, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() of the existing file error = %v", err)
+	}
+	defer closer.Close()
+	if reopened.Len() != 2 {
+		t.Fatalf("got %d records after reopening want 2", reopened.Len())
+	}
+	if appended, _ := reopened.Append(testEvent(t, "2", "user.created", "admin", now)); appended {
+		t.Error("an event recorded before reopening was appended again")
+	}
+	reopened.Append(testEvent(t, "3", "user.deleted", "admin", now))
+	records := reopened.Query(Filter{})
+	if len(records) != 3 || records[2].Sequence != 3 || records[2].PrevHash != records[1].Hash {
+		t.Errorf("got %+v want 3 chained records", records)
+	}
+	if err := reopened.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestStore_Tampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	store, closer, _ := OpenStore(path)
+	now := time.Now().UTC()
+	store.Append(testEvent(t, "1", "user.created", "admin", now))
+	store.Append(testEvent(t, "2", "user.deleted", "admin", now))
+	closer.Close()
+
+	content, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(content), `"actor":"admin"`, `"actor":"someone-else"`, 1)
+	os.WriteFile(path, []byte(tampered), 0o600)
+
+	if _, _, err := OpenStore(path); !errors.Is(err, ErrTampered) {
+		t.Errorf("got %v want %v", err, ErrTampered)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	os.WriteFile(path, []byte(lines[1]), 0o600)
+	if _, _, err := OpenStore(path); !errors.Is(err, ErrTampered) {
+		t.Errorf("removed first record: got %v want %v", err, ErrTampered)
+	}
+
+	os.WriteFile(path, []byte("not json\n"), 0o600)
+	if _, _, err := OpenStore(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("got %v want an error on line 1", err)
+	}
+}
+
+func TestStore_Query(t *testing.T) {
+	store := NewMemoryStore()
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Append(testEvent(t, "1", "user.created", "admin", day))
+	store.Append(testEvent(t, "2", "user.updated", "ada", day.Add(time.Hour)))
+	store.Append(testEvent(t, "3", "order.placed", "", day.Add(2*time.Hour)))
+	store.Append(testEvent(t, "4", "user.deleted", "admin", day.Add(3*time.Hour)))
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"all", Filter{}, []int64{1, 2, 3, 4}},
+		{"type pattern", Filter{Type: "user.*"}, []int64{1, 2, 4}},
+		{"actor", Filter{Actor: "admin"}, []int64{1, 4}},
+		{"subject", Filter{Subject: "u-2"}, []int64{2}},
+		{"time range", Filter{Since: day.Add(time.Hour), Until: day.Add(3 * time.Hour)}, []int64{2, 3}},
+		{"after and limit", Filter{After: 1, Limit: 2}, []int64{2, 3}},
+		{"tenant", Filter{Tenant: "acme"}, nil},
+		{"after the end", Filter{After: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := store.Query(tt.filter)
+			if len(records) != len(tt.want) {
+				t.Fatalf("got %d records want %v", len(records), tt.want)
+			}
+			for i, record := range records {
+				if record.Sequence != tt.want[i] {
+					t.Errorf("record %d got sequence %d want %d", i, record.Sequence, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies the write model as the producer of its events
+const eventSource = "catalog"
+
+// CreateProduct asks to add a product to the catalog
+type CreateProduct struct {
+	Name       string `json:"name"`
+	PriceCents int64  `json:"price_cents"`
+}
+
+// RenameProduct asks to change the name of a product. A positive
+// ExpectedVersion rejects the command when the product changed since.
+type RenameProduct struct {
+	ProductID       string `json:"-"`
+	Name            string `json:"name"`
+	ExpectedVersion int64  `json:"expected_version"`
+}
+
+// ChangePrice asks to change the price of a product
+type ChangePrice struct {
+	ProductID       string `json:"-"`
+	PriceCents      int64  `json:"price_cents"`
+	ExpectedVersion int64  `json:"expected_version"`
+}
+
+// DiscontinueProduct asks to stop selling a product
+type DiscontinueProduct struct {
+	ProductID       string `json:"-"`
+	Reason          string `json:"reason"`
+	ExpectedVersion int64  `json:"expected_version"`
+}
+
+// CommandResult is the outcome of an accepted command: the product, its
+// version after the command and the position of the event in the log. The
+// read models reflect the command once they reach that position.
+type CommandResult struct {
+	ProductID string `json:"product_id"`
+	Version   int64  `json:"version"`
+	Position  int64  `json:"position"`
+}
+
+// CommandHandler is the write side: it validates commands against the
+// write model and records their outcome as events in the log. It returns
+// as soon as the event is recorded, without waiting for the read models.
+type CommandHandler struct {
+	mutex    sync.Mutex
+	products map[string]*Product
+	log      *EventLog
+	ids      uuid.IDGenerator
+	now      func() time.Time
+}
+
+// NewCommandHandler creates a handler recording events in log
+func NewCommandHandler(log *EventLog, ids uuid.IDGenerator) *CommandHandler {
+	return &CommandHandler{
+		products: make(map[string]*Product),
+		log:      log,
+		ids:      ids,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// CreateProduct handles cmd
+func (h *CommandHandler) CreateProduct(ctx context.Context, cmd CreateProduct) (CommandResult, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	product, err := NewProduct(h.ids.NewID(), cmd.Name, cmd.PriceCents, h.now())
+	if err != nil {
+		return CommandResult{}, err
+	}
+	h.products[product.ID] = product
+	return h.record(ctx, product)
+}
+
+// RenameProduct handles cmd
+func (h *CommandHandler) RenameProduct(ctx context.Context, cmd RenameProduct) (CommandResult, error) {
+	if err := validateName(cmd.Name); err != nil {
+		return CommandResult{}, err
+	}
+	return h.change(ctx, cmd.ProductID, cmd.ExpectedVersion, func(product *Product) error {
+		return product.Rename(cmd.Name, h.now())
+	})
+}
+
+// ChangePrice handles cmd
+func (h *CommandHandler) ChangePrice(ctx context.Context, cmd ChangePrice) (CommandResult, error) {
+	if err := validatePrice(cmd.PriceCents); err != nil {
+		return CommandResult{}, err
+	}
+	return h.change(ctx, cmd.ProductID, cmd.ExpectedVersion, func(product *Product) error {
+		return product.ChangePrice(cmd.PriceCents, h.now())
+	})
+}
+
+// DiscontinueProduct handles cmd
+func (h *CommandHandler) DiscontinueProduct(ctx context.Context, cmd DiscontinueProduct) (CommandResult, error) {
+	return h.change(ctx, cmd.ProductID, cmd.ExpectedVersion, func(product *Product) error {
+		return product.Discontinue(cmd.Reason, h.now())
+	})
+}
+
+// change applies the command apply to the product with id, unless it is
+// not at the expected version, and records the resulting events
+func (h *CommandHandler) change(ctx context.Context, id string, expectedVersion int64, apply func(*Product) error) (CommandResult, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	product, ok := h.products[id]
+	if !ok {
+		return CommandResult{}, ErrProductNotFound
+	}
+	if expectedVersion > 0 && product.Version != expectedVersion {
+		return CommandResult{}, fmt.Errorf("%w: expected version %d, current version %d", ErrConcurrentUpdate, expectedVersion, product.Version)
+	}
+	if err := apply(product); err != nil {
+		return CommandResult{}, err
+	}
+	return h.record(ctx, product)
+}
+
+// record appends the events recorded by product to the log and clears
+// them. The caller holds the mutex, so events are logged in the order of
+// the versions.
+func (h *CommandHandler) record(ctx context.Context, product *Product) (CommandResult, error) {
+	var position int64
+	for _, change := range product.Changes() {
+		event, err := change.Envelope(h.ids.NewID(), eventSource, product.ID)
+		if err != nil {
+			return CommandResult{}, err
+		}
+		position = h.log.Append(event)
+		slog.InfoContext(ctx, "Command accepted", "event_type", change.Type, "product_id", product.ID, "version", product.Version, "position", position)
+	}
+	product.ClearChanges()
+	return CommandResult{ProductID: product.ID, Version: product.Version, Position: position}, nil
+}
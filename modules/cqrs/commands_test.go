@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+func newTestCommandHandler() (*CommandHandler, *EventLog) {
+	log := NewEventLog()
+	return NewCommandHandler(log, uuid.NewSequenceGenerator("id-")), log
+}
+
+func TestCommandHandler(t *testing.T) {
+	commands, log := newTestCommandHandler()
+	ctx := context.Background()
+
+	created, err := commands.CreateProduct(ctx, CreateProduct{Name: "Mug", PriceCents: 900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Version != 1 || created.Position != 1 {
+		t.Errorf("got %+v want version 1 at position 1", created)
+	}
+	id := created.ProductID
+
+	renamed, err := commands.RenameProduct(ctx, RenameProduct{ProductID: id, Name: "Large mug", ExpectedVersion: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commands.ChangePrice(ctx, ChangePrice{ProductID: id, PriceCents: 1200}); err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Version != 2 || renamed.Position != 2 {
+		t.Errorf("got %+v want version 2 at position 2", renamed)
+	}
+
+	tests := []struct {
+		name    string
+		command func() error
+		wantErr error
+	}{
+		{"stale version", func() error {
+			_, err := commands.RenameProduct(ctx, RenameProduct{ProductID: id, Name: "Cup", ExpectedVersion: 1})
+			return err
+		}, ErrConcurrentUpdate},
+		{"same name", func() error {
+			_, err := commands.RenameProduct(ctx, RenameProduct{ProductID: id, Name: "Large mug"})
+			return err
+		}, ErrNothingChanged},
+		{"same price", func() error {
+			_, err := commands.ChangePrice(ctx, ChangePrice{ProductID: id, PriceCents: 1200})
+			return err
+		}, ErrNothingChanged},
+		{"unknown product", func() error {
+			_, err := commands.ChangePrice(ctx, ChangePrice{ProductID: "missing", PriceCents: 100})
+			return err
+		}, ErrProductNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.command(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := commands.DiscontinueProduct(ctx, DiscontinueProduct{ProductID: id, Reason: "broken", ExpectedVersion: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commands.ChangePrice(ctx, ChangePrice{ProductID: id, PriceCents: 100}); !errors.Is(err, ErrProductDiscontinued) {
+		t.Errorf("got %v want %v", err, ErrProductDiscontinued)
+	}
+
+	recorded, _, position := log.After(0)
+	wantTypes := []string{EventTypeProductCreated, EventTypeProductRenamed, EventTypePriceChanged, EventTypeProductDiscontinued}
+	if position != int64(len(wantTypes)) {
+		t.Fatalf("got %d events want %d, rejected commands record none", position, len(wantTypes))
+	}
+	for i, event := range recorded {
+		var data ProductEventData
+		if err := event.Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != wantTypes[i] || event.Subject != id || data.Version != int64(i+1) {
+			t.Errorf("got %s of %s at version %d want %s at version %d", event.Type, event.Subject, data.Version, wantTypes[i], i+1)
+		}
+	}
+}
+
+func TestCommandHandlerValidation(t *testing.T) {
+	commands, _ := newTestCommandHandler()
+	ctx := context.Background()
+	var validationErr *domain.ValidationError
+
+	for _, cmd := range []CreateProduct{
+		{Name: "", PriceCents: 100},
+		{Name: "Mug", PriceCents: 0},
+		{Name: string(make([]byte, 201)), PriceCents: 100},
+	} {
+		if _, err := commands.CreateProduct(ctx, cmd); !errors.As(err, &validationErr) {
+			t.Errorf("CreateProduct(%+v) got %v want a validation error", cmd, err)
+		}
+	}
+	if _, err := commands.RenameProduct(ctx, RenameProduct{ProductID: "p", Name: ""}); !errors.As(err, &validationErr) {
+		t.Errorf("got %v want a validation error", err)
+	}
+	if _, err := commands.ChangePrice(ctx, ChangePrice{ProductID: "p", PriceCents: -1}); !errors.As(err, &validationErr) {
+		t.Errorf("got %v want a validation error", err)
+	}
+
+	// Every invalid field of a new product is reported at once
+	var errs domain.ValidationErrors
+	if _, err := commands.CreateProduct(ctx, CreateProduct{}); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("got %v want the name and price validation errors", err)
+	}
+}
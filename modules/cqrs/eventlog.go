@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// EventLog is the ordered log of the events of the write model. The
+// position of an event is its index in the log plus one, so read models can
+// remember how far they got and catch up from there.
+type EventLog struct {
+	mutex   sync.Mutex
+	events  []events.Event
+	changed chan struct{}
+}
+
+// NewEventLog creates an empty log
+func NewEventLog() *EventLog {
+	return &EventLog{changed: make(chan struct{})}
+}
+
+// Append appends events to the log, wakes up the readers waiting for them,
+// and returns the position of the last one
+func (l *EventLog) Append(appended ...events.Event) int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = append(l.events, appended...)
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return int64(len(l.events))
+}
+
+// After returns the events after position, the channel closed on the next
+// append, and the position of the last event
+func (l *EventLog) After(position int64) ([]events.Event, <-chan struct{}, int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	end := int64(len(l.events))
+	if position >= end {
+		return nil, l.changed, end
+	}
+	return append([]events.Event(nil), l.events[position:]...), l.changed, end
+}
+
+// Position returns the position of the last event
+func (l *EventLog) Position() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int64(len(l.events))
+}
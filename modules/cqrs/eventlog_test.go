@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestEventLog(t *testing.T) {
+	log := NewEventLog()
+	pending, changed, position := log.After(0)
+	if len(pending) != 0 || position != 0 {
+		t.Fatalf("got %d events at position %d want an empty log", len(pending), position)
+	}
+
+	if got := log.Append(events.Event{ID: "e1"}, events.Event{ID: "e2"}); got != 2 {
+		t.Errorf("got position %d want 2", got)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("got the change channel open after an append")
+	}
+
+	pending, changed, position = log.After(1)
+	if len(pending) != 1 || pending[0].ID != "e2" || position != 2 {
+		t.Errorf("got %v at position %d want e2 at position 2", pending, position)
+	}
+	select {
+	case <-changed:
+		t.Error("got the change channel closed without an append")
+	default:
+	}
+	if got := log.Position(); got != 2 {
+		t.Errorf("got position %d want 2", got)
+	}
+}
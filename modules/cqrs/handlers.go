@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// maxConsistencyWait bounds how long a query waits for its read model to
+// reach the min_position it asks for
+const maxConsistencyWait = 5 * time.Second
+
+// positionHeader tells clients the position of the read model they read
+const positionHeader = "X-Read-Model-Position"
+
+// lagBody is the body of GET /lag
+type lagBody struct {
+	Position   int64           `json:"position"`
+	ReadModels []ProjectionLag `json:"read_models"`
+}
+
+// CatalogHandler serves the commands and the queries of the catalog. They
+// share nothing but the event log: commands go to the write model, queries
+// to the read models.
+type CatalogHandler struct {
+	commands        *CommandHandler
+	list            *ListView
+	detail          *DetailView
+	listProjector   *Projector
+	detailProjector *Projector
+	log             *EventLog
+	mux             *http.ServeMux
+}
+
+// NewCatalogHandler creates the handler of the products and lag routes
+func NewCatalogHandler(commands *CommandHandler, list *ListView, detail *DetailView, listProjector, detailProjector *Projector, log *EventLog) *CatalogHandler {
+	h := &CatalogHandler{
+		commands:        commands,
+		list:            list,
+		detail:          detail,
+		listProjector:   listProjector,
+		detailProjector: detailProjector,
+		log:             log,
+		mux:             http.NewServeMux(),
+	}
+	h.mux.HandleFunc("POST /products", h.handleCreateProduct)
+	h.mux.HandleFunc("POST /products/{id}/rename", h.handleRenameProduct)
+	h.mux.HandleFunc("POST /products/{id}/price", h.handleChangePrice)
+	h.mux.HandleFunc("POST /products/{id}/discontinue", h.handleDiscontinueProduct)
+	h.mux.HandleFunc("GET /products", h.handleListProducts)
+	h.mux.HandleFunc("GET /products/{id}", h.handleGetProduct)
+	h.mux.HandleFunc("GET /lag", h.handleLag)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *CatalogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleCreateProduct handles the CreateProduct command
+func (h *CatalogHandler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
+	var cmd CreateProduct
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	result, err := h.commands.CreateProduct(r.Context(), cmd)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/products/"+result.ProductID)
+	httpx.WriteJSON(w, http.StatusAccepted, result)
+}
+
+// handleRenameProduct handles the RenameProduct command
+func (h *CatalogHandler) handleRenameProduct(w http.ResponseWriter, r *http.Request) {
+	var cmd RenameProduct
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	cmd.ProductID = r.PathValue("id")
+	result, err := h.commands.RenameProduct(r.Context(), cmd)
+	h.writeResult(w, r, result, err)
+}
+
+// handleChangePrice handles the ChangePrice command
+func (h *CatalogHandler) handleChangePrice(w http.ResponseWriter, r *http.Request) {
+	var cmd ChangePrice
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	cmd.ProductID = r.PathValue("id")
+	result, err := h.commands.ChangePrice(r.Context(), cmd)
+	h.writeResult(w, r, result, err)
+}
+
+// handleDiscontinueProduct handles the DiscontinueProduct command
+func (h *CatalogHandler) handleDiscontinueProduct(w http.ResponseWriter, r *http.Request) {
+	var cmd DiscontinueProduct
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	cmd.ProductID = r.PathValue("id")
+	result, err := h.commands.DiscontinueProduct(r.Context(), cmd)
+	h.writeResult(w, r, result, err)
+}
+
+// handleListProducts queries the list read model
+func (h *CatalogHandler) handleListProducts(w http.ResponseWriter, r *http.Request) {
+	if !h.awaitPosition(w, r, h.listProjector) {
+		return
+	}
+	includeDiscontinued := r.URL.Query().Get("include_discontinued") == "true"
+	httpx.WriteJSON(w, http.StatusOK, h.list.List(includeDiscontinued))
+}
+
+// handleGetProduct queries the detail read model
+func (h *CatalogHandler) handleGetProduct(w http.ResponseWriter, r *http.Request) {
+	if !h.awaitPosition(w, r, h.detailProjector) {
+		return
+	}
+	product, err := h.detail.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, product)
+}
+
+// handleLag reports how far each read model is behind the write model
+func (h *CatalogHandler) handleLag(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, lagBody{
+		Position:   h.log.Position(),
+		ReadModels: []ProjectionLag{h.listProjector.Lag(), h.detailProjector.Lag()},
+	})
+}
+
+// awaitPosition waits for the read model of projector to reach the
+// min_position query parameter, the position of a command result, so
+// clients can read their own writes. It writes the position of the read
+// model, or a problem when it did not get there in time, and reports
+// whether the query may proceed.
+func (h *CatalogHandler) awaitPosition(w http.ResponseWriter, r *http.Request, projector *Projector) bool {
+	if value := r.URL.Query().Get("min_position"); value != "" {
+		position, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || position < 0 {
+			problem.Write(w, r, http.StatusBadRequest, "Invalid min_position: "+value)
+			return false
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), maxConsistencyWait)
+		defer cancel()
+		if !projector.WaitFor(ctx, position) {
+			w.Header().Set(positionHeader, strconv.FormatInt(projector.Position(), 10))
+			problem.Write(w, r, http.StatusServiceUnavailable,
+				fmt.Sprintf("The read model is at position %d, not yet at %d", projector.Position(), position))
+			return false
+		}
+	}
+	w.Header().Set(positionHeader, strconv.FormatInt(projector.Position(), 10))
+	return true
+}
+
+// writeResult writes the result of a command, or its error
+func (h *CatalogHandler) writeResult(w http.ResponseWriter, r *http.Request, result CommandResult, err error) {
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusAccepted, result)
+}
+
+// writeError writes the problem matching a command or query error
+func (h *CatalogHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr), errors.Is(err, ErrNothingChanged):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrProductNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrConcurrentUpdate), errors.Is(err, ErrProductDiscontinued):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// decodeBody decodes the JSON body of r into dst, writing a problem and
+// returning false when it is invalid
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(dst); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCatalogHandler(t *testing.T) {
+	commands, log := newTestCommandHandler()
+	list, detail := NewListView(), NewDetailView()
+	listProjector := NewProjector("list", list, log, 0)
+	detailProjector := NewProjector("detail", detail, log, 0)
+	handler := NewCatalogHandler(commands, list, detail, listProjector, detailProjector, log)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name":"Mug","price_cents":900}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("got status %d want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	var created CommandResult
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	id := created.ProductID
+
+	// The projectors are not running: the read models lag behind
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"lagging detail", http.MethodGet, "/products/" + id, "", http.StatusNotFound},
+		{"rename", http.MethodPost, "/products/" + id + "/rename", `{"name":"Cup","expected_version":1}`, http.StatusAccepted},
+		{"stale rename", http.MethodPost, "/products/" + id + "/rename", `{"name":"Bowl","expected_version":1}`, http.StatusConflict},
+		{"price", http.MethodPost, "/products/" + id + "/price", `{"price_cents":1000}`, http.StatusAccepted},
+		{"invalid price", http.MethodPost, "/products/" + id + "/price", `{"price_cents":0}`, http.StatusUnprocessableEntity},
+		{"discontinue missing", http.MethodPost, "/products/missing/discontinue", `{}`, http.StatusNotFound},
+		{"invalid JSON", http.MethodPost, "/products", `{`, http.StatusBadRequest},
+		{"invalid min position", http.MethodGet, "/products?min_position=soon", "", http.StatusBadRequest},
+		{"list", http.MethodGet, "/products", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lag", nil))
+	var lag lagBody
+	if err := json.NewDecoder(rec.Body).Decode(&lag); err != nil {
+		t.Fatal(err)
+	}
+	if lag.Position != 3 || len(lag.ReadModels) != 2 || lag.ReadModels[0].Behind != 3 || lag.ReadModels[1].ReadModel != "detail" {
+		t.Errorf("got %+v want both read models 3 events behind", lag)
+	}
+
+	go listProjector.Run(t.Context())
+	go detailProjector.Run(t.Context())
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/"+id+"?min_position=3", nil))
+	var product ProductDetail
+	if err := json.NewDecoder(rec.Body).Decode(&product); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || product.Name != "Cup" || product.PriceCents != 1000 || rec.Header().Get(positionHeader) != "3" {
+		t.Errorf("got %d %+v at position %s want the cup at 1000 at position 3", rec.Code, product, rec.Header().Get(positionHeader))
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort                  = "8089"
+	defaultHost                  = "localhost"
+	defaultListProjectionDelay   = 500 * time.Millisecond
+	defaultDetailProjectionDelay = 2 * time.Second
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	listDelay, detailDelay, err := loadProjectionDelays()
+	if err != nil {
+		fatal("Invalid projection configuration", "error", err)
+	}
+
+	// The write side records events in the log; each read model follows it
+	// in its own goroutine, at its own pace
+	eventLog := NewEventLog()
+	commands := NewCommandHandler(eventLog, uuid.GeneratorFunc(uuid.NewGoogle))
+	list := NewListView()
+	detail := NewDetailView()
+	listProjector := NewProjector("list", list, eventLog, listDelay)
+	detailProjector := NewProjector("detail", detail, eventLog, detailDelay)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go listProjector.Run(ctx)
+	go detailProjector.Run(ctx)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewCatalogHandler(commands, list, detail, listProjector, detailProjector, eventLog)
+	mux.Handle("/products", api)
+	mux.Handle("/products/", api)
+	mux.Handle("/lag", api)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting CQRS catalog", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"list_projection_delay", listDelay, "detail_projection_delay", detailDelay)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("CQRS catalog failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down CQRS catalog")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("CQRS catalog forced to shutdown", "error", err)
+	}
+	slog.Info("CQRS catalog exited")
+}
+
+// loadProjectionDelays reads the delay of the events of the list and detail
+// read models from LIST_PROJECTION_DELAY and DETAIL_PROJECTION_DELAY
+func loadProjectionDelays() (list, detail time.Duration, err error) {
+	list, detail = defaultListProjectionDelay, defaultDetailProjectionDelay
+	for _, setting := range []struct {
+		key   string
+		value *time.Duration
+	}{{"LIST_PROJECTION_DELAY", &list}, {"DETAIL_PROJECTION_DELAY", &detail}} {
+		if value := os.Getenv(setting.key); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return 0, 0, fmt.Errorf("%s must be a duration that is not negative, got %q", setting.key, value)
+			}
+			*setting.value = d
+		}
+	}
+	return list, detail, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "cqrs",
+		"endpoints": map[string]string{
+			"products": "/products",
+			"lag":      "/lag",
+			"health":   "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
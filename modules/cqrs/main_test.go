@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadProjectionDelays(t *testing.T) {
+	tests := []struct {
+		name       string
+		list       string
+		detail     string
+		wantList   time.Duration
+		wantDetail time.Duration
+		wantErr    bool
+	}{
+		{"defaults", "", "", defaultListProjectionDelay, defaultDetailProjectionDelay, false},
+		{"custom", "0", "10s", 0, 10 * time.Second, false},
+		{"negative", "-1s", "", 0, 0, true},
+		{"invalid", "", "later", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LIST_PROJECTION_DELAY", tt.list)
+			t.Setenv("DETAIL_PROJECTION_DELAY", tt.detail)
+			list, detail, err := loadProjectionDelays()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadProjectionDelays() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if list != tt.wantList || detail != tt.wantDetail {
+				t.Errorf("got %v and %v want %v and %v", list, detail, tt.wantList, tt.wantDetail)
+			}
+		})
+	}
+}
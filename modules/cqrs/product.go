@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+// Event types of the product aggregate
+const (
+	EventTypeProductCreated      = "product.created"
+	EventTypeProductRenamed      = "product.renamed"
+	EventTypePriceChanged        = "product.price_changed"
+	EventTypeProductDiscontinued = "product.discontinued"
+)
+
+// Errors of the product aggregate
+var (
+	ErrProductNotFound     = errors.New("product not found")
+	ErrProductDiscontinued = errors.New("product is discontinued")
+	ErrConcurrentUpdate    = errors.New("product was changed concurrently")
+	ErrNothingChanged      = errors.New("command changes nothing")
+)
+
+// Product is the write model: the state needed to decide whether a command
+// is valid, and nothing more. Queries never read it, they read the read
+// models instead. Each change records the event the log receives.
+type Product struct {
+	domain.AggregateRoot
+	Name         string
+	PriceCents   int64
+	Discontinued bool
+}
+
+// ProductEventData is the payload of product events: the fields the event
+// sets, and the version of the product after it
+type ProductEventData struct {
+	ProductID  string    `json:"product_id"`
+	Name       string    `json:"name,omitempty"`
+	PriceCents int64     `json:"price_cents,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Version    int64     `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// validateName checks the name of a product
+func validateName(name string) error {
+	if err := domain.Required("name", name); err != nil {
+		return err
+	}
+	return domain.MaxLength("name", name, 200)
+}
+
+// validatePrice checks the price of a product
+func validatePrice(priceCents int64) error {
+	return domain.Positive("price_cents", priceCents)
+}
+
+// NewProduct creates the product with id, recording product.created. Both
+// an invalid name and an invalid price are reported at once.
+func NewProduct(id, name string, priceCents int64, now time.Time) (*Product, error) {
+	var errs domain.ValidationErrors
+	errs.Check(validateName(name))
+	errs.Check(validatePrice(priceCents))
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	product := &Product{AggregateRoot: domain.AggregateRoot{ID: id}, Name: name, PriceCents: priceCents}
+	product.record(EventTypeProductCreated, ProductEventData{Name: name, PriceCents: priceCents}, now)
+	return product, nil
+}
+
+// Rename renames the product, recording product.renamed
+func (p *Product) Rename(name string, now time.Time) error {
+	if p.Discontinued {
+		return ErrProductDiscontinued
+	}
+	if p.Name == name {
+		return ErrNothingChanged
+	}
+	p.Name = name
+	p.record(EventTypeProductRenamed, ProductEventData{Name: name}, now)
+	return nil
+}
+
+// ChangePrice changes the price of the product, recording
+// product.price_changed
+func (p *Product) ChangePrice(priceCents int64, now time.Time) error {
+	if p.Discontinued {
+		return ErrProductDiscontinued
+	}
+	if p.PriceCents == priceCents {
+		return ErrNothingChanged
+	}
+	p.PriceCents = priceCents
+	p.record(EventTypePriceChanged, ProductEventData{PriceCents: priceCents}, now)
+	return nil
+}
+
+// Discontinue stops selling the product for reason, recording
+// product.discontinued
+func (p *Product) Discontinue(reason string, now time.Time) error {
+	if p.Discontinued {
+		return ErrProductDiscontinued
+	}
+	p.Discontinued = true
+	p.record(EventTypeProductDiscontinued, ProductEventData{Reason: reason}, now)
+	return nil
+}
+
+// record records an event of eventType with data, completed with the
+// product and the version the event brings it to
+func (p *Product) record(eventType string, data ProductEventData, now time.Time) {
+	data.ProductID = p.ID
+	data.Version = p.Version + 1
+	data.OccurredAt = now
+	p.Record(eventType, data)
+}
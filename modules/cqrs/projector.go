@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ProjectionLag describes how far a read model is behind the write model
+type ProjectionLag struct {
+	ReadModel string `json:"read_model"`
+	Position  int64  `json:"position"`
+	// Behind is the number of events not applied yet
+	Behind int64 `json:"behind"`
+	// LagMS is the age of the oldest event not applied yet, in milliseconds
+	LagMS int64 `json:"lag_ms"`
+}
+
+// Projector keeps a read model up to date by applying the events of the log
+// in order, in its own goroutine. Delay slows every event down, to make the
+// eventual consistency of the read model visible.
+type Projector struct {
+	name     string
+	model    ReadModel
+	log      *EventLog
+	delay    time.Duration
+	mutex    sync.Mutex
+	position int64
+	pending  []events.Event
+	now      func() time.Time
+}
+
+// NewProjector creates a projector of model named name, applying every
+// event of log delay after the previous one
+func NewProjector(name string, model ReadModel, log *EventLog, delay time.Duration) *Projector {
+	return &Projector{name: name, model: model, log: log, delay: delay, now: time.Now}
+}
+
+// Run applies the events of the log as they are appended until ctx is done
+func (p *Projector) Run(ctx context.Context) {
+	for {
+		pending, changed, _ := p.log.After(p.Position())
+		p.mutex.Lock()
+		p.pending = pending
+		p.mutex.Unlock()
+		if len(pending) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				continue
+			}
+		}
+
+		for _, event := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.delay):
+			}
+			if err := p.model.Apply(event); err != nil {
+				// A read model that cannot apply an event is a bug; skip it
+				// rather than stop projecting
+				slog.ErrorContext(ctx, "Failed to apply event", "read_model", p.name, "event_type", event.Type, "event_id", event.ID, "error", err)
+			}
+			p.mutex.Lock()
+			p.position++
+			p.pending = p.pending[1:]
+			p.mutex.Unlock()
+			slog.DebugContext(ctx, "Event applied", "read_model", p.name, "event_type", event.Type, "position", p.Position())
+		}
+	}
+}
+
+// Position returns the position of the last event applied
+func (p *Projector) Position() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.position
+}
+
+// Lag returns how far the read model is behind the log
+func (p *Projector) Lag() ProjectionLag {
+	end := p.log.Position()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	lag := ProjectionLag{ReadModel: p.name, Position: p.position, Behind: end - p.position}
+	if lag.Behind > 0 {
+		oldest := p.now()
+		if len(p.pending) > 0 {
+			oldest = p.pending[0].Time
+		} else if pending, _, _ := p.log.After(p.position); len(pending) > 0 {
+			oldest = pending[0].Time
+		}
+		lag.LagMS = max(p.now().Sub(oldest).Milliseconds(), 0)
+	}
+	return lag
+}
+
+// WaitFor waits until the read model reached position or ctx is done, and
+// reports whether it did
+func (p *Projector) WaitFor(ctx context.Context, position int64) bool {
+	for {
+		if p.Position() >= position {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
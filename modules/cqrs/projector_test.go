@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProjector(t *testing.T) {
+	commands, log := newTestCommandHandler()
+	list := NewListView()
+	projector := NewProjector("list", list, log, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go projector.Run(ctx)
+
+	result, err := commands.CreateProduct(ctx, CreateProduct{Name: "Mug", PriceCents: 900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	if !projector.WaitFor(waitCtx, result.Position) {
+		t.Fatalf("got position %d want %d", projector.Position(), result.Position)
+	}
+	if products := list.List(false); len(products) != 1 {
+		t.Errorf("got %+v want the mug", products)
+	}
+	if lag := projector.Lag(); lag.Behind != 0 || lag.LagMS != 0 || lag.Position != 1 {
+		t.Errorf("got %+v want no lag", lag)
+	}
+}
+
+func TestProjectorLag(t *testing.T) {
+	commands, log := newTestCommandHandler()
+	projector := NewProjector("detail", NewDetailView(), log, time.Hour)
+	now := time.Now()
+	projector.now = func() time.Time { return now.Add(3 * time.Second) }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	commands.CreateProduct(ctx, CreateProduct{Name: "Mug", PriceCents: 900})
+	commands.CreateProduct(ctx, CreateProduct{Name: "Plate", PriceCents: 900})
+	lag := projector.Lag()
+	if lag.Behind != 2 || lag.Position != 0 || lag.LagMS < 2900 {
+		t.Errorf("got %+v want 2 events about 3s behind", lag)
+	}
+
+	go projector.Run(ctx)
+	waitCtx, waitCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer waitCancel()
+	if projector.WaitFor(waitCtx, 1) {
+		t.Error("got the read model up to date before the projection delay")
+	}
+}
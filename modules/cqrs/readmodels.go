@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ReadModel is a view of the products shaped for its queries, built from
+// the events of the log
+type ReadModel interface {
+	// Apply updates the view with the next event of the log
+	Apply(event events.Event) error
+}
+
+// ProductSummary is a line of the product list
+type ProductSummary struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	PriceCents   int64  `json:"price_cents"`
+	Discontinued bool   `json:"discontinued"`
+}
+
+// ListView is the read model of the product list: one small line per
+// product, kept sorted by name
+type ListView struct {
+	mutex    sync.RWMutex
+	products map[string]*ProductSummary
+}
+
+// NewListView creates an empty list
+func NewListView() *ListView {
+	return &ListView{products: make(map[string]*ProductSummary)}
+}
+
+// Apply updates the list with event
+func (v *ListView) Apply(event events.Event) error {
+	var data ProductEventData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if event.Type == EventTypeProductCreated {
+		v.products[data.ProductID] = &ProductSummary{ID: data.ProductID, Name: data.Name, PriceCents: data.PriceCents}
+		return nil
+	}
+	product, ok := v.products[data.ProductID]
+	if !ok {
+		return fmt.Errorf("%s of unknown product %s", event.Type, data.ProductID)
+	}
+	switch event.Type {
+	case EventTypeProductRenamed:
+		product.Name = data.Name
+	case EventTypePriceChanged:
+		product.PriceCents = data.PriceCents
+	case EventTypeProductDiscontinued:
+		product.Discontinued = true
+	}
+	return nil
+}
+
+// List returns the products sorted by name, without the discontinued ones
+// unless includeDiscontinued is set
+func (v *ListView) List(includeDiscontinued bool) []ProductSummary {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	products := make([]ProductSummary, 0, len(v.products))
+	for _, product := range v.products {
+		if includeDiscontinued || !product.Discontinued {
+			products = append(products, *product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Name != products[j].Name {
+			return products[i].Name < products[j].Name
+		}
+		return products[i].ID < products[j].ID
+	})
+	return products
+}
+
+// PricePoint is a price of a product and when it started
+type PricePoint struct {
+	PriceCents int64     `json:"price_cents"`
+	Since      time.Time `json:"since"`
+}
+
+// ProductDetail is the full page of a product, with its price history
+type ProductDetail struct {
+	ID                 string       `json:"id"`
+	Name               string       `json:"name"`
+	PriceCents         int64        `json:"price_cents"`
+	PriceHistory       []PricePoint `json:"price_history"`
+	Discontinued       bool         `json:"discontinued"`
+	DiscontinuedReason string       `json:"discontinued_reason,omitempty"`
+	Version            int64        `json:"version"`
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+}
+
+// DetailView is the read model of the product pages
+type DetailView struct {
+	mutex    sync.RWMutex
+	products map[string]*ProductDetail
+}
+
+// NewDetailView creates an empty view
+func NewDetailView() *DetailView {
+	return &DetailView{products: make(map[string]*ProductDetail)}
+}
+
+// Apply updates the page of the product of event
+func (v *DetailView) Apply(event events.Event) error {
+	var data ProductEventData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if event.Type == EventTypeProductCreated {
+		v.products[data.ProductID] = &ProductDetail{
+			ID:           data.ProductID,
+			Name:         data.Name,
+			PriceCents:   data.PriceCents,
+			PriceHistory: []PricePoint{{PriceCents: data.PriceCents, Since: data.OccurredAt}},
+			Version:      data.Version,
+			CreatedAt:    data.OccurredAt,
+			UpdatedAt:    data.OccurredAt,
+		}
+		return nil
+	}
+	product, ok := v.products[data.ProductID]
+	if !ok {
+		return fmt.Errorf("%s of unknown product %s", event.Type, data.ProductID)
+	}
+	switch event.Type {
+	case EventTypeProductRenamed:
+		product.Name = data.Name
+	case EventTypePriceChanged:
+		product.PriceCents = data.PriceCents
+		product.PriceHistory = append(product.PriceHistory, PricePoint{PriceCents: data.PriceCents, Since: data.OccurredAt})
+	case EventTypeProductDiscontinued:
+		product.Discontinued = true
+		product.DiscontinuedReason = data.Reason
+	}
+	product.Version = data.Version
+	product.UpdatedAt = data.OccurredAt
+	return nil
+}
+
+// Get returns the page of the product with id
+func (v *DetailView) Get(id string) (*ProductDetail, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	product, ok := v.products[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	found := *product
+	found.PriceHistory = append([]PricePoint(nil), product.PriceHistory...)
+	return &found, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// applyAll applies the events of the log to the models
+func applyAll(t *testing.T, log *EventLog, models ...ReadModel) {
+	t.Helper()
+	recorded, _, _ := log.After(0)
+	for _, model := range models {
+		for _, event := range recorded {
+			if err := model.Apply(event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestReadModels(t *testing.T) {
+	commands, log := newTestCommandHandler()
+	ctx := context.Background()
+	mug, _ := commands.CreateProduct(ctx, CreateProduct{Name: "Mug", PriceCents: 900})
+	plate, _ := commands.CreateProduct(ctx, CreateProduct{Name: "Plate", PriceCents: 1500})
+	commands.ChangePrice(ctx, ChangePrice{ProductID: mug.ProductID, PriceCents: 1100})
+	commands.RenameProduct(ctx, RenameProduct{ProductID: plate.ProductID, Name: "Bowl"})
+	commands.DiscontinueProduct(ctx, DiscontinueProduct{ProductID: mug.ProductID, Reason: "broken"})
+
+	list := NewListView()
+	detail := NewDetailView()
+	applyAll(t, log, list, detail)
+
+	products := list.List(false)
+	if len(products) != 1 || products[0].Name != "Bowl" {
+		t.Errorf("got %+v want the bowl only", products)
+	}
+	products = list.List(true)
+	if len(products) != 2 || products[1].Name != "Mug" || products[1].PriceCents != 1100 || !products[1].Discontinued {
+		t.Errorf("got %+v want the bowl, then the discontinued mug at 1100", products)
+	}
+
+	product, err := detail.Get(mug.ProductID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Version != 3 || !product.Discontinued || product.DiscontinuedReason != "broken" || len(product.PriceHistory) != 2 || product.PriceHistory[1].PriceCents != 1100 {
+		t.Errorf("got %+v want the discontinued mug at version 3 with 2 prices", product)
+	}
+	if _, err := detail.Get("missing"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("got %v want %v", err, ErrProductNotFound)
+	}
+}
+
+func TestReadModelsUnknownProduct(t *testing.T) {
+	event, err := events.New("e1", EventTypeProductRenamed, eventSource, "p1", ProductEventData{ProductID: "p1", Name: "Cup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []ReadModel{NewListView(), NewDetailView()} {
+		if err := model.Apply(event); err == nil {
+			t.Errorf("%T got no error renaming an unknown product", model)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Event types of the account aggregate
+const (
+	EventTypeAccountOpened  = "account.opened"
+	EventTypeMoneyDeposited = "account.money_deposited"
+	EventTypeMoneyWithdrawn = "account.money_withdrawn"
+	EventTypeAccountClosed  = "account.closed"
+)
+
+// Errors of the account aggregate
+var (
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAccountClosed     = errors.New("account is closed")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrBalanceNotZero    = errors.New("account balance is not zero")
+)
+
+// Account is the bank account aggregate. Its state is never stored: it is
+// the result of applying the events of its stream in order, starting from
+// the zero Account or from a snapshot. Its root counts the applied events
+// as its version.
+type Account struct {
+	domain.AggregateRoot
+	Owner        string    `json:"owner"`
+	BalanceCents int64     `json:"balance_cents"`
+	Closed       bool      `json:"closed"`
+	OpenedAt     time.Time `json:"opened_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AccountOpenedData is the payload of account.opened
+type AccountOpenedData struct {
+	AccountID string `json:"account_id"`
+	Owner     string `json:"owner"`
+}
+
+// MoneyData is the payload of account.money_deposited and
+// account.money_withdrawn
+type MoneyData struct {
+	AccountID   string `json:"account_id"`
+	AmountCents int64  `json:"amount_cents"`
+	Description string `json:"description,omitempty"`
+}
+
+// AccountClosedData is the payload of account.closed
+type AccountClosedData struct {
+	AccountID string `json:"account_id"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Change is an event decided by a command, before it is stored
+type Change = domain.DomainEvent
+
+// Open decides the opening of a new account
+func (a *Account) Open(id, owner string) (Change, error) {
+	if a.Version > 0 {
+		return Change{}, fmt.Errorf("account %s is already open", id)
+	}
+	if err := domain.Required("owner", owner); err != nil {
+		return Change{}, err
+	}
+	if err := domain.MaxLength("owner", owner, 200); err != nil {
+		return Change{}, err
+	}
+	return Change{Type: EventTypeAccountOpened, Data: AccountOpenedData{AccountID: id, Owner: owner}}, nil
+}
+
+// Deposit decides a deposit of amountCents
+func (a *Account) Deposit(amountCents int64, description string) (Change, error) {
+	if err := a.checkMovement(amountCents); err != nil {
+		return Change{}, err
+	}
+	return Change{Type: EventTypeMoneyDeposited, Data: MoneyData{AccountID: a.ID, AmountCents: amountCents, Description: description}}, nil
+}
+
+// Withdraw decides a withdrawal of amountCents, which the balance must cover
+func (a *Account) Withdraw(amountCents int64, description string) (Change, error) {
+	if err := a.checkMovement(amountCents); err != nil {
+		return Change{}, err
+	}
+	if amountCents > a.BalanceCents {
+		return Change{}, fmt.Errorf("%w: balance is %d cents, withdrawing %d", ErrInsufficientFunds, a.BalanceCents, amountCents)
+	}
+	return Change{Type: EventTypeMoneyWithdrawn, Data: MoneyData{AccountID: a.ID, AmountCents: amountCents, Description: description}}, nil
+}
+
+// Close decides the closing of the account, whose balance must be zero
+func (a *Account) Close(reason string) (Change, error) {
+	if a.Closed {
+		return Change{}, ErrAccountClosed
+	}
+	if a.BalanceCents != 0 {
+		return Change{}, fmt.Errorf("%w: %d cents left", ErrBalanceNotZero, a.BalanceCents)
+	}
+	return Change{Type: EventTypeAccountClosed, Data: AccountClosedData{AccountID: a.ID, Reason: reason}}, nil
+}
+
+// checkMovement checks that money may move in or out of the account
+func (a *Account) checkMovement(amountCents int64) error {
+	if a.Closed {
+		return ErrAccountClosed
+	}
+	return domain.Positive("amount_cents", amountCents)
+}
+
+// Apply applies an event of the account's stream to its state. Events are
+// facts: Apply never refuses one, whatever the state.
+func (a *Account) Apply(event events.Event) error {
+	switch event.Type {
+	case EventTypeAccountOpened:
+		var data AccountOpenedData
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		a.ID, a.Owner, a.OpenedAt = data.AccountID, data.Owner, event.Time
+	case EventTypeMoneyDeposited, EventTypeMoneyWithdrawn:
+		var data MoneyData
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		if event.Type == EventTypeMoneyWithdrawn {
+			data.AmountCents = -data.AmountCents
+		}
+		a.BalanceCents += data.AmountCents
+	case EventTypeAccountClosed:
+		a.Closed = true
+	default:
+		return fmt.Errorf("unknown account event type %s", event.Type)
+	}
+	a.Version++
+	a.UpdatedAt = event.Time
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// apply applies the event of change to account
+func apply(t *testing.T, account *Account, change Change) {
+	t.Helper()
+	event, err := events.New("e", change.Type, eventSource, account.ID, change.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := account.Apply(event); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+}
+
+func TestAccount(t *testing.T) {
+	var account Account
+	change, err := account.Open("a", "Ada")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	apply(t, &account, change)
+	if _, err := account.Open("a", "Ada"); err == nil {
+		t.Error("Open() of an open account succeeded, want an error")
+	}
+
+	change, _ = account.Deposit(500, "salary")
+	apply(t, &account, change)
+	change, _ = account.Withdraw(200, "rent")
+	apply(t, &account, change)
+	if account.ID != "a" || account.Owner != "Ada" || account.BalanceCents != 300 || account.Version != 3 {
+		t.Errorf("got %+v want Ada's account a with 300 cents at version 3", account)
+	}
+
+	tests := []struct {
+		name   string
+		decide func() (Change, error)
+		want   error
+	}{
+		{"overdraft", func() (Change, error) { return account.Withdraw(301, "") }, ErrInsufficientFunds},
+		{"close with money", func() (Change, error) { return account.Close("") }, ErrBalanceNotZero},
+		{"zero deposit", func() (Change, error) { return account.Deposit(0, "") }, &domain.ValidationError{}},
+		{"negative withdrawal", func() (Change, error) { return account.Withdraw(-1, "") }, &domain.ValidationError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.decide()
+			var validationErr *domain.ValidationError
+			if _, isValidation := tt.want.(*domain.ValidationError); isValidation {
+				if !errors.As(err, &validationErr) {
+					t.Errorf("got error %v want a validation error", err)
+				}
+			} else if !errors.Is(err, tt.want) {
+				t.Errorf("got error %v want %v", err, tt.want)
+			}
+		})
+	}
+
+	change, _ = account.Withdraw(300, "")
+	apply(t, &account, change)
+	change, err = account.Close("moving")
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	apply(t, &account, change)
+	if _, err := account.Deposit(100, ""); !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("got error %v want %v", err, ErrAccountClosed)
+	}
+}
+
+func TestAccount_Apply_UnknownType(t *testing.T) {
+	var account Account
+	event, _ := events.New("e", "account.frozen", eventSource, "a", struct{}{})
+	event.Time = time.Now()
+	if err := account.Apply(event); err == nil {
+		t.Error("Apply() of an unknown event type succeeded, want an error")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// AccountHandler serves the accounts, their events and their snapshots
+type AccountHandler struct {
+	service *AccountService
+	mux     *http.ServeMux
+}
+
+// NewAccountHandler creates the handler of the accounts routes
+func NewAccountHandler(service *AccountService) *AccountHandler {
+	h := &AccountHandler{service: service, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /accounts", h.handleOpen)
+	h.mux.HandleFunc("GET /accounts/{id}", h.handleGet)
+	h.mux.HandleFunc("POST /accounts/{id}/deposits", h.handleDeposit)
+	h.mux.HandleFunc("POST /accounts/{id}/withdrawals", h.handleWithdraw)
+	h.mux.HandleFunc("POST /accounts/{id}/close", h.handleClose)
+	h.mux.HandleFunc("GET /accounts/{id}/balance", h.handleBalance)
+	h.mux.HandleFunc("GET /accounts/{id}/events", h.handleEvents)
+	h.mux.HandleFunc("GET /accounts/{id}/snapshots", h.handleSnapshots)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *AccountHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleOpen opens an account
+func (h *AccountHandler) handleOpen(w http.ResponseWriter, r *http.Request) {
+	var cmd OpenAccount
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	account, err := h.service.Open(cmd)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/accounts/"+account.ID)
+	httpx.WriteJSON(w, http.StatusCreated, account)
+}
+
+// handleGet returns the current state of an account
+func (h *AccountHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	account, err := h.service.Get(r.PathValue("id"))
+	h.writeAccount(w, r, account, err)
+}
+
+// handleDeposit deposits money on an account
+func (h *AccountHandler) handleDeposit(w http.ResponseWriter, r *http.Request) {
+	var cmd MoveMoney
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	account, err := h.service.Deposit(r.PathValue("id"), cmd)
+	h.writeAccount(w, r, account, err)
+}
+
+// handleWithdraw withdraws money from an account
+func (h *AccountHandler) handleWithdraw(w http.ResponseWriter, r *http.Request) {
+	var cmd MoveMoney
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	account, err := h.service.Withdraw(r.PathValue("id"), cmd)
+	h.writeAccount(w, r, account, err)
+}
+
+// handleClose closes an account
+func (h *AccountHandler) handleClose(w http.ResponseWriter, r *http.Request) {
+	var cmd CloseAccount
+	if !decodeBody(w, r, &cmd) {
+		return
+	}
+	account, err := h.service.Close(r.PathValue("id"), cmd)
+	h.writeAccount(w, r, account, err)
+}
+
+// handleBalance returns the balance of an account at the RFC 3339 time of
+// the at query parameter, now without it
+func (h *AccountHandler) handleBalance(w http.ResponseWriter, r *http.Request) {
+	at := h.service.now()
+	if value := r.URL.Query().Get("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "Invalid at, expected an RFC 3339 time: "+value)
+			return
+		}
+		at = parsed.UTC()
+	}
+	balance, err := h.service.BalanceAt(r.PathValue("id"), at)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, balance)
+}
+
+// handleEvents returns the event stream of an account
+func (h *AccountHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.service.History(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, stored)
+}
+
+// handleSnapshots returns the snapshots taken of an account
+func (h *AccountHandler) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.service.Snapshots(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, snapshots)
+}
+
+// writeAccount writes the account, or its error
+func (h *AccountHandler) writeAccount(w http.ResponseWriter, r *http.Request, account Account, err error) {
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, account)
+}
+
+// writeError writes the problem matching a service error
+func (h *AccountHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr), errors.Is(err, ErrInsufficientFunds):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrAccountNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrAccountClosed), errors.Is(err, ErrBalanceNotZero), errors.Is(err, ErrConcurrencyConflict):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// decodeBody decodes the JSON body of r into dst, writing a problem and
+// returning false when it is invalid
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(dst); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccountHandler(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	service, _ := newTestService(2, start)
+	handler := NewAccountHandler(service)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"owner":"Ada"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got status %d want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var account Account
+	if err := json.NewDecoder(rec.Body).Decode(&account); err != nil {
+		t.Fatal(err)
+	}
+	id := account.ID
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"deposit", http.MethodPost, "/accounts/" + id + "/deposits", `{"amount_cents":500}`, http.StatusOK},
+		{"withdraw", http.MethodPost, "/accounts/" + id + "/withdrawals", `{"amount_cents":200}`, http.StatusOK},
+		{"overdraft", http.MethodPost, "/accounts/" + id + "/withdrawals", `{"amount_cents":1000}`, http.StatusUnprocessableEntity},
+		{"close with money", http.MethodPost, "/accounts/" + id + "/close", `{}`, http.StatusConflict},
+		{"open without owner", http.MethodPost, "/accounts", `{}`, http.StatusUnprocessableEntity},
+		{"invalid JSON", http.MethodPost, "/accounts/" + id + "/deposits", `{`, http.StatusBadRequest},
+		{"missing account", http.MethodGet, "/accounts/missing", "", http.StatusNotFound},
+		{"invalid at", http.MethodGet, "/accounts/" + id + "/balance?at=yesterday", "", http.StatusBadRequest},
+		{"get", http.MethodGet, "/accounts/" + id, "", http.StatusOK},
+		{"events", http.MethodGet, "/accounts/" + id + "/events", "", http.StatusOK},
+		{"snapshots", http.MethodGet, "/accounts/" + id + "/snapshots", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	// The deposit was recorded an hour after opening, the withdrawal two
+	rec = httptest.NewRecorder()
+	at := start.Add(90 * time.Minute).Format(time.RFC3339)
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/accounts/"+id+"/balance?at="+at, nil))
+	var balance Balance
+	if err := json.NewDecoder(rec.Body).Decode(&balance); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || balance.BalanceCents != 500 || balance.Version != 2 {
+		t.Errorf("got %d %+v want 500 cents at version 2", rec.Code, balance)
+	}
+}
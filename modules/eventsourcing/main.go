@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort           = "8090"
+	defaultHost           = "localhost"
+	defaultEventStoreFile = "eventstore.jsonl"
+	defaultSnapshotEvery  = 5
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+	storeFile := config.Getenv("EVENT_STORE_FILE", defaultEventStoreFile)
+
+	snapshotEvery, err := loadSnapshotEvery()
+	if err != nil {
+		fatal("Invalid snapshot configuration", "error", err)
+	}
+
+	// The event store is the only state kept; snapshots are rebuilt from it
+	store, closer, err := OpenEventStore(storeFile)
+	if err != nil {
+		fatal("Failed to open event store", "file", storeFile, "error", err)
+	}
+	defer closer.Close()
+	service := NewAccountService(store, NewSnapshotStore(), snapshotEvery, uuid.GeneratorFunc(uuid.NewGoogle))
+	if err := service.RebuildSnapshots(); err != nil {
+		fatal("Failed to rebuild snapshots", "error", err)
+	}
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewAccountHandler(service)
+	mux.Handle("/accounts", api)
+	mux.Handle("/accounts/", api)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting event sourcing service", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"event_store_file", storeFile, "streams", len(store.Streams()), "snapshot_every", snapshotEvery)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Event sourcing service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down event sourcing service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Event sourcing service forced to shutdown", "error", err)
+	}
+	slog.Info("Event sourcing service exited")
+}
+
+// loadSnapshotEvery reads from SNAPSHOT_EVERY how many events of an account
+// are replayed between snapshots, 0 to take none
+func loadSnapshotEvery() (int64, error) {
+	value := os.Getenv("SNAPSHOT_EVERY")
+	if value == "" {
+		return defaultSnapshotEvery, nil
+	}
+	every, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || every < 0 {
+		return 0, fmt.Errorf("SNAPSHOT_EVERY must be an integer that is not negative, got %q", value)
+	}
+	return every, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "eventsourcing",
+		"endpoints": map[string]string{
+			"accounts": "/accounts",
+			"health":   "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestLoadSnapshotEvery(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"default", "", defaultSnapshotEvery, false},
+		{"custom", "50", 50, false},
+		{"disabled", "0", 0, false},
+		{"negative", "-1", 0, true},
+		{"invalid", "often", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SNAPSHOT_EVERY", tt.value)
+			got, err := loadSnapshotEvery()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadSnapshotEvery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %d want %d", got, tt.want)
+			}
+		})
+	}
+}
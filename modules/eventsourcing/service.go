@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies the service as the producer of its events
+const eventSource = "bank"
+
+// OpenAccount asks to open an account for owner
+type OpenAccount struct {
+	Owner string `json:"owner"`
+}
+
+// MoveMoney asks to deposit or withdraw money
+type MoveMoney struct {
+	AmountCents int64  `json:"amount_cents"`
+	Description string `json:"description"`
+}
+
+// CloseAccount asks to close an account
+type CloseAccount struct {
+	Reason string `json:"reason"`
+}
+
+// Balance is the balance of an account at a point in time, and the work it
+// took to compute it
+type Balance struct {
+	AccountID       string    `json:"account_id"`
+	At              time.Time `json:"at"`
+	BalanceCents    int64     `json:"balance_cents"`
+	Version         int64     `json:"version"`
+	SnapshotVersion int64     `json:"snapshot_version"`
+	EventsReplayed  int       `json:"events_replayed"`
+}
+
+// AccountService handles the commands and queries of the accounts. Every
+// command loads the account from its events, decides a new event and
+// appends it to the stream at the version it loaded, so a concurrent
+// command on the same account fails rather than being lost.
+type AccountService struct {
+	store         *EventStore
+	snapshots     *SnapshotStore
+	snapshotEvery int64
+	ids           uuid.IDGenerator
+	now           func() time.Time
+}
+
+// NewAccountService creates the service of the accounts of store, taking a
+// snapshot of an account every snapshotEvery events, never when 0
+func NewAccountService(store *EventStore, snapshots *SnapshotStore, snapshotEvery int64, ids uuid.IDGenerator) *AccountService {
+	return &AccountService{
+		store:         store,
+		snapshots:     snapshots,
+		snapshotEvery: snapshotEvery,
+		ids:           ids,
+		now:           func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Open opens a new account
+func (s *AccountService) Open(cmd OpenAccount) (Account, error) {
+	id := s.ids.NewID()
+	return s.execute(id, true, func(account *Account) (Change, error) {
+		return account.Open(id, cmd.Owner)
+	})
+}
+
+// Deposit deposits money on the account
+func (s *AccountService) Deposit(id string, cmd MoveMoney) (Account, error) {
+	return s.execute(id, false, func(account *Account) (Change, error) {
+		return account.Deposit(cmd.AmountCents, cmd.Description)
+	})
+}
+
+// Withdraw withdraws money from the account
+func (s *AccountService) Withdraw(id string, cmd MoveMoney) (Account, error) {
+	return s.execute(id, false, func(account *Account) (Change, error) {
+		return account.Withdraw(cmd.AmountCents, cmd.Description)
+	})
+}
+
+// Close closes the account
+func (s *AccountService) Close(id string, cmd CloseAccount) (Account, error) {
+	return s.execute(id, false, func(account *Account) (Change, error) {
+		return account.Close(cmd.Reason)
+	})
+}
+
+// execute loads the account, lets decide choose the event of a command and
+// appends it to the account's stream
+func (s *AccountService) execute(id string, opening bool, decide func(*Account) (Change, error)) (Account, error) {
+	account, _, err := s.load(id, time.Time{})
+	if err != nil {
+		return Account{}, err
+	}
+	if account.Version == 0 && !opening {
+		return Account{}, ErrAccountNotFound
+	}
+	change, err := decide(&account)
+	if err != nil {
+		return Account{}, err
+	}
+
+	event, err := events.New(s.ids.NewID(), change.Type, eventSource, id, change.Data)
+	if err != nil {
+		return Account{}, err
+	}
+	now := s.now()
+	event.Time = now
+	if _, err := s.store.Append(id, account.Version, event); err != nil {
+		return Account{}, err
+	}
+	if err := account.Apply(event); err != nil {
+		return Account{}, err
+	}
+	s.snapshot(account, now)
+	return account, nil
+}
+
+// snapshot saves a snapshot of the account taken at now when its version
+// is due one
+func (s *AccountService) snapshot(account Account, now time.Time) {
+	if s.snapshotEvery > 0 && account.Version%s.snapshotEvery == 0 {
+		s.snapshots.Save(Snapshot{StreamID: account.ID, Version: account.Version, Account: account, TakenAt: now})
+	}
+}
+
+// load rebuilds the account as of until, the latest state with a zero
+// until, from its latest snapshot at that time and the events after it. It
+// also returns the number of events replayed.
+func (s *AccountService) load(id string, until time.Time) (Account, int, error) {
+	var account Account
+	if snapshot, ok := s.snapshots.Latest(id, until); ok {
+		account = snapshot.Account
+	}
+	stored := s.store.Load(id, account.Version, until)
+	for _, event := range stored {
+		if err := account.Apply(event.Event); err != nil {
+			return Account{}, 0, fmt.Errorf("replaying %s version %d: %w", id, event.Version, err)
+		}
+	}
+	return account, len(stored), nil
+}
+
+// Get returns the current state of the account
+func (s *AccountService) Get(id string) (Account, error) {
+	account, _, err := s.load(id, time.Time{})
+	if err != nil {
+		return Account{}, err
+	}
+	if account.Version == 0 {
+		return Account{}, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// BalanceAt returns the balance of the account at time at, replaying its
+// events up to then from the latest snapshot taken before
+func (s *AccountService) BalanceAt(id string, at time.Time) (Balance, error) {
+	if s.store.Version(id) == 0 {
+		return Balance{}, ErrAccountNotFound
+	}
+	var snapshotVersion int64
+	if snapshot, ok := s.snapshots.Latest(id, at); ok {
+		snapshotVersion = snapshot.Version
+	}
+	account, replayed, err := s.load(id, at)
+	if err != nil {
+		return Balance{}, err
+	}
+	return Balance{
+		AccountID:       id,
+		At:              at,
+		BalanceCents:    account.BalanceCents,
+		Version:         account.Version,
+		SnapshotVersion: snapshotVersion,
+		EventsReplayed:  replayed,
+	}, nil
+}
+
+// History returns the events of the account, oldest first
+func (s *AccountService) History(id string) ([]StoredEvent, error) {
+	stored := s.store.Load(id, 0, time.Time{})
+	if len(stored) == 0 {
+		return nil, ErrAccountNotFound
+	}
+	return stored, nil
+}
+
+// Snapshots returns the snapshots of the account, oldest first
+func (s *AccountService) Snapshots(id string) ([]Snapshot, error) {
+	if s.store.Version(id) == 0 {
+		return nil, ErrAccountNotFound
+	}
+	return s.snapshots.List(id), nil
+}
+
+// RebuildSnapshots replays the stream of every account to take the
+// snapshots it is due, e.g. after loading the event store on startup
+func (s *AccountService) RebuildSnapshots() error {
+	if s.snapshotEvery <= 0 {
+		return nil
+	}
+	now := s.now()
+	for _, id := range s.store.Streams() {
+		var account Account
+		for _, event := range s.store.Load(id, 0, time.Time{}) {
+			if err := account.Apply(event.Event); err != nil {
+				return fmt.Errorf("replaying %s version %d: %w", id, event.Version, err)
+			}
+			s.snapshot(account, now)
+		}
+	}
+	return nil
+}
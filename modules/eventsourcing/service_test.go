@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// newTestService creates a service over a memory store whose clock advances
+// an hour with every event, starting at start
+func newTestService(snapshotEvery int64, start time.Time) (*AccountService, *EventStore) {
+	store := NewMemoryEventStore()
+	service := NewAccountService(store, NewSnapshotStore(), snapshotEvery, uuid.NewSequenceGenerator("id-"))
+	clock := start.Add(-time.Hour)
+	service.now = func() time.Time {
+		clock = clock.Add(time.Hour)
+		return clock
+	}
+	return service, store
+}
+
+func TestAccountService(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	service, _ := newTestService(3, start)
+
+	account, err := service.Open(OpenAccount{Owner: "Ada"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	id := account.ID
+	// Balances after versions 2 to 7: 100, 300, 250, 650, 600, 1600
+	for i, amount := range []int64{100, 200, -50, 400, -50, 1000} {
+		if amount > 0 {
+			_, err = service.Deposit(id, MoveMoney{AmountCents: amount})
+		} else {
+			_, err = service.Withdraw(id, MoveMoney{AmountCents: -amount})
+		}
+		if err != nil {
+			t.Fatalf("movement %d error = %v", i, err)
+		}
+	}
+
+	account, err = service.Get(id)
+	if err != nil || account.BalanceCents != 1600 || account.Version != 7 {
+		t.Errorf("got %+v, %v want 1600 cents at version 7", account, err)
+	}
+	snapshots, _ := service.Snapshots(id)
+	if len(snapshots) != 2 || snapshots[0].Version != 3 || snapshots[1].Account.BalanceCents != 600 {
+		t.Errorf("got %+v want snapshots at versions 3 and 6", snapshots)
+	}
+
+	// Version n is recorded n-1 hours after start
+	tests := []struct {
+		name         string
+		at           time.Time
+		wantBalance  int64
+		wantVersion  int64
+		wantSnapshot int64
+		wantReplayed int
+	}{
+		{"before opening", start.Add(-time.Minute), 0, 0, 0, 0},
+		{"at opening", start, 0, 1, 0, 1},
+		{"before the first snapshot", start.Add(90 * time.Minute), 100, 2, 0, 2},
+		{"at the first snapshot", start.Add(2 * time.Hour), 300, 3, 3, 0},
+		{"after the first snapshot", start.Add(4*time.Hour + time.Minute), 650, 5, 3, 2},
+		{"now", start.Add(24 * time.Hour), 1600, 7, 6, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, err := service.BalanceAt(id, tt.at)
+			if err != nil {
+				t.Fatalf("BalanceAt() error = %v", err)
+			}
+			if balance.BalanceCents != tt.wantBalance || balance.Version != tt.wantVersion ||
+				balance.SnapshotVersion != tt.wantSnapshot || balance.EventsReplayed != tt.wantReplayed {
+				t.Errorf("got %+v want %d cents at version %d from snapshot %d replaying %d events",
+					balance, tt.wantBalance, tt.wantVersion, tt.wantSnapshot, tt.wantReplayed)
+			}
+		})
+	}
+}
+
+func TestAccountService_Errors(t *testing.T) {
+	service, _ := newTestService(0, time.Now().UTC())
+	account, _ := service.Open(OpenAccount{Owner: "Ada"})
+
+	if _, err := service.Deposit("missing", MoveMoney{AmountCents: 100}); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("got error %v want %v", err, ErrAccountNotFound)
+	}
+	if _, err := service.BalanceAt("missing", time.Now()); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("got error %v want %v", err, ErrAccountNotFound)
+	}
+	if _, err := service.Withdraw(account.ID, MoveMoney{AmountCents: 1}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("got error %v want %v", err, ErrInsufficientFunds)
+	}
+	if _, err := service.Close(account.ID, CloseAccount{}); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := service.Close(account.ID, CloseAccount{}); !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("got error %v want %v", err, ErrAccountClosed)
+	}
+	if history, _ := service.History(account.ID); len(history) != 2 {
+		t.Errorf("got %d events want 2", len(history))
+	}
+	if snapshots, _ := service.Snapshots(account.ID); len(snapshots) != 0 {
+		t.Errorf("got %d snapshots want none when disabled", len(snapshots))
+	}
+}
+
+func TestAccountService_RebuildSnapshots(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	service, store := newTestService(2, start)
+	account, _ := service.Open(OpenAccount{Owner: "Ada"})
+	for range 4 {
+		service.Deposit(account.ID, MoveMoney{AmountCents: 100})
+	}
+
+	// A new service over the same events, as after a restart
+	rebuilt := NewAccountService(store, NewSnapshotStore(), 2, uuid.NewSequenceGenerator("id-"))
+	if err := rebuilt.RebuildSnapshots(); err != nil {
+		t.Fatalf("RebuildSnapshots() error = %v", err)
+	}
+	want, _ := service.Snapshots(account.ID)
+	got, _ := rebuilt.Snapshots(account.ID)
+	if len(got) != 2 || len(want) != 2 || got[1].Version != want[1].Version || !reflect.DeepEqual(got[1].Account, want[1].Account) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot is the state of an account after a version of its stream, so
+// loading it replays only the events after that version
+type Snapshot struct {
+	StreamID string    `json:"stream_id"`
+	Version  int64     `json:"version"`
+	Account  Account   `json:"account"`
+	TakenAt  time.Time `json:"taken_at"`
+}
+
+// SnapshotStore keeps every snapshot taken of each stream, in memory. The
+// snapshots are a cache of the event store: they can always be rebuilt by
+// replaying the events, as is done on startup.
+type SnapshotStore struct {
+	mutex     sync.RWMutex
+	snapshots map[string][]Snapshot
+}
+
+// NewSnapshotStore creates a store without snapshots
+func NewSnapshotStore() *SnapshotStore {
+	return &SnapshotStore{snapshots: make(map[string][]Snapshot)}
+}
+
+// Save adds the snapshot to the snapshots of its stream, replacing one of
+// the same version
+func (s *SnapshotStore) Save(snapshot Snapshot) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshots := s.snapshots[snapshot.StreamID]
+	i := sort.Search(len(snapshots), func(i int) bool { return snapshots[i].Version >= snapshot.Version })
+	if i < len(snapshots) && snapshots[i].Version == snapshot.Version {
+		snapshots[i] = snapshot
+		return
+	}
+	snapshots = append(snapshots, Snapshot{})
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = snapshot
+	s.snapshots[snapshot.StreamID] = snapshots
+}
+
+// Latest returns the snapshot of the stream with the highest version whose
+// last event is not after until, or the latest one with a zero until
+func (s *SnapshotStore) Latest(streamID string, until time.Time) (Snapshot, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshots := s.snapshots[streamID]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if until.IsZero() || !snapshots[i].Account.UpdatedAt.After(until) {
+			return snapshots[i], true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// List returns the snapshots of the stream, oldest first
+func (s *SnapshotStore) List(streamID string) []Snapshot {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]Snapshot{}, s.snapshots[streamID]...)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	store := NewSnapshotStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, version := range []int64{10, 5, 15} {
+		store.Save(Snapshot{StreamID: "a", Version: version, Account: Account{AggregateRoot: domain.AggregateRoot{Version: version}, UpdatedAt: start.Add(time.Duration(version) * time.Hour)}})
+	}
+	store.Save(Snapshot{StreamID: "a", Version: 10, Account: Account{AggregateRoot: domain.AggregateRoot{Version: 10}, BalanceCents: 42, UpdatedAt: start.Add(10 * time.Hour)}})
+
+	snapshots := store.List("a")
+	if len(snapshots) != 3 || snapshots[0].Version != 5 || snapshots[1].Account.BalanceCents != 42 || snapshots[2].Version != 15 {
+		t.Errorf("got %+v want versions 5, 10 replaced and 15", snapshots)
+	}
+
+	tests := []struct {
+		name        string
+		until       time.Time
+		wantVersion int64
+		wantOK      bool
+	}{
+		{"latest", time.Time{}, 15, true},
+		{"between", start.Add(12 * time.Hour), 10, true},
+		{"at a snapshot", start.Add(5 * time.Hour), 5, true},
+		{"before the first", start.Add(time.Hour), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, ok := store.Latest("a", tt.until)
+			if ok != tt.wantOK || snapshot.Version != tt.wantVersion {
+				t.Errorf("got version %d, %v want %d, %v", snapshot.Version, ok, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
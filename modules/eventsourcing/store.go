@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ErrConcurrencyConflict reports an append to a stream that changed since
+// it was read
+var ErrConcurrencyConflict = errors.New("stream was changed concurrently")
+
+// StoredEvent is an event of a stream as kept in the store: its version in
+// the stream, and its position among the events of every stream
+type StoredEvent struct {
+	StreamID string       `json:"stream_id"`
+	Version  int64        `json:"version"`
+	Position int64        `json:"position"`
+	Event    events.Event `json:"event"`
+}
+
+// EventStore is the append-only store of the event streams, one stream per
+// aggregate. It keeps the events in memory and, unless it is a memory store,
+// in a JSON Lines file, one event per line, to survive restarts.
+type EventStore struct {
+	mutex   sync.RWMutex
+	file    io.Writer
+	sync    func() error
+	events  []StoredEvent
+	streams map[string][]int
+}
+
+// OpenEventStore opens the store kept in the file at path, created when
+// missing, and loads the events already in it
+func OpenEventStore(path string) (*EventStore, io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	store := newEventStore(file, file.Sync)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var stored StoredEvent
+		if err := json.Unmarshal(scanner.Bytes(), &stored); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("%s: line %d: %w", path, line, err)
+		}
+		if err := store.add(stored); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("%s: line %d: %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return store, file, nil
+}
+
+// NewMemoryEventStore creates a store kept in memory only
+func NewMemoryEventStore() *EventStore {
+	return newEventStore(io.Discard, func() error { return nil })
+}
+
+func newEventStore(file io.Writer, sync func() error) *EventStore {
+	return &EventStore{file: file, sync: sync, streams: make(map[string][]int)}
+}
+
+// add adds an event read from the file, checking that it follows the
+// events before it
+func (s *EventStore) add(stored StoredEvent) error {
+	if stored.Position != int64(len(s.events))+1 {
+		return fmt.Errorf("event at position %d, expected %d", stored.Position, len(s.events)+1)
+	}
+	if stored.Version != int64(len(s.streams[stored.StreamID]))+1 {
+		return fmt.Errorf("event of %s at version %d, expected %d", stored.StreamID, stored.Version, len(s.streams[stored.StreamID])+1)
+	}
+	s.streams[stored.StreamID] = append(s.streams[stored.StreamID], len(s.events))
+	s.events = append(s.events, stored)
+	return nil
+}
+
+// Append appends events to the stream, provided it is still at
+// expectedVersion, 0 for a new stream. It returns the stored events.
+func (s *EventStore) Append(streamID string, expectedVersion int64, appended ...events.Event) ([]StoredEvent, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	version := int64(len(s.streams[streamID]))
+	if version != expectedVersion {
+		return nil, fmt.Errorf("%w: %s is at version %d, expected %d", ErrConcurrencyConflict, streamID, version, expectedVersion)
+	}
+
+	stored := make([]StoredEvent, len(appended))
+	var lines []byte
+	for i, event := range appended {
+		stored[i] = StoredEvent{
+			StreamID: streamID,
+			Version:  version + int64(i) + 1,
+			Position: int64(len(s.events)) + int64(i) + 1,
+			Event:    event,
+		}
+		line, err := json.Marshal(stored[i])
+		if err != nil {
+			return nil, err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	// The events of an append are written at once, so they are stored all
+	// together or not at all
+	if _, err := s.file.Write(lines); err != nil {
+		return nil, fmt.Errorf("writing events: %w", err)
+	}
+	if err := s.sync(); err != nil {
+		return nil, fmt.Errorf("syncing events: %w", err)
+	}
+	for _, event := range stored {
+		s.streams[streamID] = append(s.streams[streamID], len(s.events))
+		s.events = append(s.events, event)
+	}
+	return stored, nil
+}
+
+// Load returns the events of the stream after version fromVersion whose
+// time is not after until, or every one after fromVersion with a zero until
+func (s *EventStore) Load(streamID string, fromVersion int64, until time.Time) []StoredEvent {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var loaded []StoredEvent
+	indexes := s.streams[streamID]
+	for _, index := range indexes[min(max(fromVersion, 0), int64(len(indexes))):] {
+		event := s.events[index]
+		if !until.IsZero() && event.Event.Time.After(until) {
+			break
+		}
+		loaded = append(loaded, event)
+	}
+	return loaded
+}
+
+// Version returns the version of the stream, 0 when it has no events
+func (s *EventStore) Version(streamID string) int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return int64(len(s.streams[streamID]))
+}
+
+// Streams returns the IDs of the streams, in the order they started
+func (s *EventStore) Streams() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var ids []string
+	for _, event := range s.events {
+		if event.Version == 1 {
+			ids = append(ids, event.StreamID)
+		}
+	}
+	return ids
+}
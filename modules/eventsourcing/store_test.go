@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// testEvent creates a deposit event of amountCents on account at time at
+func testEvent(t testing.TB, id, account string, amountCents int64, at time.Time) events.Event {
+	t.Helper()
+	event, err := events.New(id, EventTypeMoneyDeposited, eventSource, account, MoneyData{AccountID: account, AmountCents: amountCents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Time = at
+	return event
+}
+
+func TestEventStore_AppendAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eventstore.jsonl")
+	store, closer, err := OpenEventStore(path)
+	if err != nil {
+		t.Fatalf("OpenEventStore() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := store.Append("a", 0, testEvent(t, "1", "a", 100, now), testEvent(t, "2", "a", 200, now)); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	stored, err := store.Append("b", 0, testEvent(t, "3", "b", 300, now))
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if stored[0].Version != 1 || stored[0].Position != 3 {
+		t.Errorf("got version %d at position %d want version 1 at position 3", stored[0].Version, stored[0].Position)
+	}
+	closer.Close()
+
+	reopened, closer, err := OpenEventStore(path)
+	if err != nil {
+		t.Fatalf("OpenEventStore() of the existing file error = %v", err)
+	}
+	defer closer.Close()
+	if reopened.Version("a") != 2 || reopened.Version("b") != 1 {
+		t.Errorf("got versions %d and %d want 2 and 1", reopened.Version("a"), reopened.Version("b"))
+	}
+	if streams := reopened.Streams(); len(streams) != 2 || streams[0] != "a" || streams[1] != "b" {
+		t.Errorf("got streams %v want [a b]", streams)
+	}
+	if _, err := reopened.Append("a", 2, testEvent(t, "4", "a", 400, now)); err != nil {
+		t.Fatalf("Append() after reopening error = %v", err)
+	}
+	if loaded := reopened.Load("a", 2, time.Time{}); len(loaded) != 1 || loaded[0].Event.ID != "4" || loaded[0].Position != 4 {
+		t.Errorf("got %+v want event 4 at position 4", loaded)
+	}
+}
+
+func TestEventStore_OpenInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not JSON", "{\n"},
+		{"gap in positions", `{"stream_id":"a","version":1,"position":2}` + "\n"},
+		{"gap in versions", `{"stream_id":"a","version":1,"position":1}` + "\n" + `{"stream_id":"a","version":3,"position":2}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "eventstore.jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := OpenEventStore(path); err == nil {
+				t.Error("OpenEventStore() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestEventStore_Append_Conflict(t *testing.T) {
+	store := NewMemoryEventStore()
+	now := time.Now().UTC()
+	store.Append("a", 0, testEvent(t, "1", "a", 100, now))
+
+	_, err := store.Append("a", 0, testEvent(t, "2", "a", 100, now))
+	if !errors.Is(err, ErrConcurrencyConflict) {
+		t.Errorf("got error %v want %v", err, ErrConcurrencyConflict)
+	}
+	if store.Version("a") != 1 {
+		t.Errorf("got version %d want 1, the conflicting event not stored", store.Version("a"))
+	}
+}
+
+func TestEventStore_Load_Until(t *testing.T) {
+	store := NewMemoryEventStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"1", "2", "3"} {
+		store.Append("a", int64(i), testEvent(t, id, "a", 100, start.Add(time.Duration(i)*time.Hour)))
+	}
+
+	tests := []struct {
+		name        string
+		fromVersion int64
+		until       time.Time
+		want        int
+	}{
+		{"all", 0, time.Time{}, 3},
+		{"after version", 1, time.Time{}, 2},
+		{"until", 0, start.Add(time.Hour), 2},
+		{"before the first", 0, start.Add(-time.Second), 0},
+		{"past the end", 5, time.Time{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.Load("a", tt.fromVersion, tt.until); len(got) != tt.want {
+				t.Errorf("got %d events want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkEventStore appends events to the memory store and to the file
+// store, which writes and syncs every append, then loads the streams, and
+// reports the throughput and latencies of each. Compare runs with
+// benchstat, see pkg/events BenchmarkTransports.
+func BenchmarkEventStore(b *testing.B) {
+	stores := []struct {
+		name string
+		open func(b *testing.B) *EventStore
+	}{
+		{"memory", func(*testing.B) *EventStore { return NewMemoryEventStore() }},
+		{"file", func(b *testing.B) *EventStore {
+			store, closer, err := OpenEventStore(filepath.Join(b.TempDir(), "eventstore.jsonl"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { closer.Close() })
+			return store
+		}},
+	}
+	const streams = 100
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range stores {
+		b.Run(tt.name+"/append", func(b *testing.B) {
+			store := tt.open(b)
+			event := testEvent(b, "1", "a", 100, now)
+			var latencies []time.Duration
+			started := time.Now()
+			for i := 0; b.Loop(); i++ {
+				stream := fmt.Sprint("account-", i%streams)
+				appendStarted := time.Now()
+				if _, err := store.Append(stream, store.Version(stream), event); err != nil {
+					b.Fatal(err)
+				}
+				latencies = append(latencies, time.Since(appendStarted))
+			}
+			reportLatencies(b, latencies, time.Since(started))
+		})
+
+		b.Run(tt.name+"/load", func(b *testing.B) {
+			store := tt.open(b)
+			for i := range 100 * streams {
+				stream := fmt.Sprint("account-", i%streams)
+				if _, err := store.Append(stream, store.Version(stream), testEvent(b, fmt.Sprint(i), stream, 100, now)); err != nil {
+					b.Fatal(err)
+				}
+			}
+			var latencies []time.Duration
+			loaded := 0
+			started := time.Now()
+			for i := 0; b.Loop(); i++ {
+				loadStarted := time.Now()
+				loaded += len(store.Load(fmt.Sprint("account-", i%streams), 0, time.Time{}))
+				latencies = append(latencies, time.Since(loadStarted))
+			}
+			elapsed := time.Since(started)
+			reportLatencies(b, latencies, elapsed)
+			b.ReportMetric(float64(loaded)/elapsed.Seconds(), "events/s")
+		})
+	}
+}
+
+// reportLatencies reports the throughput of the operations and their
+// median and 99th percentile latencies
+func reportLatencies(b *testing.B, latencies []time.Duration, elapsed time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	percentile := func(p int) time.Duration {
+		return latencies[(len(latencies)-1)*p/100]
+	}
+	b.ReportMetric(float64(len(latencies))/elapsed.Seconds(), "ops/s")
+	b.ReportMetric(float64(percentile(50).Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(percentile(99).Nanoseconds()), "p99-ns")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// accessLogText writes one human-readable line per request through the standard logger
+	accessLogText = "text"
+
+	// accessLogJSON writes one JSON object per request
+	accessLogJSON = "json"
+)
+
+// AccessLogEntry describes a served request
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	LatencyMs float64   `json:"latency_ms"`
+	RequestID string    `json:"request_id"`
+	UserAgent string    `json:"user_agent"`
+	Remote    string    `json:"remote_addr"`
+}
+
+// AccessLogger writes access log entries in text or JSON format
+type AccessLogger struct {
+	format string
+	out    io.Writer
+	text   *log.Logger
+	mutex  sync.Mutex
+}
+
+// NewAccessLogger creates an AccessLogger writing format entries to out
+func NewAccessLogger(format string, out io.Writer) (*AccessLogger, error) {
+	if format != accessLogText && format != accessLogJSON {
+		return nil, fmt.Errorf("access log format must be %q or %q, got %q", accessLogText, accessLogJSON, format)
+	}
+	return &AccessLogger{
+		format: format,
+		out:    out,
+		text:   log.New(out, "", log.LstdFlags),
+	}, nil
+}
+
+// Log writes one entry
+func (l *AccessLogger) Log(entry AccessLogEntry) {
+	if l.format == accessLogText {
+		l.text.Printf("%s %s %d %dB %.3fms %s %s %q",
+			entry.Method,
+			entry.Path,
+			entry.Status,
+			entry.Bytes,
+			entry.LatencyMs,
+			entry.Remote,
+			entry.RequestID,
+			entry.UserAgent,
+		)
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		slog.Error("Error encoding access log entry", "error", err)
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.out.Write(append(line, '\n'))
+}
+
+// loggingMiddleware writes an access log entry for every request
+func loggingMiddleware(logger *AccessLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// Create a response writer wrapper to capture status code and size
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		// Call the next handler
+		next.ServeHTTP(wrapper, r)
+
+		// Log the request
+		logger.Log(AccessLogEntry{
+			Time:      start.UTC(),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    wrapper.statusCode,
+			Bytes:     wrapper.bytes,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			RequestID: RequestIDFromContext(r.Context()),
+			UserAgent: r.UserAgent(),
+			Remote:    r.RemoteAddr,
+		})
+	})
+}
+
+// responseWriter wraps http.ResponseWriter to capture status code and body size
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int64
+}
+
+// WriteHeader captures the status code
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes written
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := NewAccessLogger(accessLogJSON, &out)
+		if err != nil {
+			t.Fatalf("NewAccessLogger() error = %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.Header.Set("User-Agent", "test-agent")
+		req = req.WithContext(ContextWithRequestID(req.Context(), "req-1"))
+		loggingMiddleware(logger, handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry AccessLogEntry
+		if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatalf("access log is not JSON: %v: %q", err, out.String())
+		}
+		if entry.Method != http.MethodPost || entry.Path != "/users" || entry.Status != http.StatusCreated ||
+			entry.Bytes != 5 || entry.RequestID != "req-1" || entry.UserAgent != "test-agent" || entry.Time.IsZero() {
+			t.Errorf("unexpected access log entry: %+v", entry)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := NewAccessLogger(accessLogText, &out)
+		if err != nil {
+			t.Fatalf("NewAccessLogger() error = %v", err)
+		}
+
+		loggingMiddleware(logger, handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		if line := out.String(); !strings.Contains(line, "GET /users 201 5B") {
+			t.Errorf("unexpected access log line: %q", line)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := NewAccessLogger("xml", &bytes.Buffer{}); err == nil {
+			t.Error("NewAccessLogger() should reject unknown formats")
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// DemoDataStore is implemented by stores whose content can be reset to the
+// demonstration fixtures
+type DemoDataStore interface {
+	// Seed loads the fixture users missing from the store and returns them
+	Seed(ctx context.Context) ([]User, error)
+
+	// Reset removes every user and returns how many were removed
+	Reset(ctx context.Context) (int, error)
+}
+
+// AdminHandler serves the /admin endpoints that make workshop demos
+// repeatable without restarting the process
+type AdminHandler struct {
+	store DemoDataStore
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(store DemoDataStore) *AdminHandler {
+	return &AdminHandler{
+		store: store,
+	}
+}
+
+// ServeHTTP handles POST /admin/seed and POST /admin/reset
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var handle func(w http.ResponseWriter, r *http.Request)
+	switch r.URL.Path {
+	case "/admin/seed":
+		handle = h.handleSeed
+	case "/admin/reset":
+		handle = h.handleReset
+	default:
+		writeErrorMessage(w, r, http.StatusNotFound, "endpoint not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		handle(w, r)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleSeed handles POST /admin/seed
+func (h *AdminHandler) handleSeed(w http.ResponseWriter, r *http.Request) {
+	users, err := h.store.Seed(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Admin seeded users", "count", len(users))
+	writeAdminResponse(w, map[string]interface{}{
+		"seeded": len(users),
+		"users":  UserList(users),
+	})
+}
+
+// handleReset handles POST /admin/reset
+func (h *AdminHandler) handleReset(w http.ResponseWriter, r *http.Request) {
+	removed, err := h.store.Reset(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Admin reset removed users", "count", removed)
+	writeAdminResponse(w, map[string]interface{}{
+		"removed": removed,
+	})
+}
+
+// writeAdminResponse writes a 200 JSON response
+func writeAdminResponse(w http.ResponseWriter, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("Error encoding admin response", "error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestAdminHandler_SeedAndReset(t *testing.T) {
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+	handler := NewAdminHandler(service)
+
+	var received []string
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		received = append(received, event.Type)
+		return nil
+	})
+
+	if _, err := service.CreateUser("Workshop User", "workshop@example.com"); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   map[string]float64
+		expectedUsers  int
+	}{
+		{"seed existing fixtures", http.MethodPost, "/admin/seed", http.StatusOK, map[string]float64{"seeded": 0}, 4},
+		{"reset", http.MethodPost, "/admin/reset", http.StatusOK, map[string]float64{"removed": 4}, 0},
+		{"reset empty store", http.MethodPost, "/admin/reset", http.StatusOK, map[string]float64{"removed": 0}, 0},
+		{"seed", http.MethodPost, "/admin/seed", http.StatusOK, map[string]float64{"seeded": 3}, 3},
+		{"wrong method", http.MethodGet, "/admin/seed", http.StatusMethodNotAllowed, nil, 3},
+		{"unknown endpoint", http.MethodPost, "/admin/drop", http.StatusNotFound, nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != nil {
+				var body map[string]interface{}
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				for key, want := range tt.expectedBody {
+					if body[key] != want {
+						t.Errorf("response %s = %v, want %v", key, body[key], want)
+					}
+				}
+			}
+
+			users, _ := service.GetUsers()
+			if len(users) != tt.expectedUsers {
+				t.Errorf("store contains %d users, want %d", len(users), tt.expectedUsers)
+			}
+		})
+	}
+
+	deleted, created := 0, 0
+	for _, eventType := range received {
+		switch eventType {
+		case EventTypeUserDeleted:
+			deleted++
+		case EventTypeUserCreated:
+			created++
+		}
+	}
+	// The workshop user plus the three seeded fixtures
+	if deleted != 4 || created != 4 {
+		t.Errorf("received %d user.created and %d user.deleted events, want 4 and 4", created, deleted)
+	}
+}
+
+func TestAdminHandler_RequiresAdmin(t *testing.T) {
+	secret := []byte("test-secret")
+	service := NewInMemoryUserService()
+	validator := NewJWTValidator(JWTConfig{HMACSecret: secret})
+	handler := authMiddleware(validator, NewAuthorizer(SingleService(service)).Middleware(
+		func(*http.Request) Permission { return PermissionDemoDataManage },
+		NewAdminHandler(service),
+	))
+
+	expires := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"editor", signToken(t, "HS256", secret, map[string]interface{}{"sub": "editor", "exp": expires, "roles": []string{"editor"}}), http.StatusForbidden},
+		{"admin", signToken(t, "HS256", secret, map[string]interface{}{"sub": "admin", "exp": expires, "roles": []string{"admin"}}), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/reset", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// AuditRecord is an entry of the audit log: who changed which resource, how,
+// and in which request
+type AuditRecord struct {
+	ID         string                 `json:"id"`
+	Time       time.Time              `json:"time"`
+	Tenant     string                 `json:"tenant"`
+	Actor      string                 `json:"actor,omitempty"`
+	Action     string                 `json:"action"`
+	Resource   string                 `json:"resource"`
+	ResourceID string                 `json:"resource_id"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is the value of a field before and after a change. Before is
+// null for created resources.
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditFilter selects audit records; zero fields match every record
+type AuditFilter struct {
+	Tenant     string
+	Actor      string
+	Action     string
+	ResourceID string
+	RequestID  string
+	Since      time.Time
+	Until      time.Time
+}
+
+// matches reports whether record is selected by the filter
+func (f AuditFilter) matches(record AuditRecord) bool {
+	return (f.Tenant == "" || record.Tenant == f.Tenant) &&
+		(f.Actor == "" || record.Actor == f.Actor) &&
+		(f.Action == "" || record.Action == f.Action) &&
+		(f.ResourceID == "" || record.ResourceID == f.ResourceID) &&
+		(f.RequestID == "" || record.RequestID == f.RequestID) &&
+		(f.Since.IsZero() || !record.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || record.Time.Before(f.Until))
+}
+
+// AuditLog is an append-only audit store fed by domain events. Records are
+// never changed or removed; it keeps them in memory for the life of the process.
+type AuditLog struct {
+	records []AuditRecord
+	mutex   sync.RWMutex
+}
+
+// NewAuditLog creates an empty audit log
+func NewAuditLog() *AuditLog {
+	return &AuditLog{}
+}
+
+// Record appends the audit record of a user event. It is an events.Handler,
+// subscribed to the user events of the event bus.
+func (l *AuditLog) Record(_ context.Context, event events.Event) error {
+	var data UserEventData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	changes, err := diffUsers(data.Previous, data.User)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.records = append(l.records, AuditRecord{
+		ID:         event.ID,
+		Time:       event.Time,
+		Tenant:     event.Tenant,
+		Actor:      event.Actor,
+		Action:     event.Type,
+		Resource:   "user",
+		ResourceID: event.Subject,
+		RequestID:  event.RequestID,
+		Changes:    changes,
+	})
+	return nil
+}
+
+// Len returns the number of records in the log
+func (l *AuditLog) Len() int {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return len(l.records)
+}
+
+// Query returns the records selected by filter, newest first
+func (l *AuditLog) Query(filter AuditFilter) []AuditRecord {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	records := []AuditRecord{}
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if filter.matches(l.records[i]) {
+			records = append(records, l.records[i])
+		}
+	}
+	return records
+}
+
+// diffUsers returns the fields of the JSON representation of a user that
+// differ between before and after. Fields hidden from JSON, such as the
+// password hash, are never recorded.
+func diffUsers(before *User, after User) (map[string]FieldChange, error) {
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	beforeFields := map[string]interface{}{}
+	if before != nil {
+		if beforeFields, err = jsonFields(*before); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := make(map[string]FieldChange)
+	for name, value := range afterFields {
+		if previous, ok := beforeFields[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[name] = FieldChange{Before: previous, After: value}
+		}
+	}
+	for name, previous := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			changes[name] = FieldChange{Before: previous}
+		}
+	}
+	return changes, nil
+}
+
+// jsonFields decodes the JSON representation of user into its top-level fields
+func jsonFields(user User) (map[string]interface{}, error) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// AuditHandler serves the audit records of the request's tenant
+type AuditHandler struct {
+	log *AuditLog
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(log *AuditLog) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// ServeHTTP handles GET /audit?actor=&action=&user_id=&request_id=&since=&until=&page=&per_page=
+func (h *AuditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	records := h.log.Query(filter)
+	start, end := page.bounds(len(records))
+	writePaginationHeaders(w, r, page, len(records))
+	writeJSON(w, http.StatusOK, records[start:end])
+}
+
+// parseAuditFilter reads the filter query parameters; the tenant is the one of the request
+func parseAuditFilter(r *http.Request) (AuditFilter, error) {
+	query := r.URL.Query()
+	filter := AuditFilter{
+		Tenant:     TenantFromContext(r.Context()),
+		Actor:      query.Get("actor"),
+		Action:     query.Get("action"),
+		ResourceID: query.Get("user_id"),
+		RequestID:  query.Get("request_id"),
+	}
+
+	var errs ValidationErrors
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if value := query.Get(bound.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				errs.Add(bound.name, bound.name+" must be an RFC 3339 timestamp")
+			}
+			*bound.value = parsed
+		}
+	}
+	return filter, errs.Err()
+}
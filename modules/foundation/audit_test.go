@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+func TestAuditLog_RecordsChanges(t *testing.T) {
+	auditLog := NewAuditLog()
+	bus := NewEventBus()
+	bus.Subscribe(auditLog.Record, "user.*")
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	ctx = ContextWithClaims(ctx, &Claims{Subject: "admin-1"})
+	scoped := service.WithContext(ctx)
+
+	user, err := scoped.CreateUser("Ada", "ada@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if _, err := scoped.UpdateUser(user.ID, "Ada Lovelace", user.Email, user.Version); err != nil {
+		t.Fatalf("UpdateUser() error: %v", err)
+	}
+
+	records := auditLog.Query(AuditFilter{ResourceID: user.ID})
+	if len(records) != 2 {
+		t.Fatalf("records got %d want 2", len(records))
+	}
+
+	updated, created := records[0], records[1]
+	if created.Action != EventTypeUserCreated || updated.Action != EventTypeUserUpdated {
+		t.Errorf("actions got %v, %v want %v, %v", created.Action, updated.Action, EventTypeUserCreated, EventTypeUserUpdated)
+	}
+	for _, record := range records {
+		if record.Actor != "admin-1" {
+			t.Errorf("actor got %q want %q", record.Actor, "admin-1")
+		}
+		if record.RequestID != "req-1" {
+			t.Errorf("request ID got %q want %q", record.RequestID, "req-1")
+		}
+		if _, ok := record.Changes["password_hash"]; ok {
+			t.Error("password hash recorded in the audit log")
+		}
+	}
+
+	if change := created.Changes["name"]; change.Before != nil || change.After != "Ada" {
+		t.Errorf("created name change got %v want {<nil> Ada}", change)
+	}
+	if change := updated.Changes["name"]; change.Before != "Ada" || change.After != "Ada Lovelace" {
+		t.Errorf("updated name change got %v want {Ada Ada Lovelace}", change)
+	}
+	if _, ok := updated.Changes["email"]; ok {
+		t.Error("unchanged email recorded as a change")
+	}
+}
+
+func TestAuditFilter_Matches(t *testing.T) {
+	now := time.Now()
+	record := AuditRecord{
+		Time:       now,
+		Tenant:     defaultTenant,
+		Actor:      "admin-1",
+		Action:     EventTypeUserDeleted,
+		ResourceID: "42",
+		RequestID:  "req-1",
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{"empty filter", AuditFilter{}, true},
+		{"matching actor and action", AuditFilter{Actor: "admin-1", Action: EventTypeUserDeleted}, true},
+		{"other actor", AuditFilter{Actor: "someone"}, false},
+		{"other tenant", AuditFilter{Tenant: "acme"}, false},
+		{"other request", AuditFilter{RequestID: "req-2"}, false},
+		{"within range", AuditFilter{Since: now.Add(-time.Minute), Until: now.Add(time.Minute)}, true},
+		{"before since", AuditFilter{Since: now.Add(time.Minute)}, false},
+		{"until is exclusive", AuditFilter{Until: now}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(record); got != tt.want {
+				t.Errorf("matches() got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditHandler(t *testing.T) {
+	auditLog := NewAuditLog()
+	ids := uuid.GeneratorFunc(uuid.NewGoogle)
+	for _, change := range []struct {
+		id, eventType, tenant, actor, user string
+	}{
+		{"1", EventTypeUserCreated, defaultTenant, "admin-1", "a"},
+		{"2", EventTypeUserDeleted, defaultTenant, "admin-2", "a"},
+		{"3", EventTypeUserCreated, "acme", "admin-1", "b"},
+	} {
+		event, err := NewUserEvent(ids, change.eventType, change.tenant, User{ID: change.user}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		event.ID, event.Actor = change.id, change.actor
+		if err := auditLog.Record(context.Background(), event); err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+	handler := NewAuditHandler(auditLog)
+
+	tests := []struct {
+		name     string
+		method   string
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{"tenant records newest first", http.MethodGet, "", http.StatusOK, []string{"2", "1"}},
+		{"filter by actor", http.MethodGet, "?actor=admin-1", http.StatusOK, []string{"1"}},
+		{"filter by action", http.MethodGet, "?action=user.deleted", http.StatusOK, []string{"2"}},
+		{"invalid since", http.MethodGet, "?since=yesterday", http.StatusBadRequest, nil},
+		{"method not allowed", http.MethodPost, "", http.StatusMethodNotAllowed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/audit"+tt.query, nil)
+			req = req.WithContext(ContextWithTenant(req.Context(), defaultTenant))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status code got %v want %v", rr.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var records []AuditRecord
+			if err := json.NewDecoder(rr.Body).Decode(&records); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+			if len(records) != len(tt.wantIDs) {
+				t.Fatalf("records got %d want %d", len(records), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if records[i].ID != id {
+					t.Errorf("record %d got %q want %q", i, records[i].ID, id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+
+	defaultJWTClockSkew = 30 * time.Second
+)
+
+// Claims represents the JWT claims the service understands
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Roles     []Role   `json:"roles,omitempty"`
+}
+
+// audience holds the "aud" claim, which may be a single string or an array
+type audience []string
+
+// UnmarshalJSON accepts both the string and the array form of "aud"
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// JWTConfig holds the key material and rules used to validate tokens
+type JWTConfig struct {
+	// HMACSecret enables HS256 tokens when set
+	HMACSecret []byte
+	// RSAPublicKey enables RS256 tokens when set
+	RSAPublicKey *rsa.PublicKey
+	// Issuer, when set, must match the "iss" claim
+	Issuer string
+	// Audience, when set, must be contained in the "aud" claim
+	Audience string
+	// ClockSkew is the leeway applied to exp/nbf/iat checks
+	ClockSkew time.Duration
+}
+
+// Enabled reports whether any verification key is configured
+func (c JWTConfig) Enabled() bool {
+	return len(c.HMACSecret) > 0 || c.RSAPublicKey != nil
+}
+
+// loadJWTConfig builds the JWT configuration from settings, reading the RSA
+// public key file when one is configured
+func loadJWTConfig(settings AuthSettings) (JWTConfig, error) {
+	cfg := JWTConfig{
+		HMACSecret: []byte(settings.HS256Secret),
+		Issuer:     settings.Issuer,
+		Audience:   settings.Audience,
+		ClockSkew:  settings.ClockSkew,
+	}
+
+	if path := settings.RS256PublicKeyFile; path != "" {
+		key, err := loadRSAPublicKey(path)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.RSAPublicKey = key
+	}
+
+	return cfg, nil
+}
+
+// loadRSAPublicKey reads a PEM encoded RSA public key from disk
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading RSA public key")
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in %s", path)
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing RSA public key")
+	}
+
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("%s does not contain an RSA public key", path)
+	}
+	return key, nil
+}
+
+// JWTValidator verifies bearer tokens against a JWTConfig
+type JWTValidator struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTValidator creates a new JWTValidator
+func NewJWTValidator(config JWTConfig) *JWTValidator {
+	return &JWTValidator{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Validate verifies the token signature and registered claims
+func (v *JWTValidator) Validate(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, NewUnauthorizedError("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, NewUnauthorizedError("malformed token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, NewUnauthorizedError("malformed token signature")
+	}
+
+	if err := v.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, NewUnauthorizedError("malformed token claims")
+	}
+
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// verifySignature checks the signature with the key matching the algorithm.
+// Only algorithms with a configured key are accepted, which prevents
+// algorithm confusion attacks (e.g. "none" or HS256 signed with a public key).
+func (v *JWTValidator) verifySignature(alg, signingInput string, signature []byte) error {
+	switch {
+	case alg == algHS256 && len(v.config.HMACSecret) > 0:
+		mac := hmac.New(sha256.New, v.config.HMACSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return NewUnauthorizedError("invalid token signature")
+		}
+		return nil
+	case alg == algRS256 && v.config.RSAPublicKey != nil:
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(v.config.RSAPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return NewUnauthorizedError("invalid token signature")
+		}
+		return nil
+	default:
+		return NewUnauthorizedError(fmt.Sprintf("unsupported token algorithm %q", alg))
+	}
+}
+
+// validateClaims checks the time based and identity claims
+func (v *JWTValidator) validateClaims(claims *Claims) error {
+	now := v.now()
+	skew := v.config.ClockSkew
+
+	if claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0).Add(skew)) {
+		return NewUnauthorizedError("token has expired")
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-skew)) {
+		return NewUnauthorizedError("token is not valid yet")
+	}
+	if claims.IssuedAt != 0 && now.Before(time.Unix(claims.IssuedAt, 0).Add(-skew)) {
+		return NewUnauthorizedError("token was issued in the future")
+	}
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return NewUnauthorizedError("token issuer is not trusted")
+	}
+	if v.config.Audience != "" && !slices.Contains(claims.Audience, v.config.Audience) {
+		return NewUnauthorizedError("token audience is not accepted")
+	}
+	if claims.Subject == "" {
+		return NewUnauthorizedError("token has no subject")
+	}
+	return nil
+}
+
+// signHS256 creates a compact HS256 token for claims, accepted by a
+// JWTValidator configured with the same secret
+func signHS256(secret []byte, claims *Claims) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": algHS256, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding token claims")
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+type claimsContextKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the authenticated claims
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	canonicalLineFromContext(ctx).setUserID(claims.Subject)
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the authenticated claims stored in ctx, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
+
+// authMiddleware validates bearer tokens and attaches their claims to the
+// request context. Safe methods may be called anonymously; mutating methods
+// require a valid token.
+func authMiddleware(validator *JWTValidator, next http.Handler) http.Handler {
+	return authenticate(validator, func(r *http.Request) bool {
+		return !isSafeMethod(r.Method)
+	}, next)
+}
+
+// authenticate validates a bearer token when one is present and attaches its
+// claims to the request context. Requests without a token are rejected only
+// when required reports true for them and no session authenticated them.
+func authenticate(validator *JWTValidator, required func(*http.Request) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := bearerToken(r)
+		if !found {
+			// A browser session authenticated the request already
+			if _, ok := ClaimsFromContext(r.Context()); ok || !required(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, NewUnauthorizedError("missing bearer token"))
+			return
+		}
+
+		claims, err := validator.Validate(token)
+		if err != nil {
+			appErr, _ := IsAppError(err)
+			writeUnauthorized(w, r, appErr)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+	})
+}
+
+// bearerToken extracts the token from the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// isSafeMethod reports whether the HTTP method does not modify state
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeUnauthorized writes a 401 response with an invalid_token challenge
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, err *AppError) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, err.Message))
+	writeError(w, r, err)
+}
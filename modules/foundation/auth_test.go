@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signToken builds a compact JWT for tests using the given algorithm and key
+func signToken(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("signing token: %v", err)
+		}
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTValidator_Validate(t *testing.T) {
+	secret := []byte("test-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	validator := NewJWTValidator(JWTConfig{
+		HMACSecret:   secret,
+		RSAPublicKey: &rsaKey.PublicKey,
+		Issuer:       "learning-event-driven",
+		Audience:     "user-service",
+		ClockSkew:    30 * time.Second,
+	})
+	validator.now = func() time.Time { return now }
+
+	baseClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub": "user-1",
+			"iss": "learning-event-driven",
+			"aud": []string{"user-service"},
+			"exp": now.Add(time.Minute).Unix(),
+		}
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := baseClaims()
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid HS256", signToken(t, algHS256, secret, baseClaims()), false},
+		{"valid RS256", signToken(t, algRS256, rsaKey, baseClaims()), false},
+		{"string audience", signToken(t, algHS256, secret, with("aud", "user-service")), false},
+		{"expired within skew", signToken(t, algHS256, secret, with("exp", now.Add(-10*time.Second).Unix())), false},
+		{"expired", signToken(t, algHS256, secret, with("exp", now.Add(-time.Minute).Unix())), true},
+		{"not valid yet", signToken(t, algHS256, secret, with("nbf", now.Add(time.Minute).Unix())), true},
+		{"wrong issuer", signToken(t, algHS256, secret, with("iss", "someone-else")), true},
+		{"wrong audience", signToken(t, algHS256, secret, with("aud", "other-service")), true},
+		{"missing subject", signToken(t, algHS256, secret, with("sub", "")), true},
+		{"wrong secret", signToken(t, algHS256, []byte("other-secret"), baseClaims()), true},
+		{"unsupported algorithm", signToken(t, "none", secret, baseClaims()), true},
+		{"malformed", "not-a-token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := validator.Validate(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if appErr, ok := IsAppError(err); !ok || appErr.Type != ErrorTypeUnauthorized {
+					t.Errorf("Validate() error = %v, want %v", err, ErrorTypeUnauthorized)
+				}
+				return
+			}
+			if claims.Subject != "user-1" {
+				t.Errorf("Validate() subject = %v, want user-1", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	secret := []byte("test-secret")
+	validator := NewJWTValidator(JWTConfig{HMACSecret: secret})
+	token := signToken(t, algHS256, secret, map[string]interface{}{
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	var gotSubject string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSubject = ""
+		if claims, ok := ClaimsFromContext(r.Context()); ok {
+			gotSubject = claims.Subject
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := authMiddleware(validator, next)
+
+	tests := []struct {
+		name           string
+		method         string
+		authorization  string
+		expectedStatus int
+		expectedSub    string
+	}{
+		{"anonymous read", http.MethodGet, "", http.StatusOK, ""},
+		{"authenticated read", http.MethodGet, "Bearer " + token, http.StatusOK, "user-1"},
+		{"anonymous write", http.MethodPost, "", http.StatusUnauthorized, ""},
+		{"authenticated write", http.MethodPost, "Bearer " + token, http.StatusOK, "user-1"},
+		{"invalid token on read", http.MethodGet, "Bearer invalid", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users", strings.NewReader(`{}`))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("middleware returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("middleware should send a WWW-Authenticate challenge")
+			}
+			if rr.Code == http.StatusOK && gotSubject != tt.expectedSub {
+				t.Errorf("claims subject = %q, want %q", gotSubject, tt.expectedSub)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// avatarsPath is the path the local avatar store is served under
+const avatarsPath = "/avatars/"
+
+// avatarFormField is the multipart form field carrying the avatar image
+const avatarFormField = "avatar"
+
+// avatarTypes maps the accepted image types, sniffed from the content, to
+// the extension of their files
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// AvatarStore validates avatar images and keeps them in a blob store
+type AvatarStore struct {
+	blobs    BlobStore
+	maxBytes int64
+}
+
+// NewAvatarStore creates a store of avatars of at most maxBytes in blobs
+func NewAvatarStore(blobs BlobStore, maxBytes int64) *AvatarStore {
+	return &AvatarStore{blobs: blobs, maxBytes: maxBytes}
+}
+
+// Save validates the avatar image of the user and stores it, returning the
+// URL it is served from. Its type is sniffed from the content rather than
+// trusted from the client. The key is derived from the content, so a new
+// image gets a new URL and caches never serve a stale avatar.
+func (a *AvatarStore) Save(ctx context.Context, userID string, image io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(image, a.maxBytes+1))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return "", NewPayloadTooLargeError(maxBytesErr.Limit)
+	case err != nil:
+		return "", NewValidationError(avatarFormField, "avatar image could not be read")
+	}
+	if int64(len(data)) > a.maxBytes {
+		return "", &AppError{
+			Type:    ErrorTypePayloadTooLarge,
+			Message: fmt.Sprintf("avatar exceeds %d bytes", a.maxBytes),
+			Details: map[string]interface{}{"limit_bytes": a.maxBytes},
+		}
+	}
+	if len(data) == 0 {
+		return "", NewValidationError(avatarFormField, "avatar image is empty")
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := avatarTypes[contentType]
+	if !ok {
+		return "", NewValidationError(avatarFormField, fmt.Sprintf("avatar must be a PNG, JPEG, GIF or WebP image, got %s", contentType))
+	}
+
+	sum := sha256.Sum256(data)
+	key := avatarKey(userID, hex.EncodeToString(sum[:16])+extension)
+	if err := a.blobs.Put(ctx, key, contentType, data); err != nil {
+		return "", NewInternalError("failed to store avatar", err)
+	}
+	return a.blobs.URL(key), nil
+}
+
+// Delete removes the avatar served from avatarURL. URLs outside the store,
+// e.g. from a previous configuration, are left alone.
+func (a *AvatarStore) Delete(ctx context.Context, avatarURL string) error {
+	key, ok := strings.CutPrefix(avatarURL, a.blobs.URL(""))
+	if !ok || key == "" {
+		return nil
+	}
+	return a.blobs.Delete(ctx, key)
+}
+
+// avatarKey returns the blob key of the avatar file of a user. User IDs may
+// be chosen by ID schemes with any characters, so they are escaped.
+func avatarKey(userID, file string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", ".", "_").Replace(userID) + "/" + file
+}
+
+// handleUploadAvatar handles POST /users/{id}/avatar with a multipart form
+// whose avatar field carries the image. The previous avatar is removed once
+// the user points to the new one.
+func (h *UserHandler) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	version, ok := h.resolveIfMatch(w, r, userID)
+	if !ok {
+		return
+	}
+	previous, err := h.serviceFor(r).GetUserByID(userID)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	image, err := avatarPart(r)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	avatarURL, err := h.avatars.Save(r.Context(), userID, image)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	user, err := h.serviceFor(r).SetAvatar(userID, avatarURL, version)
+	if err != nil {
+		if avatarURL != previous.AvatarURL {
+			h.deleteAvatar(r.Context(), avatarURL)
+		}
+		h.handleError(w, r, err)
+		return
+	}
+	if previous.AvatarURL != "" && previous.AvatarURL != avatarURL {
+		h.deleteAvatar(r.Context(), previous.AvatarURL)
+	}
+
+	w.Header().Set("ETag", user.ETag())
+	h.writeResponse(w, r, http.StatusOK, user)
+}
+
+// deleteAvatar removes an avatar no user points to. Failures only leave an
+// unused file behind, so they are logged.
+func (h *UserHandler) deleteAvatar(ctx context.Context, avatarURL string) {
+	if err := h.avatars.Delete(ctx, avatarURL); err != nil {
+		h.logger.WarnContext(ctx, "Failed to delete unused avatar", "url", avatarURL, "error", err)
+	}
+}
+
+// avatarPart returns the avatar field of the multipart form of r, streamed
+// rather than buffered to disk
+func avatarPart(r *http.Request) (io.Reader, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, NewValidationError(avatarFormField, "request must be multipart/form-data with an avatar field")
+	}
+	form, err := r.MultipartReader()
+	if err != nil {
+		return nil, NewValidationError(avatarFormField, "invalid multipart body")
+	}
+	for {
+		part, err := form.NextPart()
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return nil, NewPayloadTooLargeError(maxBytesErr.Limit)
+		case errors.Is(err, io.EOF):
+			return nil, NewValidationError(avatarFormField, "avatar field is required")
+		case err != nil:
+			return nil, NewValidationError(avatarFormField, "invalid multipart body")
+		}
+		if part.FormName() == avatarFormField {
+			return part, nil
+		}
+	}
+}
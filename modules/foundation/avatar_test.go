@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngImage and gifImage start with the signatures content sniffing looks for
+var (
+	pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	gifImage = []byte("GIF89a\x01\x00\x01\x00")
+)
+
+func TestAvatarStore_Save(t *testing.T) {
+	avatars := NewAvatarStore(&LocalBlobStore{Dir: t.TempDir(), BaseURL: avatarsPath}, 64)
+
+	tests := []struct {
+		name     string
+		userID   string
+		image    []byte
+		wantURL  string
+		wantType ErrorType
+	}{
+		{"png", "1", pngImage, "/avatars/1/", ""},
+		{"gif", "1", gifImage, "/avatars/1/", ""},
+		{"user id escaped", "../2", pngImage, "/avatars/___2/", ""},
+		{"text", "1", []byte("hello"), "", ErrorTypeValidation},
+		{"html", "1", []byte("<html><script>alert(1)</script>"), "", ErrorTypeValidation},
+		{"empty", "1", nil, "", ErrorTypeValidation},
+		{"too large", "1", append(pngImage, make([]byte, 64)...), "", ErrorTypePayloadTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, err := avatars.Save(context.Background(), tt.userID, bytes.NewReader(tt.image))
+			if tt.wantType != "" {
+				var appErr *AppError
+				if !errors.As(err, &appErr) || appErr.Type != tt.wantType {
+					t.Fatalf("got error %v want %s", err, tt.wantType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if !strings.HasPrefix(url, tt.wantURL) {
+				t.Errorf("got URL %q want it under %q", url, tt.wantURL)
+			}
+		})
+	}
+
+	first, _ := avatars.Save(context.Background(), "1", bytes.NewReader(pngImage))
+	second, _ := avatars.Save(context.Background(), "1", bytes.NewReader(pngImage))
+	if first != second || !strings.HasSuffix(first, ".png") {
+		t.Errorf("got URLs %q and %q want the same URL named after the content", first, second)
+	}
+}
+
+// avatarRequest builds a multipart upload of image in field
+func avatarRequest(t *testing.T, userID, field string, image []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, "avatar.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(image)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userID+"/avatar", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("If-Match", "*")
+	return req
+}
+
+func TestUserHandler_UploadAvatar(t *testing.T) {
+	dir := t.TempDir()
+	service := NewInMemoryUserService()
+	handler := maxBodyMiddleware(1024, NewUserHandler(service, WithAvatars(NewAvatarStore(&LocalBlobStore{Dir: dir, BaseURL: avatarsPath}, 512))))
+	user, err := service.CreateUser("Avatar User", "avatar@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	noIfMatch := avatarRequest(t, user.ID, "avatar", pngImage)
+	noIfMatch.Header.Del("If-Match")
+	notMultipart := httptest.NewRequest(http.MethodPost, "/users/"+user.ID+"/avatar", bytes.NewReader(pngImage))
+	notMultipart.Header.Set("Content-Type", "image/png")
+	notMultipart.Header.Set("If-Match", "*")
+	staleETag := avatarRequest(t, user.ID, "avatar", pngImage)
+	staleETag.Header.Set("If-Match", `"`+user.ID+`-0"`)
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{"without If-Match", noIfMatch, http.StatusPreconditionRequired},
+		{"stale ETag", staleETag, http.StatusPreconditionFailed},
+		{"missing user", avatarRequest(t, "missing", "avatar", pngImage), http.StatusNotFound},
+		{"not multipart", notMultipart, http.StatusBadRequest},
+		{"missing field", avatarRequest(t, user.ID, "photo", pngImage), http.StatusBadRequest},
+		{"not an image", avatarRequest(t, user.ID, "avatar", []byte("plain text")), http.StatusBadRequest},
+		{"over the avatar limit", avatarRequest(t, user.ID, "avatar", append(pngImage, make([]byte, 600)...)), http.StatusRequestEntityTooLarge},
+		{"over the body limit", avatarRequest(t, user.ID, "avatar", append(pngImage, make([]byte, 2048)...)), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := upload(tt.req); rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	rec := upload(avatarRequest(t, user.ID, "avatar", pngImage))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d want 200: %s", rec.Code, rec.Body)
+	}
+	first, _ := service.GetUserByID(user.ID)
+	if !strings.HasPrefix(first.AvatarURL, avatarsPath+user.ID+"/") || rec.Header().Get("ETag") != first.ETag() {
+		t.Fatalf("got avatar %q and ETag %q want the uploaded avatar of version %d", first.AvatarURL, rec.Header().Get("ETag"), first.Version)
+	}
+	if !strings.Contains(rec.Body.String(), `"avatar_url":"`+first.AvatarURL+`"`) {
+		t.Errorf("got body %s want the avatar URL", rec.Body)
+	}
+
+	// A new avatar replaces the file of the previous one
+	if rec := upload(avatarRequest(t, user.ID, "avatar", gifImage)); rec.Code != http.StatusOK {
+		t.Fatalf("got status %d want 200: %s", rec.Code, rec.Body)
+	}
+	second, _ := service.GetUserByID(user.ID)
+	if second.AvatarURL == first.AvatarURL || !strings.HasSuffix(second.AvatarURL, ".gif") {
+		t.Errorf("got avatar %q want a new gif avatar", second.AvatarURL)
+	}
+	if _, err := os.Stat(filepath.Join(dir, strings.TrimPrefix(first.AvatarURL, avatarsPath))); !os.IsNotExist(err) {
+		t.Errorf("got %v want the previous avatar removed", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, strings.TrimPrefix(second.AvatarURL, avatarsPath))); err != nil {
+		t.Errorf("got %v want the new avatar stored", err)
+	}
+
+	// Without an avatar store the route does not exist
+	rec = httptest.NewRecorder()
+	NewUserHandler(service).ServeHTTP(rec, avatarRequest(t, user.ID, "avatar", pngImage))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d want 404 without avatar store", rec.Code)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BlobStore keeps files, such as avatars, under keys like "1/ab12.png" and
+// tells where they are served from
+type BlobStore interface {
+	// Put stores data under key, replacing any previous content
+	Put(ctx context.Context, key, contentType string, data []byte) error
+
+	// Delete removes the blob under key. Missing blobs are not an error.
+	Delete(ctx context.Context, key string) error
+
+	// URL returns the URL the blob under key is served from
+	URL(key string) string
+}
+
+// loadBlobStore creates the blob store of the avatars selected by settings
+func loadBlobStore(settings AvatarSettings) (BlobStore, error) {
+	switch store := settings.Store; store {
+	case "local":
+		if settings.Dir == "" {
+			return nil, errors.New("AVATAR_DIR is required by the local avatar store")
+		}
+		baseURL := settings.PublicURL
+		if baseURL == "" {
+			baseURL = avatarsPath
+		}
+		return &LocalBlobStore{Dir: settings.Dir, BaseURL: baseURL}, nil
+	case "s3":
+		if settings.S3Bucket == "" || settings.S3Region == "" {
+			return nil, errors.New("AVATAR_S3_BUCKET and AVATAR_S3_REGION are required by the s3 avatar store")
+		}
+		if settings.S3AccessKeyID == "" || settings.S3SecretAccessKey == "" {
+			return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required by the s3 avatar store")
+		}
+		return &S3BlobStore{
+			Bucket:          settings.S3Bucket,
+			Region:          settings.S3Region,
+			Endpoint:        settings.S3Endpoint,
+			AccessKeyID:     settings.S3AccessKeyID,
+			SecretAccessKey: settings.S3SecretAccessKey,
+			PublicURL:       settings.PublicURL,
+			Client:          &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	default:
+		return nil, errors.Errorf("AVATAR_STORE must be 'local' or 's3', got %q", store)
+	}
+}
+
+// LocalBlobStore keeps blobs as files of a directory, served by this
+// service under BaseURL
+type LocalBlobStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// Put writes data to the file of key. The file is written under a temporary
+// name first, so readers never see it half written.
+func (s *LocalBlobStore) Put(_ context.Context, key, _ string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "creating blob directory")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return errors.Wrap(err, "creating blob")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing blob")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "writing blob")
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return errors.Wrap(err, "writing blob")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), path), "storing blob")
+}
+
+// Delete removes the file of key
+func (s *LocalBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "deleting blob")
+	}
+	return nil
+}
+
+// URL returns the URL of key under BaseURL
+func (s *LocalBlobStore) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + key
+}
+
+// Handler serves the blobs of the directory, to be mounted under the path of
+// BaseURL with the prefix stripped. Keys name their content, so blobs never
+// change and may be cached forever.
+func (s *LocalBlobStore) Handler() http.Handler {
+	files := http.FileServer(http.Dir(s.Dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Directories are not listed
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// path returns the file of key, refusing keys that escape the directory
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", errors.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// S3BlobStore keeps blobs in an Amazon S3 bucket, or an S3 compatible
+// service such as MinIO, signing requests with AWS Signature Version 4. The
+// bucket serves them, so its objects must be publicly readable.
+type S3BlobStore struct {
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Endpoint overrides https://<bucket>.s3.<region>.amazonaws.com with a
+	// path-style endpoint, e.g. http://localhost:9000 for MinIO
+	Endpoint string
+	// PublicURL is the base URL blobs are served from, e.g. a CDN, the
+	// bucket URL when empty
+	PublicURL string
+	Client    *http.Client
+	now       func() time.Time
+}
+
+// Put uploads data as the object key
+func (s *S3BlobStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	return s.do(ctx, http.MethodPut, key, contentType, data)
+}
+
+// Delete removes the object key
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, http.MethodDelete, key, "", nil)
+}
+
+// URL returns the URL of the object key under PublicURL or the bucket
+func (s *S3BlobStore) URL(key string) string {
+	if s.PublicURL != "" {
+		return strings.TrimSuffix(s.PublicURL, "/") + "/" + key
+	}
+	return s.objectURL(key)
+}
+
+// objectURL returns the S3 API URL of the object key
+func (s *S3BlobStore) objectURL(key string) string {
+	escaped := (&url.URL{Path: key}).EscapedPath()
+	if s.Endpoint != "" {
+		return strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + escaped
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, escaped)
+}
+
+// do sends a signed request about the object key
+func (s *S3BlobStore) do(ctx context.Context, method, key, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "s3")
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, body, now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "s3")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("s3: %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers of the s3 service to req
+func (s *S3BlobStore) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hexSHA256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := []string{"host:" + req.URL.Host}
+	signedHeaders := "host"
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers = append([]string{"content-type:" + contentType}, headers...)
+		signedHeaders = "content-type;host"
+	}
+	headers = append(headers, "x-amz-content-sha256:"+payloadHash, "x-amz-date:"+amzDate)
+	signedHeaders += ";x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		strings.Join(headers, "\n") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadBlobStore(t *testing.T) {
+	s3 := AvatarSettings{Store: "s3", S3Bucket: "avatars", S3Region: "eu-west-1", S3AccessKeyID: "AKID", S3SecretAccessKey: "secret"}
+	tests := []struct {
+		name     string
+		settings AvatarSettings
+		want     string
+		wantURL  string
+		wantErr  bool
+	}{
+		{"local", AvatarSettings{Store: "local", Dir: "avatars"}, "*main.LocalBlobStore", "/avatars/1/a.png", false},
+		{"local with public url", AvatarSettings{Store: "local", Dir: "avatars", PublicURL: "https://cdn.example.com/"}, "*main.LocalBlobStore", "https://cdn.example.com/1/a.png", false},
+		{"local without dir", AvatarSettings{Store: "local"}, "", "", true},
+		{"s3", s3, "*main.S3BlobStore", "https://avatars.s3.eu-west-1.amazonaws.com/1/a.png", false},
+		{"s3 without credentials", AvatarSettings{Store: "s3", S3Bucket: "avatars", S3Region: "eu-west-1"}, "", "", true},
+		{"unknown", AvatarSettings{Store: "ftp"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := loadBlobStore(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadBlobStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := fmt.Sprintf("%T", store); got != tt.want {
+				t.Errorf("got %s want %s", got, tt.want)
+			}
+			if got := store.URL("1/a.png"); got != tt.wantURL {
+				t.Errorf("URL() = %q, want %q", got, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store := &LocalBlobStore{Dir: t.TempDir(), BaseURL: avatarsPath}
+
+	if err := store.Put(ctx, "1/a.png", "image/png", []byte("png")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(store.Dir, "1", "a.png"))
+	if err != nil || string(data) != "png" {
+		t.Fatalf("got %q, %v want the stored file", data, err)
+	}
+	for _, key := range []string{"../escape.png", "/abs.png", ""} {
+		if err := store.Put(ctx, key, "image/png", []byte("png")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+
+	handler := http.StripPrefix(avatarsPath, store.Handler())
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/avatars/1/a.png", http.StatusOK},
+		{"/avatars/1/missing.png", http.StatusNotFound},
+		{"/avatars/1/", http.StatusNotFound},
+		{"/avatars/../go.mod", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("GET %s: got status %d want %d", tt.path, rec.Code, tt.wantStatus)
+		}
+		if tt.wantStatus == http.StatusOK && (rec.Body.String() != "png" || !strings.Contains(rec.Header().Get("Cache-Control"), "immutable")) {
+			t.Errorf("GET %s: got %q with Cache-Control %q want the cached file", tt.path, rec.Body, rec.Header().Get("Cache-Control"))
+		}
+	}
+
+	if err := store.Delete(ctx, "1/a.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, "1/a.png"); err != nil {
+		t.Errorf("Delete() of a missing blob error = %v, want nil", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, "1", "a.png")); !os.IsNotExist(err) {
+		t.Errorf("got %v want the file removed", err)
+	}
+}
+
+func TestS3BlobStore(t *testing.T) {
+	type request struct {
+		method, path, contentType, authorization, payloadHash, body string
+	}
+	var requests []request
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Get("Content-Type"),
+			r.Header.Get("Authorization"), r.Header.Get("X-Amz-Content-Sha256"), string(body)})
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	store := &S3BlobStore{
+		Bucket:          "avatars",
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        server.URL,
+		now:             func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "1/a.png", "image/png", []byte("png")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Delete(ctx, "1/a.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests want 2", len(requests))
+	}
+	put, del := requests[0], requests[1]
+	if put.method != http.MethodPut || put.path != "/avatars/1/a.png" || put.contentType != "image/png" || put.body != "png" || put.payloadHash != hexSHA256([]byte("png")) {
+		t.Errorf("got %+v want a PUT of the object", put)
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20240102/eu-west-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(put.authorization, wantPrefix) || len(put.authorization) != len(wantPrefix)+64 {
+		t.Errorf("got Authorization %q want %q followed by a signature", put.authorization, wantPrefix)
+	}
+	if del.method != http.MethodDelete || del.path != "/avatars/1/a.png" || !strings.Contains(del.authorization, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("got %+v want a signed DELETE of the object", del)
+	}
+
+	status = http.StatusForbidden
+	if err := store.Put(ctx, "1/a.png", "image/png", []byte("png")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("got error %v want the refused upload", err)
+	}
+	if got, want := store.URL("1/a.png"), server.URL+"/avatars/1/a.png"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+}
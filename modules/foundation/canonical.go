@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CanonicalLine collects what happened while serving one request, logged as
+// a single wide record once the request ends. The components serving the
+// request enrich the line of its context; all methods are safe on a nil line,
+// so they can be called outside of requests.
+type CanonicalLine struct {
+	tenant       string
+	userID       string
+	serviceCalls int
+	serviceTime  time.Duration
+	events       int
+	mutex        sync.Mutex
+}
+
+type canonicalLineContextKey struct{}
+
+// contextWithCanonicalLine returns a copy of ctx collecting into line
+func contextWithCanonicalLine(ctx context.Context, line *CanonicalLine) context.Context {
+	return context.WithValue(ctx, canonicalLineContextKey{}, line)
+}
+
+// canonicalLineFromContext returns the line of the request of ctx, or nil
+func canonicalLineFromContext(ctx context.Context) *CanonicalLine {
+	line, _ := ctx.Value(canonicalLineContextKey{}).(*CanonicalLine)
+	return line
+}
+
+// setTenant records the tenant the request was served for
+func (l *CanonicalLine) setTenant(tenant string) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tenant = tenant
+}
+
+// setUserID records the token subject that made the request
+func (l *CanonicalLine) setUserID(userID string) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.userID = userID
+}
+
+// addServiceCall records a UserService call that took duration
+func (l *CanonicalLine) addServiceCall(duration time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.serviceCalls++
+	l.serviceTime += duration
+}
+
+// addEvent records a published domain event
+func (l *CanonicalLine) addEvent() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events++
+}
+
+// canonicalLogToggle logs canonical lines with canonicalLogMiddleware while
+// enabled is true, so configuration reloads can turn them on and off
+func canonicalLogToggle(enabled *atomic.Bool, logger *slog.Logger, next http.Handler) http.Handler {
+	logged := canonicalLogMiddleware(logger, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enabled.Load() {
+			logged.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// canonicalLogMiddleware logs one canonical_log_line record per request to
+// logger, with the timings, identity and events collected while serving it.
+// Unlike the access log, the record goes through the application logger, so
+// it carries the request ID and follows LOG_FORMAT.
+func canonicalLogMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		line := &CanonicalLine{}
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapper, r.WithContext(contextWithCanonicalLine(r.Context(), line)))
+
+		line.mutex.Lock()
+		defer line.mutex.Unlock()
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", spanRoute(r.URL.Path)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapper.statusCode),
+			slog.Int64("bytes", wrapper.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("service_calls", line.serviceCalls),
+			slog.Float64("service_ms", float64(line.serviceTime.Microseconds())/1000),
+			slog.Int("events_published", line.events),
+		}
+		if line.tenant != "" {
+			attrs = append(attrs, slog.String("tenant", line.tenant))
+		}
+		if line.userID != "" {
+			attrs = append(attrs, slog.String("user_id", line.userID))
+		}
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", spanContext.TraceID().String()))
+		}
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "canonical_log_line", attrs...)
+	})
+}
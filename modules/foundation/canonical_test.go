@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+)
+
+func TestCanonicalLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("logging.New() error = %v", err)
+	}
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+
+	// Enrich the line the way the tenant router, authentication and the user handlers do
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ContextWithClaims(ContextWithTenant(r.Context(), "acme"), &Claims{Subject: "user-1"})
+		users := TraceUserService(ctx, service)
+		if _, err := users.GetUsers(); err != nil {
+			t.Fatalf("GetUsers() error: %v", err)
+		}
+		if _, err := users.CreateUser("Ada", "ada@example.com"); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"1"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set(requestIDHeader, "req-1")
+	requestIDMiddleware(canonicalLogMiddleware(logger, handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("want one JSON record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"msg":              "canonical_log_line",
+		"method":           "POST",
+		"route":            "/users",
+		"status":           float64(http.StatusCreated),
+		"bytes":            float64(10),
+		"service_calls":    float64(2),
+		"events_published": float64(1),
+		"tenant":           "acme",
+		"user_id":          "user-1",
+		"request_id":       "req-1",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s got %v want %v", key, record[key], value)
+		}
+	}
+	for _, key := range []string{"duration_ms", "service_ms"} {
+		if _, ok := record[key].(float64); !ok {
+			t.Errorf("%s got %v want a number", key, record[key])
+		}
+	}
+}
+
+func TestCanonicalLine_OutsideRequests(t *testing.T) {
+	// Components enrich the line of any context, with or without a request
+	line := canonicalLineFromContext(context.Background())
+	line.setTenant("acme")
+	line.setUserID("user-1")
+	line.addServiceCall(time.Millisecond)
+	line.addEvent()
+	if line != nil {
+		t.Errorf("canonicalLineFromContext() got %v want nil", line)
+	}
+}
+
+func TestCanonicalLogToggle(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("logging.New() error = %v", err)
+	}
+	enabled := new(atomic.Bool)
+	handler := canonicalLogToggle(enabled, logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	if buf.Len() != 0 {
+		t.Errorf("disabled toggle logged %q want nothing", buf.String())
+	}
+
+	enabled.Store(true)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	if !bytes.Contains(buf.Bytes(), []byte("canonical_log_line")) {
+		t.Errorf("enabled toggle logged %q want a canonical_log_line", buf.String())
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/chaos"
+)
+
+// loadChaos returns the injector of the configured faults, or nil when
+// chaos is disabled
+func loadChaos(settings ChaosSettings) (*chaos.Injector, error) {
+	if !settings.Enabled {
+		return nil, nil
+	}
+	injector, err := chaos.New(chaos.Config{
+		Latency:        settings.Latency,
+		LatencyRate:    settings.LatencyRate,
+		ErrorRate:      settings.ErrorRate,
+		ErrorStatus:    settings.ErrorStatus,
+		PartialRate:    settings.PartialRate,
+		HeaderTriggers: settings.HeaderTriggers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CHAOS_*: %w", err)
+	}
+	return injector, nil
+}
+
+// chaosMiddleware injects the faults of injector into the requests served by
+// next, unless injector is nil. Probes and health checks are spared, so
+// orchestrators do not restart the service in the middle of a demo.
+func chaosMiddleware(injector *chaos.Injector, next http.Handler) http.Handler {
+	if injector == nil {
+		return next
+	}
+	faulty := injector.Middleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health", "/healthz", "/readyz":
+			next.ServeHTTP(w, r)
+		default:
+			faulty.ServeHTTP(w, r)
+		}
+	})
+}
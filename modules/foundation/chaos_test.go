@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/chaos"
+)
+
+func TestLoadChaos(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings ChaosSettings
+		wantNil  bool
+		wantErr  bool
+	}{
+		{"disabled", ChaosSettings{ErrorRate: 2}, true, false},
+		{"enabled", ChaosSettings{Enabled: true, ErrorRate: 0.1, ErrorStatus: 503}, false, false},
+		{"invalid rate", ChaosSettings{Enabled: true, PartialRate: -1}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector, err := loadChaos(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadChaos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (injector == nil) != tt.wantNil {
+				t.Errorf("got injector %v want nil %v", injector, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestChaosMiddleware(t *testing.T) {
+	injector, err := loadChaos(ChaosSettings{Enabled: true, ErrorRate: 1, ErrorStatus: http.StatusBadGateway})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/users", http.StatusBadGateway},
+		{"/health", http.StatusOK},
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		chaosMiddleware(injector, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s got status %d want %d", tt.path, rec.Code, tt.want)
+		}
+		if tt.want != http.StatusOK && rec.Header().Get(chaos.InjectedHeader) != "error=502" {
+			t.Errorf("%s got %s %q want the injected error", tt.path, chaos.InjectedHeader, rec.Header().Get(chaos.InjectedHeader))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	chaosMiddleware(nil, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d without chaos want 200", rec.Code)
+	}
+}
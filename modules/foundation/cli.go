@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+)
+
+// usageError is an error in the command line, as opposed to a failure of a
+// command. Its message is empty when the flag set has already reported it.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// command is a subcommand of the binary
+type command struct {
+	name    string
+	summary string
+	// run executes the command with the arguments following its name
+	run func(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+// commands returns the subcommands in the order of the usage message
+func commands() []command {
+	return []command{
+		{"serve", "Serve the HTTP and gRPC APIs (the default command)", runServe},
+		{"migrate", "Apply pending store migrations", runMigrate},
+		{"seed", "Load the demo users into a running server", runSeed},
+		{"tail", "Print the user events of a running server as JSON lines", runTail},
+		{"replay", "Rebuild the users from JSON lines of events, as printed by tail", runReplay},
+		{"loadtest", "Send a steady rate of users API requests and report latencies", runLoadTest},
+		{"smoke", "Check the users API and its events on a running deployment", runSmoke},
+		{"dump-config", "Print the effective configuration", runDumpConfig},
+		{"example-config", "Print an example config file documenting every setting", runExampleConfig},
+	}
+}
+
+// runCLI runs the command named by the first of args, serve when args are
+// empty or start with a flag, and returns the exit code of the process
+func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(ctx, args, stdout, stderr)
+		var usage *usageError
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.As(err, &usage):
+			if usage.message != "" {
+				fmt.Fprintln(stderr, usage.message)
+			}
+			return 2
+		default:
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stderr, "Unknown command %q\n\n", name)
+	printCommands(stderr)
+	return 2
+}
+
+// printCommands writes the usage message of the binary to w
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: user-service <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun user-service <command> --help for the flags of a command.\n")
+}
+
+// newCommandFlags creates the flag set of the command name, reporting errors
+// to stderr with a usage message listing its flags after summary
+func newCommandFlags(name, summary string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("user-service "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: user-service %s [flags]\n\n%s.\n\nFlags:\n", name, summary)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseCommand parses the flags of a command taking no arguments, along with
+// the config flags, and loads the configuration
+func parseCommand(flags *flag.FlagSet, args []string) (Config, error) {
+	cfg, rest, err := parseConfig(flags, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return Config{}, err
+	case errors.Is(err, errInvalidFlags):
+		return Config{}, &usageError{}
+	case err != nil:
+		return Config{}, &usageError{"Invalid configuration:\n" + err.Error()}
+	case len(rest) > 0:
+		return Config{}, &usageError{fmt.Sprintf("Unexpected arguments %q", strings.Join(rest, " "))}
+	}
+	return cfg, nil
+}
+
+// runServe serves the APIs until ctx is done
+func runServe(ctx context.Context, args []string, _, stderr io.Writer) error {
+	summary := "Serve the HTTP and gRPC APIs"
+	cfg, err := parseCommand(newCommandFlags("serve", summary, stderr), args)
+	if err != nil {
+		return err
+	}
+	// Reloads read the same flags, so they keep overriding the file and environment
+	serve(ctx, cfg, func() (Config, error) {
+		cfg, _, err := parseConfig(newCommandFlags("serve", summary, stderr), args)
+		return cfg, err
+	})
+	return nil
+}
+
+// runMigrate applies the migrations of the configured stores
+func runMigrate(_ context.Context, args []string, stdout, stderr io.Writer) error {
+	cfg, err := parseCommand(newCommandFlags("migrate", "Apply pending store migrations", stderr), args)
+	if err != nil {
+		return err
+	}
+	// Users live in memory and sessions are schemaless Redis or memory
+	// entries, so no store has a schema to migrate yet
+	fmt.Fprintf(stdout, "No migrations to apply: users are stored in memory and sessions in %s\n", cfg.Session.Store)
+	return nil
+}
+
+// runDumpConfig prints the effective configuration
+func runDumpConfig(_ context.Context, args []string, stdout, stderr io.Writer) error {
+	cfg, err := parseCommand(newCommandFlags("dump-config", "Print the effective configuration, with secrets redacted", stderr), args)
+	if err != nil {
+		return err
+	}
+	return dumpConfig(stdout, cfg)
+}
+
+// exampleConfigHeader introduces the output of example-config
+const exampleConfigHeader = `# Example configuration of user-service, with every setting at its default.
+# Each setting is commented with its type and the environment variable
+# overriding it, and can also be set by a flag named after its key, such as
+# --server.port. Secret settings are better kept out of this file.
+`
+
+// runExampleConfig prints an example config file generated from Config
+func runExampleConfig(_ context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := newCommandFlags("example-config", "Print an example config file documenting every setting", stderr)
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return err
+	} else if err != nil {
+		return &usageError{}
+	}
+	if flags.NArg() > 0 {
+		return &usageError{fmt.Sprintf("Unexpected arguments %q", strings.Join(flags.Args(), " "))}
+	}
+
+	data, err := config.Example(&Config{})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "%s\n%s", exampleConfigHeader, data)
+	return err
+}
+
+// apiClient calls the API of a running server
+type apiClient struct {
+	baseURL string
+	token   string
+	tenant  string
+	client  *http.Client
+}
+
+// apiFlags defines the flags locating and authenticating to a running server.
+// The URL defaults to the server configured by cfg once parsed.
+func apiFlags(flags *flag.FlagSet) *apiClient {
+	api := &apiClient{client: http.DefaultClient}
+	flags.StringVar(&api.baseURL, "url", "", "base URL of the server (default: the configured host and port)")
+	flags.StringVar(&api.token, "token", os.Getenv("USER_SERVICE_TOKEN"), "bearer token, overriding $USER_SERVICE_TOKEN")
+	flags.StringVar(&api.tenant, "tenant", "", "tenant, sent as "+tenantHeader)
+	return api
+}
+
+// resolve defaults the base URL to the server configured by cfg
+func (c *apiClient) resolve(cfg Config) {
+	if c.baseURL == "" {
+		scheme := "http"
+		if cfg.TLS.Enabled() {
+			scheme = "https"
+		}
+		c.baseURL = fmt.Sprintf("%s://%s:%s", scheme, cfg.Server.Host, cfg.Server.Port)
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+}
+
+// apiStatusError is the error of a response other than 2xx
+type apiStatusError struct {
+	method  string
+	path    string
+	status  string
+	code    int
+	message string
+}
+
+func (e *apiStatusError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.method, e.path, e.status, e.message)
+}
+
+// do sends a request to path with an optional JSON body and returns the
+// response, or an *apiStatusError for responses other than 2xx
+func (c *apiClient) do(ctx context.Context, method, path string, body interface{}, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenant != "" {
+		req.Header.Set(tenantHeader, c.tenant)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &apiStatusError{method: method, path: path, status: resp.Status, code: resp.StatusCode, message: strings.TrimSpace(string(message))}
+	}
+	return resp, nil
+}
+
+// runSeed loads the demo users through POST /admin/seed
+func runSeed(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := newCommandFlags("seed", "Load the demo users into a running server", stderr)
+	api := apiFlags(flags)
+	cfg, err := parseCommand(flags, args)
+	if err != nil {
+		return err
+	}
+	api.resolve(cfg)
+
+	resp, err := api.do(ctx, http.MethodPost, "/admin/seed", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(stdout, resp.Body)
+	return err
+}
+
+// tailQuery subscribes to user events with the fields printed by tail
+const tailQuery = `subscription($types: [String!]) {
+	userEvents(types: $types) { id type time user { id name email roles status version createdAt updatedAt } }
+}`
+
+// tailedEvent is a user event as printed by tail and read by replay
+type tailedEvent struct {
+	ID   string                 `json:"id"`
+	Type string                 `json:"type"`
+	Time time.Time              `json:"time"`
+	User map[string]interface{} `json:"user"`
+}
+
+// runTail prints the user events of a running server until ctx is done or
+// the server ends the stream
+func runTail(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := newCommandFlags("tail", "Print the user events of a running server as JSON lines", stderr)
+	api := apiFlags(flags)
+	types := flags.String("types", "", "comma-separated event types to print, e.g. user.created,user.deleted (default: all)")
+	cfg, err := parseCommand(flags, args)
+	if err != nil {
+		return err
+	}
+	api.resolve(cfg)
+
+	variables := map[string]interface{}{}
+	if *types != "" {
+		variables["types"] = strings.Split(*types, ",")
+	}
+	resp, err := api.do(ctx, http.MethodPost, "/graphql",
+		map[string]interface{}{"query": tailQuery, "variables": variables},
+		http.Header{"Accept": {"text/event-stream"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readSSE(resp.Body, func(event, data string) error {
+		if event != "next" {
+			return nil
+		}
+		var result struct {
+			Data struct {
+				UserEvents json.RawMessage `json:"userEvents"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return fmt.Errorf("invalid subscription result: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			return errors.New(result.Errors[0].Message)
+		}
+		_, err := fmt.Fprintf(stdout, "%s\n", result.Data.UserEvents)
+		return err
+	})
+	// Interrupting tail is its normal end
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// readSSE calls handle with the type and data of every event of a
+// server-sent event stream
+func readSSE(r io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 || event != "" {
+				if err := handle(event, strings.Join(data, "\n")); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
+
+// runReplay rebuilds the users from events read as JSON lines and prints
+// them as a JSON array sorted by ID
+func runReplay(_ context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := newCommandFlags("replay", "Rebuild the users from JSON lines of events, as printed by tail", stderr)
+	file := flags.String("file", "-", "file of events, - for standard input")
+	until := flags.String("until", "", "replay the events before this RFC 3339 time only (default: all)")
+	if _, err := parseCommand(flags, args); err != nil {
+		return err
+	}
+
+	var before time.Time
+	if *until != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339, *until); err != nil {
+			return &usageError{"--until must be an RFC 3339 timestamp"}
+		}
+	}
+
+	input := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	users, err := replayEvents(input, before)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(users)
+}
+
+// replayEvents applies the events read from r, up to before unless it is
+// zero, to an empty set of users. Each event carries a snapshot of its user,
+// which replaces the previous one; deleted users are removed.
+func replayEvents(r io.Reader, before time.Time) ([]map[string]interface{}, error) {
+	users := make(map[string]map[string]interface{})
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var event tailedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: invalid event: %w", line, err)
+		}
+		id, _ := event.User["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("line %d: event %s has no user ID", line, event.ID)
+		}
+		if !before.IsZero() && !event.Time.Before(before) {
+			continue
+		}
+		if event.Type == EventTypeUserDeleted {
+			delete(users, id)
+			continue
+		}
+		users[id] = event.User
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(users))
+	for id := range users {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	replayed := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		replayed[i] = users[id]
+	}
+	return replayed, nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCLI_Usage(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"unknown command", []string{"frobnicate"}, 2, `Unknown command "frobnicate"`},
+		{"help", []string{"migrate", "--help"}, 0, "Usage: user-service migrate"},
+		{"unknown flag", []string{"migrate", "--nope"}, 2, "flag provided but not defined"},
+		{"extra arguments", []string{"migrate", "now"}, 2, `Unexpected arguments "now"`},
+		{"invalid config", []string{"dump-config", "--server.max_body_bytes", "lots"}, 2, "--server.max_body_bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runCLI(context.Background(), tt.args, &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("exit code got %d want %d (stderr %q)", code, tt.code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr got %q want it to contain %q", stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRunCLI_DumpConfigAndMigrate(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(context.Background(), []string{"dump-config", "--server.port", "9000"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("dump-config exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `port: "9000"`) {
+		t.Errorf("dump-config got %q want the port set by its flag", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := runCLI(context.Background(), []string{"migrate"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("migrate exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "No migrations to apply") {
+		t.Errorf("migrate got %q want no migrations", stdout.String())
+	}
+}
+
+func TestRunCLI_ExampleConfig(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(context.Background(), []string{"example-config"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	for _, want := range []string{
+		"# Example configuration of user-service",
+		"  port: \"8080\" # string, env PORT\n",
+		"  hs256_secret: \"\" # string, secret, env JWT_HS256_SECRET\n",
+		"  request_timeout: 10s # duration, env REQUEST_TIMEOUT\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("example-config got\n%s\nwant it to contain %q", stdout.String(), want)
+		}
+	}
+
+	// The example is a valid config file of the defaults
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, stdout.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _, err := parseConfig(nil, []string{"--config", path})
+	if err != nil {
+		t.Fatalf("parseConfig() of the example error: %v", err)
+	}
+	defaults, _, err := parseConfig(nil, nil)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	loaded.File = ""
+	if !reflect.DeepEqual(loaded, defaults) {
+		t.Errorf("parseConfig() of the example got %+v want the defaults %+v", loaded, defaults)
+	}
+}
+
+func TestRunCLI_Seed(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{"seeded":3}`))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"seed", "--url", server.URL, "--token", "secret", "--tenant", "acme"}
+	if code := runCLI(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/admin/seed" {
+		t.Errorf("request got %s %s want POST /admin/seed", got.Method, got.URL.Path)
+	}
+	if got.Header.Get("Authorization") != "Bearer secret" || got.Header.Get(tenantHeader) != "acme" {
+		t.Errorf("headers got %v want the token and tenant", got.Header)
+	}
+	if stdout.String() != `{"seeded":3}` {
+		t.Errorf("stdout got %q want the response", stdout.String())
+	}
+
+	// Failed requests fail the command
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	stderr.Reset()
+	if code := runCLI(context.Background(), []string{"seed", "--url", failing.URL}, io.Discard, &stderr); code != 1 {
+		t.Errorf("exit code got %d want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "403 Forbidden") {
+		t.Errorf("stderr got %q want the status", stderr.String())
+	}
+}
+
+func TestRunCLI_Tail(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	var query struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "not a subscription", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&query)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, id := range []string{"e1", "e2"} {
+			fmt.Fprintf(w, "event: next\ndata: {\"data\":{\"userEvents\":{\"id\":%q,\"type\":\"user.created\"}}}\n\n", id)
+		}
+		fmt.Fprint(w, "event: complete\ndata:\n\n")
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"tail", "--url", server.URL, "--types", "user.created,user.deleted"}
+	if code := runCLI(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	want := `{"id":"e1","type":"user.created"}` + "\n" + `{"id":"e2","type":"user.created"}` + "\n"
+	if stdout.String() != want {
+		t.Errorf("stdout got %q want %q", stdout.String(), want)
+	}
+	if !strings.Contains(query.Query, "userEvents") || fmt.Sprint(query.Variables["types"]) != "[user.created user.deleted]" {
+		t.Errorf("query got %+v want a userEvents subscription filtered by the types", query)
+	}
+}
+
+func TestRunCLI_Replay(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	events := strings.Join([]string{
+		`{"id":"e1","type":"user.created","time":"2026-01-01T10:00:00Z","user":{"id":"u2","name":"Bob","version":1}}`,
+		`{"id":"e2","type":"user.created","time":"2026-01-01T10:01:00Z","user":{"id":"u1","name":"Alice","version":1}}`,
+		`{"id":"e3","type":"user.updated","time":"2026-01-01T10:02:00Z","user":{"id":"u1","name":"Alicia","version":2}}`,
+		`{"id":"e4","type":"user.deleted","time":"2026-01-01T10:03:00Z","user":{"id":"u2","name":"Bob","version":1}}`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte(events), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		names []string
+	}{
+		{"all events", []string{"replay", "--file", path}, []string{"Alicia"}},
+		{"until", []string{"replay", "--file", path, "--until", "2026-01-01T10:02:00Z"}, []string{"Alice", "Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCLI(context.Background(), tt.args, &stdout, &stderr); code != 0 {
+				t.Fatalf("exit code got %d want 0 (stderr %q)", code, stderr.String())
+			}
+			var users []map[string]interface{}
+			if err := json.Unmarshal(stdout.Bytes(), &users); err != nil {
+				t.Fatalf("invalid output %q: %v", stdout.String(), err)
+			}
+			var names []string
+			for _, user := range users {
+				names = append(names, user["name"].(string))
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.names) {
+				t.Errorf("users got %v want %v", names, tt.names)
+			}
+		})
+	}
+
+	// Events without a user cannot be replayed
+	if _, err := replayEvents(strings.NewReader(`{"id":"e1","type":"user.created"}`), time.Time{}); err == nil {
+		t.Error("replayEvents() should fail on an event without a user")
+	}
+}
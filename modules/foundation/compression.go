@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// defaultCompressionMinSize is the smallest response body worth compressing;
+// below it the gzip framing overhead outweighs the savings.
+const defaultCompressionMinSize = 1024
+
+// compressor creates a compressing writer for one content coding
+type compressor interface {
+	// Encoding returns the Content-Encoding token, e.g. "gzip"
+	Encoding() string
+	// NewWriter wraps w; the returned writer must be closed to flush
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// gzipCompressor implements compressor with a pool of gzip writers
+type gzipCompressor struct {
+	pool sync.Pool
+}
+
+// Encoding returns the gzip content coding
+func (c *gzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+// NewWriter returns a pooled gzip writer that returns itself to the pool on Close
+func (c *gzipCompressor) NewWriter(w io.Writer) io.WriteCloser {
+	gz, ok := c.pool.Get().(*gzip.Writer)
+	if !ok {
+		gz = gzip.NewWriter(w)
+	} else {
+		gz.Reset(w)
+	}
+	return &pooledGzipWriter{Writer: gz, pool: &c.pool}
+}
+
+// pooledGzipWriter puts the gzip writer back into the pool once closed
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+// Close flushes the gzip stream and releases the writer
+func (w *pooledGzipWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	return err
+}
+
+// incompressibleTypes lists content types that are already compressed or streamed
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/octet-stream",
+	// Server-sent events must reach the client unbuffered
+	"text/event-stream",
+}
+
+// isCompressible reports whether a response of the given content type benefits from compression
+func isCompressible(contentType string) bool {
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiateEncoding picks the first supported compressor the client accepts.
+// Codings with q=0 are explicitly refused; "*" accepts any coding.
+func negotiateEncoding(acceptEncoding string, compressors []compressor) compressor {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q > 0
+	}
+
+	for _, c := range compressors {
+		if ok, listed := accepted[c.Encoding()]; listed {
+			if ok {
+				return c
+			}
+			continue
+		}
+		if accepted["*"] {
+			return c
+		}
+	}
+	return nil
+}
+
+// compressionMiddleware compresses responses for clients that accept it.
+// Compressors are tried in order of preference, so a brotli implementation
+// can be registered ahead of gzip without changing the middleware.
+func compressionMiddleware(next http.Handler, minSize int, compressors ...compressor) http.Handler {
+	if len(compressors) == 0 {
+		compressors = []compressor{&gzipCompressor{}}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Caches must tell compressed and uncompressed responses apart,
+		// including the uncompressed ones served to clients without gzip
+		w.Header().Add("Vary", "Accept-Encoding")
+		c := negotiateEncoding(r.Header.Get("Accept-Encoding"), compressors)
+		if c == nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			compressor:     c,
+			minSize:        minSize,
+			statusCode:     http.StatusOK,
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				// Leave an undecided response unwritten, so the recovery
+				// middleware can still answer 500
+				cw.release()
+				panic(p)
+			}
+			if err := cw.Close(); err != nil {
+				slog.ErrorContext(r.Context(), "Error closing compressed response", "error", err)
+			}
+		}()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body is large enough and of a type worth compressing
+type compressWriter struct {
+	http.ResponseWriter
+	compressor compressor
+	minSize    int
+
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	writer      io.WriteCloser
+}
+
+// WriteHeader records the status code; it is sent once the encoding is decided
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.statusCode = code
+
+	if !bodyAllowed(code) {
+		cw.decide(false)
+	}
+}
+
+// Write buffers data until minSize is reached, then streams it
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	cw.wroteHeader = true
+	if cw.decided {
+		if cw.writer != nil {
+			return cw.writer.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered data so streaming handlers keep working
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(len(cw.buf) >= cw.minSize)
+	}
+	if gz, ok := cw.writer.(interface{ Flush() error }); ok {
+		gz.Flush()
+	}
+	// The writers below may only expose Unwrap, which the controller follows
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack lets protocol upgrades bypass compression
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close decides the encoding for small bodies and flushes the compressor
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.writer != nil {
+		return cw.writer.Close()
+	}
+	return nil
+}
+
+// release drops the buffered body of a response abandoned by a panic and
+// returns the compressor, without deciding the encoding
+func (cw *compressWriter) release() {
+	cw.buf = nil
+	if cw.writer != nil {
+		cw.writer.Close()
+	}
+}
+
+// decide sends the headers and the buffered body, compressed when requested
+// and the response is eligible
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	header := cw.Header()
+	compress = compress &&
+		bodyAllowed(cw.statusCode) &&
+		header.Get("Content-Encoding") == "" &&
+		isCompressible(header.Get("Content-Type"))
+
+	if compress {
+		header.Set("Content-Encoding", cw.compressor.Encoding())
+		header.Del("Content-Length")
+	}
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	if compress {
+		cw.writer = cw.compressor.NewWriter(cw.ResponseWriter)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.writer != nil {
+		_, err := cw.writer.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// bodyAllowed reports whether a response with the status code may carry a body
+func bodyAllowed(code int) bool {
+	return code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	compressors := []compressor{&gzipCompressor{}}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{"no header", "", ""},
+		{"gzip", "gzip, deflate", "gzip"},
+		{"gzip refused", "gzip;q=0, deflate", ""},
+		{"wildcard", "*", "gzip"},
+		{"wildcard with gzip refused", "*, gzip;q=0", ""},
+		{"unsupported only", "br", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if c := negotiateEncoding(tt.acceptEncoding, compressors); c != nil {
+				got = c.Encoding()
+			}
+			if got != tt.want {
+				t.Errorf("negotiateEncoding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat("event-driven ", 200)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{"large JSON is compressed", "gzip", "application/json", large, "gzip"},
+		{"small body is not compressed", "gzip", "application/json", `{"ok":true}`, ""},
+		{"client without gzip", "", "application/json", large, ""},
+		{"already compressed type", "gzip", "image/png", large, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := compressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusOK)
+				io.WriteString(w, tt.body)
+			}), defaultCompressionMinSize)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rr.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+
+			body := rr.Body.Bytes()
+			if tt.wantEncoding == "gzip" {
+				gz, err := gzip.NewReader(rr.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				if body, err = io.ReadAll(gz); err != nil {
+					t.Fatalf("reading gzip body: %v", err)
+				}
+			}
+			if string(body) != tt.body {
+				t.Errorf("body mismatch after decoding: got %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware_FlushThroughWrappers(t *testing.T) {
+	// Middlewares outside compression wrap the writer without implementing
+	// http.Flusher themselves, like recoveryMiddleware
+	handler := recoveryMiddleware(nil, compressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+	}), defaultCompressionMinSize))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("flushed headers did not reach the client: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+)
+
+// Config is the configuration of the service. Each setting has a default,
+// can be set in the YAML or JSON file named by --config or CONFIG_FILE, and
+// is overridden by its environment variable, or its entry in the .env file
+// named by --env-file or ENV_FILE, and then by its flag, named by its file
+// key like --server.port.
+type Config struct {
+	// File is the config file the configuration was loaded from, if any
+	File string `yaml:"-"`
+
+	Server  ServerSettings  `yaml:"server"`
+	TLS     TLSConfig       `yaml:"tls"`
+	Log     LogSettings     `yaml:"log"`
+	Tracing TracingSettings `yaml:"tracing"`
+	SLO     SLOSettings     `yaml:"slo"`
+	Sentry  SentrySettings  `yaml:"sentry"`
+	IDs     IDSettings      `yaml:"ids"`
+	Users   UserSettings    `yaml:"users"`
+	Auth    AuthSettings    `yaml:"auth"`
+	OIDC    OIDCSettings    `yaml:"oidc"`
+	Session SessionSettings `yaml:"session"`
+	Secrets SecretsSettings `yaml:"secrets"`
+	Avatars AvatarSettings  `yaml:"avatars"`
+	Chaos   ChaosSettings   `yaml:"chaos"`
+}
+
+// ServerSettings configures the HTTP and gRPC servers and request handling
+type ServerSettings struct {
+	Host               string        `yaml:"host" env:"HOST" default:"localhost"`
+	Port               string        `yaml:"port" env:"PORT" default:"8080"`
+	GRPCPort           string        `yaml:"grpc_port" env:"GRPC_PORT" default:"9090"`
+	H2C                bool          `yaml:"h2c" env:"H2C_ENABLED"`
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY" default:"0s"`
+	TenantDomain       string        `yaml:"tenant_domain" env:"TENANT_DOMAIN"`
+	Tenants            []string      `yaml:"tenants" env:"TENANTS"`
+	// RequestTimeout stays below the server's WriteTimeout so the 504 can still be written
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT" default:"10s"`
+	RouteTimeouts  []string      `yaml:"route_timeouts" env:"ROUTE_TIMEOUTS"`
+	MaxBodyBytes   int64         `yaml:"max_body_bytes" env:"MAX_BODY_BYTES" default:"1048576"`
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"IDEMPOTENCY_TTL" default:"24h"`
+	ErrorFormat    string        `yaml:"error_format" env:"ERROR_FORMAT" default:"problem"`
+	DebugEndpoints bool          `yaml:"debug_endpoints" env:"DEBUG_ENDPOINTS"`
+}
+
+// LogSettings configures the application, access and canonical logs
+type LogSettings struct {
+	Format       string `yaml:"format" env:"LOG_FORMAT" default:"text"`
+	Level        string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	AccessFormat string `yaml:"access_format" env:"ACCESS_LOG_FORMAT" default:"text"`
+	Canonical    bool   `yaml:"canonical" env:"CANONICAL_LOG" default:"true"`
+}
+
+// TracingSettings configures the export of OpenTelemetry traces
+type TracingSettings struct {
+	Endpoint    string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" default:"user-service"`
+	SampleRate  string `yaml:"sample_rate" env:"TRACE_SAMPLE_RATE" default:"1"`
+	Sampling    string `yaml:"sampling" env:"TRACE_SAMPLING" default:"head"`
+}
+
+// SLOSettings configures the latency SLO of endpoints
+type SLOSettings struct {
+	Target       string   `yaml:"target" env:"SLO_TARGET" default:"500ms@99"`
+	RouteTargets []string `yaml:"route_targets" env:"ROUTE_SLO_TARGETS"`
+}
+
+// SentrySettings configures error reporting
+type SentrySettings struct {
+	DSN         string `yaml:"dsn" env:"SENTRY_DSN" secret:"true"`
+	Environment string `yaml:"environment" env:"SENTRY_ENVIRONMENT"`
+}
+
+// IDSettings configures the ID scheme of users and events
+type IDSettings struct {
+	Scheme        string   `yaml:"scheme" env:"ID_SCHEME" default:"uuidv4"`
+	EntitySchemes []string `yaml:"entity_schemes" env:"ENTITY_ID_SCHEMES"`
+	SnowflakeNode int64    `yaml:"snowflake_node" env:"SNOWFLAKE_NODE" default:"0"`
+	NanoIDLength  int      `yaml:"nanoid_length" env:"NANOID_LENGTH" default:"21"`
+	UserPrefix    string   `yaml:"user_prefix" env:"USER_ID_PREFIX"`
+}
+
+// UserSettings configures the validation of users and the demo users seeded
+type UserSettings struct {
+	EmailValidation string `yaml:"email_validation" env:"EMAIL_VALIDATION" default:"standard"`
+	Fixtures        string `yaml:"fixtures" env:"FIXTURES" default:"default"`
+	FixturesDir     string `yaml:"fixtures_dir" env:"FIXTURES_DIR"`
+}
+
+// AuthSettings configures JWT authentication and password logins
+type AuthSettings struct {
+	HS256Secret        string        `yaml:"hs256_secret" env:"JWT_HS256_SECRET" secret:"true"`
+	RS256PublicKeyFile string        `yaml:"rs256_public_key_file" env:"JWT_RS256_PUBLIC_KEY_FILE"`
+	Issuer             string        `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience           string        `yaml:"audience" env:"JWT_AUDIENCE"`
+	ClockSkew          time.Duration `yaml:"clock_skew" env:"JWT_CLOCK_SKEW" default:"30s"`
+	LoginTokenTTL      time.Duration `yaml:"login_token_ttl" env:"LOGIN_TOKEN_TTL" default:"1h"`
+}
+
+// OIDCSettings configures sign-in with an OpenID Connect provider
+type OIDCSettings struct {
+	IssuerURL    string        `yaml:"issuer_url" env:"OIDC_ISSUER_URL"`
+	ClientID     string        `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string        `yaml:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string        `yaml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	Scopes       string        `yaml:"scopes" env:"OIDC_SCOPES" default:"openid email profile"`
+	TokenTTL     time.Duration `yaml:"token_ttl" env:"OIDC_TOKEN_TTL" default:"1h"`
+}
+
+// SessionSettings configures browser sessions
+type SessionSettings struct {
+	Store    string        `yaml:"store" env:"SESSION_STORE" default:"memory"`
+	RedisURL string        `yaml:"redis_url" env:"REDIS_URL" default:"redis://localhost:6379/0"`
+	TTL      time.Duration `yaml:"ttl" env:"SESSION_TTL" default:"24h"`
+}
+
+// AvatarSettings configures the storage of user avatars
+type AvatarSettings struct {
+	Store string `yaml:"store" env:"AVATAR_STORE" default:"local"`
+	Dir   string `yaml:"dir" env:"AVATAR_DIR" default:"avatars"`
+	// MaxBytes stays below server.max_body_bytes, which bounds the whole upload
+	MaxBytes          int64  `yaml:"max_bytes" env:"AVATAR_MAX_BYTES" default:"524288"`
+	PublicURL         string `yaml:"public_url" env:"AVATAR_PUBLIC_URL"`
+	S3Bucket          string `yaml:"s3_bucket" env:"AVATAR_S3_BUCKET"`
+	S3Region          string `yaml:"s3_region" env:"AVATAR_S3_REGION"`
+	S3Endpoint        string `yaml:"s3_endpoint" env:"AVATAR_S3_ENDPOINT"`
+	S3AccessKeyID     string `yaml:"s3_access_key_id" env:"AWS_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `yaml:"s3_secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+}
+
+// ChaosSettings configures the faults injected into requests for
+// resilience demos. Rates are probabilities between 0 and 1.
+type ChaosSettings struct {
+	Enabled        bool          `yaml:"enabled" env:"CHAOS_ENABLED"`
+	Latency        time.Duration `yaml:"latency" env:"CHAOS_LATENCY" default:"0s"`
+	LatencyRate    float64       `yaml:"latency_rate" env:"CHAOS_LATENCY_RATE"`
+	ErrorRate      float64       `yaml:"error_rate" env:"CHAOS_ERROR_RATE"`
+	ErrorStatus    int           `yaml:"error_status" env:"CHAOS_ERROR_STATUS" default:"503"`
+	PartialRate    float64       `yaml:"partial_rate" env:"CHAOS_PARTIAL_RATE"`
+	HeaderTriggers bool          `yaml:"header_triggers" env:"CHAOS_HEADER_TRIGGERS"`
+}
+
+// errInvalidFlags marks command lines rejected by the flag set, which has
+// already reported them along with its usage
+var errInvalidFlags = errors.New("invalid flags")
+
+// SecretsSettings configures where the secret settings, tagged secret:"true",
+// are fetched from at startup
+type SecretsSettings struct {
+	Provider     string `yaml:"provider" env:"SECRETS_PROVIDER" default:"env"`
+	VaultAddress string `yaml:"vault_address" env:"VAULT_ADDR" default:"http://127.0.0.1:8200"`
+	VaultToken   string `yaml:"vault_token" env:"VAULT_TOKEN" secret:"true"`
+	VaultPath    string `yaml:"vault_path" env:"VAULT_SECRET_PATH" default:"secret/data/user-service"`
+}
+
+// secretsProvider returns the provider of the secret settings, or nil when
+// they are read from the environment like every other setting
+func secretsProvider(settings SecretsSettings) config.SecretsProvider {
+	if settings.Provider == "vault" {
+		return config.NewVaultProvider(settings.VaultAddress, settings.VaultToken, settings.VaultPath)
+	}
+	return nil
+}
+
+// parseConfig loads the configuration from defaults, the config file, the
+// environment, with the .env file if any, the secrets provider and the flags
+// in args, in increasing precedence, and validates it. The config
+// flags are added to flags, which may define flags of a command, or to a new
+// set when flags is nil. It returns the arguments left after the flags.
+func parseConfig(flags *flag.FlagSet, args []string) (Config, []string, error) {
+	var cfg Config
+	if flags == nil {
+		flags = flag.NewFlagSet("user-service", flag.ContinueOnError)
+	}
+	path := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON configuration file, overriding $CONFIG_FILE")
+	envFile := flags.String("env-file", os.Getenv("ENV_FILE"), ".env file of the environment variables not set, overriding $ENV_FILE")
+	if err := config.DefineFlags(flags, &cfg); err != nil {
+		return Config{}, nil, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, fmt.Errorf("%w: %w", errInvalidFlags, err)
+	}
+
+	opts := []config.Option{config.WithFlags(flags)}
+	if *envFile != "" {
+		opts = append(opts, config.WithDotEnv(*envFile))
+	}
+	err := config.Load(*path, &cfg, opts...)
+	// The settings of the provider come from the other layers, so the
+	// secrets are fetched by loading them again
+	if provider := secretsProvider(cfg.Secrets); err == nil && provider != nil && len(cfg.Secrets.problems()) == 0 {
+		cfg = Config{}
+		err = config.Load(*path, &cfg, append(opts, config.WithSecrets(context.Background(), provider))...)
+	}
+	if err == nil {
+		err = cfg.Validate()
+	}
+	cfg.File = *path
+	return cfg, flags.Args(), err
+}
+
+// dumpConfig writes the effective configuration to w as YAML, with secrets
+// redacted, in the format of the config file
+func dumpConfig(w io.Writer, cfg Config) error {
+	data, err := config.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// newLogger creates the application logger writing to stderr in the format
+// and level of settings. The level can be changed at runtime through the
+// returned LevelVar.
+func newLogger(settings LogSettings) (*slog.Logger, *slog.LevelVar, error) {
+	level, err := logging.ParseLevel(settings.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+	logger, err := logging.New(os.Stderr, logging.Format(strings.ToLower(settings.Format)), levelVar)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logger, levelVar, nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	cfg, _, err := parseConfig(nil, nil)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.Server.Host != "localhost" || cfg.Server.Port != "8080" || cfg.Server.GRPCPort != "9090" {
+		t.Errorf("Server got %+v want localhost:8080 and gRPC port 9090", cfg.Server)
+	}
+	if cfg.Server.RequestTimeout != 10*time.Second || cfg.Server.MaxBodyBytes != 1<<20 || cfg.Server.IdempotencyTTL != 24*time.Hour {
+		t.Errorf("Server got %+v want the default limits", cfg.Server)
+	}
+	if !cfg.Log.Canonical || cfg.Server.DebugEndpoints || cfg.Server.ErrorFormat != "problem" {
+		t.Errorf("got canonical log %v, debug endpoints %v and error format %q want true, false and problem",
+			cfg.Log.Canonical, cfg.Server.DebugEndpoints, cfg.Server.ErrorFormat)
+	}
+	if cfg.Auth.ClockSkew != defaultJWTClockSkew || cfg.Auth.LoginTokenTTL != time.Hour || cfg.Session.TTL != 24*time.Hour {
+		t.Errorf("got clock skew %v, login token TTL %v and session TTL %v want 30s, 1h and 24h",
+			cfg.Auth.ClockSkew, cfg.Auth.LoginTokenTTL, cfg.Session.TTL)
+	}
+
+	// The defaults are a working configuration
+	if _, err := loadIDGenerators(cfg.IDs); err != nil {
+		t.Errorf("loadIDGenerators() error: %v", err)
+	}
+	if _, err := loadTracingConfig(cfg.Tracing); err != nil {
+		t.Errorf("loadTracingConfig() error: %v", err)
+	}
+	if _, err := loadSLOTracker(cfg.SLO); err != nil {
+		t.Errorf("loadSLOTracker() error: %v", err)
+	}
+	if _, err := loadOIDCConfig(cfg.OIDC); err != nil {
+		t.Errorf("loadOIDCConfig() error: %v", err)
+	}
+	if _, err := loadSessionStore(cfg.Session); err != nil {
+		t.Errorf("loadSessionStore() error: %v", err)
+	}
+	if _, _, err := newLogger(cfg.Log); err != nil {
+		t.Errorf("newLogger() error: %v", err)
+	}
+}
+
+func TestLoadConfig_FileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+server:
+  port: "9000"
+  request_timeout: 5s
+  route_timeouts:
+    - /users=2s
+    - /graphql=30s
+log:
+  format: json
+  canonical: false
+ids:
+  entity_schemes: [user=ulid]
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "9100")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, _, err := parseConfig(nil, nil)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.Server.Port != "9100" {
+		t.Errorf("Port got %v want the environment's 9100", cfg.Server.Port)
+	}
+	if cfg.Server.RequestTimeout != 5*time.Second || !reflect.DeepEqual(cfg.Server.RouteTimeouts, []string{"/users=2s", "/graphql=30s"}) {
+		t.Errorf("got timeouts %v and %v want those of the file", cfg.Server.RequestTimeout, cfg.Server.RouteTimeouts)
+	}
+	if cfg.Log.Format != "json" || cfg.Log.Level != "debug" || cfg.Log.Canonical {
+		t.Errorf("Log got %+v want json and debug without canonical log", cfg.Log)
+	}
+	if !reflect.DeepEqual(cfg.IDs.EntitySchemes, []string{"user=ulid"}) || cfg.IDs.Scheme != "uuidv4" {
+		t.Errorf("IDs got %+v want the file's entity schemes over the default scheme", cfg.IDs)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"debug endpoints", map[string]string{"DEBUG_ENDPOINTS": "sometimes"}},
+		{"canonical log", map[string]string{"CANONICAL_LOG": "sometimes"}},
+		{"drain delay", map[string]string{"SHUTDOWN_DRAIN_DELAY": "soon"}},
+		{"body size", map[string]string{"MAX_BODY_BYTES": "1MB"}},
+		{"snowflake node", map[string]string{"SNOWFLAKE_NODE": "first"}},
+		{"missing file", map[string]string{"CONFIG_FILE": filepath.Join(os.TempDir(), "missing", "config.yaml")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if _, _, err := parseConfig(nil, nil); err == nil {
+				t.Errorf("parseConfig() with %v got no error", tt.env)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Flags(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env.yaml")
+	flagFile := filepath.Join(dir, "flag.json")
+	if err := os.WriteFile(envFile, []byte("server:\n  port: \"9000\"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if err := os.WriteFile(flagFile, []byte(`{"server": {"port": "9001", "host": "0.0.0.0"}, "log": {"level": "warn"}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", envFile)
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("DEBUG_ENDPOINTS", "")
+
+	cfg, args, err := parseConfig(nil, []string{"--config", flagFile, "--log.level=error", "--server.debug_endpoints", "dump-config"})
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.Server.Port != "9001" || cfg.Server.Host != "0.0.0.0" {
+		t.Errorf("got %s:%s want 0.0.0.0:9001 from the file named by --config", cfg.Server.Host, cfg.Server.Port)
+	}
+	if cfg.Log.Level != "error" {
+		t.Errorf("Level got %v want the flag's error over the environment", cfg.Log.Level)
+	}
+	if !cfg.Server.DebugEndpoints {
+		t.Errorf("DebugEndpoints got false want true from the bare flag")
+	}
+	if !reflect.DeepEqual(args, []string{"dump-config"}) {
+		t.Errorf("args got %v want [dump-config]", args)
+	}
+
+	if _, _, err := parseConfig(nil, []string{"--server.max_body_bytes=1MB"}); err == nil {
+		t.Errorf("parseConfig() with an invalid flag value got no error")
+	}
+}
+
+func TestParseConfig_EnvFile(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("PORT=9000\nLOG_LEVEL=debug\nJWT_HS256_SECRET='dev secret'\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ENV_FILE", envFile)
+	t.Setenv("PORT", "")
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("JWT_HS256_SECRET", "")
+
+	cfg, _, err := parseConfig(nil, []string{"--log.format=json"})
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.Server.Port != "9000" || cfg.Auth.HS256Secret != "dev secret" {
+		t.Errorf("got port %q and secret %q want 9000 and dev secret from the .env file", cfg.Server.Port, cfg.Auth.HS256Secret)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("Level got %v want the environment's warn over the .env file", cfg.Log.Level)
+	}
+
+	// The file is opt-in, so a missing one is an error rather than ignored
+	if _, _, err := parseConfig(nil, []string{"--env-file", filepath.Join(t.TempDir(), ".env")}); err == nil {
+		t.Errorf("parseConfig() with a missing .env file got no error")
+	}
+}
+
+func TestParseConfig_VaultSecrets(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/user-service" || r.Header.Get("X-Vault-Token") != "dev-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"JWT_HS256_SECRET": "from-vault", "OIDC_CLIENT_SECRET": "oidc-secret"}, "metadata": {}}}`))
+	}))
+	defer vault.Close()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SECRETS_PROVIDER", "vault")
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "dev-token")
+	t.Setenv("JWT_HS256_SECRET", "from-env")
+
+	cfg, _, err := parseConfig(nil, []string{"--server.port=9001"})
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	if cfg.Auth.HS256Secret != "from-vault" || cfg.OIDC.ClientSecret != "oidc-secret" {
+		t.Errorf("got secrets %q and %q want them from Vault", cfg.Auth.HS256Secret, cfg.OIDC.ClientSecret)
+	}
+	if cfg.Server.Port != "9001" || cfg.Secrets.VaultToken != "dev-token" {
+		t.Errorf("got port %q and Vault token %q want the other layers kept", cfg.Server.Port, cfg.Secrets.VaultToken)
+	}
+
+	t.Setenv("VAULT_TOKEN", "expired")
+	if _, _, err := parseConfig(nil, nil); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("parseConfig() error got %v want Vault's error", err)
+	}
+	t.Setenv("SECRETS_PROVIDER", "keychain")
+	if _, _, err := parseConfig(nil, nil); err == nil {
+		t.Errorf("parseConfig() with an unknown secrets provider got no error")
+	}
+}
+
+func TestDumpConfig(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("JWT_HS256_SECRET", "s3cr3t")
+
+	cfg, _, err := parseConfig(nil, []string{"--server.port=9001"})
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := dumpConfig(&buf, cfg); err != nil {
+		t.Fatalf("dumpConfig() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"server:\n  host: localhost\n  port: \"9001\"\n", "request_timeout: 10s", "hs256_secret: <redacted>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dumpConfig() got\n%s\nwant it to contain %q", out, want)
+		}
+	}
+	if strings.Contains(out, "s3cr3t") {
+		t.Errorf("dumpConfig() leaks the JWT secret")
+	}
+
+	// The dump is a config file reproducing the same configuration
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(strings.Replace(out, "<redacted>", "s3cr3t", 1)), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	t.Setenv("JWT_HS256_SECRET", "")
+	reloaded, _, err := parseConfig(nil, []string{"--config", path})
+	if err != nil {
+		t.Fatalf("parseConfig() of the dump error: %v", err)
+	}
+	if reloaded.File != path {
+		t.Errorf("File got %q want %q", reloaded.File, path)
+	}
+	reloaded.File = cfg.File
+	if !reflect.DeepEqual(reloaded, cfg) {
+		t.Errorf("parseConfig() of the dump got %+v want %+v", reloaded, cfg)
+	}
+}
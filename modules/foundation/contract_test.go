@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/contract"
+	"github.com/captain-corgi/learning-event-driven/pkg/events/eventstest"
+)
+
+// TestConsumerContracts checks the user events, as served on GET /events,
+// against the fields the other modules declare they read from them
+func TestConsumerContracts(t *testing.T) {
+	contracts, err := contract.LoadFor("..", "foundation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) == 0 {
+		t.Fatal("got no contracts want the ones of the consumers of user events")
+	}
+
+	recorder := eventstest.NewRecorder()
+	bus := NewEventBus()
+	bus.Subscribe(recorder.Handle)
+	service := NewInMemoryUserService(WithEventPublisher(bus), WithFixtures(nil))
+
+	user, err := service.RegisterUser("Ada Lovelace", "ada@example.com", "correct horse battery")
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	steps := []func(version int64) (*User, error){
+		func(version int64) (*User, error) {
+			return service.UpdateUser(user.ID, "Ada King", user.Email, version)
+		},
+		func(version int64) (*User, error) {
+			return service.AssignRoles(user.ID, []Role{RoleAdmin}, version)
+		},
+		func(version int64) (*User, error) {
+			return service.ChangeStatus(user.ID, UserStatusActive, version)
+		},
+		func(version int64) (*User, error) {
+			return service.ChangeStatus(user.ID, UserStatusSuspended, version)
+		},
+		func(version int64) (*User, error) {
+			return service.ChangePassword(user.ID, "correct horse battery", "battery staple horse", version)
+		},
+	}
+	for i, step := range steps {
+		if user, err = step(user.Version); err != nil {
+			t.Fatalf("step %d error = %v", i, err)
+		}
+	}
+	if err := service.DeleteUser(user.ID, user.Version); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	contract.Verify(t, contracts, recorder.Events())
+}
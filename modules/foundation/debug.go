@@ -0,0 +1,28 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+func init() {
+	// Runtime figures next to the memstats and cmdline published by expvar itself
+	expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+	expvar.Publish("gomaxprocs", expvar.Func(func() interface{} { return runtime.GOMAXPROCS(0) }))
+}
+
+// NewDebugHandler serves the net/http/pprof profiles under /debug/pprof/ and
+// the expvar variables under /debug/vars. It is not registered on
+// http.DefaultServeMux, so importing pprof does not expose anything by itself.
+func NewDebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDebugHandler(t *testing.T) {
+	handler := NewDebugHandler()
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/debug/pprof/", http.StatusOK},
+		{"/debug/pprof/goroutine?debug=1", http.StatusOK},
+		{"/debug/pprof/cmdline", http.StatusOK},
+		{"/debug/vars", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status code got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var vars map[string]interface{}
+	if err := json.NewDecoder(rr.Body).Decode(&vars); err != nil {
+		t.Fatalf("invalid /debug/vars body: %v", err)
+	}
+	for _, name := range []string{"goroutines", "gomaxprocs", "memstats", "http_panics_total"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("/debug/vars is missing %q", name)
+		}
+	}
+}
+
+func TestDebugHandler_RequiresPermission(t *testing.T) {
+	secret := []byte("test-secret")
+	service := NewInMemoryUserService()
+	validator := NewJWTValidator(JWTConfig{HMACSecret: secret})
+	handler := authenticate(validator, func(*http.Request) bool { return true },
+		NewAuthorizer(SingleService(service)).Middleware(
+			func(*http.Request) Permission { return PermissionDebugRead },
+			NewDebugHandler(),
+		))
+
+	expires := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"viewer", signToken(t, "HS256", secret, map[string]interface{}{"sub": "viewer", "exp": expires, "roles": []string{"viewer"}}), http.StatusForbidden},
+		{"admin", signToken(t, "HS256", secret, map[string]interface{}{"sub": "admin", "exp": expires, "roles": []string{"admin"}}), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status code got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
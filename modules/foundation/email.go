@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// EmailStrictness is how strictly the email addresses of users are validated
+type EmailStrictness string
+
+const (
+	// EmailRFC accepts any address of RFC 5322 without display name, such
+	// as user@localhost or "john doe"@example.com
+	EmailRFC EmailStrictness = "rfc"
+	// EmailStandard also requires a domain name with a top-level domain,
+	// such as example.com, rejecting single labels and IP literals
+	EmailStandard EmailStrictness = "standard"
+	// EmailStrict also restricts the local part to letters, digits and
+	// . _ % + -, the addresses most mail providers hand out
+	EmailStrict EmailStrictness = "strict"
+)
+
+// defaultEmailStrictness validates emails unless configured otherwise
+const defaultEmailStrictness = EmailStandard
+
+// Length limits of RFC 5321
+const (
+	maxEmailLength       = 254
+	maxLocalPartLength   = 64
+	maxDomainLength      = 253
+	maxDomainLabelLength = 63
+)
+
+// loadEmailStrictness returns the strictness of settings (EMAIL_VALIDATION)
+func loadEmailStrictness(settings UserSettings) (EmailStrictness, error) {
+	switch strictness := EmailStrictness(settings.EmailValidation); strictness {
+	case EmailRFC, EmailStandard, EmailStrict:
+		return strictness, nil
+	default:
+		return "", fmt.Errorf("EMAIL_VALIDATION must be one of rfc, standard or strict, got %q", settings.EmailValidation)
+	}
+}
+
+// isValidEmail reports whether email is a bare address, without display
+// name, comments or surrounding spaces, valid at strictness
+func isValidEmail(email string, strictness EmailStrictness) bool {
+	if len(email) > maxEmailLength {
+		return false
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" {
+		return false
+	}
+	// The address is written back the way it was parsed, quoting the local
+	// part when needed, so comments and spaces make it differ
+	if strings.Trim(address.String(), "<>") != email {
+		return false
+	}
+
+	at := strings.LastIndex(address.Address, "@")
+	local, domain := address.Address[:at], address.Address[at+1:]
+	if len(local) > maxLocalPartLength {
+		return false
+	}
+	switch strictness {
+	case EmailRFC:
+		return true
+	case EmailStrict:
+		if !isPlainLocalPart(local) {
+			return false
+		}
+	}
+	return isDomainName(domain)
+}
+
+// isPlainLocalPart reports whether local only has letters, digits and
+// . _ % + -, with dots between other characters
+func isPlainLocalPart(local string) bool {
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return false
+	}
+	for _, r := range local {
+		if !isASCIILetterOrDigit(r) && !strings.ContainsRune("._%+-", r) {
+			return false
+		}
+	}
+	return local != ""
+}
+
+// isDomainName reports whether domain is a name of at least two labels of
+// letters, digits and inner hyphens, whose top-level label is not numeric
+func isDomainName(domain string) bool {
+	if len(domain) > maxDomainLength {
+		return false
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxDomainLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			// Internationalized domain names are written in letters of any script
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+				return false
+			}
+		}
+	}
+	return strings.IndexFunc(labels[len(labels)-1], func(r rune) bool { return !unicode.IsDigit(r) }) >= 0
+}
+
+// isASCIILetterOrDigit reports whether r is an ASCII letter or digit
+func isASCIILetterOrDigit(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
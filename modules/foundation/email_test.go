@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestIsValidEmail_Strictness(t *testing.T) {
+	tests := []struct {
+		email                 string
+		rfc, standard, strict bool
+	}{
+		{"ada@example.com", true, true, true},
+		{"ada.lovelace+news@mail.example.co.uk", true, true, true},
+		{"ada@localhost", true, false, false},
+		{"ada@[192.168.0.1]", true, false, false},
+		{"ada@example.123", true, false, false},
+		{`"ada lovelace"@example.com`, true, true, false},
+		{"ada!#$&'*/=?^`{|}~@example.com", true, true, false},
+		{"adá@bücher.de", true, true, false},
+		{"ada@-example.com", true, false, false},
+		{"ada@exa_mple.com", true, false, false},
+		{"Ada <ada@example.com>", false, false, false},
+		{"ada@example.com (Ada)", false, false, false},
+		{" ada@example.com", false, false, false},
+		{"ada..lovelace@example.com", false, false, false},
+		{".ada@example.com", false, false, false},
+		{"ada@example..com", false, false, false},
+		{"ada@@example.com", false, false, false},
+		{"ada,bob@example.com", false, false, false},
+		{strings.Repeat("a", 65) + "@example.com", false, false, false},
+		{"ada@" + strings.Repeat("a", 64) + ".com", true, false, false},
+		{"ada@" + strings.Repeat("a.", 125) + "com", false, false, false},
+	}
+	for _, tt := range tests {
+		for strictness, want := range map[EmailStrictness]bool{EmailRFC: tt.rfc, EmailStandard: tt.standard, EmailStrict: tt.strict} {
+			if got := isValidEmail(tt.email, strictness); got != want {
+				t.Errorf("isValidEmail(%q, %s) = %v, want %v", tt.email, strictness, got, want)
+			}
+		}
+	}
+}
+
+func TestUser_Validate_EmailStrictness(t *testing.T) {
+	user := &User{Name: "Ada", Email: "ada@localhost"}
+	if err := user.Validate(); err == nil {
+		t.Error("Validate() got no error want the single-label domain rejected by default")
+	}
+	if err := user.Validate(WithEmailStrictness(EmailRFC)); err != nil {
+		t.Errorf("Validate(rfc) got %v want no error", err)
+	}
+
+	service := NewInMemoryUserService(WithEmailValidation(EmailRFC))
+	created, err := service.CreateUser("Ada", "ada@localhost")
+	if err != nil {
+		t.Fatalf("CreateUser() got %v want the address accepted at the rfc level", err)
+	}
+	if _, err := service.UpdateUser(created.ID, "Ada", "ada@mail", 0); err != nil {
+		t.Errorf("UpdateUser() got %v want the address accepted at the rfc level", err)
+	}
+	if _, err := NewInMemoryUserService(WithEmailValidation(EmailStrict)).CreateUser("Ada", `"ada lovelace"@example.com`); err == nil {
+		t.Error("CreateUser() got no error want the quoted address rejected at the strict level")
+	}
+}
+
+func TestLoadEmailStrictness(t *testing.T) {
+	for _, value := range []string{"rfc", "standard", "strict"} {
+		if got, err := loadEmailStrictness(UserSettings{EmailValidation: value}); err != nil || string(got) != value {
+			t.Errorf("loadEmailStrictness(%q) got %q, %v", value, got, err)
+		}
+	}
+	if _, err := loadEmailStrictness(UserSettings{EmailValidation: "STRICT"}); err == nil {
+		t.Error("loadEmailStrictness() got no error want an unknown level rejected")
+	}
+}
+
+// FuzzIsValidEmail checks that validating arbitrary input never panics, that
+// each level accepts a subset of the addresses of the lower levels and that
+// accepted addresses are bare addresses of net/mail
+func FuzzIsValidEmail(f *testing.F) {
+	for _, seed := range []string{
+		"ada@example.com", `"ada lovelace"@example.com`, "ada@[::1]", "Ada <ada@example.com>",
+		"adá@bücher.de", "ada@example.com (Ada)", "=?utf-8?q?ada?= <ada@example.com>", "@", "\xff@example.com",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, email string) {
+		rfc := isValidEmail(email, EmailRFC)
+		standard := isValidEmail(email, EmailStandard)
+		strict := isValidEmail(email, EmailStrict)
+		if strict && !standard || standard && !rfc {
+			t.Fatalf("isValidEmail(%q) = rfc %v, standard %v, strict %v, want stricter levels to accept less", email, rfc, standard, strict)
+		}
+		if !rfc {
+			return
+		}
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Name != "" || len(email) > maxEmailLength {
+			t.Fatalf("isValidEmail(%q) accepted an address that is not bare: %v, %v", email, address, err)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ResponseEncoder renders response bodies in a single media type
+type ResponseEncoder interface {
+	// ContentType returns the media type written to the Content-Type header
+	ContentType() string
+
+	// Encode writes data to w
+	Encode(w io.Writer, data interface{}) error
+}
+
+// EncoderRegistry selects a ResponseEncoder based on the Accept header.
+// The first registered encoder is the default and wins ties.
+type EncoderRegistry struct {
+	entries []encoderEntry
+}
+
+// encoderEntry is a registered encoder and the media types it serves
+type encoderEntry struct {
+	encoder    ResponseEncoder
+	mediaTypes []string
+}
+
+// NewEncoderRegistry creates a registry with the given encoders in order of preference
+func NewEncoderRegistry(encoders ...ResponseEncoder) *EncoderRegistry {
+	r := &EncoderRegistry{}
+	for _, encoder := range encoders {
+		r.Register(encoder)
+	}
+	return r
+}
+
+// DefaultEncoderRegistry returns a registry serving JSON, XML and MessagePack
+func DefaultEncoderRegistry() *EncoderRegistry {
+	r := NewEncoderRegistry()
+	r.Register(jsonEncoder{})
+	r.Register(xmlEncoder{}, "text/xml")
+	r.Register(msgpackEncoder{}, "application/x-msgpack")
+	return r
+}
+
+// Register adds an encoder for its content type and any alias media types
+func (r *EncoderRegistry) Register(encoder ResponseEncoder, aliases ...string) {
+	r.entries = append(r.entries, encoderEntry{
+		encoder:    encoder,
+		mediaTypes: append([]string{encoder.ContentType()}, aliases...),
+	})
+}
+
+// Default returns the encoder used when the client expresses no preference
+func (r *EncoderRegistry) Default() ResponseEncoder {
+	return r.entries[0].encoder
+}
+
+// ContentTypes returns the content types of the registered encoders
+func (r *EncoderRegistry) ContentTypes() []string {
+	types := make([]string, len(r.entries))
+	for i, entry := range r.entries {
+		types[i] = entry.encoder.ContentType()
+	}
+	return types
+}
+
+// Negotiate returns the encoder best matching the Accept header. It returns
+// false when none of the registered media types is acceptable.
+func (r *EncoderRegistry) Negotiate(accept string) (ResponseEncoder, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return r.Default(), true
+	}
+
+	ranges := parseAccept(accept)
+	var best ResponseEncoder
+	bestQ := 0.0
+	for _, entry := range r.entries {
+		for _, mediaType := range entry.mediaTypes {
+			if q := acceptQuality(ranges, mediaType); q > bestQ {
+				best, bestQ = entry.encoder, q
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// acceptedMediaRange is one entry of an Accept header
+type acceptedMediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses the media ranges of an Accept header, skipping malformed ones
+func parseAccept(accept string) []acceptedMediaRange {
+	var ranges []acceptedMediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptedMediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality the client assigns to mediaType, taken
+// from the most specific matching range (exact, then type/*, then */*)
+func acceptQuality(ranges []acceptedMediaRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, mediaRange := range ranges {
+		var s int
+		switch mediaRange.mediaType {
+		case mediaType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = mediaRange.q, s
+		}
+	}
+	return q
+}
+
+// jsonEncoder renders application/json
+type jsonEncoder struct{}
+
+// ContentType returns application/json
+func (jsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+// Encode writes data as JSON
+func (jsonEncoder) Encode(w io.Writer, data interface{}) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// xmlEncoder renders application/xml. Besides types with xml tags it supports
+// the map[string]interface{} bodies used for errors, writing one element per key.
+type xmlEncoder struct{}
+
+// ContentType returns application/xml
+func (xmlEncoder) ContentType() string {
+	return "application/xml"
+}
+
+// Encode writes data as an XML document
+func (xmlEncoder) Encode(w io.Writer, data interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	var err error
+	switch body := data.(type) {
+	case map[string]interface{}:
+		if len(body) == 1 {
+			// A single top-level key such as "error" becomes the root element
+			for name, value := range body {
+				err = encodeXMLValue(enc, name, value)
+			}
+		} else {
+			err = encodeXMLValue(enc, "response", body)
+		}
+	default:
+		err = enc.Encode(data)
+	}
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// encodeXMLValue writes value as an element called name, expanding maps into child elements
+func encodeXMLValue(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := encodeXMLValue(enc, key, v[key]); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	default:
+		return enc.EncodeElement(v, start)
+	}
+}
+
+// msgpackEncoder renders application/msgpack using the json struct tags
+type msgpackEncoder struct{}
+
+// ContentType returns application/msgpack
+func (msgpackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+// Encode writes data as MessagePack
+func (msgpackEncoder) Encode(w io.Writer, data interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(data)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestEncoderRegistry_Negotiate(t *testing.T) {
+	registry := DefaultEncoderRegistry()
+
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+		ok       bool
+	}{
+		{"no preference", "", "application/json", true},
+		{"any type", "*/*", "application/json", true},
+		{"xml", "application/xml", "application/xml", true},
+		{"xml alias", "text/xml", "application/xml", true},
+		{"msgpack", "application/msgpack", "application/msgpack", true},
+		{"msgpack alias", "application/x-msgpack", "application/msgpack", true},
+		{"quality ordering", "application/json;q=0.5, application/xml", "application/xml", true},
+		{"specific beats wildcard", "application/*;q=0.2, application/msgpack;q=0.9", "application/msgpack", true},
+		{"excluded type", "application/json;q=0, application/*", "application/xml", true},
+		{"unsupported", "text/html", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, ok := registry.Negotiate(tt.accept)
+			if ok != tt.ok {
+				t.Fatalf("Negotiate(%q) ok = %v, want %v", tt.accept, ok, tt.ok)
+			}
+			if ok && encoder.ContentType() != tt.expected {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.accept, encoder.ContentType(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ContentNegotiation(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+	user, err := service.CreateUser("Ada Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Run("xml user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+user.ID, nil)
+		req.Header.Set("Accept", "application/xml")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/xml" {
+			t.Fatalf("Content-Type = %q, want application/xml", ct)
+		}
+		var decoded User
+		if err := xml.Unmarshal(rr.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal XML response: %v", err)
+		}
+		if decoded.Email != user.Email || len(decoded.Roles) != 1 || decoded.Roles[0] != RoleViewer {
+			t.Errorf("XML response decoded to unexpected user: %+v", decoded)
+		}
+	})
+
+	t.Run("xml user list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Accept", "application/xml")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var decoded struct {
+			XMLName xml.Name `xml:"users"`
+			Users   []User   `xml:"user"`
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal XML response: %v", err)
+		}
+		if len(decoded.Users) != 4 {
+			t.Errorf("XML response contains %d users, want 4", len(decoded.Users))
+		}
+	})
+
+	t.Run("msgpack user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+user.ID, nil)
+		req.Header.Set("Accept", "application/msgpack")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/msgpack" {
+			t.Fatalf("Content-Type = %q, want application/msgpack", ct)
+		}
+		var decoded map[string]interface{}
+		if err := msgpack.Unmarshal(rr.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal MessagePack response: %v", err)
+		}
+		if decoded["email"] != user.Email || decoded["id"] != user.ID {
+			t.Errorf("MessagePack response decoded to unexpected user: %v", decoded)
+		}
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotAcceptable {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotAcceptable)
+		}
+	})
+}
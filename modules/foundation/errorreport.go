@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// defaultFlushTimeout bounds Flush when its context has no deadline
+const defaultFlushTimeout = 2 * time.Second
+
+// ErrorReporter sends unexpected errors, such as internal errors and
+// recovered panics, to an error tracking service
+type ErrorReporter interface {
+	// Report sends err, which happened while serving the request of ctx
+	Report(ctx context.Context, err error)
+
+	// Flush waits until the pending reports are sent or ctx ends
+	Flush(ctx context.Context) error
+}
+
+// noopReporter is the ErrorReporter used when no error tracking is configured
+type noopReporter struct{}
+
+// Report does nothing
+func (noopReporter) Report(context.Context, error) {}
+
+// Flush does nothing
+func (noopReporter) Flush(context.Context) error { return nil }
+
+// loadErrorReporter reports errors to Sentry when a DSN is set, tagged with
+// the environment, and drops them otherwise
+func loadErrorReporter(settings SentrySettings) (ErrorReporter, error) {
+	if settings.DSN == "" {
+		return noopReporter{}, nil
+	}
+	return NewSentryReporter(sentry.ClientOptions{
+		Dsn:         settings.DSN,
+		Environment: settings.Environment,
+	})
+}
+
+// shouldReport tells whether err is unexpected: internal errors and errors
+// that are not application errors. Client errors are part of normal operation.
+func shouldReport(err error) bool {
+	appErr, ok := IsAppError(err)
+	return !ok || appErr.Type == ErrorTypeInternal
+}
+
+// SentryReporter reports errors to Sentry
+type SentryReporter struct {
+	hub *sentry.Hub
+}
+
+// NewSentryReporter creates a SentryReporter with the client options. The
+// stack of the reporting goroutine is attached to every report, which for
+// panics includes the panicking frames.
+func NewSentryReporter(options sentry.ClientOptions) (*SentryReporter, error) {
+	options.AttachStacktrace = true
+	client, err := sentry.NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return &SentryReporter{hub: sentry.NewHub(client, sentry.NewScope())}, nil
+}
+
+// Report sends err tagged with the request ID and tenant of ctx, and the
+// token subject as the user
+func (r *SentryReporter) Report(ctx context.Context, err error) {
+	// Each report gets its own scope so concurrent requests do not mix their tags
+	hub := r.hub.Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		if requestID := RequestIDFromContext(ctx); requestID != "" {
+			scope.SetTag("request_id", requestID)
+		}
+		scope.SetTag("tenant", TenantFromContext(ctx))
+		if claims, ok := ClaimsFromContext(ctx); ok {
+			scope.SetUser(sentry.User{ID: claims.Subject})
+		}
+	})
+	hub.CaptureException(err)
+}
+
+// Flush waits until the pending reports are sent or ctx ends
+func (r *SentryReporter) Flush(ctx context.Context) error {
+	timeout := defaultFlushTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if !r.hub.Flush(timeout) {
+		return errors.New("error reports not sent before the deadline")
+	}
+	return nil
+}
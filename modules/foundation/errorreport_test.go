@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// recordingReporter keeps the reported errors
+type recordingReporter struct {
+	errors []error
+}
+
+func (r *recordingReporter) Report(_ context.Context, err error) {
+	r.errors = append(r.errors, err)
+}
+
+func (r *recordingReporter) Flush(context.Context) error { return nil }
+
+// recordingTransport keeps the events a Sentry client sends
+type recordingTransport struct {
+	events []*sentry.Event
+	mutex  sync.Mutex
+}
+
+func (t *recordingTransport) Flush(time.Duration) bool       { return true }
+func (t *recordingTransport) Configure(sentry.ClientOptions) {}
+func (t *recordingTransport) Close()                         {}
+
+func (t *recordingTransport) SendEvent(event *sentry.Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.events = append(t.events, event)
+}
+
+// failingUserService fails every lookup with an unexpected error
+type failingUserService struct {
+	UserService
+}
+
+func (failingUserService) GetUserByID(string) (*User, error) {
+	return nil, errors.New("store offline")
+}
+
+func TestShouldReport(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unexpected error", errors.New("store offline"), true},
+		{"internal error", NewInternalError("internal server error", errors.New("boom")), true},
+		{"wrapped internal error", WrapError(NewInternalError("internal server error", nil), "loading users"), true},
+		{"not found", NewNotFoundError("user", "1"), false},
+		{"validation", NewValidationError("email", "invalid email"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldReport(tt.err); got != tt.want {
+				t.Errorf("shouldReport() got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	transport := &recordingTransport{}
+	reporter, err := NewSentryReporter(sentry.ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatalf("NewSentryReporter() error: %v", err)
+	}
+
+	ctx := ContextWithTenant(ContextWithRequestID(context.Background(), "req-1"), "acme")
+	reporter.Report(ContextWithClaims(ctx, &Claims{Subject: "user-1"}), errors.New("store offline"))
+	reporter.Report(context.Background(), errors.New("disk full"))
+	if err := reporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	if len(transport.events) != 2 {
+		t.Fatalf("events got %d want 2", len(transport.events))
+	}
+	event := transport.events[0]
+	if len(event.Exception) == 0 || event.Exception[len(event.Exception)-1].Value != "store offline" {
+		t.Errorf("exception got %+v want store offline", event.Exception)
+	}
+	if event.Tags["request_id"] != "req-1" || event.Tags["tenant"] != "acme" || event.User.ID != "user-1" {
+		t.Errorf("event got tags %v and user %q want req-1, acme and user-1", event.Tags, event.User.ID)
+	}
+
+	// Tags of one report do not leak into the next
+	if other := transport.events[1]; other.Tags["request_id"] != "" || other.User.ID != "" {
+		t.Errorf("second event got tags %v and user %q want none", other.Tags, other.User.ID)
+	}
+}
+
+func TestUserHandler_ReportsUnexpectedErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		service      UserService
+		wantCode     int
+		wantReported int
+	}{
+		{"unexpected error", failingUserService{}, http.StatusInternalServerError, 1},
+		{"not found", NewInMemoryUserService(), http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &recordingReporter{}
+			handler := NewUserHandler(tt.service, WithErrorReporter(reporter))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/missing", nil))
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("status code got %v want %v", rr.Code, tt.wantCode)
+			}
+			if len(reporter.errors) != tt.wantReported {
+				t.Errorf("reported got %v want %d errors", reporter.errors, tt.wantReported)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddleware_ReportsPanics(t *testing.T) {
+	reporter := &recordingReporter{}
+	handler := recoveryMiddleware(reporter, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(errors.New("nil map"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if len(reporter.errors) != 1 || reporter.errors[0].Error() != "panic: nil map" {
+		t.Fatalf("reported got %v want panic: nil map", reporter.errors)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -15,14 +16,45 @@ const (
 	ErrorTypeNotFound   ErrorType = "NOT_FOUND_ERROR"
 	ErrorTypeConflict   ErrorType = "CONFLICT_ERROR"
 	ErrorTypeInternal   ErrorType = "INTERNAL_ERROR"
+
+	ErrorTypePreconditionFailed ErrorType = "PRECONDITION_FAILED_ERROR"
+	ErrorTypeUnauthorized       ErrorType = "UNAUTHORIZED_ERROR"
+	ErrorTypeForbidden          ErrorType = "FORBIDDEN_ERROR"
+	ErrorTypePayloadTooLarge    ErrorType = "PAYLOAD_TOO_LARGE_ERROR"
+	ErrorTypeTimeout            ErrorType = "TIMEOUT_ERROR"
 )
 
 // AppError represents a custom application error
 type AppError struct {
-	Type    ErrorType `json:"type"`
-	Message string    `json:"message"`
-	Field   string    `json:"field,omitempty"`
-	Cause   error     `json:"-"`
+	Type    ErrorType              `json:"type"`
+	Message string                 `json:"message"`
+	Field   string                 `json:"field,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Errors  []FieldError           `json:"errors,omitempty"`
+	Cause   error                  `json:"-"`
+}
+
+// FieldError describes a problem with a single field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects field errors so every problem of a request is
+// reported at once instead of only the first one
+type ValidationErrors []FieldError
+
+// Add records a problem with field
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+// Err returns nil when no problem was recorded, or a validation error listing all of them
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return NewValidationErrors(v)
 }
 
 // Error implements the error interface
@@ -47,6 +79,16 @@ func (e *AppError) HTTPStatusCode() int {
 		return http.StatusNotFound
 	case ErrorTypeConflict:
 		return http.StatusConflict
+	case ErrorTypePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrorTypeUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorTypeForbidden:
+		return http.StatusForbidden
+	case ErrorTypePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrorTypeTimeout:
+		return http.StatusGatewayTimeout
 	case ErrorTypeInternal:
 		return http.StatusInternalServerError
 	default:
@@ -63,6 +105,21 @@ func NewValidationError(field, message string) *AppError {
 	}
 }
 
+// NewValidationErrors creates a validation error for several field errors.
+// A single field error keeps its field and message on the error itself.
+func NewValidationErrors(fieldErrors []FieldError) *AppError {
+	err := &AppError{
+		Type:    ErrorTypeValidation,
+		Message: fmt.Sprintf("%d fields are invalid", len(fieldErrors)),
+		Errors:  fieldErrors,
+	}
+	if len(fieldErrors) == 1 {
+		err.Field = fieldErrors[0].Field
+		err.Message = fieldErrors[0].Message
+	}
+	return err
+}
+
 // NewNotFoundError creates a new not found error
 func NewNotFoundError(resource, id string) *AppError {
 	return &AppError{
@@ -79,6 +136,55 @@ func NewConflictError(message string) *AppError {
 	}
 }
 
+// NewPreconditionFailedError creates a new precondition failed error
+func NewPreconditionFailedError(message string) *AppError {
+	return &AppError{
+		Type:    ErrorTypePreconditionFailed,
+		Message: message,
+	}
+}
+
+// NewUnauthorizedError creates a new unauthorized error
+func NewUnauthorizedError(message string) *AppError {
+	return &AppError{
+		Type:    ErrorTypeUnauthorized,
+		Message: message,
+	}
+}
+
+// NewForbiddenError creates a new forbidden error naming the missing permission
+func NewForbiddenError(permission Permission) *AppError {
+	return &AppError{
+		Type:    ErrorTypeForbidden,
+		Message: fmt.Sprintf("missing permission '%s'", permission),
+		Details: map[string]interface{}{
+			"permission": permission,
+		},
+	}
+}
+
+// NewPayloadTooLargeError creates a new error for a request body exceeding limit bytes
+func NewPayloadTooLargeError(limit int64) *AppError {
+	return &AppError{
+		Type:    ErrorTypePayloadTooLarge,
+		Message: fmt.Sprintf("request body exceeds %d bytes", limit),
+		Details: map[string]interface{}{
+			"limit_bytes": limit,
+		},
+	}
+}
+
+// NewTimeoutError creates a new error for a request that exceeded its time budget
+func NewTimeoutError(timeout time.Duration) *AppError {
+	return &AppError{
+		Type:    ErrorTypeTimeout,
+		Message: fmt.Sprintf("request did not complete within %s", timeout),
+		Details: map[string]interface{}{
+			"timeout_ms": timeout.Milliseconds(),
+		},
+	}
+}
+
 // NewInternalError creates a new internal error with cause
 func NewInternalError(message string, cause error) *AppError {
 	return &AppError{
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies this service as the producer of its events
+const eventSource = "user-service"
+
+// Types of the events published by this service
+const (
+	EventTypeUserCreated       = "user.created"
+	EventTypeUserUpdated       = "user.updated"
+	EventTypeUserDeleted       = "user.deleted"
+	EventTypeUserRolesAssigned = "user.roles_assigned"
+	EventTypeUserActivated     = "user.activated"
+	EventTypeUserSuspended     = "user.suspended"
+
+	EventTypeUserPasswordChanged = "user.password_changed"
+
+	// EventTypeLogLevelChanged is an operational event of the process, not of a tenant
+	EventTypeLogLevelChanged = "ops.log_level_changed"
+	// EventTypeConfigReloaded is an operational event of the process, not of a tenant
+	EventTypeConfigReloaded = "ops.config_reloaded"
+)
+
+// UserEventData is the payload of user events: a snapshot of the user after
+// the change and, except for created users, a snapshot from before it
+type UserEventData struct {
+	User     User  `json:"user"`
+	Previous *User `json:"previous,omitempty"`
+}
+
+// NewUserEvent creates a user event of tenant carrying a snapshot of user
+// and, when not nil, of the user before the change, with an ID from ids
+func NewUserEvent(ids uuid.IDGenerator, eventType, tenant string, user User, previous *User) (events.Event, error) {
+	return newEvent(ids, eventType, user.ID, tenant, UserEventData{User: user, Previous: previous})
+}
+
+// newEvent creates an event of this service of tenant about subject,
+// carrying data, with an ID from ids
+func newEvent(ids uuid.IDGenerator, eventType, subject, tenant string, data interface{}) (events.Event, error) {
+	event, err := events.New(ids.NewID(), eventType, eventSource, subject, data)
+	if err != nil {
+		return events.Event{}, err
+	}
+	event.Tenant = tenant
+	return event, nil
+}
+
+// ErrEventBusClosed is returned for the events published after the bus was closed
+var ErrEventBusClosed = errors.New("event bus closed")
+
+// EventBus is the in-process bus of pkg/events counting the events published
+// and their deliveries to subscribers, and logging failing subscribers
+type EventBus struct {
+	bus         *events.Bus
+	logger      *slog.Logger
+	subscribers atomic.Int64
+	published   atomic.Int64
+	consumed    atomic.Int64
+	failed      atomic.Int64
+
+	// delivering counts the events being delivered, and drained is closed
+	// once the bus is closed and none is left
+	mutex      sync.Mutex
+	closed     bool
+	delivering int
+	drained    chan struct{}
+}
+
+// EventBusStats counts the events of an EventBus since it was created
+type EventBusStats struct {
+	Published int64 `json:"published"`
+	Consumed  int64 `json:"consumed"`
+	Failed    int64 `json:"failed"`
+}
+
+// EventBusOption configures an EventBus
+type EventBusOption func(*EventBus)
+
+// WithBusLogger sets the logger reporting failing subscribers
+func WithBusLogger(logger *slog.Logger) EventBusOption {
+	return func(b *EventBus) {
+		b.logger = logger
+	}
+}
+
+// NewEventBus creates a new EventBus logging to the default logger unless configured otherwise
+func NewEventBus(opts ...EventBusOption) *EventBus {
+	bus := &EventBus{
+		bus:    events.NewBus(),
+		logger: slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(bus)
+	}
+	return bus
+}
+
+// Subscribe registers a handler for the events whose type matches one of
+// patterns, or every event without patterns, and returns a function that
+// removes it again
+func (b *EventBus) Subscribe(handler events.Handler, patterns ...string) (unsubscribe func()) {
+	b.subscribers.Add(1)
+	remove := b.bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		if err := handler(ctx, event); err != nil {
+			b.failed.Add(1)
+			b.logger.ErrorContext(ctx, "Event handler failed",
+				"event_type", event.Type, "event_id", event.ID, "error", err)
+			return nil
+		}
+		b.consumed.Add(1)
+		return nil
+	}, patterns...)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			b.subscribers.Add(-1)
+		})
+	}
+}
+
+// Publish delivers the event synchronously to every subscriber. A failing
+// subscriber is logged and does not prevent delivery to the others. Once the
+// bus is closed, the event is dropped with ErrEventBusClosed.
+func (b *EventBus) Publish(ctx context.Context, event events.Event) error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return ErrEventBusClosed
+	}
+	b.delivering++
+	b.mutex.Unlock()
+	defer func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.delivering--
+		if b.closed && b.delivering == 0 {
+			close(b.drained)
+		}
+	}()
+
+	b.published.Add(1)
+	canonicalLineFromContext(ctx).addEvent()
+	return b.bus.Publish(ctx, event)
+}
+
+// Close stops accepting events and waits until the events being delivered
+// reached every subscriber, or ctx ends
+func (b *EventBus) Close(ctx context.Context) error {
+	b.mutex.Lock()
+	if !b.closed {
+		b.closed = true
+		b.drained = make(chan struct{})
+		if b.delivering == 0 {
+			close(b.drained)
+		}
+	}
+	drained := b.drained
+	b.mutex.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the number of published events, and of deliveries to
+// subscribers that consumed them or failed
+func (b *EventBus) Stats() EventBusStats {
+	return EventBusStats{
+		Published: b.published.Load(),
+		Consumed:  b.consumed.Load(),
+		Failed:    b.failed.Load(),
+	}
+}
+
+// noopPublisher discards events; it is used when no bus is configured
+type noopPublisher struct{}
+
+// Publish discards the event
+func (noopPublisher) Publish(context.Context, events.Event) error {
+	return nil
+}
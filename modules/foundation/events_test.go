@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+)
+
+func TestEventBus_SubscribeAndUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+
+	var received []string
+	unsubscribe := bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		received = append(received, event.Type)
+		return nil
+	})
+
+	bus.Publish(context.Background(), events.Event{Type: EventTypeUserCreated})
+	unsubscribe()
+	bus.Publish(context.Background(), events.Event{Type: EventTypeUserDeleted})
+
+	if len(received) != 1 || received[0] != EventTypeUserCreated {
+		t.Errorf("received = %v, want [%s]", received, EventTypeUserCreated)
+	}
+}
+
+func TestEventBus_LogsFailingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatText, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("logging.New() error = %v", err)
+	}
+	bus := NewEventBus(WithBusLogger(logger))
+
+	delivered := false
+	bus.Subscribe(func(context.Context, events.Event) error { return errors.New("boom") })
+	bus.Subscribe(func(context.Context, events.Event) error {
+		delivered = true
+		return nil
+	})
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	bus.Publish(ctx, events.Event{ID: "evt-1", Type: EventTypeUserCreated})
+
+	if !delivered {
+		t.Error("a failing handler prevented delivery to the others")
+	}
+	for _, want := range []string{"event_id=evt-1", "event_type=user.created", "error=boom", "request_id=req-1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log %q does not contain %q", buf.String(), want)
+		}
+	}
+
+	if stats, want := bus.Stats(), (EventBusStats{Published: 1, Consumed: 1, Failed: 1}); stats != want {
+		t.Errorf("Stats() got %+v want %+v", stats, want)
+	}
+}
+
+func TestInMemoryUserService_PublishesEvents(t *testing.T) {
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+
+	var received []events.Event
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		// Subscribers may call back into the service without deadlocking
+		if _, err := service.GetUsers(); err != nil {
+			t.Errorf("GetUsers() from subscriber failed: %v", err)
+		}
+		received = append(received, event)
+		return nil
+	})
+
+	user, err := service.CreateUser("Event User", "event@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if _, err := service.UpdateUser(user.ID, "Event User", "event2@example.com", 0); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	// Updates changing nothing publish nothing
+	if _, err := service.UpdateUser(user.ID, "Event User", "", 0); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if _, err := service.AssignRoles(user.ID, []Role{RoleEditor}, 0); err != nil {
+		t.Fatalf("AssignRoles() error = %v", err)
+	}
+	if err := service.DeleteUser(user.ID, 0); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	// Failed operations publish nothing
+	service.DeleteUser(user.ID, 0)
+
+	want := []string{EventTypeUserCreated, EventTypeUserUpdated, EventTypeUserRolesAssigned, EventTypeUserDeleted}
+	if len(received) != len(want) {
+		t.Fatalf("received %d events, want %d", len(received), len(want))
+	}
+	for i, event := range received {
+		if event.Type != want[i] {
+			t.Errorf("event %d type = %s, want %s", i, event.Type, want[i])
+		}
+		if event.Subject != user.ID || event.Source != eventSource || event.ID == "" {
+			t.Errorf("event %d has incomplete metadata: %+v", i, event)
+		}
+	}
+}
+
+func TestEventBus_Close(t *testing.T) {
+	bus := NewEventBus()
+	delivering := make(chan struct{})
+	release := make(chan struct{})
+	bus.Subscribe(func(context.Context, events.Event) error {
+		close(delivering)
+		<-release
+		return nil
+	})
+
+	published := make(chan error)
+	go func() {
+		published <- bus.Publish(context.Background(), events.Event{Type: EventTypeUserCreated})
+	}()
+	<-delivering
+
+	// Close waits for the event in delivery
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() during a delivery got %v want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if err := <-published; err != nil {
+		t.Errorf("Publish() before Close() got %v want nil", err)
+	}
+	if err := bus.Close(context.Background()); err != nil {
+		t.Errorf("Close() once drained got %v want nil", err)
+	}
+
+	if err := bus.Publish(context.Background(), events.Event{Type: EventTypeUserDeleted}); !errors.Is(err, ErrEventBusClosed) {
+		t.Errorf("Publish() after Close() got %v want %v", err, ErrEventBusClosed)
+	}
+	if stats := bus.Stats(); stats.Published != 1 {
+		t.Errorf("Stats() got %+v want the event published before Close() only", stats)
+	}
+}
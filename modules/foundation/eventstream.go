@@ -0,0 +1,11 @@
+package main
+
+import (
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// newEventStream serves the events published on bus, of every tenant, as
+// server-sent events, so other services can follow them at GET /events
+func newEventStream(bus *EventBus) *events.Stream {
+	return events.NewStream(bus)
+}
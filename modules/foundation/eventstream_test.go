@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+func TestEventStream(t *testing.T) {
+	bus := NewEventBus()
+	stream := newEventStream(bus)
+	server := httptest.NewServer(stream)
+	defer server.Close()
+	defer stream.Close()
+
+	resp, err := http.Get(server.URL + "?types=user.deleted")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	user := User{ID: "u1", Name: "Ada", Email: "ada@example.com"}
+	ids := uuid.GeneratorFunc(uuid.NewGoogle)
+	created, err := NewUserEvent(ids, EventTypeUserCreated, "acme", user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(context.Background(), created)
+	deleted, err := NewUserEvent(ids, EventTypeUserDeleted, "acme", user, &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted.Actor, deleted.RequestID = "admin", "req-1"
+	bus.Publish(context.Background(), deleted)
+
+	// Stop reading once the first event arrived
+	errStop := errors.New("stop")
+	var got events.Event
+	err = events.ReadSSE(bufio.NewReader(resp.Body), func(m events.Message) error {
+		if err := json.Unmarshal([]byte(m.Data), &got); err != nil {
+			return err
+		}
+		return errStop
+	})
+	if err != errStop {
+		t.Fatalf("ReadSSE() error: %v", err)
+	}
+	if got.Type != string(EventTypeUserDeleted) || got.Tenant != "acme" || got.Subject != "u1" || got.Source != eventSource ||
+		got.Actor != "admin" || got.RequestID != "req-1" {
+		t.Errorf("event got %+v want the user.deleted event of acme", got)
+	}
+	var data UserEventData
+	if err := got.Decode(&data); err != nil || data.User.Email != "ada@example.com" {
+		t.Errorf("data got %+v, %v want the user snapshot", data, err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// parseFields reads the ?fields=id,name query parameter. It returns nil when
+// no fields are requested, meaning the full representation.
+func parseFields(query url.Values) map[string]bool {
+	var fields map[string]bool
+	for _, value := range query["fields"] {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				if fields == nil {
+					fields = make(map[string]bool)
+				}
+				fields[field] = true
+			}
+		}
+	}
+	return fields
+}
+
+// sparseFieldset is a response reduced to the requested top-level fields of
+// its resource, or of every resource when the response is a collection.
+// Each format filters its own representation of the full response, so any
+// resource type is supported without extra code. Unknown fields are ignored.
+type sparseFieldset struct {
+	data   interface{}
+	fields map[string]bool
+}
+
+// isCollection reports whether the response is a list of resources
+func (s sparseFieldset) isCollection() bool {
+	kind := reflect.ValueOf(s.data).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// filter keeps the requested keys of a decoded object, or of each object in a decoded list
+func (s sparseFieldset) filter(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key := range v {
+			if !s.fields[key] {
+				delete(v, key)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			s.filter(item)
+		}
+	}
+	return value
+}
+
+// MarshalJSON renders the requested fields of the JSON representation
+func (s sparseFieldset) MarshalJSON() ([]byte, error) {
+	full, err := json.Marshal(s.data)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(full))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(s.filter(decoded))
+}
+
+// EncodeMsgpack renders the requested fields of the MessagePack representation
+func (s sparseFieldset) EncodeMsgpack(enc *msgpack.Encoder) error {
+	var full bytes.Buffer
+	fullEnc := msgpack.NewEncoder(&full)
+	fullEnc.SetCustomStructTag("json")
+	if err := fullEnc.Encode(s.data); err != nil {
+		return err
+	}
+
+	var decoded interface{}
+	if err := msgpack.NewDecoder(&full).Decode(&decoded); err != nil {
+		return err
+	}
+	return enc.Encode(s.filter(decoded))
+}
+
+// MarshalXML renders the requested child elements of the resource elements
+// of the XML representation: the root element, or its children for collections
+func (s sparseFieldset) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	full, err := xml.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+
+	resourceDepth := 1
+	if s.isCollection() {
+		resourceDepth = 2
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(full))
+	depth, skipping := 0, 0
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if skipping == 0 && depth == resourceDepth+1 && !s.fields[t.Name.Local] {
+				skipping = depth
+			}
+		case xml.EndElement:
+			depth--
+			if skipping > depth {
+				skipping = 0
+				continue
+			}
+		}
+		if skipping == 0 {
+			if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestUserHandler_SparseFieldsets(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+	user, err := service.CreateUser("Ada Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"all fields", "/users/" + user.ID, []string{"created_at", "email", "id", "name", "roles", "status", "updated_at", "version"}},
+		{"selected fields", "/users/" + user.ID + "?fields=id,name", []string{"id", "name"}},
+		{"repeated parameter", "/users/" + user.ID + "?fields=id&fields=version", []string{"id", "version"}},
+		{"unknown field ignored", "/users/" + user.ID + "?fields=email,password", []string{"email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(body) != len(tt.expected) {
+				t.Errorf("response has fields %v, want %v", body, tt.expected)
+			}
+			for _, field := range tt.expected {
+				if _, ok := body[field]; !ok {
+					t.Errorf("response is missing field %q", field)
+				}
+			}
+		})
+	}
+
+	t.Run("collection", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users?fields=email", nil))
+
+		var body []map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		for _, item := range body {
+			if len(item) != 1 || item["email"] == nil {
+				t.Errorf("collection item = %v, want only email", item)
+			}
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users?fields=id,roles", nil)
+		req.Header.Set("Accept", "application/xml")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var decoded struct {
+			Users []struct {
+				ID    string   `xml:"id"`
+				Name  string   `xml:"name"`
+				Roles []string `xml:"roles>role"`
+			} `xml:"user"`
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal XML response: %v\n%s", err, rr.Body)
+		}
+		if len(decoded.Users) != 4 {
+			t.Fatalf("XML response contains %d users, want 4", len(decoded.Users))
+		}
+		for _, u := range decoded.Users {
+			if u.ID == "" || len(u.Roles) == 0 || u.Name != "" {
+				t.Errorf("XML user = %+v, want only id and roles", u)
+			}
+		}
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+user.ID+"?fields=version,name", nil)
+		req.Header.Set("Accept", "application/msgpack")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var decoded map[string]interface{}
+		if err := msgpack.Unmarshal(rr.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal MessagePack response: %v", err)
+		}
+		if len(decoded) != 2 || decoded["name"] != user.Name {
+			t.Errorf("MessagePack response = %v, want only name and version", decoded)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/fixtures"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// embeddedFixtures holds the fixture files of each environment, such as
+// fixtures/demo/users.yaml
+//
+//go:embed fixtures
+var embeddedFixtures embed.FS
+
+// defaultUserFixtures are the demo users of stores created without WithFixtures
+var defaultUserFixtures = mustLoadUserFixtures(UserSettings{Fixtures: fixtures.DefaultEnvironment})
+
+// UserFixture is a demo user of the fixture files. The roles default to
+// viewer and the status to active.
+type UserFixture struct {
+	Name   string     `json:"name"`
+	Email  string     `json:"email"`
+	Roles  []Role     `json:"roles,omitempty"`
+	Status UserStatus `json:"status,omitempty"`
+}
+
+// newUser creates a fresh user of the fixture with an ID from ids
+func (f UserFixture) newUser(ids uuid.IDGenerator) *User {
+	user := NewUser(ids, f.Name, f.Email)
+	user.Status = UserStatusActive
+	if f.Status != "" {
+		user.Status = f.Status
+	}
+	if len(f.Roles) > 0 {
+		user.Roles = slices.Clone(f.Roles)
+	}
+	return user
+}
+
+// problems returns what keeps the fixture from being a valid user
+func (f UserFixture) problems() []string {
+	var problems []string
+	if appErr, ok := IsAppError((&User{Name: f.Name, Email: f.Email}).Validate()); ok {
+		for _, fieldErr := range appErr.Errors {
+			problems = append(problems, fieldErr.Message)
+		}
+		if len(appErr.Errors) == 0 {
+			problems = append(problems, appErr.Message)
+		}
+	}
+	for _, role := range f.Roles {
+		if !role.IsValid() {
+			problems = append(problems, fmt.Sprintf("unknown role '%s'", role))
+		}
+	}
+	if f.Status != "" && (!f.Status.IsValid() || f.Status == UserStatusDeleted) {
+		problems = append(problems, fmt.Sprintf("status must be pending, active or suspended, got '%s'", f.Status))
+	}
+	return problems
+}
+
+// loadUserFixtures loads the demo users of the environment of settings
+// (FIXTURES), from the files of FIXTURES_DIR when set and else from the
+// embedded ones, and checks that they are valid users with distinct emails
+func loadUserFixtures(settings UserSettings) ([]UserFixture, error) {
+	var fsys fs.FS
+	if settings.FixturesDir != "" {
+		fsys = os.DirFS(settings.FixturesDir)
+	} else {
+		fsys, _ = fs.Sub(embeddedFixtures, "fixtures")
+	}
+
+	// An unknown environment is a typo rather than a request for the defaults
+	environments, err := fixtures.Environments(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("FIXTURES_DIR: %w", err)
+	}
+	if !slices.Contains(environments, settings.Fixtures) {
+		return nil, fmt.Errorf("FIXTURES must be one of %s, got %q", strings.Join(environments, ", "), settings.Fixtures)
+	}
+
+	users, err := fixtures.Load[UserFixture](fsys, settings.Fixtures, "users")
+	if err != nil {
+		return nil, fmt.Errorf("FIXTURES: %w", err)
+	}
+	emails := make(map[string]bool, len(users))
+	for i, user := range users {
+		problems := user.problems()
+		if emails[user.Email] {
+			problems = append(problems, "email is already used by another fixture")
+		}
+		emails[user.Email] = true
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("FIXTURES: users[%d] (%s): %s", i, user.Email, strings.Join(problems, "; "))
+		}
+	}
+	return users, nil
+}
+
+// mustLoadUserFixtures loads the fixtures of settings, which are embedded
+// and known to be valid
+func mustLoadUserFixtures(settings UserSettings) []UserFixture {
+	users, err := loadUserFixtures(settings)
+	if err != nil {
+		panic(err)
+	}
+	return users
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadUserFixtures(t *testing.T) {
+	for env, want := range map[string]int{"default": 3, "demo": 6, "empty": 0} {
+		users, err := loadUserFixtures(UserSettings{Fixtures: env})
+		if err != nil {
+			t.Fatalf("loadUserFixtures(%s) got %v", env, err)
+		}
+		if len(users) != want {
+			t.Errorf("loadUserFixtures(%s) got %d users want %d", env, len(users), want)
+		}
+	}
+
+	if _, err := loadUserFixtures(UserSettings{Fixtures: "staging"}); err == nil || !strings.Contains(err.Error(), "default, demo, empty") {
+		t.Errorf("loadUserFixtures(staging) got %v want the environments listed", err)
+	}
+}
+
+func TestLoadUserFixtures_Dir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(env, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, env), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, env, "users.json"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("default", `[{"name":"Ada","email":"ada@example.com","roles":["admin"],"status":"pending"}]`)
+	write("roles", `[{"name":"Ada","email":"ada@example.com","roles":["owner"]}]`)
+	write("twins", `[{"name":"Ada","email":"ada@example.com"},{"name":"Ada","email":"ada@example.com"}]`)
+	write("deleted", `[{"name":"","email":"ada@example.com","status":"deleted"}]`)
+
+	users, err := loadUserFixtures(UserSettings{Fixtures: "default", FixturesDir: dir})
+	if err != nil {
+		t.Fatalf("loadUserFixtures() got %v", err)
+	}
+	service := NewInMemoryUserService(WithFixtures(users))
+	seeded, err := service.GetUsers()
+	if err != nil || len(seeded) != 1 {
+		t.Fatalf("GetUsers() got %v, %v want the fixture user", seeded, err)
+	}
+	if user := seeded[0]; user.Email != "ada@example.com" || user.Status != UserStatusPending || user.Roles[0] != RoleAdmin {
+		t.Errorf("got %+v want the pending admin of the fixture", user)
+	}
+
+	for env, want := range map[string]string{
+		"roles":   "users[0] (ada@example.com): unknown role 'owner'",
+		"twins":   "users[1] (ada@example.com): email is already used by another fixture",
+		"deleted": "name cannot be empty; status must be pending, active or suspended, got 'deleted'",
+	} {
+		if _, err := loadUserFixtures(UserSettings{Fixtures: env, FixturesDir: dir}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("loadUserFixtures(%s) got %v want %q", env, err, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/golden"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// goldenHeaders are the response headers recorded in golden files
+var goldenHeaders = []string{"Allow", "Content-Type", "ETag", "Last-Modified", "Link", "X-Total-Count"}
+
+// steppingClock returns a clock starting at start that moves a second
+// forward each time it is read
+func steppingClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+// TestAPIGolden records the response of each endpoint in
+// testdata/golden/api. A change to the shape of a response fails the test
+// with a diff; go test -run TestAPIGolden -update accepts it.
+func TestAPIGolden(t *testing.T) {
+	service := NewInMemoryUserService(
+		WithIDGenerator(uuid.NewSequenceGenerator("event")),
+		WithUserIDGenerator(uuid.NewSequenceGenerator("user")),
+		WithClock(steppingClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+	)
+	handler := NewUserHandler(service)
+
+	// The steps share the store, so each one sees the changes of the previous ones
+	steps := []struct {
+		name    string
+		method  string
+		path    string
+		ifMatch string
+		body    string
+	}{
+		{"list-users", http.MethodGet, "/users", "", ""},
+		{"list-users-page", http.MethodGet, "/users?page=2&per_page=2", "", ""},
+		{"get-user", http.MethodGet, "/users/user-0001", "", ""},
+		{"get-user-not-found", http.MethodGet, "/users/user-9999", "", ""},
+		{"get-user-by-email", http.MethodGet, "/users/by-email/jane.smith@example.com", "", ""},
+		{"batch-get-users", http.MethodPost, "/users/batch-get", "", `{"ids":["user-0002","user-9999"]}`},
+		{"create-user", http.MethodPost, "/users", "", `{"name":"Ada Lovelace","email":"ada@example.com"}`},
+		{"create-user-invalid", http.MethodPost, "/users", "", `{"name":"","email":"not-an-email"}`},
+		{"create-user-duplicate", http.MethodPost, "/users", "", `{"name":"Ada","email":"ada@example.com"}`},
+		{"update-user", http.MethodPut, "/users/user-0004", `"user-0004-1"`, `{"name":"Ada King","email":"ada.king@example.com"}`},
+		{"update-user-stale", http.MethodPut, "/users/user-0004", `"user-0004-1"`, `{"name":"Ada","email":"ada@example.com"}`},
+		{"update-user-no-if-match", http.MethodPut, "/users/user-0004", "", `{"name":"Ada","email":"ada@example.com"}`},
+		{"update-user-invalid", http.MethodPut, "/users/user-0004", `"user-0004-2"`, `{"email":"not-an-email"}`},
+		{"update-user-unchanged", http.MethodPut, "/users/user-0004", `"user-0004-2"`, `{"name":"Ada King"}`},
+		{"assign-roles", http.MethodPut, "/users/user-0004/roles", `"user-0004-2"`, `{"roles":["editor"]}`},
+		{"activate-user", http.MethodPost, "/users/user-0004/activate", `"user-0004-3"`, ""},
+		{"suspend-user", http.MethodPost, "/users/user-0004/suspend", `"user-0004-4"`, ""},
+		{"delete-user", http.MethodDelete, "/users/user-0004", `"user-0004-5"`, ""},
+		{"method-not-allowed", http.MethodPatch, "/users", "", ""},
+		{"options", http.MethodOptions, "/users/user-0001", "", ""},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+			if step.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if step.ifMatch != "" {
+				req.Header.Set("If-Match", step.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			golden.AssertResponse(t, "api/"+step.name+".json", rec.Result(), goldenHeaders...)
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/graphql-go/graphql"
+)
+
+// subscriptionBufferSize is how many events a slow subscription may lag
+// behind before further events are dropped for it
+const subscriptionBufferSize = 16
+
+// GraphQLRequest represents a GraphQL-over-HTTP request
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLHandler serves the /graphql endpoint. Queries and mutations resolve
+// against the same UserService as the REST API; subscriptions stream user
+// events from the EventBus as server-sent events.
+type GraphQLHandler struct {
+	schema     graphql.Schema
+	service    UserService
+	bus        *EventBus
+	authorizer *Authorizer
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler. A nil authorizer disables
+// permission checks, mirroring the REST API when authentication is off.
+func NewGraphQLHandler(service UserService, bus *EventBus, authorizer *Authorizer) (*GraphQLHandler, error) {
+	h := &GraphQLHandler{
+		service:    service,
+		bus:        bus,
+		authorizer: authorizer,
+		done:       make(chan struct{}),
+	}
+
+	schema, err := h.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// Close ends all active subscription streams, e.g. during shutdown
+func (h *GraphQLHandler) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// ServeHTTP implements http.Handler for GET and POST GraphQL requests
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeErrorMessage(w, r, http.StatusBadRequest, "invalid variables")
+				return
+			}
+		}
+	case http.MethodPost:
+		if !decodeJSONBody(w, r, &req, false) {
+			return
+		}
+	default:
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if req.Query == "" {
+		writeErrorMessage(w, r, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	params := graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        r.Context(),
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.serveSubscription(w, r, params)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, graphql.Do(params))
+}
+
+// serveSubscription streams results following the GraphQL over SSE protocol:
+// each result is a "next" event and the stream ends with a "complete" event
+func (h *GraphQLHandler) serveSubscription(w http.ResponseWriter, r *http.Request, params graphql.Params) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	params.Context = ctx
+
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's WriteTimeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		slog.ErrorContext(r.Context(), "Error clearing write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	for result := range graphql.Subscribe(params) {
+		data, err := json.Marshal(result)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error encoding subscription result", "error", err)
+			continue
+		}
+		fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+		rc.Flush()
+	}
+
+	fmt.Fprint(w, "event: complete\ndata:\n\n")
+	rc.Flush()
+}
+
+// serviceFor returns the service traced as part of the resolver context ctx
+func (h *GraphQLHandler) serviceFor(ctx context.Context) UserService {
+	return TraceUserService(ctx, h.service)
+}
+
+// authorize checks a permission when authorization is enabled
+func (h *GraphQLHandler) authorize(ctx context.Context, permission Permission) error {
+	if h.authorizer == nil {
+		return nil
+	}
+	return toGraphQLError(h.authorizer.Authorize(ctx, permission))
+}
+
+// changeStatusField builds a mutation moving a user to status
+func (h *GraphQLHandler) changeStatusField(userType *graphql.Object, status UserStatus, expectedVersionArg *graphql.ArgumentConfig) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(userType),
+		Args: graphql.FieldConfigArgument{
+			"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			"expectedVersion": expectedVersionArg,
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if err := h.authorize(p.Context, PermissionUsersSetStatus); err != nil {
+				return nil, err
+			}
+			user, err := h.serviceFor(p.Context).ChangeStatus(p.Args["id"].(string), status, expectedVersion(p.Args))
+			return user, toGraphQLError(err)
+		},
+	}
+}
+
+// buildSchema defines the GraphQL schema and its resolvers
+func (h *GraphQLHandler) buildSchema() (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"avatarUrl": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if url := p.Source.(*User).AvatarURL; url != "" {
+						return url, nil
+					}
+					return nil, nil
+				},
+			},
+			"roles":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"status":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*User).CreatedAt, nil
+				},
+			},
+			"updatedAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*User).UpdatedAt, nil
+				},
+			},
+		},
+	})
+
+	userEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserEvent",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"type": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"time": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"user": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var data UserEventData
+					if err := p.Source.(events.Event).Decode(&data); err != nil {
+						return nil, fmt.Errorf("event has no user payload: %w", err)
+					}
+					return &data.User, nil
+				},
+			},
+		},
+	})
+
+	expectedVersionArg := &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Only apply the change if the user is still at this version",
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersRead); err != nil {
+						return nil, err
+					}
+					users, err := h.serviceFor(p.Context).GetUsers()
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+					result := make([]*User, len(users))
+					for i := range users {
+						result[i] = &users[i]
+					}
+					return result, nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersRead); err != nil {
+						return nil, err
+					}
+					user, err := h.serviceFor(p.Context).GetUserByID(p.Args["id"].(string))
+					if appErr, ok := IsAppError(err); ok && appErr.Type == ErrorTypeNotFound {
+						return nil, nil
+					}
+					return user, toGraphQLError(err)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"name":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersCreate); err != nil {
+						return nil, err
+					}
+					user, err := h.serviceFor(p.Context).CreateUser(p.Args["name"].(string), p.Args["email"].(string))
+					return user, toGraphQLError(err)
+				},
+			},
+			"updateUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"name":            &graphql.ArgumentConfig{Type: graphql.String},
+					"email":           &graphql.ArgumentConfig{Type: graphql.String},
+					"expectedVersion": expectedVersionArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersUpdate); err != nil {
+						return nil, err
+					}
+					name, _ := p.Args["name"].(string)
+					email, _ := p.Args["email"].(string)
+					user, err := h.serviceFor(p.Context).UpdateUser(p.Args["id"].(string), name, email, expectedVersion(p.Args))
+					return user, toGraphQLError(err)
+				},
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"expectedVersion": expectedVersionArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersDelete); err != nil {
+						return nil, err
+					}
+					if err := h.serviceFor(p.Context).DeleteUser(p.Args["id"].(string), expectedVersion(p.Args)); err != nil {
+						return nil, toGraphQLError(err)
+					}
+					return true, nil
+				},
+			},
+			"assignRoles": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"roles":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+					"expectedVersion": expectedVersionArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersAssignRoles); err != nil {
+						return nil, err
+					}
+					var roles []Role
+					for _, role := range p.Args["roles"].([]interface{}) {
+						roles = append(roles, Role(role.(string)))
+					}
+					user, err := h.serviceFor(p.Context).AssignRoles(p.Args["id"].(string), roles, expectedVersion(p.Args))
+					return user, toGraphQLError(err)
+				},
+			},
+			"activateUser": h.changeStatusField(userType, UserStatusActive, expectedVersionArg),
+			"suspendUser":  h.changeStatusField(userType, UserStatusSuspended, expectedVersionArg),
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"userEvents": &graphql.Field{
+				Type:        graphql.NewNonNull(userEventType),
+				Description: "Streams user events, optionally filtered by event type",
+				Args: graphql.FieldConfigArgument{
+					"types": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := h.authorize(p.Context, PermissionUsersRead); err != nil {
+						return nil, err
+					}
+					return h.subscribeUserEvents(p.Context, eventTypesArg(p.Args)), nil
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
+
+// subscribeUserEvents forwards matching bus events of the tenant in ctx to a
+// channel until ctx ends. Events are dropped for a subscriber that falls too far behind rather than
+// blocking the publisher.
+func (h *GraphQLHandler) subscribeUserEvents(ctx context.Context, types []string) chan interface{} {
+	received := make(chan interface{}, subscriptionBufferSize)
+	tenant := TenantFromContext(ctx)
+
+	unsubscribe := h.bus.Subscribe(func(_ context.Context, event events.Event) error {
+		if event.Tenant != tenant || len(types) > 0 && !slices.Contains(types, event.Type) {
+			return nil
+		}
+		select {
+		case received <- event:
+		default:
+			slog.WarnContext(ctx, "Dropping event for slow GraphQL subscriber", "event_type", event.Type, "event_id", event.ID)
+		}
+		return nil
+	}, "user.*")
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+	}()
+
+	return received
+}
+
+// expectedVersion reads the optional expectedVersion argument
+func expectedVersion(args map[string]interface{}) int64 {
+	if version, ok := args["expectedVersion"].(int); ok {
+		return int64(version)
+	}
+	return 0
+}
+
+// eventTypesArg reads the optional types filter of the userEvents subscription
+func eventTypesArg(args map[string]interface{}) []string {
+	raw, _ := args["types"].([]interface{})
+	types := make([]string, 0, len(raw))
+	for _, t := range raw {
+		types = append(types, t.(string))
+	}
+	return types
+}
+
+// graphQLError exposes an AppError's type and field as GraphQL error extensions
+type graphQLError struct {
+	*AppError
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e graphQLError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"type": e.Type}
+	if e.Field != "" {
+		extensions["field"] = e.Field
+	}
+	for key, value := range e.Details {
+		extensions[key] = value
+	}
+	if len(e.Errors) > 0 {
+		extensions["errors"] = e.Errors
+	}
+	return extensions
+}
+
+// toGraphQLError converts service errors so clients receive structured extensions
+func toGraphQLError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := IsAppError(err); ok {
+		return graphQLError{appErr}
+	}
+	slog.Error("Unexpected error", "error", err)
+	return fmt.Errorf("internal server error")
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// doGraphQL executes a GraphQL request against the handler and decodes the result
+func doGraphQL(t *testing.T, handler http.Handler, query string, variables map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	body, _ := json.Marshal(GraphQLRequest{Query: query, Variables: variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return result
+}
+
+func TestGraphQLHandler_QueriesAndMutations(t *testing.T) {
+	service := NewInMemoryUserService(WithEventPublisher(NewEventBus()))
+	handler, err := NewGraphQLHandler(service, NewEventBus(), nil)
+	if err != nil {
+		t.Fatalf("NewGraphQLHandler() error = %v", err)
+	}
+
+	created := doGraphQL(t, handler, `mutation($name: String!, $email: String!) {
+		createUser(name: $name, email: $email) { id name email version roles }
+	}`, map[string]interface{}{"name": "Grace Hopper", "email": "grace@example.com"})
+	if created["errors"] != nil {
+		t.Fatalf("createUser returned errors: %v", created["errors"])
+	}
+	user := created["data"].(map[string]interface{})["createUser"].(map[string]interface{})
+	if user["email"] != "grace@example.com" || user["version"] != float64(1) {
+		t.Errorf("createUser returned unexpected user: %v", user)
+	}
+
+	fetched := doGraphQL(t, handler, `query($id: ID!) { user(id: $id) { name createdAt } }`,
+		map[string]interface{}{"id": user["id"]})
+	if got := fetched["data"].(map[string]interface{})["user"].(map[string]interface{})["name"]; got != "Grace Hopper" {
+		t.Errorf("user query name = %v, want Grace Hopper", got)
+	}
+
+	listed := doGraphQL(t, handler, `{ users { id } }`, nil)
+	if users := listed["data"].(map[string]interface{})["users"].([]interface{}); len(users) != 4 {
+		t.Errorf("users query returned %d users, want 4", len(users))
+	}
+
+	stale := doGraphQL(t, handler, `mutation($id: ID!) { deleteUser(id: $id, expectedVersion: 7) }`,
+		map[string]interface{}{"id": user["id"]})
+	errs, _ := stale["errors"].([]interface{})
+	if len(errs) != 1 {
+		t.Fatalf("deleteUser with stale version should fail, got %v", stale)
+	}
+	extensions := errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	if extensions["type"] != string(ErrorTypePreconditionFailed) {
+		t.Errorf("error extensions type = %v, want %v", extensions["type"], ErrorTypePreconditionFailed)
+	}
+}
+
+func TestGraphQLHandler_Authorization(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler, err := NewGraphQLHandler(service, NewEventBus(), NewAuthorizer(SingleService(service)))
+	if err != nil {
+		t.Fatalf("NewGraphQLHandler() error = %v", err)
+	}
+
+	result := doGraphQL(t, handler, `mutation { createUser(name: "Anon", email: "anon@example.com") { id } }`, nil)
+	errs, _ := result["errors"].([]interface{})
+	if len(errs) != 1 {
+		t.Fatalf("anonymous createUser should be forbidden, got %v", result)
+	}
+	extensions := errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	if extensions["permission"] != string(PermissionUsersCreate) {
+		t.Errorf("missing permission = %v, want %v", extensions["permission"], PermissionUsersCreate)
+	}
+}
+
+func TestGraphQLHandler_Subscription(t *testing.T) {
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+	handler, err := NewGraphQLHandler(service, bus, nil)
+	if err != nil {
+		t.Fatalf("NewGraphQLHandler() error = %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	existing, err := service.CreateUser("Filtered User", "filtered@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	body := `{"query":"subscription { userEvents(types: [\"user.created\"]) { type user { email } } }"}`
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscription request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// Publish once the subscription is registered on the bus
+	go func() {
+		for {
+			if bus.subscribers.Load() > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		// Filtered out by the types argument
+		service.UpdateUser(existing.ID, "Filtered Renamed", "renamed@example.com", 0)
+		service.CreateUser("Streamed User", "streamed@example.com")
+	}()
+
+	var events []string
+	var completed bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "event: complete" {
+			completed = true
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, data)
+			// Closing the handler ends the stream with a complete event
+			handler.Close()
+		}
+	}
+
+	if !completed {
+		t.Error("stream should end with a complete event")
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected exactly one streamed event, got %d: %v", len(events), events)
+	}
+	if !strings.Contains(events[0], `"type":"user.created"`) || !strings.Contains(events[0], "streamed@example.com") {
+		t.Errorf("unexpected event payload: %s", events[0])
+	}
+}
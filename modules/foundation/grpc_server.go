@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userv1 "github.com/captain-corgi/learning-event-driven/modules/foundation/proto/user/v1"
+)
+
+// grpcMetrics counts calls and accumulated latency per method and status code
+var grpcMetrics = struct {
+	calls     *expvar.Map
+	latencyMs *expvar.Map
+}{
+	calls:     expvar.NewMap("grpc_calls_total"),
+	latencyMs: expvar.NewMap("grpc_latency_ms_total"),
+}
+
+// grpcMethodPermissions maps each RPC to the permission it requires
+var grpcMethodPermissions = map[string]Permission{
+	userv1.UserService_ListUsers_FullMethodName:  PermissionUsersRead,
+	userv1.UserService_GetUser_FullMethodName:    PermissionUsersRead,
+	userv1.UserService_CreateUser_FullMethodName: PermissionUsersCreate,
+	userv1.UserService_UpdateUser_FullMethodName: PermissionUsersUpdate,
+	userv1.UserService_DeleteUser_FullMethodName: PermissionUsersDelete,
+}
+
+// GRPCUserServer implements the gRPC UserService on top of the same
+// UserService used by the HTTP handlers, resolved per tenant
+type GRPCUserServer struct {
+	userv1.UnimplementedUserServiceServer
+	services ServiceResolver
+}
+
+// NewGRPCUserServer creates a new GRPCUserServer
+func NewGRPCUserServer(services ServiceResolver) *GRPCUserServer {
+	return &GRPCUserServer{
+		services: services,
+	}
+}
+
+// NewGRPCServer creates a grpc.Server with the UserService registered and the
+// logging, metrics, tenant and (when configured) authentication interceptors
+// chained. Calls for tenants not passing check are not found.
+func NewGRPCServer(services ServiceResolver, check TenantCheck, validator *JWTValidator, authorizer *Authorizer) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{
+		loggingUnaryInterceptor,
+		metricsUnaryInterceptor,
+		tenantUnaryInterceptor(check),
+	}
+	if validator != nil && authorizer != nil {
+		interceptors = append(interceptors, authUnaryInterceptor(validator, authorizer))
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	userv1.RegisterUserServiceServer(server, NewGRPCUserServer(services))
+	return server
+}
+
+// ListUsers returns all users
+func (s *GRPCUserServer) ListUsers(ctx context.Context, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	users, err := s.services(ctx).GetUsers()
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	resp := &userv1.ListUsersResponse{Users: make([]*userv1.User, 0, len(users))}
+	for i := range users {
+		resp.Users = append(resp.Users, toProtoUser(&users[i]))
+	}
+	return resp, nil
+}
+
+// GetUser returns a user by ID
+func (s *GRPCUserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	user, err := s.services(ctx).GetUserByID(req.GetId())
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &userv1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+// CreateUser creates a new user
+func (s *GRPCUserServer) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.CreateUserResponse, error) {
+	user, err := s.services(ctx).CreateUser(req.GetName(), req.GetEmail())
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &userv1.CreateUserResponse{User: toProtoUser(user)}, nil
+}
+
+// UpdateUser updates an existing user
+func (s *GRPCUserServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.UpdateUserResponse, error) {
+	if req.Name == nil && req.Email == nil {
+		return nil, status.Error(codes.InvalidArgument, "no fields to update")
+	}
+
+	user, err := s.services(ctx).UpdateUser(req.GetId(), req.GetName(), req.GetEmail(), req.GetExpectedVersion())
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &userv1.UpdateUserResponse{User: toProtoUser(user)}, nil
+}
+
+// DeleteUser deletes a user by ID
+func (s *GRPCUserServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	if err := s.services(ctx).DeleteUser(req.GetId(), req.GetExpectedVersion()); err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+// toProtoUser converts a User to its protobuf representation
+func toProtoUser(user *User) *userv1.User {
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = string(role)
+	}
+
+	return &userv1.User{
+		Id:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Roles:     roles,
+		Status:    string(user.Status),
+		Version:   user.Version,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+}
+
+// GRPCCode returns the gRPC status code matching the error type
+func (e *AppError) GRPCCode() codes.Code {
+	switch e.Type {
+	case ErrorTypeValidation:
+		return codes.InvalidArgument
+	case ErrorTypeNotFound:
+		return codes.NotFound
+	case ErrorTypeConflict:
+		return codes.AlreadyExists
+	case ErrorTypePreconditionFailed:
+		return codes.FailedPrecondition
+	case ErrorTypeUnauthorized:
+		return codes.Unauthenticated
+	case ErrorTypeForbidden:
+		return codes.PermissionDenied
+	case ErrorTypePayloadTooLarge:
+		return codes.ResourceExhausted
+	case ErrorTypeTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// toGRPCError converts service errors into gRPC status errors
+func toGRPCError(err error) error {
+	if appErr, ok := IsAppError(err); ok {
+		st := status.New(appErr.GRPCCode(), appErr.Message)
+		if len(appErr.Errors) == 0 {
+			return st.Err()
+		}
+
+		// Report every invalid field as a BadRequest field violation
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range appErr.Errors {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field,
+				Description: fieldErr.Message,
+			})
+		}
+		if detailed, detailsErr := st.WithDetails(badRequest); detailsErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	}
+
+	slog.Error("Unexpected error", "error", err)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// loggingUnaryInterceptor logs every RPC with its status code and duration
+func loggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	slog.InfoContext(ctx, "gRPC request",
+		"method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start))
+	return resp, err
+}
+
+// metricsUnaryInterceptor records call counts and latency per method and code
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	key := info.FullMethod + " " + status.Code(err).String()
+	grpcMetrics.calls.Add(key, 1)
+	grpcMetrics.latencyMs.AddFloat(key, float64(time.Since(start).Microseconds())/1000)
+	return resp, err
+}
+
+// authUnaryInterceptor validates the bearer token from the "authorization"
+// metadata and enforces the permission of the called method. As with the
+// HTTP API, reads may be anonymous while mutating RPCs require a valid token.
+func authUnaryInterceptor(validator *JWTValidator, authorizer *Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		permission, ok := grpcMethodPermissions[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "method is not exposed")
+		}
+
+		token, found := grpcBearerToken(ctx)
+		if found {
+			claims, err := validator.Validate(token)
+			if err != nil {
+				return nil, toGRPCError(err)
+			}
+			ctx = ContextWithClaims(ctx, claims)
+		} else if permission != PermissionUsersRead {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		if err := authorizer.Authorize(ctx, permission); err != nil {
+			return nil, toGRPCError(err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcBearerToken extracts the token from the "authorization" metadata
+func grpcBearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// stopGRPCServer stops accepting RPCs and waits for pending ones to finish,
+// forcing the stop when ctx expires first
+func stopGRPCServer(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
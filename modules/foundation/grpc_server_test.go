@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	userv1 "github.com/captain-corgi/learning-event-driven/modules/foundation/proto/user/v1"
+)
+
+// newGRPCTestClient serves server over an in-memory listener and returns a client for it
+func newGRPCTestClient(t *testing.T, server *grpc.Server) userv1.UserServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create gRPC client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+func TestGRPCUserServer_CRUD(t *testing.T) {
+	client := newGRPCTestClient(t, NewGRPCServer(SingleService(NewInMemoryUserService()), AllowTenants(), nil, nil))
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "Ada Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	user := created.GetUser()
+	if user.GetVersion() != 1 || user.GetCreatedAt() == nil {
+		t.Errorf("CreateUser() returned unexpected user: %v", user)
+	}
+
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{Email: "not-an-email"})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("invalid CreateUser() code = %v, want %v", got, codes.InvalidArgument)
+	}
+	var violations int
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations += len(badRequest.GetFieldViolations())
+		}
+	}
+	if violations != 2 {
+		t.Errorf("invalid CreateUser() reported %d field violations, want 2", violations)
+	}
+
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "Ada Again", Email: "ada@example.com"})
+	if got := status.Code(err); got != codes.AlreadyExists {
+		t.Errorf("duplicate CreateUser() code = %v, want %v", got, codes.AlreadyExists)
+	}
+
+	updated, err := client.UpdateUser(ctx, &userv1.UpdateUserRequest{
+		Id:              user.GetId(),
+		Name:            proto.String("Ada King"),
+		Email:           proto.String("ada.king@example.com"),
+		ExpectedVersion: user.GetVersion(),
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if updated.GetUser().GetName() != "Ada King" || updated.GetUser().GetVersion() != 2 {
+		t.Errorf("UpdateUser() returned unexpected user: %v", updated.GetUser())
+	}
+
+	fetched, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: user.GetId()})
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if fetched.GetUser().GetEmail() != "ada.king@example.com" {
+		t.Errorf("GetUser() email = %q, want %q", fetched.GetUser().GetEmail(), "ada.king@example.com")
+	}
+
+	listed, err := client.ListUsers(ctx, &userv1.ListUsersRequest{})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(listed.GetUsers()) != 4 {
+		t.Errorf("ListUsers() returned %d users, want 4", len(listed.GetUsers()))
+	}
+
+	_, err = client.DeleteUser(ctx, &userv1.DeleteUserRequest{Id: user.GetId(), ExpectedVersion: 1})
+	if got := status.Code(err); got != codes.FailedPrecondition {
+		t.Errorf("stale DeleteUser() code = %v, want %v", got, codes.FailedPrecondition)
+	}
+
+	if _, err := client.DeleteUser(ctx, &userv1.DeleteUserRequest{Id: user.GetId()}); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{Id: user.GetId()})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("GetUser() after delete code = %v, want %v", got, codes.NotFound)
+	}
+}
+
+func TestGRPCUserServer_Authentication(t *testing.T) {
+	secret := []byte("test-secret")
+	service := NewInMemoryUserService()
+	validator := NewJWTValidator(JWTConfig{HMACSecret: secret})
+	client := newGRPCTestClient(t, NewGRPCServer(SingleService(service), AllowTenants(), validator, NewAuthorizer(SingleService(service))))
+
+	expires := time.Now().Add(time.Hour).Unix()
+	viewerToken := signToken(t, "HS256", secret, map[string]interface{}{"sub": "viewer", "exp": expires})
+	editorToken := signToken(t, "HS256", secret, map[string]interface{}{"sub": "editor", "exp": expires, "roles": []string{"editor"}})
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	create := &userv1.CreateUserRequest{Name: "Grace Hopper", Email: "grace@example.com"}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		call     func(ctx context.Context) error
+		expected codes.Code
+	}{
+		{"anonymous read", context.Background(), func(ctx context.Context) error {
+			_, err := client.ListUsers(ctx, &userv1.ListUsersRequest{})
+			return err
+		}, codes.OK},
+		{"anonymous create", context.Background(), func(ctx context.Context) error {
+			_, err := client.CreateUser(ctx, create)
+			return err
+		}, codes.Unauthenticated},
+		{"invalid token", withToken("not-a-token"), func(ctx context.Context) error {
+			_, err := client.ListUsers(ctx, &userv1.ListUsersRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"viewer create", withToken(viewerToken), func(ctx context.Context) error {
+			_, err := client.CreateUser(ctx, create)
+			return err
+		}, codes.PermissionDenied},
+		{"editor create", withToken(editorToken), func(ctx context.Context) error {
+			_, err := client.CreateUser(ctx, create)
+			return err
+		}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(tt.ctx)); got != tt.expected {
+				t.Errorf("call returned wrong code: got %v want %v", got, tt.expected)
+			}
+		})
+	}
+}
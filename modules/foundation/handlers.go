@@ -2,103 +2,280 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 )
 
 // UserHandler handles HTTP requests for user operations
 type UserHandler struct {
-	service UserService
+	service  UserService
+	encoders *EncoderRegistry
+	logger   *slog.Logger
+	reporter ErrorReporter
+	avatars  *AvatarStore
+	mux      *http.ServeMux
+}
+
+// methodOrder is the order in which methods are listed in Allow headers
+var methodOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// UserHandlerOption configures optional dependencies of the UserHandler
+type UserHandlerOption func(*UserHandler)
+
+// WithEncoders sets the registry used to negotiate the response format
+func WithEncoders(encoders *EncoderRegistry) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.encoders = encoders
+	}
+}
+
+// WithHandlerLogger sets the logger reporting responses that could not be written
+func WithHandlerLogger(logger *slog.Logger) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.logger = logger
+	}
+}
+
+// WithErrorReporter sets the reporter receiving unexpected errors
+func WithErrorReporter(reporter ErrorReporter) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.reporter = reporter
+	}
+}
+
+// WithAvatars enables avatar uploads, kept in avatars
+func WithAvatars(avatars *AvatarStore) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.avatars = avatars
+	}
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(service UserService) *UserHandler {
-	return &UserHandler{
-		service: service,
+func NewUserHandler(service UserService, opts ...UserHandlerOption) *UserHandler {
+	h := &UserHandler{
+		service:  service,
+		encoders: DefaultEncoderRegistry(),
+		logger:   slog.Default(),
+		reporter: noopReporter{},
+		mux:      http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	// The collection is also served with a trailing slash
+	for _, collection := range []string{"/users", "/users/{$}"} {
+		h.handle("GET "+collection, h.handleGetUsers)
+		h.handle("POST "+collection, h.handleCreateUser)
+	}
+	h.handle("POST /users/batch-get", h.handleBatchGetUsers)
+	h.handle("GET /users/by-email/{email}", h.handleGetUserByEmail)
+	h.handle("GET /users/{id}", h.handleGetUser)
+	h.handle("PUT /users/{id}", h.handleUpdateUser)
+	h.handle("DELETE /users/{id}", h.handleDeleteUser)
+	h.handle("PUT /users/{id}/roles", h.handleAssignRoles)
+	h.handle("POST /users/{id}/change-password", h.handleChangePassword)
+	h.handle("POST /users/{id}/activate", func(w http.ResponseWriter, r *http.Request) {
+		h.handleChangeStatus(w, r, UserStatusActive)
+	})
+	h.handle("POST /users/{id}/suspend", func(w http.ResponseWriter, r *http.Request) {
+		h.handleChangeStatus(w, r, UserStatusSuspended)
+	})
+	if h.avatars != nil {
+		h.handle("POST /users/{id}/avatar", h.handleUploadAvatar)
+	}
+	return h
+}
+
+// handle registers fn for pattern, which must be a route of
+// userRoutePermissions so it is authorized like it is routed
+func (h *UserHandler) handle(pattern string, fn http.HandlerFunc) {
+	if _, ok := userRoutePermissions[pattern]; !ok {
+		panic(fmt.Sprintf("route %q has no permission in userRoutePermissions", pattern))
 	}
+	h.mux.HandleFunc(pattern, fn)
 }
 
 // ServeHTTP implements http.Handler interface for routing
 func (h *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+
+	// OPTIONS and unsupported methods are answered from the registered routes,
+	// with the same error shape as every other response
+	if _, pattern := h.mux.Handler(r); pattern == "" || r.Method == http.MethodOptions {
+		allowed := h.allowedMethods(r)
+		if len(allowed) == 0 {
+			h.writeErrorResponse(w, r, http.StatusNotFound, "endpoint not found")
+			return
+		}
 
-	// Parse the path
-	path := strings.TrimPrefix(r.URL.Path, "/users")
-
-	switch {
-	case path == "" || path == "/":
-		switch r.Method {
-		case http.MethodGet:
-			h.handleGetUsers(w, r)
-		case http.MethodPost:
-			h.handleCreateUser(w, r)
-		default:
-			h.writeErrorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
 		}
-	case strings.HasPrefix(path, "/"):
-		userID := strings.TrimPrefix(path, "/")
-		switch r.Method {
-		case http.MethodGet:
-			h.handleGetUser(w, r, userID)
-		case http.MethodPut:
-			h.handleUpdateUser(w, r, userID)
-		case http.MethodDelete:
-			h.handleDeleteUser(w, r, userID)
-		default:
-			h.writeErrorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		h.writeErrorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Reject unsupported formats before any change is applied
+	if _, ok := h.encoders.Negotiate(r.Header.Get("Accept")); !ok {
+		h.writeErrorResponse(w, r, http.StatusNotAcceptable, "supported media types: "+strings.Join(h.encoders.ContentTypes(), ", "))
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// allowedMethods returns the methods registered for the path of r. HEAD is
+// served by GET routes and is not listed on its own.
+func (h *UserHandler) allowedMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range methodOrder {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := h.mux.Handler(probe); strings.HasPrefix(pattern, method+" ") {
+			allowed = append(allowed, method)
 		}
-	default:
-		h.writeErrorResponse(w, http.StatusNotFound, "endpoint not found")
 	}
+	return allowed
 }
 
-// handleGetUsers handles GET /users
+// handleGetUsers handles GET /users?page=&per_page=
 func (h *UserHandler) handleGetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.service.GetUsers()
+	page, err := parsePage(r.URL.Query())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	users, err := h.serviceFor(r).GetUsers()
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, users)
+	if h.notModified(w, r, h.serviceFor(r).LastModified()) {
+		return
+	}
+
+	writePaginationHeaders(w, r, page, len(users))
+	start, end := page.bounds(len(users))
+	h.writeResponse(w, r, http.StatusOK, UserList(users[start:end]))
 }
 
 // handleGetUser handles GET /users/{id}
-func (h *UserHandler) handleGetUser(w http.ResponseWriter, r *http.Request, userID string) {
-	user, err := h.service.GetUserByID(userID)
+func (h *UserHandler) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.serviceFor(r).GetUserByID(r.PathValue("id"))
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, user)
+	w.Header().Set("ETag", user.ETag())
+	if h.notModified(w, r, user.UpdatedAt) {
+		return
+	}
+	h.writeResponse(w, r, http.StatusOK, user)
+}
+
+// maxBatchGetIDs caps the number of IDs a batch get may request
+const maxBatchGetIDs = 100
+
+// BatchGetUsersRequest represents the request body for getting several users at once
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchGetUsersResponse lists the users found by a batch get and the IDs that do not exist
+type BatchGetUsersResponse struct {
+	XMLName xml.Name `json:"-" xml:"batch"`
+	Users   UserList `json:"users" xml:"users"`
+	Missing []string `json:"missing" xml:"missing>id"`
+}
+
+// handleBatchGetUsers handles POST /users/batch-get
+func (h *UserHandler) handleBatchGetUsers(w http.ResponseWriter, r *http.Request) {
+	var req BatchGetUsersRequest
+	if !decodeJSONBody(w, r, &req, true) {
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		h.handleError(w, r, NewValidationError("ids", "at least one ID is required"))
+		return
+	}
+	if len(req.IDs) > maxBatchGetIDs {
+		h.handleError(w, r, NewValidationError("ids", fmt.Sprintf("at most %d IDs can be requested at once", maxBatchGetIDs)))
+		return
+	}
+
+	// Each ID is looked up once, keeping the order of first appearance
+	ids := make([]string, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	users, missing, err := h.serviceFor(r).GetUsersByIDs(ids)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.writeResponse(w, r, http.StatusOK, BatchGetUsersResponse{Users: users, Missing: missing})
+}
+
+// handleGetUserByEmail handles GET /users/by-email/{email}
+func (h *UserHandler) handleGetUserByEmail(w http.ResponseWriter, r *http.Request) {
+	user, err := h.serviceFor(r).GetUserByEmail(r.PathValue("email"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", user.ETag())
+	h.writeResponse(w, r, http.StatusOK, user)
 }
 
 // CreateUserRequest represents the request body for creating a user
 type CreateUserRequest struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	// Password is optional; users without one cannot log in with POST /login
+	Password string `json:"password,omitempty"`
 }
 
 // handleCreateUser handles POST /users
 func (h *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	var req CreateUserRequest
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "invalid JSON body")
+	if !decodeJSONBody(w, r, &req, true) {
 		return
 	}
 
-	user, err := h.service.CreateUser(req.Name, req.Email)
+	user, err := h.serviceFor(r).RegisterUser(req.Name, req.Email, req.Password)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusCreated, user)
+	w.Header().Set("ETag", user.ETag())
+	h.writeResponse(w, r, http.StatusCreated, user)
 }
 
 // UpdateUserRequest represents the request body for updating a user
@@ -108,79 +285,290 @@ type UpdateUserRequest struct {
 }
 
 // handleUpdateUser handles PUT /users/{id}
-func (h *UserHandler) handleUpdateUser(w http.ResponseWriter, r *http.Request, userID string) {
+func (h *UserHandler) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	version, ok := h.resolveIfMatch(w, r, userID)
+	if !ok {
+		return
+	}
+
 	var req UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "invalid JSON body")
+	if !decodeJSONBody(w, r, &req, false) {
 		return
 	}
 
 	if req.Name == nil && req.Email == nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "no fields to update")
+		h.writeErrorResponse(w, r, http.StatusBadRequest, "no fields to update")
 		return
 	}
 
+	// The service keeps the fields given empty, so clearing one is refused here
 	var name, email string
+	var errs ValidationErrors
 	if req.Name != nil {
-		name = *req.Name
+		if name = *req.Name; name == "" {
+			errs.Add("name", "name cannot be empty")
+		}
 	}
 	if req.Email != nil {
-		email = *req.Email
+		if email = *req.Email; email == "" {
+			errs.Add("email", "email cannot be empty")
+		}
+	}
+	if err := errs.Err(); err != nil {
+		h.handleError(w, r, err)
+		return
 	}
 
-	user, err := h.service.UpdateUser(userID, name, email)
+	user, err := h.serviceFor(r).UpdateUser(userID, name, email, version)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, user)
+	w.Header().Set("ETag", user.ETag())
+	h.writeResponse(w, r, http.StatusOK, user)
 }
 
 // handleDeleteUser handles DELETE /users/{id}
-func (h *UserHandler) handleDeleteUser(w http.ResponseWriter, r *http.Request, userID string) {
-	err := h.service.DeleteUser(userID)
+func (h *UserHandler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	version, ok := h.resolveIfMatch(w, r, userID)
+	if !ok {
+		return
+	}
+
+	err := h.serviceFor(r).DeleteUser(userID, version)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// handleError handles application errors and writes appropriate HTTP responses
-func (h *UserHandler) handleError(w http.ResponseWriter, err error) {
-	if appErr, ok := IsAppError(err); ok {
-		h.writeJSONResponse(w, appErr.HTTPStatusCode(), map[string]interface{}{
-			"error": map[string]interface{}{
-				"type":    appErr.Type,
-				"message": appErr.Message,
-				"field":   appErr.Field,
-			},
+// AssignRolesRequest represents the request body for assigning roles to a user
+type AssignRolesRequest struct {
+	Roles []Role `json:"roles"`
+}
+
+// handleAssignRoles handles PUT /users/{id}/roles
+func (h *UserHandler) handleAssignRoles(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	version, ok := h.resolveIfMatch(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req AssignRolesRequest
+	if !decodeJSONBody(w, r, &req, false) {
+		return
+	}
+
+	user, err := h.serviceFor(r).AssignRoles(userID, req.Roles, version)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", user.ETag())
+	h.writeResponse(w, r, http.StatusOK, user)
+}
+
+// handleChangeStatus handles POST /users/{id}/activate and POST /users/{id}/suspend
+func (h *UserHandler) handleChangeStatus(w http.ResponseWriter, r *http.Request, status UserStatus) {
+	userID := r.PathValue("id")
+	version, ok := h.resolveIfMatch(w, r, userID)
+	if !ok {
+		return
+	}
+
+	user, err := h.serviceFor(r).ChangeStatus(userID, status, version)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", user.ETag())
+	h.writeResponse(w, r, http.StatusOK, user)
+}
+
+// ChangePasswordRequest represents the request body for changing a password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// handleChangePassword handles POST /users/{id}/change-password. An
+// authenticated caller may only change their own password.
+func (h *UserHandler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Subject != userID {
+		h.handleError(w, r, &AppError{Type: ErrorTypeForbidden, Message: "only the user may change their password"})
+		return
+	}
+
+	version, ok := h.resolveIfMatch(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !decodeJSONBody(w, r, &req, true) {
+		return
+	}
+
+	user, err := h.serviceFor(r).ChangePassword(userID, req.CurrentPassword, req.NewPassword, version)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", user.ETag())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveIfMatch evaluates the If-Match precondition of a mutating request and
+// returns the user version the service must still find when applying the change.
+// "*" only requires the user to exist, so it resolves to version zero.
+// It writes the error response itself and returns false when the request must stop.
+func (h *UserHandler) resolveIfMatch(w http.ResponseWriter, r *http.Request, userID string) (int64, bool) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		h.writeErrorResponse(w, r, http.StatusPreconditionRequired, "If-Match header is required")
+		return 0, false
+	}
+	if strings.TrimSpace(ifMatch) == "*" {
+		return 0, true
+	}
+
+	user, err := h.serviceFor(r).GetUserByID(userID)
+	if err != nil {
+		h.handleError(w, r, err)
+		return 0, false
+	}
+
+	current := user.ETag()
+	for _, tag := range strings.Split(ifMatch, ",") {
+		// If-Match uses strong comparison, so weak tags never match
+		if strings.TrimSpace(tag) == current {
+			return user.Version, true
+		}
+	}
+
+	h.handleError(w, r, NewPreconditionFailedError("If-Match does not match the current ETag"))
+	return 0, false
+}
+
+// notModified sets Last-Modified and answers 304 Not Modified when the
+// If-Modified-Since precondition shows the client's copy is still current.
+// HTTP dates have second precision, so modified is compared truncated.
+func (h *UserHandler) notModified(w http.ResponseWriter, r *http.Request, modified time.Time) bool {
+	modified = modified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.After(since) {
+		return false
+	}
+
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// handleError handles application errors and writes appropriate HTTP
+// responses. Unexpected errors are also sent to the error reporter.
+func (h *UserHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if shouldReport(err) {
+		h.reporter.Report(r.Context(), err)
+	}
+	writeError(w, r, err)
+}
+
+// writeResponse writes data in the format negotiated from the Accept header,
+// reduced to the fields listed in ?fields= when present
+func (h *UserHandler) writeResponse(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	encoder, ok := h.encoders.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		encoder = h.encoders.Default()
+	}
+
+	if fields := parseFields(r.URL.Query()); fields != nil {
+		data = sparseFieldset{data: data, fields: fields}
+	}
+
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(statusCode)
+	if err := encoder.Encode(w, data); err != nil {
+		h.logger.ErrorContext(r.Context(), "Error encoding response", "content_type", encoder.ContentType(), "error", err)
+	}
+}
+
+// serviceFor returns the service traced as part of request r
+func (h *UserHandler) serviceFor(r *http.Request) UserService {
+	return TraceUserService(r.Context(), h.service)
+}
+
+// writeErrorResponse writes a simple error response
+func (h *UserHandler) writeErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	writeErrorMessage(w, r, statusCode, message)
+}
+
+// writeError maps an error to its HTTP representation.
+// It is shared by handlers and middleware so every error has the same shape:
+// an RFC 7807 problem document, or the legacy error body when enabled.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr, ok := IsAppError(err)
+	if !ok {
+		// Log unexpected errors
+		slog.ErrorContext(r.Context(), "Unexpected error", "error", err)
+		writeErrorMessage(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if legacyErrorFormat {
+		body := map[string]interface{}{
+			"type":    appErr.Type,
+			"message": appErr.Message,
+			"field":   appErr.Field,
+		}
+		if len(appErr.Details) > 0 {
+			body["details"] = appErr.Details
+		}
+		if len(appErr.Errors) > 0 {
+			body["errors"] = appErr.Errors
+		}
+		writeJSON(w, appErr.HTTPStatusCode(), map[string]interface{}{
+			"error": body,
 		})
 		return
 	}
 
-	// Log unexpected errors
-	log.Printf("Unexpected error: %v", err)
-	h.writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
+	writeProblem(w, NewProblemFromError(appErr, r))
 }
 
-// writeJSONResponse writes a JSON response
-func (h *UserHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+		slog.Error("Error encoding JSON response", "error", err)
 	}
 }
 
-// writeErrorResponse writes a simple error response
-func (h *UserHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	h.writeJSONResponse(w, statusCode, map[string]interface{}{
-		"error": map[string]interface{}{
-			"message": message,
-		},
-	})
+// writeErrorMessage writes a simple error response
+func writeErrorMessage(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	if legacyErrorFormat {
+		writeJSON(w, statusCode, map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": message,
+			},
+		})
+		return
+	}
+
+	writeProblem(w, NewProblem(statusCode, message, r))
 }
 
 // healthHandler handles health check requests
@@ -192,7 +580,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		"version": "1.0.0",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding health response: %v", err)
+		slog.ErrorContext(r.Context(), "Error encoding health response", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
@@ -210,17 +598,35 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 		"version": "1.0.0",
 		"endpoints": map[string]interface{}{
 			"users": map[string]interface{}{
-				"GET /users":         "Get all users",
-				"POST /users":        "Create a new user",
-				"GET /users/{id}":    "Get user by ID",
-				"PUT /users/{id}":    "Update user by ID",
-				"DELETE /users/{id}": "Delete user by ID",
+				"GET /users":                       "Get all users",
+				"POST /users":                      "Create a new user",
+				"GET /users/{id}":                  "Get user by ID",
+				"GET /users/by-email/{email}":      "Get user by email",
+				"POST /users/batch-get":            "Get several users by ID",
+				"PUT /users/{id}":                  "Update user by ID",
+				"DELETE /users/{id}":               "Delete user by ID",
+				"POST /users/{id}/activate":        "Activate user by ID",
+				"POST /users/{id}/suspend":         "Suspend user by ID",
+				"POST /users/{id}/change-password": "Change the user's password",
+				"POST /users/{id}/avatar":          "Upload the user's avatar as multipart/form-data",
+			},
+			"admin": map[string]interface{}{
+				"POST /admin/seed":     "Load the demo users",
+				"POST /admin/reset":    "Remove all users",
+				"PUT /admin/log-level": "Change the log level: debug, info, warn or error",
 			},
-			"health": "GET /health - Health check",
+			"graphql": "POST /graphql - GraphQL API (subscriptions via Accept: text/event-stream)",
+			"audit":   "GET /audit - Audit log of changes (actor, action, user_id, request_id, since, until)",
+			"events":  "GET /events - Server-sent events of every tenant, for other services (types)",
+			"avatars": "GET /avatars/{user}/{file} - Avatar images of the local avatar store",
+			"health":  "GET /health - Health check",
+			"stats":   "GET /stats - Uptime, requests by status class, events and store sizes",
+			"healthz": "GET /healthz - Liveness probe",
+			"readyz":  "GET /readyz - Readiness probe",
 		},
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding root response: %v", err)
+		slog.ErrorContext(r.Context(), "Error encoding root response", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
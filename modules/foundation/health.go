@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultHealthCheckTimeout bounds checks registered without their own timeout
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency can serve requests
+type HealthCheck func(ctx context.Context) error
+
+// Dependency statuses reported by a HealthRegistry
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+// DependencyHealth is the result of the check of one dependency
+type DependencyHealth struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the result of every registered check
+type HealthReport struct {
+	Healthy      bool
+	Dependencies map[string]DependencyHealth
+}
+
+// registeredCheck is a check with the time it is given
+type registeredCheck struct {
+	name    string
+	timeout time.Duration
+	check   HealthCheck
+}
+
+// HealthRegistry holds the health checks of the dependencies of the service,
+// such as stores, transports and projections. Components register a named
+// check with a timeout, so one slow dependency cannot hold up the others.
+type HealthRegistry struct {
+	checks []registeredCheck
+	mutex  sync.RWMutex
+}
+
+// NewHealthRegistry creates an empty HealthRegistry
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{}
+}
+
+// Register adds the check of the dependency called name, which fails when it
+// takes longer than timeout. A zero timeout is defaultHealthCheckTimeout.
+// Registering a name again replaces its check.
+func (h *HealthRegistry) Register(name string, timeout time.Duration, check HealthCheck) {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	registered := registeredCheck{name: name, timeout: timeout, check: check}
+	for i, c := range h.checks {
+		if c.name == name {
+			h.checks[i] = registered
+			return
+		}
+	}
+	h.checks = append(h.checks, registered)
+}
+
+// Check runs every registered check concurrently and reports the status and
+// latency of each dependency. The report is healthy when every check passes.
+func (h *HealthRegistry) Check(ctx context.Context) HealthReport {
+	h.mutex.RLock()
+	checks := append([]registeredCheck(nil), h.checks...)
+	h.mutex.RUnlock()
+
+	results := make([]DependencyHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Healthy: true, Dependencies: make(map[string]DependencyHealth, len(checks))}
+	for i, c := range checks {
+		report.Dependencies[c.name] = results[i]
+		if results[i].Status != DependencyUp {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// runCheck runs c within its timeout. A check ignoring its context is
+// reported as down once the timeout expires; it is left to finish on its own.
+func runCheck(ctx context.Context, c registeredCheck) DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", c.timeout)
+	}
+
+	result := DependencyHealth{
+		Status:    DependencyUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = DependencyDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthRegistry_Check(t *testing.T) {
+	passing := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	// hanging ignores its context, like a client without deadline support
+	release := make(chan struct{})
+	defer close(release)
+	hanging := func(context.Context) error {
+		<-release
+		return nil
+	}
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name        string
+		check       HealthCheck
+		wantStatus  string
+		wantError   string
+		wantHealthy bool
+	}{
+		{"passing", passing, DependencyUp, "", true},
+		{"failing", failing, DependencyDown, "connection refused", false},
+		{"slow check times out", slow, DependencyDown, "timed out after 20ms", false},
+		{"check ignoring its context times out", hanging, DependencyDown, "timed out after 20ms", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := NewHealthRegistry()
+			health.Register("users", 0, passing)
+			health.Register("broker", 20*time.Millisecond, tt.check)
+
+			start := time.Now()
+			report := health.Check(context.Background())
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Check() took %v, want the timeout to bound it", elapsed)
+			}
+
+			if report.Healthy != tt.wantHealthy {
+				t.Errorf("Healthy got %v want %v", report.Healthy, tt.wantHealthy)
+			}
+			got := report.Dependencies["broker"]
+			if got.Status != tt.wantStatus || got.Error != tt.wantError {
+				t.Errorf("broker got %+v want status %q and error %q", got, tt.wantStatus, tt.wantError)
+			}
+			if users := report.Dependencies["users"]; users.Status != DependencyUp {
+				t.Errorf("users got %+v want up", users)
+			}
+		})
+	}
+}
+
+func TestHealthRegistry_Latency(t *testing.T) {
+	health := NewHealthRegistry()
+	health.Register("store", 0, func(context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	if latency := health.Check(context.Background()).Dependencies["store"].LatencyMs; latency < 10 {
+		t.Errorf("latency got %vms want at least 10ms", latency)
+	}
+}
+
+func TestHealthRegistry_RegisterReplaces(t *testing.T) {
+	health := NewHealthRegistry()
+	health.Register("users", 0, func(context.Context) error { return errors.New("down") })
+	health.Register("users", 0, func(context.Context) error { return nil })
+
+	report := health.Check(context.Background())
+	if len(report.Dependencies) != 1 || !report.Healthy {
+		t.Errorf("report got %+v want one healthy dependency", report)
+	}
+}
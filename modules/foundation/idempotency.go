@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotencyKeyHeader is the request header carrying the client-chosen key
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// perRequestHeaders belong to the request that produced a stored response and
+// are not replayed: a replay keeps the ones set for the retry itself
+var perRequestHeaders = map[string]bool{
+	http.CanonicalHeaderKey(requestIDHeader): true,
+	"Set-Cookie":                             true,
+	"Connection":                             true,
+	"Date":                                   true,
+}
+
+// idempotentResponse is a stored response, or a reservation while the first
+// request with the key is still being processed
+type idempotentResponse struct {
+	fingerprint string
+	completed   bool
+	statusCode  int
+	header      http.Header
+	body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore maps idempotency keys to the response of the first
+// request that used them, for a limited time
+type IdempotencyStore struct {
+	responses map[string]*idempotentResponse
+	ttl       time.Duration
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// NewIdempotencyStore creates a store keeping responses for ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		responses: make(map[string]*idempotentResponse),
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// begin reserves key for a request with the given fingerprint. It returns the
+// stored response when the key was already used, or false when the caller
+// reserved the key and must complete or release it.
+func (s *IdempotencyStore) begin(key, fingerprint string) (*idempotentResponse, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	for k, response := range s.responses {
+		if response.completed && now.After(response.expiresAt) {
+			delete(s.responses, k)
+		}
+	}
+
+	if response, ok := s.responses[key]; ok {
+		return response, true
+	}
+
+	s.responses[key] = &idempotentResponse{fingerprint: fingerprint}
+	return nil, false
+}
+
+// complete stores the response of the request that reserved key
+func (s *IdempotencyStore) complete(key string, statusCode int, header http.Header, body []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	response, ok := s.responses[key]
+	if !ok {
+		return
+	}
+	response.completed = true
+	response.statusCode = statusCode
+	response.header = header
+	response.body = body
+	response.expiresAt = s.now().Add(s.ttl)
+}
+
+// release removes the reservation of key so the request can be retried
+func (s *IdempotencyStore) release(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.responses, key)
+}
+
+// Middleware makes POST requests carrying an Idempotency-Key safe to retry.
+// The first response for a key is stored and replayed for retries with the
+// same payload; server errors are not stored so the request can be retried.
+func (s *IdempotencyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			// Let the handler report oversized or unreadable bodies
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Keys are scoped to the tenant and caller so clients cannot replay each other's responses
+		key := TenantFromContext(r.Context()) + ":" + idempotencyKey
+		if claims, ok := ClaimsFromContext(r.Context()); ok {
+			key = TenantFromContext(r.Context()) + ":" + claims.Subject + ":" + idempotencyKey
+		}
+		digest := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(digest[:])
+
+		if stored, found := s.begin(key, fingerprint); found {
+			switch {
+			case stored.fingerprint != fingerprint:
+				writeErrorMessage(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+			case !stored.completed:
+				writeError(w, r, NewConflictError("a request with this Idempotency-Key is still being processed"))
+			default:
+				for name, values := range stored.header {
+					if !perRequestHeaders[name] {
+						w.Header()[name] = values
+					}
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.statusCode)
+				w.Write(stored.body)
+			}
+			return
+		}
+
+		// Release the key when the handler panics, or retries would conflict forever
+		defer func() {
+			if p := recover(); p != nil {
+				s.release(key)
+				panic(p)
+			}
+		}()
+
+		recorder := &idempotencyRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.statusCode >= http.StatusInternalServerError {
+			s.release(key)
+			return
+		}
+		s.complete(key, recorder.statusCode, recorder.Header().Clone(), recorder.body.Bytes())
+	})
+}
+
+// idempotencyRecorder writes the response through while keeping a copy of it
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader captures the status code
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	rec.statusCode = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Write copies the body before writing it through
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore_Middleware(t *testing.T) {
+	service := NewInMemoryUserService()
+	store := NewIdempotencyStore(time.Hour)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	handler := store.Middleware(NewUserHandler(service))
+
+	requests := 0
+	post := func(key, body string) *httptest.ResponseRecorder {
+		requests++
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set(requestIDHeader, fmt.Sprintf("req-%d", requests))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		requestIDMiddleware(handler).ServeHTTP(rr, req)
+		return rr
+	}
+	countUsers := func() int {
+		users, _ := service.GetUsers()
+		return len(users)
+	}
+
+	body := `{"name":"Retry User","email":"retry@example.com"}`
+	first := post("key-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request returned wrong status code: got %v want %v", first.Code, http.StatusCreated)
+	}
+
+	retry := post("key-1", body)
+	if retry.Code != http.StatusCreated {
+		t.Errorf("retry returned wrong status code: got %v want %v", retry.Code, http.StatusCreated)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Error("retry should replay the stored response")
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry should be marked as replayed")
+	}
+	if got := retry.Header().Get(requestIDHeader); got != "req-2" {
+		t.Errorf("retry request ID got %q want its own req-2", got)
+	}
+	if got := countUsers(); got != 4 {
+		t.Errorf("retry should not create another user, got %d users", got)
+	}
+
+	if rr := post("key-1", `{"name":"Other User","email":"other@example.com"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with different payload returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	// Errors are stored too, so the retry of a conflicting create stays a conflict
+	if rr := post("key-2", body); rr.Code != http.StatusConflict {
+		t.Errorf("duplicate email returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+	}
+
+	if rr := post("", body); rr.Code != http.StatusConflict {
+		t.Errorf("request without key should reach the handler, got %v", rr.Code)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if rr := post("key-1", body); rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("expired key should not replay the stored response")
+	}
+}
+
+func TestIdempotencyStore_InProgress(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+		req.Header.Set(idempotencyKeyHeader, "key")
+		return req
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest())
+	close(release)
+	<-done
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("concurrent request returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+	}
+}
+
+func TestIdempotencyStore_Panic(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour)
+	panicking := true
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panicking {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func() (rr *httptest.ResponseRecorder, recovered any) {
+		defer func() { recovered = recover() }()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+		req.Header.Set(idempotencyKeyHeader, "key")
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr, nil
+	}
+
+	if _, recovered := serve(); recovered != "handler failed" {
+		t.Fatalf("panic got %v want it passed on", recovered)
+	}
+
+	panicking = false
+	if rr, _ := serve(); rr.Code != http.StatusCreated {
+		t.Errorf("retry after a panic returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+}
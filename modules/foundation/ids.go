@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// defaultIDGenerator generates the IDs of users, events and requests unless
+// configured otherwise
+var defaultIDGenerator uuid.IDGenerator = uuid.GoogleGenerator
+
+// Entities whose ID scheme is configurable
+const (
+	entityUser  = "user"
+	entityEvent = "event"
+)
+
+// IDGenerators holds the ID generator of each entity
+type IDGenerators struct {
+	User  uuid.IDGenerator
+	Event uuid.IDGenerator
+}
+
+// loadIDGenerators creates the generator of every entity in the scheme of
+// settings (ID_SCHEME), overridden per entity by entries of ENTITY_ID_SCHEMES
+// such as "user=ulid" or "event=uuidv7". SNOWFLAKE_NODE and NANOID_LENGTH
+// configure those schemes, and when USER_ID_PREFIX is set, e.g. to usr, user
+// IDs carry the prefix like usr_<id>.
+func loadIDGenerators(settings IDSettings) (IDGenerators, error) {
+	schemes := map[string]uuid.Scheme{
+		entityUser:  uuid.Scheme(settings.Scheme),
+		entityEvent: uuid.Scheme(settings.Scheme),
+	}
+	for _, entry := range settings.EntitySchemes {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		entity, scheme, ok := strings.Cut(entry, "=")
+		entity = strings.TrimSpace(entity)
+		if _, known := schemes[entity]; !ok || !known {
+			return IDGenerators{}, fmt.Errorf("invalid ENTITY_ID_SCHEMES entry %q, want user=scheme or event=scheme", entry)
+		}
+		schemes[entity] = uuid.Scheme(strings.TrimSpace(scheme))
+	}
+
+	node := settings.SnowflakeNode
+	if node < 0 || node > 1023 {
+		return IDGenerators{}, fmt.Errorf("SNOWFLAKE_NODE must be a number between 0 and 1023")
+	}
+	length := settings.NanoIDLength
+	if length <= 0 {
+		return IDGenerators{}, fmt.Errorf("NANOID_LENGTH must be a positive number")
+	}
+
+	newGenerator := func(entity, prefix string) (uuid.IDGenerator, error) {
+		generator, err := uuid.NewGenerator(uuid.GeneratorConfig{
+			Scheme:        schemes[entity],
+			SnowflakeNode: node,
+			NanoIDLength:  length,
+			Prefix:        prefix,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s ID configuration: %w", entity, err)
+		}
+		return generator, nil
+	}
+
+	var generators IDGenerators
+	var err error
+	if generators.User, err = newGenerator(entityUser, settings.UserPrefix); err != nil {
+		return IDGenerators{}, err
+	}
+	if generators.Event, err = newGenerator(entityEvent, ""); err != nil {
+		return IDGenerators{}, err
+	}
+	return generators, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+func TestLoadIDGenerators(t *testing.T) {
+	isUUID := func(version int) func(string) bool {
+		return func(id string) bool { return uuid.MustBeVersion(id, version) == nil }
+	}
+	isULID := func(id string) bool { _, err := uuid.ParseULID(id); return err == nil }
+
+	// settings returns the default settings changed by apply
+	settings := func(apply func(*IDSettings)) IDSettings {
+		s := IDSettings{Scheme: "uuidv4", NanoIDLength: uuid.DefaultNanoIDLength}
+		if apply != nil {
+			apply(&s)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		settings  IDSettings
+		wantUser  func(string) bool
+		wantEvent func(string) bool
+		wantErr   bool
+	}{
+		{
+			name:      "defaults",
+			settings:  settings(nil),
+			wantUser:  isUUID(4),
+			wantEvent: isUUID(4),
+		},
+		{
+			name:      "scheme of every entity",
+			settings:  settings(func(s *IDSettings) { s.Scheme = "uuidv7" }),
+			wantUser:  isUUID(7),
+			wantEvent: isUUID(7),
+		},
+		{
+			name: "per-entity schemes",
+			settings: settings(func(s *IDSettings) {
+				s.Scheme = "uuidv7"
+				s.EntitySchemes = []string{"user=ulid"}
+			}),
+			wantUser:  isULID,
+			wantEvent: isUUID(7),
+		},
+		{
+			name: "prefixed user IDs",
+			settings: settings(func(s *IDSettings) {
+				s.EntitySchemes = []string{" user = ulid ", "event=uuidv4"}
+				s.UserPrefix = "usr"
+			}),
+			wantUser: func(id string) bool {
+				return strings.HasPrefix(id, "usr_") && isULID(strings.TrimPrefix(id, "usr_"))
+			},
+			wantEvent: isUUID(4),
+		},
+		{
+			name: "configured snowflake and nanoid",
+			settings: settings(func(s *IDSettings) {
+				s.EntitySchemes = []string{"user=nanoid", "event=snowflake"}
+				s.NanoIDLength = 10
+				s.SnowflakeNode = 3
+			}),
+			wantUser:  func(id string) bool { return len(id) == 10 },
+			wantEvent: func(id string) bool { _, err := strconv.ParseInt(id, 10, 64); return err == nil },
+		},
+		{name: "unknown scheme", settings: settings(func(s *IDSettings) { s.Scheme = "serial" }), wantErr: true},
+		{name: "unknown entity", settings: settings(func(s *IDSettings) { s.EntitySchemes = []string{"order=ulid"} }), wantErr: true},
+		{name: "malformed entry", settings: settings(func(s *IDSettings) { s.EntitySchemes = []string{"ulid"} }), wantErr: true},
+		{name: "invalid prefix", settings: settings(func(s *IDSettings) { s.UserPrefix = "USR" }), wantErr: true},
+		{name: "snowflake node out of range", settings: settings(func(s *IDSettings) { s.SnowflakeNode = 1024 }), wantErr: true},
+		{name: "invalid nanoid length", settings: settings(func(s *IDSettings) { s.NanoIDLength = 0 }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generators, err := loadIDGenerators(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadIDGenerators() error got %v want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if id := generators.User.NewID(); !tt.wantUser(id) {
+				t.Errorf("user NewID() got unexpected ID %v", id)
+			}
+			if id := generators.Event.NewID(); !tt.wantEvent(id) {
+				t.Errorf("event NewID() got unexpected ID %v", id)
+			}
+		})
+	}
+}
+
+func TestInMemoryUserService_WithUserIDGenerator(t *testing.T) {
+	userIDs, err := uuid.NewPrefixedGenerator("usr", uuid.NewSequenceGenerator("user"))
+	if err != nil {
+		t.Fatalf("NewPrefixedGenerator() error = %v", err)
+	}
+	bus := NewEventBus()
+	var published []events.Event
+	bus.Subscribe(func(_ context.Context, event events.Event) error {
+		published = append(published, event)
+		return nil
+	})
+	service := NewInMemoryUserService(
+		WithIDGenerator(uuid.NewSequenceGenerator("event")),
+		WithUserIDGenerator(userIDs),
+		WithEventPublisher(bus),
+	)
+
+	user, err := service.CreateUser("Alice Johnson", "alice@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.ID != "usr_user-0004" {
+		t.Errorf("CreateUser() ID got %v want %v", user.ID, "usr_user-0004")
+	}
+	if got, err := service.GetUserByID("usr_user-0004"); err != nil || got.Name != "Alice Johnson" {
+		t.Errorf("GetUserByID() got %v, %v want the created user", got, err)
+	}
+
+	// Events keep the IDs of the service's generator
+	if len(published) != 1 || published[0].ID != "event-0001" || published[0].Subject != "usr_user-0004" {
+		t.Errorf("published events got %+v want event-0001 about usr_user-0004", published)
+	}
+}
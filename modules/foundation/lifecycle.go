@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// UserStatus is the lifecycle state of a user
+type UserStatus string
+
+const (
+	UserStatusPending   UserStatus = "pending"
+	UserStatusActive    UserStatus = "active"
+	UserStatusSuspended UserStatus = "suspended"
+	UserStatusDeleted   UserStatus = "deleted"
+)
+
+// userStatusTransitions lists the statuses each status may move to.
+// Deleted is terminal.
+var userStatusTransitions = map[UserStatus][]UserStatus{
+	UserStatusPending:   {UserStatusActive, UserStatusDeleted},
+	UserStatusActive:    {UserStatusSuspended, UserStatusDeleted},
+	UserStatusSuspended: {UserStatusActive, UserStatusDeleted},
+	UserStatusDeleted:   {},
+}
+
+// IsValid reports whether the status is a known status
+func (s UserStatus) IsValid() bool {
+	_, ok := userStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a user may move from s to next
+func (s UserStatus) CanTransitionTo(next UserStatus) bool {
+	return slices.Contains(userStatusTransitions[s], next)
+}
+
+// statusEventTypes maps the statuses reachable through ChangeStatus to the
+// event published when a user enters them
+var statusEventTypes = map[UserStatus]string{
+	UserStatusActive:    EventTypeUserActivated,
+	UserStatusSuspended: EventTypeUserSuspended,
+}
+
+// TransitionTo moves the user to status, rejecting illegal transitions with a conflict error
+func (u *User) TransitionTo(status UserStatus) error {
+	if !status.IsValid() {
+		return NewValidationError("status", fmt.Sprintf("unknown status '%s'", status))
+	}
+	if !u.Status.CanTransitionTo(status) {
+		err := NewConflictError(fmt.Sprintf("cannot change status from '%s' to '%s'", u.Status, status))
+		err.Details = map[string]interface{}{
+			"from": u.Status,
+			"to":   status,
+		}
+		return err
+	}
+
+	u.Status = status
+	u.Version++
+	u.UpdatedAt = time.Now()
+	return nil
+}
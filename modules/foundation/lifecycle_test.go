@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestUserStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from     UserStatus
+		to       UserStatus
+		expected bool
+	}{
+		{UserStatusPending, UserStatusActive, true},
+		{UserStatusPending, UserStatusSuspended, false},
+		{UserStatusPending, UserStatusDeleted, true},
+		{UserStatusActive, UserStatusSuspended, true},
+		{UserStatusActive, UserStatusPending, false},
+		{UserStatusSuspended, UserStatusActive, true},
+		{UserStatusSuspended, UserStatusDeleted, true},
+		{UserStatusDeleted, UserStatusActive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.expected {
+				t.Errorf("CanTransitionTo() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInMemoryUserService_ChangeStatus(t *testing.T) {
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+
+	var received []events.Event
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	user, err := service.CreateUser("Pending User", "pending@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.Status != UserStatusPending {
+		t.Errorf("new user status = %v, want %v", user.Status, UserStatusPending)
+	}
+
+	if _, err := service.ChangeStatus(user.ID, UserStatusSuspended, 0); err == nil {
+		t.Error("suspending a pending user should fail")
+	} else if appErr, _ := IsAppError(err); appErr == nil || appErr.Type != ErrorTypeConflict {
+		t.Errorf("illegal transition error = %v, want conflict", err)
+	}
+
+	activated, err := service.ChangeStatus(user.ID, UserStatusActive, user.Version)
+	if err != nil {
+		t.Fatalf("ChangeStatus(active) error = %v", err)
+	}
+	if activated.Status != UserStatusActive || activated.Version != user.Version+1 {
+		t.Errorf("activated user = %+v", activated)
+	}
+
+	if _, err := service.ChangeStatus(user.ID, UserStatusSuspended, 0); err != nil {
+		t.Fatalf("ChangeStatus(suspended) error = %v", err)
+	}
+	if _, err := service.ChangeStatus(user.ID, UserStatusDeleted, 0); err == nil {
+		t.Error("ChangeStatus(deleted) should be rejected in favour of DeleteUser")
+	}
+	if err := service.DeleteUser(user.ID, 0); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	want := []string{EventTypeUserCreated, EventTypeUserActivated, EventTypeUserSuspended, EventTypeUserDeleted}
+	if len(received) != len(want) {
+		t.Fatalf("received %d events, want %d", len(received), len(want))
+	}
+	for i, event := range received {
+		if event.Type != want[i] {
+			t.Errorf("event %d type = %v, want %v", i, event.Type, want[i])
+		}
+	}
+	var deleted UserEventData
+	if err := received[3].Decode(&deleted); err != nil {
+		t.Fatal(err)
+	}
+	if deleted.User.Status != UserStatusDeleted {
+		t.Errorf("user.deleted snapshot status = %v, want %v", deleted.User.Status, UserStatusDeleted)
+	}
+}
+
+func TestUserHandler_ChangeStatus(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+	user, err := service.CreateUser("Status User", "status@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		ifMatch        bool
+		expectedStatus int
+		expectedUser   UserStatus
+	}{
+		{"activate without If-Match", "/users/" + user.ID + "/activate", false, http.StatusPreconditionRequired, ""},
+		{"suspend pending user", "/users/" + user.ID + "/suspend", true, http.StatusConflict, ""},
+		{"activate", "/users/" + user.ID + "/activate", true, http.StatusOK, UserStatusActive},
+		{"suspend", "/users/" + user.ID + "/suspend", true, http.StatusOK, UserStatusSuspended},
+		{"unknown user", "/users/missing/activate", true, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.ifMatch {
+				req.Header.Set("If-Match", "*")
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedUser != "" {
+				var body User
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if body.Status != tt.expectedUser {
+					t.Errorf("user status = %v, want %v", body.Status, tt.expectedUser)
+				}
+			}
+		})
+	}
+}
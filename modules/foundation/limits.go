@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// maxBodyMiddleware limits request bodies to limit bytes. Requests declaring
+// a larger Content-Length are rejected up front; bodies sent without a length
+// fail while being read, once the limit is crossed.
+func maxBodyMiddleware(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			writeError(w, r, NewPayloadTooLargeError(limit))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeJSONBody decodes the request body into dst. It writes a 413 response
+// for bodies over the limit and a 400 response for malformed JSON, and
+// returns false when the request must stop.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, disallowUnknownFields bool) bool {
+	dec := json.NewDecoder(r.Body)
+	if disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, NewPayloadTooLargeError(maxBytesErr.Limit))
+			return false
+		}
+		writeErrorMessage(w, r, http.StatusBadRequest, "invalid JSON body")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodyMiddleware(t *testing.T) {
+	handler := maxBodyMiddleware(64, NewUserHandler(NewInMemoryUserService()))
+	oversized := `{"name":"` + strings.Repeat("a", 100) + `","email":"big@example.com"}`
+
+	tests := []struct {
+		name           string
+		body           io.Reader
+		chunked        bool
+		expectedStatus int
+	}{
+		{"within limit", strings.NewReader(`{"name":"Small","email":"small@example.com"}`), false, http.StatusCreated},
+		{"declared length over limit", strings.NewReader(oversized), false, http.StatusRequestEntityTooLarge},
+		{"streamed body over limit", strings.NewReader(oversized), true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", tt.body)
+			if tt.chunked {
+				// An unknown length forces the limit to be enforced while reading
+				req.ContentLength = -1
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge {
+				var body struct {
+					Code       ErrorType `json:"code"`
+					LimitBytes int64     `json:"limit_bytes"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if body.Code != ErrorTypePayloadTooLarge || body.LimitBytes != 64 {
+					t.Errorf("unexpected error body: %s", rr.Body.String())
+				}
+			}
+		})
+	}
+}
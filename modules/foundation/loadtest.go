@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// loadOperations are the operations a load test mixes, in report order
+var loadOperations = []string{"list", "get", "create", "update", "delete"}
+
+// defaultLoadMix is mostly reads, with enough writes to exercise the store
+const defaultLoadMix = "list=40,get=40,create=10,update=8,delete=2"
+
+// loadWeight is the share of an operation in the mix
+type loadWeight struct {
+	operation string
+	weight    int
+}
+
+// parseLoadMix parses a mix of operations such as list=40,get=60
+func parseLoadMix(mix string) ([]loadWeight, error) {
+	var weights []loadWeight
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(mix, ",") {
+		operation, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		weight, err := strconv.Atoi(value)
+		switch {
+		case !ok || err != nil || weight < 0:
+			return nil, fmt.Errorf("invalid mix entry %q, want operation=weight", part)
+		case !slices.Contains(loadOperations, operation):
+			return nil, fmt.Errorf("unknown operation %q, want one of %s", operation, strings.Join(loadOperations, ", "))
+		case seen[operation]:
+			return nil, fmt.Errorf("operation %q appears twice", operation)
+		}
+		seen[operation] = true
+		if weight > 0 {
+			weights = append(weights, loadWeight{operation, weight})
+		}
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("mix %q has no operation with a positive weight", mix)
+	}
+	return weights, nil
+}
+
+// loadStats are the outcomes of the requests of an operation
+type loadStats struct {
+	latencies []time.Duration
+	failed    int
+}
+
+// loadUser is a user created by the load test
+type loadUser struct {
+	id    string
+	email string
+}
+
+// loadTest sends a mix of operations to the users API and records their
+// latencies and errors
+type loadTest struct {
+	api     *apiClient
+	weights []loadWeight
+	total   int
+	runID   string
+	seq     atomic.Int64
+
+	mutex    sync.Mutex
+	ids      []string
+	created  []loadUser
+	updating map[string]int
+	stats    map[string]*loadStats
+}
+
+// newLoadTest creates a load test of api with the mix of weights
+func newLoadTest(api *apiClient, weights []loadWeight) *loadTest {
+	test := &loadTest{
+		api:      api,
+		weights:  weights,
+		runID:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		updating: make(map[string]int),
+		stats:    make(map[string]*loadStats),
+	}
+	for _, w := range weights {
+		test.total += w.weight
+	}
+	return test
+}
+
+// pick draws an operation from the mix
+func (l *loadTest) pick() string {
+	n := rand.IntN(l.total)
+	for _, w := range l.weights {
+		if n < w.weight {
+			return w.operation
+		}
+		n -= w.weight
+	}
+	return l.weights[len(l.weights)-1].operation
+}
+
+// loadIDs reads the IDs of the first page of users, which get reads
+func (l *loadTest) loadIDs(ctx context.Context) error {
+	resp, err := l.api.do(ctx, http.MethodGet, "/users?per_page="+strconv.Itoa(maxPerPage), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var users []struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return fmt.Errorf("reading the users: %w", err)
+	}
+	for _, user := range users {
+		l.ids = append(l.ids, user.ID)
+	}
+	return nil
+}
+
+// run sends requests at rps until duration elapsed or ctx is done, with at
+// most concurrency requests in flight. Requests due while the limit is
+// reached are skipped, so a slow server shows in the skipped count instead
+// of slowing the rate down. It returns the number of skipped requests.
+func (l *loadTest) run(ctx context.Context, rps int, duration time.Duration, concurrency int) int {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	skipped := 0
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return skipped
+		case <-ticker.C:
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			skipped++
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			// Requests still in flight at the end are let finish
+			l.execute(context.WithoutCancel(ctx), l.pick())
+		}()
+	}
+}
+
+// execute performs an operation and records its outcome. Operations that
+// need a user created by the test create one when there is none.
+func (l *loadTest) execute(ctx context.Context, operation string) {
+	l.mutex.Lock()
+	var id string
+	var user loadUser
+	switch operation {
+	case "get":
+		if len(l.ids) == 0 {
+			operation = "list"
+		} else {
+			id = l.ids[rand.IntN(len(l.ids))]
+		}
+	case "update":
+		if len(l.created) == 0 {
+			operation = "create"
+		} else {
+			user = l.created[rand.IntN(len(l.created))]
+			l.updating[user.id]++
+		}
+	case "delete":
+		// Users being updated are left alone, so no update fails with a 404
+		// because of the test itself
+		i := slices.IndexFunc(l.created, func(u loadUser) bool { return l.updating[u.id] == 0 })
+		if i < 0 {
+			operation = "create"
+		} else {
+			// Deleted users are forgotten first, so no other request uses them
+			user = l.created[i]
+			l.created = append(l.created[:i], l.created[i+1:]...)
+			l.forget(user.id)
+		}
+	}
+	l.mutex.Unlock()
+
+	n := l.seq.Add(1)
+	var method, path string
+	var body interface{}
+	header := http.Header{}
+	switch operation {
+	case "list":
+		method, path = http.MethodGet, "/users"
+	case "get":
+		method, path = http.MethodGet, "/users/"+id
+	case "create":
+		user.email = fmt.Sprintf("loadtest-%s-%d@example.com", l.runID, n)
+		method, path = http.MethodPost, "/users"
+		body = CreateUserRequest{Name: fmt.Sprintf("Load Test %d", n), Email: user.email}
+	case "update":
+		name := fmt.Sprintf("Load Test %d", n)
+		method, path = http.MethodPut, "/users/"+user.id
+		body = UpdateUserRequest{Name: &name, Email: &user.email}
+		header.Set("If-Match", "*")
+	case "delete":
+		method, path = http.MethodDelete, "/users/"+user.id
+		header.Set("If-Match", "*")
+	}
+
+	start := time.Now()
+	resp, err := l.api.do(ctx, method, path, body, header)
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err == nil {
+		if operation == "create" {
+			err = json.NewDecoder(resp.Body).Decode(&created)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	latency := time.Since(start)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if operation == "update" {
+		if l.updating[user.id]--; l.updating[user.id] == 0 {
+			delete(l.updating, user.id)
+		}
+	}
+	stats := l.stats[operation]
+	if stats == nil {
+		stats = &loadStats{}
+		l.stats[operation] = stats
+	}
+	stats.latencies = append(stats.latencies, latency)
+	if err != nil {
+		stats.failed++
+		return
+	}
+	if operation == "create" {
+		l.created = append(l.created, loadUser{id: created.ID, email: user.email})
+		l.ids = append(l.ids, created.ID)
+	}
+}
+
+// forget removes id from the IDs read by get. The caller must hold the mutex.
+func (l *loadTest) forget(id string) {
+	for i, known := range l.ids {
+		if known == id {
+			l.ids = append(l.ids[:i], l.ids[i+1:]...)
+			return
+		}
+	}
+}
+
+// cleanup deletes the users created by the test that are left, and returns
+// how many it deleted
+func (l *loadTest) cleanup(ctx context.Context) int {
+	deleted := 0
+	for _, user := range l.created {
+		resp, err := l.api.do(ctx, http.MethodDelete, "/users/"+user.id, nil, http.Header{"If-Match": {"*"}})
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		deleted++
+	}
+	l.created = nil
+	return deleted
+}
+
+// percentile returns the latency below which p percent of the sorted
+// latencies fall, by the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// report writes the requests, error rates and latency percentiles of each
+// operation and of all of them
+func (l *loadTest) report(w io.Writer, elapsed time.Duration, skipped int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var all []time.Duration
+	failed := 0
+	for _, stats := range l.stats {
+		all = append(all, stats.latencies...)
+		failed += stats.failed
+	}
+	fmt.Fprintf(w, "%d requests in %s (%.1f req/s), %d errors (%.2f%%), %d skipped at the concurrency limit\n\n",
+		len(all), elapsed.Round(time.Millisecond), float64(len(all))/elapsed.Seconds(), failed, errorRate(failed, len(all)), skipped)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "operation\trequests\terrors\terror rate\tp50\tp90\tp99\tmax\t")
+	row := func(name string, latencies []time.Duration, failed int) {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.2f%%\t%s\t%s\t%s\t%s\t\n", name, len(latencies), failed, errorRate(failed, len(latencies)),
+			roundLatency(percentile(latencies, 50)), roundLatency(percentile(latencies, 90)),
+			roundLatency(percentile(latencies, 99)), roundLatency(percentile(latencies, 100)))
+	}
+	for _, operation := range loadOperations {
+		if stats, ok := l.stats[operation]; ok {
+			row(operation, stats.latencies, stats.failed)
+		}
+	}
+	row("total", all, failed)
+	return table.Flush()
+}
+
+// errorRate returns the percentage of failed requests
+func errorRate(failed, requests int) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return 100 * float64(failed) / float64(requests)
+}
+
+// roundLatency rounds a latency for display
+func roundLatency(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// runLoadTest sends a mix of requests to the users API of a running server
+// at a steady rate and reports latency percentiles and error rates
+func runLoadTest(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := newCommandFlags("loadtest", "Send a steady rate of users API requests to a running server and report latencies and errors", stderr)
+	api := apiFlags(flags)
+	rps := flags.Int("rps", 50, "requests per second")
+	duration := flags.Duration("duration", 10*time.Second, "how long to send requests")
+	concurrency := flags.Int("concurrency", 64, "maximum number of requests in flight")
+	mix := flags.String("mix", defaultLoadMix, "weights of the operations: list, get, create, update and delete")
+	cleanup := flags.Bool("cleanup", true, "delete the users created by the test that are left at the end")
+	cfg, err := parseCommand(flags, args)
+	if err != nil {
+		return err
+	}
+	weights, err := parseLoadMix(*mix)
+	switch {
+	case err != nil:
+		return &usageError{"Invalid --mix: " + err.Error()}
+	case *rps < 1:
+		return &usageError{"--rps must be at least 1"}
+	case *duration <= 0:
+		return &usageError{"--duration must be positive"}
+	case *concurrency < 1:
+		return &usageError{"--concurrency must be at least 1"}
+	}
+	api.resolve(cfg)
+
+	test := newLoadTest(api, weights)
+	if err := test.loadIDs(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Load testing %s at %d req/s for %s with %s\n", api.baseURL, *rps, *duration, *mix)
+	start := time.Now()
+	skipped := test.run(ctx, *rps, *duration, *concurrency)
+	elapsed := time.Since(start)
+	if err := test.report(stdout, elapsed, skipped); err != nil {
+		return err
+	}
+	if *cleanup {
+		if deleted := test.cleanup(context.WithoutCancel(ctx)); deleted > 0 {
+			fmt.Fprintf(stdout, "\nDeleted the %d users created by the test\n", deleted)
+		}
+	}
+	return nil
+}
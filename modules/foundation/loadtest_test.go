@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLoadMix(t *testing.T) {
+	tests := []struct {
+		mix     string
+		want    int
+		wantErr bool
+	}{
+		{defaultLoadMix, 5, false},
+		{"get=1, list=0", 1, false},
+		{"get", 0, true},
+		{"get=-1,list=1", 0, true},
+		{"search=1", 0, true},
+		{"get=1,get=2", 0, true},
+		{"get=0", 0, true},
+	}
+	for _, tt := range tests {
+		weights, err := parseLoadMix(tt.mix)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLoadMix(%q) error = %v, wantErr %v", tt.mix, err, tt.wantErr)
+		}
+		if len(weights) != tt.want {
+			t.Errorf("parseLoadMix(%q) got %v want %d operations", tt.mix, weights, tt.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond, 0: time.Millisecond} {
+		if got := percentile(latencies, p); got != want {
+			t.Errorf("percentile(%v) got %v want %v", p, got, want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile() of no latencies got %v want 0", got)
+	}
+}
+
+func TestRunCLI_LoadTest(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	service := NewInMemoryUserService()
+	before := service.Count()
+	server := httptest.NewServer(NewUserHandler(service))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"loadtest", "--url", server.URL, "--rps", "400", "--duration", "250ms", "--mix", "list=1,get=1,create=2,update=1,delete=1"}
+	if code := runCLI(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	output := stdout.String()
+	for _, want := range []string{"Load testing " + server.URL, " 0 errors", "operation", "p99", "create", "total"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("stdout got\n%s\nwant it to contain %q", output, want)
+		}
+	}
+	if got := service.Count(); got != before {
+		t.Errorf("got %d users after the test want the %d before, the created users cleaned up", got, before)
+	}
+
+	// Failed requests are counted, not fatal
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/users" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	stdout.Reset()
+	args = []string{"loadtest", "--url", failing.URL, "--rps", "200", "--duration", "100ms", "--mix", "create=1"}
+	if code := runCLI(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code got %d want 0 (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "100.00%") {
+		t.Errorf("stdout got\n%s\nwant every request failed", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := runCLI(context.Background(), []string{"loadtest", "--mix", "search=1"}, &stdout, &stderr); code != 2 {
+		t.Errorf("exit code got %d want 2", code)
+	}
+	if !strings.Contains(stderr.String(), "Invalid --mix") {
+		t.Errorf("stderr got %q want the invalid mix", stderr.String())
+	}
+}
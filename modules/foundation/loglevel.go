@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// logLevels are the levels that can be set at runtime, by name
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// LogLevelChangedData is the payload of ops.log_level_changed events
+type LogLevelChangedData struct {
+	Level    string `json:"level"`
+	Previous string `json:"previous"`
+}
+
+// LogLevelRequest represents the request body for PUT /admin/log-level
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevelHandler serves the level of the application logger, which can be
+// changed without restarting the process
+type LogLevelHandler struct {
+	level     *slog.LevelVar
+	publisher events.Publisher
+	ids       uuid.IDGenerator
+}
+
+// NewLogLevelHandler creates a LogLevelHandler changing level and publishing
+// every change as an operational event with an ID from ids
+func NewLogLevelHandler(level *slog.LevelVar, publisher events.Publisher, ids uuid.IDGenerator) *LogLevelHandler {
+	return &LogLevelHandler{
+		level:     level,
+		publisher: publisher,
+		ids:       ids,
+	}
+}
+
+// ServeHTTP handles GET and PUT /admin/log-level
+func (h *LogLevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"level": levelName(h.level.Level())})
+	case http.MethodPut:
+		h.handleSetLevel(w, r)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, PUT, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, OPTIONS")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleSetLevel handles PUT /admin/log-level
+func (h *LogLevelHandler) handleSetLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if !decodeJSONBody(w, r, &req, true) {
+		return
+	}
+
+	level, ok := logLevels[strings.ToLower(req.Level)]
+	if !ok {
+		var errs ValidationErrors
+		errs.Add("level", "level must be one of debug, info, warn or error")
+		writeError(w, r, errs.Err())
+		return
+	}
+
+	previous := h.level.Level()
+	h.level.Set(level)
+	data := LogLevelChangedData{Level: levelName(level), Previous: levelName(previous)}
+
+	// Logged at warn so the change shows up at every level but error
+	slog.WarnContext(r.Context(), "Log level changed", "level", data.Level, "previous", data.Previous)
+	event, err := newEvent(h.ids, EventTypeLogLevelChanged, "log-level", "", data)
+	if err != nil {
+		writeError(w, r, NewInternalError("failed to create the event", err))
+		return
+	}
+	event.RequestID = RequestIDFromContext(r.Context())
+	if claims, ok := ClaimsFromContext(r.Context()); ok {
+		event.Actor = claims.Subject
+	}
+	if err := h.publisher.Publish(r.Context(), event); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to publish event", "event_type", event.Type, "error", err)
+	}
+
+	writeJSON(w, http.StatusOK, data)
+}
+
+// levelName returns the lower-case name of level, as accepted by LOG_LEVEL
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
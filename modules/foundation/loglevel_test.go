@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		wantCode  int
+		wantLevel slog.Level
+		wantEvent bool
+	}{
+		{"get current level", http.MethodGet, "", http.StatusOK, slog.LevelInfo, false},
+		{"set debug", http.MethodPut, `{"level":"debug"}`, http.StatusOK, slog.LevelDebug, true},
+		{"level names ignore case", http.MethodPut, `{"level":"WARN"}`, http.StatusOK, slog.LevelWarn, true},
+		{"unknown level", http.MethodPut, `{"level":"verbose"}`, http.StatusBadRequest, slog.LevelInfo, false},
+		{"level offsets are rejected", http.MethodPut, `{"level":"info+2"}`, http.StatusBadRequest, slog.LevelInfo, false},
+		{"unknown field", http.MethodPut, `{"lvl":"debug"}`, http.StatusBadRequest, slog.LevelInfo, false},
+		{"method not allowed", http.MethodPost, `{"level":"debug"}`, http.StatusMethodNotAllowed, slog.LevelInfo, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := new(slog.LevelVar)
+			bus := NewEventBus()
+			var published []events.Event
+			bus.Subscribe(func(_ context.Context, event events.Event) error {
+				published = append(published, event)
+				return nil
+			})
+
+			req := httptest.NewRequest(tt.method, "/admin/log-level", strings.NewReader(tt.body))
+			req = req.WithContext(ContextWithClaims(ContextWithRequestID(req.Context(), "req-1"), &Claims{Subject: "admin-1"}))
+			rr := httptest.NewRecorder()
+			NewLogLevelHandler(level, bus, uuid.GoogleGenerator).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status code got %v want %v", rr.Code, tt.wantCode)
+			}
+			if level.Level() != tt.wantLevel {
+				t.Errorf("level got %v want %v", level.Level(), tt.wantLevel)
+			}
+			if rr.Code == http.StatusOK {
+				var body LogLevelChangedData
+				if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+					t.Fatalf("invalid JSON body: %v", err)
+				}
+				if body.Level != levelName(tt.wantLevel) {
+					t.Errorf("body level got %q want %q", body.Level, levelName(tt.wantLevel))
+				}
+			}
+
+			if !tt.wantEvent {
+				if len(published) != 0 {
+					t.Errorf("events got %d want 0", len(published))
+				}
+				return
+			}
+			if len(published) != 1 {
+				t.Fatalf("events got %d want 1", len(published))
+			}
+			event := published[0]
+			if event.Type != EventTypeLogLevelChanged || event.Actor != "admin-1" || event.RequestID != "req-1" {
+				t.Errorf("event got %s by %q in %q want %s by admin-1 in req-1", event.Type, event.Actor, event.RequestID, EventTypeLogLevelChanged)
+			}
+			var data LogLevelChangedData
+			if err := event.Decode(&data); err != nil || data.Previous != "info" || data.Level != levelName(tt.wantLevel) {
+				t.Errorf("event data got %s want info -> %s", event.Data, levelName(tt.wantLevel))
+			}
+		})
+	}
+}
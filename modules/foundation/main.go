@@ -2,128 +2,497 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
-const (
-	defaultPort = "8080"
-	defaultHost = "localhost"
-)
-
 func main() {
-	// Get configuration from environment variables
-	port := getEnv("PORT", defaultPort)
-	host := getEnv("HOST", defaultHost)
+	// SIGINT and SIGTERM shut the server down gracefully and stop tailing events
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := runCLI(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// serve runs the HTTP and gRPC servers configured by cfg until ctx is done,
+// then shuts them down gracefully. Configuration reloads call load.
+func serve(ctx context.Context, cfg Config, load func() (Config, error)) {
+	started := time.Now()
+
+	// Log structured records in the configured format and level
+	logger, logLevel, err := newLogger(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	port := cfg.Server.Port
+	host := cfg.Server.Host
+
+	// Generate the IDs of users and events in their configured schemes
+	ids, err := loadIDGenerators(cfg.IDs)
+	if err != nil {
+		fatal("Invalid ID configuration", "error", err)
+	}
+	emailStrictness, err := loadEmailStrictness(cfg.Users)
+	if err != nil {
+		fatal("Invalid user configuration", "error", err)
+	}
+	userFixtures, err := loadUserFixtures(cfg.Users)
+	if err != nil {
+		fatal("Invalid user fixtures", "error", err)
+	}
+
+	// Serve the default tenant and the configured ones only
+	allowedTenants, err := loadAllowedTenants(cfg.Server.Tenants)
+	if err != nil {
+		fatal("Invalid tenants", "error", err)
+	}
+
+	// Create the event bus and an isolated user store per tenant publishing to it
+	eventBus := NewEventBus(WithBusLogger(logger.With("component", "event-bus")))
+	tenants := NewTenantRegistry(allowedTenants, func(tenant string) *InMemoryUserService {
+		return NewInMemoryUserService(
+			WithEventPublisher(eventBus),
+			WithIDGenerator(ids.Event),
+			WithUserIDGenerator(ids.User),
+			WithEmailValidation(emailStrictness),
+			WithFixtures(userFixtures),
+			WithTenant(tenant),
+			WithLogger(logger.With("component", "user-service")),
+		)
+	})
+	tenantDomain := cfg.Server.TenantDomain
+
+	// Record every change in the append-only audit log
+	auditLog := NewAuditLog()
+	eventBus.Subscribe(auditLog.Record, "user.*")
+
+	// Count the requests served by status class for GET /stats
+	requestCounter := NewRequestCounter()
+
+	// Coordinate graceful shutdown and in-flight request draining
+	shutdownManager := NewShutdownManager(cfg.Server.ShutdownDrainDelay)
+
+	// Report liveness and readiness; dependencies register their checks below
+	health := NewHealthRegistry()
+	probes := NewProbes(health, shutdownManager.Draining)
+	health.Register("users", 0, func(context.Context) error {
+		_, err := tenants.Service(defaultTenant).GetUsers()
+		return err
+	})
 
-	// Create user service
-	userService := NewInMemoryUserService()
+	// Measure every endpoint against its latency SLO, published as the "slo" expvar
+	sloTracker, err := loadSLOTracker(cfg.SLO)
+	if err != nil {
+		fatal("Invalid SLO configuration", "error", err)
+	}
+	expvar.Publish("slo", expvar.Func(func() interface{} { return sloTracker.Snapshot() }))
+
+	// Inject faults into requests when chaos is enabled, for resilience demos
+	chaosInjector, err := loadChaos(cfg.Chaos)
+	if err != nil {
+		fatal("Invalid chaos configuration", "error", err)
+	}
+	if chaosInjector != nil {
+		slog.Warn("Chaos is enabled: requests get injected latency and failures")
+	}
+
+	// Send unexpected errors to Sentry when a DSN is configured
+	reporter, err := loadErrorReporter(cfg.Sentry)
+	if err != nil {
+		fatal("Invalid error reporting configuration", "error", err)
+	}
+
+	// Export OpenTelemetry traces when a collector is configured
+	tracingConfig, err := loadTracingConfig(cfg.Tracing)
+	if err != nil {
+		fatal("Invalid tracing configuration", "error", err)
+	}
+	shutdownTracing, err := setupTracing(context.Background(), tracingConfig)
+	if err != nil {
+		fatal("Invalid tracing configuration", "error", err)
+	}
+
+	// Select the error response format
+	legacyErrorFormat, err = loadErrorFormat(cfg.Server.ErrorFormat)
+	if err != nil {
+		fatal("Invalid error format", "error", err)
+	}
+
+	// Choose the access log format
+	accessLogger, err := NewAccessLogger(cfg.Log.AccessFormat, os.Stderr)
+	if err != nil {
+		fatal("Invalid access log format", "error", err)
+	}
+
+	// Limit request body sizes
+	maxBodyBytes := cfg.Server.MaxBodyBytes
+
+	// Limit how long each route may take to respond
+	routeTimeouts, err := loadRouteTimeouts(cfg.Server.RequestTimeout, cfg.Server.RouteTimeouts)
+	if err != nil {
+		fatal("Invalid route timeouts", "error", err)
+	}
+
+	// Remember responses of POST requests carrying an Idempotency-Key
+	idempotencyStore := NewIdempotencyStore(cfg.Server.IdempotencyTTL)
+
+	// Load authentication configuration
+	var jwtConfig JWTConfig
+	jwtConfig, err = loadJWTConfig(cfg.Auth)
+	if err != nil {
+		fatal("Invalid JWT configuration", "error", err)
+	}
+
+	// Sign users in with an OpenID Connect provider when one is configured
+	oidcConfig, err := loadOIDCConfig(cfg.OIDC)
+	if err != nil {
+		fatal("Invalid OIDC configuration", "error", err)
+	}
+
+	// Keep avatar uploads in a local directory served below, or in S3
+	blobStore, err := loadBlobStore(cfg.Avatars)
+	if err != nil {
+		fatal("Invalid avatar store", "error", err)
+	}
+	avatars := NewAvatarStore(blobStore, cfg.Avatars.MaxBytes)
+
+	// Profiling and runtime variables are only served when asked for, because
+	// profiles expose internals of the process
+	debugEnabled := cfg.Server.DebugEndpoints
+
+	// Users with a password log in for a token signed with the HS256 secret
+	loginTokenTTL := cfg.Auth.LoginTokenTTL
 
 	// Create handlers
-	userHandler := NewUserHandler(userService)
+	var authorizer *Authorizer
+	if jwtConfig.Enabled() {
+		authorizer = NewAuthorizer(tenants.ServiceFor)
+	} else {
+		slog.Warn("JWT authentication disabled: set JWT_HS256_SECRET or JWT_RS256_PUBLIC_KEY_FILE to enable it")
+	}
+
+	var validator *JWTValidator
+	if authorizer != nil {
+		validator = NewJWTValidator(jwtConfig)
+	}
+
+	// Browser clients authenticate with a session cookie instead of a token
+	var sessionManager *SessionManager
+	if authorizer != nil {
+		sessionStore, err := loadSessionStore(cfg.Session)
+		if err != nil {
+			fatal("Invalid session store", "error", err)
+		}
+		sessionTTL := cfg.Session.TTL
+		if pinger, ok := sessionStore.(interface{ Ping(context.Context) error }); ok {
+			health.Register("sessions", time.Second, pinger.Ping)
+		}
+		sessionStore = TraceSessionStore(sessionStore)
+		sessionManager = NewSessionManager(sessionStore, sessionTTL)
+		if closer, ok := sessionStore.(io.Closer); ok {
+			shutdownManager.Register("sessions", func(context.Context) error {
+				return closer.Close()
+			})
+		}
+	}
+
+	// Each tenant is served by its own handlers on top of its own store
+	var userHandler http.Handler = NewTenantRouter(tenantDomain, allowedTenants, func(tenant string) http.Handler {
+		userHandler := NewUserHandler(tenants.Service(tenant),
+			WithHandlerLogger(logger.With("component", "user-handler")), WithErrorReporter(reporter), WithAvatars(avatars))
+		var handler http.Handler = idempotencyStore.Middleware(userHandler)
+		if authorizer != nil {
+			handler = authorizer.Middleware(userOperationPermission, handler)
+		}
+		return handler
+	})
+	var adminHandler http.Handler = NewTenantRouter(tenantDomain, allowedTenants, func(tenant string) http.Handler {
+		var handler http.Handler = NewAdminHandler(tenants.Service(tenant))
+		if authorizer != nil {
+			handler = authorizer.Middleware(func(*http.Request) Permission { return PermissionDemoDataManage }, handler)
+		}
+		return handler
+	})
+	var auditHandler http.Handler = NewTenantRouter(tenantDomain, allowedTenants, func(string) http.Handler {
+		var handler http.Handler = NewAuditHandler(auditLog)
+		if authorizer != nil {
+			handler = authorizer.Middleware(func(*http.Request) Permission { return PermissionAuditRead }, handler)
+		}
+		return handler
+	})
+	// The log level belongs to the process, so it is shared by every tenant
+	var logLevelHandler http.Handler = NewLogLevelHandler(logLevel, eventBus, ids.Event)
+	if authorizer != nil {
+		logLevelHandler = authorizer.Middleware(func(*http.Request) Permission { return PermissionLogLevelManage }, logLevelHandler)
+	}
+	// Other services follow the events of every tenant, so the stream is shared too
+	eventStream := newEventStream(eventBus)
+	var eventStreamHandler http.Handler = eventStream
+	if authorizer != nil {
+		eventStreamHandler = authorizer.Middleware(func(*http.Request) Permission { return PermissionEventsRead }, eventStreamHandler)
+	}
+	graphqlRouter := NewTenantRouter(tenantDomain, allowedTenants, func(tenant string) http.Handler {
+		handler, err := NewGraphQLHandler(tenants.Service(tenant), eventBus, authorizer)
+		if err != nil {
+			fatal("Invalid GraphQL schema", "error", err)
+		}
+		return handler
+	})
+	// Build the default tenant's GraphQL handler up front so schema errors surface at startup
+	graphqlRouter.handler(defaultTenant)
+	var graphqlRoute http.Handler = graphqlRouter
+	if authorizer != nil {
+		// Reads, including POST /users/batch-get, may be anonymous
+		userHandler = authenticate(validator, func(r *http.Request) bool {
+			return userOperationPermission(r) != PermissionUsersRead
+		}, userHandler)
+		adminHandler = authMiddleware(validator, adminHandler)
+		auditHandler = authenticate(validator, func(*http.Request) bool { return true }, auditHandler)
+		logLevelHandler = authenticate(validator, func(*http.Request) bool { return true }, logLevelHandler)
+		eventStreamHandler = authenticate(validator, func(*http.Request) bool { return true }, eventStreamHandler)
+		// GraphQL resolvers authorize each field, so tokens are optional here
+		graphqlRoute = authenticate(validator, func(*http.Request) bool { return false }, graphqlRoute)
+	}
 
 	// Setup routes
 	mux := http.NewServeMux()
 
 	// API routes
-	mux.Handle("/users", userHandler)
-	mux.Handle("/users/", userHandler)
-	mux.HandleFunc("/health", healthHandler)
+	mux.Handle("/users", routeTimeouts.Wrap("/users", userHandler))
+	mux.Handle("/users/", routeTimeouts.Wrap("/users/", userHandler))
+	mux.Handle("/graphql", routeTimeouts.Wrap("/graphql", graphqlRoute))
+	mux.Handle("/admin/", routeTimeouts.Wrap("/admin/", adminHandler))
+	mux.Handle("/audit", routeTimeouts.Wrap("/audit", auditHandler))
+	mux.Handle("/admin/log-level", routeTimeouts.Wrap("/admin/log-level", logLevelHandler))
+	// Streams last as long as their subscribers, so no timeout applies
+	mux.Handle("/events", eventStreamHandler)
+	if oidcConfig.Enabled() {
+		// Logins remember the tenant they started in, so one handler serves every tenant
+		oidcHandler := NewOIDCHandler(oidcConfig, jwtConfig, tenants.ServiceFor)
+		mux.Handle("/auth/", routeTimeouts.Wrap("/auth/", NewTenantRouter(tenantDomain, allowedTenants, func(string) http.Handler {
+			return oidcHandler
+		})))
+	}
+	if local, ok := blobStore.(*LocalBlobStore); ok {
+		mux.Handle(avatarsPath, http.StripPrefix(avatarsPath, local.Handler()))
+	}
+	mux.Handle("/health", shutdownManager.HealthMiddleware(http.HandlerFunc(healthHandler)))
+	mux.Handle("/healthz", probes.LivenessHandler())
+	mux.Handle("/readyz", probes.ReadinessHandler())
+	mux.Handle("/stats", NewStatsHandler(started, requestCounter, eventBus, tenants, auditLog))
+	if len(jwtConfig.HMACSecret) > 0 {
+		// Credentials are checked in the store of the request's tenant
+		loginHandler := NewLoginHandler(tenants.ServiceFor, jwtConfig, loginTokenTTL)
+		mux.Handle("/login", routeTimeouts.Wrap("/login", NewTenantRouter(tenantDomain, allowedTenants, func(string) http.Handler {
+			return loginHandler
+		})))
+	}
+	if sessionManager != nil {
+		sessionHandler := routeTimeouts.Wrap("/session", NewSessionHandler(sessionManager, validator))
+		mux.Handle("/session", sessionHandler)
+		mux.Handle("/session/", sessionHandler)
+	}
+	if debugEnabled {
+		// Profiles run longer than route timeouts, so none is applied here
+		var debugHandler http.Handler = NewDebugHandler()
+		if authorizer != nil {
+			debugHandler = authenticate(validator, func(*http.Request) bool { return true },
+				authorizer.Middleware(func(*http.Request) Permission { return PermissionDebugRead }, debugHandler))
+		} else {
+			slog.Warn("Debug endpoints are enabled without authentication: do not expose this port")
+		}
+		mux.Handle("/debug/", debugHandler)
+	}
 	mux.HandleFunc("/", rootHandler)
 
+	var routes http.Handler = mux
+	if sessionManager != nil {
+		routes = sessionManager.Middleware(mux)
+	}
+
+	var handler http.Handler = loggingMiddleware(accessLogger, requestCounter.Middleware(sloMiddleware(sloTracker, recoveryMiddleware(reporter, shutdownManager.Middleware(chaosMiddleware(chaosInjector, compressionMiddleware(maxBodyMiddleware(maxBodyBytes, routes), defaultCompressionMinSize)))))))
+	// One wide record per request next to the access log, for log-based analytics
+	canonicalLog := new(atomic.Bool)
+	canonicalLog.Store(cfg.Log.Canonical)
+	handler = canonicalLogToggle(canonicalLog, logger, handler)
+
 	// Create server
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", host, port),
-		Handler:      loggingMiddleware(mux),
+		Handler:      tracingMiddleware(requestIDMiddleware(handler)),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Configure HTTPS when certificates are available
+	tlsConfig := cfg.TLS
+	scheme := "http"
+	var redirectServer *http.Server
+	if tlsConfig.Enabled() {
+		serverTLS, err := newServerTLSConfig(tlsConfig, host)
+		if err != nil {
+			fatal("Invalid TLS configuration", "error", err)
+		}
+		server.TLSConfig = serverTLS
+		scheme = "https"
+
+		if tlsConfig.RedirectPort != "" {
+			redirectServer = &http.Server{
+				Addr:         fmt.Sprintf("%s:%s", host, tlsConfig.RedirectPort),
+				Handler:      httpsRedirectHandler(port),
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 5 * time.Second,
+			}
+			go func() {
+				slog.Info("Redirecting HTTP to HTTPS", "addr", redirectServer.Addr)
+				if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					fatal("Redirect server failed to start", "error", err)
+				}
+			}()
+		}
+	}
+
+	// End long-lived subscription and event streams so stopping the server is not blocked
+	shutdownManager.RegisterDrain("graphql-subscriptions", func(context.Context) error {
+		graphqlRouter.Close()
+		return nil
+	})
+	shutdownManager.RegisterDrain("event-stream", func(context.Context) error {
+		eventStream.Close()
+		return nil
+	})
+
+	// Serve HTTP/2 over TLS, and over cleartext (h2c) when enabled
+	server.Protocols = serverProtocols(server.TLSConfig != nil, cfg.Server.H2C)
+
+	// Serve the gRPC API alongside HTTP, sharing the same user service
+	grpcPort := cfg.Server.GRPCPort
+	grpcServer := NewGRPCServer(tenants.ServiceFor, allowedTenants, validator, authorizer)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", host, grpcPort))
+	if err != nil {
+		fatal("gRPC server failed to listen", "error", err)
+	}
+	shutdownManager.Register("grpc", func(ctx context.Context) error {
+		return stopGRPCServer(ctx, grpcServer)
+	})
+	// Once nothing publishes anymore, let the subscribers finish the events in delivery
+	shutdownManager.Register("event-bus", eventBus.Close)
+	// Flush spans after the other hooks, which may still record some
+	if tracingConfig.Enabled() {
+		shutdownManager.Register("tracing", shutdownTracing)
+	}
+	shutdownManager.Register("error-reports", reporter.Flush)
+	go func() {
+		slog.Info("Starting gRPC server", "addr", grpcListener.Addr().String())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			fatal("gRPC server failed", "error", err)
+		}
+	}()
+
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting server on %s:%s", host, port)
-		log.Printf("API endpoints:")
-		log.Printf("  GET    /              - API information")
-		log.Printf("  GET    /health        - Health check")
-		log.Printf("  GET    /users         - Get all users")
-		log.Printf("  POST   /users         - Create user")
-		log.Printf("  GET    /users/{id}    - Get user by ID")
-		log.Printf("  PUT    /users/{id}    - Update user")
-		log.Printf("  DELETE /users/{id}    - Delete user")
-		log.Printf("")
-		log.Printf("Example requests:")
-		log.Printf("  curl http://%s:%s/users", host, port)
-		log.Printf("  curl -X POST http://%s:%s/users -H 'Content-Type: application/json' -d '{\"name\":\"Alice\",\"email\":\"alice@example.com\"}'", host, port)
-
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+		slog.Info("Starting server", "url", fmt.Sprintf("%s://%s:%s", scheme, host, port))
+		endpoints := []string{
+			"GET    /              - API information",
+			"GET    /health        - Health check",
+			"GET    /healthz       - Liveness probe",
+			"GET    /readyz        - Readiness probe",
+			"GET    /stats         - Uptime and runtime statistics",
+			"GET    /users         - Get all users",
+			"POST   /users         - Create user",
+			"GET    /users/{id}    - Get user by ID",
+			"GET    /users/by-email/{email} - Get user by email",
+			"POST   /users/batch-get - Get several users by ID",
+			"PUT    /users/{id}    - Update user",
+			"DELETE /users/{id}    - Delete user",
+			"PUT    /users/{id}/roles - Assign roles",
+			"POST   /users/{id}/activate - Activate user",
+			"POST   /users/{id}/suspend  - Suspend user",
+			"POST   /users/{id}/change-password - Change password",
+			"POST   /graphql       - GraphQL queries, mutations and subscriptions",
+			"POST   /admin/seed    - Load the demo users",
+			"POST   /admin/reset   - Remove all users",
+			"PUT    /admin/log-level - Change the log level",
+			"GET    /audit         - Audit log of changes",
+			"GET    /events        - Stream of the events of every tenant, for other services",
+		}
+		if len(jwtConfig.HMACSecret) > 0 {
+			endpoints = append(endpoints, "POST   /login         - Exchange email and password for a token")
+		}
+		if debugEnabled {
+			endpoints = append(endpoints,
+				"GET    /debug/pprof/  - Runtime profiles",
+				"GET    /debug/vars    - Runtime variables (expvar)",
+			)
+		}
+		if sessionManager != nil {
+			endpoints = append(endpoints,
+				"POST   /session/login  - Start a browser session from a bearer token",
+				"POST   /session/logout - End the browser session",
+			)
+		}
+		if oidcConfig.Enabled() {
+			endpoints = append(endpoints,
+				fmt.Sprintf("GET    /auth/login    - Sign in with %s", oidcConfig.IssuerURL),
+				"GET    /auth/callback - OIDC redirect target, returns a bearer token",
+			)
+		}
+		for _, endpoint := range endpoints {
+			slog.Debug("API endpoint", "endpoint", endpoint)
+		}
+		slog.Debug("Example request", "command", fmt.Sprintf("curl %s://%s:%s/users", scheme, host, port))
+
+		var err error
+		if server.TLSConfig != nil {
+			// Certificates come from TLSConfig, so no file paths are passed here
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("Server failed to start", "error", err)
 		}
 	}()
 
+	// Apply changes of the config file, or the environment on SIGHUP, without a restart
+	reloader := NewConfigReloader(cfg, load, RuntimeSettings{LogLevel: logLevel, CanonicalLog: canonicalLog}, eventBus, ids.Event)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go reloader.Run(ctx, reload, configPollInterval)
+
 	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Create a deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Attempt graceful shutdown
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	// Drain in-flight requests, then run the registered shutdown hooks
+	report := shutdownManager.Shutdown(ctx, redirectServer, server)
+	if !report.Clean {
+		fatal("Server forced to shutdown", "report", report.String())
 	}
 
-	log.Println("Server exited")
-}
-
-// getEnv gets an environment variable with a fallback default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-// loggingMiddleware logs HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		// Create a response writer wrapper to capture status code
-		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-
-		// Call the next handler
-		next.ServeHTTP(wrapper, r)
-
-		// Log the request
-		duration := time.Since(start)
-		log.Printf("%s %s %d %v %s",
-			r.Method,
-			r.URL.Path,
-			wrapper.statusCode,
-			duration,
-			r.RemoteAddr,
-		)
-	})
-}
-
-// responseWriter wraps http.ResponseWriter to capture status code
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
+	slog.Info("Server exited", "report", report.String())
 }
 
-// WriteHeader captures the status code
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -1,20 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
 )
 
 func TestUser_Validate(t *testing.T) {
 	tests := []struct {
-		name    string
-		user    User
-		wantErr bool
-		errType ErrorType
+		name       string
+		user       User
+		wantErr    bool
+		errType    ErrorType
+		wantFields []string
 	}{
 		{
 			name: "valid user",
@@ -52,8 +58,20 @@ func TestUser_Validate(t *testing.T) {
 				Name:  "John Doe",
 				Email: "invalid-email",
 			},
-			wantErr: true,
-			errType: ErrorTypeValidation,
+			wantErr:    true,
+			errType:    ErrorTypeValidation,
+			wantFields: []string{"email"},
+		},
+		{
+			name: "all fields invalid",
+			user: User{
+				ID:    "123",
+				Name:  "",
+				Email: "invalid-email",
+			},
+			wantErr:    true,
+			errType:    ErrorTypeValidation,
+			wantFields: []string{"name", "email"},
 		},
 	}
 
@@ -70,6 +88,15 @@ func TestUser_Validate(t *testing.T) {
 					if appErr.Type != tt.errType {
 						t.Errorf("User.Validate() error type = %v, want %v", appErr.Type, tt.errType)
 					}
+					if tt.wantFields != nil {
+						var fields []string
+						for _, fieldErr := range appErr.Errors {
+							fields = append(fields, fieldErr.Field)
+						}
+						if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+							t.Errorf("User.Validate() error fields = %v, want %v", fields, tt.wantFields)
+						}
+					}
 				} else {
 					t.Errorf("User.Validate() expected AppError, got %T", err)
 				}
@@ -137,6 +164,32 @@ func TestInMemoryUserService_CreateUser(t *testing.T) {
 	}
 }
 
+func TestInMemoryUserService_WithIDGenerator(t *testing.T) {
+	ids := uuid.NewSequenceGenerator("id")
+	bus := NewEventBus()
+	var published []events.Event
+	bus.Subscribe(func(_ context.Context, event events.Event) error {
+		published = append(published, event)
+		return nil
+	})
+	service := NewInMemoryUserService(WithIDGenerator(ids), WithEventPublisher(bus))
+
+	// The three fixture users take the first IDs
+	user, err := service.CreateUser("Alice Johnson", "alice@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.ID != "id-0004" {
+		t.Errorf("CreateUser() ID got %v want %v", user.ID, "id-0004")
+	}
+	if len(published) != 1 || published[0].ID != "id-0005" {
+		t.Errorf("published events got %+v want one with ID id-0005", published)
+	}
+	if _, err := service.GetUserByID("id-0001"); err != nil {
+		t.Errorf("GetUserByID(id-0001) error = %v", err)
+	}
+}
+
 func TestInMemoryUserService_GetUserByID(t *testing.T) {
 	service := NewInMemoryUserService()
 
@@ -186,6 +239,164 @@ func TestInMemoryUserService_GetUserByID(t *testing.T) {
 	}
 }
 
+func TestInMemoryUserService_GetUserByEmail(t *testing.T) {
+	service := NewInMemoryUserService()
+
+	user, err := service.CreateUser("Test User", "test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	if _, err := service.UpdateUser(user.ID, "Renamed User", "renamed@example.com", 0); err != nil {
+		t.Fatalf("Failed to update test user: %v", err)
+	}
+	seeded, err := service.GetUserByEmail("bob.johnson@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail() error = %v", err)
+	}
+	if err := service.DeleteUser(seeded.ID, 0); err != nil {
+		t.Fatalf("Failed to delete seeded user: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		wantID  string
+		wantErr bool
+	}{
+		{"current email", "renamed@example.com", user.ID, false},
+		{"previous email", "test@example.com", "", true},
+		{"deleted user", "bob.johnson@example.com", "", true},
+		{"different case", "RENAMED@example.com", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.GetUserByEmail(tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetUserByEmail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if appErr, ok := IsAppError(err); !ok || appErr.Type != ErrorTypeNotFound {
+					t.Errorf("GetUserByEmail() error = %v, want not found", err)
+				}
+				return
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("GetUserByEmail() ID = %v, want %v", got.ID, tt.wantID)
+			}
+		})
+	}
+
+	if _, err := service.CreateUser("Reuse", "test@example.com"); err != nil {
+		t.Errorf("previous email should be free again, got %v", err)
+	}
+}
+
+func TestUserHandler_GetUserByEmail(t *testing.T) {
+	handler := NewUserHandler(NewInMemoryUserService())
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"existing email", "/users/by-email/jane.smith@example.com", http.StatusOK},
+		{"unknown email", "/users/by-email/nobody@example.com", http.StatusNotFound},
+		{"missing email", "/users/by-email/", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var user User
+			if err := json.Unmarshal(rr.Body.Bytes(), &user); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if user.Email != "jane.smith@example.com" || rr.Header().Get("ETag") != user.ETag() {
+				t.Errorf("unexpected user %+v with ETag %q", user, rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestUserHandler_BatchGetUsers(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+	first, _ := service.CreateUser("First User", "first@example.com")
+	second, _ := service.CreateUser("Second User", "second@example.com")
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedUsers   []string
+		expectedMissing []string
+	}{
+		{
+			name:            "found and missing",
+			body:            `{"ids":["` + second.ID + `","unknown","` + first.ID + `","` + second.ID + `"]}`,
+			expectedStatus:  http.StatusOK,
+			expectedUsers:   []string{second.ID, first.ID},
+			expectedMissing: []string{"unknown"},
+		},
+		{
+			name:            "all found",
+			body:            `{"ids":["` + first.ID + `"]}`,
+			expectedStatus:  http.StatusOK,
+			expectedUsers:   []string{first.ID},
+			expectedMissing: []string{},
+		},
+		{
+			name:           "no ids",
+			body:           `{"ids":[]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many ids",
+			body:           `{"ids":[` + strings.Repeat(`"x",`, maxBatchGetIDs) + `"x"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users/batch-get", strings.NewReader(tt.body)))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp BatchGetUsersResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			var ids []string
+			for _, user := range resp.Users {
+				ids = append(ids, user.ID)
+			}
+			if !slices.Equal(ids, tt.expectedUsers) {
+				t.Errorf("users = %v, want %v", ids, tt.expectedUsers)
+			}
+			if !slices.Equal(resp.Missing, tt.expectedMissing) {
+				t.Errorf("missing = %v, want %v", resp.Missing, tt.expectedMissing)
+			}
+		})
+	}
+}
+
 func TestUserHandler_GetUsers(t *testing.T) {
 	service := NewInMemoryUserService()
 	handler := NewUserHandler(service)
@@ -280,7 +491,7 @@ func TestIsValidEmail(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isValidEmail(tt.email); got != tt.want {
+			if got := isValidEmail(tt.email, defaultEmailStrictness); got != tt.want {
 				t.Errorf("isValidEmail() = %v, want %v", got, tt.want)
 			}
 		})
@@ -291,7 +502,7 @@ func TestNewUser(t *testing.T) {
 	name := "Test User"
 	email := "test@example.com"
 
-	user := NewUser(name, email)
+	user := NewUser(uuid.GoogleGenerator, name, email)
 
 	if user.Name != name {
 		t.Errorf("NewUser() name = %v, want %v", user.Name, name)
@@ -311,23 +522,277 @@ func TestNewUser(t *testing.T) {
 }
 
 func TestUser_Update(t *testing.T) {
-	user := NewUser("Original Name", "original@example.com")
-	originalUpdatedAt := user.UpdatedAt
+	tests := []struct {
+		name        string
+		newName     string
+		newEmail    string
+		wantName    string
+		wantEmail   string
+		wantVersion int64
+		wantErr     bool
+	}{
+		{"both fields", "Updated Name", "updated@example.com", "Updated Name", "updated@example.com", 2, false},
+		{"name only", "Updated Name", "", "Updated Name", "original@example.com", 2, false},
+		{"email only", "", "updated@example.com", "Original Name", "updated@example.com", 2, false},
+		{"unchanged", "Original Name", "original@example.com", "Original Name", "original@example.com", 1, false},
+		{"nothing", "", "", "Original Name", "original@example.com", 1, false},
+		{"invalid email", "Updated Name", "not-an-email", "Original Name", "original@example.com", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := NewUser(uuid.GoogleGenerator, "Original Name", "original@example.com")
+			originalUpdatedAt := user.UpdatedAt
+
+			// Wait a bit to ensure timestamp difference
+			time.Sleep(time.Millisecond)
+
+			err := user.Update(tt.newName, tt.newEmail)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if user.Name != tt.wantName || user.Email != tt.wantEmail || user.Version != tt.wantVersion {
+				t.Errorf("Update() = %q <%s> at version %d, want %q <%s> at version %d",
+					user.Name, user.Email, user.Version, tt.wantName, tt.wantEmail, tt.wantVersion)
+			}
+			if changed := user.UpdatedAt.After(originalUpdatedAt); changed != (tt.wantVersion > 1) {
+				t.Errorf("Update() moved UpdatedAt = %v, want %v", changed, tt.wantVersion > 1)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ConditionalRequests(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+
+	user, err := service.CreateUser("Test User", "test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	staleETag := user.ETag()
+
+	if _, err := service.UpdateUser(user.ID, "Renamed User", "renamed@example.com", 0); err != nil {
+		t.Fatalf("Failed to update test user: %v", err)
+	}
+	current, err := service.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get test user: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"update without If-Match", http.MethodPut, "", http.StatusPreconditionRequired},
+		{"update with stale ETag", http.MethodPut, staleETag, http.StatusPreconditionFailed},
+		{"update with weak ETag", http.MethodPut, "W/" + current.ETag(), http.StatusPreconditionFailed},
+		{"update with current ETag", http.MethodPut, current.ETag(), http.StatusOK},
+		{"delete with stale ETag", http.MethodDelete, current.ETag(), http.StatusPreconditionFailed},
+		{"delete with wildcard", http.MethodDelete, "*", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "/users/"+user.ID, strings.NewReader(`{"name":"Updated Name","email":"updated@example.com"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+			if rr.Code == http.StatusOK && rr.Header().Get("ETag") == current.ETag() {
+				t.Error("handler should return a new ETag after a successful update")
+			}
+		})
+	}
+}
+
+func TestUserHandler_IfModifiedSince(t *testing.T) {
+	// HTTP dates have a resolution of a second, which the clock moves by
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	service := NewInMemoryUserService(WithClock(func() time.Time { return now }))
+	handler := NewUserHandler(service)
+
+	user, err := service.CreateUser("Test User", "test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	get := func(path, ifModifiedSince string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	userModified := get("/users/"+user.ID, "").Header().Get("Last-Modified")
+	listModified := get("/users", "").Header().Get("Last-Modified")
+	if userModified == "" || listModified == "" {
+		t.Fatalf("Last-Modified missing: user %q, list %q", userModified, listModified)
+	}
+	past := user.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		name            string
+		path            string
+		ifModifiedSince string
+		expectedStatus  int
+	}{
+		{"user without condition", "/users/" + user.ID, "", http.StatusOK},
+		{"user unchanged", "/users/" + user.ID, userModified, http.StatusNotModified},
+		{"user changed since", "/users/" + user.ID, past, http.StatusOK},
+		{"user invalid date", "/users/" + user.ID, "yesterday", http.StatusOK},
+		{"list unchanged", "/users", listModified, http.StatusNotModified},
+		{"list changed since", "/users", past, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(tt.path, tt.ifModifiedSince)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 response has a body: %q", rr.Body)
+			}
+		})
+	}
 
-	// Wait a bit to ensure timestamp difference
-	time.Sleep(time.Millisecond)
+	// Removing a user changes the list even though no remaining user was updated
+	now = now.Add(time.Second)
+	if err := service.DeleteUser(user.ID, 0); err != nil {
+		t.Fatalf("Failed to delete test user: %v", err)
+	}
+	if rr := get("/users", listModified); rr.Code != http.StatusOK {
+		t.Errorf("list after delete returned %v, want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestUserHandler_AllowedMethods(t *testing.T) {
+	handler := NewUserHandler(NewInMemoryUserService())
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{"options collection", http.MethodOptions, "/users", http.StatusNoContent, "GET, POST, OPTIONS"},
+		{"options user", http.MethodOptions, "/users/1", http.StatusNoContent, "GET, PUT, DELETE, OPTIONS"},
+		{"options roles", http.MethodOptions, "/users/1/roles", http.StatusNoContent, "PUT, OPTIONS"},
+		{"method not allowed on collection", http.MethodDelete, "/users", http.StatusMethodNotAllowed, "GET, POST, OPTIONS"},
+		{"method not allowed on user", http.MethodPost, "/users/1", http.StatusMethodNotAllowed, "GET, PUT, DELETE, OPTIONS"},
+		{"options collection with trailing slash", http.MethodOptions, "/users/", http.StatusNoContent, "GET, POST, OPTIONS"},
+		{"method not allowed on status change", http.MethodGet, "/users/1/activate", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{"unknown path", http.MethodOptions, "/users/1/unknown", http.StatusNotFound, ""},
+		{"unknown method on unknown path", http.MethodGet, "/users/1/roles/admin", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	newName := "Updated Name"
-	newEmail := "updated@example.com"
-	user.Update(newName, newEmail)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
 
-	if user.Name != newName {
-		t.Errorf("Update() name = %v, want %v", user.Name, newName)
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+			if allow := rr.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("handler returned wrong Allow header: got %q want %q", allow, tt.expectedAllow)
+			}
+		})
 	}
-	if user.Email != newEmail {
-		t.Errorf("Update() email = %v, want %v", user.Email, newEmail)
+}
+
+func TestUserHandler_CreateUserValidationErrors(t *testing.T) {
+	handler := NewUserHandler(NewInMemoryUserService())
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"","email":"not-an-email"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
 	}
-	if !user.UpdatedAt.After(originalUpdatedAt) {
-		t.Error("Update() should update the UpdatedAt timestamp")
+
+	var body struct {
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	expected := []FieldError{
+		{Field: "name", Message: "name cannot be empty"},
+		{Field: "email", Message: "email format is invalid"},
+	}
+	if len(body.Errors) != len(expected) {
+		t.Fatalf("handler returned %d field errors, want %d: %s", len(body.Errors), len(expected), rr.Body.String())
+	}
+	for i := range expected {
+		if body.Errors[i] != expected[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, body.Errors[i], expected[i])
+		}
+	}
+}
+
+func TestUserHandler_UpdateUserValidationErrors(t *testing.T) {
+	service := NewInMemoryUserService()
+	user, err := service.CreateUser("Ada", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewUserHandler(service)
+
+	tests := []struct {
+		name string
+		body string
+		want FieldError
+	}{
+		{"invalid email", `{"email":"not-an-email"}`, FieldError{Field: "email", Message: "email format is invalid"}},
+		{"empty name", `{"name":""}`, FieldError{Field: "name", Message: "name cannot be empty"}},
+		{"empty email", `{"name":"Ada Lovelace","email":""}`, FieldError{Field: "email", Message: "email cannot be empty"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/users/"+user.ID, strings.NewReader(tt.body))
+			req.Header.Set("If-Match", "*")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+			var body struct {
+				Errors []FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(body.Errors) != 1 || body.Errors[0] != tt.want {
+				t.Errorf("handler returned errors %+v, want %+v", body.Errors, tt.want)
+			}
+		})
+	}
+
+	if got, _ := service.GetUserByID(user.ID); got.Name != "Ada" || got.Email != "ada@example.com" || got.Version != 1 {
+		t.Errorf("got %+v want the user unchanged at version 1", got)
 	}
 }
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// oidcStateCookie binds a login to the browser that started it
+	oidcStateCookie = "oidc_state"
+
+	// oidcLoginTTL is how long a started login may take to come back
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// OIDCConfig holds the OpenID Connect client registration
+type OIDCConfig struct {
+	// IssuerURL is the provider, e.g. https://keycloak.example.com/realms/demo
+	IssuerURL string
+	ClientID  string
+	// ClientSecret is optional; public clients rely on PKCE alone
+	ClientSecret string
+	// RedirectURL is the registered callback, e.g. http://localhost:8080/auth/callback
+	RedirectURL string
+	Scopes      []string
+	// TokenTTL is the lifetime of the local token issued after login
+	TokenTTL time.Duration
+}
+
+// Enabled reports whether a provider is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
+}
+
+// loadOIDCConfig builds the OpenID Connect configuration from settings, whose
+// scopes are separated by spaces
+func loadOIDCConfig(settings OIDCSettings) (OIDCConfig, error) {
+	cfg := OIDCConfig{
+		IssuerURL:    strings.TrimSuffix(settings.IssuerURL, "/"),
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
+		RedirectURL:  settings.RedirectURL,
+		Scopes:       strings.Fields(settings.Scopes),
+		TokenTTL:     settings.TokenTTL,
+	}
+
+	if cfg.TokenTTL <= 0 {
+		return cfg, errors.New("OIDC_TOKEN_TTL must be a positive duration")
+	}
+
+	if cfg.Enabled() && cfg.RedirectURL == "" {
+		return cfg, errors.New("OIDC_REDIRECT_URL is required when OIDC_ISSUER_URL is set")
+	}
+	return cfg, nil
+}
+
+// oidcProvider is the part of the provider discovery document the client uses
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the ID token claims read after the signature is verified
+type idTokenClaims struct {
+	Subject       string `json:"sub"`
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// pendingLogin is a login redirected to the provider and not yet completed
+type pendingLogin struct {
+	verifier string
+	nonce    string
+	tenant   string
+	expires  time.Time
+}
+
+// OIDCHandler signs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE, and exchanges the verified ID token for
+// a local HS256 token accepted by the auth middleware
+type OIDCHandler struct {
+	config   OIDCConfig
+	local    JWTConfig
+	services ServiceResolver
+	client   *http.Client
+	now      func() time.Time
+
+	mutex    sync.Mutex
+	provider *oidcProvider
+	keys     map[string]*rsa.PublicKey
+	pending  map[string]pendingLogin
+}
+
+// NewOIDCHandler creates a handler for config. Local tokens are signed with
+// the HMAC secret of local and carry its issuer and audience.
+func NewOIDCHandler(config OIDCConfig, local JWTConfig, services ServiceResolver) *OIDCHandler {
+	return &OIDCHandler{
+		config:   config,
+		local:    local,
+		services: services,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+		keys:     make(map[string]*rsa.PublicKey),
+		pending:  make(map[string]pendingLogin),
+	}
+}
+
+// ServeHTTP implements http.Handler for GET /auth/login and GET /auth/callback
+func (h *OIDCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var handle func(http.ResponseWriter, *http.Request)
+	switch r.URL.Path {
+	case "/auth/login":
+		handle = h.handleLogin
+	case "/auth/callback":
+		handle = h.handleCallback
+	default:
+		writeErrorMessage(w, r, http.StatusNotFound, "endpoint not found")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	handle(w, r)
+}
+
+// handleLogin redirects the browser to the provider's authorization endpoint
+func (h *OIDCHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.discover(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	state, nonce, verifier := randomToken(), randomToken(), randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	h.mutex.Lock()
+	now := h.now()
+	for key, login := range h.pending {
+		if now.After(login.expires) {
+			delete(h.pending, key)
+		}
+	}
+	h.pending[state] = pendingLogin{
+		verifier: verifier,
+		nonce:    nonce,
+		tenant:   TenantFromContext(r.Context()),
+		expires:  now.Add(oidcLoginTTL),
+	}
+	h.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {h.config.ClientID},
+		"redirect_uri":          {h.config.RedirectURL},
+		"scope":                 {strings.Join(h.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, provider.AuthorizationEndpoint+"?"+query.Encode(), http.StatusFound)
+}
+
+// handleCallback completes a login: it checks the state, redeems the code,
+// verifies the ID token and answers with a local bearer token
+func (h *OIDCHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		writeError(w, r, NewUnauthorizedError(fmt.Sprintf("login failed: %s %s", providerErr, query.Get("error_description"))))
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		writeError(w, r, NewUnauthorizedError("login state does not match this browser"))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth", MaxAge: -1})
+
+	// A state is redeemed at most once
+	h.mutex.Lock()
+	login, ok := h.pending[state]
+	delete(h.pending, state)
+	h.mutex.Unlock()
+	if !ok || h.now().After(login.expires) {
+		writeError(w, r, NewUnauthorizedError("login expired or was already completed"))
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		writeError(w, r, NewValidationError("code", "authorization code is required"))
+		return
+	}
+
+	provider, err := h.discover(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	rawIDToken, err := h.exchange(r.Context(), provider, code, login.verifier)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	claims, err := h.verifyIDToken(r.Context(), provider, rawIDToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if claims.Nonce != login.nonce {
+		writeError(w, r, NewUnauthorizedError("ID token nonce does not match the login"))
+		return
+	}
+
+	token, err := h.issueToken(ContextWithTenant(r.Context(), login.tenant), claims)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(h.config.TokenTTL.Seconds()),
+	})
+}
+
+// issueToken creates the local token of a verified identity. A verified email
+// of an existing user makes that user the subject, so the token carries the
+// user's roles; other identities keep the provider's subject.
+func (h *OIDCHandler) issueToken(ctx context.Context, identity *idTokenClaims) (string, error) {
+	subject := identity.Subject
+	if identity.EmailVerified && identity.Email != "" {
+		if user, err := h.services(ctx).GetUserByEmail(identity.Email); err == nil {
+			subject = user.ID
+		}
+	}
+
+	now := h.now()
+	claims := &Claims{
+		Subject:   subject,
+		Issuer:    h.local.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(h.config.TokenTTL).Unix(),
+	}
+	if h.local.Audience != "" {
+		claims.Audience = audience{h.local.Audience}
+	}
+	return signHS256(h.local.HMACSecret, claims)
+}
+
+// discover fetches the provider discovery document once
+func (h *OIDCHandler) discover(ctx context.Context) (*oidcProvider, error) {
+	h.mutex.Lock()
+	provider := h.provider
+	h.mutex.Unlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	provider = &oidcProvider{}
+	if err := h.getJSON(ctx, h.config.IssuerURL+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, NewInternalError("OIDC discovery failed", err)
+	}
+	// The document must describe the configured issuer, or tokens could be minted elsewhere
+	if strings.TrimSuffix(provider.Issuer, "/") != h.config.IssuerURL {
+		return nil, NewInternalError("OIDC discovery failed", errors.Errorf("issuer %q does not match %q", provider.Issuer, h.config.IssuerURL))
+	}
+
+	h.mutex.Lock()
+	h.provider = provider
+	h.mutex.Unlock()
+	return provider, nil
+}
+
+// exchange redeems an authorization code and its PKCE verifier for an ID token
+func (h *OIDCHandler) exchange(ctx context.Context, provider *oidcProvider, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {h.config.RedirectURL},
+		"client_id":     {h.config.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", NewInternalError("building token request", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if h.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(h.config.ClientID), url.QueryEscape(h.config.ClientSecret))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", NewInternalError("token request failed", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", NewInternalError("decoding token response", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", NewUnauthorizedError(fmt.Sprintf("code exchange rejected: %s %s", body.Error, body.ErrorDescription))
+	}
+	if body.IDToken == "" {
+		return "", NewUnauthorizedError("token response has no ID token")
+	}
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the ID token signature against the provider keys and
+// its issuer, audience and lifetime, then returns its identity claims
+func (h *OIDCHandler) verifyIDToken(ctx context.Context, provider *oidcProvider, token string) (*idTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, NewUnauthorizedError("malformed ID token")
+	}
+	var header struct {
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, NewUnauthorizedError("malformed ID token header")
+	}
+
+	key, err := h.signingKey(ctx, provider, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	validator := NewJWTValidator(JWTConfig{
+		RSAPublicKey: key,
+		Issuer:       provider.Issuer,
+		Audience:     h.config.ClientID,
+		ClockSkew:    defaultJWTClockSkew,
+	})
+	validator.now = h.now
+	if _, err := validator.Validate(token); err != nil {
+		return nil, err
+	}
+
+	var claims idTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, NewUnauthorizedError("malformed ID token claims")
+	}
+	return &claims, nil
+}
+
+// signingKey returns the provider key with kid, refreshing the key set when
+// it is unknown so rotated keys are picked up. A token without kid is
+// accepted when the provider publishes a single key.
+func (h *OIDCHandler) signingKey(ctx context.Context, provider *oidcProvider, kid string) (*rsa.PublicKey, error) {
+	if key, ok := h.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := h.getJSON(ctx, provider.JWKSURI, &set); err != nil {
+		return nil, NewInternalError("fetching provider keys failed", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if jwk.Kty != "RSA" || errN != nil || errE != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	h.mutex.Lock()
+	h.keys = keys
+	h.mutex.Unlock()
+
+	if key, ok := h.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, NewUnauthorizedError(fmt.Sprintf("ID token signed with unknown key %q", kid))
+}
+
+// lookupKey returns a cached provider key
+func (h *OIDCHandler) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if key, ok := h.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(h.keys) == 1 {
+		for _, key := range h.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// getJSON decodes the JSON document at target into v
+func (h *OIDCHandler) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("GET %s answered %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// randomToken returns 32 random bytes, base64url encoded. That is also a
+// valid PKCE code verifier (43 characters).
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
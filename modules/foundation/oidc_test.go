@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a minimal OpenID Connect provider issuing RS256 ID tokens
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mutex     sync.Mutex
+	challenge string
+	nonce     string
+	email     string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	p := &fakeProvider{t: t, key: key, email: "john.doe@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcProvider{
+			Issuer:                p.server.URL,
+			AuthorizationEndpoint: p.server.URL + "/authorize",
+			TokenEndpoint:         p.server.URL + "/token",
+			JWKSURI:               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "good-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": signToken(t, algRS256, key, map[string]interface{}{
+				"iss":            p.server.URL,
+				"aud":            "user-service-client",
+				"sub":            "provider-subject",
+				"exp":            time.Now().Add(time.Minute).Unix(),
+				"nonce":          p.nonce,
+				"email":          p.email,
+				"email_verified": true,
+			}),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize records the PKCE challenge and nonce of a login redirect, as the
+// provider's authorization endpoint would
+func (p *fakeProvider) authorize(location string) url.Values {
+	u, err := url.Parse(location)
+	if err != nil {
+		p.t.Fatalf("invalid redirect %q: %v", location, err)
+	}
+	query := u.Query()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.challenge = query.Get("code_challenge")
+	p.nonce = query.Get("nonce")
+	return query
+}
+
+// setEmail changes the email claim of the next ID tokens
+func (p *fakeProvider) setEmail(email string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.email = email
+}
+
+func TestOIDCHandler_Login(t *testing.T) {
+	provider := newFakeProvider(t)
+	service := NewInMemoryUserService()
+	secret := []byte("local-secret")
+	handler := NewOIDCHandler(OIDCConfig{
+		IssuerURL:   provider.server.URL,
+		ClientID:    "user-service-client",
+		RedirectURL: "http://localhost:8080/auth/callback",
+		Scopes:      []string{"openid", "email"},
+		TokenTTL:    time.Hour,
+	}, JWTConfig{HMACSecret: secret}, SingleService(service))
+
+	// login starts the flow and redirects to the provider
+	login := func() (*http.Cookie, url.Values) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("login status got %d want %d", rec.Code, http.StatusFound)
+		}
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != oidcStateCookie || !cookies[0].HttpOnly {
+			t.Fatalf("login cookies got %v want an HttpOnly %s cookie", cookies, oidcStateCookie)
+		}
+		return cookies[0], provider.authorize(rec.Header().Get("Location"))
+	}
+	callback := func(cookie *http.Cookie, state, code string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback?"+url.Values{"state": {state}, "code": {code}}.Encode(), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("redirect parameters", func(t *testing.T) {
+		_, query := login()
+		for key, want := range map[string]string{
+			"response_type":         "code",
+			"client_id":             "user-service-client",
+			"redirect_uri":          "http://localhost:8080/auth/callback",
+			"scope":                 "openid email",
+			"code_challenge_method": "S256",
+		} {
+			if got := query.Get(key); got != want {
+				t.Errorf("%s got %q want %q", key, got, want)
+			}
+		}
+		if query.Get("state") == "" || query.Get("nonce") == "" || len(query.Get("code_challenge")) != 43 {
+			t.Errorf("state, nonce or code_challenge missing: %v", query)
+		}
+	})
+
+	t.Run("successful login", func(t *testing.T) {
+		cookie, query := login()
+		rec := callback(cookie, query.Get("state"), "good-code")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("callback status got %d want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+
+		var body struct {
+			AccessToken string `json:"access_token"`
+			TokenType   string `json:"token_type"`
+			ExpiresIn   int64  `json:"expires_in"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding callback response: %v", err)
+		}
+		if body.TokenType != "Bearer" || body.ExpiresIn != 3600 {
+			t.Errorf("token type and lifetime got %q %d want Bearer 3600", body.TokenType, body.ExpiresIn)
+		}
+
+		claims, err := NewJWTValidator(JWTConfig{HMACSecret: secret}).Validate(body.AccessToken)
+		if err != nil {
+			t.Fatalf("local token is not accepted by the auth middleware: %v", err)
+		}
+		john, _ := service.GetUserByEmail("john.doe@example.com")
+		if claims.Subject != john.ID {
+			t.Errorf("subject got %q want the local user %q", claims.Subject, john.ID)
+		}
+
+		// The state is single use
+		if rec := callback(cookie, query.Get("state"), "good-code"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("replayed callback status got %d want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("unknown email keeps the provider subject", func(t *testing.T) {
+		provider.setEmail("stranger@example.com")
+		defer provider.setEmail("john.doe@example.com")
+
+		cookie, query := login()
+		rec := callback(cookie, query.Get("state"), "good-code")
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		claims, err := NewJWTValidator(JWTConfig{HMACSecret: secret}).Validate(body.AccessToken)
+		if err != nil {
+			t.Fatalf("local token is invalid: %v", err)
+		}
+		if claims.Subject != "provider-subject" {
+			t.Errorf("subject got %q want %q", claims.Subject, "provider-subject")
+		}
+	})
+
+	tests := []struct {
+		name       string
+		cookie     bool
+		state      string
+		code       string
+		wantStatus int
+	}{
+		{"missing cookie", false, "", "good-code", http.StatusUnauthorized},
+		{"state mismatch", true, "forged", "good-code", http.StatusUnauthorized},
+		{"missing code", true, "", "", http.StatusBadRequest},
+		{"rejected code", true, "", "bad-code", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, query := login()
+			state := query.Get("state")
+			if tt.state != "" {
+				state = tt.state
+			}
+			if !tt.cookie {
+				cookie = nil
+			}
+			if rec := callback(cookie, state, tt.code); rec.Code != tt.wantStatus {
+				t.Errorf("callback status got %d want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("provider error", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/callback?error=access_denied", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status got %d want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestLoadOIDCConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    OIDCSettings
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{"disabled", OIDCSettings{TokenTTL: time.Hour}, false, false},
+		{"enabled", OIDCSettings{IssuerURL: "https://idp.example.com/", ClientID: "client", RedirectURL: "http://localhost:8080/auth/callback", TokenTTL: time.Hour}, true, false},
+		{"missing redirect", OIDCSettings{IssuerURL: "https://idp.example.com", ClientID: "client", TokenTTL: time.Hour}, true, true},
+		{"invalid token TTL", OIDCSettings{TokenTTL: 0}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadOIDCConfig(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadOIDCConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cfg.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() got %v want %v", cfg.Enabled(), tt.wantEnabled)
+			}
+			if cfg.IssuerURL != "" && cfg.IssuerURL[len(cfg.IssuerURL)-1] == '/' {
+				t.Errorf("issuer keeps its trailing slash: %q", cfg.IssuerURL)
+			}
+		})
+	}
+}
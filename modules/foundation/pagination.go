@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultPerPage is the page size used when per_page is not set
+	defaultPerPage = 20
+
+	// maxPerPage caps the page size a client can request
+	maxPerPage = 100
+)
+
+// Page is a 1-based page of a collection
+type Page struct {
+	Number  int
+	PerPage int
+}
+
+// parsePage reads the page and per_page query parameters, defaulting to
+// the first page of defaultPerPage items
+func parsePage(query url.Values) (Page, error) {
+	page := Page{Number: 1, PerPage: defaultPerPage}
+
+	var errs ValidationErrors
+	if value := query.Get("page"); value != "" {
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 1 {
+			errs.Add("page", "page must be a positive integer")
+		}
+		page.Number = number
+	}
+	if value := query.Get("per_page"); value != "" {
+		perPage, err := strconv.Atoi(value)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			errs.Add("per_page", fmt.Sprintf("per_page must be between 1 and %d", maxPerPage))
+		}
+		page.PerPage = perPage
+	}
+	return page, errs.Err()
+}
+
+// lastPage returns the number of the last page of a collection of total items.
+// An empty collection still has one (empty) page.
+func (p Page) lastPage(total int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + p.PerPage - 1) / p.PerPage
+}
+
+// bounds returns the slice bounds of the page in a collection of total items
+func (p Page) bounds(total int) (start, end int) {
+	start = min((p.Number-1)*p.PerPage, total)
+	end = min(start+p.PerPage, total)
+	return start, end
+}
+
+// writePaginationHeaders sets X-Total-Count and a Link header with the
+// first, prev, next and last pages. Links keep the other query parameters
+// of the request and are relative to it.
+func writePaginationHeaders(w http.ResponseWriter, r *http.Request, page Page, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	last := page.lastPage(total)
+	link := func(number int, rel string) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(number))
+		query.Set("per_page", strconv.Itoa(page.PerPage))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if page.Number > 1 {
+		links = append(links, link(min(page.Number-1, last), "prev"))
+	}
+	if page.Number < last {
+		links = append(links, link(page.Number+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserHandler_Pagination(t *testing.T) {
+	service := NewInMemoryUserService()
+	for i := 0; i < 4; i++ {
+		if _, err := service.CreateUser(fmt.Sprintf("User %d", i), fmt.Sprintf("user%d@example.com", i)); err != nil {
+			t.Fatalf("Failed to create test user: %v", err)
+		}
+	}
+	handler := NewUserHandler(service)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedUsers  int
+		expectedLink   string
+	}{
+		{
+			name:           "defaults",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedUsers:  7,
+			expectedLink:   `</users?page=1&per_page=20>; rel="first", </users?page=1&per_page=20>; rel="last"`,
+		},
+		{
+			name:           "first page",
+			query:          "?per_page=3",
+			expectedStatus: http.StatusOK,
+			expectedUsers:  3,
+			expectedLink:   `</users?page=1&per_page=3>; rel="first", </users?page=2&per_page=3>; rel="next", </users?page=3&per_page=3>; rel="last"`,
+		},
+		{
+			name:           "middle page",
+			query:          "?page=2&per_page=3",
+			expectedStatus: http.StatusOK,
+			expectedUsers:  3,
+			expectedLink:   `</users?page=1&per_page=3>; rel="first", </users?page=1&per_page=3>; rel="prev", </users?page=3&per_page=3>; rel="next", </users?page=3&per_page=3>; rel="last"`,
+		},
+		{
+			name:           "last page",
+			query:          "?page=3&per_page=3",
+			expectedStatus: http.StatusOK,
+			expectedUsers:  1,
+			expectedLink:   `</users?page=1&per_page=3>; rel="first", </users?page=2&per_page=3>; rel="prev", </users?page=3&per_page=3>; rel="last"`,
+		},
+		{
+			name:           "past the end",
+			query:          "?page=9&per_page=3",
+			expectedStatus: http.StatusOK,
+			expectedUsers:  0,
+			expectedLink:   `</users?page=1&per_page=3>; rel="first", </users?page=3&per_page=3>; rel="prev", </users?page=3&per_page=3>; rel="last"`,
+		},
+		{
+			name:           "invalid page",
+			query:          "?page=0&per_page=1000",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				var problem ProblemDetails
+				if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if len(problem.Errors) != 2 {
+					t.Errorf("problem lists %d errors, want 2", len(problem.Errors))
+				}
+				return
+			}
+
+			var users []User
+			if err := json.Unmarshal(rr.Body.Bytes(), &users); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(users) != tt.expectedUsers {
+				t.Errorf("response contains %d users, want %d", len(users), tt.expectedUsers)
+			}
+			if got := rr.Header().Get("X-Total-Count"); got != "7" {
+				t.Errorf("X-Total-Count = %q, want %q", got, "7")
+			}
+			if got := rr.Header().Get("Link"); got != tt.expectedLink {
+				t.Errorf("Link = %q, want %q", got, tt.expectedLink)
+			}
+		})
+	}
+}
+
+func TestUserHandler_PaginationIsStable(t *testing.T) {
+	handler := NewUserHandler(NewInMemoryUserService())
+
+	seen := make(map[string]bool)
+	for page := 1; page <= 3; page++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users?page=%d&per_page=1", page), nil))
+
+		var users []User
+		if err := json.Unmarshal(rr.Body.Bytes(), &users); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(users) != 1 || seen[users[0].ID] {
+			t.Fatalf("page %d returned %v, want one user not seen before", page, users)
+		}
+		seen[users[0].ID] = true
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+// argon2id parameters, following the OWASP recommendation of 19 MiB memory,
+// two iterations and one thread
+const (
+	argon2Memory  uint32 = 19 * 1024
+	argon2Time    uint32 = 2
+	argon2Threads uint8  = 1
+	argon2KeyLen  uint32 = 32
+	argon2SaltLen        = 16
+)
+
+// validatePassword checks the password policy
+func validatePassword(password string) error {
+	var errs ValidationErrors
+	if len(password) < minPasswordLength {
+		errs.Add("password", fmt.Sprintf("password must be at least %d characters", minPasswordLength))
+	}
+	if len(password) > maxPasswordLength {
+		errs.Add("password", fmt.Sprintf("password must be at most %d characters", maxPasswordLength))
+	}
+	return errs.Err()
+}
+
+// hashPassword hashes password with argon2id and a random salt into the PHC
+// string format, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", NewInternalError("failed to generate password salt", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyPassword reports whether password matches an encoded argon2id hash.
+// The parameters are read from the hash, so hashes created with older
+// parameters keep working.
+func verifyPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	got := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// burnPasswordCheck spends the time of a password verification, so unknown
+// emails cannot be told apart from wrong passwords by timing
+func burnPasswordCheck(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = hashPassword("dummy-password")
+	})
+	verifyPassword(dummyPasswordHash, password)
+}
+
+// LoginRequest represents the request body of POST /login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginHandler serves POST /login, exchanging email and password for a local
+// HS256 token accepted by the auth middleware
+type LoginHandler struct {
+	services ServiceResolver
+	local    JWTConfig
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewLoginHandler creates a handler signing tokens with the HMAC secret of
+// local, valid for ttl
+func NewLoginHandler(services ServiceResolver, local JWTConfig, ttl time.Duration) *LoginHandler {
+	return &LoginHandler{
+		services: services,
+		local:    local,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// ServeHTTP handles POST /login
+func (h *LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodOptions:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req LoginRequest
+	if !decodeJSONBody(w, r, &req, true) {
+		return
+	}
+
+	user, err := h.services(r.Context()).Authenticate(req.Email, req.Password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	now := h.now()
+	claims := &Claims{
+		Subject:   user.ID,
+		Issuer:    h.local.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(h.ttl).Unix(),
+	}
+	if h.local.Audience != "" {
+		claims.Audience = audience{h.local.Audience}
+	}
+	token, err := signHS256(h.local.HMACSecret, claims)
+	if err != nil {
+		writeError(w, r, NewInternalError("failed to sign token", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(h.ttl.Seconds()),
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestPasswordHashing(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hashPassword() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("hash got %q want the argon2id PHC format", hash)
+	}
+	if other, _ := hashPassword("correct horse"); other == hash {
+		t.Error("hashes of the same password share a salt")
+	}
+
+	tests := []struct {
+		name     string
+		encoded  string
+		password string
+		want     bool
+	}{
+		{"matching password", hash, "correct horse", true},
+		{"wrong password", hash, "correct horse!", false},
+		{"not an argon2id hash", "$2a$10$abcdefghijklmnopqrstuv", "correct horse", false},
+		{"empty hash", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyPassword(tt.encoded, tt.password); got != tt.want {
+				t.Errorf("verifyPassword() got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInMemoryUserService_Passwords(t *testing.T) {
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+
+	var received []events.Event
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	if _, err := service.RegisterUser("", "invalid", "short"); err == nil {
+		t.Fatal("RegisterUser() accepted invalid input")
+	} else if appErr, _ := IsAppError(err); len(appErr.Errors) != 3 {
+		t.Errorf("RegisterUser() reported %v want name, email and password errors", appErr.Errors)
+	}
+
+	user, err := service.RegisterUser("Alice", "alice@example.com", "s3cret-pass")
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	if user.PasswordHash == "" {
+		t.Fatal("registered user has no password hash")
+	}
+	if data, _ := json.Marshal(user); strings.Contains(string(data), "argon2id") {
+		t.Errorf("password hash is rendered: %s", data)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  ErrorType
+	}{
+		{"valid credentials", "alice@example.com", "s3cret-pass", ""},
+		{"wrong password", "alice@example.com", "wrong-pass", ErrorTypeUnauthorized},
+		{"unknown email", "nobody@example.com", "s3cret-pass", ErrorTypeUnauthorized},
+		{"user without password", "john.doe@example.com", "", ErrorTypeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Authenticate(tt.email, tt.password)
+			if tt.wantErr == "" {
+				if err != nil || got.ID != user.ID {
+					t.Errorf("Authenticate() got %v, %v want %s", got, err, user.ID)
+				}
+				return
+			}
+			if appErr, ok := IsAppError(err); !ok || appErr.Type != tt.wantErr {
+				t.Errorf("Authenticate() error got %v want %s", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := service.ChangePassword(user.ID, "wrong-pass", "n3w-password", 0); err == nil {
+		t.Error("ChangePassword() accepted a wrong current password")
+	}
+	if _, err := service.ChangePassword(user.ID, "s3cret-pass", "short", 0); err == nil {
+		t.Error("ChangePassword() accepted a password violating the policy")
+	}
+	if _, err := service.ChangePassword(user.ID, "s3cret-pass", "n3w-password", user.Version+1); err == nil {
+		t.Error("ChangePassword() ignored a stale version")
+	}
+
+	changed, err := service.ChangePassword(user.ID, "s3cret-pass", "n3w-password", user.Version)
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if changed.Version != user.Version+1 {
+		t.Errorf("version got %d want %d", changed.Version, user.Version+1)
+	}
+	if _, err := service.Authenticate("alice@example.com", "s3cret-pass"); err == nil {
+		t.Error("old password still works")
+	}
+	if _, err := service.Authenticate("alice@example.com", "n3w-password"); err != nil {
+		t.Errorf("new password rejected: %v", err)
+	}
+
+	if last := received[len(received)-1]; last.Type != EventTypeUserPasswordChanged || last.Subject != user.ID {
+		t.Errorf("last event got %s for %s want %s for %s", last.Type, last.Subject, EventTypeUserPasswordChanged, user.ID)
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	service := NewInMemoryUserService()
+	user, err := service.RegisterUser("Alice", "alice@example.com", "s3cret-pass")
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	local := JWTConfig{HMACSecret: []byte("test-secret"), Issuer: "user-service"}
+	handler := NewLoginHandler(SingleService(service), local, time.Hour)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"valid credentials", http.MethodPost, `{"email":"alice@example.com","password":"s3cret-pass"}`, http.StatusOK},
+		{"wrong password", http.MethodPost, `{"email":"alice@example.com","password":"nope-nope"}`, http.StatusUnauthorized},
+		{"unknown field", http.MethodPost, `{"email":"alice@example.com","password":"s3cret-pass","remember":true}`, http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/login", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status got %d want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				AccessToken string `json:"access_token"`
+			}
+			json.NewDecoder(rec.Body).Decode(&body)
+			claims, err := NewJWTValidator(local).Validate(body.AccessToken)
+			if err != nil {
+				t.Fatalf("login token rejected: %v", err)
+			}
+			if claims.Subject != user.ID {
+				t.Errorf("subject got %q want %q", claims.Subject, user.ID)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+	user, err := service.RegisterUser("Alice", "alice@example.com", "s3cret-pass")
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		subject    string
+		ifMatch    bool
+		body       string
+		wantStatus int
+	}{
+		{"without If-Match", "", false, `{"current_password":"s3cret-pass","new_password":"n3w-password"}`, http.StatusPreconditionRequired},
+		{"another user", "someone-else", true, `{"current_password":"s3cret-pass","new_password":"n3w-password"}`, http.StatusForbidden},
+		{"wrong current password", user.ID, true, `{"current_password":"wrong-pass","new_password":"n3w-password"}`, http.StatusUnauthorized},
+		{"weak new password", user.ID, true, `{"current_password":"s3cret-pass","new_password":"short"}`, http.StatusBadRequest},
+		{"changed", user.ID, true, `{"current_password":"s3cret-pass","new_password":"n3w-password"}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users/"+user.ID+"/change-password", strings.NewReader(tt.body))
+			if tt.ifMatch {
+				req.Header.Set("If-Match", "*")
+			}
+			if tt.subject != "" {
+				req = req.WithContext(ContextWithClaims(context.Background(), &Claims{Subject: tt.subject}))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status got %d want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	if _, err := service.Authenticate("alice@example.com", "n3w-password"); err != nil {
+		t.Errorf("new password rejected: %v", err)
+	}
+}
+
+func TestUserHandler_CreateUserWithPassword(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Alice","email":"alice@example.com","password":"s3cret-pass"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status got %d want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "password") || strings.Contains(rec.Body.String(), "argon2id") {
+		t.Errorf("response exposes the password: %s", rec.Body.String())
+	}
+	if _, err := service.Authenticate("alice@example.com", "s3cret-pass"); err != nil {
+		t.Errorf("registered password rejected: %v", err)
+	}
+}
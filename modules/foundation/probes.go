@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Probes serves the Kubernetes-style liveness and readiness endpoints.
+// Liveness only tells whether the process still serves requests, so a
+// failing dependency never gets the pod restarted. Readiness runs the checks
+// of the health registry and fails while draining, so traffic is routed elsewhere.
+type Probes struct {
+	health   *HealthRegistry
+	draining func() bool
+}
+
+// NewProbes creates probes that report not ready when a check of health
+// fails or once draining returns true
+func NewProbes(health *HealthRegistry, draining func() bool) *Probes {
+	return &Probes{health: health, draining: draining}
+}
+
+// LivenessHandler handles GET /healthz: 200 as long as the process serves requests
+func (p *Probes) LivenessHandler() http.Handler {
+	return probeHandler(func(*http.Request) (int, map[string]interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"status":  "alive",
+			"service": "user-service",
+		}
+	})
+}
+
+// ReadinessHandler handles GET /readyz: 200 when every check passes, 503
+// otherwise or while draining. The status and latency of each dependency is listed.
+func (p *Probes) ReadinessHandler() http.Handler {
+	return probeHandler(func(r *http.Request) (int, map[string]interface{}) {
+		if p.draining != nil && p.draining() {
+			return http.StatusServiceUnavailable, map[string]interface{}{
+				"status":  "draining",
+				"service": "user-service",
+			}
+		}
+
+		report := p.health.Check(r.Context())
+		if !report.Healthy {
+			return http.StatusServiceUnavailable, map[string]interface{}{
+				"status":  "not_ready",
+				"service": "user-service",
+				"checks":  report.Dependencies,
+			}
+		}
+		return http.StatusOK, map[string]interface{}{
+			"status":  "ready",
+			"service": "user-service",
+			"checks":  report.Dependencies,
+		}
+	})
+}
+
+// probeHandler answers GET and HEAD requests with the status and JSON body of report
+func probeHandler(report func(*http.Request) (int, map[string]interface{})) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		status, body := report(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			slog.ErrorContext(r.Context(), "Error encoding probe response", "error", err)
+		}
+	})
+}
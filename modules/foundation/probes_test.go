@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbes_Readiness(t *testing.T) {
+	passing := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name       string
+		checks     map[string]HealthCheck
+		draining   bool
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{"no checks", nil, false, http.StatusOK, "ready", map[string]string{}},
+		{"all pass", map[string]HealthCheck{"users": passing, "sessions": passing}, false, http.StatusOK, "ready", map[string]string{"users": "up", "sessions": "up"}},
+		{"one fails", map[string]HealthCheck{"users": passing, "sessions": failing}, false, http.StatusServiceUnavailable, "not_ready", map[string]string{"users": "up", "sessions": "down"}},
+		{"draining", map[string]HealthCheck{"users": passing}, true, http.StatusServiceUnavailable, "draining", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := NewHealthRegistry()
+			for name, check := range tt.checks {
+				health.Register(name, 0, check)
+			}
+			probes := NewProbes(health, func() bool { return tt.draining })
+
+			rr := httptest.NewRecorder()
+			probes.ReadinessHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rr.Code != tt.wantCode {
+				t.Errorf("status code got %v want %v", rr.Code, tt.wantCode)
+			}
+
+			var body struct {
+				Status string                      `json:"status"`
+				Checks map[string]DependencyHealth `json:"checks"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+			if body.Status != tt.wantStatus {
+				t.Errorf("status got %q want %q", body.Status, tt.wantStatus)
+			}
+			if len(body.Checks) != len(tt.wantChecks) {
+				t.Errorf("checks got %v want %v", body.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if body.Checks[name].Status != want {
+					t.Errorf("check %s got %q want %q", name, body.Checks[name].Status, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProbes_Liveness(t *testing.T) {
+	// A failing dependency or draining must not make the process look dead
+	health := NewHealthRegistry()
+	health.Register("users", 0, func(context.Context) error { return errors.New("unavailable") })
+	probes := NewProbes(health, func() bool { return true })
+
+	tests := []struct {
+		method   string
+		wantCode int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodHead, http.StatusOK},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			probes.LivenessHandler().ServeHTTP(rr, httptest.NewRequest(tt.method, "/healthz", nil))
+			if rr.Code != tt.wantCode {
+				t.Errorf("status code got %v want %v", rr.Code, tt.wantCode)
+			}
+		})
+	}
+}
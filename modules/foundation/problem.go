@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// problemContentType is the media type of RFC 7807 problem documents
+const problemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the type URI of problems derived from an ErrorType
+const problemTypeBase = "/problems/"
+
+// legacyErrorFormat switches error responses back to the {"error": {...}}
+// body used before problem documents. It is set from ERROR_FORMAT at startup.
+var legacyErrorFormat bool
+
+// loadErrorFormat reads the ERROR_FORMAT setting ("problem" or "legacy")
+func loadErrorFormat(format string) (legacy bool, err error) {
+	switch format {
+	case "problem":
+		return false, nil
+	case "legacy":
+		return true, nil
+	default:
+		return false, NewValidationError("ERROR_FORMAT", "must be 'problem' or 'legacy'")
+	}
+}
+
+// ProblemDetails is an RFC 7807 problem document. Code, Field, Errors and
+// Extensions are extension members carrying the AppError type, field, field
+// errors and details.
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Code       ErrorType
+	Field      string
+	Errors     []FieldError
+	Extensions map[string]interface{}
+}
+
+// NewProblem creates a problem without a specific type for a status and message
+func NewProblem(status int, detail string, r *http.Request) *ProblemDetails {
+	p := &ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+	return p
+}
+
+// NewProblemFromError creates a problem from an application error
+func NewProblemFromError(err *AppError, r *http.Request) *ProblemDetails {
+	p := NewProblem(err.HTTPStatusCode(), err.Message, r)
+	p.Type = problemTypeBase + strings.ReplaceAll(strings.ToLower(string(err.Type)), "_", "-")
+	p.Code = err.Type
+	p.Field = err.Field
+	p.Errors = err.Errors
+	p.Extensions = err.Details
+	return p
+}
+
+// MarshalJSON writes the standard members followed by the extension members.
+// Extensions never override standard members.
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	body := make(map[string]interface{}, len(p.Extensions)+8)
+	for key, value := range p.Extensions {
+		body[key] = value
+	}
+
+	body["type"] = p.Type
+	body["title"] = p.Title
+	body["status"] = p.Status
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		body["instance"] = p.Instance
+	}
+	if p.Code != "" {
+		body["code"] = p.Code
+	}
+	if p.Field != "" {
+		body["field"] = p.Field
+	}
+	if len(p.Errors) > 0 {
+		body["errors"] = p.Errors
+	}
+	return json.Marshal(body)
+}
+
+// writeProblem writes a problem document with the application/problem+json media type
+func writeProblem(w http.ResponseWriter, p *ProblemDetails) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.Error("Error encoding problem response", "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError_ProblemDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected map[string]interface{}
+	}{
+		{
+			name: "validation error",
+			err:  NewValidationError("email", "invalid email format"),
+			expected: map[string]interface{}{
+				"type":     "/problems/validation-error",
+				"title":    "Bad Request",
+				"status":   float64(http.StatusBadRequest),
+				"detail":   "invalid email format",
+				"instance": "/users/42",
+				"code":     "VALIDATION_ERROR",
+				"field":    "email",
+			},
+		},
+		{
+			name: "details become extension members",
+			err:  NewForbiddenError(PermissionUsersDelete),
+			expected: map[string]interface{}{
+				"type":       "/problems/forbidden-error",
+				"title":      "Forbidden",
+				"status":     float64(http.StatusForbidden),
+				"detail":     "missing permission 'users:delete'",
+				"instance":   "/users/42",
+				"code":       "FORBIDDEN_ERROR",
+				"permission": "users:delete",
+			},
+		},
+		{
+			name: "unexpected error",
+			err:  http.ErrBodyNotAllowed,
+			expected: map[string]interface{}{
+				"type":     "about:blank",
+				"title":    "Internal Server Error",
+				"status":   float64(http.StatusInternalServerError),
+				"detail":   "internal server error",
+				"instance": "/users/42",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/users/42", nil)
+			rr := httptest.NewRecorder()
+			writeError(rr, req, tt.err)
+
+			if ct := rr.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(body) != len(tt.expected) {
+				t.Errorf("problem has %d members, want %d: %v", len(body), len(tt.expected), body)
+			}
+			for key, want := range tt.expected {
+				if body[key] != want {
+					t.Errorf("problem[%q] = %v, want %v", key, body[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteError_LegacyFormat(t *testing.T) {
+	legacyErrorFormat = true
+	t.Cleanup(func() { legacyErrorFormat = false })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	rr := httptest.NewRecorder()
+	writeError(rr, req, NewNotFoundError("user", "42"))
+
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var body struct {
+		Error struct {
+			Type    ErrorType `json:"type"`
+			Message string    `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if body.Error.Type != ErrorTypeNotFound || body.Error.Message != "user with id '42' not found" {
+		t.Errorf("unexpected legacy error body: %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import "net/http"
+
+// serverProtocols returns the protocols the server accepts. HTTP/1.1 is
+// always served; with TLS, HTTP/2 is negotiated through ALPN, and without
+// TLS h2c lets gRPC and multiplexing HTTP/2 clients share the same port.
+func serverProtocols(tlsEnabled, h2c bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if tlsEnabled {
+		protocols.SetHTTP2(true)
+	}
+	if h2c {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	return protocols
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerProtocols_H2C(t *testing.T) {
+	tests := []struct {
+		name      string
+		h2c       bool
+		wantProto int
+	}{
+		{"h2c enabled", true, 2},
+		{"h2c disabled", false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			server.Config.Protocols = serverProtocols(false, tt.h2c)
+			server.Start()
+			defer server.Close()
+
+			// The client prefers h2c but falls back to HTTP/1.1
+			clientProtocols := new(http.Protocols)
+			clientProtocols.SetHTTP1(!tt.h2c)
+			clientProtocols.SetUnencryptedHTTP2(tt.h2c)
+			client := &http.Client{Transport: &http.Transport{Protocols: clientProtocols}}
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.ProtoMajor != tt.wantProto {
+				t.Errorf("response protocol = %s, want HTTP/%d", resp.Proto, tt.wantProto)
+			}
+		})
+	}
+}
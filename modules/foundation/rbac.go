@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// Role represents a named set of permissions that can be assigned to a user
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// Permission represents a single operation a caller may perform
+type Permission string
+
+const (
+	PermissionUsersRead           Permission = "users:read"
+	PermissionUsersCreate         Permission = "users:create"
+	PermissionUsersUpdate         Permission = "users:update"
+	PermissionUsersDelete         Permission = "users:delete"
+	PermissionUsersAssignRoles    Permission = "users:assign-roles"
+	PermissionUsersSetStatus      Permission = "users:set-status"
+	PermissionUsersChangePassword Permission = "users:change-password"
+	PermissionDemoDataManage      Permission = "demo-data:manage"
+	PermissionDebugRead           Permission = "debug:read"
+	PermissionAuditRead           Permission = "audit:read"
+	PermissionLogLevelManage      Permission = "log-level:manage"
+	PermissionEventsRead          Permission = "events:read"
+)
+
+// rolePermissions defines which permissions each role grants
+var rolePermissions = map[Role][]Permission{
+	// Everyone may change their own password; the handler checks ownership
+	RoleViewer: {PermissionUsersRead, PermissionUsersChangePassword},
+	RoleEditor: {PermissionUsersRead, PermissionUsersChangePassword, PermissionUsersCreate, PermissionUsersUpdate},
+	RoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersChangePassword,
+		PermissionUsersCreate,
+		PermissionUsersUpdate,
+		PermissionUsersDelete,
+		PermissionUsersAssignRoles,
+		PermissionUsersSetStatus,
+		PermissionDemoDataManage,
+		PermissionDebugRead,
+		PermissionAuditRead,
+		PermissionLogLevelManage,
+		PermissionEventsRead,
+	},
+}
+
+// IsValid reports whether the role is a known role
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Grants reports whether the role includes the given permission
+func (r Role) Grants(permission Permission) bool {
+	return slices.Contains(rolePermissions[r], permission)
+}
+
+// hasPermission reports whether any of the roles grants the permission
+func hasPermission(roles []Role, permission Permission) bool {
+	for _, role := range roles {
+		if role.Grants(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// userRoutePermissions maps each route of the user API to the permission it
+// requires. NewUserHandler registers only these routes, and
+// userOperationPermission matches requests against the same patterns, so
+// routing and authorization cannot disagree.
+var userRoutePermissions = map[string]Permission{
+	"GET /users":                       PermissionUsersRead,
+	"GET /users/{$}":                   PermissionUsersRead,
+	"POST /users":                      PermissionUsersCreate,
+	"POST /users/{$}":                  PermissionUsersCreate,
+	"POST /users/batch-get":            PermissionUsersRead,
+	"GET /users/by-email/{email}":      PermissionUsersRead,
+	"GET /users/{id}":                  PermissionUsersRead,
+	"PUT /users/{id}":                  PermissionUsersUpdate,
+	"DELETE /users/{id}":               PermissionUsersDelete,
+	"PUT /users/{id}/roles":            PermissionUsersAssignRoles,
+	"POST /users/{id}/change-password": PermissionUsersChangePassword,
+	"POST /users/{id}/activate":        PermissionUsersSetStatus,
+	"POST /users/{id}/suspend":         PermissionUsersSetStatus,
+	"POST /users/{id}/avatar":          PermissionUsersUpdate,
+}
+
+// userPermissionRoutes matches requests to the patterns of userRoutePermissions
+var userPermissionRoutes = func() *http.ServeMux {
+	mux := http.NewServeMux()
+	for pattern := range userRoutePermissions {
+		mux.Handle(pattern, http.NotFoundHandler())
+	}
+	return mux
+}()
+
+// userOperationPermission returns the permission required by the route of the
+// user API serving r. Requests no route serves are answered 404 or 405, which
+// only needs the permission to read users.
+func userOperationPermission(r *http.Request) Permission {
+	_, pattern := userPermissionRoutes.Handler(r)
+	if permission, ok := userRoutePermissions[pattern]; ok {
+		return permission
+	}
+	return PermissionUsersRead
+}
+
+// Authorizer resolves the roles of the caller and checks them against the
+// permission required by an operation
+type Authorizer struct {
+	services ServiceResolver
+}
+
+// NewAuthorizer creates a new Authorizer looking up user records in the
+// service of the caller's tenant
+func NewAuthorizer(services ServiceResolver) *Authorizer {
+	return &Authorizer{
+		services: services,
+	}
+}
+
+// RolesFor returns the effective roles of the caller described by claims.
+// Every caller, including anonymous ones, has the viewer role. Roles come
+// from the token's "roles" claim and from the user record matching the
+// subject in the tenant of ctx.
+func (a *Authorizer) RolesFor(ctx context.Context, claims *Claims) []Role {
+	roles := []Role{RoleViewer}
+	if claims == nil {
+		return roles
+	}
+
+	roles = append(roles, claims.Roles...)
+	if user, err := a.services(ctx).GetUserByID(claims.Subject); err == nil {
+		roles = append(roles, user.Roles...)
+	}
+	return roles
+}
+
+// Authorize returns a forbidden error when the caller in ctx lacks the permission
+func (a *Authorizer) Authorize(ctx context.Context, permission Permission) error {
+	claims, _ := ClaimsFromContext(ctx)
+	if !hasPermission(a.RolesFor(ctx, claims), permission) {
+		return NewForbiddenError(permission)
+	}
+	return nil
+}
+
+// Middleware enforces the permission returned by permissionFor for every request
+func (a *Authorizer) Middleware(permissionFor func(*http.Request) Permission, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Authorize(r.Context(), permissionFor(r)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRole_Grants(t *testing.T) {
+	tests := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{RoleViewer, PermissionUsersRead, true},
+		{RoleViewer, PermissionUsersCreate, false},
+		{RoleEditor, PermissionUsersUpdate, true},
+		{RoleEditor, PermissionUsersDelete, false},
+		{RoleAdmin, PermissionUsersDelete, true},
+		{RoleAdmin, PermissionUsersAssignRoles, true},
+		{Role("guest"), PermissionUsersRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+" "+string(tt.permission), func(t *testing.T) {
+			if got := tt.role.Grants(tt.permission); got != tt.want {
+				t.Errorf("Role.Grants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_Middleware(t *testing.T) {
+	service := NewInMemoryUserService()
+	editor, err := service.CreateUser("Editor User", "editor@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	if _, err := service.AssignRoles(editor.ID, []Role{RoleEditor}, 0); err != nil {
+		t.Fatalf("Failed to assign roles: %v", err)
+	}
+
+	authorizer := NewAuthorizer(SingleService(service))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := authorizer.Middleware(userOperationPermission, next)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		claims         *Claims
+		expectedStatus int
+		missing        Permission
+	}{
+		{"anonymous read", http.MethodGet, "/users", nil, http.StatusOK, ""},
+		{"anonymous create", http.MethodPost, "/users", nil, http.StatusForbidden, PermissionUsersCreate},
+		{"anonymous batch get", http.MethodPost, "/users/batch-get", nil, http.StatusOK, ""},
+		{"editor role from user record", http.MethodPut, "/users/1", &Claims{Subject: editor.ID}, http.StatusOK, ""},
+		{"editor cannot delete", http.MethodDelete, "/users/1", &Claims{Subject: editor.ID}, http.StatusForbidden, PermissionUsersDelete},
+		{"admin role from token", http.MethodDelete, "/users/1", &Claims{Subject: "svc", Roles: []Role{RoleAdmin}}, http.StatusOK, ""},
+		{"editor uploads avatar", http.MethodPost, "/users/1/avatar", &Claims{Subject: editor.ID}, http.StatusOK, ""},
+		{"anonymous avatar upload", http.MethodPost, "/users/1/avatar", nil, http.StatusForbidden, PermissionUsersUpdate},
+		{"viewer cannot assign roles", http.MethodPut, "/users/1/roles", &Claims{Subject: "svc", Roles: []Role{RoleViewer}}, http.StatusForbidden, PermissionUsersAssignRoles},
+		{"viewer cannot delete a user named like a route", http.MethodDelete, "/users/change-password", &Claims{Subject: "svc", Roles: []Role{RoleViewer}}, http.StatusForbidden, PermissionUsersDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`))
+			if tt.claims != nil {
+				req = req.WithContext(ContextWithClaims(req.Context(), tt.claims))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("middleware returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			if tt.missing != "" {
+				var body struct {
+					Permission string `json:"permission"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if got := body.Permission; got != string(tt.missing) {
+					t.Errorf("missing permission = %q, want %q", got, tt.missing)
+				}
+			}
+		})
+	}
+}
+
+func TestUserOperationPermission(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   Permission
+	}{
+		{http.MethodGet, "/users/", PermissionUsersRead},
+		{http.MethodPost, "/users/", PermissionUsersCreate},
+		{http.MethodHead, "/users/1", PermissionUsersRead},
+		{http.MethodPost, "/users/1/change-password", PermissionUsersChangePassword},
+		{http.MethodDelete, "/users/change-password", PermissionUsersDelete},
+		{http.MethodPut, "/users/activate", PermissionUsersUpdate},
+		{http.MethodPost, "/users/1/suspend", PermissionUsersSetStatus},
+		{http.MethodDelete, "/users/1/roles", PermissionUsersRead},
+		{http.MethodPatch, "/users/1", PermissionUsersRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := userOperationPermission(req); got != tt.want {
+				t.Errorf("userOperationPermission() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserHandler_AssignRoles(t *testing.T) {
+	service := NewInMemoryUserService()
+	handler := NewUserHandler(service)
+
+	user, err := service.CreateUser("Test User", "test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"unknown role", `{"roles":["superuser"]}`, http.StatusBadRequest},
+		{"no roles", `{"roles":[]}`, http.StatusBadRequest},
+		{"valid roles", `{"roles":["editor","viewer"]}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/users/"+user.ID+"/roles", strings.NewReader(tt.body))
+			req.Header.Set("If-Match", "*")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	updated, err := service.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get test user: %v", err)
+	}
+	if len(updated.Roles) != 2 || updated.Roles[0] != RoleEditor {
+		t.Errorf("AssignRoles() roles = %v, want [editor viewer]", updated.Roles)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// httpPanics counts handler panics recovered by recoveryMiddleware
+var httpPanics = expvar.NewInt("http_panics_total")
+
+// recoveryMiddleware recovers from panics in next so a failing handler does
+// not crash the process. The panic is logged with its stack and request ID,
+// counted in http_panics_total, sent to reporter and answered with a 500
+// problem response.
+func recoveryMiddleware(reporter ErrorReporter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			stack := debug.Stack()
+			if hp, ok := p.(*handlerPanic); ok {
+				// Recovered in another goroutine, whose stack it carries
+				p, stack = hp.value, hp.stack
+			}
+			if p == http.ErrAbortHandler {
+				// Deliberate aborts are handled by net/http
+				panic(p)
+			}
+
+			logPanic(r, p, stack)
+			requestID := RequestIDFromContext(r.Context())
+			cause := fmt.Errorf("panic: %v", p)
+			if err, ok := p.(error); ok {
+				cause = fmt.Errorf("panic: %w", err)
+			}
+			reporter.Report(r.Context(), cause)
+
+			if rw.wroteHeader {
+				// Part of the response is already sent; it cannot be replaced
+				return
+			}
+			err := NewInternalError("internal server error", cause)
+			err.Details = map[string]interface{}{
+				"request_id": requestID,
+			}
+			writeError(rw, r, err)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// handlerPanic is a panic recovered in the goroutine running a handler and
+// raised again in the goroutine serving the request, with the stack of the
+// handler
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// logPanic logs the panic p of the handler serving r with its stack and
+// counts it in http_panics_total
+func logPanic(r *http.Request, p interface{}, stack []byte) {
+	httpPanics.Add(1)
+	slog.ErrorContext(r.Context(), "Panic serving request",
+		"method", r.Method, "path", r.URL.Path, "panic", p, "stack", string(stack))
+}
+
+// recoveryWriter records whether the response was started
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader records that the response was started
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write records that the response was started
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+		recovered      int64
+	}{
+		{
+			name: "no panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ok",
+		},
+		{
+			name: "panic before response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			expectedStatus: http.StatusInternalServerError,
+			recovered:      1,
+		},
+		{
+			name: "panic after response started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "partial",
+			recovered:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := httpPanics.Value()
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set(requestIDHeader, "req-123")
+
+			rr := httptest.NewRecorder()
+			requestIDMiddleware(recoveryMiddleware(noopReporter{}, tt.handler)).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("middleware returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if got := httpPanics.Value() - before; got != tt.recovered {
+				t.Errorf("http_panics_total increased by %d, want %d", got, tt.recovered)
+			}
+			if tt.expectedBody != "" {
+				if rr.Body.String() != tt.expectedBody {
+					t.Errorf("body = %q, want %q", rr.Body.String(), tt.expectedBody)
+				}
+				return
+			}
+
+			var problem map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if problem["code"] != string(ErrorTypeInternal) || problem["request_id"] != "req-123" {
+				t.Errorf("unexpected problem: %v", problem)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddleware_AbortHandler(t *testing.T) {
+	handler := recoveryMiddleware(noopReporter{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler to propagate", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+}
+
+func TestRecoveryMiddleware_CompressedResponse(t *testing.T) {
+	handler := recoveryMiddleware(noopReporter{}, compressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"partial":`))
+		panic("boom")
+	}), defaultCompressionMinSize))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("middleware returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+	var problem map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to unmarshal response %q: %v", rr.Body.String(), err)
+	}
+	if problem["code"] != string(ErrorTypeInternal) {
+		t.Errorf("unexpected problem: %v", problem)
+	}
+}
+
+func TestRecoveryMiddleware_TimedHandler(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+
+	handler := recoveryMiddleware(noopReporter{}, timeoutMiddleware(time.Second, http.HandlerFunc(panicInHandler)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("middleware returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(logs.String(), "panicInHandler") || !strings.Contains(logs.String(), "panic=boom") {
+		t.Errorf("log %q does not show the panic with the handler frame", logs.String())
+	}
+}
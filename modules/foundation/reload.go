@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 2 * time.Second
+
+// Triggers of a configuration reload
+const (
+	reloadTriggerFile   = "file"
+	reloadTriggerSignal = "sighup"
+)
+
+// reloadableSettings are the settings applied without a restart. Changes to
+// any other setting are reported as requiring one.
+var reloadableSettings = map[string]bool{
+	"log.level":     true,
+	"log.canonical": true,
+}
+
+// ConfigReloadedData is the payload of ops.config_reloaded events
+type ConfigReloadedData struct {
+	Trigger         string   `json:"trigger"`
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// RuntimeSettings are the settings of the running process a reload changes
+type RuntimeSettings struct {
+	LogLevel     *slog.LevelVar
+	CanonicalLog *atomic.Bool
+}
+
+// ConfigReloader reloads the configuration when its file changes or the
+// process receives SIGHUP, applies the reloadable settings to the running
+// process and publishes every reload as an operational event
+type ConfigReloader struct {
+	load      func() (Config, error)
+	runtime   RuntimeSettings
+	publisher events.Publisher
+	ids       uuid.IDGenerator
+
+	mutex   sync.Mutex
+	current Config
+	modTime time.Time
+}
+
+// NewConfigReloader creates a reloader of the current configuration, loading
+// new ones with load, with event IDs from ids
+func NewConfigReloader(current Config, load func() (Config, error), runtime RuntimeSettings, publisher events.Publisher, ids uuid.IDGenerator) *ConfigReloader {
+	r := &ConfigReloader{
+		load:      load,
+		runtime:   runtime,
+		publisher: publisher,
+		ids:       ids,
+		current:   current,
+	}
+	r.modTime, _ = r.fileModTime()
+	return r
+}
+
+// Run reloads the configuration on every signal received and whenever the
+// modification time of the config file changes, checked every interval,
+// until ctx is done
+func (r *ConfigReloader) Run(ctx context.Context, signals <-chan os.Signal, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.Reload(ctx, reloadTriggerSignal)
+		case <-ticker.C:
+			modTime, err := r.fileModTime()
+			r.mutex.Lock()
+			changed := err == nil && !modTime.Equal(r.modTime)
+			r.mutex.Unlock()
+			if changed {
+				r.Reload(ctx, reloadTriggerFile)
+			}
+		}
+	}
+}
+
+// Reload loads the configuration and applies the reloadable settings that
+// changed. An invalid configuration is logged and the current one kept.
+func (r *ConfigReloader) Reload(ctx context.Context, trigger string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Read the file's time first, so a write during the reload triggers another
+	modTime, _ := r.fileModTime()
+	updated, err := r.load()
+	r.modTime = modTime
+	if err != nil {
+		slog.ErrorContext(ctx, "Configuration reload failed, keeping the current configuration", "trigger", trigger, "error", err)
+		return err
+	}
+
+	changed, err := config.Changed(&r.current, &updated)
+	if err != nil {
+		return err
+	}
+	data := ConfigReloadedData{Trigger: trigger, Applied: []string{}}
+	for _, key := range changed {
+		if reloadableSettings[key] {
+			data.Applied = append(data.Applied, key)
+		} else {
+			data.RestartRequired = append(data.RestartRequired, key)
+		}
+	}
+
+	level, err := logging.ParseLevel(updated.Log.Level)
+	if err != nil {
+		slog.ErrorContext(ctx, "Configuration reload failed, keeping the current configuration", "trigger", trigger, "error", err)
+		return err
+	}
+	// A level set through PUT /admin/log-level stays until the file changes it
+	if updated.Log.Level != r.current.Log.Level {
+		r.runtime.LogLevel.Set(level)
+	}
+	r.runtime.CanonicalLog.Store(updated.Log.Canonical)
+	// Settings needing a restart keep their current values, so they are
+	// reported again by later reloads until then
+	r.current.Log = updated.Log
+
+	// Logged at warn like log level changes, so reloads show up at every level but error
+	slog.WarnContext(ctx, "Configuration reloaded", "trigger", trigger,
+		"applied", strings.Join(data.Applied, ","), "restart_required", strings.Join(data.RestartRequired, ","))
+	event, err := newEvent(r.ids, EventTypeConfigReloaded, "config", "", data)
+	if err != nil {
+		return err
+	}
+	if err := r.publisher.Publish(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "Failed to publish event", "event_type", event.Type, "error", err)
+	}
+	return nil
+}
+
+// fileModTime returns the modification time of the config file, or the zero
+// time when there is none
+func (r *ConfigReloader) fileModTime() (time.Time, error) {
+	if r.current.File == "" {
+		return time.Time{}, nil
+	}
+	info, err := os.Stat(r.current.File)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
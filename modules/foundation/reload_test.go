@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// reloadFixture is a config file loaded into a ConfigReloader, recording the
+// events it publishes
+type reloadFixture struct {
+	path      string
+	reloader  *ConfigReloader
+	level     *slog.LevelVar
+	canonical *atomic.Bool
+
+	mutex  sync.Mutex
+	events []events.Event
+}
+
+func newReloadFixture(t *testing.T, file string) *reloadFixture {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("CANONICAL_LOG", "")
+
+	f := &reloadFixture{path: filepath.Join(t.TempDir(), "config.yaml")}
+	f.write(t, file)
+	load := func() (Config, error) {
+		cfg, _, err := parseConfig(nil, []string{"--config", f.path})
+		return cfg, err
+	}
+	cfg, err := load()
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+
+	f.level = new(slog.LevelVar)
+	f.canonical = new(atomic.Bool)
+	f.canonical.Store(cfg.Log.Canonical)
+	bus := NewEventBus()
+	bus.Subscribe(func(_ context.Context, event events.Event) error {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.events = append(f.events, event)
+		return nil
+	})
+	f.reloader = NewConfigReloader(cfg, load, RuntimeSettings{LogLevel: f.level, CanonicalLog: f.canonical}, bus, uuid.GoogleGenerator)
+	return f
+}
+
+// write replaces the config file, moving its modification time forward so
+// every write is seen as a change
+func (f *reloadFixture) write(t *testing.T, file string) {
+	t.Helper()
+	if err := os.WriteFile(f.path, []byte(file), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	modTime := time.Now().Add(time.Duration(len(file)) * time.Second)
+	if err := os.Chtimes(f.path, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() error: %v", err)
+	}
+}
+
+// reloadedData decodes the data of an ops.config_reloaded event
+func reloadedData(t *testing.T, event events.Event) ConfigReloadedData {
+	t.Helper()
+	var data ConfigReloadedData
+	if err := event.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func (f *reloadFixture) published() []events.Event {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]events.Event(nil), f.events...)
+}
+
+func TestConfigReloader_Reload(t *testing.T) {
+	f := newReloadFixture(t, "log:\n  level: info\n")
+
+	f.write(t, "log:\n  level: debug\n  canonical: false\nserver:\n  port: \"9000\"\n")
+	if err := f.reloader.Reload(context.Background(), reloadTriggerFile); err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if f.level.Level() != slog.LevelDebug {
+		t.Errorf("level got %v want %v", f.level.Level(), slog.LevelDebug)
+	}
+	if f.canonical.Load() {
+		t.Errorf("canonical log got enabled want disabled")
+	}
+
+	published := f.published()
+	if len(published) != 1 {
+		t.Fatalf("events got %d want 1", len(published))
+	}
+	if published[0].Type != EventTypeConfigReloaded || published[0].Subject != "config" {
+		t.Errorf("event got %s of %q want %s of config", published[0].Type, published[0].Subject, EventTypeConfigReloaded)
+	}
+	want := ConfigReloadedData{
+		Trigger:         reloadTriggerFile,
+		Applied:         []string{"log.level", "log.canonical"},
+		RestartRequired: []string{"server.port"},
+	}
+	if got := reloadedData(t, published[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("event data got %+v want %+v", got, want)
+	}
+
+	// A level set at runtime stays while the file does not change it
+	f.level.Set(slog.LevelWarn)
+	if err := f.reloader.Reload(context.Background(), reloadTriggerSignal); err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if f.level.Level() != slog.LevelWarn {
+		t.Errorf("level got %v want the runtime level %v", f.level.Level(), slog.LevelWarn)
+	}
+	published = f.published()
+	want = ConfigReloadedData{Trigger: reloadTriggerSignal, Applied: []string{}, RestartRequired: []string{"server.port"}}
+	if len(published) != 2 || !reflect.DeepEqual(reloadedData(t, published[1]), want) {
+		t.Errorf("events got %+v want a second reload with %+v", published, want)
+	}
+}
+
+func TestConfigReloader_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"invalid value", "log:\n  canonical: sometimes\n"},
+		{"unknown log level", "log:\n  level: verbose\n"},
+		{"unknown key", "log:\n  colour: true\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newReloadFixture(t, "log:\n  level: info\n")
+
+			f.write(t, tt.file)
+			if err := f.reloader.Reload(context.Background(), reloadTriggerFile); err == nil {
+				t.Fatalf("Reload() got no error")
+			}
+			if f.level.Level() != slog.LevelInfo || !f.canonical.Load() {
+				t.Errorf("got level %v and canonical log %v want the current info and true", f.level.Level(), f.canonical.Load())
+			}
+			if published := f.published(); len(published) != 0 {
+				t.Errorf("events got %d want 0", len(published))
+			}
+		})
+	}
+}
+
+func TestConfigReloader_Run(t *testing.T) {
+	f := newReloadFixture(t, "log:\n  level: info\n")
+	signals := make(chan os.Signal, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.reloader.Run(ctx, signals, 10*time.Millisecond)
+		close(done)
+	}()
+
+	waitForEvents := func(n int) []events.Event {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if published := f.published(); len(published) >= n {
+				return published
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("events got %d want %d", len(f.published()), n)
+		return nil
+	}
+
+	// Unchanged files are not reloaded
+	time.Sleep(50 * time.Millisecond)
+	if published := f.published(); len(published) != 0 {
+		t.Fatalf("events got %d before any change want 0", len(published))
+	}
+
+	f.write(t, "log:\n  level: error\n")
+	published := waitForEvents(1)
+	var data ConfigReloadedData
+	if err := published[0].Decode(&data); err != nil || data.Trigger != reloadTriggerFile {
+		t.Errorf("trigger got %q want %q", data.Trigger, reloadTriggerFile)
+	}
+	if f.level.Level() != slog.LevelError {
+		t.Errorf("level got %v want %v", f.level.Level(), slog.LevelError)
+	}
+
+	signals <- syscall.SIGHUP
+	published = waitForEvents(2)
+	if err := published[1].Decode(&data); err != nil || data.Trigger != reloadTriggerSignal {
+		t.Errorf("trigger got %q want %q", data.Trigger, reloadTriggerSignal)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not stop when its context was done")
+	}
+}
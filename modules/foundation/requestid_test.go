@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{"generated", "", false},
+		{"client ID reused", "abc-123_x.y", true},
+		{"unsafe client ID replaced", "bad id\nwith newline", false},
+		{"overlong client ID replaced", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if seen == "" || rr.Header().Get(requestIDHeader) != seen {
+				t.Fatalf("context ID %q, response header %q", seen, rr.Header().Get(requestIDHeader))
+			}
+			if (seen == tt.incoming) != tt.reused {
+				t.Errorf("request ID = %q, reused %v, want reused %v", seen, seen == tt.incoming, tt.reused)
+			}
+		})
+	}
+}
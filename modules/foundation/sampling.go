@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxPendingTraces bounds the traces a tail sampler holds at once. Spans of
+// traces started while it is full are decided one by one.
+const maxPendingTraces = 10000
+
+// SamplingMode selects when the sampling decision of a trace is made
+type SamplingMode string
+
+const (
+	// SamplingHead decides when a trace starts, keeping a fraction of them
+	SamplingHead SamplingMode = "head"
+	// SamplingTail decides when a trace ends, keeping every trace with an
+	// error and a fraction of the others
+	SamplingTail SamplingMode = "tail"
+)
+
+// parseSampleRate parses a fraction between 0 and 1, or a percentage such as "25%"
+func parseSampleRate(value string) (float64, error) {
+	percentage, isPercentage := strings.CutSuffix(strings.TrimSpace(value), "%")
+	rate, err := strconv.ParseFloat(percentage, 64)
+	if isPercentage {
+		rate /= 100
+	}
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("TRACE_SAMPLE_RATE must be a number between 0 and 1 or a percentage")
+	}
+	return rate, nil
+}
+
+// tailSamplingProcessor buffers the spans of each trace until its local root
+// span ends, then passes the whole trace to next when one of its spans failed
+// or when the trace is among the sampled fraction of the others. Spans ending
+// after their local root are decided on their own; the fraction is chosen by
+// trace ID, so they follow the decision of a trace kept without error.
+type tailSamplingProcessor struct {
+	next    sdktrace.SpanProcessor
+	sampler sdktrace.Sampler
+	pending map[trace.TraceID][]sdktrace.ReadOnlySpan
+	mutex   sync.Mutex
+}
+
+// newTailSamplingProcessor creates a processor passing every trace with an
+// error and a rate fraction of the others to next
+func newTailSamplingProcessor(next sdktrace.SpanProcessor, rate float64) *tailSamplingProcessor {
+	return &tailSamplingProcessor{
+		next:    next,
+		sampler: sdktrace.TraceIDRatioBased(rate),
+		pending: make(map[trace.TraceID][]sdktrace.ReadOnlySpan),
+	}
+}
+
+// OnStart starts buffering the trace of a local root span
+func (p *tailSamplingProcessor) OnStart(_ context.Context, span sdktrace.ReadWriteSpan) {
+	if !isLocalRoot(span) {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.pending) < maxPendingTraces {
+		p.pending[span.SpanContext().TraceID()] = nil
+	}
+}
+
+// OnEnd buffers span and decides on its trace once the local root ended
+func (p *tailSamplingProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	traceID := span.SpanContext().TraceID()
+
+	p.mutex.Lock()
+	spans, buffered := p.pending[traceID]
+	spans = append(spans, span)
+	switch {
+	case !buffered:
+		// The local root already ended or the trace did not fit
+	case isLocalRoot(span):
+		delete(p.pending, traceID)
+	default:
+		p.pending[traceID] = spans
+		p.mutex.Unlock()
+		return
+	}
+	p.mutex.Unlock()
+
+	if !p.keep(traceID, spans) {
+		return
+	}
+	for _, s := range spans {
+		p.next.OnEnd(s)
+	}
+}
+
+// isLocalRoot reports whether span is the first span of its trace in this process
+func isLocalRoot(span sdktrace.ReadOnlySpan) bool {
+	parent := span.Parent()
+	return !parent.IsValid() || parent.IsRemote()
+}
+
+// keep reports whether a trace made of spans is exported
+func (p *tailSamplingProcessor) keep(traceID trace.TraceID, spans []sdktrace.ReadOnlySpan) bool {
+	for _, s := range spans {
+		if s.Status().Code == codes.Error {
+			return true
+		}
+	}
+	result := p.sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: traceID})
+	return result.Decision == sdktrace.RecordAndSample
+}
+
+// Shutdown drops the undecided traces and shuts next down
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.mutex.Lock()
+	p.pending = make(map[trace.TraceID][]sdktrace.ReadOnlySpan)
+	p.mutex.Unlock()
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the decided traces held by next
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
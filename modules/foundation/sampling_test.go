@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestParseSampleRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "1", want: 1},
+		{value: "0.25", want: 0.25},
+		{value: "25%", want: 0.25},
+		{value: "0%", want: 0},
+		{value: "-0.1", wantErr: true},
+		{value: "101%", wantErr: true},
+		{value: "%", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSampleRate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSampleRate() error got %v want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSampleRate() got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailSamplingProcessor(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		childErr  bool
+		wantSpans int
+	}{
+		{name: "trace without error outside the fraction", rate: 0},
+		{name: "trace with an error", rate: 0, childErr: true, wantSpans: 2},
+		{name: "trace without error in the fraction", rate: 1, wantSpans: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			processor := newTailSamplingProcessor(recorder, tt.rate)
+			provider := sdktrace.NewTracerProvider(
+				sdktrace.WithSpanProcessor(processor),
+				sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+			)
+			tracer := provider.Tracer("test")
+
+			ctx, root := tracer.Start(context.Background(), "GET /users")
+			_, child := tracer.Start(ctx, "UserService.GetUserByID")
+			if tt.childErr {
+				child.SetStatus(codes.Error, "store offline")
+			}
+			child.End()
+			if len(recorder.Ended()) != 0 {
+				t.Fatal("spans were exported before the trace ended")
+			}
+			root.End()
+
+			if got := len(recorder.Ended()); got != tt.wantSpans {
+				t.Errorf("exported spans got %d want %d", got, tt.wantSpans)
+			}
+			if len(processor.pending) != 0 {
+				t.Errorf("pending traces got %d want 0", len(processor.pending))
+			}
+		})
+	}
+}
+
+func TestTailSamplingProcessor_LateSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(newTailSamplingProcessor(recorder, 0)),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	tracer := provider.Tracer("test")
+
+	// A span ending after its root, e.g. work continued in a goroutine
+	ctx, root := tracer.Start(context.Background(), "POST /users")
+	_, late := tracer.Start(ctx, "publish")
+	root.End()
+	late.SetStatus(codes.Error, "broker unavailable")
+	late.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 || ended[0].Name() != "publish" {
+		t.Errorf("exported spans got %d want the failed late span", len(ended))
+	}
+}
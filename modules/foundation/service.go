@@ -1,45 +1,198 @@
 package main
 
 import (
-	"crypto/rand"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
 )
 
 // InMemoryUserService implements UserService using in-memory storage
 type InMemoryUserService struct {
-	users map[string]*User
-	mutex sync.RWMutex
+	users      map[string]*User
+	emails     map[string]string // email index: email -> user ID
+	mutex      sync.RWMutex
+	publisher  events.Publisher
+	tenant     string
+	logger     *slog.Logger
+	ids        uuid.IDGenerator
+	userIDs    uuid.IDGenerator
+	now        func() time.Time
+	modifiedAt time.Time
+	strictness EmailStrictness
+	fixtures   []UserFixture
+}
+
+// ServiceOption configures an InMemoryUserService
+type ServiceOption func(*InMemoryUserService)
+
+// WithEventPublisher makes the service publish domain events for every change
+func WithEventPublisher(publisher events.Publisher) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.publisher = publisher
+	}
+}
+
+// WithTenant sets the tenant the store belongs to, recorded in its events
+func WithTenant(tenant string) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.tenant = tenant
+	}
+}
+
+// WithLogger sets the logger reporting events that could not be published
+func WithLogger(logger *slog.Logger) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.logger = logger
+	}
+}
+
+// WithIDGenerator sets the generator of the IDs of users and events
+func WithIDGenerator(ids uuid.IDGenerator) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.ids = ids
+	}
+}
+
+// WithUserIDGenerator sets the generator of user IDs, e.g. one adding a
+// prefix; it defaults to the generator of WithIDGenerator
+func WithUserIDGenerator(ids uuid.IDGenerator) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.userIDs = ids
+	}
+}
+
+// WithClock sets the clock timestamping changes, e.g. a fixed one for stable
+// output in tests
+func WithClock(now func() time.Time) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.now = now
+	}
+}
+
+// WithEmailValidation sets how strictly the emails of users are validated
+func WithEmailValidation(strictness EmailStrictness) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.strictness = strictness
+	}
+}
+
+// WithFixtures sets the demo users seeded when the store is created and by
+// Seed, instead of the default ones
+func WithFixtures(users []UserFixture) ServiceOption {
+	return func(s *InMemoryUserService) {
+		s.fixtures = users
+	}
 }
 
 // NewInMemoryUserService creates a new instance of InMemoryUserService
-func NewInMemoryUserService() *InMemoryUserService {
+func NewInMemoryUserService(opts ...ServiceOption) *InMemoryUserService {
 	service := &InMemoryUserService{
-		users: make(map[string]*User),
+		users:      make(map[string]*User),
+		emails:     make(map[string]string),
+		publisher:  noopPublisher{},
+		tenant:     defaultTenant,
+		logger:     slog.Default(),
+		ids:        defaultIDGenerator,
+		now:        time.Now,
+		strictness: defaultEmailStrictness,
+		fixtures:   defaultUserFixtures,
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	service.modifiedAt = service.now()
+	if service.userIDs == nil {
+		service.userIDs = service.ids
 	}
 
-	// Seed with some initial data
+	// Seed with the fixture users
 	service.seedData()
 
 	return service
 }
 
-// seedData adds some initial users for demonstration
+// seedData adds the fixture users for demonstration
 func (s *InMemoryUserService) seedData() {
-	users := []*User{
-		NewUser("John Doe", "john.doe@example.com"),
-		NewUser("Jane Smith", "jane.smith@example.com"),
-		NewUser("Bob Johnson", "bob.johnson@example.com"),
-	}
+	s.addFixtures()
+}
 
-	for _, user := range users {
+// addFixtures stores the fixture users whose email is not taken yet and
+// returns copies of the added users. The caller must hold the mutex.
+func (s *InMemoryUserService) addFixtures() []User {
+	added := []User{}
+	for _, fixture := range s.fixtures {
+		user := fixture.newUser(s.userIDs)
+		if _, exists := s.emails[user.Email]; exists {
+			continue
+		}
+		s.touch(user)
+		user.CreatedAt = user.UpdatedAt
 		s.users[user.ID] = user
+		s.emails[user.Email] = user.ID
+		added = append(added, *user)
+	}
+	return added
+}
+
+// touch records that user changed at the current time of the clock. The
+// caller must hold the mutex.
+func (s *InMemoryUserService) touch(user *User) {
+	s.modifiedAt = s.now()
+	user.UpdatedAt = s.modifiedAt
+}
+
+// Seed loads the fixture users that are missing from the store and publishes
+// a user.created event for each of them. Seeding twice adds nothing.
+func (s *InMemoryUserService) Seed(ctx context.Context) ([]User, error) {
+	s.mutex.Lock()
+	added := s.addFixtures()
+	s.mutex.Unlock()
+
+	for _, user := range added {
+		s.publish(ctx, EventTypeUserCreated, &user, nil)
+	}
+	return added, nil
+}
+
+// Reset removes every user and publishes a user.deleted event for each of
+// them. It returns the number of removed users.
+func (s *InMemoryUserService) Reset(ctx context.Context) (int, error) {
+	s.mutex.Lock()
+	removed := make([]*User, 0, len(s.users))
+	previous := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		previous = append(previous, *user)
+		// Every stored status may move to deleted
+		user.TransitionTo(UserStatusDeleted)
+		s.touch(user)
+		removed = append(removed, user)
+	}
+	s.users = make(map[string]*User)
+	s.emails = make(map[string]string)
+	s.modifiedAt = s.now()
+	s.mutex.Unlock()
+
+	for i, user := range removed {
+		s.publish(ctx, EventTypeUserDeleted, user, &previous[i])
 	}
+	return len(removed), nil
 }
 
-// GetUsers returns all users
+// Count returns the number of users in the store
+func (s *InMemoryUserService) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.users)
+}
+
+// GetUsers returns all users, oldest first
 func (s *InMemoryUserService) GetUsers() ([]User, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -49,6 +202,14 @@ func (s *InMemoryUserService) GetUsers() ([]User, error) {
 		users = append(users, *user)
 	}
 
+	// A stable order keeps pages consistent between requests
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+
 	return users, nil
 }
 
@@ -66,14 +227,87 @@ func (s *InMemoryUserService) GetUserByID(id string) (*User, error) {
 	userCopy := *user
 	return &userCopy, nil
 }
+
+// LastModified returns when a user was last created, changed or removed
+func (s *InMemoryUserService) LastModified() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.modifiedAt
+}
+
+// GetUsersByIDs returns the users with the given IDs, in the order of ids,
+// and the IDs that do not exist. All users are read from the same snapshot.
+func (s *InMemoryUserService) GetUsersByIDs(ids []string) ([]User, []string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]User, 0, len(ids))
+	missing := []string{}
+	for _, id := range ids {
+		if user, exists := s.users[id]; exists {
+			users = append(users, *user)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return users, missing, nil
+}
+
+// GetUserByEmail returns the user with exactly the given email
+func (s *InMemoryUserService) GetUserByEmail(email string) (*User, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	id, exists := s.emails[email]
+	if !exists {
+		return nil, &AppError{
+			Type:    ErrorTypeNotFound,
+			Message: fmt.Sprintf("user with email '%s' not found", email),
+		}
+	}
+
+	userCopy := *s.users[id]
+	return &userCopy, nil
+}
+
+// CreateUser creates a new user and publishes a user.created event
 func (s *InMemoryUserService) CreateUser(name, email string) (*User, error) {
-	user := NewUser(name, email)
+	return s.RegisterUser(name, email, "")
+}
 
-	// Validate before taking the write lock (cheap)
-	if err := user.Validate(); err != nil {
+// RegisterUser creates a new user signing in with password and publishes a
+// user.created event. An empty password creates a user without password.
+func (s *InMemoryUserService) RegisterUser(name, email, password string) (*User, error) {
+	return s.WithContext(context.Background()).RegisterUser(name, email, password)
+}
+
+// createUser stores a new user under the write lock
+func (s *InMemoryUserService) createUser(name, email, password string) (*User, error) {
+	user := NewUser(s.userIDs, name, email)
+
+	// Validate before taking the write lock (cheap), reporting every invalid field
+	var errs ValidationErrors
+	if appErr, ok := IsAppError(user.Validate(WithEmailStrictness(s.strictness))); ok {
+		errs = append(errs, appErr.Errors...)
+	}
+	if password != "" {
+		if appErr, ok := IsAppError(validatePassword(password)); ok {
+			errs = append(errs, appErr.Errors...)
+		}
+	}
+	if err := errs.Err(); err != nil {
 		return nil, err
 	}
 
+	// Hashing is deliberately slow, so it also happens outside the lock
+	if password != "" {
+		hash, err := hashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -82,76 +316,404 @@ func (s *InMemoryUserService) CreateUser(name, email string) (*User, error) {
 		return nil, err
 	}
 
+	s.touch(user)
+	user.CreatedAt = user.UpdatedAt
 	s.users[user.ID] = user
+	s.emails[user.Email] = user.ID
 	userCopy := *user
 	return &userCopy, nil
 }
 
-// UpdateUser updates an existing user
-func (s *InMemoryUserService) UpdateUser(id, name, email string) (*User, error) {
+// Authenticate returns the user with email when password is theirs. Unknown
+// emails, users without password and wrong passwords get the same error.
+func (s *InMemoryUserService) Authenticate(email, password string) (*User, error) {
+	s.mutex.RLock()
+	var user *User
+	if id, exists := s.emails[email]; exists {
+		userCopy := *s.users[id]
+		user = &userCopy
+	}
+	s.mutex.RUnlock()
+
+	// Passwords are verified outside the lock, as hashing is slow
+	if user == nil {
+		burnPasswordCheck(password)
+		return nil, NewUnauthorizedError("invalid email or password")
+	}
+	if !user.CheckPassword(password) {
+		return nil, NewUnauthorizedError("invalid email or password")
+	}
+	if user.Status == UserStatusSuspended {
+		return nil, &AppError{Type: ErrorTypeForbidden, Message: "user is suspended"}
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password of a user and publishes a
+// user.password_changed event
+func (s *InMemoryUserService) ChangePassword(id, currentPassword, newPassword string, expectedVersion int64) (*User, error) {
+	return s.WithContext(context.Background()).ChangePassword(id, currentPassword, newPassword, expectedVersion)
+}
+
+// changePassword stores the new password hash under the write lock, unless
+// the password changed since previousHash was verified. It returns the user
+// after and before the change.
+func (s *InMemoryUserService) changePassword(id, previousHash, hash string, expectedVersion int64) (user, previous *User, err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	user, exists := s.users[id]
 	if !exists {
-		return nil, NewNotFoundError("user", id)
+		return nil, nil, NewNotFoundError("user", id)
+	}
+
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+
+	if user.PasswordHash != previousHash {
+		return nil, nil, NewConflictError("password was changed concurrently")
+	}
+	previousCopy := *user
+	user.setPasswordHash(hash)
+	s.touch(user)
+
+	userCopy := *user
+	return &userCopy, &previousCopy, nil
+}
+
+// UpdateUser updates an existing user and publishes a user.updated event,
+// unless nothing changed
+func (s *InMemoryUserService) UpdateUser(id, name, email string, expectedVersion int64) (*User, error) {
+	return s.WithContext(context.Background()).UpdateUser(id, name, email, expectedVersion)
+}
+
+// updateUser applies an update under the write lock and returns the user
+// after and before the update
+func (s *InMemoryUserService) updateUser(id, name, email string, expectedVersion int64) (user, previous *User, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return nil, nil, NewNotFoundError("user", id)
+	}
+
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return nil, nil, err
 	}
 
 	// Check if email already exists for another user
 	if email != "" && email != user.Email {
-		for _, existingUser := range s.users {
-			if existingUser.ID != id && existingUser.Email == email {
-				return nil, NewConflictError("email already exists")
-			}
+		if err := s.checkEmailExists(email); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Update the user
-	user.Update(name, email)
-
-	// Validate the updated user
-	if err := user.Validate(); err != nil {
-		return nil, err
+	// Update the user and move its email index entry if the email changed
+	previousCopy := *user
+	if err := user.Update(name, email, WithEmailStrictness(s.strictness)); err != nil {
+		return nil, nil, err
+	}
+	if user.Email != previousCopy.Email {
+		delete(s.emails, previousCopy.Email)
+		s.emails[user.Email] = user.ID
+	}
+	// An update changing nothing leaves the timestamps alone
+	if user.Version != previousCopy.Version {
+		s.touch(user)
 	}
 
 	// Return a copy
 	userCopy := *user
-	return &userCopy, nil
+	return &userCopy, &previousCopy, nil
+}
+
+// DeleteUser deletes a user by ID and publishes a user.deleted event
+func (s *InMemoryUserService) DeleteUser(id string, expectedVersion int64) error {
+	return s.WithContext(context.Background()).DeleteUser(id, expectedVersion)
 }
 
-// DeleteUser deletes a user by ID
-func (s *InMemoryUserService) DeleteUser(id string) error {
+// deleteUser removes a user under the write lock and returns its last state
+// and its state before the removal
+func (s *InMemoryUserService) deleteUser(id string, expectedVersion int64) (user, previous *User, err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, exists := s.users[id]; !exists {
-		return NewNotFoundError("user", id)
+	user, exists := s.users[id]
+	if !exists {
+		return nil, nil, NewNotFoundError("user", id)
+	}
+
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+
+	// The deleted status is recorded in the snapshot carried by the event
+	previousCopy := *user
+	if err := user.TransitionTo(UserStatusDeleted); err != nil {
+		return nil, nil, err
 	}
 
 	delete(s.users, id)
+	delete(s.emails, user.Email)
+	s.touch(user)
+	return user, &previousCopy, nil
+}
+
+// AssignRoles replaces the roles of an existing user and publishes a
+// user.roles_assigned event
+func (s *InMemoryUserService) AssignRoles(id string, roles []Role, expectedVersion int64) (*User, error) {
+	return s.WithContext(context.Background()).AssignRoles(id, roles, expectedVersion)
+}
+
+// assignRoles replaces roles under the write lock and returns the user
+// after and before the change
+func (s *InMemoryUserService) assignRoles(id string, roles []Role, expectedVersion int64) (user, previous *User, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return nil, nil, NewNotFoundError("user", id)
+	}
+
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+
+	previousCopy := *user
+	if err := user.AssignRoles(roles); err != nil {
+		return nil, nil, err
+	}
+	s.touch(user)
+
+	userCopy := *user
+	return &userCopy, &previousCopy, nil
+}
+
+// ChangeStatus moves a user to the active or suspended status and publishes
+// a user.activated or user.suspended event
+func (s *InMemoryUserService) ChangeStatus(id string, status UserStatus, expectedVersion int64) (*User, error) {
+	return s.WithContext(context.Background()).ChangeStatus(id, status, expectedVersion)
+}
+
+// changeStatus applies a status transition under the write lock and returns
+// the user after and before the transition
+func (s *InMemoryUserService) changeStatus(id string, status UserStatus, expectedVersion int64) (user, previous *User, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return nil, nil, NewNotFoundError("user", id)
+	}
+
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+
+	previousCopy := *user
+	if err := user.TransitionTo(status); err != nil {
+		return nil, nil, err
+	}
+	s.touch(user)
+
+	userCopy := *user
+	return &userCopy, &previousCopy, nil
+}
+
+// SetAvatar replaces the avatar of a user and publishes a user.updated event
+func (s *InMemoryUserService) SetAvatar(id, avatarURL string, expectedVersion int64) (*User, error) {
+	return s.WithContext(context.Background()).SetAvatar(id, avatarURL, expectedVersion)
+}
+
+// setAvatar replaces the avatar URL under the write lock and returns the
+// user after and before the change
+func (s *InMemoryUserService) setAvatar(id, avatarURL string, expectedVersion int64) (user, previous *User, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return nil, nil, NewNotFoundError("user", id)
+	}
+
+	if err := checkVersion(user, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+
+	previousCopy := *user
+	user.SetAvatar(avatarURL)
+	s.touch(user)
+
+	userCopy := *user
+	return &userCopy, &previousCopy, nil
+}
+
+// publish emits a user event recording the actor and request ID of ctx and,
+// when known, the user's state before the change. It is called after the
+// lock is released so subscribers may safely call back into the service.
+func (s *InMemoryUserService) publish(ctx context.Context, eventType string, user, previous *User) {
+	event, err := NewUserEvent(s.ids, eventType, s.tenant, *user, previous)
+	if err != nil {
+		s.logger.Error("Failed to create event",
+			"event_type", eventType, "user_id", user.ID, "tenant", s.tenant, "error", err)
+		return
+	}
+	event.RequestID = RequestIDFromContext(ctx)
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		event.Actor = claims.Subject
+	}
+
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		s.logger.Error("Failed to publish event",
+			"event_type", eventType, "user_id", user.ID, "tenant", s.tenant, "error", err)
+	}
+}
+
+// WithContext returns a view of the store serving the request of ctx. The
+// view shares the users of s, and its events record the request's actor and ID.
+func (s *InMemoryUserService) WithContext(ctx context.Context) UserService {
+	return &scopedUserService{InMemoryUserService: s, ctx: ctx}
+}
+
+// scopedUserService is the view of an InMemoryUserService serving one request.
+// Reads are served by the embedded store; changes publish their events with ctx.
+type scopedUserService struct {
+	*InMemoryUserService
+	ctx context.Context
+}
+
+// CreateUser creates a new user and publishes a user.created event
+func (s *scopedUserService) CreateUser(name, email string) (*User, error) {
+	return s.RegisterUser(name, email, "")
+}
+
+// RegisterUser creates a new user signing in with password and publishes a
+// user.created event. An empty password creates a user without password.
+func (s *scopedUserService) RegisterUser(name, email, password string) (*User, error) {
+	user, err := s.createUser(name, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(s.ctx, EventTypeUserCreated, user, nil)
+	return user, nil
+}
+
+// ChangePassword replaces the password of a user and publishes a
+// user.password_changed event
+func (s *scopedUserService) ChangePassword(id, currentPassword, newPassword string, expectedVersion int64) (*User, error) {
+	if err := validatePassword(newPassword); err != nil {
+		return nil, err
+	}
+
+	// Both hashing steps are slow, so they run on a copy outside the lock
+	current, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(current, expectedVersion); err != nil {
+		return nil, err
+	}
+	if !current.CheckPassword(currentPassword) {
+		return nil, NewUnauthorizedError("current password is incorrect")
+	}
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	user, previous, err := s.changePassword(id, current.PasswordHash, hash, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(s.ctx, EventTypeUserPasswordChanged, user, previous)
+	return user, nil
+}
+
+// UpdateUser updates an existing user and publishes a user.updated event,
+// unless nothing changed
+func (s *scopedUserService) UpdateUser(id, name, email string, expectedVersion int64) (*User, error) {
+	user, previous, err := s.updateUser(id, name, email, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing happened to a user whose version did not move
+	if user.Version != previous.Version {
+		s.publish(s.ctx, EventTypeUserUpdated, user, previous)
+	}
+	return user, nil
+}
+
+// DeleteUser deletes a user by ID and publishes a user.deleted event
+func (s *scopedUserService) DeleteUser(id string, expectedVersion int64) error {
+	user, previous, err := s.deleteUser(id, expectedVersion)
+	if err != nil {
+		return err
+	}
+
+	s.publish(s.ctx, EventTypeUserDeleted, user, previous)
 	return nil
 }
 
-// checkEmailExists checks if an email already exists
-func (s *InMemoryUserService) checkEmailExists(email string) error {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+// AssignRoles replaces the roles of an existing user and publishes a
+// user.roles_assigned event
+func (s *scopedUserService) AssignRoles(id string, roles []Role, expectedVersion int64) (*User, error) {
+	user, previous, err := s.assignRoles(id, roles, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, user := range s.users {
-		if user.Email == email {
-			return NewConflictError("email already exists")
-		}
+	s.publish(s.ctx, EventTypeUserRolesAssigned, user, previous)
+	return user, nil
+}
+
+// ChangeStatus moves a user to the active or suspended status and publishes
+// a user.activated or user.suspended event
+func (s *scopedUserService) ChangeStatus(id string, status UserStatus, expectedVersion int64) (*User, error) {
+	eventType, ok := statusEventTypes[status]
+	if !ok {
+		return nil, NewValidationError("status", fmt.Sprintf("status cannot be changed to '%s'", status))
+	}
+
+	user, previous, err := s.changeStatus(id, status, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(s.ctx, eventType, user, previous)
+	return user, nil
+}
+
+// SetAvatar replaces the avatar of a user and publishes a user.updated event
+func (s *scopedUserService) SetAvatar(id, avatarURL string, expectedVersion int64) (*User, error) {
+	user, previous, err := s.setAvatar(id, avatarURL, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(s.ctx, EventTypeUserUpdated, user, previous)
+	return user, nil
+}
+
+// checkEmailExists checks if an email already exists.
+// The caller must hold the mutex.
+func (s *InMemoryUserService) checkEmailExists(email string) error {
+	if _, exists := s.emails[email]; exists {
+		return NewConflictError("email already exists")
 	}
 	return nil
 }
 
-// generateID generates a simple random ID for demonstration
-func generateID() string {
-	b := make([]byte, 8)
-	if _, err := rand.Read(b); err != nil {
-		// Fallback to timestamp-based ID; log for visibility
-		log.Printf("rand.Read failed: %v", err)
-		return fmt.Sprintf("%d", time.Now().UnixNano())
+// checkVersion verifies an optimistic concurrency precondition.
+// An expectedVersion of zero means the operation is unconditional.
+func checkVersion(user *User, expectedVersion int64) error {
+	if expectedVersion != 0 && user.Version != expectedVersion {
+		return NewPreconditionFailedError("user has been modified since it was last read")
 	}
-	return fmt.Sprintf("%x", b)
+	return nil
 }
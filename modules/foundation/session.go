@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// sessionCookie carries the session ID of browser clients
+	sessionCookie = "session_id"
+
+	// csrfHeader must echo the session's CSRF token on state-changing requests
+	csrfHeader = "X-CSRF-Token"
+)
+
+// ErrSessionNotFound is returned by session stores for unknown or expired sessions
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a server-side browser session
+type Session struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"sub"`
+	CSRFToken string    `json:"csrf_token"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore persists sessions until they expire
+type SessionStore interface {
+	// Save stores the session until its ExpiresAt
+	Save(ctx context.Context, session *Session) error
+
+	// Get returns the session with id, or ErrSessionNotFound
+	Get(ctx context.Context, id string) (*Session, error)
+
+	// Delete removes the session with id; deleting an unknown session is not an error
+	Delete(ctx context.Context, id string) error
+}
+
+// loadSessionStore creates the store named by settings ("memory" or "redis")
+// and, for Redis, connects to its URL
+func loadSessionStore(settings SessionSettings) (SessionStore, error) {
+	switch store := settings.Store; store {
+	case "memory":
+		return NewMemorySessionStore(), nil
+	case "redis":
+		options, err := redis.ParseURL(settings.RedisURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid REDIS_URL")
+		}
+		return NewRedisSessionStore(redis.NewClient(options)), nil
+	default:
+		return nil, errors.Errorf("SESSION_STORE must be 'memory' or 'redis', got %q", store)
+	}
+}
+
+// MemorySessionStore keeps sessions in process memory. Sessions are lost on
+// restart and not shared between replicas.
+type MemorySessionStore struct {
+	sessions map[string]Session
+	now      func() time.Time
+	mutex    sync.Mutex
+}
+
+// NewMemorySessionStore creates an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]Session),
+		now:      time.Now,
+	}
+}
+
+// Save stores a copy of session and drops expired sessions
+func (s *MemorySessionStore) Save(_ context.Context, session *Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	for id, stored := range s.sessions {
+		if !now.Before(stored.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+// Get returns a copy of the session with id
+func (s *MemorySessionStore) Get(_ context.Context, id string) (*Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !s.now().Before(session.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// Delete removes the session with id
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// RedisSessionStore keeps sessions in Redis as JSON under "session:<id>",
+// expiring with the session, so they survive restarts and are shared by replicas
+type RedisSessionStore struct {
+	client *redis.Client
+}
+
+// NewRedisSessionStore creates a session store on client
+func NewRedisSessionStore(client *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{client: client}
+}
+
+// key returns the Redis key of a session
+func (s *RedisSessionStore) key(id string) string {
+	return "session:" + id
+}
+
+// Save stores session with a time to live ending at its expiry
+func (s *RedisSessionStore) Save(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return errors.Wrap(err, "encoding session")
+	}
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return errors.Wrap(s.client.Set(ctx, s.key(session.ID), data, ttl).Err(), "saving session")
+}
+
+// Get returns the session with id
+func (s *RedisSessionStore) Get(ctx context.Context, id string) (*Session, error) {
+	data, err := s.client.Get(ctx, s.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "loading session")
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, errors.Wrap(err, "decoding session")
+	}
+	return &session, nil
+}
+
+// Delete removes the session with id
+func (s *RedisSessionStore) Delete(ctx context.Context, id string) error {
+	return errors.Wrap(s.client.Del(ctx, s.key(id)).Err(), "deleting session")
+}
+
+// Ping checks that Redis is reachable
+func (s *RedisSessionStore) Ping(ctx context.Context) error {
+	return errors.Wrap(s.client.Ping(ctx).Err(), "pinging redis")
+}
+
+// Close releases the Redis connections
+func (s *RedisSessionStore) Close() error {
+	return s.client.Close()
+}
+
+// SessionManager authenticates browser clients with a session cookie and
+// protects their state-changing requests against cross-site request forgery
+type SessionManager struct {
+	store SessionStore
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// NewSessionManager creates a manager keeping sessions in store for ttl
+func NewSessionManager(store SessionStore, ttl time.Duration) *SessionManager {
+	return &SessionManager{store: store, ttl: ttl, now: time.Now}
+}
+
+// Start creates a session for subject and sets its cookie on w
+func (m *SessionManager) Start(w http.ResponseWriter, r *http.Request, subject string) (*Session, error) {
+	now := m.now()
+	session := &Session{
+		ID:        randomToken(),
+		Subject:   subject,
+		CSRFToken: randomToken(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+	if err := m.store.Save(r.Context(), session); err != nil {
+		return nil, NewInternalError("failed to start session", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.ID,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return session, nil
+}
+
+// End deletes the session and clears its cookie
+func (m *SessionManager) End(w http.ResponseWriter, r *http.Request, session *Session) error {
+	if err := m.store.Delete(r.Context(), session.ID); err != nil {
+		return NewInternalError("failed to end session", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// load returns the session named by the cookie of r
+func (m *SessionManager) load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrSessionNotFound
+	}
+	session, err := m.store.Get(r.Context(), cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	if !m.now().Before(session.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// Middleware authenticates requests carrying a valid session cookie as the
+// session's subject. Requests with a bearer token are left to the token
+// authentication. State-changing session requests must pass the CSRF check.
+func (m *SessionManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, found := bearerToken(r); found {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, err := m.load(r)
+		if err == ErrSessionNotFound {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			writeError(w, r, NewInternalError("failed to load session", err))
+			return
+		}
+
+		if !isSafeMethod(r.Method) {
+			if err := checkCSRF(r, session); err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		ctx := ContextWithSession(r.Context(), session)
+		ctx = ContextWithClaims(ctx, &Claims{Subject: session.Subject})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkCSRF rejects cross-site requests riding on the session cookie: the
+// request must echo the session's CSRF token, which other sites cannot read,
+// and must not come from a foreign Origin
+func checkCSRF(r *http.Request, session *Session) error {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			return &AppError{Type: ErrorTypeForbidden, Message: "cross-origin request rejected"}
+		}
+	}
+
+	token := r.Header.Get(csrfHeader)
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) != 1 {
+		return &AppError{
+			Type:    ErrorTypeForbidden,
+			Message: "missing or invalid CSRF token",
+			Details: map[string]interface{}{
+				"header": csrfHeader,
+			},
+		}
+	}
+	return nil
+}
+
+type sessionContextKey struct{}
+
+// ContextWithSession returns a copy of ctx carrying the session of the request
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session stored in ctx, if any
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(*Session)
+	return session, ok
+}
+
+// SessionHandler serves the /session endpoints that exchange a bearer token
+// for a browser session and end it again
+type SessionHandler struct {
+	sessions  *SessionManager
+	validator *JWTValidator
+}
+
+// NewSessionHandler creates a new SessionHandler. Requests must pass through
+// the session middleware so the current session is in their context.
+func NewSessionHandler(sessions *SessionManager, validator *JWTValidator) *SessionHandler {
+	return &SessionHandler{
+		sessions:  sessions,
+		validator: validator,
+	}
+}
+
+// ServeHTTP handles GET /session, POST /session/login and POST /session/logout
+func (h *SessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var method string
+	var handle func(w http.ResponseWriter, r *http.Request)
+	switch r.URL.Path {
+	case "/session":
+		method, handle = http.MethodGet, h.handleGetSession
+	case "/session/login":
+		method, handle = http.MethodPost, h.handleLogin
+	case "/session/logout":
+		method, handle = http.MethodPost, h.handleLogout
+	default:
+		writeErrorMessage(w, r, http.StatusNotFound, "endpoint not found")
+		return
+	}
+
+	switch r.Method {
+	case method:
+		handle(w, r)
+	case http.MethodOptions:
+		w.Header().Set("Allow", method+", OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", method+", OPTIONS")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleGetSession handles GET /session, returning the CSRF token to the page
+func (h *SessionHandler) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := SessionFromContext(r.Context())
+	if !ok {
+		writeError(w, r, NewUnauthorizedError("no active session"))
+		return
+	}
+	writeSessionResponse(w, http.StatusOK, session)
+}
+
+// handleLogin handles POST /session/login: a valid bearer token, e.g. from the
+// OIDC callback, starts a session for its subject
+func (h *SessionHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	token, found := bearerToken(r)
+	if !found {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, r, NewUnauthorizedError("missing bearer token"))
+		return
+	}
+	claims, err := h.validator.Validate(token)
+	if err != nil {
+		appErr, _ := IsAppError(err)
+		writeUnauthorized(w, r, appErr)
+		return
+	}
+
+	session, err := h.sessions.Start(w, r, claims.Subject)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeSessionResponse(w, http.StatusCreated, session)
+}
+
+// handleLogout handles POST /session/logout. The middleware has already
+// checked the CSRF token of the session.
+func (h *SessionHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	session, ok := SessionFromContext(r.Context())
+	if !ok {
+		writeError(w, r, NewUnauthorizedError("no active session"))
+		return
+	}
+	if err := h.sessions.End(w, r, session); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSessionResponse writes the client view of a session; the session ID
+// stays in the HttpOnly cookie
+func writeSessionResponse(w http.ResponseWriter, statusCode int, session *Session) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"subject":    session.Subject,
+		"csrf_token": session.CSRFToken,
+		"expires_at": session.ExpiresAt,
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemorySessionStore()
+	store.now = func() time.Time { return now }
+
+	session := &Session{ID: "s1", Subject: "user-1", ExpiresAt: now.Add(time.Hour)}
+	if err := store.Save(ctx, session); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Changing the saved value must not change the stored session
+	session.Subject = "changed"
+	got, err := store.Get(ctx, "s1")
+	if err != nil || got.Subject != "user-1" {
+		t.Fatalf("Get() got %v, %v want user-1", got, err)
+	}
+
+	if _, err := store.Get(ctx, "unknown"); err != ErrSessionNotFound {
+		t.Errorf("Get(unknown) error got %v want %v", err, ErrSessionNotFound)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := store.Get(ctx, "s1"); err != ErrSessionNotFound {
+		t.Errorf("Get(expired) error got %v want %v", err, ErrSessionNotFound)
+	}
+
+	store.Save(ctx, &Session{ID: "s2", ExpiresAt: now.Add(time.Hour)})
+	if len(store.sessions) != 1 {
+		t.Errorf("expired sessions were not dropped: %d stored", len(store.sessions))
+	}
+	if err := store.Delete(ctx, "s2"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "s2"); err != ErrSessionNotFound {
+		t.Errorf("Get(deleted) error got %v want %v", err, ErrSessionNotFound)
+	}
+}
+
+func TestSessionFlow(t *testing.T) {
+	secret := []byte("test-secret")
+	validator := NewJWTValidator(JWTConfig{HMACSecret: secret})
+	service := NewInMemoryUserService()
+	admin, _ := service.GetUserByEmail("john.doe@example.com")
+	token := signToken(t, algHS256, secret, map[string]interface{}{
+		"sub": admin.ID,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	sessions := NewSessionManager(NewMemorySessionStore(), time.Hour)
+	authorizer := NewAuthorizer(SingleService(service))
+	mux := http.NewServeMux()
+	mux.Handle("/session/", NewSessionHandler(sessions, validator))
+	mux.Handle("/session", NewSessionHandler(sessions, validator))
+	mux.Handle("/users", authMiddleware(validator, authorizer.Middleware(userOperationPermission, NewUserHandler(service))))
+	handler := sessions.Middleware(mux)
+
+	do := func(method, path string, headers map[string]string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"name":"Alice","email":"alice@example.com"}`))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/session/login", nil, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("login without token got %d want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec := do(http.MethodPost, "/session/login", map[string]string{"Authorization": "Bearer " + token}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("login got %d want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("login cookies got %v want an HttpOnly SameSite=Lax %s cookie", cookies, sessionCookie)
+	}
+	cookie := cookies[0]
+	var body struct {
+		Subject   string `json:"subject"`
+		CSRFToken string `json:"csrf_token"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Subject != admin.ID || body.CSRFToken == "" {
+		t.Fatalf("login body got %+v want the subject and a CSRF token", body)
+	}
+	if strings.Contains(rec.Body.String(), cookie.Value) {
+		t.Error("login body exposes the session ID")
+	}
+
+	if rec := do(http.MethodGet, "/session", nil, cookie); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), body.CSRFToken) {
+		t.Errorf("GET /session got %d %s want 200 with the CSRF token", rec.Code, rec.Body.String())
+	}
+
+	csrf := map[string]string{csrfHeader: body.CSRFToken}
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"missing CSRF token", nil, http.StatusForbidden},
+		{"wrong CSRF token", map[string]string{csrfHeader: "forged"}, http.StatusForbidden},
+		{"foreign origin", map[string]string{csrfHeader: body.CSRFToken, "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"same origin", map[string]string{csrfHeader: body.CSRFToken, "Origin": "http://example.com"}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(http.MethodPost, "/users", tt.headers, cookie); rec.Code != tt.wantStatus {
+				t.Errorf("POST /users got %d want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	if rec := do(http.MethodPost, "/session/logout", nil, cookie); rec.Code != http.StatusForbidden {
+		t.Errorf("logout without CSRF token got %d want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := do(http.MethodPost, "/session/logout", csrf, cookie); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout got %d want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := do(http.MethodPost, "/users", csrf, cookie); rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST /users after logout got %d want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := do(http.MethodGet, "/session", nil, cookie); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /session after logout got %d want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoadSessionStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		store    string
+		redisURL string
+		wantErr  bool
+	}{
+		{"memory", "memory", "", false},
+		{"redis", "redis", "redis://localhost:6379/1", false},
+		{"invalid Redis URL", "redis", "localhost:6379", true},
+		{"unknown store", "memcached", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := loadSessionStore(SessionSettings{Store: tt.store, RedisURL: tt.redisURL})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadSessionStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if redisStore, ok := store.(*RedisSessionStore); ok {
+				redisStore.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShutdownHook is a named step run after the HTTP servers have drained,
+// e.g. draining event consumers or flushing an outbox relay
+type ShutdownHook struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// ShutdownStep records the outcome of one shutdown step
+type ShutdownStep struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ShutdownReport summarizes a graceful shutdown
+type ShutdownReport struct {
+	Steps            []ShutdownStep `json:"steps"`
+	Duration         time.Duration  `json:"duration"`
+	InFlightAtSignal int64          `json:"in_flight_at_signal"`
+	Clean            bool           `json:"clean"`
+}
+
+// String renders the report as a single log-friendly line
+func (r ShutdownReport) String() string {
+	parts := make([]string, 0, len(r.Steps))
+	for _, step := range r.Steps {
+		status := "ok"
+		if step.Error != "" {
+			status = "failed: " + step.Error
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s (%v)", step.Name, status, step.Duration.Round(time.Millisecond)))
+	}
+	return fmt.Sprintf("clean=%t in_flight_at_signal=%d duration=%v steps=[%s]",
+		r.Clean, r.InFlightAtSignal, r.Duration.Round(time.Millisecond), strings.Join(parts, ", "))
+}
+
+// ShutdownManager coordinates draining: it tracks in-flight requests, flips
+// health checks to "draining" so load balancers stop routing new work, ends
+// long-lived streams and runs registered hooks in order once HTTP traffic
+// has stopped
+type ShutdownManager struct {
+	drainDelay time.Duration
+
+	draining atomic.Bool
+	inFlight atomic.Int64
+
+	mutex      sync.Mutex
+	drainHooks []ShutdownHook
+	hooks      []ShutdownHook
+}
+
+// NewShutdownManager creates a new ShutdownManager. drainDelay is how long
+// the service keeps serving while reporting "draining" before it stops
+// listening, giving load balancers time to deregister it.
+func NewShutdownManager(drainDelay time.Duration) *ShutdownManager {
+	return &ShutdownManager{
+		drainDelay: drainDelay,
+	}
+}
+
+// Register adds a hook run during shutdown, in registration order
+func (m *ShutdownManager) Register(name string, fn func(ctx context.Context) error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.hooks = append(m.hooks, ShutdownHook{Name: name, Fn: fn})
+}
+
+// RegisterDrain adds a hook run once the drain delay is over, before the
+// servers stop, in registration order. It ends what the servers would
+// otherwise wait for, such as event streams and their subscribers.
+func (m *ShutdownManager) RegisterDrain(name string, fn func(ctx context.Context) error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.drainHooks = append(m.drainHooks, ShutdownHook{Name: name, Fn: fn})
+}
+
+// Draining reports whether shutdown has started
+func (m *ShutdownManager) Draining() bool {
+	return m.draining.Load()
+}
+
+// InFlight returns the number of requests currently being served
+func (m *ShutdownManager) InFlight() int64 {
+	return m.inFlight.Load()
+}
+
+// Middleware counts in-flight requests and asks clients to close their
+// keep-alive connections once draining has started
+func (m *ShutdownManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		if m.Draining() {
+			w.Header().Set("Connection", "close")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HealthMiddleware reports 503 "draining" from the health endpoint once shutdown has started
+func (m *ShutdownManager) HealthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.Draining() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		response := map[string]interface{}{
+			"status":    "draining",
+			"service":   "user-service",
+			"in_flight": m.InFlight(),
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.ErrorContext(r.Context(), "Error encoding health response", "error", err)
+		}
+	})
+}
+
+// Shutdown drains the service: it flips to draining, waits for the drain
+// delay, runs the drain hooks, stops the servers (which waits for in-flight
+// requests), then runs the registered hooks. Every step runs even if an
+// earlier one failed.
+func (m *ShutdownManager) Shutdown(ctx context.Context, servers ...*http.Server) ShutdownReport {
+	start := time.Now()
+	m.draining.Store(true)
+
+	report := ShutdownReport{
+		InFlightAtSignal: m.InFlight(),
+		Clean:            true,
+	}
+
+	run := func(name string, fn func(ctx context.Context) error) {
+		stepStart := time.Now()
+		step := ShutdownStep{Name: name}
+		if err := fn(ctx); err != nil {
+			step.Error = err.Error()
+			report.Clean = false
+		}
+		step.Duration = time.Since(stepStart)
+		report.Steps = append(report.Steps, step)
+	}
+
+	if m.drainDelay > 0 {
+		run("drain-delay", func(ctx context.Context) error {
+			select {
+			case <-time.After(m.drainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	m.mutex.Lock()
+	drainHooks := append([]ShutdownHook(nil), m.drainHooks...)
+	hooks := append([]ShutdownHook(nil), m.hooks...)
+	m.mutex.Unlock()
+
+	for _, hook := range drainHooks {
+		run(hook.Name, hook.Fn)
+	}
+
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		run("http "+server.Addr, server.Shutdown)
+	}
+
+	for _, hook := range hooks {
+		run(hook.Name, hook.Fn)
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShutdownManager_HealthMiddleware(t *testing.T) {
+	manager := NewShutdownManager(0)
+	handler := manager.HealthMiddleware(http.HandlerFunc(healthHandler))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("health before shutdown: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	manager.Shutdown(context.Background())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("health while draining: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestShutdownManager_DrainsInFlightRequests(t *testing.T) {
+	manager := NewShutdownManager(0)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	completed := make(chan struct{})
+	handler := manager.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+		close(completed)
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	var hookRanAfterRequest bool
+	manager.Register("event-consumers", func(ctx context.Context) error {
+		select {
+		case <-completed:
+			hookRanAfterRequest = true
+		default:
+		}
+		return nil
+	})
+	manager.Register("outbox-relay", func(ctx context.Context) error {
+		return errors.New("relay unavailable")
+	})
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	report := manager.Shutdown(context.Background(), server)
+
+	if report.InFlightAtSignal != 1 {
+		t.Errorf("InFlightAtSignal = %d, want 1", report.InFlightAtSignal)
+	}
+	if !hookRanAfterRequest {
+		t.Error("hooks should run after in-flight requests completed")
+	}
+	if report.Clean {
+		t.Error("report should not be clean when a hook fails")
+	}
+	if len(report.Steps) != 3 || report.Steps[2].Name != "outbox-relay" || report.Steps[2].Error == "" {
+		t.Errorf("unexpected shutdown steps: %+v", report.Steps)
+	}
+}
+
+func TestShutdownManager_RegisterDrain(t *testing.T) {
+	manager := NewShutdownManager(0)
+
+	// A stream the server waits for until the drain hook ends it
+	started := make(chan struct{})
+	closed := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-closed
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	manager.Register("event-bus", func(context.Context) error { return nil })
+	manager.RegisterDrain("event-stream", func(context.Context) error {
+		close(closed)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report := manager.Shutdown(ctx, server)
+
+	if !report.Clean {
+		t.Errorf("report should be clean once the stream ended, got %s", report)
+	}
+	var names []string
+	for _, step := range report.Steps {
+		names = append(names, step.Name)
+	}
+	if want := []string{"event-stream", "http " + server.Addr, "event-bus"}; !slices.Equal(names, want) {
+		t.Errorf("steps got %v want %v", names, want)
+	}
+}
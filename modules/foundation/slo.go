@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sloBuckets is the number of one-minute buckets kept per endpoint, which
+// bounds the longest burn-rate window
+const sloBuckets = 60
+
+// SLOTarget is a latency service level objective: the fraction of requests,
+// Objective, that must be answered without server error within Latency
+type SLOTarget struct {
+	Latency   time.Duration
+	Objective float64
+}
+
+// parseSLOTarget parses a target written as latency@percentage, e.g. "200ms@99.5"
+func parseSLOTarget(value string) (SLOTarget, error) {
+	latencyValue, objectiveValue, ok := strings.Cut(strings.TrimSpace(value), "@")
+	latency, err := time.ParseDuration(latencyValue)
+	if !ok || err != nil || latency <= 0 {
+		return SLOTarget{}, fmt.Errorf("invalid SLO target %q, want latency@percentage such as 200ms@99.5", value)
+	}
+	percentage, err := strconv.ParseFloat(objectiveValue, 64)
+	if err != nil || percentage <= 0 || percentage >= 100 {
+		return SLOTarget{}, fmt.Errorf("invalid SLO target %q, the percentage must be between 0 and 100", value)
+	}
+	return SLOTarget{Latency: latency, Objective: percentage / 100}, nil
+}
+
+// SLOStatus is the compliance of one endpoint with its target
+type SLOStatus struct {
+	LatencyTargetMs float64 `json:"latency_target_ms"`
+	Objective       float64 `json:"objective"`
+	Requests        int64   `json:"requests"`
+	Good            int64   `json:"good"`
+	// ErrorBudgetRemaining is the fraction of the error budget left since
+	// startup; it is negative once the budget is exhausted
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	// BurnRate5m and BurnRate1h tell how fast the budget is spent over the
+	// last 5 minutes and hour: 1 spends it exactly over the SLO window
+	BurnRate5m float64 `json:"burn_rate_5m"`
+	BurnRate1h float64 `json:"burn_rate_1h"`
+}
+
+// sloBucket counts the requests of one minute
+type sloBucket struct {
+	minute int64
+	total  int64
+	good   int64
+}
+
+// endpointSLO tracks the requests of one endpoint
+type endpointSLO struct {
+	target  SLOTarget
+	total   int64
+	good    int64
+	buckets [sloBuckets]sloBucket
+}
+
+// SLOTracker measures each endpoint, named after its method and resource
+// like "GET /users", against its latency SLO
+type SLOTracker struct {
+	defaultTarget SLOTarget
+	routes        map[string]SLOTarget
+	endpoints     map[string]*endpointSLO
+	now           func() time.Time
+	mutex         sync.Mutex
+}
+
+// NewSLOTracker creates a tracker applying defaultTarget to every route
+// without its own target in routes, keyed by resource such as "/users"
+func NewSLOTracker(defaultTarget SLOTarget, routes map[string]SLOTarget) *SLOTracker {
+	return &SLOTracker{
+		defaultTarget: defaultTarget,
+		routes:        routes,
+		endpoints:     make(map[string]*endpointSLO),
+		now:           time.Now,
+	}
+}
+
+// loadSLOTracker creates a tracker with the default target of settings
+// (SLO_TARGET) and the per-route targets of ROUTE_SLO_TARGETS, entries such
+// as "/users=200ms@99.5" or "/graphql=1s@99"
+func loadSLOTracker(settings SLOSettings) (*SLOTracker, error) {
+	defaultTarget, err := parseSLOTarget(settings.Target)
+	if err != nil {
+		return nil, fmt.Errorf("SLO_TARGET: %w", err)
+	}
+
+	routes := make(map[string]SLOTarget)
+	for _, entry := range settings.RouteTargets {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("invalid ROUTE_SLO_TARGETS entry %q, want /route=latency@percentage", entry)
+		}
+		target, err := parseSLOTarget(value)
+		if err != nil {
+			return nil, fmt.Errorf("ROUTE_SLO_TARGETS: %w", err)
+		}
+		routes[strings.TrimSuffix(strings.TrimSpace(route), "/")] = target
+	}
+	return NewSLOTracker(defaultTarget, routes), nil
+}
+
+// Record counts a request to path answered with status after latency. It is
+// good when answered without server error within the target latency.
+func (t *SLOTracker) Record(method, path string, status int, latency time.Duration) {
+	route := spanRoute(path)
+	endpoint := method + " " + route
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	e, ok := t.endpoints[endpoint]
+	if !ok {
+		target, ok := t.routes[route]
+		if !ok {
+			target = t.defaultTarget
+		}
+		e = &endpointSLO{target: target}
+		t.endpoints[endpoint] = e
+	}
+
+	minute := t.now().Unix() / 60
+	bucket := &e.buckets[minute%sloBuckets]
+	if bucket.minute != minute {
+		*bucket = sloBucket{minute: minute}
+	}
+
+	good := status < http.StatusInternalServerError && latency <= e.target.Latency
+	e.total++
+	bucket.total++
+	if good {
+		e.good++
+		bucket.good++
+	}
+}
+
+// Snapshot returns the status of every endpoint that received requests
+func (t *SLOTracker) Snapshot() map[string]SLOStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	minute := t.now().Unix() / 60
+	statuses := make(map[string]SLOStatus, len(t.endpoints))
+	for endpoint, e := range t.endpoints {
+		budget := 1 - e.target.Objective
+		statuses[endpoint] = SLOStatus{
+			LatencyTargetMs:      float64(e.target.Latency.Microseconds()) / 1000,
+			Objective:            e.target.Objective,
+			Requests:             e.total,
+			Good:                 e.good,
+			ErrorBudgetRemaining: 1 - badRatio(e.total, e.good)/budget,
+			BurnRate5m:           e.burnRate(minute, 5),
+			BurnRate1h:           e.burnRate(minute, 60),
+		}
+	}
+	return statuses
+}
+
+// burnRate is the error rate of the last minutes, including the current
+// one, relative to the error rate the objective allows
+func (e *endpointSLO) burnRate(minute int64, minutes int) float64 {
+	var total, good int64
+	for _, bucket := range e.buckets {
+		if bucket.minute > minute-int64(minutes) && bucket.minute <= minute {
+			total += bucket.total
+			good += bucket.good
+		}
+	}
+	return badRatio(total, good) / (1 - e.target.Objective)
+}
+
+// badRatio is the fraction of requests that were not good, 0 without requests
+func badRatio(total, good int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(total-good) / float64(total)
+}
+
+// sloMiddleware records the latency and status of every request in tracker.
+// Server-sent event streams and /debug profiles are long-lived by design and
+// are not measured.
+func sloMiddleware(tracker *SLOTracker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") || strings.HasPrefix(r.URL.Path, "/debug/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapper, r)
+		tracker.Record(r.Method, r.URL.Path, wrapper.statusCode, time.Since(start))
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseSLOTarget(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SLOTarget
+		wantErr bool
+	}{
+		{value: "500ms@99", want: SLOTarget{Latency: 500 * time.Millisecond, Objective: 0.99}},
+		{value: "1s@99.9", want: SLOTarget{Latency: time.Second, Objective: 0.999}},
+		{value: "500ms", wantErr: true},
+		{value: "fast@99", wantErr: true},
+		{value: "0s@99", wantErr: true},
+		{value: "500ms@100", wantErr: true},
+		{value: "500ms@high", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSLOTarget(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSLOTarget() error got %v want error %v", err, tt.wantErr)
+			}
+			if got.Latency != tt.want.Latency || math.Abs(got.Objective-tt.want.Objective) > 1e-9 {
+				t.Errorf("parseSLOTarget() got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSLOTracker(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		routes  []string
+		wantErr bool
+	}{
+		{name: "defaults", target: "500ms@99"},
+		{name: "route targets", target: "500ms@99", routes: []string{"/users=200ms@99.5", " /graphql/=1s@99"}},
+		{name: "invalid default target", target: "500ms", wantErr: true},
+		{name: "missing route slash", target: "500ms@99", routes: []string{"users=200ms@99.5"}, wantErr: true},
+		{name: "invalid target", target: "500ms@99", routes: []string{"/users=200ms"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadSLOTracker(SLOSettings{Target: tt.target, RouteTargets: tt.routes})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadSLOTracker() error got %v want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSLOTracker_Snapshot(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker(SLOTarget{Latency: 100 * time.Millisecond, Objective: 0.9},
+		map[string]SLOTarget{"/graphql": {Latency: time.Second, Objective: 0.99}})
+	tracker.now = func() time.Time { return now }
+
+	// An hour ago every request was bad; it only counts in the budget
+	now = now.Add(-time.Hour)
+	for i := 0; i < 10; i++ {
+		tracker.Record(http.MethodGet, "/users", http.StatusOK, time.Second)
+	}
+	now = now.Add(time.Hour)
+	// 20 good requests, one slow and one failing now
+	for i := 0; i < 20; i++ {
+		tracker.Record(http.MethodGet, "/users/42", http.StatusOK, 10*time.Millisecond)
+	}
+	tracker.Record(http.MethodGet, "/users", http.StatusOK, 200*time.Millisecond)
+	tracker.Record(http.MethodGet, "/users", http.StatusInternalServerError, time.Millisecond)
+	// Client errors answered fast are good, and routes use their own target
+	tracker.Record(http.MethodPost, "/graphql", http.StatusBadRequest, 500*time.Millisecond)
+
+	snapshot := tracker.Snapshot()
+	users := snapshot["GET /users"]
+	if users.Requests != 32 || users.Good != 20 {
+		t.Errorf("GET /users requests got %d/%d good want 20/32", users.Good, users.Requests)
+	}
+	// 12 bad requests of 32 against a 10% budget
+	if want := 1 - (12.0/32)/0.1; math.Abs(users.ErrorBudgetRemaining-want) > 1e-9 {
+		t.Errorf("error budget remaining got %v want %v", users.ErrorBudgetRemaining, want)
+	}
+	// 2 bad requests of 22 in the last 5 minutes, the earlier ones are older than an hour
+	if want := (2.0 / 22) / 0.1; math.Abs(users.BurnRate5m-want) > 1e-9 || math.Abs(users.BurnRate1h-want) > 1e-9 {
+		t.Errorf("burn rates got %v and %v want %v", users.BurnRate5m, users.BurnRate1h, want)
+	}
+
+	graphql := snapshot["POST /graphql"]
+	if graphql.Good != 1 || graphql.LatencyTargetMs != 1000 || graphql.BurnRate5m != 0 || graphql.ErrorBudgetRemaining != 1 {
+		t.Errorf("POST /graphql got %+v want one good request against 1s@99", graphql)
+	}
+}
+
+func TestSLOMiddleware(t *testing.T) {
+	tracker := NewSLOTracker(SLOTarget{Latency: time.Second, Objective: 0.99}, nil)
+	handler := sloMiddleware(tracker, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/debug/pprof/profile", nil))
+
+	snapshot := tracker.Snapshot()
+	if len(snapshot) != 1 || snapshot["GET /readyz"].Requests != 1 || snapshot["GET /readyz"].Good != 0 {
+		t.Errorf("snapshot got %+v want one bad GET /readyz request", snapshot)
+	}
+}
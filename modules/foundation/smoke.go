@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// smokeEventTypes are the events the smoke test expects of its user, in
+// the order they are published
+var smokeEventTypes = []string{EventTypeUserCreated, EventTypeUserUpdated, EventTypeUserDeleted}
+
+// smokeCheck is a step of the smoke test
+type smokeCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// smokeTest goes through the life of a user of its own on a running
+// server, checking each response, and expects the events of that life on
+// the event stream of the server
+type smokeTest struct {
+	api           *apiClient
+	eventsTimeout time.Duration
+	runID         string
+
+	userID  string
+	name    string
+	email   string
+	deleted bool
+	stream  io.Closer
+	// received gets the events of the stream, and is closed when it ends
+	received chan events.Event
+}
+
+// newSmokeTest creates a smoke test of api, waiting up to eventsTimeout for
+// each event
+func newSmokeTest(api *apiClient, eventsTimeout time.Duration) *smokeTest {
+	runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	return &smokeTest{
+		api:           api,
+		eventsTimeout: eventsTimeout,
+		runID:         runID,
+		name:          "Smoke Test " + runID,
+		email:         fmt.Sprintf("smoke-%s@example.com", runID),
+	}
+}
+
+// checks returns the steps of the test in order, each relying on the ones
+// before it
+func (s *smokeTest) checks() []smokeCheck {
+	return []smokeCheck{
+		{"health", s.checkHealth},
+		{"follow events", s.followEvents},
+		{"create user", s.createUser},
+		{"read user", s.readUser},
+		{"update user", s.updateUser},
+		{"read updated user", s.readUser},
+		{"delete user", s.deleteUser},
+		{"read deleted user", s.readDeletedUser},
+		{"consume events", s.consumeEvents},
+	}
+}
+
+// run runs the checks until one fails, reporting each to w, and cleans up.
+// It returns the error of the failed check.
+func (s *smokeTest) run(ctx context.Context, w io.Writer) error {
+	defer s.cleanup(context.WithoutCancel(ctx), w)
+
+	for _, check := range s.checks() {
+		start := time.Now()
+		err := check.run(ctx)
+		elapsed := roundLatency(time.Since(start))
+		if err != nil {
+			fmt.Fprintf(w, "FAIL  %-18s %v: %v\n", check.name, elapsed, err)
+			return fmt.Errorf("%s: %w", check.name, err)
+		}
+		fmt.Fprintf(w, "ok    %-18s %v\n", check.name, elapsed)
+	}
+	return nil
+}
+
+// cleanup stops following the events and deletes the user of the test
+// when a failure left it behind
+func (s *smokeTest) cleanup(ctx context.Context, w io.Writer) {
+	if s.stream != nil {
+		s.stream.Close()
+	}
+	if s.userID == "" || s.deleted {
+		return
+	}
+	resp, err := s.api.do(ctx, http.MethodDelete, "/users/"+s.userID, nil, http.Header{"If-Match": {"*"}})
+	if err != nil {
+		fmt.Fprintf(w, "Failed to delete the user %s created by the test: %v\n", s.userID, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// checkHealth expects the server to be healthy
+func (s *smokeTest) checkHealth(ctx context.Context) error {
+	resp, err := s.api.do(ctx, http.MethodGet, "/health", nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// followEvents opens the event stream before the user is created, so no
+// event of the test is missed
+func (s *smokeTest) followEvents(ctx context.Context) error {
+	resp, err := s.api.do(ctx, http.MethodGet, "/events?types=user.*", nil, http.Header{"Accept": {"text/event-stream"}})
+	if err != nil {
+		return err
+	}
+	s.stream = resp.Body
+	s.received = make(chan events.Event, 64)
+	go func() {
+		defer close(s.received)
+		events.ReadSSE(bufio.NewReader(resp.Body), func(m events.Message) error {
+			var event events.Event
+			if err := json.Unmarshal([]byte(m.Data), &event); err != nil {
+				return err
+			}
+			select {
+			case s.received <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return nil
+}
+
+// createUser creates the user of the test
+func (s *smokeTest) createUser(ctx context.Context) error {
+	resp, err := s.api.do(ctx, http.MethodPost, "/users", CreateUserRequest{Name: s.name, Email: s.email}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var created User
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	if created.ID == "" {
+		return errors.New("created user has no ID")
+	}
+	s.userID = created.ID
+	return nil
+}
+
+// readUser expects the user of the test to hold its latest name and email
+func (s *smokeTest) readUser(ctx context.Context) error {
+	resp, err := s.api.do(ctx, http.MethodGet, "/users/"+s.userID, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	if user.ID != s.userID || user.Name != s.name || user.Email != s.email {
+		return fmt.Errorf("got user %s %q <%s> want %s %q <%s>", user.ID, user.Name, user.Email, s.userID, s.name, s.email)
+	}
+	return nil
+}
+
+// updateUser renames the user of the test
+func (s *smokeTest) updateUser(ctx context.Context) error {
+	name := s.name + " (updated)"
+	resp, err := s.api.do(ctx, http.MethodPut, "/users/"+s.userID, UpdateUserRequest{Name: &name}, http.Header{"If-Match": {"*"}})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	s.name = name
+	return nil
+}
+
+// deleteUser deletes the user of the test
+func (s *smokeTest) deleteUser(ctx context.Context) error {
+	resp, err := s.api.do(ctx, http.MethodDelete, "/users/"+s.userID, nil, http.Header{"If-Match": {"*"}})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	s.deleted = true
+	return nil
+}
+
+// readDeletedUser expects the user of the test to be gone
+func (s *smokeTest) readDeletedUser(ctx context.Context) error {
+	resp, err := s.api.do(ctx, http.MethodGet, "/users/"+s.userID, nil, nil)
+	var status *apiStatusError
+	switch {
+	case err == nil:
+		resp.Body.Close()
+		return fmt.Errorf("got the deleted user %s want %s", s.userID, http.StatusText(http.StatusNotFound))
+	case errors.As(err, &status) && status.code == http.StatusNotFound:
+		return nil
+	default:
+		return err
+	}
+}
+
+// consumeEvents expects the events of the life of the user of the test on
+// the stream, in order
+func (s *smokeTest) consumeEvents(ctx context.Context) error {
+	for _, eventType := range smokeEventTypes {
+		if err := s.awaitEvent(ctx, eventType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// awaitEvent skips the events of the stream until the event of eventType
+// of the user of the test
+func (s *smokeTest) awaitEvent(ctx context.Context, eventType string) error {
+	timeout := time.NewTimer(s.eventsTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case event, ok := <-s.received:
+			if !ok {
+				return fmt.Errorf("the event stream ended before the %s event of %s", eventType, s.userID)
+			}
+			if event.Type == eventType && event.Subject == s.userID {
+				return nil
+			}
+		case <-timeout.C:
+			return fmt.Errorf("no %s event of %s within %v", eventType, s.userID, s.eventsTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// runSmoke checks a running server: it creates, reads, updates and deletes
+// a user of its own, and expects the events of these changes on its event
+// stream. It fails at the first check failing, so a deployment can be
+// verified by its exit code.
+func runSmoke(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := newCommandFlags("smoke", "Check the users API and the user events of a running server, exiting with 1 when a check fails", stderr)
+	api := apiFlags(flags)
+	timeout := flags.Duration("timeout", 30*time.Second, "time limit of the whole test")
+	eventsTimeout := flags.Duration("events-timeout", 10*time.Second, "how long to wait for each event")
+	cfg, err := parseCommand(flags, args)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *timeout <= 0:
+		return &usageError{"--timeout must be positive"}
+	case *eventsTimeout <= 0:
+		return &usageError{"--events-timeout must be positive"}
+	}
+	api.resolve(cfg)
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	fmt.Fprintf(stdout, "Smoke testing %s\n", api.baseURL)
+	start := time.Now()
+	test := newSmokeTest(api, *eventsTimeout)
+	if err := test.run(ctx, stdout); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "\nPassed %d checks in %v\n", len(test.checks()), roundLatency(time.Since(start)))
+	return nil
+}
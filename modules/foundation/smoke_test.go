@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newSmokeServer serves the users API, its event stream and the health
+// check like a deployment, with the event stream left out unless streamed
+func newSmokeServer(t *testing.T, service *InMemoryUserService, bus *EventBus, streamed bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	handler := NewUserHandler(service)
+	mux.Handle("/users", handler)
+	mux.Handle("/users/", handler)
+	mux.HandleFunc("/health", healthHandler)
+	if streamed {
+		stream := newEventStream(bus)
+		t.Cleanup(stream.Close)
+		mux.Handle("/events", stream)
+	} else {
+		// A stream that never sends an event
+		mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			http.NewResponseController(w).Flush()
+			<-r.Context().Done()
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunCLI_Smoke(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	bus := NewEventBus()
+	service := NewInMemoryUserService(WithEventPublisher(bus))
+	before := service.Count()
+	server := newSmokeServer(t, service, bus, true)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(context.Background(), []string{"smoke", "--url", server.URL}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code got %d want 0 (stdout %q, stderr %q)", code, stdout.String(), stderr.String())
+	}
+	output := stdout.String()
+	for _, check := range (&smokeTest{}).checks() {
+		if !strings.Contains(output, "ok    "+check.name) {
+			t.Errorf("stdout got\n%s\nwant check %q passed", output, check.name)
+		}
+	}
+	if !strings.Contains(output, "Passed 9 checks") {
+		t.Errorf("stdout got\n%s\nwant a summary of the 9 checks", output)
+	}
+	if got := service.Count(); got != before {
+		t.Errorf("got %d users after the test want the %d before", got, before)
+	}
+}
+
+func TestRunCLI_Smoke_Failures(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	t.Run("missing events", func(t *testing.T) {
+		service := NewInMemoryUserService()
+		server := newSmokeServer(t, service, nil, false)
+
+		var stdout, stderr bytes.Buffer
+		args := []string{"smoke", "--url", server.URL, "--events-timeout", "50ms"}
+		if code := runCLI(context.Background(), args, &stdout, &stderr); code != 1 {
+			t.Fatalf("exit code got %d want 1", code)
+		}
+		if !strings.Contains(stdout.String(), "FAIL  consume events") || !strings.Contains(stderr.String(), "no user.created event") {
+			t.Errorf("got stdout\n%s\nstderr %q want the consume events check failed", stdout.String(), stderr.String())
+		}
+	})
+
+	t.Run("user left behind", func(t *testing.T) {
+		service := NewInMemoryUserService()
+		before := service.Count()
+		api := newSmokeServer(t, service, nil, false)
+		// Updates fail, so the test stops before deleting its user
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				http.Error(w, "read only", http.StatusServiceUnavailable)
+				return
+			}
+			api.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		var stdout, stderr bytes.Buffer
+		if code := runCLI(context.Background(), []string{"smoke", "--url", server.URL}, &stdout, &stderr); code != 1 {
+			t.Fatalf("exit code got %d want 1", code)
+		}
+		if !strings.Contains(stdout.String(), "FAIL  update user") || !strings.Contains(stderr.String(), "503") {
+			t.Errorf("got stdout\n%s\nstderr %q want the update user check failed", stdout.String(), stderr.String())
+		}
+		if got := service.Count(); got != before {
+			t.Errorf("got %d users after the test want the %d before, the user of the test cleaned up", got, before)
+		}
+	})
+
+	t.Run("invalid flags", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := runCLI(context.Background(), []string{"smoke", "--timeout", "0s"}, &stdout, &stderr); code != 2 {
+			t.Errorf("exit code got %d want 2", code)
+		}
+	})
+}
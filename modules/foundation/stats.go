@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// statusClasses names the classes of response status codes, by hundreds
+var statusClasses = [...]string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// RequestCounter counts the requests served, by status class
+type RequestCounter struct {
+	classes [len(statusClasses)]atomic.Int64
+}
+
+// NewRequestCounter creates a RequestCounter starting at zero
+func NewRequestCounter() *RequestCounter {
+	return &RequestCounter{}
+}
+
+// Middleware counts the status of every response of next
+func (c *RequestCounter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapper, r)
+		c.Record(wrapper.statusCode)
+	})
+}
+
+// Record counts a response with status; codes outside 100-599 are ignored
+func (c *RequestCounter) Record(status int) {
+	if class := status/100 - 1; class >= 0 && class < len(statusClasses) {
+		c.classes[class].Add(1)
+	}
+}
+
+// Snapshot returns the total number of requests and the number of each status class
+func (c *RequestCounter) Snapshot() map[string]int64 {
+	counts := make(map[string]int64, len(statusClasses)+1)
+	var total int64
+	for i, class := range statusClasses {
+		counts[class] = c.classes[i].Load()
+		total += counts[class]
+	}
+	counts["total"] = total
+	return counts
+}
+
+// StatsHandler serves GET /stats: uptime, requests, events and store sizes
+// of the process, for demos and smoke checks
+type StatsHandler struct {
+	started  time.Time
+	requests *RequestCounter
+	bus      *EventBus
+	tenants  *TenantRegistry
+	audit    *AuditLog
+	now      func() time.Time
+}
+
+// NewStatsHandler creates a StatsHandler reporting the uptime since started
+func NewStatsHandler(started time.Time, requests *RequestCounter, bus *EventBus, tenants *TenantRegistry, audit *AuditLog) *StatsHandler {
+	return &StatsHandler{
+		started:  started,
+		requests: requests,
+		bus:      bus,
+		tenants:  tenants,
+		audit:    audit,
+		now:      time.Now,
+	}
+}
+
+// ServeHTTP handles GET /stats
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeErrorMessage(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	users := make(map[string]int)
+	for _, tenant := range h.tenants.Tenants() {
+		users[tenant] = h.tenants.Service(tenant).Count()
+	}
+	uptime := h.now().Sub(h.started)
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"service":        "user-service",
+		"started_at":     h.started.UTC(),
+		"uptime":         uptime.Truncate(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"requests":       h.requests.Snapshot(),
+		"events":         h.bus.Stats(),
+		"stores": map[string]interface{}{
+			"users":         users,
+			"audit_records": h.audit.Len(),
+		},
+		"goroutines": runtime.NumGoroutine(),
+	})
+}
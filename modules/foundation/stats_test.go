@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestCounter(t *testing.T) {
+	counter := NewRequestCounter()
+	for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusNotModified, http.StatusNotFound, http.StatusServiceUnavailable, 0, 600} {
+		counter.Record(status)
+	}
+
+	want := map[string]int64{"total": 5, "1xx": 0, "2xx": 2, "3xx": 1, "4xx": 1, "5xx": 1}
+	got := counter.Snapshot()
+	for class, count := range want {
+		if got[class] != count {
+			t.Errorf("%s got %d want %d", class, got[class], count)
+		}
+	}
+}
+
+func TestRequestCounter_Middleware(t *testing.T) {
+	counter := NewRequestCounter()
+	handler := counter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := counter.Snapshot(); got["2xx"] != 2 || got["4xx"] != 1 {
+		t.Errorf("Snapshot() got %v want 2 2xx and 1 4xx", got)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	bus := NewEventBus()
+	auditLog := NewAuditLog()
+	bus.Subscribe(auditLog.Record, "user.*")
+	tenants := NewTenantRegistry(AllowTenants("acme"), func(tenant string) *InMemoryUserService {
+		return NewInMemoryUserService(WithTenant(tenant), WithEventPublisher(bus))
+	})
+	if _, err := tenants.Service("acme").CreateUser("Ada", "ada@example.com"); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	tenants.Service(defaultTenant).Reset(context.Background())
+
+	counter := NewRequestCounter()
+	counter.Record(http.StatusOK)
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewStatsHandler(started, counter, bus, tenants, auditLog)
+	handler.now = func() time.Time { return started.Add(90 * time.Minute) }
+
+	tests := []struct {
+		method   string
+		wantCode int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/stats", nil))
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status code got %v want %v", rr.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Uptime        string           `json:"uptime"`
+				UptimeSeconds int64            `json:"uptime_seconds"`
+				Requests      map[string]int64 `json:"requests"`
+				Events        EventBusStats    `json:"events"`
+				Stores        struct {
+					Users        map[string]int `json:"users"`
+					AuditRecords int            `json:"audit_records"`
+				} `json:"stores"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+
+			if body.Uptime != "1h30m0s" || body.UptimeSeconds != 5400 {
+				t.Errorf("uptime got %q (%ds) want 1h30m0s (5400s)", body.Uptime, body.UptimeSeconds)
+			}
+			if body.Requests["total"] != 1 || body.Requests["2xx"] != 1 {
+				t.Errorf("requests got %v want one 2xx", body.Requests)
+			}
+			// One user created in acme, three fixture users removed from the default tenant
+			if want := (EventBusStats{Published: 4, Consumed: 4}); body.Events != want {
+				t.Errorf("events got %+v want %+v", body.Events, want)
+			}
+			if body.Stores.Users["acme"] != 4 || body.Stores.Users[defaultTenant] != 0 || body.Stores.AuditRecords != 4 {
+				t.Errorf("stores got %+v want 4 acme users, 0 default users and 4 audit records", body.Stores)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// defaultTenant serves requests that do not name a tenant
+	defaultTenant = "default"
+
+	// tenantHeader is the request header (and gRPC metadata key) naming the tenant
+	tenantHeader = "X-Tenant-ID"
+)
+
+// tenantIDPattern restricts tenant IDs to DNS-label-like names so they can
+// also be taken from a subdomain
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// tenantContextKey is the context key under which the tenant ID is stored
+type tenantContextKey struct{}
+
+// ContextWithTenant returns a copy of ctx carrying the tenant ID, which is
+// also added to every record logged with the context
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	canonicalLineFromContext(ctx).setTenant(tenant)
+	ctx = logging.ContextWithAttrs(ctx, slog.String("tenant", tenant))
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ID stored in ctx, or the default tenant
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenant
+	}
+	return defaultTenant
+}
+
+// validateTenant checks that a tenant ID is well formed
+func validateTenant(tenant string) error {
+	if !tenantIDPattern.MatchString(tenant) {
+		return NewValidationError("tenant", fmt.Sprintf("tenant ID '%s' must be a lowercase DNS label", tenant))
+	}
+	return nil
+}
+
+// resolveTenant returns the tenant of a request: the X-Tenant-ID header,
+// else the subdomain of baseDomain in the Host (acme.example.com → acme when
+// baseDomain is example.com), else the default tenant
+func resolveTenant(r *http.Request, baseDomain string) (string, error) {
+	if tenant := r.Header.Get(tenantHeader); tenant != "" {
+		return tenant, validateTenant(tenant)
+	}
+
+	if baseDomain != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if subdomain, ok := strings.CutSuffix(strings.ToLower(host), "."+baseDomain); ok {
+			return subdomain, validateTenant(subdomain)
+		}
+	}
+
+	return defaultTenant, nil
+}
+
+// TenantCheck returns an error for the tenants that are not served
+type TenantCheck func(tenant string) error
+
+// AllowTenants serves the default tenant and the given tenants only, so
+// stores and handlers are built for a bounded set of tenants. Other tenants
+// are not found.
+func AllowTenants(tenants ...string) TenantCheck {
+	allowed := map[string]bool{defaultTenant: true}
+	for _, tenant := range tenants {
+		allowed[tenant] = true
+	}
+	return func(tenant string) error {
+		if !allowed[tenant] {
+			return NewNotFoundError("tenant", tenant)
+		}
+		return nil
+	}
+}
+
+// loadAllowedTenants returns the check serving the default tenant and the
+// tenants of TENANTS
+func loadAllowedTenants(tenants []string) (TenantCheck, error) {
+	for _, tenant := range tenants {
+		if validateTenant(tenant) != nil {
+			return nil, fmt.Errorf("invalid TENANTS entry %q, want a lowercase DNS label", tenant)
+		}
+	}
+	return AllowTenants(tenants...), nil
+}
+
+// ServiceResolver returns the user service of the tenant carried by ctx
+type ServiceResolver func(ctx context.Context) UserService
+
+// SingleService resolves every tenant to the same service
+func SingleService(service UserService) ServiceResolver {
+	return func(context.Context) UserService {
+		return service
+	}
+}
+
+// TenantRegistry keeps an isolated user store per served tenant, created on
+// first use
+type TenantRegistry struct {
+	services   map[string]*InMemoryUserService
+	check      TenantCheck
+	newService func(tenant string) *InMemoryUserService
+	mutex      sync.Mutex
+}
+
+// NewTenantRegistry creates a registry building the store of each tenant
+// passing check with newService
+func NewTenantRegistry(check TenantCheck, newService func(tenant string) *InMemoryUserService) *TenantRegistry {
+	return &TenantRegistry{
+		services:   make(map[string]*InMemoryUserService),
+		check:      check,
+		newService: newService,
+	}
+}
+
+// Service returns the store of tenant, creating it when needed. It returns
+// nil for a tenant that is not served, which the tenant routers and the gRPC
+// server reject before any store is needed.
+func (t *TenantRegistry) Service(tenant string) *InMemoryUserService {
+	if t.check(tenant) != nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	service, ok := t.services[tenant]
+	if !ok {
+		service = t.newService(tenant)
+		t.services[tenant] = service
+	}
+	return service
+}
+
+// ServiceFor returns the store of the tenant carried by ctx, traced as part
+// of ctx. It is a ServiceResolver.
+func (t *TenantRegistry) ServiceFor(ctx context.Context) UserService {
+	return TraceUserService(ctx, t.Service(TenantFromContext(ctx)))
+}
+
+// Tenants returns the IDs of the tenants whose store was created, sorted
+func (t *TenantRegistry) Tenants() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tenants := make([]string, 0, len(t.services))
+	for tenant := range t.services {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// TenantRouter resolves the tenant of each request and serves it with the
+// handler of that tenant, built on first use. Requests for tenants that are
+// not served are not found.
+type TenantRouter struct {
+	baseDomain string
+	check      TenantCheck
+	build      func(tenant string) http.Handler
+	handlers   map[string]http.Handler
+	mutex      sync.Mutex
+}
+
+// NewTenantRouter creates a router building per-tenant handlers with build.
+// baseDomain enables taking the tenant from the subdomain; it may be empty.
+func NewTenantRouter(baseDomain string, check TenantCheck, build func(tenant string) http.Handler) *TenantRouter {
+	return &TenantRouter{
+		baseDomain: strings.ToLower(baseDomain),
+		check:      check,
+		build:      build,
+		handlers:   make(map[string]http.Handler),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (t *TenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, err := resolveTenant(r, t.baseDomain)
+	if err == nil {
+		err = t.check(tenant)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	t.handler(tenant).ServeHTTP(w, r.WithContext(ContextWithTenant(r.Context(), tenant)))
+}
+
+// handler returns the handler of tenant, building it when needed
+func (t *TenantRouter) handler(tenant string) http.Handler {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	handler, ok := t.handlers[tenant]
+	if !ok {
+		handler = t.build(tenant)
+		t.handlers[tenant] = handler
+	}
+	return handler
+}
+
+// Close closes the built handlers that hold resources, such as GraphQL
+// subscription streams
+func (t *TenantRouter) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, handler := range t.handlers {
+		if closer, ok := handler.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// tenantUnaryInterceptor reads the tenant from the "x-tenant-id" metadata,
+// rejects the tenants not passing check and stores it in the context of the call
+func tenantUnaryInterceptor(check TenantCheck) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		tenant := defaultTenant
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(tenantHeader); len(values) > 0 {
+			tenant = values[0]
+			if err := validateTenant(tenant); err != nil {
+				return nil, toGRPCError(err)
+			}
+		}
+		if err := check(tenant); err != nil {
+			return nil, toGRPCError(err)
+		}
+		return handler(ContextWithTenant(ctx, tenant), req)
+	}
+}
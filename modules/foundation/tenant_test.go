@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/captain-corgi/learning-event-driven/modules/foundation/proto/user/v1"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestResolveTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		header     string
+		baseDomain string
+		expected   string
+		wantErr    bool
+	}{
+		{"no tenant", "localhost:8080", "", "", defaultTenant, false},
+		{"header", "localhost:8080", "acme", "", "acme", false},
+		{"invalid header", "localhost:8080", "Acme_Corp", "", "", true},
+		{"subdomain", "acme.users.test:8080", "", "users.test", "acme", false},
+		{"header beats subdomain", "acme.users.test", "globex", "users.test", "globex", false},
+		{"base domain itself", "users.test", "", "users.test", defaultTenant, false},
+		{"nested subdomain", "a.b.users.test", "", "users.test", "", true},
+		{"subdomain disabled", "acme.users.test", "", "", defaultTenant, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(tenantHeader, tt.header)
+			}
+
+			tenant, err := resolveTenant(req, tt.baseDomain)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTenant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tenant != tt.expected {
+				t.Errorf("resolveTenant() = %q, want %q", tenant, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTenantRouter_IsolatesTenants(t *testing.T) {
+	bus := NewEventBus()
+	tenants := NewTenantRegistry(AllowTenants("acme", "globex"), func(tenant string) *InMemoryUserService {
+		return NewInMemoryUserService(WithEventPublisher(bus), WithTenant(tenant))
+	})
+	var built []string
+	router := NewTenantRouter("", AllowTenants("acme", "globex"), func(tenant string) http.Handler {
+		built = append(built, tenant)
+		return NewUserHandler(tenants.Service(tenant))
+	})
+
+	var published []events.Event
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		published = append(published, event)
+		return nil
+	})
+
+	request := func(method, path, tenant, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if tenant != "" {
+			req.Header.Set(tenantHeader, tenant)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The same email may exist once per tenant
+	for _, tenant := range []string{"acme", "globex"} {
+		rr := request(http.MethodPost, "/users", tenant, `{"name":"Shared","email":"shared@example.com"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("create in %s returned %v: %s", tenant, rr.Code, rr.Body)
+		}
+	}
+
+	var created User
+	rr := request(http.MethodPost, "/users", "acme", `{"name":"Acme Only","email":"acme@example.com"}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		tenant         string
+		path           string
+		expectedStatus int
+	}{
+		{"owner tenant", "acme", "/users/" + created.ID, http.StatusOK},
+		{"other tenant", "globex", "/users/" + created.ID, http.StatusNotFound},
+		{"default tenant", "", "/users/" + created.ID, http.StatusNotFound},
+		{"invalid tenant", "not a tenant", "/users", http.StatusBadRequest},
+		{"unknown tenant", "initech", "/users", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := request(http.MethodGet, tt.path, tt.tenant, ""); rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	if got := strings.Join(tenants.Tenants(), ","); got != "acme,default,globex" {
+		t.Errorf("Tenants() = %q, want %q", got, "acme,default,globex")
+	}
+	if got := strings.Join(built, ","); got != "acme,globex,default" {
+		t.Errorf("built handlers of %q, want %q", got, "acme,globex,default")
+	}
+	if service := tenants.Service("initech"); service != nil {
+		t.Error("Service() of an unknown tenant should be nil")
+	}
+
+	wantTenants := []string{"acme", "globex", "acme"}
+	if len(published) != len(wantTenants) {
+		t.Fatalf("received %d events, want %d", len(published), len(wantTenants))
+	}
+	for i, event := range published {
+		if event.Tenant != wantTenants[i] {
+			t.Errorf("event %d tenant = %q, want %q", i, event.Tenant, wantTenants[i])
+		}
+	}
+}
+
+func TestGRPCUserServer_Tenants(t *testing.T) {
+	tenants := NewTenantRegistry(AllowTenants("acme", "globex"), func(tenant string) *InMemoryUserService {
+		return NewInMemoryUserService(WithTenant(tenant))
+	})
+	client := newGRPCTestClient(t, NewGRPCServer(tenants.ServiceFor, AllowTenants("acme"), nil, nil))
+
+	acme := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme")
+	created, err := client.CreateUser(acme, &userv1.CreateUserRequest{Name: "Acme User", Email: "user@acme.test"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if _, err := client.GetUser(acme, &userv1.GetUserRequest{Id: created.GetUser().GetId()}); err != nil {
+		t.Errorf("GetUser() in owner tenant error = %v", err)
+	}
+
+	_, err = client.GetUser(context.Background(), &userv1.GetUserRequest{Id: created.GetUser().GetId()})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("GetUser() in default tenant code = %v, want %v", got, codes.NotFound)
+	}
+
+	invalid := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "Not Valid")
+	_, err = client.ListUsers(invalid, &userv1.ListUsersRequest{})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("ListUsers() with invalid tenant code = %v, want %v", got, codes.InvalidArgument)
+	}
+
+	unknown := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "initech")
+	_, err = client.ListUsers(unknown, &userv1.ListUsersRequest{})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("ListUsers() with unknown tenant code = %v, want %v", got, codes.NotFound)
+	}
+}
+
+func TestLoadAllowedTenants(t *testing.T) {
+	check, err := loadAllowedTenants([]string{"acme"})
+	if err != nil {
+		t.Fatalf("loadAllowedTenants() error = %v", err)
+	}
+	for tenant, wantErr := range map[string]bool{defaultTenant: false, "acme": false, "globex": true} {
+		if err := check(tenant); (err != nil) != wantErr {
+			t.Errorf("check(%q) error = %v, wantErr %v", tenant, err, wantErr)
+		}
+	}
+
+	if _, err := loadAllowedTenants([]string{"Acme Corp"}); err == nil {
+		t.Error("loadAllowedTenants() with an invalid tenant ID should fail")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteTimeouts holds the time budget of each route
+type RouteTimeouts struct {
+	defaultTimeout time.Duration
+	routes         map[string]time.Duration
+}
+
+// loadRouteTimeouts applies the default budget (REQUEST_TIMEOUT) to every
+// route without an override in routes (ROUTE_TIMEOUTS), entries such as
+// "/users=2s" or "/graphql=30s". A zero duration disables the timeout of a route.
+func loadRouteTimeouts(defaultTimeout time.Duration, routes []string) (RouteTimeouts, error) {
+	timeouts := RouteTimeouts{defaultTimeout: defaultTimeout, routes: make(map[string]time.Duration)}
+	if defaultTimeout < 0 {
+		return RouteTimeouts{}, fmt.Errorf("REQUEST_TIMEOUT must be a non-negative duration")
+	}
+
+	for _, entry := range routes {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(entry, "=")
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || timeout < 0 || !strings.HasPrefix(route, "/") {
+			return RouteTimeouts{}, fmt.Errorf("invalid ROUTE_TIMEOUTS entry %q, want /route=duration", entry)
+		}
+		timeouts.routes[strings.TrimSuffix(strings.TrimSpace(route), "/")] = timeout
+	}
+	return timeouts, nil
+}
+
+// For returns the budget of the route registered under pattern
+func (t RouteTimeouts) For(pattern string) time.Duration {
+	if timeout, ok := t.routes[strings.TrimSuffix(pattern, "/")]; ok {
+		return timeout
+	}
+	return t.defaultTimeout
+}
+
+// Wrap applies the budget of the route registered under pattern to handler
+func (t RouteTimeouts) Wrap(pattern string, handler http.Handler) http.Handler {
+	return timeoutMiddleware(t.For(pattern), handler)
+}
+
+// timeoutMiddleware gives next a context with a deadline of timeout and answers
+// 504 with a TIMEOUT_ERROR when next has not finished by then. The response of
+// next is buffered so it can be replaced, and is discarded after the deadline.
+// Server-sent event streams are long-lived by design and are not limited.
+// A panic of next is raised again with the stack of next, for the recovery
+// middleware; one after the deadline is logged and counted here instead.
+func timeoutMiddleware(timeout time.Duration, next http.Handler) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan *handlerPanic, 1)
+		go func() {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				tw.mutex.Lock()
+				defer tw.mutex.Unlock()
+				if !tw.timedOut {
+					panicked <- &handlerPanic{value: p, stack: debug.Stack()}
+				} else if p != http.ErrAbortHandler {
+					// The request is answered, so nobody else sees this panic
+					logPanic(r, p, debug.Stack())
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.mutex.Lock()
+			defer tw.mutex.Unlock()
+			for name, values := range tw.header {
+				w.Header()[name] = values
+			}
+			if tw.code == 0 {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			w.Write(tw.body.Bytes())
+		case <-ctx.Done():
+			tw.mutex.Lock()
+			defer tw.mutex.Unlock()
+			select {
+			case p := <-panicked:
+				// The handler panicked as the deadline passed
+				panic(p)
+			default:
+			}
+			tw.timedOut = true
+			if ctx.Err() == context.DeadlineExceeded {
+				writeError(w, r, NewTimeoutError(timeout))
+			}
+		}
+	})
+}
+
+// timeoutWriter buffers a response until the handler finishes in time
+type timeoutWriter struct {
+	header   http.Header
+	body     bytes.Buffer
+	code     int
+	timedOut bool
+	mutex    sync.Mutex
+}
+
+// Header returns the buffered header
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write buffers b, failing once the deadline has passed
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
+
+// WriteHeader records the status code of the first call
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
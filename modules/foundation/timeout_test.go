@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		w.Header().Set("X-Handler", "fast")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	})
+	// slow writes once the middleware has answered, reporting the write error
+	release := make(chan struct{})
+	lateWrite := make(chan error, 1)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, err := w.Write([]byte("too late"))
+		lateWrite <- err
+	})
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("event"))
+	})
+
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		handler        http.Handler
+		accept         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"finishes in time", time.Second, fast, "", http.StatusCreated, "done"},
+		{"exceeds budget", 10 * time.Millisecond, slow, "", http.StatusGatewayTimeout, ""},
+		{"event stream is not limited", 10 * time.Millisecond, stream, "text/event-stream", http.StatusOK, "event"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			timeoutMiddleware(tt.timeout, tt.handler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("middleware returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.expectedBody)
+			}
+			if tt.expectedStatus != http.StatusGatewayTimeout {
+				return
+			}
+
+			close(release)
+			if err := <-lateWrite; err != http.ErrHandlerTimeout {
+				t.Errorf("Write() after deadline error = %v, want %v", err, http.ErrHandlerTimeout)
+			}
+
+			var problem map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if problem["code"] != string(ErrorTypeTimeout) || problem["timeout_ms"] != float64(10) {
+				t.Errorf("unexpected problem: %v", problem)
+			}
+		})
+	}
+
+	t.Run("headers of finished handler", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		timeoutMiddleware(time.Second, fast).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users", nil))
+		if got := rr.Header().Get("X-Handler"); got != "fast" {
+			t.Errorf("X-Handler = %q, want %q", got, "fast")
+		}
+	})
+}
+
+// panicInHandler is a handler whose frame the stack of its panic must show
+func panicInHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+func TestTimeoutMiddleware_Panic(t *testing.T) {
+	defer func() {
+		hp, ok := recover().(*handlerPanic)
+		if !ok {
+			t.Fatalf("recovered %T, want a *handlerPanic", hp)
+		}
+		if hp.value != "boom" || !strings.Contains(string(hp.stack), "panicInHandler") {
+			t.Errorf("got panic %v with stack %s want boom with the handler frame", hp.value, hp.stack)
+		}
+	}()
+	timeoutMiddleware(time.Second, http.HandlerFunc(panicInHandler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+}
+
+func TestTimeoutMiddleware_PanicAfterDeadline(t *testing.T) {
+	release := make(chan struct{})
+	handler := timeoutMiddleware(10*time.Millisecond, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		panic("late")
+	}))
+
+	before := httpPanics.Value()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status code got %v want %v", rr.Code, http.StatusGatewayTimeout)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for httpPanics.Value() == before {
+		if time.Now().After(deadline) {
+			t.Fatal("panic after the deadline was not counted in http_panics_total")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLoadRouteTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  time.Duration
+		routes   []string
+		expected map[string]time.Duration
+		wantErr  bool
+	}{
+		{"defaults", 10 * time.Second, nil, map[string]time.Duration{"/users": 10 * time.Second}, false},
+		{"overrides", 5 * time.Second, []string{"/users=2s", " /graphql/=0s"}, map[string]time.Duration{"/users/": 2 * time.Second, "/graphql": 0, "/admin/": 5 * time.Second}, false},
+		{"negative default", -time.Second, nil, nil, true},
+		{"invalid entry", 10 * time.Second, []string{"/users"}, nil, true},
+		{"relative route", 10 * time.Second, []string{"users=1s"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeouts, err := loadRouteTimeouts(tt.request, tt.routes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRouteTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for route, want := range tt.expected {
+				if got := timeouts.For(route); got != want {
+					t.Errorf("For(%q) = %v, want %v", route, got, want)
+				}
+			}
+		})
+	}
+}
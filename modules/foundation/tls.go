@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// selfSignedValidity is how long generated development certificates stay valid
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// TLSConfig holds the HTTPS settings of the server
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM files loaded (and reloaded on change) at runtime
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE"`
+	// SelfSigned generates an in-memory certificate for local development
+	SelfSigned bool `yaml:"self_signed" env:"TLS_SELF_SIGNED"`
+	// RedirectPort, when set, serves plain HTTP redirects to HTTPS on that port
+	RedirectPort string `yaml:"redirect_port" env:"TLS_REDIRECT_PORT"`
+}
+
+// Enabled reports whether the server should serve HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.SelfSigned || (c.CertFile != "" && c.KeyFile != "")
+}
+
+// newServerTLSConfig builds the *tls.Config for the server. Certificates from
+// files are reloaded automatically when they change on disk, so rotated
+// certificates are picked up without a restart.
+func newServerTLSConfig(cfg TLSConfig, host string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		loader := &certificateLoader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+		if _, err := loader.GetCertificate(nil); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = loader.GetCertificate
+		return tlsConfig, nil
+	}
+
+	cert, err := generateSelfSignedCertificate([]string{host, "localhost", "127.0.0.1", "::1"})
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	return tlsConfig, nil
+}
+
+// certificateLoader serves a key pair from disk and reloads it when the
+// certificate file's modification time changes
+type certificateLoader struct {
+	certFile string
+	keyFile  string
+
+	mutex   sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (l *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	info, err := os.Stat(l.certFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading TLS certificate")
+	}
+
+	l.mutex.RLock()
+	cert, modTime := l.cert, l.modTime
+	l.mutex.RUnlock()
+
+	if cert != nil && info.ModTime().Equal(modTime) {
+		return cert, nil
+	}
+
+	loaded, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if cert != nil {
+			// Keep serving the previous certificate while a rotation is half written
+			return cert, nil
+		}
+		return nil, errors.Wrap(err, "loading TLS key pair")
+	}
+
+	l.mutex.Lock()
+	l.cert, l.modTime = &loaded, info.ModTime()
+	l.mutex.Unlock()
+
+	return &loaded, nil
+}
+
+// generateSelfSignedCertificate creates an in-memory certificate valid for hosts.
+// It is intended for local development only.
+func generateSelfSignedCertificate(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "generating private key")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "generating serial number")
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"learning-event-driven development"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "creating certificate")
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        template,
+	}, nil
+}
+
+// httpsRedirectHandler redirects plain HTTP requests to the HTTPS port
+func httpsRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		target := "https://" + net.JoinHostPort(host, httpsPort) + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateSelfSignedCertificate(t *testing.T) {
+	cert, err := generateSelfSignedCertificate([]string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("generateSelfSignedCertificate() error = %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("generated certificate is invalid: %v", err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("certificate should be valid for localhost: %v", err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("certificate should be valid for 127.0.0.1: %v", err)
+	}
+}
+
+func TestCertificateLoader_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	writeKeyPair := func(host string, modTime time.Time) {
+		t.Helper()
+		cert, err := generateSelfSignedCertificate([]string{host})
+		if err != nil {
+			t.Fatalf("generating certificate: %v", err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+		if err != nil {
+			t.Fatalf("marshaling key: %v", err)
+		}
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+		if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(certFile, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	writeKeyPair("first.example.com", now.Add(-time.Hour))
+	loader := &certificateLoader{certFile: certFile, keyFile: keyFile}
+
+	first, err := loader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	writeKeyPair("second.example.com", now)
+	second, err := loader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	if first == second {
+		t.Error("GetCertificate() should reload the certificate after it changed on disk")
+	}
+	leaf, err := x509.ParseCertificate(second.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("second.example.com"); err != nil {
+		t.Errorf("reloaded certificate has unexpected hosts: %v", err)
+	}
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/users?limit=1", nil)
+	rr := httptest.NewRecorder()
+
+	httpsRedirectHandler("8443").ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPermanentRedirect {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusPermanentRedirect)
+	}
+	if got, want := rr.Header().Get("Location"), "https://localhost:8443/users?limit=1"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
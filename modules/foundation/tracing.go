@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation of this service in exported spans
+const tracerName = "github.com/captain-corgi/learning-event-driven/modules/foundation"
+
+// TracingConfig configures the export of OpenTelemetry traces
+type TracingConfig struct {
+	Endpoint    string       // OTLP/HTTP collector URL; empty disables export
+	SampleRate  float64      // fraction of new traces that are recorded
+	Sampling    SamplingMode // when the sampling decision is made
+	ServiceName string
+}
+
+// loadTracingConfig builds the tracing configuration from settings: the OTLP
+// endpoint, e.g. "http://localhost:4318", sample rate, sampling mode and
+// service name
+func loadTracingConfig(settings TracingSettings) (TracingConfig, error) {
+	config := TracingConfig{
+		Endpoint:    settings.Endpoint,
+		Sampling:    SamplingMode(strings.ToLower(settings.Sampling)),
+		ServiceName: settings.ServiceName,
+	}
+
+	rate, err := parseSampleRate(settings.SampleRate)
+	if err != nil {
+		return TracingConfig{}, err
+	}
+	config.SampleRate = rate
+
+	if config.Sampling != SamplingHead && config.Sampling != SamplingTail {
+		return TracingConfig{}, fmt.Errorf("TRACE_SAMPLING must be head or tail")
+	}
+	return config, nil
+}
+
+// Enabled reports whether spans are exported
+func (c TracingConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// newTracerProvider creates a provider exporting batches of spans to the
+// collector. Traces started by a caller keep the caller's sampling decision.
+// With head sampling new ones are recorded at the configured rate; with tail
+// sampling all are recorded and the decision is made when they end.
+func newTracerProvider(ctx context.Context, config TracingConfig) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", config.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Sampling == SamplingTail {
+		return sdktrace.NewTracerProvider(
+			sdktrace.WithSpanProcessor(newTailSamplingProcessor(sdktrace.NewBatchSpanProcessor(exporter), config.SampleRate)),
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+		), nil
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRate))),
+	), nil
+}
+
+// setupTracing installs the global tracer provider and W3C trace context
+// propagation. Without an endpoint the global no-op provider is kept, so the
+// instrumentation costs next to nothing. The returned function flushes and
+// stops the exporter.
+func setupTracing(ctx context.Context, config TracingConfig) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider, err := newTracerProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// tracingMiddleware starts a server span for every request, continuing a
+// trace propagated by the client. Spans are named after the method and the
+// first path segment, e.g. "GET /users", to keep their number bounded.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + spanRoute(r.URL.Path)
+		}),
+	)
+}
+
+// spanRoute returns the first segment of path, which names the resource
+func spanRoute(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return "/" + segment
+}
+
+// tracer returns the tracer of this service from the global provider
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// endSpan records err on span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracingUserService records a span for every call of the UserService it
+// decorates, as a child of the span of the request it serves
+type TracingUserService struct {
+	ctx  context.Context
+	next UserService
+}
+
+// TraceUserService decorates service so its calls are traced as part of ctx.
+// A service that can serve a single request is bound to ctx first.
+func TraceUserService(ctx context.Context, service UserService) UserService {
+	if scoper, ok := service.(interface {
+		WithContext(context.Context) UserService
+	}); ok {
+		service = scoper.WithContext(ctx)
+	}
+	return &TracingUserService{ctx: ctx, next: service}
+}
+
+// start starts the span of operation
+func (s *TracingUserService) start(operation string, attrs ...attribute.KeyValue) (trace.Span, time.Time) {
+	attrs = append(attrs, attribute.String("tenant", TenantFromContext(s.ctx)))
+	_, span := tracer().Start(s.ctx, "UserService."+operation, trace.WithAttributes(attrs...))
+	return span, time.Now()
+}
+
+// end ends the span of a call started at start, and adds the call to the
+// canonical log line of the request
+func (s *TracingUserService) end(span trace.Span, start time.Time, err error) {
+	canonicalLineFromContext(s.ctx).addServiceCall(time.Since(start))
+	endSpan(span, err)
+}
+
+// GetUsers traces UserService.GetUsers
+func (s *TracingUserService) GetUsers() (users []User, err error) {
+	span, start := s.start("GetUsers")
+	defer func() { s.end(span, start, err) }()
+	users, err = s.next.GetUsers()
+	span.SetAttributes(attribute.Int("users.count", len(users)))
+	return users, err
+}
+
+// GetUserByID traces UserService.GetUserByID
+func (s *TracingUserService) GetUserByID(id string) (user *User, err error) {
+	span, start := s.start("GetUserByID", attribute.String("user.id", id))
+	defer func() { s.end(span, start, err) }()
+	return s.next.GetUserByID(id)
+}
+
+// LastModified is not traced; it only reads a timestamp
+func (s *TracingUserService) LastModified() time.Time {
+	return s.next.LastModified()
+}
+
+// GetUsersByIDs traces UserService.GetUsersByIDs
+func (s *TracingUserService) GetUsersByIDs(ids []string) (users []User, missing []string, err error) {
+	span, start := s.start("GetUsersByIDs", attribute.Int("users.requested", len(ids)))
+	defer func() { s.end(span, start, err) }()
+	return s.next.GetUsersByIDs(ids)
+}
+
+// GetUserByEmail traces UserService.GetUserByEmail
+func (s *TracingUserService) GetUserByEmail(email string) (user *User, err error) {
+	span, start := s.start("GetUserByEmail")
+	defer func() { s.end(span, start, err) }()
+	return s.next.GetUserByEmail(email)
+}
+
+// CreateUser traces UserService.CreateUser
+func (s *TracingUserService) CreateUser(name, email string) (user *User, err error) {
+	span, start := s.start("CreateUser")
+	defer func() { s.end(span, start, err) }()
+	return s.next.CreateUser(name, email)
+}
+
+// RegisterUser traces UserService.RegisterUser
+func (s *TracingUserService) RegisterUser(name, email, password string) (user *User, err error) {
+	span, start := s.start("RegisterUser")
+	defer func() { s.end(span, start, err) }()
+	return s.next.RegisterUser(name, email, password)
+}
+
+// Authenticate traces UserService.Authenticate
+func (s *TracingUserService) Authenticate(email, password string) (user *User, err error) {
+	span, start := s.start("Authenticate")
+	defer func() { s.end(span, start, err) }()
+	return s.next.Authenticate(email, password)
+}
+
+// ChangePassword traces UserService.ChangePassword
+func (s *TracingUserService) ChangePassword(id, currentPassword, newPassword string, expectedVersion int64) (user *User, err error) {
+	span, start := s.start("ChangePassword", attribute.String("user.id", id))
+	defer func() { s.end(span, start, err) }()
+	return s.next.ChangePassword(id, currentPassword, newPassword, expectedVersion)
+}
+
+// UpdateUser traces UserService.UpdateUser
+func (s *TracingUserService) UpdateUser(id, name, email string, expectedVersion int64) (user *User, err error) {
+	span, start := s.start("UpdateUser", attribute.String("user.id", id))
+	defer func() { s.end(span, start, err) }()
+	return s.next.UpdateUser(id, name, email, expectedVersion)
+}
+
+// DeleteUser traces UserService.DeleteUser
+func (s *TracingUserService) DeleteUser(id string, expectedVersion int64) (err error) {
+	span, start := s.start("DeleteUser", attribute.String("user.id", id))
+	defer func() { s.end(span, start, err) }()
+	return s.next.DeleteUser(id, expectedVersion)
+}
+
+// AssignRoles traces UserService.AssignRoles
+func (s *TracingUserService) AssignRoles(id string, roles []Role, expectedVersion int64) (user *User, err error) {
+	span, start := s.start("AssignRoles", attribute.String("user.id", id))
+	defer func() { s.end(span, start, err) }()
+	return s.next.AssignRoles(id, roles, expectedVersion)
+}
+
+// ChangeStatus traces UserService.ChangeStatus
+func (s *TracingUserService) ChangeStatus(id string, status UserStatus, expectedVersion int64) (user *User, err error) {
+	span, start := s.start("ChangeStatus", attribute.String("user.id", id), attribute.String("user.status", string(status)))
+	defer func() { s.end(span, start, err) }()
+	return s.next.ChangeStatus(id, status, expectedVersion)
+}
+
+// SetAvatar traces UserService.SetAvatar
+func (s *TracingUserService) SetAvatar(id, avatarURL string, expectedVersion int64) (user *User, err error) {
+	span, start := s.start("SetAvatar", attribute.String("user.id", id))
+	defer func() { s.end(span, start, err) }()
+	return s.next.SetAvatar(id, avatarURL, expectedVersion)
+}
+
+// TracingSessionStore records a span for every call of the SessionStore it decorates
+type TracingSessionStore struct {
+	next SessionStore
+}
+
+// TraceSessionStore decorates store so its calls are traced
+func TraceSessionStore(store SessionStore) *TracingSessionStore {
+	return &TracingSessionStore{next: store}
+}
+
+// Save traces SessionStore.Save
+func (s *TracingSessionStore) Save(ctx context.Context, session *Session) (err error) {
+	ctx, span := tracer().Start(ctx, "SessionStore.Save")
+	defer func() { endSpan(span, err) }()
+	return s.next.Save(ctx, session)
+}
+
+// Get traces SessionStore.Get. A missing session is an expected outcome, not an error.
+func (s *TracingSessionStore) Get(ctx context.Context, id string) (session *Session, err error) {
+	ctx, span := tracer().Start(ctx, "SessionStore.Get")
+	session, err = s.next.Get(ctx, id)
+	span.SetAttributes(attribute.Bool("session.found", err == nil))
+	if err == ErrSessionNotFound {
+		endSpan(span, nil)
+	} else {
+		endSpan(span, err)
+	}
+	return session, err
+}
+
+// Delete traces SessionStore.Delete
+func (s *TracingSessionStore) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := tracer().Start(ctx, "SessionStore.Delete")
+	defer func() { endSpan(span, err) }()
+	return s.next.Delete(ctx, id)
+}
+
+// Close closes the decorated store when it holds resources
+func (s *TracingSessionStore) Close() error {
+	if closer, ok := s.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every span for the duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// findSpan returns the ended span called name
+func findSpan(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestLoadTracingConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		rate     string
+		sampling string
+		want     float64
+		wantMode SamplingMode
+		enabled  bool
+		wantErr  bool
+	}{
+		{name: "disabled by default", want: 1, wantMode: SamplingHead},
+		{name: "endpoint enables export", endpoint: "http://localhost:4318", want: 1, wantMode: SamplingHead, enabled: true},
+		{name: "sample rate", endpoint: "http://localhost:4318", rate: "0.25", want: 0.25, wantMode: SamplingHead, enabled: true},
+		{name: "sample percentage", rate: "5%", want: 0.05, wantMode: SamplingHead},
+		{name: "tail sampling", rate: "0.1", sampling: "Tail", want: 0.1, wantMode: SamplingTail},
+		{name: "rate above one", rate: "2", wantErr: true},
+		{name: "percentage above 100", rate: "150%", wantErr: true},
+		{name: "rate not a number", rate: "half", wantErr: true},
+		{name: "unknown sampling", sampling: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := TracingSettings{Endpoint: tt.endpoint, ServiceName: "user-service", SampleRate: "1", Sampling: "head"}
+			if tt.rate != "" {
+				settings.SampleRate = tt.rate
+			}
+			if tt.sampling != "" {
+				settings.Sampling = tt.sampling
+			}
+
+			config, err := loadTracingConfig(settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadTracingConfig() error got %v want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.SampleRate != tt.want {
+				t.Errorf("SampleRate got %v want %v", config.SampleRate, tt.want)
+			}
+			if config.Sampling != tt.wantMode {
+				t.Errorf("Sampling got %v want %v", config.Sampling, tt.wantMode)
+			}
+			if config.Enabled() != tt.enabled {
+				t.Errorf("Enabled() got %v want %v", config.Enabled(), tt.enabled)
+			}
+			if config.ServiceName != "user-service" {
+				t.Errorf("ServiceName got %q want %q", config.ServiceName, "user-service")
+			}
+		})
+	}
+}
+
+func TestSpanRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/", want: "/"},
+		{path: "/users", want: "/users"},
+		{path: "/users/123/roles", want: "/users"},
+		{path: "/admin/seed", want: "/admin"},
+	}
+
+	for _, tt := range tests {
+		if got := spanRoute(tt.path); got != tt.want {
+			t.Errorf("spanRoute(%q) got %q want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTracing_HTTPRequestSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	service := NewInMemoryUserService()
+	users, _ := service.GetUsers()
+	handler := tracingMiddleware(NewUserHandler(service))
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus codes.Code
+	}{
+		{name: "existing user", id: users[0].ID, wantStatus: codes.Unset},
+		{name: "missing user", id: "missing", wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.Reset()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+tt.id, nil))
+
+			spans := recorder.Ended()
+			server := findSpan(spans, "GET /users")
+			if server == nil {
+				t.Fatalf("no server span in %d spans", len(spans))
+			}
+			call := findSpan(spans, "UserService.GetUserByID")
+			if call == nil {
+				t.Fatal("no UserService.GetUserByID span")
+			}
+			if call.Parent().SpanID() != server.SpanContext().SpanID() {
+				t.Error("service span is not a child of the server span")
+			}
+			if call.Status().Code != tt.wantStatus {
+				t.Errorf("service span status got %v want %v", call.Status().Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestTracingSessionStore(t *testing.T) {
+	recorder := recordSpans(t)
+	store := TraceSessionStore(NewMemorySessionStore())
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "unknown"); err != ErrSessionNotFound {
+		t.Fatalf("Get(unknown) error got %v want %v", err, ErrSessionNotFound)
+	}
+
+	span := findSpan(recorder.Ended(), "SessionStore.Get")
+	if span == nil {
+		t.Fatal("no SessionStore.Get span")
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("a missing session marked the span as failed")
+	}
+}
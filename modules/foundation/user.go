@@ -1,102 +1,214 @@
 package main
 
 import (
+	"encoding/xml"
+	"fmt"
+	"slices"
 	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
 )
 
 // User represents a user entity in our system
 type User struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	XMLName   xml.Name   `json:"-" xml:"user"`
+	ID        string     `json:"id" xml:"id"`
+	Name      string     `json:"name" xml:"name"`
+	Email     string     `json:"email" xml:"email"`
+	AvatarURL string     `json:"avatar_url,omitempty" xml:"avatar_url,omitempty"`
+	Roles     []Role     `json:"roles" xml:"roles>role"`
+	Status    UserStatus `json:"status" xml:"status"`
+	Version   int64      `json:"version" xml:"version"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" xml:"updated_at"`
+
+	// PasswordHash is the argon2id hash of the password. It is never rendered.
+	PasswordHash string `json:"-" xml:"-"`
+}
+
+// UserList is a collection of users. It renders as a <users> element in XML.
+type UserList []User
+
+// MarshalXML wraps the users in a <users> root element
+func (l UserList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	users := struct {
+		Users []User `xml:"user"`
+	}{Users: l}
+	return e.EncodeElement(users, xml.StartElement{Name: xml.Name{Local: "users"}})
 }
 
 // UserService defines the interface for user operations
 type UserService interface {
-	// GetUsers returns all users
+	// GetUsers returns all users, oldest first
 	GetUsers() ([]User, error)
 
 	// GetUserByID returns a user by their ID
 	GetUserByID(id string) (*User, error)
 
+	// LastModified returns when a user was last created, changed or removed
+	LastModified() time.Time
+
+	// GetUsersByIDs returns the users with the given IDs, in the order of ids,
+	// and the IDs that do not exist
+	GetUsersByIDs(ids []string) ([]User, []string, error)
+
+	// GetUserByEmail returns the user with exactly the given email
+	GetUserByEmail(email string) (*User, error)
+
 	// CreateUser creates a new user
 	CreateUser(name, email string) (*User, error)
 
-	// UpdateUser updates an existing user
-	UpdateUser(id, name, email string) (*User, error)
+	// RegisterUser creates a new user who signs in with password
+	RegisterUser(name, email, password string) (*User, error)
+
+	// Authenticate returns the user with email when password is theirs
+	Authenticate(email, password string) (*User, error)
+
+	// ChangePassword replaces the password of a user after checking the
+	// current one. A non-zero expectedVersion makes the change conditional on
+	// the stored version matching it.
+	ChangePassword(id, currentPassword, newPassword string, expectedVersion int64) (*User, error)
+
+	// UpdateUser updates an existing user, keeping the name or email given
+	// empty, and returns it unchanged when the update changes nothing. A
+	// non-zero expectedVersion makes the update conditional on the stored
+	// version matching it.
+	UpdateUser(id, name, email string, expectedVersion int64) (*User, error)
 
-	// DeleteUser deletes a user by ID
-	DeleteUser(id string) error
+	// DeleteUser deletes a user by ID. A non-zero expectedVersion makes the
+	// delete conditional on the stored version matching it.
+	DeleteUser(id string, expectedVersion int64) error
+
+	// AssignRoles replaces the roles of a user. A non-zero expectedVersion
+	// makes the change conditional on the stored version matching it.
+	AssignRoles(id string, roles []Role, expectedVersion int64) (*User, error)
+
+	// ChangeStatus moves a user to the active or suspended status. A non-zero
+	// expectedVersion makes the change conditional on the stored version matching it.
+	ChangeStatus(id string, status UserStatus, expectedVersion int64) (*User, error)
+
+	// SetAvatar replaces the URL of the avatar of a user. A non-zero
+	// expectedVersion makes the change conditional on the stored version matching it.
+	SetAvatar(id, avatarURL string, expectedVersion int64) (*User, error)
 }
 
-// NewUser creates a new User instance with generated ID and timestamps
-func NewUser(name, email string) *User {
+// NewUser creates a new pending User instance with an ID from ids and timestamps
+func NewUser(ids uuid.IDGenerator, name, email string) *User {
 	now := time.Now()
 	return &User{
-		ID:        generateID(),
+		ID:        ids.NewID(),
 		Name:      name,
 		Email:     email,
+		Roles:     []Role{RoleViewer},
+		Status:    UserStatusPending,
+		Version:   1,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 }
 
-// Update updates the user's fields and timestamp
-func (u *User) Update(name, email string) {
-	// Create a temporary user to validate new values
-	temp := &User{Name: name, Email: email}
-	if err := temp.Validate(); err != nil {
-		return // or return the error
-	}
+// Update replaces the user's name and email, keeping those given empty.
+// The result is validated with opts: when it is invalid, the user is left
+// unchanged and the validation errors are returned. The version and the
+// timestamp only move when a field changes.
+func (u *User) Update(name, email string, opts ...ValidateOption) error {
+	updated := User{Name: u.Name, Email: u.Email}
 	if name != "" {
-		u.Name = name
+		updated.Name = name
 	}
 	if email != "" {
-		u.Email = email
+		updated.Email = email
+	}
+	if err := updated.Validate(opts...); err != nil {
+		return err
+	}
+	if updated.Name == u.Name && updated.Email == u.Email {
+		return nil
 	}
+
+	u.Name = updated.Name
+	u.Email = updated.Email
+	u.Version++
 	u.UpdatedAt = time.Now()
+	return nil
 }
 
-// Validate checks if the user has valid data
-func (u *User) Validate() error {
-	if u.Name == "" {
-		return NewValidationError("name", "name cannot be empty")
+// AssignRoles replaces the user's roles after validating them
+func (u *User) AssignRoles(roles []Role) error {
+	var errs ValidationErrors
+	if len(roles) == 0 {
+		errs.Add("roles", "at least one role is required")
 	}
-	if u.Email == "" {
-		return NewValidationError("email", "email cannot be empty")
+	for i, role := range roles {
+		if !role.IsValid() {
+			errs.Add(fmt.Sprintf("roles[%d]", i), fmt.Sprintf("unknown role '%s'", role))
+		}
 	}
-	// Simple email validation
-	if !isValidEmail(u.Email) {
-		return NewValidationError("email", "email format is invalid")
+	if err := errs.Err(); err != nil {
+		return err
 	}
+
+	u.Roles = slices.Clone(roles)
+	u.Version++
+	u.UpdatedAt = time.Now()
 	return nil
 }
 
-// isValidEmail performs basic email validation
-func isValidEmail(email string) bool {
-	// Simple validation - contains @ and at least one dot after @
-	atIndex := -1
-	for i, char := range email {
-		if char == '@' {
-			if atIndex != -1 {
-				return false // Multiple @ symbols
-			}
-			atIndex = i
-		}
-	}
+// SetAvatar replaces the URL of the user's avatar
+func (u *User) SetAvatar(avatarURL string) {
+	u.AvatarURL = avatarURL
+	u.Version++
+	u.UpdatedAt = time.Now()
+}
+
+// setPasswordHash replaces the password with an already computed hash
+func (u *User) setPasswordHash(hash string) {
+	u.PasswordHash = hash
+	u.Version++
+	u.UpdatedAt = time.Now()
+}
+
+// CheckPassword reports whether password is the user's password. Users
+// without a password never match.
+func (u *User) CheckPassword(password string) bool {
+	return u.PasswordHash != "" && verifyPassword(u.PasswordHash, password)
+}
 
-	if atIndex == -1 || atIndex == 0 || atIndex == len(email)-1 {
-		return false
+// ETag returns the entity tag identifying the current version of the user
+func (u *User) ETag() string {
+	return fmt.Sprintf(`"%s-%d"`, u.ID, u.Version)
+}
+
+// ValidateOption configures the validation of a user
+type ValidateOption func(*validateOptions)
+
+// validateOptions are the rules a user is validated with
+type validateOptions struct {
+	email EmailStrictness
+}
+
+// WithEmailStrictness validates the email at strictness instead of EmailStandard
+func WithEmailStrictness(strictness EmailStrictness) ValidateOption {
+	return func(o *validateOptions) {
+		o.email = strictness
 	}
+}
 
-	// Check for dot after @
-	for i := atIndex + 1; i < len(email); i++ {
-		if email[i] == '.' && i < len(email)-1 {
-			return true
-		}
+// Validate checks if the user has valid data and reports every invalid field
+func (u *User) Validate(opts ...ValidateOption) error {
+	options := validateOptions{email: defaultEmailStrictness}
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	return false
+	var errs ValidationErrors
+	if u.Name == "" {
+		errs.Add("name", "name cannot be empty")
+	}
+	if u.Email == "" {
+		errs.Add("email", "email cannot be empty")
+	} else if !isValidEmail(u.Email, options.email) {
+		errs.Add("email", "email format is invalid")
+	}
+	return errs.Err()
 }
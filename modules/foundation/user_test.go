@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// UserServiceContract is the conformance suite of UserService: every
+// implementation must pass it. newService returns a fresh service, which may
+// already hold users of its own. Run it from a test of the implementation:
+//
+//	func TestMyUserService(t *testing.T) {
+//		UserServiceContract(t, func(t *testing.T) UserService { return NewMyUserService(t) })
+//	}
+func UserServiceContract(t *testing.T, newService func(t *testing.T) UserService) {
+	t.Helper()
+	tests := []struct {
+		name string
+		test func(t *testing.T, service UserService)
+	}{
+		{"create and read", testCreateAndRead},
+		{"create invalid", testCreateInvalid},
+		{"missing users", testMissingUsers},
+		{"users in order", testUsersInOrder},
+		{"update", testUpdate},
+		{"delete", testDelete},
+		{"roles", testRoles},
+		{"status", testStatus},
+		{"avatar", testAvatar},
+		{"passwords", testPasswords},
+		{"expected version", testExpectedVersion},
+		{"copies", testCopies},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newService(t))
+		})
+	}
+}
+
+func TestUserServiceContract(t *testing.T) {
+	implementations := []struct {
+		name       string
+		newService func(t *testing.T) UserService
+	}{
+		{"in memory", func(*testing.T) UserService {
+			return NewInMemoryUserService(WithEventPublisher(NewEventBus()))
+		}},
+		{"request scoped", func(*testing.T) UserService {
+			return NewInMemoryUserService().WithContext(context.Background())
+		}},
+		{"traced", func(*testing.T) UserService {
+			return TraceUserService(context.Background(), NewInMemoryUserService())
+		}},
+		{"tenant registry", func(*testing.T) UserService {
+			registry := NewTenantRegistry(AllowTenants("acme"), func(tenant string) *InMemoryUserService {
+				return NewInMemoryUserService(WithTenant(tenant))
+			})
+			return registry.ServiceFor(ContextWithTenant(context.Background(), "acme"))
+		}},
+	}
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			UserServiceContract(t, impl.newService)
+		})
+	}
+}
+
+// wantErrorType fails the test unless err is an application error of type
+func wantErrorType(t *testing.T, err error, want ErrorType) {
+	t.Helper()
+	appErr, ok := IsAppError(err)
+	if !ok || appErr.Type != want {
+		t.Fatalf("got error %v want %s", err, want)
+	}
+}
+
+// mustCreate creates a user or fails the test
+func mustCreate(t *testing.T, service UserService, name, email string) *User {
+	t.Helper()
+	user, err := service.CreateUser(name, email)
+	if err != nil {
+		t.Fatalf("CreateUser(%q, %q) error = %v", name, email, err)
+	}
+	return user
+}
+
+func testCreateAndRead(t *testing.T, service UserService) {
+	before := service.LastModified()
+	user := mustCreate(t, service, "Ada Lovelace", "ada@example.com")
+	if user.ID == "" || user.Version != 1 || user.Status != UserStatusPending || len(user.Roles) != 1 || user.Roles[0] != RoleViewer {
+		t.Errorf("got %+v want a pending viewer at version 1 with an ID", user)
+	}
+	if service.LastModified().Before(before) {
+		t.Errorf("got LastModified %v want at least %v", service.LastModified(), before)
+	}
+
+	byID, err := service.GetUserByID(user.ID)
+	if err != nil || byID.Email != "ada@example.com" || byID.Version != 1 {
+		t.Errorf("GetUserByID() = %+v, %v want the created user", byID, err)
+	}
+	byEmail, err := service.GetUserByEmail("ada@example.com")
+	if err != nil || byEmail.ID != user.ID {
+		t.Errorf("GetUserByEmail() = %+v, %v want the created user", byEmail, err)
+	}
+
+	_, err = service.CreateUser("Another Ada", "ada@example.com")
+	wantErrorType(t, err, ErrorTypeConflict)
+}
+
+func testCreateInvalid(t *testing.T, service UserService) {
+	_, err := service.CreateUser("", "not an email")
+	wantErrorType(t, err, ErrorTypeValidation)
+	if appErr, _ := IsAppError(err); len(appErr.Errors) != 2 {
+		t.Errorf("got field errors %v want both the name and the email", appErr.Errors)
+	}
+	_, err = service.RegisterUser("Ada", "ada@example.com", "short")
+	wantErrorType(t, err, ErrorTypeValidation)
+	if _, err := service.GetUserByEmail("ada@example.com"); err == nil {
+		t.Error("got the user of an invalid registration stored")
+	}
+}
+
+func testMissingUsers(t *testing.T, service UserService) {
+	_, err := service.GetUserByID("missing")
+	wantErrorType(t, err, ErrorTypeNotFound)
+	_, err = service.GetUserByEmail("missing@example.com")
+	wantErrorType(t, err, ErrorTypeNotFound)
+	_, err = service.UpdateUser("missing", "Ada", "ada@example.com", 0)
+	wantErrorType(t, err, ErrorTypeNotFound)
+	wantErrorType(t, service.DeleteUser("missing", 0), ErrorTypeNotFound)
+	_, err = service.SetAvatar("missing", "/avatars/missing", 0)
+	wantErrorType(t, err, ErrorTypeNotFound)
+
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	users, missing, err := service.GetUsersByIDs([]string{"missing", user.ID, "gone"})
+	if err != nil || len(users) != 1 || users[0].ID != user.ID || len(missing) != 2 || missing[0] != "missing" || missing[1] != "gone" {
+		t.Errorf("GetUsersByIDs() = %v, %v, %v want the user and both missing IDs in order", users, missing, err)
+	}
+}
+
+func testUsersInOrder(t *testing.T, service UserService) {
+	first := mustCreate(t, service, "First", "first@example.com")
+	time.Sleep(time.Millisecond)
+	second := mustCreate(t, service, "Second", "second@example.com")
+
+	users, err := service.GetUsers()
+	if err != nil {
+		t.Fatalf("GetUsers() error = %v", err)
+	}
+	positions := make(map[string]int)
+	for i, user := range users {
+		positions[user.ID] = i
+	}
+	firstAt, okFirst := positions[first.ID]
+	secondAt, okSecond := positions[second.ID]
+	if !okFirst || !okSecond || firstAt > secondAt {
+		t.Errorf("got positions %d and %d want both users, oldest first", firstAt, secondAt)
+	}
+	for i := 1; i < len(users); i++ {
+		if users[i].CreatedAt.Before(users[i-1].CreatedAt) {
+			t.Errorf("got %s created before %s want oldest first", users[i].ID, users[i-1].ID)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	mustCreate(t, service, "Grace", "grace@example.com")
+
+	updated, err := service.UpdateUser(user.ID, "Ada Lovelace", "ada@example.com", 0)
+	if err != nil || updated.Name != "Ada Lovelace" || updated.Email != "ada@example.com" || updated.Version != 2 {
+		t.Fatalf("UpdateUser() = %+v, %v want the new name, the same email and version 2", updated, err)
+	}
+
+	_, err = service.UpdateUser(user.ID, "Ada Lovelace", "grace@example.com", 0)
+	wantErrorType(t, err, ErrorTypeConflict)
+
+	if _, err := service.UpdateUser(user.ID, "Ada Lovelace", "lovelace@example.com", 0); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if _, err := service.GetUserByEmail("ada@example.com"); err == nil {
+		t.Error("got the user by its previous email want not found")
+	}
+	if byEmail, err := service.GetUserByEmail("lovelace@example.com"); err != nil || byEmail.ID != user.ID {
+		t.Errorf("GetUserByEmail() = %+v, %v want the user by its new email", byEmail, err)
+	}
+	mustCreate(t, service, "Another Ada", "ada@example.com")
+
+	// Empty fields keep their value
+	renamed, err := service.UpdateUser(user.ID, "Ada King", "", 0)
+	if err != nil || renamed.Name != "Ada King" || renamed.Email != "lovelace@example.com" || renamed.Version != 4 {
+		t.Fatalf("UpdateUser() = %+v, %v want the new name, the same email and version 4", renamed, err)
+	}
+	if byEmail, err := service.GetUserByEmail("lovelace@example.com"); err != nil || byEmail.ID != user.ID {
+		t.Errorf("GetUserByEmail() = %+v, %v want the user after a rename", byEmail, err)
+	}
+
+	// Invalid values fail and change nothing
+	_, err = service.UpdateUser(user.ID, "", "not an email", 0)
+	wantErrorType(t, err, ErrorTypeValidation)
+	if got, _ := service.GetUserByID(user.ID); got.Email != "lovelace@example.com" || got.Version != 4 {
+		t.Errorf("got %+v want the user unchanged at version 4", got)
+	}
+
+	// An update changing nothing keeps the version and the timestamps
+	unchanged, err := service.UpdateUser(user.ID, "Ada King", "lovelace@example.com", 4)
+	if err != nil || unchanged.Version != 4 || !unchanged.UpdatedAt.Equal(renamed.UpdatedAt) {
+		t.Errorf("UpdateUser() = %+v, %v want the user at version 4 updated at %v", unchanged, err, renamed.UpdatedAt)
+	}
+}
+
+func testDelete(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	if err := service.DeleteUser(user.ID, 0); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	_, err := service.GetUserByID(user.ID)
+	wantErrorType(t, err, ErrorTypeNotFound)
+	wantErrorType(t, service.DeleteUser(user.ID, 0), ErrorTypeNotFound)
+
+	// The email of a deleted user is free again
+	mustCreate(t, service, "Another Ada", "ada@example.com")
+}
+
+func testRoles(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	updated, err := service.AssignRoles(user.ID, []Role{RoleEditor, RoleViewer}, 0)
+	if err != nil || len(updated.Roles) != 2 || updated.Roles[0] != RoleEditor || updated.Version != 2 {
+		t.Errorf("AssignRoles() = %+v, %v want both roles at version 2", updated, err)
+	}
+	_, err = service.AssignRoles(user.ID, nil, 0)
+	wantErrorType(t, err, ErrorTypeValidation)
+	_, err = service.AssignRoles(user.ID, []Role{"owner"}, 0)
+	wantErrorType(t, err, ErrorTypeValidation)
+}
+
+func testStatus(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	_, err := service.ChangeStatus(user.ID, UserStatusSuspended, 0)
+	wantErrorType(t, err, ErrorTypeConflict)
+	_, err = service.ChangeStatus(user.ID, UserStatusDeleted, 0)
+	wantErrorType(t, err, ErrorTypeValidation)
+
+	for _, status := range []UserStatus{UserStatusActive, UserStatusSuspended, UserStatusActive} {
+		updated, err := service.ChangeStatus(user.ID, status, 0)
+		if err != nil || updated.Status != status {
+			t.Fatalf("ChangeStatus(%s) = %+v, %v", status, updated, err)
+		}
+	}
+	if stored, _ := service.GetUserByID(user.ID); stored.Status != UserStatusActive || stored.Version != 4 {
+		t.Errorf("got %s at version %d want active at version 4", stored.Status, stored.Version)
+	}
+}
+
+func testAvatar(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	updated, err := service.SetAvatar(user.ID, "/avatars/ada.png", 0)
+	if err != nil || updated.AvatarURL != "/avatars/ada.png" || updated.Version != 2 {
+		t.Fatalf("SetAvatar() = %+v, %v want the avatar at version 2", updated, err)
+	}
+	cleared, err := service.SetAvatar(user.ID, "", 0)
+	if err != nil || cleared.AvatarURL != "" || cleared.Version != 3 {
+		t.Errorf("SetAvatar(\"\") = %+v, %v want no avatar at version 3", cleared, err)
+	}
+}
+
+func testPasswords(t *testing.T, service UserService) {
+	user, err := service.RegisterUser("Ada", "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	if user.PasswordHash == "correct horse" {
+		t.Error("got the password stored in clear")
+	}
+	if _, err := service.Authenticate("ada@example.com", "correct horse"); err != nil {
+		t.Errorf("Authenticate() error = %v", err)
+	}
+	_, err = service.Authenticate("ada@example.com", "wrong password")
+	wantErrorType(t, err, ErrorTypeUnauthorized)
+	_, err = service.Authenticate("nobody@example.com", "correct horse")
+	wantErrorType(t, err, ErrorTypeUnauthorized)
+
+	_, err = service.ChangePassword(user.ID, "wrong password", "battery staple", 0)
+	wantErrorType(t, err, ErrorTypeUnauthorized)
+	changed, err := service.ChangePassword(user.ID, "correct horse", "battery staple", 0)
+	if err != nil || changed.Version != 2 {
+		t.Fatalf("ChangePassword() = %+v, %v want version 2", changed, err)
+	}
+	_, err = service.Authenticate("ada@example.com", "correct horse")
+	wantErrorType(t, err, ErrorTypeUnauthorized)
+
+	// Users without password never authenticate
+	mustCreate(t, service, "Grace", "grace@example.com")
+	_, err = service.Authenticate("grace@example.com", "")
+	wantErrorType(t, err, ErrorTypeUnauthorized)
+
+	if _, err := service.ChangeStatus(user.ID, UserStatusActive, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ChangeStatus(user.ID, UserStatusSuspended, 0); err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.Authenticate("ada@example.com", "battery staple")
+	wantErrorType(t, err, ErrorTypeForbidden)
+}
+
+func testExpectedVersion(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	stale := user.Version
+	if _, err := service.UpdateUser(user.ID, "Ada Lovelace", "ada@example.com", stale); err != nil {
+		t.Fatalf("UpdateUser() at the current version error = %v", err)
+	}
+
+	_, err := service.UpdateUser(user.ID, "Ada King", "ada@example.com", stale)
+	wantErrorType(t, err, ErrorTypePreconditionFailed)
+	_, err = service.AssignRoles(user.ID, []Role{RoleEditor}, stale)
+	wantErrorType(t, err, ErrorTypePreconditionFailed)
+	_, err = service.ChangeStatus(user.ID, UserStatusActive, stale)
+	wantErrorType(t, err, ErrorTypePreconditionFailed)
+	_, err = service.SetAvatar(user.ID, "/avatars/ada.png", stale)
+	wantErrorType(t, err, ErrorTypePreconditionFailed)
+	wantErrorType(t, service.DeleteUser(user.ID, stale), ErrorTypePreconditionFailed)
+
+	if stored, _ := service.GetUserByID(user.ID); stored.Name != "Ada Lovelace" || stored.Version != 2 {
+		t.Errorf("got %+v want the user unchanged by the rejected changes", stored)
+	}
+	if err := service.DeleteUser(user.ID, 2); err != nil {
+		t.Errorf("DeleteUser() at the current version error = %v", err)
+	}
+}
+
+func testCopies(t *testing.T, service UserService) {
+	user := mustCreate(t, service, "Ada", "ada@example.com")
+	user.Name = "changed"
+	if fetched, _ := service.GetUserByID(user.ID); fetched.Name != "Ada" {
+		t.Errorf("got name %q want the stored user unchanged by the created copy", fetched.Name)
+	}
+	fetched, _ := service.GetUserByID(user.ID)
+	fetched.Email = "changed@example.com"
+	if _, err := service.GetUserByEmail("ada@example.com"); err != nil {
+		t.Errorf("got error %v want the stored user unchanged by the fetched copy", err)
+	}
+	if again, _ := service.GetUserByID(user.ID); again.Email != "ada@example.com" {
+		t.Errorf("got email %q want the stored user unchanged by the fetched copy", again.Email)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// configProblems collects the problems of a configuration, each naming the
+// setting by its file key and environment variable
+type configProblems []error
+
+// add records a problem of the setting key, set by env
+func (p *configProblems) add(key, env, format string, args ...interface{}) {
+	*p = append(*p, fmt.Errorf("%s (%s): %s", key, env, fmt.Sprintf(format, args...)))
+}
+
+// check records err, the error of a loader, if any
+func (p *configProblems) check(err error) {
+	if err != nil {
+		*p = append(*p, err)
+	}
+}
+
+// port checks that value is a TCP port, or empty when optional
+func (p *configProblems) port(key, env, value string, optional bool) {
+	if value == "" && optional {
+		return
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		p.add(key, env, "must be a port between 1 and 65535, got %q", value)
+	}
+}
+
+// url checks that value is an absolute URL with one of schemes, unless empty
+func (p *configProblems) url(key, env, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		p.add(key, env, "must be an absolute URL, got %q", value)
+		return
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return
+		}
+	}
+	p.add(key, env, "must be a %s URL, got %q", schemes[0], value)
+}
+
+// positive checks that d is above zero
+func (p *configProblems) positive(key, env string, d time.Duration) {
+	if d <= 0 {
+		p.add(key, env, "must be a positive duration, got %v", d)
+	}
+}
+
+// nonNegative checks that d is zero or above
+func (p *configProblems) nonNegative(key, env string, d time.Duration) {
+	if d < 0 {
+		p.add(key, env, "must not be negative, got %v", d)
+	}
+}
+
+// oneOf checks that value is one of values
+func (p *configProblems) oneOf(key, env, value string, values ...string) {
+	for _, v := range values {
+		if value == v {
+			return
+		}
+	}
+	p.add(key, env, "must be one of %v, got %q", values, value)
+}
+
+// Validate checks the resolved configuration as a whole, including the
+// settings required by the selected backends, and returns every problem
+// found, joined, so they can all be fixed before the next start
+func (c Config) Validate() error {
+	var p configProblems
+
+	// Server
+	if c.Server.Host == "" {
+		p.add("server.host", "HOST", "must not be empty")
+	}
+	p.port("server.port", "PORT", c.Server.Port, false)
+	p.port("server.grpc_port", "GRPC_PORT", c.Server.GRPCPort, false)
+	if c.Server.Port != "" && c.Server.Port == c.Server.GRPCPort {
+		p.add("server.grpc_port", "GRPC_PORT", "must differ from server.port, both are %s", c.Server.Port)
+	}
+	p.nonNegative("server.shutdown_drain_delay", "SHUTDOWN_DRAIN_DELAY", c.Server.ShutdownDrainDelay)
+	_, err := loadRouteTimeouts(c.Server.RequestTimeout, c.Server.RouteTimeouts)
+	p.check(err)
+	_, err = loadAllowedTenants(c.Server.Tenants)
+	p.check(err)
+	if c.Server.MaxBodyBytes <= 0 {
+		p.add("server.max_body_bytes", "MAX_BODY_BYTES", "must be a positive number of bytes, got %d", c.Server.MaxBodyBytes)
+	}
+	p.positive("server.idempotency_ttl", "IDEMPOTENCY_TTL", c.Server.IdempotencyTTL)
+	_, err = loadErrorFormat(c.Server.ErrorFormat)
+	p.check(err)
+
+	// TLS
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		p.add("tls.key_file", "TLS_KEY_FILE", "tls.cert_file and tls.key_file must be set together")
+	}
+	p.port("tls.redirect_port", "TLS_REDIRECT_PORT", c.TLS.RedirectPort, true)
+	if c.TLS.RedirectPort != "" && !c.TLS.Enabled() {
+		p.add("tls.redirect_port", "TLS_REDIRECT_PORT", "requires TLS, with tls.cert_file and tls.key_file or tls.self_signed")
+	}
+
+	// Logs
+	p.oneOf("log.format", "LOG_FORMAT", strings.ToLower(c.Log.Format), string(logging.FormatText), string(logging.FormatJSON))
+	p.oneOf("log.access_format", "ACCESS_LOG_FORMAT", c.Log.AccessFormat, accessLogText, accessLogJSON)
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		p.add("log.level", "LOG_LEVEL", "%v", err)
+	}
+
+	// Observability
+	_, err = loadTracingConfig(c.Tracing)
+	p.check(err)
+	p.url("tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", c.Tracing.Endpoint, "http", "https")
+	_, err = loadSLOTracker(c.SLO)
+	p.check(err)
+	p.url("sentry.dsn", "SENTRY_DSN", c.Sentry.DSN, "https", "http")
+
+	// IDs
+	_, err = loadIDGenerators(c.IDs)
+	p.check(err)
+
+	// Users
+	_, err = loadEmailStrictness(c.Users)
+	p.check(err)
+	_, err = loadUserFixtures(c.Users)
+	p.check(err)
+
+	// Authentication
+	_, err = loadJWTConfig(c.Auth)
+	p.check(err)
+	p.nonNegative("auth.clock_skew", "JWT_CLOCK_SKEW", c.Auth.ClockSkew)
+	p.positive("auth.login_token_ttl", "LOGIN_TOKEN_TTL", c.Auth.LoginTokenTTL)
+	oidc, err := loadOIDCConfig(c.OIDC)
+	p.check(err)
+	p.url("oidc.issuer_url", "OIDC_ISSUER_URL", c.OIDC.IssuerURL, "https", "http")
+	p.url("oidc.redirect_url", "OIDC_REDIRECT_URL", c.OIDC.RedirectURL, "https", "http")
+	if (c.OIDC.IssuerURL == "") != (c.OIDC.ClientID == "") {
+		p.add("oidc.client_id", "OIDC_CLIENT_ID", "oidc.issuer_url and oidc.client_id must be set together")
+	}
+	if oidc.Enabled() && c.Auth.HS256Secret == "" {
+		p.add("auth.hs256_secret", "JWT_HS256_SECRET", "is required with OIDC, to sign local tokens")
+	}
+
+	// Sessions
+	p.oneOf("session.store", "SESSION_STORE", c.Session.Store, "memory", "redis")
+	if c.Session.Store == "redis" {
+		if _, err := redis.ParseURL(c.Session.RedisURL); err != nil {
+			p.add("session.redis_url", "REDIS_URL", "%v", err)
+		}
+	}
+	p.positive("session.ttl", "SESSION_TTL", c.Session.TTL)
+
+	// Avatars
+	_, err = loadBlobStore(c.Avatars)
+	p.check(err)
+	if c.Avatars.MaxBytes <= 0 {
+		p.add("avatars.max_bytes", "AVATAR_MAX_BYTES", "must be a positive number of bytes, got %d", c.Avatars.MaxBytes)
+	} else if c.Avatars.MaxBytes >= c.Server.MaxBodyBytes {
+		p.add("avatars.max_bytes", "AVATAR_MAX_BYTES", "must be below server.max_body_bytes (%d), which also counts the multipart encoding", c.Server.MaxBodyBytes)
+	}
+	p.url("avatars.public_url", "AVATAR_PUBLIC_URL", c.Avatars.PublicURL, "https", "http")
+	p.url("avatars.s3_endpoint", "AVATAR_S3_ENDPOINT", c.Avatars.S3Endpoint, "https", "http")
+
+	// Chaos
+	_, err = loadChaos(c.Chaos)
+	p.check(err)
+
+	p = append(p, c.Secrets.problems()...)
+	return errors.Join(p...)
+}
+
+// problems returns the problems of the secrets settings, checked before the
+// secrets are fetched
+func (s SecretsSettings) problems() configProblems {
+	var p configProblems
+	p.oneOf("secrets.provider", "SECRETS_PROVIDER", s.Provider, "env", "vault")
+	if s.Provider == "vault" {
+		if s.VaultAddress == "" {
+			p.add("secrets.vault_address", "VAULT_ADDR", "is required with the vault provider")
+		}
+		p.url("secrets.vault_address", "VAULT_ADDR", s.VaultAddress, "https", "http")
+		if s.VaultToken == "" {
+			p.add("secrets.vault_token", "VAULT_TOKEN", "is required with the vault provider")
+		}
+	}
+	return p
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the default configuration
+func validConfig(t *testing.T) Config {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	cfg, _, err := parseConfig(nil, nil)
+	if err != nil {
+		t.Fatalf("parseConfig() error: %v", err)
+	}
+	return cfg
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   []string
+	}{
+		{"defaults", func(cfg *Config) {}, nil},
+		{"ports", func(cfg *Config) {
+			cfg.Server.Port = "80a"
+			cfg.Server.GRPCPort = "70000"
+			cfg.TLS.RedirectPort = "8081"
+		}, []string{
+			`server.port (PORT): must be a port between 1 and 65535, got "80a"`,
+			`server.grpc_port (GRPC_PORT): must be a port between 1 and 65535, got "70000"`,
+			"tls.redirect_port (TLS_REDIRECT_PORT): requires TLS",
+		}},
+		{"same ports", func(cfg *Config) { cfg.Server.GRPCPort = cfg.Server.Port }, []string{"server.grpc_port (GRPC_PORT): must differ from server.port"}},
+		{"durations", func(cfg *Config) {
+			cfg.Server.ShutdownDrainDelay = -time.Second
+			cfg.Session.TTL = 0
+			cfg.Auth.LoginTokenTTL = -time.Hour
+		}, []string{
+			"server.shutdown_drain_delay (SHUTDOWN_DRAIN_DELAY): must not be negative, got -1s",
+			"session.ttl (SESSION_TTL): must be a positive duration, got 0s",
+			"auth.login_token_ttl (LOGIN_TOKEN_TTL): must be a positive duration, got -1h0m0s",
+		}},
+		{"urls", func(cfg *Config) {
+			cfg.Tracing.Endpoint = "localhost:4318"
+			cfg.Sentry.DSN = "ftp://key@sentry.example.com/1"
+		}, []string{
+			`tracing.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT): must be an absolute URL, got "localhost:4318"`,
+			`sentry.dsn (SENTRY_DSN): must be a https URL`,
+		}},
+		{"enumerations", func(cfg *Config) {
+			cfg.Log.Format = "xml"
+			cfg.Log.Level = "verbose"
+			cfg.Session.Store = "memcached"
+			cfg.Server.ErrorFormat = "html"
+		}, []string{
+			`log.format (LOG_FORMAT): must be one of [text json], got "xml"`,
+			`log.level (LOG_LEVEL): unknown log level "verbose"`,
+			`session.store (SESSION_STORE): must be one of [memory redis], got "memcached"`,
+			"ERROR_FORMAT",
+		}},
+		{"loaders", func(cfg *Config) {
+			cfg.Server.RouteTimeouts = []string{"users=2s"}
+			cfg.SLO.Target = "fast"
+			cfg.IDs.Scheme = "serial"
+		}, []string{"ROUTE_TIMEOUTS", "SLO_TARGET", "serial"}},
+		{"oidc backend", func(cfg *Config) {
+			cfg.OIDC.IssuerURL = "https://accounts.example.com"
+			cfg.OIDC.ClientID = "user-service"
+		}, []string{
+			"OIDC_REDIRECT_URL is required",
+			"auth.hs256_secret (JWT_HS256_SECRET): is required with OIDC",
+		}},
+		{"redis backend", func(cfg *Config) {
+			cfg.Session.Store = "redis"
+			cfg.Session.RedisURL = "localhost:6379"
+		}, []string{"session.redis_url (REDIS_URL)"}},
+		{"avatars", func(cfg *Config) {
+			cfg.Avatars.MaxBytes = cfg.Server.MaxBodyBytes
+			cfg.Avatars.PublicURL = "cdn.example.com"
+		}, []string{
+			"avatars.max_bytes (AVATAR_MAX_BYTES): must be below server.max_body_bytes (1048576)",
+			`avatars.public_url (AVATAR_PUBLIC_URL): must be an absolute URL, got "cdn.example.com"`,
+		}},
+		{"email validation", func(cfg *Config) { cfg.Users.EmailValidation = "lenient" }, []string{`EMAIL_VALIDATION must be one of rfc, standard or strict, got "lenient"`}},
+		{"fixtures", func(cfg *Config) { cfg.Users.Fixtures = "staging" }, []string{`FIXTURES must be one of default, demo, empty, got "staging"`}},
+		{"s3 backend", func(cfg *Config) { cfg.Avatars.Store = "s3" }, []string{"AVATAR_S3_BUCKET and AVATAR_S3_REGION are required"}},
+		{"chaos", func(cfg *Config) {
+			cfg.Chaos.Enabled = true
+			cfg.Chaos.ErrorRate = 2
+		}, []string{"CHAOS_*: chaos: error rate must be between 0 and 1, got 2"}},
+		{"vault backend", func(cfg *Config) {
+			cfg.Secrets.Provider = "vault"
+			cfg.Secrets.VaultAddress = ""
+		}, []string{
+			"secrets.vault_address (VAULT_ADDR): is required with the vault provider",
+			"secrets.vault_token (VAULT_TOKEN): is required with the vault provider",
+		}},
+		{"tls files", func(cfg *Config) { cfg.TLS.CertFile = "cert.pem" }, []string{"tls.cert_file and tls.key_file must be set together"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() got %v want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() got no error want %v", tt.want)
+			}
+			// Every problem is reported, one per line
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() got\n%v\nwant it to contain %q", err, want)
+				}
+			}
+			if lines := strings.Count(err.Error(), "\n") + 1; lines != len(tt.want) {
+				t.Errorf("Validate() got %d problems want %d:\n%v", lines, len(tt.want), err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Validates(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	_, _, err := parseConfig(nil, []string{"--server.port=0", "--log.format=xml"})
+	if err == nil {
+		t.Fatal("parseConfig() with invalid settings got no error")
+	}
+	for _, want := range []string{"server.port (PORT)", "log.format (LOG_FORMAT)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("parseConfig() error got %v want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signToken creates an HS256 token for claims
+func signToken(t *testing.T, secret, alg string, claims interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to encode claims: %v", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticator_Validate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	auth := NewAuthenticator([]byte("secret"))
+	auth.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", signToken(t, "secret", "HS256", Claims{Subject: "alice", ExpiresAt: now.Add(time.Minute).Unix()}), false},
+		{"within clock skew", signToken(t, "secret", "HS256", Claims{Subject: "alice", ExpiresAt: now.Add(-10 * time.Second).Unix()}), false},
+		{"expired", signToken(t, "secret", "HS256", Claims{Subject: "alice", ExpiresAt: now.Add(-time.Hour).Unix()}), true},
+		{"not valid yet", signToken(t, "secret", "HS256", Claims{Subject: "alice", NotBefore: now.Add(time.Hour).Unix()}), true},
+		{"wrong secret", signToken(t, "other", "HS256", Claims{Subject: "alice"}), true},
+		{"unsupported algorithm", signToken(t, "secret", "none", Claims{Subject: "alice"}), true},
+		{"malformed", "not-a-token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := auth.Validate(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Subject != "alice" {
+				t.Errorf("subject got %q want %q", claims.Subject, "alice")
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	auth := NewAuthenticator([]byte("secret"))
+	var subject string
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = ""
+		if claims, ok := ClaimsFromContext(r.Context()); ok {
+			subject = claims.Subject
+		}
+	}))
+	valid := "Bearer " + signToken(t, "secret", "HS256", Claims{Subject: "alice"})
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantStatus    int
+		wantSubject   string
+	}{
+		{"anonymous read", http.MethodGet, "", http.StatusOK, ""},
+		{"authenticated read", http.MethodGet, valid, http.StatusOK, "alice"},
+		{"anonymous write", http.MethodPost, "", http.StatusUnauthorized, ""},
+		{"authenticated write", http.MethodPost, valid, http.StatusOK, "alice"},
+		{"invalid token on read", http.MethodGet, "Bearer nope", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject = ""
+			req := httptest.NewRequest(tt.method, "/users", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status got %d want %d", rec.Code, tt.wantStatus)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject got %q want %q", subject, tt.wantSubject)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate challenge is missing")
+			}
+		})
+	}
+}
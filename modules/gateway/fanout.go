@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// defaultFanOutTimeout bounds each upstream call of an aggregated request
+const defaultFanOutTimeout = 3 * time.Second
+
+// forwardedHeaders are copied from the client request to every upstream call
+var forwardedHeaders = []string{"Authorization", "X-Request-ID", "X-Tenant-ID"}
+
+// FanOutCall is one upstream GET whose JSON body is returned under Name
+type FanOutCall struct {
+	Name     string
+	Upstream *url.URL
+	Path     string
+}
+
+// Aggregator answers one client request by calling several upstreams
+// concurrently and combining their JSON responses into a single document.
+// A failing call is reported under "errors" without failing the others.
+type Aggregator struct {
+	client  *http.Client
+	calls   []FanOutCall
+	timeout time.Duration
+}
+
+// AggregateResponse is the combined document of an aggregated request
+type AggregateResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors map[string]string          `json:"errors,omitempty"`
+}
+
+// NewAggregator creates an aggregator running calls with client, each limited to timeout
+func NewAggregator(client *http.Client, timeout time.Duration, calls ...FanOutCall) *Aggregator {
+	return &Aggregator{client: client, calls: calls, timeout: timeout}
+}
+
+// ServeHTTP implements http.Handler. It answers 502 only when every call failed.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.Write(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+		return
+	}
+
+	response := AggregateResponse{
+		Data:   make(map[string]json.RawMessage, len(a.calls)),
+		Errors: make(map[string]string),
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, call := range a.calls {
+		wg.Add(1)
+		go func(call FanOutCall) {
+			defer wg.Done()
+			body, err := a.fetch(r, call)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				slog.WarnContext(r.Context(), "Fan-out call failed", "call", call.Name, "error", err)
+				response.Errors[call.Name] = err.Error()
+				return
+			}
+			response.Data[call.Name] = body
+		}(call)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if len(response.Data) == 0 && len(a.calls) > 0 {
+		status = http.StatusBadGateway
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode aggregate response", "error", err)
+	}
+}
+
+// fetch performs call on behalf of the client request r and returns its JSON body
+func (a *Aggregator) fetch(r *http.Request, call FanOutCall) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, call.Upstream.JoinPath(call.Path).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for _, name := range forwardedHeaders {
+		if value := r.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream answered %d", resp.StatusCode)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("upstream answered invalid JSON")
+	}
+	return body, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAggregator(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"healthy"}`))
+		case "/users":
+			if r.Header.Get("X-Tenant-ID") != "acme" {
+				http.Error(w, "tenant was not forwarded", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`[{"id":"1"}]`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{}`))
+		case "/text":
+			w.Write([]byte(`not json`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	call := func(name, path string) FanOutCall {
+		return FanOutCall{Name: name, Upstream: target, Path: path}
+	}
+
+	tests := []struct {
+		name       string
+		calls      []FanOutCall
+		wantStatus int
+		wantData   []string
+		wantErrors []string
+	}{
+		{"all succeed", []FanOutCall{call("health", "/health"), call("users", "/users")}, http.StatusOK, []string{"health", "users"}, nil},
+		{"partial failure", []FanOutCall{call("health", "/health"), call("orders", "/orders"), call("slow", "/slow")}, http.StatusOK, []string{"health"}, []string{"orders", "slow"}},
+		{"invalid JSON", []FanOutCall{call("health", "/health"), call("text", "/text")}, http.StatusOK, []string{"health"}, []string{"text"}},
+		{"all fail", []FanOutCall{call("orders", "/orders")}, http.StatusBadGateway, nil, []string{"orders"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregator := NewAggregator(upstream.Client(), 50*time.Millisecond, tt.calls...)
+			req := httptest.NewRequest(http.MethodGet, "/api/dashboard", nil)
+			req.Header.Set("X-Tenant-ID", "acme")
+			rec := httptest.NewRecorder()
+			aggregator.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status got %d want %d", rec.Code, tt.wantStatus)
+			}
+			var response AggregateResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response.Data) != len(tt.wantData) {
+				t.Errorf("data got %v want keys %v", response.Data, tt.wantData)
+			}
+			for _, name := range tt.wantData {
+				if _, ok := response.Data[name]; !ok {
+					t.Errorf("data is missing %q", name)
+				}
+			}
+			if len(response.Errors) != len(tt.wantErrors) {
+				t.Errorf("errors got %v want keys %v", response.Errors, tt.wantErrors)
+			}
+			for _, name := range tt.wantErrors {
+				if _, ok := response.Errors[name]; !ok {
+					t.Errorf("errors is missing %q", name)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregator_MethodNotAllowed(t *testing.T) {
+	aggregator := NewAggregator(http.DefaultClient, time.Second)
+	rec := httptest.NewRecorder()
+	aggregator.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/dashboard", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status got %d want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if got := rec.Header().Get("Allow"); got != http.MethodGet {
+		t.Errorf("Allow got %q want %q", got, http.MethodGet)
+	}
+}
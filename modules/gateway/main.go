@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+)
+
+const (
+	defaultPort          = "8000"
+	defaultHost          = "localhost"
+	defaultFoundationURL = "http://localhost:8080"
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	foundationURL, err := url.Parse(config.Getenv("FOUNDATION_URL", defaultFoundationURL))
+	if err != nil || foundationURL.Host == "" {
+		fatal("Invalid FOUNDATION_URL", "url", config.Getenv("FOUNDATION_URL", defaultFoundationURL))
+	}
+
+	// Proxy each route prefix to the service owning it
+	routes, err := loadRoutes(foundationURL.String())
+	if err != nil {
+		fatal("Invalid routes", "error", err)
+	}
+
+	// Limit how many requests each client may send
+	rateLimiter, err := loadRateLimiter()
+	if err != nil {
+		fatal("Invalid rate limit", "error", err)
+	}
+
+	fanOutTimeout, err := time.ParseDuration(config.Getenv("FANOUT_TIMEOUT", defaultFanOutTimeout.String()))
+	if err != nil || fanOutTimeout <= 0 {
+		fatal("Invalid FANOUT_TIMEOUT", "error", "must be a positive duration")
+	}
+
+	// Setup routes
+	mux := http.NewServeMux()
+	mux.Handle("/api/dashboard", NewAggregator(&http.Client{}, fanOutTimeout,
+		FanOutCall{Name: "health", Upstream: foundationURL, Path: "/health"},
+		FanOutCall{Name: "users", Upstream: foundationURL, Path: "/users"},
+	))
+	mux.HandleFunc("/gateway", infoHandler(routes))
+	mux.Handle("/", NewRouter(routes))
+
+	// Authenticate first so the rate limit can key on the token subject
+	var handler http.Handler = mux
+	if rateLimiter != nil {
+		handler = rateLimiter.Middleware(handler)
+	} else {
+		slog.Warn("Rate limiting disabled: RATE_LIMIT_RPS is 0")
+	}
+	if secret := os.Getenv("JWT_HS256_SECRET"); secret != "" {
+		handler = NewAuthenticator([]byte(secret)).Middleware(handler)
+	} else {
+		slog.Warn("JWT authentication disabled: set JWT_HS256_SECRET to enable it")
+	}
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(handler),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting gateway", "url", fmt.Sprintf("http://%s:%s", host, port))
+		for _, route := range routes {
+			slog.Info("Proxying route", "prefix", route.Prefix, "upstream", route.Upstream.String())
+		}
+		slog.Info("Aggregating route", "prefix", "/api/dashboard", "upstream", foundationURL.String())
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Gateway failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	slog.Info("Shutting down gateway")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		fatal("Gateway forced to shutdown", "error", err)
+	}
+	slog.Info("Gateway exited")
+}
+
+// infoHandler describes the routes of the gateway
+func infoHandler(routes []Route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		upstreams := make(map[string]string, len(routes))
+		for _, route := range routes {
+			upstreams[route.Prefix] = route.Upstream.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"service":    "gateway",
+			"routes":     upstreams,
+			"aggregates": []string{"/api/dashboard"},
+		})
+	}
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// Route sends every request whose path starts with Prefix to Upstream
+type Route struct {
+	Prefix   string
+	Upstream *url.URL
+}
+
+// loadRoutes reads the proxied routes from GATEWAY_ROUTES, e.g.
+// "/users=http://localhost:8080,/orders=http://localhost:8081". Without it the
+// foundation API at foundationURL is exposed.
+func loadRoutes(foundationURL string) ([]Route, error) {
+	spec := config.Getenv("GATEWAY_ROUTES", fmt.Sprintf("/users=%[1]s,/graphql=%[1]s,/health=%[1]s", foundationURL))
+
+	var routes []Route
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		prefix, target, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		upstream, err := url.Parse(strings.TrimSpace(target))
+		if !ok || err != nil || !strings.HasPrefix(prefix, "/") || upstream.Scheme == "" || upstream.Host == "" {
+			return nil, fmt.Errorf("invalid GATEWAY_ROUTES entry %q, want /prefix=http://host:port", entry)
+		}
+		routes = append(routes, Route{Prefix: strings.TrimSuffix(prefix, "/"), Upstream: upstream})
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("GATEWAY_ROUTES defines no route")
+	}
+	return routes, nil
+}
+
+// Router proxies requests to the upstream of the longest matching route prefix
+type Router struct {
+	routes  []Route
+	proxies map[string]*httputil.ReverseProxy
+}
+
+// NewRouter creates a router with a reverse proxy per route
+func NewRouter(routes []Route) *Router {
+	sorted := append([]Route(nil), routes...)
+	// Longer prefixes first so /users/admin wins over /users
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Prefix) > len(sorted[j].Prefix)
+	})
+
+	router := &Router{routes: sorted, proxies: make(map[string]*httputil.ReverseProxy)}
+	for _, route := range sorted {
+		router.proxies[route.Prefix] = newReverseProxy(route.Upstream)
+	}
+	return router
+}
+
+// match returns the route serving path. A prefix matches itself and its sub-paths only.
+func (rt *Router) match(path string) (Route, bool) {
+	for _, route := range rt.routes {
+		if path == route.Prefix || strings.HasPrefix(path, route.Prefix+"/") {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
+
+// ServeHTTP implements http.Handler
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := rt.match(r.URL.Path)
+	if !ok {
+		problem.Write(w, r, http.StatusNotFound, fmt.Sprintf("no upstream serves %s", r.URL.Path))
+		return
+	}
+	rt.proxies[route.Prefix].ServeHTTP(w, r)
+}
+
+// newReverseProxy creates a proxy to upstream that records the original
+// client in the X-Forwarded-* headers and answers 502 or 504 problems when
+// the upstream cannot be reached
+func newReverseProxy(upstream *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			pr.SetXForwarded()
+			// Keep the client's Host so upstreams can resolve tenants from subdomains
+			pr.Out.Host = pr.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.ErrorContext(r.Context(), "Upstream failed",
+				"upstream", upstream.Host, "method", r.Method, "path", r.URL.Path, "error", err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				problem.Write(w, r, http.StatusGatewayTimeout, "upstream did not respond in time")
+				return
+			}
+			problem.Write(w, r, http.StatusBadGateway, "upstream is unavailable")
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+func TestLoadRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{"default foundation routes", "", 3, false},
+		{"custom routes", "/users=http://users:8080, /orders/=http://orders:8081", 2, false},
+		{"missing upstream", "/users=", 0, true},
+		{"relative prefix", "users=http://users:8080", 0, true},
+		{"no scheme", "/users=users:8080", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GATEWAY_ROUTES", tt.spec)
+			routes, err := loadRoutes("http://localhost:8080")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRoutes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(routes) != tt.want {
+				t.Errorf("loadRoutes() got %d routes want %d", len(routes), tt.want)
+			}
+			for _, route := range routes {
+				if strings.HasSuffix(route.Prefix, "/") {
+					t.Errorf("route prefix %q keeps its trailing slash", route.Prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	upstream := func(name string) *url.URL {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Upstream", name)
+			w.Header().Set("X-Seen-Path", r.URL.RequestURI())
+			w.Header().Set("X-Seen-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+			w.Header().Set("X-Seen-Authorization", r.Header.Get("Authorization"))
+		}))
+		t.Cleanup(server.Close)
+		target, _ := url.Parse(server.URL)
+		return target
+	}
+
+	router := NewRouter([]Route{
+		{Prefix: "/users", Upstream: upstream("users")},
+		{Prefix: "/users/admin", Upstream: upstream("admin")},
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantUpstream string
+	}{
+		{"exact prefix", "/users", http.StatusOK, "users"},
+		{"sub-path with query", "/users/42?fields=id", http.StatusOK, "users"},
+		{"longest prefix wins", "/users/admin/audit", http.StatusOK, "admin"},
+		{"prefix is not a word prefix", "/usersettings", http.StatusNotFound, ""},
+		{"unknown route", "/orders", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status got %d want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("X-Upstream"); got != tt.wantUpstream {
+				t.Errorf("upstream got %q want %q", got, tt.wantUpstream)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+					t.Errorf("Content-Type got %q want %q", got, problem.ContentType)
+				}
+				return
+			}
+			if got := rec.Header().Get("X-Seen-Path"); got != tt.path {
+				t.Errorf("upstream path got %q want %q", got, tt.path)
+			}
+			if got := rec.Header().Get("X-Seen-Forwarded-For"); got == "" {
+				t.Error("X-Forwarded-For was not set")
+			}
+			if got := rec.Header().Get("X-Seen-Authorization"); got != "Bearer token" {
+				t.Errorf("Authorization got %q want it forwarded", got)
+			}
+		})
+	}
+}
+
+func TestRouter_UpstreamUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	target, _ := url.Parse(server.URL)
+	server.Close()
+
+	router := NewRouter([]Route{{Prefix: "/users", Upstream: target}})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status got %d want %d", rec.Code, http.StatusBadGateway)
+	}
+	if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("Content-Type got %q want %q", got, problem.ContentType)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+const (
+	defaultRateLimit = 10.0
+	defaultRateBurst = 20
+
+	// maxIdleBuckets bounds the number of tracked clients before full
+	// (idle) buckets are dropped
+	maxIdleBuckets = 10000
+)
+
+// loadRateLimiter reads RATE_LIMIT_RPS and RATE_LIMIT_BURST. A rate of zero
+// disables rate limiting and returns nil.
+func loadRateLimiter() (*RateLimiter, error) {
+	rate, err := strconv.ParseFloat(config.Getenv("RATE_LIMIT_RPS", strconv.FormatFloat(defaultRateLimit, 'f', -1, 64)), 64)
+	if err != nil || rate < 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_RPS must be a non-negative number")
+	}
+	burst, err := strconv.Atoi(config.Getenv("RATE_LIMIT_BURST", strconv.Itoa(defaultRateBurst)))
+	if err != nil || burst < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_BURST must be a positive integer")
+	}
+	if rate == 0 {
+		return nil, nil
+	}
+	return NewRateLimiter(rate, burst), nil
+}
+
+// bucket is the token bucket of one client
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter is a per-client token bucket: each client may send burst
+// requests at once, refilled at rate requests per second
+type RateLimiter struct {
+	rate    float64
+	burst   int
+	buckets map[string]*bucket
+	now     func() time.Time
+	mutex   sync.Mutex
+}
+
+// NewRateLimiter creates a limiter allowing rate requests per second with bursts of burst
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of client. It returns the tokens left
+// and, when no token is available, how long until the next one.
+func (l *RateLimiter) Allow(client string) (ok bool, remaining int, retryAfter time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	b, exists := l.buckets[client]
+	if !exists {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: float64(l.burst), updated: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// prune drops the buckets that have refilled completely. The caller must hold the mutex.
+func (l *RateLimiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Middleware answers 429 with Retry-After once a client has used its
+// budget. Authenticated clients are limited by token subject, anonymous
+// ones by remote address.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, remaining, retryAfter := l.Allow(clientKey(r))
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			problem.Write(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies the client of a request for rate limiting
+func clientKey(r *http.Request) string {
+	if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
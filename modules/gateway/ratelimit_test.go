@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, remaining, _ := limiter.Allow("alice"); !ok || remaining != 2-i {
+			t.Fatalf("request %d got ok=%v remaining=%d want ok=true remaining=%d", i+1, ok, remaining, 2-i)
+		}
+	}
+
+	ok, _, retryAfter := limiter.Allow("alice")
+	if ok {
+		t.Fatal("request beyond the burst was allowed")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("retry after got %v want %v", retryAfter, 500*time.Millisecond)
+	}
+
+	if ok, _, _ := limiter.Allow("bob"); !ok {
+		t.Error("another client shares the exhausted bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _, _ := limiter.Allow("alice"); !ok {
+		t.Error("bucket was not refilled")
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	limiter.now = func() time.Time { return time.Unix(1700000000, 0) }
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(remoteAddr, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.RemoteAddr = remoteAddr
+		if subject != "" {
+			req = req.WithContext(context.WithValue(req.Context(), claimsContextKey{}, &Claims{Subject: subject}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		subject    string
+		wantStatus int
+	}{
+		{"first anonymous request", "10.0.0.1:1234", "", http.StatusOK},
+		{"same address, other port", "10.0.0.1:5678", "", http.StatusTooManyRequests},
+		{"other address", "10.0.0.2:1234", "", http.StatusOK},
+		{"subject on a limited address", "10.0.0.1:1234", "alice", http.StatusOK},
+		{"same subject elsewhere", "10.0.0.3:1234", "alice", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := request(tt.remoteAddr, tt.subject)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status got %d want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != "1" {
+				t.Errorf("X-RateLimit-Limit got %q want %q", got, "1")
+			}
+			if tt.wantStatus == http.StatusTooManyRequests {
+				if got := rec.Header().Get("Retry-After"); got != "1" {
+					t.Errorf("Retry-After got %q want %q", got, "1")
+				}
+				if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+					t.Errorf("Content-Type got %q want %q", got, problem.ContentType)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRateLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate, burst string
+		wantNil     bool
+		wantErr     bool
+	}{
+		{"defaults", "", "", false, false},
+		{"disabled", "0", "", true, false},
+		{"negative rate", "-1", "", true, true},
+		{"zero burst", "5", "0", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RATE_LIMIT_RPS", tt.rate)
+			t.Setenv("RATE_LIMIT_BURST", tt.burst)
+			limiter, err := loadRateLimiter()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRateLimiter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (limiter == nil) != tt.wantNil {
+				t.Errorf("loadRateLimiter() got %v, want nil %v", limiter, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/contract"
+)
+
+// TestUserEventsContract checks that the fields of user events declared in
+// contracts/foundation.json are all the emails need
+func TestUserEventsContract(t *testing.T) {
+	c, err := contract.Load(filepath.Join("contracts", "foundation.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := testRenderer(t)
+
+	for _, example := range c.Examples() {
+		to, message, ok, err := messageFor(renderer, example)
+		if err != nil || !ok {
+			t.Errorf("messageFor(%s) = %v, %v want an email", example.Type, ok, err)
+			continue
+		}
+		if to != "example" || !strings.Contains(message.Text, "example") {
+			t.Errorf("messageFor(%s) wrote to %q: %q, want the example user", example.Type, to, message.Text)
+		}
+	}
+}
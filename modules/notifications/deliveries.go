@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeliveryStatus is the state of the delivery of a notification
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending deliveries wait for their first or next attempt
+	DeliveryStatusPending DeliveryStatus = "pending"
+	// DeliveryStatusSending deliveries are being sent
+	DeliveryStatusSending DeliveryStatus = "sending"
+	// DeliveryStatusSent deliveries were accepted by the sender
+	DeliveryStatusSent DeliveryStatus = "sent"
+	// DeliveryStatusFailed deliveries gave up after the last attempt
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// Errors of the delivery store
+var (
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrDeliveryBusy     = errors.New("delivery is being sent or was sent")
+)
+
+// Delivery tracks the email sent for an event. Its bodies are rendered
+// once, so retries send the same email even after the templates change.
+type Delivery struct {
+	ID            string         `json:"id"`
+	EventID       string         `json:"event_id"`
+	EventType     string         `json:"event_type"`
+	To            string         `json:"to"`
+	Subject       string         `json:"subject"`
+	Body          string         `json:"-"`
+	HTMLBody      string         `json:"-"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	SentAt        *time.Time     `json:"sent_at,omitempty"`
+}
+
+// DeliveryStore keeps the deliveries in memory, at most one per event
+type DeliveryStore struct {
+	mutex      sync.Mutex
+	deliveries map[string]*Delivery
+	byEvent    map[string]string
+}
+
+// NewDeliveryStore creates an empty store
+func NewDeliveryStore() *DeliveryStore {
+	return &DeliveryStore{deliveries: make(map[string]*Delivery), byEvent: make(map[string]string)}
+}
+
+// Add stores a new delivery. It reports false, storing nothing, when the
+// event already has a delivery, so redelivered events send no second email.
+func (s *DeliveryStore) Add(delivery Delivery) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.byEvent[delivery.EventID]; ok {
+		return false
+	}
+	s.deliveries[delivery.ID] = &delivery
+	s.byEvent[delivery.EventID] = delivery.ID
+	return true
+}
+
+// Get returns the delivery with id
+func (s *DeliveryStore) Get(id string) (Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return *delivery, nil
+}
+
+// List returns the deliveries, oldest first, with the status or with any
+// status when it is empty
+func (s *DeliveryStore) List(status DeliveryStatus) []Delivery {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deliveries := make([]Delivery, 0, len(s.deliveries))
+	for _, delivery := range s.deliveries {
+		if status == "" || delivery.Status == status {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries
+}
+
+// Due returns the IDs of the pending deliveries whose next attempt is due at now
+func (s *DeliveryStore) Due(now time.Time) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var ids []string
+	for id, delivery := range s.deliveries {
+		if delivery.Status == DeliveryStatusPending && (delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.After(now)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Claim marks the delivery with id as being sent and returns it, so a single
+// attempt runs at a time. Pending deliveries are claimed, and failed ones
+// too when includeFailed is set.
+func (s *DeliveryStore) Claim(id string, includeFailed bool, now time.Time) (Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	if delivery.Status != DeliveryStatusPending && (!includeFailed || delivery.Status != DeliveryStatusFailed) {
+		return Delivery{}, ErrDeliveryBusy
+	}
+	delivery.Status = DeliveryStatusSending
+	delivery.NextAttemptAt = nil
+	delivery.UpdatedAt = now
+	return *delivery, nil
+}
+
+// Update replaces the stored delivery with the same ID
+func (s *DeliveryStore) Update(delivery Delivery) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; ok {
+		s.deliveries[delivery.ID] = &delivery
+	}
+}
+
+// Counts returns the number of deliveries of each status
+func (s *DeliveryStore) Counts() map[DeliveryStatus]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[DeliveryStatus]int)
+	for _, delivery := range s.deliveries {
+		counts[delivery.Status]++
+	}
+	return counts
+}
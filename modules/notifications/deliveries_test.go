@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeliveryStore(t *testing.T) {
+	store := NewDeliveryStore()
+	now := time.Now()
+	later := now.Add(time.Minute)
+
+	if !store.Add(Delivery{ID: "d1", EventID: "e1", Status: DeliveryStatusPending, CreatedAt: now}) {
+		t.Fatal("Add() of a new event got false want true")
+	}
+	if store.Add(Delivery{ID: "d2", EventID: "e1", Status: DeliveryStatusPending, CreatedAt: now}) {
+		t.Error("Add() of an event already delivered got true want false")
+	}
+	store.Add(Delivery{ID: "d3", EventID: "e3", Status: DeliveryStatusPending, NextAttemptAt: &later, CreatedAt: now.Add(time.Second)})
+
+	if due := store.Due(now); len(due) != 1 || due[0] != "d1" {
+		t.Errorf("got due %v want [d1]", due)
+	}
+	if due := store.Due(later); len(due) != 2 {
+		t.Errorf("got due %v want d1 and d3", due)
+	}
+
+	claimed, err := store.Claim("d1", false, now)
+	if err != nil || claimed.Status != DeliveryStatusSending {
+		t.Fatalf("got %+v, %v want a delivery being sent", claimed, err)
+	}
+	if _, err := store.Claim("d1", true, now); !errors.Is(err, ErrDeliveryBusy) {
+		t.Errorf("got %v want %v", err, ErrDeliveryBusy)
+	}
+	if _, err := store.Claim("missing", false, now); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("got %v want %v", err, ErrDeliveryNotFound)
+	}
+
+	claimed.Status = DeliveryStatusFailed
+	store.Update(claimed)
+	if _, err := store.Claim("d1", false, now); !errors.Is(err, ErrDeliveryBusy) {
+		t.Errorf("Claim() of a failed delivery got %v want %v", err, ErrDeliveryBusy)
+	}
+	if _, err := store.Claim("d1", true, now); err != nil {
+		t.Errorf("Claim() of a failed delivery including failed ones error = %v", err)
+	}
+
+	if list := store.List(""); len(list) != 2 || list[0].ID != "d1" {
+		t.Errorf("got %+v want d1 then d3", list)
+	}
+	if list := store.List(DeliveryStatusPending); len(list) != 1 || list[0].ID != "d3" {
+		t.Errorf("got %+v want d3", list)
+	}
+	if counts := store.Counts(); counts[DeliveryStatusPending] != 1 || counts[DeliveryStatusSending] != 1 {
+		t.Errorf("got counts %v want one pending and one sending", counts)
+	}
+}
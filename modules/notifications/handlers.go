@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// DeliveryHandler serves the delivery tracking API
+type DeliveryHandler struct {
+	notifier   *Notifier
+	deliveries *DeliveryStore
+	mux        *http.ServeMux
+}
+
+// NewDeliveryHandler creates the handler of the deliveries routes
+func NewDeliveryHandler(notifier *Notifier, deliveries *DeliveryStore) *DeliveryHandler {
+	h := &DeliveryHandler{notifier: notifier, deliveries: deliveries, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /deliveries", h.handleListDeliveries)
+	h.mux.HandleFunc("GET /deliveries/{id}", h.handleGetDelivery)
+	h.mux.HandleFunc("POST /deliveries/{id}/retry", h.handleRetryDelivery)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *DeliveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleListDeliveries lists the deliveries, of one status with ?status=
+func (h *DeliveryHandler) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	status := DeliveryStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", DeliveryStatusPending, DeliveryStatusSending, DeliveryStatusSent, DeliveryStatusFailed:
+	default:
+		problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown status %q", status))
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, h.deliveries.List(status))
+}
+
+// handleGetDelivery returns a delivery
+func (h *DeliveryHandler) handleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.deliveries.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, delivery)
+}
+
+// handleRetryDelivery attempts a pending or failed delivery now
+func (h *DeliveryHandler) handleRetryDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.notifier.Retry(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, delivery)
+}
+
+// writeError writes the problem matching a delivery error
+func (h *DeliveryHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrDeliveryNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrDeliveryBusy):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// TemplateHandler previews the email templates with sample data
+type TemplateHandler struct {
+	renderer *Renderer
+	mux      *http.ServeMux
+}
+
+// NewTemplateHandler creates the handler of the templates routes
+func NewTemplateHandler(renderer *Renderer) *TemplateHandler {
+	h := &TemplateHandler{renderer: renderer, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /templates", h.handleListTemplates)
+	h.mux.HandleFunc("GET /templates/{name}/preview", h.handlePreviewTemplate)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *TemplateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// templateInfo describes a template and the events using it
+type templateInfo struct {
+	Name       string   `json:"name"`
+	EventTypes []string `json:"event_types"`
+	Preview    string   `json:"preview"`
+}
+
+// handleListTemplates lists the templates along with the events using them
+func (h *TemplateHandler) handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates := []templateInfo{}
+	for _, name := range h.renderer.Names() {
+		info := templateInfo{Name: name, EventTypes: []string{}, Preview: "/templates/" + name + "/preview"}
+		for _, eventType := range userEventTypes {
+			if templateNames[eventType] == name {
+				info.EventTypes = append(info.EventTypes, eventType)
+			}
+		}
+		templates = append(templates, info)
+	}
+	httpx.WriteJSON(w, http.StatusOK, templates)
+}
+
+// handlePreviewTemplate renders a template for a sample user, as HTML or
+// with ?format=text as the subject and plain text body
+func (h *TemplateHandler) handlePreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "text" {
+		problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown format %q, want html or text", format))
+		return
+	}
+	name := r.PathValue("name")
+	data := TemplateData{
+		User:      recipient{ID: "preview", Name: "Ada Lovelace", Email: "ada@example.com"},
+		EventID:   "preview",
+		EventType: "preview",
+		Time:      time.Now().UTC(),
+	}
+	rendered, err := h.renderer.Render(name, data)
+	switch {
+	case errors.Is(err, ErrTemplateNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Template preview failed", "template", name, "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "Subject: %s\n\n%s", rendered.Subject, rendered.Text)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, rendered.HTML)
+}
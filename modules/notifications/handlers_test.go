@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeliveryHandler(t *testing.T) {
+	notifier, store, _, _ := newTestNotifier(t, 1, 1)
+	notifier.HandleUserEvent(context.Background(), userEvent(t, "e1", "user.created", ada))
+	failed := store.List("")[0]
+	handler := NewDeliveryHandler(notifier, store)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"list", http.MethodGet, "/deliveries", http.StatusOK},
+		{"list failed", http.MethodGet, "/deliveries?status=failed", http.StatusOK},
+		{"unknown status", http.MethodGet, "/deliveries?status=lost", http.StatusBadRequest},
+		{"get", http.MethodGet, "/deliveries/" + failed.ID, http.StatusOK},
+		{"missing", http.MethodGet, "/deliveries/missing", http.StatusNotFound},
+		{"retry", http.MethodPost, "/deliveries/" + failed.ID + "/retry", http.StatusOK},
+		{"retry sent", http.MethodPost, "/deliveries/" + failed.ID + "/retry", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/deliveries?status=sent", nil))
+	var deliveries []map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0]["attempts"] != float64(2) {
+		t.Errorf("got %v want the delivery sent at the second attempt", deliveries)
+	}
+	if _, ok := deliveries[0]["body"]; ok {
+		t.Error("deliveries expose the email body")
+	}
+}
+
+func TestTemplateHandler(t *testing.T) {
+	handler := NewTemplateHandler(testRenderer(t))
+
+	tests := []struct {
+		name            string
+		target          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"list", "/templates", http.StatusOK, "application/json", `"event_types":["user.created"]`},
+		{"preview html", "/templates/welcome/preview", http.StatusOK, "text/html; charset=utf-8", "<p>Hi Ada Lovelace,</p>"},
+		{"preview text", "/templates/welcome/preview?format=text", http.StatusOK, "text/plain; charset=utf-8", "Subject: Welcome!\n\nHi Ada Lovelace,"},
+		{"unknown format", "/templates/welcome/preview?format=pdf", http.StatusBadRequest, "application/problem+json", "pdf"},
+		{"missing", "/templates/missing/preview", http.StatusNotFound, "application/problem+json", "template not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus || rec.Header().Get("Content-Type") != tt.wantContentType {
+				t.Fatalf("got %d %s want %d %s", rec.Code, rec.Header().Get("Content-Type"), tt.wantStatus, tt.wantContentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got body %q want it to contain %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort          = "8082"
+	defaultHost          = "localhost"
+	defaultUserEventsURL = "http://localhost:8080/events"
+	defaultEmailFrom     = "no-reply@example.com"
+	defaultRetryAttempts = 5
+	defaultRetryDelay    = time.Second
+	defaultRetryMaxDelay = 5 * time.Minute
+	retryCheckInterval   = time.Second
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	userEventsURL := config.Getenv("USER_EVENTS_URL", defaultUserEventsURL)
+	if u, err := url.Parse(userEventsURL); err != nil || u.Host == "" {
+		fatal("Invalid USER_EVENTS_URL", "url", userEventsURL)
+	}
+
+	sender, err := loadSender()
+	if err != nil {
+		fatal("Invalid email sender", "error", err)
+	}
+	retry, err := loadRetryPolicy()
+	if err != nil {
+		fatal("Invalid retry policy", "error", err)
+	}
+
+	renderer, err := loadRenderer()
+	if err != nil {
+		fatal("Invalid email templates", "error", err)
+	}
+
+	deliveries := NewDeliveryStore()
+	notifier := NewNotifier(sender, renderer, config.Getenv("EMAIL_FROM", defaultEmailFrom), deliveries, uuid.GeneratorFunc(uuid.NewGoogle), retry)
+
+	// Follow the user events of the foundation service and retry failed deliveries
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	subscriber := &events.Subscriber{URL: userEventsURL, Types: userEventTypes}
+	if token := os.Getenv("USER_EVENTS_TOKEN"); token != "" {
+		subscriber.Header = http.Header{"Authorization": {"Bearer " + token}}
+	} else {
+		slog.Warn("USER_EVENTS_TOKEN is not set: the user event stream may refuse the connection")
+	}
+	go subscriber.Run(ctx, notifier.HandleUserEvent)
+	go notifier.Run(ctx, retryCheckInterval)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewDeliveryHandler(notifier, deliveries)
+	mux.Handle("/deliveries", api)
+	mux.Handle("/deliveries/", api)
+	templates := NewTemplateHandler(renderer)
+	mux.Handle("/templates", templates)
+	mux.Handle("/templates/", templates)
+	mux.HandleFunc("/health", healthHandler(deliveries))
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting notifications service", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"user_events", userEventsURL, "sender", config.Getenv("EMAIL_SENDER", "log"),
+			"templates", config.Getenv("TEMPLATES_DIR", "embedded"), "templates_reload", renderer.reload)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Notifications service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down notifications service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Notifications service forced to shutdown", "error", err)
+	}
+	slog.Info("Notifications service exited")
+}
+
+// loadSender creates the sender selected by EMAIL_SENDER: log, smtp or ses
+func loadSender() (Sender, error) {
+	switch kind := config.Getenv("EMAIL_SENDER", "log"); kind {
+	case "log":
+		return LogSender{}, nil
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("SMTP_ADDR is required by the smtp sender")
+		}
+		return SMTPSender{Addr: addr, Username: os.Getenv("SMTP_USERNAME"), Password: os.Getenv("SMTP_PASSWORD")}, nil
+	case "ses":
+		sender := SESSender{
+			Region:          os.Getenv("AWS_REGION"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Endpoint:        os.Getenv("SES_ENDPOINT"),
+			Client:          &http.Client{Timeout: 10 * time.Second},
+		}
+		if sender.Region == "" || sender.AccessKeyID == "" || sender.SecretAccessKey == "" {
+			return nil, fmt.Errorf("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required by the ses sender")
+		}
+		return sender, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_SENDER %q, want log, smtp or ses", kind)
+	}
+}
+
+// loadRenderer creates the renderer of the templates of TEMPLATES_DIR, the
+// embedded ones by default. TEMPLATES_RELOAD=true parses them again before
+// every email, to edit them without restarting during development.
+func loadRenderer() (*Renderer, error) {
+	reload := false
+	if value := os.Getenv("TEMPLATES_RELOAD"); value != "" {
+		var err error
+		if reload, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("TEMPLATES_RELOAD must be a boolean, got %q", value)
+		}
+	}
+	dir := os.Getenv("TEMPLATES_DIR")
+	if dir == "" {
+		if reload {
+			return nil, fmt.Errorf("TEMPLATES_RELOAD requires TEMPLATES_DIR, the embedded templates never change")
+		}
+		return NewRenderer(EmbeddedTemplates(), false)
+	}
+	renderer, err := NewRenderer(os.DirFS(dir), reload)
+	if err != nil {
+		return nil, fmt.Errorf("TEMPLATES_DIR %s: %w", dir, err)
+	}
+	return renderer, nil
+}
+
+// loadRetryPolicy reads the retry policy from RETRY_MAX_ATTEMPTS,
+// RETRY_DELAY and RETRY_MAX_DELAY
+func loadRetryPolicy() (RetryPolicy, error) {
+	policy := RetryPolicy{MaxAttempts: defaultRetryAttempts, Delay: defaultRetryDelay, MaxDelay: defaultRetryMaxDelay}
+	if value := os.Getenv("RETRY_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 {
+			return RetryPolicy{}, fmt.Errorf("RETRY_MAX_ATTEMPTS must be a positive integer, got %q", value)
+		}
+		policy.MaxAttempts = attempts
+	}
+	for _, setting := range []struct {
+		key   string
+		value *time.Duration
+	}{{"RETRY_DELAY", &policy.Delay}, {"RETRY_MAX_DELAY", &policy.MaxDelay}} {
+		if value := os.Getenv(setting.key); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return RetryPolicy{}, fmt.Errorf("%s must be a positive duration, got %q", setting.key, value)
+			}
+			*setting.value = d
+		}
+	}
+	if policy.MaxDelay < policy.Delay {
+		return RetryPolicy{}, fmt.Errorf("RETRY_MAX_DELAY must not be shorter than RETRY_DELAY")
+	}
+	return policy, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "notifications",
+		"endpoints": map[string]string{
+			"deliveries": "/deliveries",
+			"templates":  "/templates",
+			"health":     "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy along with the number of
+// deliveries of each status
+func healthHandler(deliveries *DeliveryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     "healthy",
+			"deliveries": deliveries.Counts(),
+		})
+	}
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"default", nil, "main.LogSender", false},
+		{"smtp", map[string]string{"EMAIL_SENDER": "smtp", "SMTP_ADDR": "localhost:1025"}, "main.SMTPSender", false},
+		{"smtp without address", map[string]string{"EMAIL_SENDER": "smtp"}, "", true},
+		{"ses", map[string]string{"EMAIL_SENDER": "ses", "AWS_REGION": "eu-west-1", "AWS_ACCESS_KEY_ID": "id", "AWS_SECRET_ACCESS_KEY": "secret"}, "main.SESSender", false},
+		{"ses without credentials", map[string]string{"EMAIL_SENDER": "ses", "AWS_REGION": "eu-west-1"}, "", true},
+		{"unknown", map[string]string{"EMAIL_SENDER": "pigeon"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"EMAIL_SENDER", "SMTP_ADDR", "AWS_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+				t.Setenv(key, tt.env[key])
+			}
+			sender, err := loadSender()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadSender() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := fmt.Sprintf("%T", sender); !tt.wantErr && got != tt.want {
+				t.Errorf("got %s want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts string
+		delay    string
+		maxDelay string
+		want     RetryPolicy
+		wantErr  bool
+	}{
+		{"defaults", "", "", "", RetryPolicy{MaxAttempts: 5, Delay: time.Second, MaxDelay: 5 * time.Minute}, false},
+		{"custom", "3", "2s", "1m", RetryPolicy{MaxAttempts: 3, Delay: 2 * time.Second, MaxDelay: time.Minute}, false},
+		{"zero attempts", "0", "", "", RetryPolicy{}, true},
+		{"invalid delay", "", "soon", "", RetryPolicy{}, true},
+		{"max delay below delay", "", "10s", "1s", RetryPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RETRY_MAX_ATTEMPTS", tt.attempts)
+			t.Setenv("RETRY_DELAY", tt.delay)
+			t.Setenv("RETRY_MAX_DELAY", tt.maxDelay)
+			got, err := loadRetryPolicy()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRetryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRenderer(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range testTemplates("Hello") {
+		if err := os.WriteFile(filepath.Join(dir, name), file.Data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		reload     string
+		wantReload bool
+		wantErr    bool
+	}{
+		{"embedded", "", "", false, false},
+		{"directory", dir, "", false, false},
+		{"directory with reload", dir, "true", true, false},
+		{"reload without directory", "", "true", false, true},
+		{"invalid reload", dir, "sometimes", false, true},
+		{"missing directory", filepath.Join(dir, "missing"), "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEMPLATES_DIR", tt.dir)
+			t.Setenv("TEMPLATES_RELOAD", tt.reload)
+			renderer, err := loadRenderer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRenderer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && renderer.reload != tt.wantReload {
+				t.Errorf("got reload %v want %v", renderer.reload, tt.wantReload)
+			}
+		})
+	}
+}
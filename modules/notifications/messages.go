@@ -0,0 +1,54 @@
+package main
+
+import "github.com/captain-corgi/learning-event-driven/pkg/events"
+
+// userEventTypes are the event types of the foundation service that notify users
+var userEventTypes = []string{
+	"user.created",
+	"user.activated",
+	"user.suspended",
+	"user.password_changed",
+	"user.deleted",
+}
+
+// recipient is the part of the user snapshot of user events used to write
+// to the user
+type recipient struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// templateNames maps the event types notifying users to the template of
+// their email
+var templateNames = map[string]string{
+	"user.created":          "welcome",
+	"user.activated":        "verification",
+	"user.suspended":        "suspended",
+	"user.password_changed": "password_changed",
+	"user.deleted":          "goodbye",
+}
+
+// messageFor renders the email notifying the user of event, and returns
+// false for events that notify nobody
+func messageFor(renderer *Renderer, event events.Event) (to string, message Rendered, ok bool, err error) {
+	name, ok := templateNames[event.Type]
+	if !ok {
+		return "", Rendered{}, false, nil
+	}
+	var data struct {
+		User recipient `json:"user"`
+	}
+	if err := event.Decode(&data); err != nil {
+		return "", Rendered{}, false, err
+	}
+	if data.User.Email == "" {
+		return "", Rendered{}, false, nil
+	}
+
+	message, err = renderer.Render(name, TemplateData{User: data.User, EventID: event.ID, EventType: event.Type, Time: event.Time})
+	if err != nil {
+		return "", Rendered{}, false, err
+	}
+	return data.User.Email, message, true, nil
+}
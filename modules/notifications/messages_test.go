@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// userEvent creates a user event like the ones of the foundation service
+func userEvent(t *testing.T, id, eventType string, user recipient) events.Event {
+	t.Helper()
+	event, err := events.New(id, eventType, "user-service", user.ID, map[string]interface{}{"user": user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestMessageFor(t *testing.T) {
+	renderer := testRenderer(t)
+	tests := []struct {
+		eventType   string
+		user        recipient
+		wantOK      bool
+		wantSubject string
+	}{
+		{"user.created", ada, true, "Welcome!"},
+		{"user.activated", ada, true, "Your account is active"},
+		{"user.suspended", ada, true, "Your account was suspended"},
+		{"user.password_changed", ada, true, "Your password was changed"},
+		{"user.deleted", ada, true, "Your account was deleted"},
+		{"user.updated", ada, false, ""},
+		{"user.created", recipient{ID: "2", Name: "No email"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			to, message, ok, err := messageFor(renderer, userEvent(t, "e1", tt.eventType, tt.user))
+			if err != nil {
+				t.Fatalf("messageFor() error = %v", err)
+			}
+			if ok != tt.wantOK || message.Subject != tt.wantSubject {
+				t.Fatalf("got %q, %v want %q, %v", message.Subject, ok, tt.wantSubject, tt.wantOK)
+			}
+			if ok && (to != ada.Email || !strings.HasPrefix(message.Text, "Hi Ada,") || !strings.Contains(message.Text, ada.Email) ||
+				!strings.Contains(message.HTML, "<p>Hi Ada,</p>")) {
+				t.Errorf("got email to %q with %+v want a greeting of Ada", to, message)
+			}
+		})
+	}
+
+	if _, _, _, err := messageFor(renderer, events.Event{Type: "user.created", Data: []byte(`[]`)}); err == nil {
+		t.Error("got nil error want a decoding error")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// RetryPolicy bounds the attempts of a delivery. The delay before the next
+// attempt starts at Delay and doubles after every failure, up to MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+}
+
+// backoff returns the delay after the failed attempt number attempts
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Notifier turns user events into emails, tracking their delivery and
+// retrying failed ones
+type Notifier struct {
+	sender     Sender
+	renderer   *Renderer
+	from       string
+	deliveries *DeliveryStore
+	ids        uuid.IDGenerator
+	retry      RetryPolicy
+	now        func() time.Time
+}
+
+// NewNotifier creates a notifier sending emails rendered by renderer from
+// the from address
+func NewNotifier(sender Sender, renderer *Renderer, from string, deliveries *DeliveryStore, ids uuid.IDGenerator, retry RetryPolicy) *Notifier {
+	return &Notifier{
+		sender:     sender,
+		renderer:   renderer,
+		from:       from,
+		deliveries: deliveries,
+		ids:        ids,
+		retry:      retry,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// HandleUserEvent records the delivery of the email notifying the user of
+// event and makes its first attempt. Events already handled are skipped.
+func (n *Notifier) HandleUserEvent(ctx context.Context, event events.Event) error {
+	to, message, ok, err := messageFor(n.renderer, event)
+	if err != nil || !ok {
+		return err
+	}
+
+	now := n.now()
+	delivery := Delivery{
+		ID:        n.ids.NewID(),
+		EventID:   event.ID,
+		EventType: event.Type,
+		To:        to,
+		Subject:   message.Subject,
+		Body:      message.Text,
+		HTMLBody:  message.HTML,
+		Status:    DeliveryStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !n.deliveries.Add(delivery) {
+		slog.DebugContext(ctx, "Skipping event already notified", "event_id", event.ID)
+		return nil
+	}
+	n.attempt(ctx, delivery.ID, false)
+	return nil
+}
+
+// Retry attempts the delivery with id again, including a failed one, and
+// returns it after the attempt
+func (n *Notifier) Retry(ctx context.Context, id string) (Delivery, error) {
+	if _, err := n.deliveries.Get(id); err != nil {
+		return Delivery{}, err
+	}
+	if err := n.attempt(ctx, id, true); err != nil {
+		return Delivery{}, err
+	}
+	return n.deliveries.Get(id)
+}
+
+// RetryDue attempts every pending delivery whose next attempt is due and
+// returns how many were attempted
+func (n *Notifier) RetryDue(ctx context.Context) int {
+	attempted := 0
+	for _, id := range n.deliveries.Due(n.now()) {
+		if n.attempt(ctx, id, false) == nil {
+			attempted++
+		}
+	}
+	return attempted
+}
+
+// Run retries due deliveries every interval until ctx is done
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.RetryDue(ctx)
+		}
+	}
+}
+
+// attempt sends the delivery with id once and records the outcome: sent, or
+// pending its next attempt, or failed when no attempt is left. It returns
+// an error when the delivery could not be claimed.
+func (n *Notifier) attempt(ctx context.Context, id string, includeFailed bool) error {
+	delivery, err := n.deliveries.Claim(id, includeFailed, n.now())
+	if err != nil {
+		return err
+	}
+	maxAttempts := n.retry.MaxAttempts
+	if includeFailed {
+		// A manual retry of a failed delivery gets one more attempt
+		maxAttempts = max(maxAttempts, delivery.Attempts+1)
+	}
+
+	err = n.sender.Send(ctx, Email{
+		From:    n.from,
+		To:      delivery.To,
+		Subject: delivery.Subject,
+		Body:    delivery.Body,
+		HTML:    delivery.HTMLBody,
+	})
+	now := n.now()
+	delivery.Attempts++
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.Status = DeliveryStatusSent
+		delivery.LastError = ""
+		delivery.SentAt = &now
+		slog.InfoContext(ctx, "Email sent", "delivery_id", delivery.ID, "event_type", delivery.EventType, "attempts", delivery.Attempts)
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = DeliveryStatusFailed
+		delivery.LastError = err.Error()
+		slog.ErrorContext(ctx, "Email delivery failed", "delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", err)
+	default:
+		next := now.Add(n.retry.backoff(delivery.Attempts))
+		delivery.Status = DeliveryStatusPending
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+		slog.WarnContext(ctx, "Email delivery failed, retrying", "delivery_id", delivery.ID, "attempts", delivery.Attempts, "retry_at", next, "error", err)
+	}
+	n.deliveries.Update(delivery)
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// flakySender fails the first failures sends, then records the emails
+type flakySender struct {
+	mutex    sync.Mutex
+	failures int
+	sent     []Email
+}
+
+func (s *flakySender) Send(ctx context.Context, email Email) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("connection refused")
+	}
+	s.sent = append(s.sent, email)
+	return nil
+}
+
+// testClock is a settable clock
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestNotifier(t *testing.T, failures, maxAttempts int) (*Notifier, *DeliveryStore, *flakySender, *testClock) {
+	t.Helper()
+	sender := &flakySender{failures: failures}
+	store := NewDeliveryStore()
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	notifier := NewNotifier(sender, testRenderer(t), "no-reply@example.com", store, uuid.NewSequenceGenerator("d"),
+		RetryPolicy{MaxAttempts: maxAttempts, Delay: time.Second, MaxDelay: 3 * time.Second})
+	notifier.now = clock.Now
+	return notifier, store, sender, clock
+}
+
+var ada = recipient{ID: "1", Name: "Ada", Email: "ada@example.com"}
+
+func TestNotifier_HandleUserEvent(t *testing.T) {
+	notifier, store, sender, _ := newTestNotifier(t, 0, 3)
+	ctx := context.Background()
+
+	event := userEvent(t, "e1", "user.created", ada)
+	for range 2 {
+		if err := notifier.HandleUserEvent(ctx, event); err != nil {
+			t.Fatalf("HandleUserEvent() error = %v", err)
+		}
+	}
+	notifier.HandleUserEvent(ctx, userEvent(t, "e2", "user.updated", ada))
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("got %d emails want 1, redelivered and silent events send none", len(sender.sent))
+	}
+	if email := sender.sent[0]; email.From != "no-reply@example.com" || email.To != ada.Email ||
+		email.Subject != "Welcome!" || !strings.HasPrefix(email.Body, "Hi Ada,") || !strings.Contains(email.HTML, "<p>Hi Ada,</p>") {
+		t.Errorf("got %+v want the welcome email from no-reply to Ada", email)
+	}
+	deliveries := store.List("")
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryStatusSent || deliveries[0].Attempts != 1 || deliveries[0].SentAt == nil {
+		t.Errorf("got %+v want one sent delivery", deliveries)
+	}
+}
+
+func TestNotifier_RetryDue(t *testing.T) {
+	notifier, store, sender, clock := newTestNotifier(t, 2, 5)
+	ctx := context.Background()
+
+	notifier.HandleUserEvent(ctx, userEvent(t, "e1", "user.created", ada))
+	delivery := store.List("")[0]
+	if delivery.Status != DeliveryStatusPending || delivery.Attempts != 1 || delivery.LastError == "" {
+		t.Fatalf("got %+v want a pending delivery after a failure", delivery)
+	}
+	if want := clock.now.Add(time.Second); !delivery.NextAttemptAt.Equal(want) {
+		t.Errorf("got next attempt at %v want %v", delivery.NextAttemptAt, want)
+	}
+
+	if n := notifier.RetryDue(ctx); n != 0 {
+		t.Errorf("got %d attempts before the delay want 0", n)
+	}
+	clock.now = clock.now.Add(time.Second)
+	notifier.RetryDue(ctx)
+	delivery, _ = store.Get(delivery.ID)
+	if want := clock.now.Add(2 * time.Second); delivery.Attempts != 2 || !delivery.NextAttemptAt.Equal(want) {
+		t.Errorf("got %+v want a second attempt retried after 2s", delivery)
+	}
+
+	clock.now = clock.now.Add(2 * time.Second)
+	notifier.RetryDue(ctx)
+	delivery, _ = store.Get(delivery.ID)
+	if delivery.Status != DeliveryStatusSent || delivery.Attempts != 3 || delivery.LastError != "" || len(sender.sent) != 1 {
+		t.Errorf("got %+v want a delivery sent at the third attempt", delivery)
+	}
+}
+
+func TestNotifier_GivesUp(t *testing.T) {
+	notifier, store, sender, clock := newTestNotifier(t, 3, 2)
+	ctx := context.Background()
+
+	notifier.HandleUserEvent(ctx, userEvent(t, "e1", "user.suspended", ada))
+	clock.now = clock.now.Add(time.Minute)
+	notifier.RetryDue(ctx)
+
+	delivery := store.List("")[0]
+	if delivery.Status != DeliveryStatusFailed || delivery.Attempts != 2 {
+		t.Fatalf("got %+v want a failed delivery after 2 attempts", delivery)
+	}
+	if n := notifier.RetryDue(ctx); n != 0 {
+		t.Errorf("got %d attempts of a failed delivery want 0", n)
+	}
+
+	// A manual retry gets one more attempt, which fails again
+	if delivery, err := notifier.Retry(ctx, delivery.ID); err != nil || delivery.Status != DeliveryStatusFailed || delivery.Attempts != 3 {
+		t.Errorf("got %+v, %v want a third failed attempt", delivery, err)
+	}
+	if delivery, err := notifier.Retry(ctx, delivery.ID); err != nil || delivery.Status != DeliveryStatusSent {
+		t.Errorf("got %+v, %v want a sent delivery", delivery, err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("got %d emails want 1", len(sender.sent))
+	}
+	if _, err := notifier.Retry(ctx, delivery.ID); !errors.Is(err, ErrDeliveryBusy) {
+		t.Errorf("got %v want %v", err, ErrDeliveryBusy)
+	}
+	if _, err := notifier.Retry(ctx, "missing"); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("got %v want %v", err, ErrDeliveryNotFound)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{Delay: time.Second, MaxDelay: 5 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := policy.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) got %v want %v", attempts, got, want)
+		}
+	}
+}
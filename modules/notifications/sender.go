@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email is an email to one recipient, with a plain text body and an
+// optional HTML alternative
+type Email struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Sender delivers emails. Implementations return an error when the email
+// may not have been delivered, so it is retried.
+type Sender interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// LogSender "sends" emails by logging them, for development
+type LogSender struct{}
+
+// Send logs the email
+func (LogSender) Send(ctx context.Context, email Email) error {
+	slog.InfoContext(ctx, "Sending email", "from", email.From, "to", email.To, "subject", email.Subject, "body", email.Body, "html_bytes", len(email.HTML))
+	return nil
+}
+
+// SMTPSender sends emails through an SMTP server
+type SMTPSender struct {
+	// Addr is the host:port of the server
+	Addr string
+	// Username and Password authenticate with PLAIN auth when Username is set.
+	// net/smtp only sends them over TLS or to localhost.
+	Username string
+	Password string
+}
+
+// Send sends the email with the SMTP protocol
+func (s SMTPSender) Send(ctx context.Context, email Email) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := strings.Cut(s.Addr, ":")
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	if err := smtp.SendMail(s.Addr, auth, email.From, []string{email.To}, formatMessage(email, time.Now())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// formatMessage formats the email as an RFC 5322 message: plain text, or
+// multipart/alternative with quoted-printable parts when it has an HTML body
+func formatMessage(email Email, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", email.From)
+	fmt.Fprintf(&b, "To: %s\r\n", email.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", email.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if email.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(crlf(email.Body))
+		return b.Bytes()
+	}
+
+	parts := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n", parts.Boundary())
+	b.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.Body},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		w, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(crlf(part.body)))
+		qp.Close()
+	}
+	parts.Close()
+	return b.Bytes()
+}
+
+// crlf ends the lines of s with CRLF, as required by the SMTP protocol
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
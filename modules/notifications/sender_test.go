@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMessage(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := string(formatMessage(Email{From: "a@example.com", To: "b@example.com", Subject: "Hi", Body: "line 1\nline 2"}, date))
+
+	for _, want := range []string{
+		"From: a@example.com\r\n",
+		"To: b@example.com\r\n",
+		"Subject: Hi\r\n",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message %q is missing %q", got, want)
+		}
+	}
+}
+
+func TestFormatMessage_HTML(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	email := Email{From: "a@example.com", To: "b@example.com", Subject: "Hi", Body: "line 1\nline 2", HTML: "<p>Caf\u00e9</p>"}
+	message, err := mail.ReadMessage(bytes.NewReader(formatMessage(email, date)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got Content-Type %q want multipart/alternative", message.Header.Get("Content-Type"))
+	}
+
+	var got []string
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart() // decodes quoted-printable
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		got = append(got, part.Header.Get("Content-Type")+": "+string(body))
+	}
+	want := []string{"text/plain; charset=utf-8: line 1\r\nline 2", "text/html; charset=utf-8: <p>Caf\u00e9</p>"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got parts %q want %q", got, want)
+	}
+}
+
+// fakeSMTPServer accepts one SMTP session and returns the received message
+func fakeSMTPServer(t *testing.T) (addr string, received <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 fake ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					messages <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 fake")
+			case command == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+
+	err := SMTPSender{Addr: addr}.Send(context.Background(), Email{From: "a@example.com", To: "b@example.com", Subject: "Hello", Body: "Hi"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	select {
+	case message := <-received:
+		if !strings.Contains(message, "Subject: Hello\r\n") || !strings.HasSuffix(message, "\r\n\r\nHi\r\n") {
+			t.Errorf("got message %q want the subject and body", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server received no message")
+	}
+}
+
+func TestSMTPSender_Unreachable(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+
+	if err := (SMTPSender{Addr: addr}).Send(context.Background(), Email{From: "a@example.com", To: "b@example.com"}); err == nil {
+		t.Error("got nil error want a connection error")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sesSendPath is the SES v2 SendEmail operation
+const sesSendPath = "/v2/email/outbound-emails"
+
+// SESSender sends emails with the Amazon SES v2 API, signing requests with
+// AWS Signature Version 4
+type SESSender struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Endpoint overrides https://email.<region>.amazonaws.com, e.g. for a
+	// local SES emulator
+	Endpoint string
+	Client   *http.Client
+	now      func() time.Time
+}
+
+// sesSendRequest is the body of the SendEmail operation
+type sesSendRequest struct {
+	FromEmailAddress string         `json:"FromEmailAddress"`
+	Destination      sesDestination `json:"Destination"`
+	Content          sesContent     `json:"Content"`
+}
+
+type sesDestination struct {
+	ToAddresses []string `json:"ToAddresses"`
+}
+
+type sesContent struct {
+	Simple sesMessage `json:"Simple"`
+}
+
+type sesMessage struct {
+	Subject sesText `json:"Subject"`
+	Body    struct {
+		Text sesText  `json:"Text"`
+		Html *sesText `json:"Html,omitempty"`
+	} `json:"Body"`
+}
+
+type sesText struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset,omitempty"`
+}
+
+// Send sends the email with the SendEmail operation
+func (s SESSender) Send(ctx context.Context, email Email) error {
+	message := sesMessage{Subject: sesText{Data: email.Subject, Charset: "UTF-8"}}
+	message.Body.Text = sesText{Data: email.Body, Charset: "UTF-8"}
+	if email.HTML != "" {
+		message.Body.Html = &sesText{Data: email.HTML, Charset: "UTF-8"}
+	}
+	body, err := json.Marshal(sesSendRequest{
+		FromEmailAddress: email.From,
+		Destination:      sesDestination{ToAddresses: []string{email.To}},
+		Content:          sesContent{Simple: message},
+	})
+	if err != nil {
+		return fmt.Errorf("ses: %w", err)
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", s.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+sesSendPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ses: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, body, now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ses: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ses: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers of the ses service to req
+func (s SESSender) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	const signedHeaders = "content-type;host;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		(&url.URL{Path: req.URL.Path}).EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type") + "\n" +
+			"host:" + req.URL.Host + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/ses/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{date, s.Region, "ses", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSESSender(t *testing.T) {
+	var got sesSendRequest
+	var authorization, amzDate string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != sesSendPath {
+			t.Errorf("got %s %s want POST %s", r.Method, r.URL.Path, sesSendPath)
+		}
+		authorization, amzDate = r.Header.Get("Authorization"), r.Header.Get("X-Amz-Date")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"MessageId":"1"}`))
+	}))
+	defer server.Close()
+
+	sender := SESSender{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        server.URL,
+		now:             func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	if err := sender.Send(context.Background(), Email{From: "a@example.com", To: "b@example.com", Subject: "Hello", Body: "Hi", HTML: "<p>Hi</p>"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got.FromEmailAddress != "a@example.com" || len(got.Destination.ToAddresses) != 1 || got.Destination.ToAddresses[0] != "b@example.com" ||
+		got.Content.Simple.Subject.Data != "Hello" || got.Content.Simple.Body.Text.Data != "Hi" ||
+		got.Content.Simple.Body.Html == nil || got.Content.Simple.Body.Html.Data != "<p>Hi</p>" {
+		t.Errorf("got request %+v want the email", got)
+	}
+	if amzDate != "20240102T030405Z" {
+		t.Errorf("got X-Amz-Date %q want %q", amzDate, "20240102T030405Z")
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20240102/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature="
+	if !strings.HasPrefix(authorization, wantPrefix) || len(authorization) != len(wantPrefix)+64 {
+		t.Errorf("got Authorization %q want %q followed by a signature", authorization, wantPrefix)
+	}
+}
+
+func TestSESSender_Sign(t *testing.T) {
+	sender := SESSender{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	signature := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, "https://email.us-east-1.amazonaws.com"+sesSendPath, nil)
+		req.Header.Set("Content-Type", "application/json")
+		sender.sign(req, []byte(body), now)
+		return req.Header.Get("Authorization")
+	}
+
+	if signature("{}") != signature("{}") {
+		t.Error("signatures of the same request differ")
+	}
+	if signature("{}") == signature(`{"a":1}`) {
+		t.Error("signatures of different bodies are equal")
+	}
+}
+
+func TestSESSender_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Email address is not verified"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sender := SESSender{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: server.URL}
+	err := sender.Send(context.Background(), Email{From: "a@example.com", To: "b@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "not verified") {
+		t.Errorf("got %v want the error of SES", err)
+	}
+}
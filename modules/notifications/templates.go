@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+// layoutTemplate wraps the content of every HTML email
+const layoutTemplate = "layout.html"
+
+// embeddedTemplates are the email templates built into the binary
+//
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// ErrTemplateNotFound is returned when rendering a template that does not exist
+var ErrTemplateNotFound = errors.New("template not found")
+
+// TemplateData is what email templates are executed with
+type TemplateData struct {
+	User      recipient
+	EventID   string
+	EventType string
+	Time      time.Time
+}
+
+// Rendered is an email rendered from a template
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// emailTemplate is the pair of templates of an email: <name>.txt defines the
+// subject and the plain text body, <name>.html the content of the HTML body
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer renders emails from the templates of a directory. With reload,
+// the templates are parsed again before every render so edits show up
+// without a restart; an invalid edit keeps the previous templates.
+type Renderer struct {
+	source    fs.FS
+	reload    bool
+	mutex     sync.RWMutex
+	templates map[string]emailTemplate
+}
+
+// NewRenderer creates a renderer of the templates of source, failing when
+// they do not parse
+func NewRenderer(source fs.FS, reload bool) (*Renderer, error) {
+	r := &Renderer{source: source, reload: reload}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// EmbeddedTemplates returns the templates built into the binary
+func EmbeddedTemplates() fs.FS {
+	templates, _ := fs.Sub(embeddedTemplates, "templates")
+	return templates
+}
+
+// Reload parses the templates again. The previous templates are kept when
+// the new ones do not parse.
+func (r *Renderer) Reload() error {
+	templates, err := parseTemplates(r.source)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.templates = templates
+	return nil
+}
+
+// Names returns the names of the templates in order
+func (r *Renderer) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the template name with data
+func (r *Renderer) Render(name string, data TemplateData) (Rendered, error) {
+	if r.reload {
+		if err := r.Reload(); err != nil {
+			slog.Warn("Invalid email templates, keeping the previous ones", "error", err)
+		}
+	}
+	r.mutex.RLock()
+	tmpl, ok := r.templates[name]
+	r.mutex.RUnlock()
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, fmt.Errorf("rendering %s subject: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Rendered{}, fmt.Errorf("rendering %s text: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, layoutTemplate, data); err != nil {
+		return Rendered{}, fmt.Errorf("rendering %s html: %w", name, err)
+	}
+	return Rendered{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// parseTemplates parses every email template of source: each <name>.html
+// other than the layout along with its <name>.txt
+func parseTemplates(source fs.FS) (map[string]emailTemplate, error) {
+	files, err := fs.Glob(source, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]emailTemplate)
+	for _, file := range files {
+		if file == layoutTemplate {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		html, err := htmltemplate.New(layoutTemplate).Option("missingkey=error").ParseFS(source, layoutTemplate, file)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		if html.Lookup("content") == nil {
+			return nil, fmt.Errorf("parsing %s: no content template defined", file)
+		}
+		text, err := texttemplate.New(name+".txt").Option("missingkey=error").ParseFS(source, name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s.txt: %w", name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("parsing %s.txt: no subject template defined", name)
+		}
+		templates[name] = emailTemplate{text: text, html: html}
+	}
+	if len(templates) == 0 {
+		return nil, errors.New("no email templates found")
+	}
+	return templates, nil
+}
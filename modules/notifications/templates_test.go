@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testRenderer creates a renderer of the embedded templates
+func testRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	renderer, err := NewRenderer(EmbeddedTemplates(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return renderer
+}
+
+// testTemplates is a template directory with a greeting template
+func testTemplates(subject string) fstest.MapFS {
+	return fstest.MapFS{
+		"layout.html":   {Data: []byte(`<body>{{template "content" .}}</body>`)},
+		"greeting.html": {Data: []byte(`{{define "content"}}<p>Hi {{.User.Name}}</p>{{end}}`)},
+		"greeting.txt":  {Data: []byte(`{{define "subject"}}` + subject + `{{end}}Hi {{.User.Name}}`)},
+	}
+}
+
+func TestRenderer_Render(t *testing.T) {
+	renderer := testRenderer(t)
+	data := TemplateData{
+		User: recipient{ID: "1", Name: "<Ada>", Email: "ada@example.com"},
+		Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	for _, eventType := range userEventTypes {
+		t.Run(eventType, func(t *testing.T) {
+			got, err := renderer.Render(templateNames[eventType], data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got.Subject == "" || !strings.HasPrefix(got.Text, "Hi <Ada>,") || !strings.Contains(got.Text, data.User.Email) {
+				t.Errorf("got subject %q and text %q want the greeting of Ada unescaped", got.Subject, got.Text)
+			}
+			if !strings.Contains(got.HTML, "<p>Hi &lt;Ada&gt;,</p>") || !strings.Contains(got.HTML, data.User.Email) {
+				t.Errorf("got html %q want the greeting of Ada escaped", got.HTML)
+			}
+		})
+	}
+
+	got, _ := renderer.Render("password_changed", data)
+	if want := "2 January 2024 at 03:04 UTC"; !strings.Contains(got.Text, want) || !strings.Contains(got.HTML, want) {
+		t.Errorf("got %+v want the time of the change %q", got, want)
+	}
+	if _, err := renderer.Render("missing", data); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("got error %v want %v", err, ErrTemplateNotFound)
+	}
+}
+
+func TestNewRenderer_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(fstest.MapFS)
+	}{
+		{"empty", func(fs fstest.MapFS) { clear(fs) }},
+		{"syntax error", func(fs fstest.MapFS) { fs["greeting.html"].Data = []byte(`{{define "content"}}{{.User.Name}`) }},
+		{"no text", func(fs fstest.MapFS) { delete(fs, "greeting.txt") }},
+		{"no subject", func(fs fstest.MapFS) { fs["greeting.txt"].Data = []byte(`Hi`) }},
+		{"no content", func(fs fstest.MapFS) { fs["greeting.html"].Data = []byte(`<p>Hi</p>`) }},
+		{"no layout", func(fs fstest.MapFS) { delete(fs, "layout.html") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates := testTemplates("Hello")
+			tt.change(templates)
+			if _, err := NewRenderer(templates, false); err == nil {
+				t.Error("got nil error want invalid templates")
+			}
+		})
+	}
+}
+
+func TestRenderer_Reload(t *testing.T) {
+	data := TemplateData{User: recipient{Name: "Ada"}}
+	subject := func(r *Renderer) string {
+		t.Helper()
+		got, err := r.Render("greeting", data)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		return got.Subject
+	}
+
+	templates := testTemplates("Hello")
+	static, _ := NewRenderer(templates, false)
+	reloading, _ := NewRenderer(templates, true)
+
+	templates["greeting.txt"].Data = []byte(`{{define "subject"}}Howdy{{end}}Hi {{.User.Name}}`)
+	if got := subject(static); got != "Hello" {
+		t.Errorf("got %q want %q without reload", got, "Hello")
+	}
+	if got := subject(reloading); got != "Howdy" {
+		t.Errorf("got %q want %q with reload", got, "Howdy")
+	}
+
+	templates["greeting.txt"].Data = []byte(`{{define "subject"}}Broken{{end`)
+	if got := subject(reloading); got != "Howdy" {
+		t.Errorf("got %q want the last valid subject %q", got, "Howdy")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/contract"
+)
+
+// TestConsumerContracts checks the order events against the fields the
+// other modules declare they read from them
+func TestConsumerContracts(t *testing.T) {
+	contracts, err := contract.LoadFor("..", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) == 0 {
+		t.Fatal("got no contracts want the ones of the consumers of order events")
+	}
+
+	service, _, publisher := newTestService()
+	ctx := context.Background()
+	order, err := service.Place(ctx, "alice", oneItem)
+	if err != nil {
+		t.Fatalf("Place() error = %v", err)
+	}
+	if _, err := service.Cancel(ctx, order.ID, "out of stock"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	contract.Verify(t, contracts, publisher.Events())
+}
+
+// TestUserEventsContract checks that the fields of user events declared in
+// contracts/foundation.json are all the orders service needs
+func TestUserEventsContract(t *testing.T) {
+	c, err := contract.Load(filepath.Join("contracts", "foundation.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, customers, _ := newTestService()
+	handler := UserEventHandler(customers, service)
+	ctx := context.Background()
+
+	for _, example := range c.Examples() {
+		if err := handler(ctx, example); err != nil {
+			t.Errorf("handler(%s) error = %v", example.Type, err)
+		}
+		customer, cached := customers.Get("example")
+		if deleted := example.Type == "user.deleted"; cached == deleted {
+			t.Errorf("after %s got cached %v want %v", example.Type, cached, !deleted)
+		}
+		if cached && (customer.Email != "example" || customer.Version != 1 || customer.UpdatedAt.IsZero()) {
+			t.Errorf("after %s got customer %+v want the example user", example.Type, customer)
+		}
+	}
+}
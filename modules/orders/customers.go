@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// customerStatusSuspended is the status of users who may not place orders
+const customerStatusSuspended = "suspended"
+
+// Customer is the part of a user of the foundation service the orders
+// service needs, kept up to date from user events
+type Customer struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CanOrder reports whether the customer may place orders
+func (c Customer) CanOrder() bool {
+	return c.Status != customerStatusSuspended
+}
+
+// CustomerCache is the local copy of the customers, so orders are placed
+// without calling the foundation service
+type CustomerCache struct {
+	mutex     sync.RWMutex
+	customers map[string]Customer
+}
+
+// NewCustomerCache creates an empty cache
+func NewCustomerCache() *CustomerCache {
+	return &CustomerCache{customers: make(map[string]Customer)}
+}
+
+// Put stores the customer, unless the cache holds a later version of it.
+// It reports whether the customer was stored.
+func (c *CustomerCache) Put(customer Customer) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if current, ok := c.customers[customer.ID]; ok && current.Version > customer.Version {
+		return false
+	}
+	c.customers[customer.ID] = customer
+	return true
+}
+
+// Remove removes the customer with id and reports whether it was cached
+func (c *CustomerCache) Remove(id string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.customers[id]
+	delete(c.customers, id)
+	return ok
+}
+
+// Get returns the customer with id
+func (c *CustomerCache) Get(id string) (Customer, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	customer, ok := c.customers[id]
+	return customer, ok
+}
+
+// List returns the cached customers sorted by ID
+func (c *CustomerCache) List() []Customer {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	customers := make([]Customer, 0, len(c.customers))
+	for _, customer := range c.customers {
+		customers = append(customers, customer)
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+	return customers
+}
+
+// Len returns the number of cached customers
+func (c *CustomerCache) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.customers)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestCustomerCache(t *testing.T) {
+	cache := NewCustomerCache()
+
+	if !cache.Put(Customer{ID: "b", Name: "Bob", Version: 2}) {
+		t.Fatal("Put() of a new customer got false want true")
+	}
+	if cache.Put(Customer{ID: "b", Name: "Stale", Version: 1}) {
+		t.Error("Put() of an older version got true want false")
+	}
+	if !cache.Put(Customer{ID: "b", Name: "Robert", Version: 3}) {
+		t.Error("Put() of a newer version got false want true")
+	}
+	cache.Put(Customer{ID: "a", Name: "Alice", Version: 1})
+
+	if got, _ := cache.Get("b"); got.Name != "Robert" {
+		t.Errorf("got name %q want %q", got.Name, "Robert")
+	}
+	list := cache.List()
+	if len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Errorf("got %+v want customers a and b", list)
+	}
+
+	if !cache.Remove("a") || cache.Remove("a") {
+		t.Error("Remove() should report only the removal of a cached customer")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("got %d customers want 1", cache.Len())
+	}
+}
+
+func TestCustomer_CanOrder(t *testing.T) {
+	for status, want := range map[string]bool{"pending": true, "active": true, "suspended": false} {
+		if got := (Customer{Status: status}).CanOrder(); got != want {
+			t.Errorf("CanOrder() of a %s customer got %v want %v", status, got, want)
+		}
+	}
+}
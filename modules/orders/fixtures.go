@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/fixtures"
+)
+
+// embeddedFixtures holds the fixture files of each environment, such as
+// fixtures/demo/orders.yaml
+//
+//go:embed fixtures
+var embeddedFixtures embed.FS
+
+// OrderFixture is a demo order of the fixture files, placed for the customer
+// with the email once the user is created
+type OrderFixture struct {
+	CustomerEmail string      `json:"customer_email"`
+	Items         []OrderItem `json:"items"`
+}
+
+// loadOrderFixtures loads the demo orders of the environment env (FIXTURES).
+// Environments without order fixtures get the default ones, which are none.
+func loadOrderFixtures(env string) ([]OrderFixture, error) {
+	fsys, _ := fs.Sub(embeddedFixtures, "fixtures")
+	orders, err := fixtures.Load[OrderFixture](fsys, env, "orders")
+	if err != nil {
+		return nil, err
+	}
+	for i, order := range orders {
+		if order.CustomerEmail == "" {
+			return nil, fmt.Errorf("orders[%d]: customer_email is required", i)
+		}
+		// The items are checked against the invariants of the aggregate
+		if _, err := NewOrder("fixture", "fixture", order.Items, time.Time{}); err != nil {
+			return nil, fmt.Errorf("orders[%d] (%s): %w", i, order.CustomerEmail, err)
+		}
+	}
+	return orders, nil
+}
+
+// SeedFixtureOrders wraps the user event handler next, placing the fixture
+// orders of each customer once next has handled their user.created event
+func SeedFixtureOrders(orderFixtures []OrderFixture, orders *OrderService, next events.Handler) events.Handler {
+	byEmail := make(map[string][]OrderFixture)
+	for _, fixture := range orderFixtures {
+		byEmail[fixture.CustomerEmail] = append(byEmail[fixture.CustomerEmail], fixture)
+	}
+
+	return func(ctx context.Context, event events.Event) error {
+		if err := next(ctx, event); err != nil || event.Type != "user.created" {
+			return err
+		}
+		var data userEventData
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		customerID := data.User.ID
+		if customerID == "" {
+			customerID = event.Subject
+		}
+		for _, fixture := range byEmail[data.User.Email] {
+			order, err := orders.Place(ctx, customerID, fixture.Items)
+			if err != nil {
+				slog.WarnContext(ctx, "Skipping fixture order", "customer_id", customerID, "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "Placed fixture order", "order_id", order.ID, "customer_id", customerID)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events/eventstest"
+)
+
+func TestLoadOrderFixtures(t *testing.T) {
+	for env, want := range map[string]int{"default": 0, "demo": 3, "empty": 0} {
+		orders, err := loadOrderFixtures(env)
+		if err != nil {
+			t.Fatalf("loadOrderFixtures(%s) error = %v", env, err)
+		}
+		if len(orders) != want {
+			t.Errorf("loadOrderFixtures(%s) got %d orders want %d", env, len(orders), want)
+		}
+	}
+}
+
+func TestSeedFixtureOrders(t *testing.T) {
+	service, customers, publisher := newTestService()
+	fixtures := []OrderFixture{
+		{CustomerEmail: "dave@example.com", Items: oneItem},
+		{CustomerEmail: "dave@example.com", Items: []OrderItem{{SKU: "mug", Quantity: 2, UnitPriceCents: 800}}},
+		{CustomerEmail: "erin@example.com", Items: oneItem},
+	}
+	handler := SeedFixtureOrders(fixtures, service, UserEventHandler(customers, service))
+	ctx := context.Background()
+
+	dave := Customer{ID: "dave", Email: "dave@example.com", Status: "active", Version: 1}
+	if err := handler(ctx, userEvent(t, "user.created", dave)); err != nil {
+		t.Fatalf("handler(user.created) error = %v", err)
+	}
+	if got := service.List("dave"); len(got) != 2 || got[1].TotalCents != 1600 {
+		t.Fatalf("got orders %+v want the two fixture orders of dave", got)
+	}
+
+	// Other events and suspended customers get no orders
+	dave.Version = 2
+	handler(ctx, userEvent(t, "user.updated", dave))
+	handler(ctx, userEvent(t, "user.created", Customer{ID: "erin", Email: "erin@example.com", Status: "suspended", Version: 1}))
+	if got := len(service.List("")); got != 2 {
+		t.Errorf("got %d orders want only the ones of dave's creation", got)
+	}
+	eventstest.Expect(t, publisher).ToHave("*").Times(2)
+	eventstest.Expect(t, publisher).ToHave(EventTypeOrderPlaced).WithPayloadField("order.customer_id", "dave").Times(2)
+}
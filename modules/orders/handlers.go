@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// placeOrderRequest is the body of POST /orders
+type placeOrderRequest struct {
+	CustomerID string      `json:"customer_id"`
+	Items      []OrderItem `json:"items"`
+}
+
+// cancelOrderRequest is the optional body of POST /orders/{id}/cancel
+type cancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// OrderHandler serves the HTTP API of the orders service
+type OrderHandler struct {
+	orders    *OrderService
+	customers *CustomerCache
+	mux       *http.ServeMux
+}
+
+// NewOrderHandler creates the handler of the orders and customers routes
+func NewOrderHandler(orders *OrderService, customers *CustomerCache) *OrderHandler {
+	h := &OrderHandler{orders: orders, customers: customers, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /orders", h.handleListOrders)
+	h.mux.HandleFunc("POST /orders", h.handlePlaceOrder)
+	h.mux.HandleFunc("GET /orders/{id}", h.handleGetOrder)
+	h.mux.HandleFunc("POST /orders/{id}/cancel", h.handleCancelOrder)
+	h.mux.HandleFunc("GET /customers", h.handleListCustomers)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *OrderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleListOrders lists the orders, of one customer with ?customer_id=
+func (h *OrderHandler) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.orders.List(r.URL.Query().Get("customer_id")))
+}
+
+// handlePlaceOrder places an order
+func (h *OrderHandler) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
+	var req placeOrderRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	order, err := h.orders.Place(r.Context(), req.CustomerID, req.Items)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/orders/"+order.ID)
+	httpx.WriteJSON(w, http.StatusCreated, order)
+}
+
+// handleGetOrder returns an order
+func (h *OrderHandler) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := h.orders.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, order)
+}
+
+// handleCancelOrder cancels an order, for the reason in the body if any
+func (h *OrderHandler) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	var req cancelOrderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+			return
+		}
+	}
+
+	order, err := h.orders.Cancel(r.Context(), r.PathValue("id"), req.Reason)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, order)
+}
+
+// handleListCustomers lists the customers known from user events
+func (h *OrderHandler) handleListCustomers(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.customers.List())
+}
+
+// writeError writes the problem matching a service error
+func (h *OrderHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr), errors.Is(err, ErrUnknownCustomer):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrOrderNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrOrderAlreadyCanceled), errors.Is(err, ErrCustomerSuspended):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
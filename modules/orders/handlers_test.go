@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+func TestOrderHandler(t *testing.T) {
+	service, customers, _ := newTestService()
+	handler := NewOrderHandler(service, customers)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/orders", `{"customer_id":"alice","items":[{"sku":"book","quantity":2,"unit_price_cents":500}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got status %d want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var order Order
+	if err := json.NewDecoder(rec.Body).Decode(&order); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Location"); got != "/orders/"+order.ID {
+		t.Errorf("got Location %q want /orders/%s", got, order.ID)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"get order", http.MethodGet, "/orders/" + order.ID, "", http.StatusOK},
+		{"missing order", http.MethodGet, "/orders/missing", "", http.StatusNotFound},
+		{"list orders of customer", http.MethodGet, "/orders?customer_id=alice", "", http.StatusOK},
+		{"invalid JSON", http.MethodPost, "/orders", "{", http.StatusBadRequest},
+		{"invalid order", http.MethodPost, "/orders", `{"customer_id":"alice"}`, http.StatusUnprocessableEntity},
+		{"unknown customer", http.MethodPost, "/orders", `{"customer_id":"nobody","items":[{"sku":"a","quantity":1}]}`, http.StatusUnprocessableEntity},
+		{"suspended customer", http.MethodPost, "/orders", `{"customer_id":"bob","items":[{"sku":"a","quantity":1}]}`, http.StatusConflict},
+		{"cancel", http.MethodPost, "/orders/" + order.ID + "/cancel", `{"reason":"too slow"}`, http.StatusOK},
+		{"cancel again", http.MethodPost, "/orders/" + order.ID + "/cancel", "", http.StatusConflict},
+		{"customers", http.MethodGet, "/customers", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.target, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code >= 400 && rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("got Content-Type %q want %q", rec.Header().Get("Content-Type"), problem.ContentType)
+			}
+		})
+	}
+
+	rec = do(http.MethodGet, "/orders?customer_id=alice", "")
+	var orders []Order
+	if err := json.NewDecoder(rec.Body).Decode(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Status != OrderStatusCancelled || orders[0].CancelReason != "too slow" {
+		t.Errorf("got %+v want the cancelled order", orders)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/fixtures"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort          = "8081"
+	defaultHost          = "localhost"
+	defaultUserEventsURL = "http://localhost:8080/events"
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	userEventsURL := config.Getenv("USER_EVENTS_URL", defaultUserEventsURL)
+	if u, err := url.Parse(userEventsURL); err != nil || u.Host == "" {
+		fatal("Invalid USER_EVENTS_URL", "url", userEventsURL)
+	}
+
+	// Orders publish their events on a local bus, streamed at GET /events
+	bus := events.NewBus()
+	eventStream := events.NewStream(bus)
+	customers := NewCustomerCache()
+	orders := NewOrderService(customers, uuid.GeneratorFunc(uuid.NewGoogle), bus)
+
+	// Demo orders of the environment are placed as their customers are created
+	orderFixtures, err := loadOrderFixtures(config.Getenv("FIXTURES", fixtures.DefaultEnvironment))
+	if err != nil {
+		fatal("Invalid FIXTURES", "error", err)
+	}
+
+	// Follow the user events of the foundation service to know the customers
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	subscriber := &events.Subscriber{URL: userEventsURL, Types: userEventTypes}
+	if token := os.Getenv("USER_EVENTS_TOKEN"); token != "" {
+		subscriber.Header = http.Header{"Authorization": {"Bearer " + token}}
+	} else {
+		slog.Warn("USER_EVENTS_TOKEN is not set: the user event stream may refuse the connection")
+	}
+	go subscriber.Run(ctx, SeedFixtureOrders(orderFixtures, orders, UserEventHandler(customers, orders)))
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewOrderHandler(orders, customers)
+	mux.Handle("/orders", api)
+	mux.Handle("/orders/", api)
+	mux.Handle("/customers", api)
+	mux.Handle("/events", eventStream)
+	mux.HandleFunc("/health", healthHandler(customers))
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	server.RegisterOnShutdown(eventStream.Close)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting orders service", "url", fmt.Sprintf("http://%s:%s", host, port), "user_events", userEventsURL)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Orders service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down orders service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Orders service forced to shutdown", "error", err)
+	}
+	slog.Info("Orders service exited")
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "orders",
+		"endpoints": map[string]string{
+			"orders":    "/orders",
+			"customers": "/customers",
+			"events":    "/events",
+			"health":    "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy along with the number of
+// customers known from user events
+func healthHandler(customers *CustomerCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    "healthy",
+			"customers": customers.Len(),
+		})
+	}
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+// OrderStatus is the state of an order in its lifecycle
+type OrderStatus string
+
+const (
+	// OrderStatusPlaced orders wait to be fulfilled
+	OrderStatusPlaced OrderStatus = "placed"
+	// OrderStatusCancelled orders are final
+	OrderStatusCancelled OrderStatus = "cancelled"
+)
+
+// Types of the events recorded by the order aggregate
+const (
+	EventTypeOrderPlaced    = "order.placed"
+	EventTypeOrderCancelled = "order.cancelled"
+)
+
+// Errors of the order aggregate
+var (
+	ErrOrderNotFound        = errors.New("order not found")
+	ErrOrderAlreadyCanceled = errors.New("order is already cancelled")
+)
+
+// OrderItem is a line of an order: a quantity of a product at a unit price
+type OrderItem struct {
+	SKU            string `json:"sku"`
+	Quantity       int    `json:"quantity"`
+	UnitPriceCents int64  `json:"unit_price_cents"`
+}
+
+// Order is the aggregate root of the orders service. Its customer is a user
+// of the foundation service, known here through the customer cache. Each
+// change records an event carrying a snapshot of the order.
+type Order struct {
+	domain.AggregateRoot
+	CustomerID   string      `json:"customer_id"`
+	Items        []OrderItem `json:"items"`
+	TotalCents   int64       `json:"total_cents"`
+	Status       OrderStatus `json:"status"`
+	CancelReason string      `json:"cancel_reason,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// OrderEventData is the payload of order events: a snapshot of the order
+// after the change
+type OrderEventData struct {
+	Order Order `json:"order"`
+}
+
+// NewOrder places an order of items for the customer, checking the invariants
+// of the aggregate: at least one item, each for a distinct product with a
+// positive quantity and a price that is not negative. Every invalid field is
+// reported at once. The order records order.placed.
+func NewOrder(id, customerID string, items []OrderItem, now time.Time) (*Order, error) {
+	var errs domain.ValidationErrors
+	errs.Check(domain.Required("customer_id", customerID))
+	if len(items) == 0 {
+		errs.Add("items", "must not be empty")
+	}
+
+	var total int64
+	skus := make(map[string]bool, len(items))
+	for i, item := range items {
+		field := fmt.Sprintf("items[%d]", i)
+		errs.Check(domain.Required(field+".sku", item.SKU))
+		if item.SKU != "" && skus[item.SKU] {
+			errs.Add(field+".sku", fmt.Sprintf("repeats %q, add up the quantities instead", item.SKU))
+		}
+		errs.Check(domain.Positive(field+".quantity", int64(item.Quantity)))
+		errs.Check(domain.NotNegative(field+".unit_price_cents", item.UnitPriceCents))
+		skus[item.SKU] = true
+		total += int64(item.Quantity) * item.UnitPriceCents
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	order := &Order{
+		AggregateRoot: domain.AggregateRoot{ID: id},
+		CustomerID:    customerID,
+		Items:         append([]OrderItem(nil), items...),
+		TotalCents:    total,
+		Status:        OrderStatusPlaced,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	order.record(EventTypeOrderPlaced)
+	return order, nil
+}
+
+// Cancel cancels the order for reason, recording order.cancelled
+func (o *Order) Cancel(reason string, now time.Time) error {
+	if o.Status == OrderStatusCancelled {
+		return ErrOrderAlreadyCanceled
+	}
+	o.Status = OrderStatusCancelled
+	o.CancelReason = reason
+	o.UpdatedAt = now
+	o.record(EventTypeOrderCancelled)
+	return nil
+}
+
+// record records an event of eventType carrying a snapshot of the order at
+// the version the event brings it to
+func (o *Order) record(eventType string) {
+	snapshot := *o
+	snapshot.ClearChanges()
+	snapshot.Version++
+	o.Record(eventType, OrderEventData{Order: snapshot})
+}
+
+// IsOpen reports whether the order can still change
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatusPlaced
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+func TestNewOrder(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		customer  string
+		items     []OrderItem
+		wantField string
+		wantTotal int64
+	}{
+		{"valid", "c1", []OrderItem{{SKU: "a", Quantity: 2, UnitPriceCents: 150}, {SKU: "b", Quantity: 1, UnitPriceCents: 99}}, "", 399},
+		{"free item", "c1", []OrderItem{{SKU: "a", Quantity: 1}}, "", 0},
+		{"missing customer", "", []OrderItem{{SKU: "a", Quantity: 1}}, "customer_id", 0},
+		{"no items", "c1", nil, "items", 0},
+		{"missing sku", "c1", []OrderItem{{Quantity: 1}}, "items[0].sku", 0},
+		{"repeated sku", "c1", []OrderItem{{SKU: "a", Quantity: 1}, {SKU: "a", Quantity: 1}}, "items[1].sku", 0},
+		{"zero quantity", "c1", []OrderItem{{SKU: "a"}}, "items[0].quantity", 0},
+		{"negative price", "c1", []OrderItem{{SKU: "a", Quantity: 1, UnitPriceCents: -1}}, "items[0].unit_price_cents", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := NewOrder("o1", tt.customer, tt.items, now)
+			if tt.wantField != "" {
+				var validationErr *domain.ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+					t.Fatalf("got error %v want a validation error of %s", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewOrder() error = %v", err)
+			}
+			if order.TotalCents != tt.wantTotal {
+				t.Errorf("got total %d want %d", order.TotalCents, tt.wantTotal)
+			}
+			if order.Status != OrderStatusPlaced || order.Version != 1 || !order.CreatedAt.Equal(now) {
+				t.Errorf("got %+v want a placed order at version 1", order)
+			}
+			if changes := order.Changes(); len(changes) != 1 || changes[0].Type != EventTypeOrderPlaced {
+				t.Errorf("got changes %+v want %s", changes, EventTypeOrderPlaced)
+			}
+		})
+	}
+}
+
+func TestNewOrder_ReportsEveryInvalidField(t *testing.T) {
+	_, err := NewOrder("o1", "", []OrderItem{{SKU: "a"}, {SKU: "a", Quantity: 1, UnitPriceCents: -1}}, time.Now())
+
+	var errs domain.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error %v want validation errors", err)
+	}
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	want := []string{"customer_id", "items[0].quantity", "items[1].sku", "items[1].unit_price_cents"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got invalid fields %v want %v", fields, want)
+	}
+}
+
+func TestOrder_Cancel(t *testing.T) {
+	now := time.Now()
+	order, err := NewOrder("o1", "c1", []OrderItem{{SKU: "a", Quantity: 1}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := order.Cancel("changed my mind", now.Add(time.Minute)); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if order.Status != OrderStatusCancelled || order.CancelReason != "changed my mind" || order.Version != 2 || order.IsOpen() {
+		t.Errorf("got %+v want a cancelled order at version 2", order)
+	}
+	if err := order.Cancel("again", now); !errors.Is(err, ErrOrderAlreadyCanceled) {
+		t.Errorf("got %v want %v", err, ErrOrderAlreadyCanceled)
+	}
+
+	changes := order.Changes()
+	if len(changes) != 2 || changes[1].Type != EventTypeOrderCancelled {
+		t.Fatalf("got changes %+v want %s after %s", changes, EventTypeOrderCancelled, EventTypeOrderPlaced)
+	}
+	if snapshot := changes[1].Data.(OrderEventData).Order; snapshot.Version != 2 || snapshot.Status != OrderStatusCancelled {
+		t.Errorf("got snapshot %+v want the cancelled order at version 2", snapshot)
+	}
+}
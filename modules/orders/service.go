@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies this service as the producer of its events
+const eventSource = "order-service"
+
+// Errors of the orders service
+var (
+	ErrUnknownCustomer   = errors.New("unknown customer")
+	ErrCustomerSuspended = errors.New("customer is suspended")
+)
+
+// OrderService places and cancels orders, publishing an event for every change
+type OrderService struct {
+	mutex     sync.RWMutex
+	orders    map[string]*Order
+	customers *CustomerCache
+	ids       uuid.IDGenerator
+	publisher events.Publisher
+	now       func() time.Time
+}
+
+// NewOrderService creates a service taking customers from the cache and
+// publishing its events with publisher
+func NewOrderService(customers *CustomerCache, ids uuid.IDGenerator, publisher events.Publisher) *OrderService {
+	return &OrderService{
+		orders:    make(map[string]*Order),
+		customers: customers,
+		ids:       ids,
+		publisher: publisher,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Place places an order of items for the customer, who must be known from
+// user events and not suspended
+func (s *OrderService) Place(ctx context.Context, customerID string, items []OrderItem) (*Order, error) {
+	customer, ok := s.customers.Get(customerID)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCustomer, customerID)
+	}
+	if !customer.CanOrder() {
+		return nil, fmt.Errorf("%w: %s", ErrCustomerSuspended, customerID)
+	}
+
+	order, err := NewOrder(s.ids.NewID(), customerID, items, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.orders[order.ID] = order
+	changes := takeChanges(order)
+	placed := *order
+	s.mutex.Unlock()
+
+	s.publish(ctx, order.ID, changes)
+	return &placed, nil
+}
+
+// Get returns the order with id
+func (s *OrderService) Get(id string) (*Order, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	order, ok := s.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	found := *order
+	return &found, nil
+}
+
+// List returns the orders, oldest first, of the customer or of every
+// customer when customerID is empty
+func (s *OrderService) List(customerID string) []Order {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	orders := make([]Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		if customerID == "" || order.CustomerID == customerID {
+			orders = append(orders, *order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+	return orders
+}
+
+// Cancel cancels the order with id for reason
+func (s *OrderService) Cancel(ctx context.Context, id, reason string) (*Order, error) {
+	s.mutex.Lock()
+	order, ok := s.orders[id]
+	if !ok {
+		s.mutex.Unlock()
+		return nil, ErrOrderNotFound
+	}
+	if err := order.Cancel(reason, s.now()); err != nil {
+		s.mutex.Unlock()
+		return nil, err
+	}
+	changes := takeChanges(order)
+	cancelled := *order
+	s.mutex.Unlock()
+
+	s.publish(ctx, id, changes)
+	return &cancelled, nil
+}
+
+// CancelCustomerOrders cancels every open order of the customer for reason
+// and returns how many were cancelled
+func (s *OrderService) CancelCustomerOrders(ctx context.Context, customerID, reason string) int {
+	s.mutex.Lock()
+	cancelled := make(map[string][]domain.DomainEvent)
+	for _, order := range s.orders {
+		if order.CustomerID == customerID && order.IsOpen() {
+			// Open orders can always be cancelled
+			_ = order.Cancel(reason, s.now())
+			cancelled[order.ID] = takeChanges(order)
+		}
+	}
+	s.mutex.Unlock()
+
+	for id, changes := range cancelled {
+		s.publish(ctx, id, changes)
+	}
+	return len(cancelled)
+}
+
+// takeChanges returns the events recorded by the order and clears them, so
+// each is published once
+func takeChanges(order *Order) []domain.DomainEvent {
+	changes := order.Changes()
+	order.ClearChanges()
+	return changes
+}
+
+// publish publishes the events recorded by the order with id, logging
+// failures: the change is made whether or not its event is delivered
+func (s *OrderService) publish(ctx context.Context, id string, changes []domain.DomainEvent) {
+	for _, change := range changes {
+		event, err := change.Envelope(s.ids.NewID(), eventSource, id)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to create event", "event_type", change.Type, "order_id", id, "error", err)
+			continue
+		}
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "Failed to publish event", "event_type", change.Type, "order_id", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/events/eventstest"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// newTestService creates a service knowing an active and a suspended customer
+func newTestService() (*OrderService, *CustomerCache, *eventstest.Recorder) {
+	customers := NewCustomerCache()
+	customers.Put(Customer{ID: "alice", Status: "active", Version: 1})
+	customers.Put(Customer{ID: "bob", Status: "suspended", Version: 1})
+	publisher := eventstest.NewRecorder()
+	return NewOrderService(customers, uuid.NewSequenceGenerator("id-"), publisher), customers, publisher
+}
+
+var oneItem = []OrderItem{{SKU: "book", Quantity: 1, UnitPriceCents: 1200}}
+
+func TestOrderService_Place(t *testing.T) {
+	service, _, publisher := newTestService()
+	ctx := context.Background()
+
+	order, err := service.Place(ctx, "alice", oneItem)
+	if err != nil {
+		t.Fatalf("Place() error = %v", err)
+	}
+	if _, err := service.Place(ctx, "nobody", oneItem); !errors.Is(err, ErrUnknownCustomer) {
+		t.Errorf("got %v want %v", err, ErrUnknownCustomer)
+	}
+	if _, err := service.Place(ctx, "bob", oneItem); !errors.Is(err, ErrCustomerSuspended) {
+		t.Errorf("got %v want %v", err, ErrCustomerSuspended)
+	}
+
+	expect := eventstest.Expect(t, publisher)
+	expect.ToHave("*").Once()
+	expect.ToHave(EventTypeOrderPlaced).
+		WithSubject(order.ID).
+		Matching("from "+eventSource, func(event events.Event) bool { return event.Source == eventSource }).
+		WithPayloadField("order.customer_id", "alice").
+		WithPayloadField("order.total_cents", 1200)
+
+	got, err := service.Get(order.ID)
+	if err != nil || got.CustomerID != "alice" {
+		t.Errorf("got %+v, %v want the order of alice", got, err)
+	}
+	if _, err := service.Get("missing"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("got %v want %v", err, ErrOrderNotFound)
+	}
+}
+
+func TestOrderService_Cancel(t *testing.T) {
+	service, _, publisher := newTestService()
+	ctx := context.Background()
+	order, _ := service.Place(ctx, "alice", oneItem)
+
+	cancelled, err := service.Cancel(ctx, order.ID, "out of stock")
+	if err != nil || cancelled.Status != OrderStatusCancelled {
+		t.Fatalf("got %+v, %v want a cancelled order", cancelled, err)
+	}
+	if _, err := service.Cancel(ctx, order.ID, ""); !errors.Is(err, ErrOrderAlreadyCanceled) {
+		t.Errorf("got %v want %v", err, ErrOrderAlreadyCanceled)
+	}
+	if _, err := service.Cancel(ctx, "missing", ""); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("got %v want %v", err, ErrOrderNotFound)
+	}
+
+	expect := eventstest.Expect(t, publisher)
+	expect.ToHave("*").Times(2)
+	expect.ToHaveInOrder(EventTypeOrderPlaced, EventTypeOrderCancelled)
+	expect.ToHave(EventTypeOrderCancelled).WithPayloadField("order.cancel_reason", "out of stock")
+}
+
+func TestOrderService_CancelCustomerOrders(t *testing.T) {
+	service, customers, publisher := newTestService()
+	ctx := context.Background()
+	customers.Put(Customer{ID: "carol", Status: "active", Version: 1})
+
+	first, _ := service.Place(ctx, "alice", oneItem)
+	service.Place(ctx, "alice", oneItem)
+	service.Place(ctx, "carol", oneItem)
+	service.Cancel(ctx, first.ID, "")
+	publisher.Reset()
+
+	if n := service.CancelCustomerOrders(ctx, "alice", cancelReasonCustomerDeleted); n != 1 {
+		t.Errorf("got %d cancelled orders want 1", n)
+	}
+	for _, order := range service.List("alice") {
+		if order.IsOpen() {
+			t.Errorf("order %s of alice is still open", order.ID)
+		}
+	}
+	if open := service.List("carol"); len(open) != 1 || !open[0].IsOpen() {
+		t.Errorf("got %+v want the open order of carol", open)
+	}
+	expect := eventstest.Expect(t, publisher)
+	expect.ToHave("*").Once()
+	expect.ToHave(EventTypeOrderCancelled).WithPayloadField("order.customer_id", "alice")
+	if got := len(service.List("")); got != 3 {
+		t.Errorf("got %d orders want 3", got)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// userEventTypes are the event types of the foundation service followed by
+// the orders service
+var userEventTypes = []string{"user.*"}
+
+// cancelReasonCustomerDeleted is the reason of orders cancelled because
+// their customer was deleted
+const cancelReasonCustomerDeleted = "customer deleted"
+
+// userEventData is the part of the payload of user events the orders
+// service reads: the snapshot of the user after the change
+type userEventData struct {
+	User Customer `json:"user"`
+}
+
+// UserEventHandler keeps customers up to date from the user events of the
+// foundation service. Deleted users are removed and their open orders
+// cancelled; every other user event stores the snapshot it carries.
+func UserEventHandler(customers *CustomerCache, orders *OrderService) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		var data userEventData
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		customer := data.User
+		if customer.ID == "" {
+			customer.ID = event.Subject
+		}
+
+		if event.Type == "user.deleted" {
+			customers.Remove(customer.ID)
+			if n := orders.CancelCustomerOrders(ctx, customer.ID, cancelReasonCustomerDeleted); n > 0 {
+				slog.InfoContext(ctx, "Cancelled orders of deleted customer", "customer_id", customer.ID, "orders", n)
+			}
+			return nil
+		}
+
+		if !customers.Put(customer) {
+			slog.DebugContext(ctx, "Skipping stale user event", "event_id", event.ID, "customer_id", customer.ID, "version", customer.Version)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/events/eventstest"
+	"github.com/captain-corgi/learning-event-driven/pkg/events/simulation"
+)
+
+// userEvent creates a user event like the ones of the foundation service
+func userEvent(t *testing.T, eventType string, user Customer) events.Event {
+	t.Helper()
+	event, err := events.New("evt-"+eventType, eventType, "user-service", user.ID, map[string]interface{}{"user": user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestUserEventHandler(t *testing.T) {
+	service, customers, publisher := newTestService()
+	handler := UserEventHandler(customers, service)
+	ctx := context.Background()
+
+	steps := []struct {
+		eventType string
+		user      Customer
+	}{
+		{"user.created", Customer{ID: "dave", Name: "Dave", Status: "pending", Version: 1}},
+		{"user.updated", Customer{ID: "dave", Name: "David", Status: "pending", Version: 2}},
+		{"user.updated", Customer{ID: "dave", Name: "Stale", Status: "pending", Version: 1}},
+	}
+	for _, step := range steps {
+		if err := handler(ctx, userEvent(t, step.eventType, step.user)); err != nil {
+			t.Fatalf("handler(%s) error = %v", step.eventType, err)
+		}
+	}
+	if got, ok := customers.Get("dave"); !ok || got.Name != "David" {
+		t.Fatalf("got %+v want David at version 2", got)
+	}
+
+	handler(ctx, userEvent(t, "user.suspended", Customer{ID: "dave", Status: "suspended", Version: 3}))
+	if _, err := service.Place(ctx, "dave", oneItem); err == nil {
+		t.Error("a suspended customer placed an order")
+	}
+	handler(ctx, userEvent(t, "user.activated", Customer{ID: "dave", Status: "active", Version: 4}))
+	order, err := service.Place(ctx, "dave", oneItem)
+	if err != nil {
+		t.Fatalf("Place() error = %v", err)
+	}
+
+	if err := handler(ctx, userEvent(t, "user.deleted", Customer{ID: "dave", Version: 4})); err != nil {
+		t.Fatalf("handler(user.deleted) error = %v", err)
+	}
+	if _, ok := customers.Get("dave"); ok {
+		t.Error("deleted customer is still cached")
+	}
+	got, _ := service.Get(order.ID)
+	if got.Status != OrderStatusCancelled || got.CancelReason != cancelReasonCustomerDeleted {
+		t.Errorf("got %+v want the order cancelled because the customer was deleted", got)
+	}
+	recorded := publisher.Events()
+	if last := recorded[len(recorded)-1]; last.Type != EventTypeOrderCancelled {
+		t.Errorf("got last event %s want %s", last.Type, EventTypeOrderCancelled)
+	}
+	eventstest.Expect(t, publisher).ToHave(EventTypeOrderCancelled).
+		WithSubject(order.ID).
+		WithPayloadField("order.cancel_reason", cancelReasonCustomerDeleted)
+}
+
+func TestUserEventHandler_InvalidData(t *testing.T) {
+	service, customers, _ := newTestService()
+	handler := UserEventHandler(customers, service)
+
+	event := events.Event{ID: "1", Type: "user.created", Data: []byte(`"not a user"`)}
+	if err := handler(context.Background(), event); err == nil {
+		t.Error("got nil error want a decoding error")
+	}
+}
+
+// TestUserEventHandler_Simulated delivers the changes of a user late, out of
+// order and twice, as a broker may, under many seeds: the cache must end
+// with the last change whatever the interleaving
+func TestUserEventHandler_Simulated(t *testing.T) {
+	config := simulation.Config{Seed: 1, MaxLatency: 20 * time.Millisecond, DuplicateRate: 0.3}
+	changes := []struct {
+		eventType string
+		status    string
+	}{
+		{"user.created", "pending"},
+		{"user.updated", "pending"},
+		{"user.suspended", "suspended"},
+		{"user.activated", "active"},
+	}
+
+	err := simulation.Explore(config, 200, func(s *simulation.Simulation) error {
+		service, customers, _ := newTestService()
+		s.Subscribe("orders", UserEventHandler(customers, service), userEventTypes...)
+		for i, change := range changes {
+			customer := Customer{ID: "dave", Name: "Dave", Status: change.status, Version: int64(i + 1)}
+			s.After(time.Duration(i)*5*time.Millisecond, "foundation", func(ctx context.Context) error {
+				return s.Publish(ctx, userEvent(t, change.eventType, customer))
+			})
+		}
+		if err := s.Run(context.Background()); err != nil {
+			return err
+		}
+		if got, _ := customers.Get("dave"); got.Version != 4 || got.Status != "active" {
+			return fmt.Errorf("got %+v want dave active at version 4", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
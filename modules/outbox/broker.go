@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ErrBrokerUnavailable reports a publish while the broker is down
+var ErrBrokerUnavailable = errors.New("broker is unavailable")
+
+// Broker is the message broker of the demo: a bus that can be taken down to
+// simulate an outage, failing every publish until it is back up
+type Broker struct {
+	bus  *events.Bus
+	down atomic.Bool
+}
+
+// NewBroker creates an available broker publishing on bus
+func NewBroker(bus *events.Bus) *Broker {
+	return &Broker{bus: bus}
+}
+
+// Publish publishes the event on the bus, unless the broker is down
+func (b *Broker) Publish(ctx context.Context, event events.Event) error {
+	if b.down.Load() {
+		return ErrBrokerUnavailable
+	}
+	return b.bus.Publish(ctx, event)
+}
+
+// SetAvailable takes the broker down or brings it back up
+func (b *Broker) SetAvailable(available bool) {
+	b.down.Store(!available)
+}
+
+// Available reports whether the broker is up
+func (b *Broker) Available() bool {
+	return !b.down.Load()
+}
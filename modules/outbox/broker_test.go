@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestBroker(t *testing.T) {
+	bus := events.NewBus()
+	var received int
+	bus.Subscribe(func(context.Context, events.Event) error {
+		received++
+		return nil
+	})
+	broker := NewBroker(bus)
+
+	broker.SetAvailable(false)
+	if err := broker.Publish(context.Background(), events.Event{ID: "e1"}); !errors.Is(err, ErrBrokerUnavailable) {
+		t.Errorf("got error %v want %v", err, ErrBrokerUnavailable)
+	}
+	broker.SetAvailable(true)
+	if err := broker.Publish(context.Background(), events.Event{ID: "e2"}); err != nil {
+		t.Errorf("Publish() error = %v", err)
+	}
+	if received != 1 || !broker.Available() {
+		t.Errorf("got %d events received want 1", received)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Consumer is a downstream service of the orders: it records the orders it
+// heard of. It is idempotent, ignoring the events it already received,
+// since the relay may publish an event more than once.
+type Consumer struct {
+	mutex      sync.Mutex
+	seen       map[string]bool
+	orders     map[string]bool
+	duplicates int
+}
+
+// NewConsumer creates a consumer that received nothing
+func NewConsumer() *Consumer {
+	return &Consumer{seen: make(map[string]bool), orders: make(map[string]bool)}
+}
+
+// Handle records the order of an order.placed event
+func (c *Consumer) Handle(_ context.Context, event events.Event) error {
+	var data OrderPlacedData
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.seen[event.ID] {
+		c.duplicates++
+		return nil
+	}
+	c.seen[event.ID] = true
+	c.orders[data.OrderID] = true
+	return nil
+}
+
+// Received reports whether the consumer heard of the order
+func (c *Consumer) Received(orderID string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.orders[orderID]
+}
+
+// Counts returns the number of distinct events received and of duplicates
+// ignored
+func (c *Consumer) Counts() (received, duplicates int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.seen), c.duplicates
+}
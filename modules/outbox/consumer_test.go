@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/events/eventstest"
+)
+
+func TestConsumer_IgnoresDuplicates(t *testing.T) {
+	consumer := NewConsumer()
+	event, err := events.New("e1", EventTypeOrderPlaced, eventSource, "o1", OrderPlacedData{OrderID: "o1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		if err := consumer.Handle(context.Background(), event); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+	received, duplicates := consumer.Counts()
+	if received != 1 || duplicates != 2 || !consumer.Received("o1") || consumer.Received("o2") {
+		t.Errorf("got %d received and %d duplicates want 1 and 2 of order o1", received, duplicates)
+	}
+}
+
+func TestConsumer_BrokerFaults(t *testing.T) {
+	broker := eventstest.NewBroker()
+	consumer := NewConsumer()
+	broker.Subscribe(consumer.Handle, EventTypeOrderPlaced)
+	broker.Script(EventTypeOrderPlaced, eventstest.Duplicate, eventstest.Hold, eventstest.Duplicate)
+
+	ctx := context.Background()
+	for _, orderID := range []string{"o1", "o2", "o3"} {
+		event, err := events.New("e-"+orderID, EventTypeOrderPlaced, eventSource, orderID, OrderPlacedData{OrderID: orderID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := broker.Publish(ctx, event); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if consumer.Received("o2") {
+		t.Error("got o2 received want it held by the broker")
+	}
+	broker.Release(ctx)
+
+	received, duplicates := consumer.Counts()
+	if received != 3 || duplicates != 2 || !consumer.Received("o2") {
+		t.Errorf("got %d received and %d duplicates want 3 and 2, o2 arriving last", received, duplicates)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Order is a row of the orders table
+type Order struct {
+	ID          string    `json:"id"`
+	Customer    string    `json:"customer"`
+	AmountCents int64     `json:"amount_cents"`
+	Method      string    `json:"method"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OutboxMessage is a row of the outbox table: an event to publish, written
+// in the same transaction as the change it announces
+type OutboxMessage struct {
+	Event     events.Event `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Attempts  int          `json:"attempts"`
+	SentAt    *time.Time   `json:"sent_at,omitempty"`
+}
+
+// Tx is a transaction of the database. Its writes are staged, then applied
+// together when it commits.
+type Tx struct {
+	orders []Order
+	outbox []OutboxMessage
+}
+
+// InsertOrder stages the insertion of an order
+func (tx *Tx) InsertOrder(order Order) {
+	tx.orders = append(tx.orders, order)
+}
+
+// InsertOutbox stages the insertion of an event into the outbox
+func (tx *Tx) InsertOutbox(message OutboxMessage) {
+	tx.outbox = append(tx.outbox, message)
+}
+
+// Database is an in-memory stand-in for a relational database with an
+// orders table and an outbox table, and atomic transactions over both
+type Database struct {
+	mutex  sync.RWMutex
+	orders []Order
+	outbox []OutboxMessage
+}
+
+// NewDatabase creates an empty database
+func NewDatabase() *Database {
+	return &Database{}
+}
+
+// Transaction runs fn in a transaction, committed when fn returns nil and
+// rolled back when it fails: either every write of fn happens, or none
+func (db *Database) Transaction(fn func(tx *Tx) error) error {
+	var tx Tx
+	if err := fn(&tx); err != nil {
+		return err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.orders = append(db.orders, tx.orders...)
+	db.outbox = append(db.outbox, tx.outbox...)
+	return nil
+}
+
+// Orders returns the orders, oldest first
+func (db *Database) Orders() []Order {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	return append([]Order{}, db.orders...)
+}
+
+// Outbox returns the outbox messages, oldest first
+func (db *Database) Outbox() []OutboxMessage {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	return append([]OutboxMessage{}, db.outbox...)
+}
+
+// Pending returns up to limit outbox messages not sent yet, oldest first,
+// and counts an attempt to send each
+func (db *Database) Pending(limit int) []OutboxMessage {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var pending []OutboxMessage
+	for i := range db.outbox {
+		if len(pending) == limit {
+			break
+		}
+		if db.outbox[i].SentAt == nil {
+			db.outbox[i].Attempts++
+			pending = append(pending, db.outbox[i])
+		}
+	}
+	return pending
+}
+
+// MarkSent records that the outbox message of the event was sent at sentAt
+func (db *Database) MarkSent(eventID string, sentAt time.Time) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	for i := range db.outbox {
+		if db.outbox[i].Event.ID == eventID {
+			db.outbox[i].SentAt = &sentAt
+			return
+		}
+	}
+}
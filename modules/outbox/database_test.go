@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestDatabase_Transaction(t *testing.T) {
+	db := NewDatabase()
+	failure := errors.New("failure")
+
+	err := db.Transaction(func(tx *Tx) error {
+		tx.InsertOrder(Order{ID: "o1"})
+		tx.InsertOutbox(OutboxMessage{Event: events.Event{ID: "e1"}})
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("got error %v want %v", err, failure)
+	}
+	if len(db.Orders()) != 0 || len(db.Outbox()) != 0 {
+		t.Fatal("a rolled back transaction wrote rows")
+	}
+
+	err = db.Transaction(func(tx *Tx) error {
+		tx.InsertOrder(Order{ID: "o2"})
+		tx.InsertOutbox(OutboxMessage{Event: events.Event{ID: "e2"}})
+		tx.InsertOutbox(OutboxMessage{Event: events.Event{ID: "e3"}})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if len(db.Orders()) != 1 || len(db.Outbox()) != 2 {
+		t.Errorf("got %d orders and %d outbox messages want 1 and 2", len(db.Orders()), len(db.Outbox()))
+	}
+}
+
+func TestDatabase_PendingAndMarkSent(t *testing.T) {
+	db := NewDatabase()
+	db.Transaction(func(tx *Tx) error {
+		for _, id := range []string{"e1", "e2", "e3"} {
+			tx.InsertOutbox(OutboxMessage{Event: events.Event{ID: id}})
+		}
+		return nil
+	})
+
+	if pending := db.Pending(2); len(pending) != 2 || pending[0].Event.ID != "e1" || pending[0].Attempts != 1 {
+		t.Fatalf("got %+v want e1 and e2 at their first attempt", pending)
+	}
+	db.MarkSent("e1", time.Now())
+	pending := db.Pending(10)
+	if len(pending) != 2 || pending[0].Event.ID != "e2" || pending[0].Attempts != 2 || pending[1].Attempts != 1 {
+		t.Errorf("got %+v want e2 at its second attempt and e3 at its first", pending)
+	}
+}
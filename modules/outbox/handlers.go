@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// brokerBody is the body of the broker routes
+type brokerBody struct {
+	Available bool `json:"available"`
+}
+
+// relayBody is the body of POST /outbox/relay
+type relayBody struct {
+	Sent  int    `json:"sent"`
+	Error string `json:"error,omitempty"`
+}
+
+// DemoHandler serves the two ways of placing orders, the tables of the
+// database, the broker switch and the verification of the outcome
+type DemoHandler struct {
+	service  *OrderService
+	relay    *Relay
+	db       *Database
+	broker   *Broker
+	consumer *Consumer
+	mux      *http.ServeMux
+}
+
+// NewDemoHandler creates the handler of the demo routes
+func NewDemoHandler(service *OrderService, relay *Relay, db *Database, broker *Broker, consumer *Consumer) *DemoHandler {
+	h := &DemoHandler{
+		service:  service,
+		relay:    relay,
+		db:       db,
+		broker:   broker,
+		consumer: consumer,
+		mux:      http.NewServeMux(),
+	}
+	h.mux.HandleFunc("POST /naive/orders", h.handlePlace(service.PlaceNaive))
+	h.mux.HandleFunc("POST /outbox/orders", h.handlePlace(service.PlaceWithOutbox))
+	h.mux.HandleFunc("GET /orders", h.handleOrders)
+	h.mux.HandleFunc("GET /outbox", h.handleOutbox)
+	h.mux.HandleFunc("POST /outbox/relay", h.handleRelay)
+	h.mux.HandleFunc("GET /broker", h.handleBroker)
+	h.mux.HandleFunc("PUT /broker", h.handleSetBroker)
+	h.mux.HandleFunc("GET /verify", h.handleVerify)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *DemoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handlePlace places an order with place, crashing at the crash point of
+// the crash query parameter
+func (h *DemoHandler) handlePlace(place func(ctx context.Context, cmd PlaceOrder, crash CrashPoint) (Order, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		crash, err := ParseCrashPoint(r.URL.Query().Get("crash"))
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		var cmd PlaceOrder
+		if !decodeBody(w, r, &cmd) {
+			return
+		}
+		order, err := place(r.Context(), cmd, crash)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, order)
+	}
+}
+
+// handleOrders lists the orders table
+func (h *DemoHandler) handleOrders(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.db.Orders())
+}
+
+// handleOutbox lists the outbox table
+func (h *DemoHandler) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.db.Outbox())
+}
+
+// handleRelay relays the outbox now, without waiting for the next run
+func (h *DemoHandler) handleRelay(w http.ResponseWriter, r *http.Request) {
+	sent, err := h.relay.RelayOnce(r.Context())
+	body := relayBody{Sent: sent}
+	if err != nil {
+		body.Error = err.Error()
+	}
+	httpx.WriteJSON(w, http.StatusOK, body)
+}
+
+// handleBroker reports whether the broker is up
+func (h *DemoHandler) handleBroker(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, brokerBody{Available: h.broker.Available()})
+}
+
+// handleSetBroker takes the broker down or brings it back up
+func (h *DemoHandler) handleSetBroker(w http.ResponseWriter, r *http.Request) {
+	var body brokerBody
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	h.broker.SetAvailable(body.Available)
+	slog.InfoContext(r.Context(), "Broker switched", "available", body.Available)
+	httpx.WriteJSON(w, http.StatusOK, body)
+}
+
+// handleVerify compares the orders with what the consumer received
+func (h *DemoHandler) handleVerify(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, Verify(h.db, h.consumer))
+}
+
+// writeError writes the problem matching an error of placing an order
+func (h *DemoHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrInvalidCrashPoint):
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrSimulatedCrash):
+		problem.Write(w, r, http.StatusInternalServerError, err.Error())
+	case errors.Is(err, ErrBrokerUnavailable):
+		problem.Write(w, r, http.StatusServiceUnavailable, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// decodeBody decodes the JSON body of r into dst, writing a problem and
+// returning false when it is invalid
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(dst); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
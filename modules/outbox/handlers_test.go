@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDemoHandler(t *testing.T) {
+	service, relay, db, broker, consumer := newTestDemo()
+	handler := NewDemoHandler(service, relay, db, broker, consumer)
+	order := `{"customer":"Ada","amount_cents":500}`
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"naive", http.MethodPost, "/naive/orders", order, http.StatusCreated},
+		{"naive crash", http.MethodPost, "/naive/orders?crash=after_commit", order, http.StatusInternalServerError},
+		{"outbox crash", http.MethodPost, "/outbox/orders?crash=after_commit", order, http.StatusInternalServerError},
+		{"invalid crash point", http.MethodPost, "/outbox/orders?crash=now", order, http.StatusBadRequest},
+		{"invalid order", http.MethodPost, "/outbox/orders", `{"customer":"Ada"}`, http.StatusUnprocessableEntity},
+		{"invalid JSON", http.MethodPost, "/outbox/orders", `{`, http.StatusBadRequest},
+		{"broker down", http.MethodPut, "/broker", `{"available":false}`, http.StatusOK},
+		{"naive with the broker down", http.MethodPost, "/naive/orders", order, http.StatusServiceUnavailable},
+		{"outbox with the broker down", http.MethodPost, "/outbox/orders", order, http.StatusCreated},
+		{"broker up", http.MethodPut, "/broker", `{"available":true}`, http.StatusOK},
+		{"relay", http.MethodPost, "/outbox/relay", "", http.StatusOK},
+		{"orders", http.MethodGet, "/orders", "", http.StatusOK},
+		{"outbox", http.MethodGet, "/outbox", "", http.StatusOK},
+		{"broker", http.MethodGet, "/broker", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/verify", nil))
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	naive, outbox := report.Methods[MethodNaive], report.Methods[MethodOutbox]
+	if report.Consistent || len(naive.Lost) != 2 || outbox.Delivered != 2 || len(outbox.Lost) != 0 {
+		t.Errorf("got %+v want 2 naive orders lost and 2 outbox orders delivered", report)
+	}
+}
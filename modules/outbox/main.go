@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort           = "8091"
+	defaultHost           = "localhost"
+	defaultRelayInterval  = time.Second
+	defaultRelayBatchSize = 100
+)
+
+// RelayConfig configures the outbox relay
+type RelayConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// CrashRate is the probability of a crash after each publish
+	CrashRate float64
+}
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	relayConfig, err := loadRelayConfig()
+	if err != nil {
+		fatal("Invalid relay configuration", "error", err)
+	}
+
+	// Events go through a broker that can be taken down, to a consumer
+	// recording the orders it hears of; they are also streamed at GET /events
+	bus := events.NewBus()
+	eventStream := events.NewStream(bus)
+	broker := NewBroker(bus)
+	consumer := NewConsumer()
+	bus.Subscribe(consumer.Handle, EventTypeOrderPlaced)
+
+	db := NewDatabase()
+	service := NewOrderService(db, broker, uuid.GeneratorFunc(uuid.NewGoogle))
+	relay := NewRelay(db, broker, relayConfig.BatchSize, func() bool {
+		return rand.Float64() < relayConfig.CrashRate
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go relay.Run(ctx, relayConfig.Interval)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewDemoHandler(service, relay, db, broker, consumer)
+	for _, pattern := range []string{"/naive/", "/outbox", "/outbox/", "/orders", "/broker", "/verify"} {
+		mux.Handle(pattern, api)
+	}
+	mux.Handle("/events", eventStream)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	server.RegisterOnShutdown(eventStream.Close)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting outbox demo", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"relay_interval", relayConfig.Interval, "relay_crash_rate", relayConfig.CrashRate)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Outbox demo failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down outbox demo")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Outbox demo forced to shutdown", "error", err)
+	}
+	slog.Info("Outbox demo exited")
+}
+
+// loadRelayConfig reads the relay configuration from RELAY_INTERVAL,
+// RELAY_BATCH_SIZE and RELAY_CRASH_RATE
+func loadRelayConfig() (RelayConfig, error) {
+	relay := RelayConfig{Interval: defaultRelayInterval, BatchSize: defaultRelayBatchSize}
+	if value := os.Getenv("RELAY_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return RelayConfig{}, fmt.Errorf("RELAY_INTERVAL must be a positive duration, got %q", value)
+		}
+		relay.Interval = interval
+	}
+	if value := os.Getenv("RELAY_BATCH_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return RelayConfig{}, fmt.Errorf("RELAY_BATCH_SIZE must be a positive integer, got %q", value)
+		}
+		relay.BatchSize = size
+	}
+	if value := os.Getenv("RELAY_CRASH_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate >= 1 {
+			return RelayConfig{}, fmt.Errorf("RELAY_CRASH_RATE must be a number from 0 to less than 1, got %q", value)
+		}
+		relay.CrashRate = rate
+	}
+	return relay, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "outbox",
+		"endpoints": map[string]string{
+			"naive_orders":  "/naive/orders",
+			"outbox_orders": "/outbox/orders",
+			"orders":        "/orders",
+			"outbox":        "/outbox",
+			"broker":        "/broker",
+			"verify":        "/verify",
+			"events":        "/events",
+			"health":        "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
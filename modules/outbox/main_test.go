@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadRelayConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  string
+		batchSize string
+		crashRate string
+		want      RelayConfig
+		wantErr   bool
+	}{
+		{"defaults", "", "", "", RelayConfig{Interval: defaultRelayInterval, BatchSize: defaultRelayBatchSize}, false},
+		{"custom", "5s", "10", "0.25", RelayConfig{Interval: 5 * time.Second, BatchSize: 10, CrashRate: 0.25}, false},
+		{"zero interval", "0s", "", "", RelayConfig{}, true},
+		{"invalid batch size", "", "many", "", RelayConfig{}, true},
+		{"crash rate of 1", "", "", "1", RelayConfig{}, true},
+		{"negative crash rate", "", "", "-0.1", RelayConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RELAY_INTERVAL", tt.interval)
+			t.Setenv("RELAY_BATCH_SIZE", tt.batchSize)
+			t.Setenv("RELAY_CRASH_RATE", tt.crashRate)
+			got, err := loadRelayConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRelayConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
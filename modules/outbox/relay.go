@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Relay publishes the events of the outbox. It marks a message sent only
+// once the broker took it, so a message is published at least once: a
+// crash between the publish and the mark publishes it again, and consumers
+// must ignore the duplicates.
+type Relay struct {
+	db        *Database
+	publisher events.Publisher
+	batchSize int
+	// crash reports whether to simulate a crash after a publish, before
+	// the message is marked sent
+	crash func() bool
+	now   func() time.Time
+}
+
+// NewRelay creates a relay publishing up to batchSize messages of db at a
+// time, crashing after a publish whenever crash returns true
+func NewRelay(db *Database, publisher events.Publisher, batchSize int, crash func() bool) *Relay {
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		batchSize: batchSize,
+		crash:     crash,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// RelayOnce publishes the pending messages of the outbox, oldest first, and
+// returns how many it sent. It stops at the first failure, leaving the
+// message pending for the next run.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	sent := 0
+	for _, message := range r.db.Pending(r.batchSize) {
+		if err := r.publisher.Publish(ctx, message.Event); err != nil {
+			return sent, fmt.Errorf("publishing event %s: %w", message.Event.ID, err)
+		}
+		if r.crash() {
+			return sent, fmt.Errorf("%w: event %s published, not marked sent", ErrSimulatedCrash, message.Event.ID)
+		}
+		r.db.MarkSent(message.Event.ID, r.now())
+		sent++
+	}
+	return sent, nil
+}
+
+// Run relays the outbox every interval until ctx is done
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := r.RelayOnce(ctx)
+			if sent > 0 {
+				slog.InfoContext(ctx, "Relayed outbox", "sent", sent)
+			}
+			if err != nil {
+				slog.WarnContext(ctx, "Relaying outbox failed, retrying", "retry_in", interval, "error", err)
+			}
+		}
+	}
+}
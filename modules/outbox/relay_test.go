@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRelay_CrashAfterPublish(t *testing.T) {
+	service, _, db, broker, consumer := newTestDemo()
+	ctx := context.Background()
+	for range 3 {
+		service.PlaceWithOutbox(ctx, PlaceOrder{Customer: "Ada", AmountCents: 500}, CrashNone)
+	}
+
+	// Crash after the second publish, before marking it sent
+	publishes := 0
+	relay := NewRelay(db, broker, 100, func() bool {
+		publishes++
+		return publishes == 2
+	})
+	sent, err := relay.RelayOnce(ctx)
+	if sent != 1 || !errors.Is(err, ErrSimulatedCrash) {
+		t.Fatalf("RelayOnce() = %d, %v want 1 sent then %v", sent, err, ErrSimulatedCrash)
+	}
+	if sent, err := relay.RelayOnce(ctx); sent != 2 || err != nil {
+		t.Fatalf("RelayOnce() after the crash = %d, %v want 2 sent", sent, err)
+	}
+
+	// The second event was published twice, and received once
+	received, duplicates := consumer.Counts()
+	if received != 3 || duplicates != 1 {
+		t.Errorf("got %d received and %d duplicates want 3 and 1", received, duplicates)
+	}
+	if sent, _ := relay.RelayOnce(ctx); sent != 0 {
+		t.Errorf("got %d sent want none once the outbox is relayed", sent)
+	}
+}
+
+func TestRelay_BrokerDown(t *testing.T) {
+	service, relay, db, broker, _ := newTestDemo()
+	ctx := context.Background()
+	service.PlaceWithOutbox(ctx, PlaceOrder{Customer: "Ada", AmountCents: 500}, CrashNone)
+
+	broker.SetAvailable(false)
+	if _, err := relay.RelayOnce(ctx); !errors.Is(err, ErrBrokerUnavailable) {
+		t.Fatalf("got error %v want %v", err, ErrBrokerUnavailable)
+	}
+	broker.SetAvailable(true)
+	if sent, err := relay.RelayOnce(ctx); sent != 1 || err != nil {
+		t.Fatalf("RelayOnce() = %d, %v want 1 sent", sent, err)
+	}
+	if message := db.Outbox()[0]; message.SentAt == nil || message.Attempts != 2 {
+		t.Errorf("got %+v want sent at the second attempt", message)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies the demo as the producer of its events
+const eventSource = "outbox-demo"
+
+// EventTypeOrderPlaced is the event announcing an order
+const EventTypeOrderPlaced = "order.placed"
+
+// Methods of writing an order and publishing its event
+const (
+	MethodNaive  = "naive"
+	MethodOutbox = "outbox"
+)
+
+// CrashPoint is where a request simulates a crash of the process
+type CrashPoint string
+
+// Crash points of the requests
+const (
+	CrashNone CrashPoint = ""
+	// CrashBeforeCommit crashes after the writes, before they are committed
+	CrashBeforeCommit CrashPoint = "before_commit"
+	// CrashAfterCommit crashes once the writes are committed, before the
+	// event is published
+	CrashAfterCommit CrashPoint = "after_commit"
+)
+
+// Errors of placing orders
+var (
+	ErrSimulatedCrash    = errors.New("simulated crash")
+	ErrInvalidCrashPoint = errors.New("invalid crash point")
+)
+
+// ParseCrashPoint parses the crash point of a request
+func ParseCrashPoint(value string) (CrashPoint, error) {
+	switch point := CrashPoint(value); point {
+	case CrashNone, CrashBeforeCommit, CrashAfterCommit:
+		return point, nil
+	}
+	return CrashNone, fmt.Errorf("%w %q, expected %s or %s", ErrInvalidCrashPoint, value, CrashBeforeCommit, CrashAfterCommit)
+}
+
+// PlaceOrder asks to place an order
+type PlaceOrder struct {
+	Customer    string `json:"customer"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+// OrderPlacedData is the payload of order.placed
+type OrderPlacedData struct {
+	OrderID     string `json:"order_id"`
+	Customer    string `json:"customer"`
+	AmountCents int64  `json:"amount_cents"`
+	Method      string `json:"method"`
+}
+
+// OrderService places orders, writing them to the database and announcing
+// them with an event, either naively or through the outbox
+type OrderService struct {
+	db        *Database
+	publisher events.Publisher
+	ids       uuid.IDGenerator
+	now       func() time.Time
+}
+
+// NewOrderService creates the service writing to db and publishing with
+// publisher
+func NewOrderService(db *Database, publisher events.Publisher, ids uuid.IDGenerator) *OrderService {
+	return &OrderService{
+		db:        db,
+		publisher: publisher,
+		ids:       ids,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// PlaceNaive commits the order, then publishes its event: two writes to two
+// systems with nothing tying them together. A crash or a broker failure
+// between them leaves an order that no consumer ever hears of.
+func (s *OrderService) PlaceNaive(ctx context.Context, cmd PlaceOrder, crash CrashPoint) (Order, error) {
+	order, event, err := s.newOrder(cmd, MethodNaive)
+	if err != nil {
+		return Order{}, err
+	}
+	err = s.db.Transaction(func(tx *Tx) error {
+		tx.InsertOrder(order)
+		if crash == CrashBeforeCommit {
+			return ErrSimulatedCrash
+		}
+		return nil
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	if crash == CrashAfterCommit {
+		return Order{}, fmt.Errorf("%w: order %s committed, its event lost", ErrSimulatedCrash, order.ID)
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		return Order{}, fmt.Errorf("order %s committed, publishing its event: %w", order.ID, err)
+	}
+	return order, nil
+}
+
+// PlaceWithOutbox commits the order and its event in the outbox in one
+// transaction: both are written or neither is. The relay publishes the
+// event later, however the request ends.
+func (s *OrderService) PlaceWithOutbox(ctx context.Context, cmd PlaceOrder, crash CrashPoint) (Order, error) {
+	order, event, err := s.newOrder(cmd, MethodOutbox)
+	if err != nil {
+		return Order{}, err
+	}
+	err = s.db.Transaction(func(tx *Tx) error {
+		tx.InsertOrder(order)
+		tx.InsertOutbox(OutboxMessage{Event: event, CreatedAt: order.CreatedAt})
+		if crash == CrashBeforeCommit {
+			return ErrSimulatedCrash
+		}
+		return nil
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	if crash == CrashAfterCommit {
+		return Order{}, fmt.Errorf("%w: order %s committed, its event left in the outbox", ErrSimulatedCrash, order.ID)
+	}
+	return order, nil
+}
+
+// newOrder validates the command and creates the order and its event
+func (s *OrderService) newOrder(cmd PlaceOrder, method string) (Order, events.Event, error) {
+	switch {
+	case cmd.Customer == "":
+		return Order{}, events.Event{}, &domain.ValidationError{Field: "customer", Message: "is required"}
+	case cmd.AmountCents <= 0:
+		return Order{}, events.Event{}, &domain.ValidationError{Field: "amount_cents", Message: "must be positive"}
+	}
+	order := Order{
+		ID:          s.ids.NewID(),
+		Customer:    cmd.Customer,
+		AmountCents: cmd.AmountCents,
+		Method:      method,
+		CreatedAt:   s.now(),
+	}
+	event, err := events.New(s.ids.NewID(), EventTypeOrderPlaced, eventSource, order.ID, OrderPlacedData{
+		OrderID:     order.ID,
+		Customer:    order.Customer,
+		AmountCents: order.AmountCents,
+		Method:      method,
+	})
+	if err != nil {
+		return Order{}, events.Event{}, err
+	}
+	event.Time = order.CreatedAt
+	return order, event, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// newTestDemo wires a service, a relay that never crashes and a consumer
+// over an available broker
+func newTestDemo() (*OrderService, *Relay, *Database, *Broker, *Consumer) {
+	bus := events.NewBus()
+	broker := NewBroker(bus)
+	consumer := NewConsumer()
+	bus.Subscribe(consumer.Handle, EventTypeOrderPlaced)
+	db := NewDatabase()
+	service := NewOrderService(db, broker, uuid.NewSequenceGenerator("id-"))
+	relay := NewRelay(db, broker, 100, func() bool { return false })
+	return service, relay, db, broker, consumer
+}
+
+func TestParseCrashPoint(t *testing.T) {
+	for _, value := range []string{"", "before_commit", "after_commit"} {
+		if point, err := ParseCrashPoint(value); err != nil || string(point) != value {
+			t.Errorf("ParseCrashPoint(%q) = %q, %v", value, point, err)
+		}
+	}
+	if _, err := ParseCrashPoint("later"); !errors.Is(err, ErrInvalidCrashPoint) {
+		t.Errorf("got error %v want %v", err, ErrInvalidCrashPoint)
+	}
+}
+
+func TestOrderService_PlaceNaive(t *testing.T) {
+	service, _, db, broker, consumer := newTestDemo()
+	ctx := context.Background()
+	cmd := PlaceOrder{Customer: "Ada", AmountCents: 500}
+
+	order, err := service.PlaceNaive(ctx, cmd, CrashNone)
+	if err != nil || !consumer.Received(order.ID) {
+		t.Fatalf("got %+v, %v want an order the consumer received", order, err)
+	}
+	if _, err := service.PlaceNaive(ctx, cmd, CrashBeforeCommit); !errors.Is(err, ErrSimulatedCrash) {
+		t.Errorf("got error %v want %v", err, ErrSimulatedCrash)
+	}
+	if _, err := service.PlaceNaive(ctx, cmd, CrashAfterCommit); !errors.Is(err, ErrSimulatedCrash) {
+		t.Errorf("got error %v want %v", err, ErrSimulatedCrash)
+	}
+	broker.SetAvailable(false)
+	if _, err := service.PlaceNaive(ctx, cmd, CrashNone); !errors.Is(err, ErrBrokerUnavailable) {
+		t.Errorf("got error %v want %v", err, ErrBrokerUnavailable)
+	}
+
+	// The crash before the commit wrote nothing; the crash after it and the
+	// broker outage each left an order no one heard of
+	if orders := db.Orders(); len(orders) != 3 {
+		t.Errorf("got %d orders want 3", len(orders))
+	}
+	if received, _ := consumer.Counts(); received != 1 {
+		t.Errorf("got %d events received want 1", received)
+	}
+}
+
+func TestOrderService_PlaceWithOutbox(t *testing.T) {
+	service, relay, db, broker, consumer := newTestDemo()
+	ctx := context.Background()
+	cmd := PlaceOrder{Customer: "Ada", AmountCents: 500}
+
+	broker.SetAvailable(false)
+	if _, err := service.PlaceWithOutbox(ctx, cmd, CrashNone); err != nil {
+		t.Fatalf("PlaceWithOutbox() with the broker down error = %v", err)
+	}
+	if _, err := service.PlaceWithOutbox(ctx, cmd, CrashBeforeCommit); !errors.Is(err, ErrSimulatedCrash) {
+		t.Errorf("got error %v want %v", err, ErrSimulatedCrash)
+	}
+	if _, err := service.PlaceWithOutbox(ctx, cmd, CrashAfterCommit); !errors.Is(err, ErrSimulatedCrash) {
+		t.Errorf("got error %v want %v", err, ErrSimulatedCrash)
+	}
+	if len(db.Orders()) != 2 || len(db.Outbox()) != 2 {
+		t.Fatalf("got %d orders and %d outbox messages want 2 and 2", len(db.Orders()), len(db.Outbox()))
+	}
+
+	broker.SetAvailable(true)
+	if sent, err := relay.RelayOnce(ctx); sent != 2 || err != nil {
+		t.Fatalf("RelayOnce() = %d, %v want 2 sent", sent, err)
+	}
+	for _, order := range db.Orders() {
+		if !consumer.Received(order.ID) {
+			t.Errorf("order %s was not received", order.ID)
+		}
+	}
+}
+
+func TestOrderService_Validation(t *testing.T) {
+	service, _, _, _, _ := newTestDemo()
+	for _, cmd := range []PlaceOrder{{AmountCents: 500}, {Customer: "Ada"}} {
+		var validationErr *domain.ValidationError
+		if _, err := service.PlaceWithOutbox(context.Background(), cmd, CrashNone); !errors.As(err, &validationErr) {
+			t.Errorf("got error %v for %+v want a validation error", err, cmd)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+// MethodReport is the consistency of the orders placed with one method
+type MethodReport struct {
+	Orders int `json:"orders"`
+	// Delivered orders were received by the consumer
+	Delivered int `json:"delivered"`
+	// Pending orders have their event waiting in the outbox
+	Pending int `json:"pending"`
+	// Lost orders were committed, but their event will never be published
+	Lost []string `json:"lost"`
+}
+
+// Report compares the orders of the database with what the consumer
+// received. The database and the consumer are consistent when no event is
+// lost: pending events will eventually be delivered.
+type Report struct {
+	Consistent        bool                    `json:"consistent"`
+	Methods           map[string]MethodReport `json:"methods"`
+	EventsReceived    int                     `json:"events_received"`
+	DuplicatesIgnored int                     `json:"duplicates_ignored"`
+}
+
+// Verify checks that every order of db reached consumer, or is on its way
+func Verify(db *Database, consumer *Consumer) Report {
+	pending := make(map[string]bool)
+	for _, message := range db.Outbox() {
+		if message.SentAt == nil {
+			pending[message.Event.Subject] = true
+		}
+	}
+
+	report := Report{Consistent: true, Methods: map[string]MethodReport{
+		MethodNaive:  {Lost: []string{}},
+		MethodOutbox: {Lost: []string{}},
+	}}
+	for _, order := range db.Orders() {
+		method := report.Methods[order.Method]
+		method.Orders++
+		switch {
+		case consumer.Received(order.ID):
+			method.Delivered++
+		case pending[order.ID]:
+			method.Pending++
+		default:
+			method.Lost = append(method.Lost, order.ID)
+			report.Consistent = false
+		}
+		report.Methods[order.Method] = method
+	}
+	report.EventsReceived, report.DuplicatesIgnored = consumer.Counts()
+	return report
+}
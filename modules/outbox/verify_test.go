@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	service, relay, db, _, consumer := newTestDemo()
+	ctx := context.Background()
+	cmd := PlaceOrder{Customer: "Ada", AmountCents: 500}
+
+	service.PlaceNaive(ctx, cmd, CrashNone)
+	lost, _ := service.PlaceNaive(ctx, cmd, CrashAfterCommit)
+	service.PlaceWithOutbox(ctx, cmd, CrashAfterCommit)
+
+	report := Verify(db, consumer)
+	naive, outbox := report.Methods[MethodNaive], report.Methods[MethodOutbox]
+	if report.Consistent || naive.Orders != 2 || naive.Delivered != 1 || len(naive.Lost) != 1 || outbox.Pending != 1 {
+		t.Fatalf("got %+v want a naive order lost and an outbox order pending", report)
+	}
+	if lost.ID != "" {
+		t.Errorf("got order %+v from a crashed request", lost)
+	}
+
+	relay.RelayOnce(ctx)
+	report = Verify(db, consumer)
+	if outbox := report.Methods[MethodOutbox]; outbox.Delivered != 1 || outbox.Pending != 0 || len(outbox.Lost) != 0 {
+		t.Errorf("got %+v want the outbox order delivered", outbox)
+	}
+	if report.EventsReceived != 2 {
+		t.Errorf("got %d events received want 2", report.EventsReceived)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/contract"
+)
+
+// TestConsumerContracts checks the payment events against the fields the
+// other modules declare they read from them
+func TestConsumerContracts(t *testing.T) {
+	contracts, err := contract.LoadFor("..", "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) == 0 {
+		t.Fatal("got no contracts want the ones of the consumers of payment events")
+	}
+
+	service, publisher := newTestService(t, 0)
+	for _, cmd := range []Command{
+		command("c1", CommandTypeReserve, reserveData{OrderID: "o1", CustomerID: "alice", AmountCents: 2500}),
+		command("c2", CommandTypeCapture, paymentData{OrderID: "o1"}),
+		command("c3", CommandTypeRelease, paymentData{OrderID: "o1", Reason: "shipping failed"}),
+	} {
+		handle(t, service, cmd)
+	}
+
+	contract.Verify(t, contracts, publisher.events)
+}
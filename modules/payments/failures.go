@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+// FailureInjector fails a share of the commands on purpose, so the
+// compensation flows of a saga can be demonstrated
+type FailureInjector struct {
+	mutex sync.Mutex
+	rate  float64
+	rand  *rand.Rand
+}
+
+// NewFailureInjector creates an injector failing rate of the commands, with
+// randomness seeded by seed so runs can be reproduced
+func NewFailureInjector(rate float64, seed uint64) (*FailureInjector, error) {
+	f := &FailureInjector{rand: rand.New(rand.NewPCG(seed, seed))}
+	if err := f.SetRate(rate); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Rate returns the share of commands failed
+func (f *FailureInjector) Rate() float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rate
+}
+
+// SetRate changes the share of commands failed, between 0 and 1
+func (f *FailureInjector) SetRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("failure rate must be between 0 and 1, got %v", rate)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rate = rate
+	return nil
+}
+
+// Fail reports whether the next command fails
+func (f *FailureInjector) Fail() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rate > 0 && f.rand.Float64() < f.rate
+}
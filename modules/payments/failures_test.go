@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestFailureInjector(t *testing.T) {
+	if _, err := NewFailureInjector(1.5, 1); err == nil {
+		t.Error("got nil error for a rate above 1")
+	}
+
+	never, _ := NewFailureInjector(0, 1)
+	always, _ := NewFailureInjector(1, 1)
+	for range 100 {
+		if never.Fail() {
+			t.Fatal("a rate of 0 failed a command")
+		}
+		if !always.Fail() {
+			t.Fatal("a rate of 1 passed a command")
+		}
+	}
+
+	sample := func(seed uint64) []bool {
+		f, _ := NewFailureInjector(0.5, seed)
+		fails := make([]bool, 50)
+		for i := range fails {
+			fails[i] = f.Fail()
+		}
+		return fails
+	}
+	first, second := sample(42), sample(42)
+	failed := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatal("the same seed chose different failures")
+		}
+		if first[i] {
+			failed++
+		}
+	}
+	if failed == 0 || failed == len(first) {
+		t.Errorf("got %d failures of %d want some", failed, len(first))
+	}
+
+	if err := never.SetRate(0.25); err != nil || never.Rate() != 0.25 {
+		t.Errorf("got rate %v, %v want 0.25", never.Rate(), err)
+	}
+	if err := never.SetRate(-1); err == nil {
+		t.Error("got nil error for a negative rate")
+	}
+}
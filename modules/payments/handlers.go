@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// failureRateBody is the body of GET and PUT /failure-rate
+type failureRateBody struct {
+	Rate float64 `json:"rate"`
+}
+
+// PaymentHandler serves the HTTP API of the payments service
+type PaymentHandler struct {
+	payments *PaymentService
+	failures *FailureInjector
+	mux      *http.ServeMux
+}
+
+// NewPaymentHandler creates the handler of the commands, payments and
+// failure rate routes
+func NewPaymentHandler(payments *PaymentService, failures *FailureInjector) *PaymentHandler {
+	h := &PaymentHandler{payments: payments, failures: failures, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /commands", h.handleCommand)
+	h.mux.HandleFunc("GET /payments", h.handleListPayments)
+	h.mux.HandleFunc("GET /payments/{order_id}", h.handleGetPayment)
+	h.mux.HandleFunc("GET /failure-rate", h.handleGetFailureRate)
+	h.mux.HandleFunc("PUT /failure-rate", h.handleSetFailureRate)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *PaymentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleCommand handles a command and answers the event replying to it.
+// Failed payments are replies too, answered with 200.
+func (h *PaymentHandler) handleCommand(w http.ResponseWriter, r *http.Request) {
+	var cmd Command
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&cmd); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	reply, err := h.payments.Handle(r.Context(), cmd)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, reply)
+}
+
+// handleListPayments lists the payments
+func (h *PaymentHandler) handleListPayments(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.payments.List())
+}
+
+// handleGetPayment returns the payment of an order
+func (h *PaymentHandler) handleGetPayment(w http.ResponseWriter, r *http.Request) {
+	payment, err := h.payments.Get(r.PathValue("order_id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, payment)
+}
+
+// handleGetFailureRate returns the share of commands failed on purpose
+func (h *PaymentHandler) handleGetFailureRate(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, failureRateBody{Rate: h.failures.Rate()})
+}
+
+// handleSetFailureRate changes the share of commands failed on purpose
+func (h *PaymentHandler) handleSetFailureRate(w http.ResponseWriter, r *http.Request) {
+	var body failureRateBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&body); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := h.failures.SetRate(body.Rate); err != nil {
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	slog.InfoContext(r.Context(), "Failure rate changed", "rate", body.Rate)
+	httpx.WriteJSON(w, http.StatusOK, body)
+}
+
+// writeError writes the problem matching a service error
+func (h *PaymentHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCommand):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrPaymentNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
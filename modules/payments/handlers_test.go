@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestPaymentHandler(t *testing.T) {
+	service, _ := newTestService(t, 0)
+	handler := NewPaymentHandler(service, service.failures)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"reserve", http.MethodPost, "/commands", `{"id":"c1","type":"payment.reserve","data":{"order_id":"o1","amount_cents":500}}`, http.StatusOK},
+		{"declined is a reply", http.MethodPost, "/commands", `{"id":"c2","type":"payment.reserve","data":{"order_id":"o1","amount_cents":500}}`, http.StatusOK},
+		{"invalid command", http.MethodPost, "/commands", `{"id":"c3","type":"payment.steal","data":{}}`, http.StatusUnprocessableEntity},
+		{"invalid JSON", http.MethodPost, "/commands", `{`, http.StatusBadRequest},
+		{"list", http.MethodGet, "/payments", "", http.StatusOK},
+		{"get", http.MethodGet, "/payments/o1", "", http.StatusOK},
+		{"missing", http.MethodGet, "/payments/missing", "", http.StatusNotFound},
+		{"set failure rate", http.MethodPut, "/failure-rate", `{"rate":0.5}`, http.StatusOK},
+		{"invalid failure rate", http.MethodPut, "/failure-rate", `{"rate":2}`, http.StatusUnprocessableEntity},
+		{"get failure rate", http.MethodGet, "/failure-rate", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	if service.failures.Rate() != 0.5 {
+		t.Errorf("got failure rate %v want 0.5", service.failures.Rate())
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(`{"id":"c2","type":"payment.reserve","data":{}}`)))
+	var reply events.Event
+	if err := json.NewDecoder(rec.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Type != EventTypePaymentDeclined {
+		t.Errorf("got %s want the first reply to c2, %s", reply.Type, EventTypePaymentDeclined)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort         = "8083"
+	defaultHost         = "localhost"
+	defaultPaymentLimit = 100000
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	limit, err := strconv.ParseInt(config.Getenv("PAYMENT_LIMIT_CENTS", strconv.Itoa(defaultPaymentLimit)), 10, 64)
+	if err != nil || limit < 0 {
+		fatal("Invalid PAYMENT_LIMIT_CENTS", "error", "must be a non-negative integer, 0 for no limit")
+	}
+	failures, err := loadFailureInjector()
+	if err != nil {
+		fatal("Invalid failure injection", "error", err)
+	}
+
+	// Replies publish on a local bus, streamed at GET /events
+	bus := events.NewBus()
+	eventStream := events.NewStream(bus)
+	payments := NewPaymentService(limit, failures, uuid.GeneratorFunc(uuid.NewGoogle), bus)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewPaymentHandler(payments, failures)
+	mux.Handle("/commands", api)
+	mux.Handle("/payments", api)
+	mux.Handle("/payments/", api)
+	mux.Handle("/failure-rate", api)
+	mux.Handle("/events", eventStream)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	server.RegisterOnShutdown(eventStream.Close)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting payments service", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"limit_cents", limit, "failure_rate", failures.Rate())
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Payments service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	slog.Info("Shutting down payments service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Payments service forced to shutdown", "error", err)
+	}
+	slog.Info("Payments service exited")
+}
+
+// loadFailureInjector reads the share of commands to fail from FAILURE_RATE
+// and the seed of its randomness from FAILURE_SEED, the current time when unset
+func loadFailureInjector() (*FailureInjector, error) {
+	rate, err := strconv.ParseFloat(config.Getenv("FAILURE_RATE", "0"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("FAILURE_RATE must be a number between 0 and 1, got %q", os.Getenv("FAILURE_RATE"))
+	}
+	seed := uint64(time.Now().UnixNano())
+	if value := os.Getenv("FAILURE_SEED"); value != "" {
+		if seed, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("FAILURE_SEED must be an unsigned integer, got %q", value)
+		}
+	}
+	return NewFailureInjector(rate, seed)
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "payments",
+		"endpoints": map[string]string{
+			"commands":     "/commands",
+			"payments":     "/payments",
+			"failure_rate": "/failure-rate",
+			"events":       "/events",
+			"health":       "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestLoadFailureInjector(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     string
+		seed     string
+		wantRate float64
+		wantErr  bool
+	}{
+		{"default", "", "", 0, false},
+		{"rate and seed", "0.3", "7", 0.3, false},
+		{"rate above 1", "3", "", 0, true},
+		{"invalid rate", "often", "", 0, true},
+		{"invalid seed", "0.1", "-1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FAILURE_RATE", tt.rate)
+			t.Setenv("FAILURE_SEED", tt.seed)
+			failures, err := loadFailureInjector()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadFailureInjector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && failures.Rate() != tt.wantRate {
+				t.Errorf("got rate %v want %v", failures.Rate(), tt.wantRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+// PaymentStatus is the state of a payment in its lifecycle
+type PaymentStatus string
+
+const (
+	// PaymentStatusReserved payments hold the amount until captured or released
+	PaymentStatusReserved PaymentStatus = "reserved"
+	// PaymentStatusCaptured payments charged the customer
+	PaymentStatusCaptured PaymentStatus = "captured"
+	// PaymentStatusReleased reservations were given back without charging
+	PaymentStatusReleased PaymentStatus = "released"
+	// PaymentStatusRefunded payments were charged, then paid back
+	PaymentStatusRefunded PaymentStatus = "refunded"
+	// PaymentStatusDeclined reservations were refused
+	PaymentStatusDeclined PaymentStatus = "declined"
+)
+
+// Types of the events recorded by the payment aggregate
+const (
+	EventTypePaymentReserved = "payment.reserved"
+	EventTypePaymentDeclined = "payment.declined"
+	EventTypePaymentCaptured = "payment.captured"
+	EventTypePaymentReleased = "payment.released"
+	EventTypePaymentRefunded = "payment.refunded"
+)
+
+// Errors of the payment aggregate
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrPaymentExists   = errors.New("payment already exists")
+	ErrInvalidChange   = errors.New("invalid payment status change")
+	ErrInjectedFailure = errors.New("injected failure")
+	ErrInvalidCommand  = errors.New("invalid command")
+)
+
+// Payment is the aggregate root of the payments service: the money of one
+// order, reserved first, then captured or given back. It is identified by its
+// order. Each status change records an event carrying a snapshot of the
+// payment.
+type Payment struct {
+	domain.AggregateRoot
+	OrderID     string        `json:"order_id"`
+	CustomerID  string        `json:"customer_id,omitempty"`
+	AmountCents int64         `json:"amount_cents"`
+	Status      PaymentStatus `json:"status"`
+	Reason      string        `json:"reason,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// ReservePayment reserves amountCents of the order for the customer,
+// recording payment.reserved. The amount must be positive and, when
+// limitCents is positive, not above it: otherwise the payment is declined,
+// recording payment.declined, and every reason is returned as
+// domain.ValidationErrors.
+func ReservePayment(orderID, customerID string, amountCents, limitCents int64, now time.Time) (*Payment, error) {
+	var errs domain.ValidationErrors
+	errs.Check(domain.Positive("amount_cents", amountCents))
+	if limitCents > 0 && amountCents > limitCents {
+		errs.Add("amount_cents", fmt.Sprintf("must not exceed the payment limit of %d cents", limitCents))
+	}
+	if err := errs.Err(); err != nil {
+		return DeclinePayment(orderID, customerID, amountCents, err, now), err
+	}
+	return newPayment(orderID, customerID, amountCents, PaymentStatusReserved, "", now), nil
+}
+
+// DeclinePayment refuses to reserve amountCents of the order for reason,
+// recording payment.declined. The declined payment is kept to be inspected.
+func DeclinePayment(orderID, customerID string, amountCents int64, reason error, now time.Time) *Payment {
+	return newPayment(orderID, customerID, amountCents, PaymentStatusDeclined, reason.Error(), now)
+}
+
+// newPayment creates the payment of an order with status, recording the
+// event of that status
+func newPayment(orderID, customerID string, amountCents int64, status PaymentStatus, reason string, now time.Time) *Payment {
+	payment := &Payment{
+		AggregateRoot: domain.AggregateRoot{ID: orderID},
+		OrderID:       orderID,
+		CustomerID:    customerID,
+		AmountCents:   amountCents,
+		Status:        status,
+		Reason:        reason,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	payment.record(statusEvents[status])
+	return payment
+}
+
+// statusEvents maps each status to the event recorded on reaching it
+var statusEvents = map[PaymentStatus]string{
+	PaymentStatusReserved: EventTypePaymentReserved,
+	PaymentStatusDeclined: EventTypePaymentDeclined,
+	PaymentStatusCaptured: EventTypePaymentCaptured,
+	PaymentStatusReleased: EventTypePaymentReleased,
+	PaymentStatusRefunded: EventTypePaymentRefunded,
+}
+
+// transitions lists the statuses each status may move to
+var transitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusReserved: {PaymentStatusCaptured, PaymentStatusReleased},
+	PaymentStatusCaptured: {PaymentStatusRefunded},
+}
+
+// transitionTo moves the payment to status, recording the event of status
+func (p *Payment) transitionTo(status PaymentStatus, reason string, now time.Time) error {
+	for _, allowed := range transitions[p.Status] {
+		if allowed == status {
+			p.Status = status
+			p.Reason = reason
+			p.UpdatedAt = now
+			p.record(statusEvents[status])
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrInvalidChange, p.Status, status)
+}
+
+// Capture charges the reserved amount, recording payment.captured
+func (p *Payment) Capture(now time.Time) error {
+	return p.transitionTo(PaymentStatusCaptured, "", now)
+}
+
+// Release gives the money back: a reservation is released, a captured
+// payment refunded, recording payment.released or payment.refunded. It is
+// the compensation of both reserve and capture.
+func (p *Payment) Release(reason string, now time.Time) error {
+	if p.Status == PaymentStatusCaptured {
+		return p.transitionTo(PaymentStatusRefunded, reason, now)
+	}
+	return p.transitionTo(PaymentStatusReleased, reason, now)
+}
+
+// record records an event of eventType carrying a snapshot of the payment at
+// the version the event brings it to
+func (p *Payment) record(eventType string) {
+	snapshot := *p
+	snapshot.ClearChanges()
+	snapshot.Version++
+	p.Record(eventType, snapshot)
+}
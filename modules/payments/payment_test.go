@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+func TestReservePayment(t *testing.T) {
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		amount     int64
+		limit      int64
+		wantStatus PaymentStatus
+		wantEvent  string
+		wantField  string
+	}{
+		{"within the limit", 100, 1000, PaymentStatusReserved, EventTypePaymentReserved, ""},
+		{"without a limit", 5000, 0, PaymentStatusReserved, EventTypePaymentReserved, ""},
+		{"zero amount", 0, 1000, PaymentStatusDeclined, EventTypePaymentDeclined, "amount_cents"},
+		{"over the limit", 5000, 1000, PaymentStatusDeclined, EventTypePaymentDeclined, "amount_cents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment, err := ReservePayment("o1", "c1", tt.amount, tt.limit, now)
+			if tt.wantField != "" {
+				var validationErr *domain.ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+					t.Fatalf("got error %v want a validation error of %s", err, tt.wantField)
+				}
+				if payment.Reason != err.Error() {
+					t.Errorf("got reason %q want %q", payment.Reason, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("ReservePayment() error = %v", err)
+			}
+			if payment.ID != "o1" || payment.Status != tt.wantStatus || payment.Version != 1 {
+				t.Errorf("got %+v want a %s payment of o1 at version 1", payment, tt.wantStatus)
+			}
+			if changes := payment.Changes(); len(changes) != 1 || changes[0].Type != tt.wantEvent {
+				t.Errorf("got changes %+v want %s", changes, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestPayment_Transitions(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		from    PaymentStatus
+		change  func(*Payment) error
+		want    PaymentStatus
+		event   string
+		wantErr bool
+	}{
+		{"capture reserved", PaymentStatusReserved, func(p *Payment) error { return p.Capture(now) }, PaymentStatusCaptured, EventTypePaymentCaptured, false},
+		{"release reserved", PaymentStatusReserved, func(p *Payment) error { return p.Release("cancelled", now) }, PaymentStatusReleased, EventTypePaymentReleased, false},
+		{"refund captured", PaymentStatusCaptured, func(p *Payment) error { return p.Release("cancelled", now) }, PaymentStatusRefunded, EventTypePaymentRefunded, false},
+		{"capture captured", PaymentStatusCaptured, func(p *Payment) error { return p.Capture(now) }, PaymentStatusCaptured, "", true},
+		{"capture released", PaymentStatusReleased, func(p *Payment) error { return p.Capture(now) }, PaymentStatusReleased, "", true},
+		{"release declined", PaymentStatusDeclined, func(p *Payment) error { return p.Release("", now) }, PaymentStatusDeclined, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &Payment{AggregateRoot: domain.AggregateRoot{ID: "o1", Version: 1}, OrderID: "o1", Status: tt.from}
+			err := tt.change(payment)
+			if tt.wantErr != errors.Is(err, ErrInvalidChange) {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if payment.Status != tt.want {
+				t.Errorf("got status %s want %s", payment.Status, tt.want)
+			}
+			if tt.wantErr {
+				if changes := payment.Changes(); len(changes) != 0 {
+					t.Errorf("got changes %+v want none", changes)
+				}
+				return
+			}
+			if payment.Version != 2 {
+				t.Errorf("got version %d want 2", payment.Version)
+			}
+			changes := payment.Changes()
+			if len(changes) != 1 || changes[0].Type != tt.event || changes[0].Data.(Payment).Status != tt.want {
+				t.Errorf("got changes %+v want %s with the %s payment", changes, tt.event, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies this service as the producer of its events
+const eventSource = "payment-service"
+
+// Types of the commands handled by the payments service
+const (
+	CommandTypeReserve = "payment.reserve"
+	CommandTypeCapture = "payment.capture"
+	CommandTypeRelease = "payment.release"
+)
+
+// EventTypePaymentCaptureFailed replies to a capture that changed nothing.
+// The other replies are the events recorded by the payment aggregate.
+const EventTypePaymentCaptureFailed = "payment.capture_failed"
+
+// Command asks the payments service to do something, e.g. on behalf of a
+// saga orchestrator. Its ID makes retries safe: a command is handled once.
+type Command struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Source string          `json:"source,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// reserveData is the data of payment.reserve
+type reserveData struct {
+	OrderID     string `json:"order_id"`
+	CustomerID  string `json:"customer_id"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+// paymentData is the data of payment.capture and payment.release
+type paymentData struct {
+	OrderID string `json:"order_id"`
+	Reason  string `json:"reason"`
+}
+
+// PaymentEventData is the payload of payment events: the payment after the
+// command, the command replied to and, for failures, why it failed
+type PaymentEventData struct {
+	Payment   Payment `json:"payment"`
+	CommandID string  `json:"command_id"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// PaymentService handles payment commands, replying to each with an event
+type PaymentService struct {
+	mutex     sync.Mutex
+	payments  map[string]*Payment
+	replies   map[string]events.Event
+	limit     int64
+	failures  *FailureInjector
+	ids       uuid.IDGenerator
+	publisher events.Publisher
+	now       func() time.Time
+}
+
+// NewPaymentService creates a service declining payments above limit cents
+// and failing the commands chosen by failures
+func NewPaymentService(limit int64, failures *FailureInjector, ids uuid.IDGenerator, publisher events.Publisher) *PaymentService {
+	return &PaymentService{
+		payments:  make(map[string]*Payment),
+		replies:   make(map[string]events.Event),
+		limit:     limit,
+		failures:  failures,
+		ids:       ids,
+		publisher: publisher,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Handle handles the command and returns the event replying to it, which is
+// also published. Commands already handled return their first reply without
+// being handled or published again. Invalid commands return an error.
+func (s *PaymentService) Handle(ctx context.Context, cmd Command) (events.Event, error) {
+	if cmd.ID == "" {
+		return events.Event{}, fmt.Errorf("%w: id is required", ErrInvalidCommand)
+	}
+
+	s.mutex.Lock()
+	if reply, ok := s.replies[cmd.ID]; ok {
+		s.mutex.Unlock()
+		slog.DebugContext(ctx, "Replaying reply of a handled command", "command_id", cmd.ID)
+		return reply, nil
+	}
+	eventType, payment, failure, err := s.handle(cmd)
+	if err != nil {
+		s.mutex.Unlock()
+		return events.Event{}, err
+	}
+	data := PaymentEventData{Payment: payment, CommandID: cmd.ID}
+	if failure != nil {
+		data.Error = failure.Error()
+	}
+	reply, err := events.New(s.ids.NewID(), eventType, eventSource, payment.OrderID, data)
+	if err != nil {
+		s.mutex.Unlock()
+		return events.Event{}, err
+	}
+	s.replies[cmd.ID] = reply
+	s.mutex.Unlock()
+
+	if err := s.publisher.Publish(ctx, reply); err != nil {
+		slog.ErrorContext(ctx, "Failed to publish event", "event_type", reply.Type, "order_id", payment.OrderID, "error", err)
+	}
+	return reply, nil
+}
+
+// handle applies the command and returns the type of its reply, the payment
+// after it and the failure reported in the reply, if any. The caller holds
+// the mutex.
+func (s *PaymentService) handle(cmd Command) (eventType string, payment Payment, failure, err error) {
+	switch cmd.Type {
+	case CommandTypeReserve:
+		var data reserveData
+		if err := decodeCommand(cmd, &data); err != nil {
+			return "", Payment{}, nil, err
+		}
+		payment, failure := s.reserve(data)
+		eventType, reply := replyTo(payment, EventTypePaymentDeclined)
+		return eventType, reply, failure, nil
+
+	case CommandTypeCapture:
+		var data paymentData
+		if err := decodeCommand(cmd, &data); err != nil {
+			return "", Payment{}, nil, err
+		}
+		payment, failure := s.capture(data.OrderID)
+		eventType, reply := replyTo(payment, EventTypePaymentCaptureFailed)
+		return eventType, reply, failure, nil
+
+	case CommandTypeRelease:
+		var data paymentData
+		if err := decodeCommand(cmd, &data); err != nil {
+			return "", Payment{}, nil, err
+		}
+		eventType, reply := replyTo(s.release(data.OrderID, data.Reason), EventTypePaymentReleased)
+		return eventType, reply, nil, nil
+
+	default:
+		return "", Payment{}, nil, fmt.Errorf("%w: unknown type %q", ErrInvalidCommand, cmd.Type)
+	}
+}
+
+// replyTo returns the type of the reply to a command and the payment after
+// it: the last event the payment recorded, or fallback and the payment as it
+// is when the command changed nothing. The recorded events are cleared.
+func replyTo(payment *Payment, fallback string) (string, Payment) {
+	changes := payment.Changes()
+	payment.ClearChanges()
+	if len(changes) == 0 {
+		return fallback, *payment
+	}
+	last := changes[len(changes)-1]
+	return last.Type, last.Data.(Payment)
+}
+
+// reserve reserves the amount of an order. Declined reservations are kept so
+// they can be inspected, and may be reserved again.
+func (s *PaymentService) reserve(data reserveData) (*Payment, error) {
+	if existing, ok := s.payments[data.OrderID]; ok && existing.Status != PaymentStatusDeclined {
+		return existing, fmt.Errorf("%w for order %s", ErrPaymentExists, data.OrderID)
+	}
+
+	now := s.now()
+	payment, failure := ReservePayment(data.OrderID, data.CustomerID, data.AmountCents, s.limit, now)
+	if failure == nil && s.failures.Fail() {
+		failure = ErrInjectedFailure
+		payment = DeclinePayment(data.OrderID, data.CustomerID, data.AmountCents, failure, now)
+	}
+	s.payments[data.OrderID] = payment
+	return payment, failure
+}
+
+// capture charges the reservation of an order
+func (s *PaymentService) capture(orderID string) (*Payment, error) {
+	payment, ok := s.payments[orderID]
+	if !ok {
+		return &Payment{OrderID: orderID}, ErrPaymentNotFound
+	}
+	if s.failures.Fail() {
+		return payment, ErrInjectedFailure
+	}
+	return payment, payment.Capture(s.now())
+}
+
+// release gives back the money of an order. As a compensation it always
+// succeeds: payments without money to give back are left unchanged.
+func (s *PaymentService) release(orderID, reason string) *Payment {
+	payment, ok := s.payments[orderID]
+	if !ok {
+		return &Payment{OrderID: orderID, Status: PaymentStatusReleased, Reason: "nothing reserved"}
+	}
+	if payment.Status == PaymentStatusReserved || payment.Status == PaymentStatusCaptured {
+		// Reserved and captured payments can always be given back
+		_ = payment.Release(reason, s.now())
+	}
+	return payment
+}
+
+// decodeCommand decodes the data of cmd into dst, which must name an order
+func decodeCommand(cmd Command, dst interface{}) error {
+	if err := json.Unmarshal(cmd.Data, dst); err != nil {
+		return fmt.Errorf("%w: %s data: %v", ErrInvalidCommand, cmd.Type, err)
+	}
+	var orderID string
+	switch data := dst.(type) {
+	case *reserveData:
+		orderID = data.OrderID
+	case *paymentData:
+		orderID = data.OrderID
+	}
+	if orderID == "" {
+		return fmt.Errorf("%w: order_id is required", ErrInvalidCommand)
+	}
+	return nil
+}
+
+// Get returns the payment of the order
+func (s *PaymentService) Get(orderID string) (*Payment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payment, ok := s.payments[orderID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	found := *payment
+	return &found, nil
+}
+
+// List returns the payments, oldest first
+func (s *PaymentService) List() []Payment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payments := make([]Payment, 0, len(s.payments))
+	for _, payment := range s.payments {
+		payments = append(payments, *payment)
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if !payments[i].CreatedAt.Equal(payments[j].CreatedAt) {
+			return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+		}
+		return payments[i].OrderID < payments[j].OrderID
+	})
+	return payments
+}
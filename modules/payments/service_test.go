@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// recordingPublisher records the published events
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func newTestService(t *testing.T, failureRate float64) (*PaymentService, *recordingPublisher) {
+	t.Helper()
+	failures, err := NewFailureInjector(failureRate, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &recordingPublisher{}
+	return NewPaymentService(10000, failures, uuid.NewSequenceGenerator("evt-"), publisher), publisher
+}
+
+// command creates a command with data encoded as JSON
+func command(id, commandType string, data interface{}) Command {
+	encoded, _ := json.Marshal(data)
+	return Command{ID: id, Type: commandType, Data: encoded}
+}
+
+// handle handles the command and returns its reply and payload
+func handle(t *testing.T, service *PaymentService, cmd Command) (events.Event, PaymentEventData) {
+	t.Helper()
+	reply, err := service.Handle(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("Handle(%s) error = %v", cmd.Type, err)
+	}
+	var data PaymentEventData
+	if err := reply.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	return reply, data
+}
+
+func TestPaymentService_ReserveCaptureRelease(t *testing.T) {
+	service, publisher := newTestService(t, 0)
+
+	reply, data := handle(t, service, command("c1", CommandTypeReserve, reserveData{OrderID: "o1", CustomerID: "alice", AmountCents: 2500}))
+	if reply.Type != EventTypePaymentReserved || data.Payment.Status != PaymentStatusReserved || data.CommandID != "c1" {
+		t.Fatalf("got %s %+v want a reservation replying to c1", reply.Type, data)
+	}
+	if reply.Source != eventSource || reply.Subject != "o1" {
+		t.Errorf("got source %q subject %q want %q and o1", reply.Source, reply.Subject, eventSource)
+	}
+
+	reply, data = handle(t, service, command("c2", CommandTypeCapture, paymentData{OrderID: "o1"}))
+	if reply.Type != EventTypePaymentCaptured || data.Payment.Status != PaymentStatusCaptured {
+		t.Fatalf("got %s %+v want a captured payment", reply.Type, data)
+	}
+
+	reply, data = handle(t, service, command("c3", CommandTypeRelease, paymentData{OrderID: "o1", Reason: "shipping failed"}))
+	if reply.Type != EventTypePaymentRefunded || data.Payment.Reason != "shipping failed" {
+		t.Fatalf("got %s %+v want a refund", reply.Type, data)
+	}
+
+	if len(publisher.events) != 3 {
+		t.Errorf("got %d published events want 3", len(publisher.events))
+	}
+	if payment, err := service.Get("o1"); err != nil || payment.Version != 3 {
+		t.Errorf("got %+v, %v want the payment at version 3", payment, err)
+	}
+}
+
+func TestPaymentService_Failures(t *testing.T) {
+	service, _ := newTestService(t, 0)
+
+	tests := []struct {
+		name     string
+		cmd      Command
+		wantType string
+		wantErr  error
+	}{
+		{"over limit", command("c1", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 20000}), EventTypePaymentDeclined, &domain.ValidationError{Field: "amount_cents"}},
+		{"zero amount", command("c2", CommandTypeReserve, reserveData{OrderID: "o2"}), EventTypePaymentDeclined, &domain.ValidationError{Field: "amount_cents"}},
+		{"reserve again after a decline", command("c3", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 100}), EventTypePaymentReserved, nil},
+		{"reserve twice", command("c4", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 100}), EventTypePaymentDeclined, ErrPaymentExists},
+		{"capture unknown", command("c5", CommandTypeCapture, paymentData{OrderID: "missing"}), EventTypePaymentCaptureFailed, ErrPaymentNotFound},
+		{"capture declined", command("c6", CommandTypeCapture, paymentData{OrderID: "o2"}), EventTypePaymentCaptureFailed, ErrInvalidChange},
+		{"release unknown", command("c7", CommandTypeRelease, paymentData{OrderID: "missing"}), EventTypePaymentReleased, nil},
+		{"release declined", command("c8", CommandTypeRelease, paymentData{OrderID: "o2"}), EventTypePaymentReleased, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, data := handle(t, service, tt.cmd)
+			if reply.Type != tt.wantType {
+				t.Errorf("got %s want %s", reply.Type, tt.wantType)
+			}
+			if tt.wantErr != nil && data.Error == "" || tt.wantErr == nil && data.Error != "" {
+				t.Errorf("got error %q want %v", data.Error, tt.wantErr)
+			}
+		})
+	}
+
+	if payment, _ := service.Get("o2"); payment.Status != PaymentStatusDeclined {
+		t.Errorf("got %s want the declined payment unchanged by its release", payment.Status)
+	}
+}
+
+func TestPaymentService_InjectedFailures(t *testing.T) {
+	service, _ := newTestService(t, 1)
+
+	reply, data := handle(t, service, command("c1", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 100}))
+	if reply.Type != EventTypePaymentDeclined || data.Error != ErrInjectedFailure.Error() {
+		t.Errorf("got %s %q want an injected decline", reply.Type, data.Error)
+	}
+
+	service.failures.SetRate(0)
+	handle(t, service, command("c2", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 100}))
+	service.failures.SetRate(1)
+	reply, _ = handle(t, service, command("c3", CommandTypeCapture, paymentData{OrderID: "o1"}))
+	if reply.Type != EventTypePaymentCaptureFailed {
+		t.Errorf("got %s want %s", reply.Type, EventTypePaymentCaptureFailed)
+	}
+
+	// Compensations never fail
+	reply, _ = handle(t, service, command("c4", CommandTypeRelease, paymentData{OrderID: "o1"}))
+	if reply.Type != EventTypePaymentReleased {
+		t.Errorf("got %s want %s", reply.Type, EventTypePaymentReleased)
+	}
+}
+
+func TestPaymentService_Idempotent(t *testing.T) {
+	service, publisher := newTestService(t, 0)
+	cmd := command("c1", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 100})
+
+	first, _ := handle(t, service, cmd)
+	second, _ := handle(t, service, cmd)
+	if first.ID != second.ID || second.Type != EventTypePaymentReserved {
+		t.Errorf("got replies %s and %s want the first reply twice", first.ID, second.ID)
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("got %d published events want 1", len(publisher.events))
+	}
+}
+
+func TestPaymentService_InvalidCommands(t *testing.T) {
+	service, publisher := newTestService(t, 0)
+
+	for name, cmd := range map[string]Command{
+		"missing id":    command("", CommandTypeReserve, reserveData{OrderID: "o1", AmountCents: 1}),
+		"unknown type":  command("c1", "payment.steal", paymentData{OrderID: "o1"}),
+		"missing order": command("c2", CommandTypeCapture, paymentData{}),
+		"invalid data":  {ID: "c3", Type: CommandTypeReserve, Data: []byte(`{"amount_cents":"lots"}`)},
+		"no data":       {ID: "c4", Type: CommandTypeRelease},
+	} {
+		if _, err := service.Handle(context.Background(), cmd); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("%s: got %v want %v", name, err, ErrInvalidCommand)
+		}
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("got %d published events want none", len(publisher.events))
+	}
+}
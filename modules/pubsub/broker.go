@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// Errors of the broker
+var (
+	ErrClosed             = errors.New("broker is closed")
+	ErrInvalidName        = errors.New("topic and group names are required")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+)
+
+// Message is a message published on a topic. Attempt counts the deliveries
+// of the message to the group handling it, starting at 1.
+type Message struct {
+	ID          string          `json:"id"`
+	Topic       string          `json:"topic"`
+	Payload     json.RawMessage `json:"payload"`
+	PublishedAt time.Time       `json:"published_at"`
+	Attempt     int             `json:"attempt"`
+}
+
+// Handler processes a message. An error asks for the message to be
+// delivered again.
+type Handler func(ctx context.Context, msg Message) error
+
+// DeadLetter is a message a group gave up on after its last attempt
+type DeadLetter struct {
+	ID       string    `json:"id"`
+	Group    string    `json:"group"`
+	Message  Message   `json:"message"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// RetryPolicy bounds the deliveries of a message to a group
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+}
+
+// GroupStats counts what happened to the messages of a consumer group
+type GroupStats struct {
+	Topic        string `json:"topic"`
+	Group        string `json:"group"`
+	Members      int    `json:"members"`
+	Queued       int    `json:"queued"`
+	Received     int64  `json:"received"`
+	Deliveries   int64  `json:"deliveries"`
+	Acked        int64  `json:"acked"`
+	Retried      int64  `json:"retried"`
+	DeadLettered int64  `json:"dead_lettered"`
+}
+
+// group is a consumer group: every group of a topic receives every message
+// of the topic, and each message goes to one member of the group
+type group struct {
+	topic        string
+	name         string
+	queue        chan Message
+	members      int
+	received     atomic.Int64
+	deliveries   atomic.Int64
+	acked        atomic.Int64
+	retried      atomic.Int64
+	deadLettered atomic.Int64
+}
+
+// Broker is a minimal in-memory message broker with topics, consumer groups,
+// retries and a dead letter queue. Messages are queued per group and handled
+// concurrently by the members of the group, each receiving its share.
+type Broker struct {
+	mutex       sync.RWMutex
+	groups      map[string][]*group
+	closed      bool
+	dlqMutex    sync.Mutex
+	deadLetters []DeadLetter
+	members     sync.WaitGroup
+	queueSize   int
+	retry       RetryPolicy
+	ids         uuid.IDGenerator
+	now         func() time.Time
+	unrouted    atomic.Int64
+}
+
+// NewBroker creates a broker queueing up to queueSize messages per group
+func NewBroker(queueSize int, retry RetryPolicy, ids uuid.IDGenerator) *Broker {
+	return &Broker{
+		groups:    make(map[string][]*group),
+		queueSize: queueSize,
+		retry:     retry,
+		ids:       ids,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Subscribe adds a member handling messages with handler to the group of the
+// topic, creating the group when it is its first member. Messages published
+// before a group exists are not delivered to it.
+func (b *Broker) Subscribe(topic, groupName string, handler Handler) error {
+	if topic == "" || groupName == "" {
+		return ErrInvalidName
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	var g *group
+	for _, existing := range b.groups[topic] {
+		if existing.name == groupName {
+			g = existing
+		}
+	}
+	if g == nil {
+		g = &group{topic: topic, name: groupName, queue: make(chan Message, b.queueSize)}
+		b.groups[topic] = append(b.groups[topic], g)
+		slog.Info("Consumer group created", "topic", topic, "group", groupName)
+	}
+	g.members++
+	member := fmt.Sprintf("%s-%d", groupName, g.members)
+	slog.Info("Member joined consumer group", "topic", topic, "group", groupName, "member", member)
+
+	b.members.Add(1)
+	go func() {
+		defer b.members.Done()
+		for msg := range g.queue {
+			b.deliver(g, member, handler, msg)
+		}
+		slog.Debug("Member stopped", "topic", topic, "group", groupName, "member", member)
+	}()
+	return nil
+}
+
+// Publish queues a message with payload for every group of the topic. It
+// blocks while the queue of a group is full, until ctx is done. Messages of
+// topics without groups are dropped.
+func (b *Broker) Publish(ctx context.Context, topic string, payload json.RawMessage) (Message, error) {
+	if topic == "" {
+		return Message{}, ErrInvalidName
+	}
+	msg := Message{ID: b.ids.NewID(), Topic: topic, Payload: payload, PublishedAt: b.now()}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.closed {
+		return Message{}, ErrClosed
+	}
+	groups := b.groups[topic]
+	if len(groups) == 0 {
+		b.unrouted.Add(1)
+		slog.WarnContext(ctx, "Message dropped, no consumer group", "topic", topic, "message_id", msg.ID)
+		return msg, nil
+	}
+	for _, g := range groups {
+		select {
+		case g.queue <- msg:
+			g.received.Add(1)
+			slog.DebugContext(ctx, "Message queued", "topic", topic, "group", g.name, "message_id", msg.ID, "queued", len(g.queue))
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+	slog.InfoContext(ctx, "Message published", "topic", topic, "message_id", msg.ID, "groups", len(groups))
+	return msg, nil
+}
+
+// deliver hands the message to handler until it succeeds or the attempts
+// of the retry policy are exhausted, then dead-letters it
+func (b *Broker) deliver(g *group, member string, handler Handler, msg Message) {
+	ctx := context.Background()
+	attempts := max(b.retry.MaxAttempts, 1)
+	for msg.Attempt = 1; ; msg.Attempt++ {
+		g.deliveries.Add(1)
+		err := handler(ctx, msg)
+		if err == nil {
+			g.acked.Add(1)
+			slog.Info("Message acked", "topic", g.topic, "group", g.name, "member", member, "message_id", msg.ID, "attempt", msg.Attempt)
+			return
+		}
+		if msg.Attempt >= attempts {
+			g.deadLettered.Add(1)
+			b.deadLetter(g, msg, err)
+			slog.Error("Message dead-lettered", "topic", g.topic, "group", g.name, "member", member, "message_id", msg.ID, "attempt", msg.Attempt, "error", err)
+			return
+		}
+		g.retried.Add(1)
+		slog.Warn("Message nacked, retrying", "topic", g.topic, "group", g.name, "member", member, "message_id", msg.ID, "attempt", msg.Attempt, "retry_in", b.retry.Delay, "error", err)
+		time.Sleep(b.retry.Delay)
+	}
+}
+
+// deadLetter records the message the group gave up on
+func (b *Broker) deadLetter(g *group, msg Message, err error) {
+	b.dlqMutex.Lock()
+	defer b.dlqMutex.Unlock()
+	b.deadLetters = append(b.deadLetters, DeadLetter{
+		ID:       b.ids.NewID(),
+		Group:    g.name,
+		Message:  msg,
+		Error:    err.Error(),
+		FailedAt: b.now(),
+	})
+}
+
+// DeadLetters returns the dead letters, oldest first
+func (b *Broker) DeadLetters() []DeadLetter {
+	b.dlqMutex.Lock()
+	defer b.dlqMutex.Unlock()
+	return append([]DeadLetter{}, b.deadLetters...)
+}
+
+// Replay removes the dead letter with id and queues its message again for
+// the group that gave up on it, e.g. once the bug making it fail is fixed
+func (b *Broker) Replay(ctx context.Context, id string) (Message, error) {
+	letter, ok := b.takeDeadLetter(id)
+	if !ok {
+		return Message{}, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	msg := letter.Message
+	msg.Attempt = 0
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	err := ErrClosed
+	if !b.closed {
+		for _, g := range b.groups[msg.Topic] {
+			if g.name != letter.Group {
+				continue
+			}
+			select {
+			case g.queue <- msg:
+				slog.InfoContext(ctx, "Dead letter replayed", "topic", g.topic, "group", g.name, "message_id", msg.ID)
+				return msg, nil
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+	}
+	// Keep the dead letter for a later replay
+	b.dlqMutex.Lock()
+	b.deadLetters = append(b.deadLetters, letter)
+	b.dlqMutex.Unlock()
+	return Message{}, err
+}
+
+// takeDeadLetter removes the dead letter with id and returns it
+func (b *Broker) takeDeadLetter(id string) (DeadLetter, bool) {
+	b.dlqMutex.Lock()
+	defer b.dlqMutex.Unlock()
+	for i, letter := range b.deadLetters {
+		if letter.ID == id {
+			b.deadLetters = append(b.deadLetters[:i], b.deadLetters[i+1:]...)
+			return letter, true
+		}
+	}
+	return DeadLetter{}, false
+}
+
+// Stats returns the counters of every group, by topic and group name, and
+// the number of messages dropped for lack of a group
+func (b *Broker) Stats() ([]GroupStats, int64) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var stats []GroupStats
+	for _, groups := range b.groups {
+		for _, g := range groups {
+			stats = append(stats, GroupStats{
+				Topic:        g.topic,
+				Group:        g.name,
+				Members:      g.members,
+				Queued:       len(g.queue),
+				Received:     g.received.Load(),
+				Deliveries:   g.deliveries.Load(),
+				Acked:        g.acked.Load(),
+				Retried:      g.retried.Load(),
+				DeadLettered: g.deadLettered.Load(),
+			})
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Topic != stats[j].Topic {
+			return stats[i].Topic < stats[j].Topic
+		}
+		return stats[i].Group < stats[j].Group
+	})
+	return stats, b.unrouted.Load()
+}
+
+// Close stops accepting messages and waits for the members to handle the
+// messages already queued
+func (b *Broker) Close() {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return
+	}
+	b.closed = true
+	for _, groups := range b.groups {
+		for _, g := range groups {
+			close(g.queue)
+		}
+	}
+	b.mutex.Unlock()
+	b.members.Wait()
+}
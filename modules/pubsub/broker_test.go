@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// recorder records the messages handled by the members of groups
+type recorder struct {
+	mutex    sync.Mutex
+	received map[string][]Message
+}
+
+func newRecorder() *recorder {
+	return &recorder{received: make(map[string][]Message)}
+}
+
+// handler returns a handler recording messages under name, failing with
+// err while fail returns true
+func (r *recorder) handler(name string, fail func(Message) bool) Handler {
+	return func(ctx context.Context, msg Message) error {
+		r.mutex.Lock()
+		r.received[name] = append(r.received[name], msg)
+		r.mutex.Unlock()
+		if fail != nil && fail(msg) {
+			return errors.New("failed")
+		}
+		return nil
+	}
+}
+
+// count returns how many messages name handled
+func (r *recorder) count(name string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.received[name])
+}
+
+func newTestBroker(maxAttempts int) *Broker {
+	return NewBroker(10, RetryPolicy{MaxAttempts: maxAttempts}, uuid.NewSequenceGenerator("id-"))
+}
+
+// publish publishes n messages on topic
+func publish(t *testing.T, broker *Broker, topic string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := broker.Publish(context.Background(), topic, json.RawMessage(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBrokerConsumerGroups(t *testing.T) {
+	broker := newTestBroker(1)
+	rec := newRecorder()
+	// Each billing member blocks on its first message, so the second message
+	// can only be handled by the other member
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	for _, member := range []string{"billing-1", "billing-2"} {
+		handler := rec.handler(member, nil)
+		var once sync.Once
+		subscribe(t, broker, "orders", "billing", func(ctx context.Context, msg Message) error {
+			once.Do(func() {
+				started <- struct{}{}
+				<-release
+			})
+			return handler(ctx, msg)
+		})
+	}
+	subscribe(t, broker, "orders", "emails", rec.handler("emails", nil))
+	subscribe(t, broker, "payments", "billing", rec.handler("payments", nil))
+
+	publish(t, broker, "orders", 2)
+	<-started
+	<-started
+	close(release)
+	publish(t, broker, "orders", 3)
+	broker.Close()
+
+	if rec.count("billing-1") == 0 || rec.count("billing-2") == 0 {
+		t.Errorf("got %d and %d messages handled by the billing members want both to share them", rec.count("billing-1"), rec.count("billing-2"))
+	}
+	if got := rec.count("billing-1") + rec.count("billing-2"); got != 5 {
+		t.Errorf("got %d messages handled by billing want 5, each once", got)
+	}
+	if got := rec.count("emails"); got != 5 {
+		t.Errorf("got %d messages handled by emails want 5", got)
+	}
+	if got := rec.count("payments"); got != 0 {
+		t.Errorf("got %d messages of another topic want 0", got)
+	}
+
+	stats, unrouted := broker.Stats()
+	if len(stats) != 3 || stats[0].Group != "billing" || stats[0].Members != 2 || stats[0].Acked != 5 || stats[1].Group != "emails" || stats[1].Received != 5 {
+		t.Errorf("got %+v want billing and emails of orders, then billing of payments", stats)
+	}
+	if unrouted != 0 {
+		t.Errorf("got %d unrouted messages want 0", unrouted)
+	}
+}
+
+func TestBrokerDeadLetters(t *testing.T) {
+	broker := newTestBroker(3)
+	rec := newRecorder()
+	fixed := false
+	var mutex sync.Mutex
+	subscribe(t, broker, "orders", "emails", rec.handler("emails", func(msg Message) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return !fixed
+	}))
+
+	publish(t, broker, "orders", 1)
+	waitFor(t, func() bool { return len(broker.DeadLetters()) == 1 })
+	letter := broker.DeadLetters()[0]
+	if letter.Group != "emails" || letter.Message.Attempt != 3 || letter.Error != "failed" {
+		t.Errorf("got %+v want the message of emails after 3 attempts", letter)
+	}
+	if got := rec.count("emails"); got != 3 {
+		t.Errorf("got %d deliveries want 3", got)
+	}
+
+	mutex.Lock()
+	fixed = true
+	mutex.Unlock()
+	if _, err := broker.Replay(context.Background(), letter.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Replay(context.Background(), letter.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("got %v want %v", err, ErrDeadLetterNotFound)
+	}
+	broker.Close()
+
+	if got := rec.count("emails"); got != 4 {
+		t.Errorf("got %d deliveries want 4 after the replay", got)
+	}
+	if letters := broker.DeadLetters(); len(letters) != 0 {
+		t.Errorf("got %v want no dead letter", letters)
+	}
+	stats, _ := broker.Stats()
+	if stats[0].Deliveries != 4 || stats[0].Retried != 2 || stats[0].DeadLettered != 1 || stats[0].Acked != 1 {
+		t.Errorf("got %+v want 4 deliveries, 2 retries, 1 dead letter and 1 ack", stats[0])
+	}
+}
+
+func TestBrokerUnrouted(t *testing.T) {
+	broker := newTestBroker(1)
+	publish(t, broker, "nobody", 2)
+	if _, unrouted := broker.Stats(); unrouted != 2 {
+		t.Errorf("got %d unrouted messages want 2", unrouted)
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	broker := newTestBroker(1)
+	rec := newRecorder()
+	subscribe(t, broker, "orders", "emails", rec.handler("emails", nil))
+	publish(t, broker, "orders", 3)
+	broker.Close()
+	broker.Close()
+
+	if got := rec.count("emails"); got != 3 {
+		t.Errorf("got %d messages handled want the 3 queued before closing", got)
+	}
+	if _, err := broker.Publish(context.Background(), "orders", json.RawMessage(`{}`)); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v want %v", err, ErrClosed)
+	}
+	if err := broker.Subscribe("orders", "emails", rec.handler("emails", nil)); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v want %v", err, ErrClosed)
+	}
+}
+
+func TestBrokerInvalidNames(t *testing.T) {
+	broker := newTestBroker(1)
+	if err := broker.Subscribe("", "emails", newRecorder().handler("emails", nil)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("got %v want %v", err, ErrInvalidName)
+	}
+	if err := broker.Subscribe("orders", "", newRecorder().handler("emails", nil)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("got %v want %v", err, ErrInvalidName)
+	}
+	if _, err := broker.Publish(context.Background(), "", json.RawMessage(`{}`)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("got %v want %v", err, ErrInvalidName)
+	}
+}
+
+func subscribe(t *testing.T, broker *Broker, topic, group string, handler Handler) {
+	t.Helper()
+	if err := broker.Subscribe(topic, group, handler); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor waits until condition holds, failing the test after a while
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// demoTopic is the topic the demo groups subscribe to
+const demoTopic = "orders"
+
+// errDemoFailure is the failure the emails group reports for payloads asking for it
+var errDemoFailure = errors.New("payload asked to fail")
+
+// demoPayload is the part of a payload the demo handlers read: whether
+// handling it fails, and how long the work takes
+type demoPayload struct {
+	Fail   bool `json:"fail"`
+	WorkMS int  `json:"work_ms"`
+}
+
+// subscribeDemo subscribes the demo consumer groups to the orders topic:
+// billing, with two members sharing the messages, and emails, with a single
+// member failing the payloads with "fail": true until they are dead-lettered
+func subscribeDemo(broker *Broker) error {
+	for _, subscription := range []struct {
+		group   string
+		handler Handler
+	}{
+		{"billing", work(false)},
+		{"billing", work(false)},
+		{"emails", work(true)},
+	} {
+		if err := broker.Subscribe(demoTopic, subscription.group, subscription.handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// work returns a handler taking the time asked by the payload, and failing
+// when the payload asks for it and mayFail is set
+func work(mayFail bool) Handler {
+	return func(ctx context.Context, msg Message) error {
+		var payload demoPayload
+		// Payloads of another shape are handled as is
+		_ = json.Unmarshal(msg.Payload, &payload)
+
+		select {
+		case <-time.After(time.Duration(payload.WorkMS) * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if mayFail && payload.Fail {
+			return errDemoFailure
+		}
+		return nil
+	}
+}
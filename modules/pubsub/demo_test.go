@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWork(t *testing.T) {
+	tests := []struct {
+		name    string
+		mayFail bool
+		payload string
+		wantErr error
+	}{
+		{"ack", false, `{"order_id":"o1"}`, nil},
+		{"fail ignored", false, `{"fail":true}`, nil},
+		{"fail", true, `{"fail":true}`, errDemoFailure},
+		{"other shape", true, `[1,2]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := work(tt.mayFail)(context.Background(), Message{Payload: json.RawMessage(tt.payload)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := work(false)(ctx, Message{Payload: json.RawMessage(`{"work_ms":60000}`)}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v want %v", err, context.Canceled)
+	}
+}
+
+func TestSubscribeDemo(t *testing.T) {
+	broker := newTestBroker(2)
+	if err := subscribeDemo(broker); err != nil {
+		t.Fatal(err)
+	}
+	publish(t, broker, demoTopic, 1)
+	if _, err := broker.Publish(context.Background(), demoTopic, json.RawMessage(`{"fail":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	broker.Close()
+
+	stats, _ := broker.Stats()
+	if len(stats) != 2 || stats[0].Group != "billing" || stats[0].Members != 2 || stats[0].Acked != 2 {
+		t.Fatalf("got %+v want billing acking both messages", stats)
+	}
+	if stats[1].Group != "emails" || stats[1].Acked != 1 || stats[1].DeadLettered != 1 {
+		t.Errorf("got %+v want emails dead-lettering the failing message", stats[1])
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// statsBody is the body of GET /stats
+type statsBody struct {
+	Groups   []GroupStats `json:"groups"`
+	Unrouted int64        `json:"unrouted"`
+}
+
+// BrokerHandler serves the HTTP API of the broker
+type BrokerHandler struct {
+	broker *Broker
+	mux    *http.ServeMux
+}
+
+// NewBrokerHandler creates the handler of the topics, stats and dead letters routes
+func NewBrokerHandler(broker *Broker) *BrokerHandler {
+	h := &BrokerHandler{broker: broker, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /topics/{topic}/messages", h.handlePublish)
+	h.mux.HandleFunc("GET /stats", h.handleStats)
+	h.mux.HandleFunc("GET /dead-letters", h.handleListDeadLetters)
+	h.mux.HandleFunc("POST /dead-letters/{id}/replay", h.handleReplay)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *BrokerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handlePublish publishes the JSON body as the payload of a message
+func (h *BrokerHandler) handlePublish(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid body: "+err.Error())
+		return
+	}
+	if !json.Valid(payload) {
+		problem.Write(w, r, http.StatusBadRequest, "The payload must be JSON")
+		return
+	}
+
+	msg, err := h.broker.Publish(r.Context(), r.PathValue("topic"), payload)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusAccepted, msg)
+}
+
+// handleStats returns the counters of the consumer groups
+func (h *BrokerHandler) handleStats(w http.ResponseWriter, r *http.Request) {
+	groups, unrouted := h.broker.Stats()
+	if groups == nil {
+		groups = []GroupStats{}
+	}
+	httpx.WriteJSON(w, http.StatusOK, statsBody{Groups: groups, Unrouted: unrouted})
+}
+
+// handleListDeadLetters lists the dead letters
+func (h *BrokerHandler) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.broker.DeadLetters())
+}
+
+// handleReplay queues the message of a dead letter again
+func (h *BrokerHandler) handleReplay(w http.ResponseWriter, r *http.Request) {
+	msg, err := h.broker.Replay(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusAccepted, msg)
+}
+
+// writeError writes the problem matching a broker error
+func (h *BrokerHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrDeadLetterNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrClosed):
+		problem.Write(w, r, http.StatusServiceUnavailable, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
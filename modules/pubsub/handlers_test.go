@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBrokerHandler(t *testing.T) {
+	broker := newTestBroker(1)
+	subscribe(t, broker, "orders", "emails", newRecorder().handler("emails", func(Message) bool { return true }))
+	handler := NewBrokerHandler(broker)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"publish", http.MethodPost, "/topics/orders/messages", `{"order_id":"o1"}`, http.StatusAccepted},
+		{"publish without groups", http.MethodPost, "/topics/nobody/messages", `"hello"`, http.StatusAccepted},
+		{"invalid JSON", http.MethodPost, "/topics/orders/messages", `{`, http.StatusBadRequest},
+		{"stats", http.MethodGet, "/stats", "", http.StatusOK},
+		{"replay missing", http.MethodPost, "/dead-letters/missing/replay", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	waitFor(t, func() bool { return len(broker.DeadLetters()) == 1 })
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dead-letters", nil))
+	var letters []DeadLetter
+	if err := json.NewDecoder(rec.Body).Decode(&letters); err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || string(letters[0].Message.Payload) != `{"order_id":"o1"}` {
+		t.Fatalf("got %+v want the dead letter of the published message", letters)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dead-letters/"+letters[0].ID+"/replay", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("got status %d want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+
+	broker.Close()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/topics/orders/messages", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d want %d after closing", rec.Code, http.StatusServiceUnavailable)
+	}
+	if _, unrouted := broker.Stats(); unrouted != 1 {
+		t.Errorf("got %d unrouted messages want 1", unrouted)
+	}
+}
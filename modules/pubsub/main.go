@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort        = "8088"
+	defaultHost        = "localhost"
+	defaultQueueSize   = 100
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = 500 * time.Millisecond
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	queueSize, retry, err := loadBrokerConfig()
+	if err != nil {
+		fatal("Invalid broker configuration", "error", err)
+	}
+
+	// The demo consumer groups subscribe before anything is published
+	broker := NewBroker(queueSize, retry, uuid.GeneratorFunc(uuid.NewGoogle))
+	if err := subscribeDemo(broker); err != nil {
+		fatal("Failed to subscribe the demo consumer groups", "error", err)
+	}
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewBrokerHandler(broker)
+	mux.Handle("/topics/", api)
+	mux.Handle("/stats", api)
+	mux.Handle("/dead-letters", api)
+	mux.Handle("/dead-letters/", api)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting pub/sub broker", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"queue_size", queueSize, "max_attempts", retry.MaxAttempts, "retry_delay", retry.Delay)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Pub/sub broker failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down pub/sub broker")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Pub/sub broker forced to shutdown", "error", err)
+	}
+	// Handle the messages still queued before exiting
+	broker.Close()
+	slog.Info("Pub/sub broker exited")
+}
+
+// loadBrokerConfig reads the queue size of the groups from QUEUE_SIZE and
+// the retry policy from MAX_ATTEMPTS and RETRY_DELAY
+func loadBrokerConfig() (int, RetryPolicy, error) {
+	queueSize := defaultQueueSize
+	retry := RetryPolicy{MaxAttempts: defaultMaxAttempts, Delay: defaultRetryDelay}
+	for _, setting := range []struct {
+		key   string
+		value *int
+	}{{"QUEUE_SIZE", &queueSize}, {"MAX_ATTEMPTS", &retry.MaxAttempts}} {
+		if value := os.Getenv(setting.key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return 0, RetryPolicy{}, fmt.Errorf("%s must be a positive integer, got %q", setting.key, value)
+			}
+			*setting.value = n
+		}
+	}
+	if value := os.Getenv("RETRY_DELAY"); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return 0, RetryPolicy{}, fmt.Errorf("RETRY_DELAY must be a duration that is not negative, got %q", value)
+		}
+		retry.Delay = delay
+	}
+	return queueSize, retry, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "pubsub",
+		"endpoints": map[string]string{
+			"publish":      "/topics/{topic}/messages",
+			"stats":        "/stats",
+			"dead_letters": "/dead-letters",
+			"health":       "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestLoadBrokerConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		queueSize     string
+		maxAttempts   string
+		retryDelay    string
+		wantQueueSize int
+		wantRetry     RetryPolicy
+		wantErr       bool
+	}{
+		{"defaults", "", "", "", defaultQueueSize, RetryPolicy{MaxAttempts: defaultMaxAttempts, Delay: defaultRetryDelay}, false},
+		{"custom", "5", "1", "0s", 5, RetryPolicy{MaxAttempts: 1}, false},
+		{"invalid queue size", "0", "", "", 0, RetryPolicy{}, true},
+		{"invalid attempts", "", "many", "", 0, RetryPolicy{}, true},
+		{"negative delay", "", "", "-1s", 0, RetryPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("QUEUE_SIZE", tt.queueSize)
+			t.Setenv("MAX_ATTEMPTS", tt.maxAttempts)
+			t.Setenv("RETRY_DELAY", tt.retryDelay)
+			queueSize, retry, err := loadBrokerConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadBrokerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if queueSize != tt.wantQueueSize || retry != tt.wantRetry {
+				t.Errorf("got %d %+v want %d %+v", queueSize, retry, tt.wantQueueSize, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// ReportHandler lists the reports and builds them on demand
+type ReportHandler struct {
+	source EventSource
+	mux    *http.ServeMux
+}
+
+// NewReportHandler creates the handler of the reports routes
+func NewReportHandler(source EventSource) *ReportHandler {
+	h := &ReportHandler{source: source, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /reports", h.handleListReports)
+	h.mux.HandleFunc("GET /reports/{name}", h.handleReport)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *ReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleListReports handles GET /reports
+func (h *ReportHandler) handleListReports(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, reports)
+}
+
+// handleReport handles GET /reports/{name}?format=&since=&until=&type=. The
+// report is streamed as its rows are computed. The response starts with the
+// first row, so an event store failing before it gets a problem; a failure
+// afterwards aborts the response, which clients see as an incomplete
+// download rather than a complete but truncated report.
+func (h *ReportHandler) handleReport(w http.ResponseWriter, r *http.Request) {
+	report, ok := findReport(r.PathValue("name"))
+	if !ok {
+		problem.Write(w, r, http.StatusNotFound, fmt.Sprintf("No report %q", r.PathValue("name")))
+		return
+	}
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := formats[formatName]
+	if !ok {
+		problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("format must be csv or xlsx, got %q", formatName))
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var table TableWriter
+	started := false
+	start := func() error {
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", report.Name+format.Extension))
+		w.WriteHeader(http.StatusOK)
+		started = true
+		t, err := format.New(w, report.Name, report.Columns)
+		if err != nil {
+			return err
+		}
+		table = t
+		header := make([]string, len(report.Columns))
+		for i, column := range report.Columns {
+			header[i] = column.Name
+		}
+		return table.WriteRow(header)
+	}
+
+	err = report.Run(r.Context(), h.source, filter, func(row []string) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return table.WriteRow(row)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = table.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	if !started {
+		slog.ErrorContext(r.Context(), "Report failed", "report", report.Name, "error", err)
+		problem.Write(w, r, http.StatusBadGateway, "The event store could not be read")
+		return
+	}
+	slog.ErrorContext(r.Context(), "Report aborted", "report", report.Name, "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+// parseFilter reads the time bounds of GET /reports/{name}, RFC 3339 times
+// or dates, and the event type pattern of reports reading every event
+func parseFilter(r *http.Request) (EventFilter, error) {
+	query := r.URL.Query()
+	filter := EventFilter{Type: query.Get("type")}
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.Parse(dayLayout, value); err != nil {
+				return EventFilter{}, fmt.Errorf("%s must be an RFC 3339 time or a date, got %q", bound.name, value)
+			}
+		}
+		*bound.value = t
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return EventFilter{}, fmt.Errorf("since must be before until")
+	}
+	return filter, nil
+}
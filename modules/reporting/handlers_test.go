@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+func TestReportHandler(t *testing.T) {
+	handler := NewReportHandler(testEvents())
+	tests := []struct {
+		name            string
+		target          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"list", "/reports", http.StatusOK, "application/json", `"name":"users-created-weekly"`},
+		{"csv", "/reports/users-created-weekly", http.StatusOK, "text/csv; charset=utf-8", "week,users_created\n2025-01-06,2\n2025-01-13,0\n2025-01-20,1\n"},
+		{"csv with dates", "/reports/users-created-weekly?since=2025-01-13&until=2025-01-27T00:00:00Z", http.StatusOK, "text/csv; charset=utf-8", "week,users_created\n2025-01-20,1\n"},
+		{"empty", "/reports/orders-daily?since=2030-01-01", http.StatusOK, "text/csv; charset=utf-8", "day,orders_placed,orders_cancelled,revenue_cents\n"},
+		{"xlsx", "/reports/events?format=xlsx&type=order.*", http.StatusOK, formats["xlsx"].ContentType, "PK"},
+		{"unknown report", "/reports/revenue", http.StatusNotFound, problem.ContentType, ""},
+		{"unknown format", "/reports/events?format=pdf", http.StatusBadRequest, problem.ContentType, ""},
+		{"invalid since", "/reports/events?since=yesterday", http.StatusBadRequest, problem.ContentType, ""},
+		{"empty range", "/reports/events?since=2025-01-02&until=2025-01-01", http.StatusBadRequest, problem.ContentType, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("got Content-Type %q want %q", got, tt.wantContentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got body %q want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports/orders-daily?format=xlsx", nil))
+	if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="orders-daily.xlsx"`; got != want {
+		t.Errorf("got Content-Disposition %q want %q", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	var listed []Report
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed) != len(reports) {
+		t.Errorf("got %d reports, %v want %d", len(listed), err, len(reports))
+	}
+}
+
+func TestReportHandler_SourceErrors(t *testing.T) {
+	failing := testEvents()
+	failing.err = errors.New("connection reset")
+
+	// Aggregates emit no row before the events are read: the error is a problem
+	rec := httptest.NewRecorder()
+	NewReportHandler(failing).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports/users-created-weekly", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("got status %d want %d", rec.Code, http.StatusBadGateway)
+	}
+
+	// Listed events are already streaming: the response is aborted, before
+	// or after the buffered start of the body reached the client
+	server := httptest.NewServer(NewReportHandler(failing))
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL + "/reports/events")
+	if err == nil {
+		defer resp.Body.Close()
+		if _, err := io.ReadAll(resp.Body); err == nil {
+			t.Errorf("got status %d and a complete body want the download aborted", resp.StatusCode)
+		}
+	}
+}
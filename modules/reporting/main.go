@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+const (
+	defaultPort          = "8094"
+	defaultHost          = "localhost"
+	defaultEventStoreURL = "http://localhost:8085"
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	source, err := loadSource()
+	if err != nil {
+		fatal("Invalid event store", "error", err)
+	}
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewReportHandler(source)
+	mux.Handle("/reports", api)
+	mux.Handle("/reports/", api)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server. Reports are streamed for as long as they take, so
+	// responses have no write timeout.
+	server := &http.Server{
+		Addr:        fmt.Sprintf("%s:%s", host, port),
+		Handler:     logging.Middleware(mux),
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting reporting service", "url", fmt.Sprintf("http://%s:%s", host, port), "event_store", describeSource(source))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Reporting service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down reporting service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Reporting service forced to shutdown", "error", err)
+	}
+	slog.Info("Reporting service exited")
+}
+
+// loadSource creates the event store source: the history file of the audit
+// service at EVENT_STORE_FILE when set, its query API at EVENT_STORE_URL
+// otherwise
+func loadSource() (EventSource, error) {
+	if path := os.Getenv("EVENT_STORE_FILE"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("EVENT_STORE_FILE: %w", err)
+		}
+		return &FileSource{Path: path}, nil
+	}
+	address := config.Getenv("EVENT_STORE_URL", defaultEventStoreURL)
+	if u, err := url.Parse(address); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("EVENT_STORE_URL must be an http(s) URL, got %q", address)
+	}
+	return &AuditSource{URL: address, Client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// describeSource returns where source reads events from, for logs
+func describeSource(source EventSource) string {
+	switch source := source.(type) {
+	case *FileSource:
+		return source.Path
+	case *AuditSource:
+		return source.URL
+	}
+	return fmt.Sprintf("%T", source)
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "reporting",
+		"endpoints": map[string]string{
+			"reports": "/reports",
+			"report":  "/reports/{name}?format=csv|xlsx&since=&until=",
+			"health":  "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
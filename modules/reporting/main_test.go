@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSource(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(history, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", "", defaultEventStoreURL, false},
+		{"audit service", "", "https://audit.example.com", "https://audit.example.com", false},
+		{"history file", history, "", history, false},
+		{"missing file", filepath.Join(t.TempDir(), "missing.jsonl"), "", "", true},
+		{"invalid url", "", "audit:8085", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EVENT_STORE_FILE", tt.file)
+			t.Setenv("EVENT_STORE_URL", tt.url)
+			source, err := loadSource()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && describeSource(source) != tt.want {
+				t.Errorf("got %s want %s", describeSource(source), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// dayLayout formats the days and weeks of reports
+const dayLayout = "2006-01-02"
+
+// Column is a column of a report. Numeric columns are written as numbers in
+// spreadsheets.
+type Column struct {
+	Name    string `json:"name"`
+	Numeric bool   `json:"numeric,omitempty"`
+}
+
+// emitFunc writes a row of a report
+type emitFunc func(row []string) error
+
+// builder computes the rows of one run of a report from its events
+type builder interface {
+	// Add reads the next record, emitting the rows it completes
+	Add(record Record, emit emitFunc) error
+	// Finish emits the remaining rows once every record was read
+	Finish(emit emitFunc) error
+}
+
+// Report is a report built on demand from the event store
+type Report struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Columns     []Column `json:"columns"`
+	// Type is the event type pattern the report reads, every event when
+	// empty, in which case the type parameter may narrow it
+	Type       string `json:"-"`
+	newBuilder func() builder
+}
+
+// Run reads the events of the report selected by filter from source and
+// emits its rows. Reports listing events emit each row as soon as its event
+// is read; aggregates keep one counter per period.
+func (r Report) Run(ctx context.Context, source EventSource, filter EventFilter, emit emitFunc) error {
+	if r.Type != "" {
+		filter.Type = r.Type
+	}
+	b := r.newBuilder()
+	if err := source.Events(ctx, filter, func(record Record) error {
+		return b.Add(record, emit)
+	}); err != nil {
+		return err
+	}
+	return b.Finish(emit)
+}
+
+// reports are the available reports
+var reports = []Report{
+	{
+		Name:        "users-created-weekly",
+		Description: "Users created per week, weeks starting on Monday (UTC)",
+		Columns:     []Column{{Name: "week"}, {Name: "users_created", Numeric: true}},
+		Type:        "user.created",
+		newBuilder: func() builder {
+			return newPeriodCounter(weekStart, 7, 1, func(_ events.Event, counts []int64) error {
+				counts[0]++
+				return nil
+			})
+		},
+	},
+	{
+		Name:        "orders-daily",
+		Description: "Orders placed and cancelled per day (UTC) and the revenue they leave",
+		Columns: []Column{
+			{Name: "day"},
+			{Name: "orders_placed", Numeric: true},
+			{Name: "orders_cancelled", Numeric: true},
+			{Name: "revenue_cents", Numeric: true},
+		},
+		Type:       "order.*",
+		newBuilder: func() builder { return newPeriodCounter(dayStart, 1, 3, countOrder) },
+	},
+	{
+		Name:        "events",
+		Description: "Every event with its metadata, in the order of the event store",
+		Columns: []Column{
+			{Name: "sequence", Numeric: true},
+			{Name: "time"},
+			{Name: "id"},
+			{Name: "type"},
+			{Name: "source"},
+			{Name: "subject"},
+			{Name: "tenant"},
+			{Name: "actor"},
+			{Name: "request_id"},
+		},
+		newBuilder: func() builder { return eventLister{} },
+	},
+}
+
+// orderData is the part of the payload of order events read by reports
+type orderData struct {
+	Order struct {
+		TotalCents int64 `json:"total_cents"`
+	} `json:"order"`
+}
+
+// countOrder adds an order event to the orders placed, the orders cancelled
+// and the revenue of its day
+func countOrder(e events.Event, counts []int64) error {
+	var data orderData
+	switch e.Type {
+	case "order.placed":
+		if err := e.Decode(&data); err != nil {
+			return err
+		}
+		counts[0]++
+		counts[2] += data.Order.TotalCents
+	case "order.cancelled":
+		if err := e.Decode(&data); err != nil {
+			return err
+		}
+		counts[1]++
+		counts[2] -= data.Order.TotalCents
+	}
+	return nil
+}
+
+// findReport returns the report called name
+func findReport(name string) (Report, bool) {
+	for _, report := range reports {
+		if report.Name == name {
+			return report, true
+		}
+	}
+	return Report{}, false
+}
+
+// eventLister emits a row per event
+type eventLister struct{}
+
+// Add emits the row of the event of record
+func (eventLister) Add(record Record, emit emitFunc) error {
+	e := record.Event
+	return emit([]string{
+		strconv.FormatInt(record.Sequence, 10),
+		e.Time.UTC().Format(time.RFC3339),
+		e.ID, e.Type, e.Source, e.Subject, e.Tenant, e.Actor, e.RequestID,
+	})
+}
+
+// Finish emits nothing: every row was emitted
+func (eventLister) Finish(emitFunc) error { return nil }
+
+// periodCounter counts events per period, a day or a week, and emits a row
+// per period from the first to the last with events, empty periods included
+type periodCounter struct {
+	start    func(time.Time) time.Time
+	days     int
+	counters int
+	count    func(e events.Event, counts []int64) error
+	counts   map[time.Time][]int64
+}
+
+// newPeriodCounter creates a counter of periods of days, starting at the
+// time returned by start. count adds an event to the counters of its period.
+func newPeriodCounter(start func(time.Time) time.Time, days, counters int, count func(events.Event, []int64) error) *periodCounter {
+	return &periodCounter{
+		start:    start,
+		days:     days,
+		counters: counters,
+		count:    count,
+		counts:   make(map[time.Time][]int64),
+	}
+}
+
+// Add counts the event of record in its period
+func (c *periodCounter) Add(record Record, _ emitFunc) error {
+	period := c.start(record.Event.Time)
+	counts, ok := c.counts[period]
+	if !ok {
+		counts = make([]int64, c.counters)
+		c.counts[period] = counts
+	}
+	return c.count(record.Event, counts)
+}
+
+// Finish emits the row of every period in order
+func (c *periodCounter) Finish(emit emitFunc) error {
+	var first, last time.Time
+	for period := range c.counts {
+		if first.IsZero() || period.Before(first) {
+			first = period
+		}
+		if period.After(last) {
+			last = period
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	for period := first; !period.After(last); period = period.AddDate(0, 0, c.days) {
+		row := []string{period.Format(dayLayout)}
+		counts := c.counts[period]
+		for i := range c.counters {
+			var count int64
+			if counts != nil {
+				count = counts[i]
+			}
+			row = append(row, strconv.FormatInt(count, 10))
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dayStart returns the start of the UTC day of t
+func dayStart(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// weekStart returns the start of the UTC week of t, on Monday
+func weekStart(t time.Time) time.Time {
+	day := dayStart(t)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sliceSource is an event store of records in memory
+type sliceSource struct {
+	records []Record
+	// err is returned after the records, when set
+	err error
+}
+
+func (s sliceSource) Events(_ context.Context, filter EventFilter, fn func(Record) error) error {
+	for _, record := range s.records {
+		if !filter.matches(record.Event) {
+			continue
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+// testEvents are users created over three weeks and orders over two days
+func testEvents() sliceSource {
+	monday := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	order := func(totalCents int64) map[string]interface{} {
+		return map[string]interface{}{"order": map[string]int64{"total_cents": totalCents}}
+	}
+	return sliceSource{records: []Record{
+		testRecord(1, "user.created", monday, nil),
+		testRecord(2, "user.created", monday.AddDate(0, 0, 6), nil),
+		testRecord(3, "user.updated", monday.AddDate(0, 0, 6), nil),
+		testRecord(4, "order.placed", monday, order(1500)),
+		testRecord(5, "order.placed", monday.Add(time.Hour), order(500)),
+		testRecord(6, "order.cancelled", monday.AddDate(0, 0, 2), order(500)),
+		testRecord(7, "user.created", monday.AddDate(0, 0, 14), nil),
+	}}
+}
+
+// runReport returns the rows of the report called name
+func runReport(t *testing.T, name string, source EventSource, filter EventFilter) [][]string {
+	t.Helper()
+	report, ok := findReport(name)
+	if !ok {
+		t.Fatalf("no report %q", name)
+	}
+	rows := [][]string{}
+	if err := report.Run(context.Background(), source, filter, func(row []string) error {
+		if len(row) != len(report.Columns) {
+			t.Errorf("got row %v want %d columns", row, len(report.Columns))
+		}
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return rows
+}
+
+func TestReports(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   [][]string
+	}{
+		{
+			name: "users-created-weekly",
+			want: [][]string{{"2025-01-06", "2"}, {"2025-01-13", "0"}, {"2025-01-20", "1"}},
+		},
+		{
+			name:   "users-created-weekly",
+			filter: EventFilter{Type: "order.*", Since: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+			want:   [][]string{{"2025-01-06", "1"}, {"2025-01-13", "0"}, {"2025-01-20", "1"}},
+		},
+		{
+			name: "orders-daily",
+			want: [][]string{{"2025-01-06", "2", "0", "2000"}, {"2025-01-07", "0", "0", "0"}, {"2025-01-08", "0", "1", "-500"}},
+		},
+		{
+			name:   "events",
+			filter: EventFilter{Type: "user.*", Until: time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)},
+			want: [][]string{
+				{"1", "2025-01-06T09:00:00Z", "evt-1", "user.created", "test", "1", "", "", ""},
+				{"2", "2025-01-12T09:00:00Z", "evt-2", "user.created", "test", "1", "", "", ""},
+				{"3", "2025-01-12T09:00:00Z", "evt-3", "user.updated", "test", "1", "", "", ""},
+			},
+		},
+		{
+			name:   "orders-daily",
+			filter: EventFilter{Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+			want:   [][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runReport(t, tt.name, testEvents(), tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_SourceError(t *testing.T) {
+	source := testEvents()
+	source.err = errors.New("connection reset")
+	report, _ := findReport("users-created-weekly")
+	emitted := 0
+	err := report.Run(context.Background(), source, EventFilter{}, func([]string) error {
+		emitted++
+		return nil
+	})
+	if !errors.Is(err, source.err) || emitted != 0 {
+		t.Errorf("got error %v and %d rows want %v and no rows of an incomplete aggregate", err, emitted, source.err)
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), "2025-01-06"},
+		{time.Date(2025, 1, 12, 23, 59, 0, 0, time.UTC), "2025-01-06"},
+		{time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), "2024-12-30"},
+		// Monday 01:00 in Paris is still Sunday in UTC
+		{time.Date(2025, 1, 13, 0, 30, 0, 0, time.FixedZone("CET", 3600)), "2025-01-06"},
+	}
+	for _, tt := range tests {
+		if got := weekStart(tt.t).Format(dayLayout); got != tt.want {
+			t.Errorf("weekStart(%v) got %s want %s", tt.t, got, tt.want)
+		}
+	}
+}
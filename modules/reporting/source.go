@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// auditPageSize is the number of records fetched from the audit service at
+// once, the most it serves
+const auditPageSize = 1000
+
+// Record is an event of the event store with its position there
+type Record struct {
+	Sequence int64        `json:"sequence"`
+	Event    events.Event `json:"event"`
+}
+
+// EventFilter selects the events a report reads. Empty fields match every event.
+type EventFilter struct {
+	Type  string    // event type pattern, see events.Match
+	Since time.Time // inclusive, on the event time
+	Until time.Time // exclusive, on the event time
+}
+
+// matches reports whether event is selected by f
+func (f EventFilter) matches(event events.Event) bool {
+	return (f.Type == "" || events.Match(f.Type, event.Type)) &&
+		(f.Since.IsZero() || !event.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || event.Time.Before(f.Until))
+}
+
+// EventSource reads the event store
+type EventSource interface {
+	// Events calls fn with each record selected by filter, in sequence
+	// order, without holding the whole store in memory. It stops at the
+	// first error of fn and returns it.
+	Events(ctx context.Context, filter EventFilter, fn func(Record) error) error
+}
+
+// AuditSource reads the history of the audit service through its query
+// API, a page at a time
+type AuditSource struct {
+	// URL is the base URL of the audit service, e.g. http://localhost:8085
+	URL    string
+	Client *http.Client
+}
+
+// auditPage is a page of GET /audit/records
+type auditPage struct {
+	Records   []Record `json:"records"`
+	NextAfter int64    `json:"next_after"`
+}
+
+// Events reads the records selected by filter page by page
+func (s *AuditSource) Events(ctx context.Context, filter EventFilter, fn func(Record) error) error {
+	query := url.Values{"limit": {strconv.Itoa(auditPageSize)}}
+	if filter.Type != "" {
+		query.Set("type", filter.Type)
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		query.Set("until", filter.Until.Format(time.RFC3339))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for {
+		page, err := s.fetch(ctx, client, query)
+		if err != nil {
+			return err
+		}
+		for _, record := range page.Records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if page.NextAfter == 0 {
+			return nil
+		}
+		query.Set("after", strconv.FormatInt(page.NextAfter, 10))
+	}
+}
+
+// fetch gets the page of records of query
+func (s *AuditSource) fetch(ctx context.Context, client *http.Client, query url.Values) (auditPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.URL, "/")+"/audit/records?"+query.Encode(), nil)
+	if err != nil {
+		return auditPage{}, fmt.Errorf("event store: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return auditPage{}, fmt.Errorf("event store: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return auditPage{}, fmt.Errorf("event store: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	var page auditPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return auditPage{}, fmt.Errorf("event store: decoding records: %w", err)
+	}
+	return page, nil
+}
+
+// FileSource reads the JSON Lines history file of the audit service
+// directly, one record at a time
+type FileSource struct {
+	Path string
+}
+
+// Events reads the records of the file selected by filter
+func (s *FileSource) Events(ctx context.Context, filter EventFilter, fn func(Record) error) error {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("event store: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var record Record
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("event store: reading %s: %w", s.Path, err)
+		}
+		if !filter.matches(record.Event) {
+			continue
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// testRecord creates the record sequence of an event of eventType at time at
+func testRecord(sequence int64, eventType string, at time.Time, data interface{}) Record {
+	e, _ := events.New("evt-"+strconv.FormatInt(sequence, 10), eventType, "test", "1", data)
+	e.Time = at
+	return Record{Sequence: sequence, Event: e}
+}
+
+// sequences returns the sequences of records
+func sequences(records []Record) []int64 {
+	var got []int64
+	for _, record := range records {
+		got = append(got, record.Sequence)
+	}
+	return got
+}
+
+func TestAuditSource(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		page := auditPage{}
+		switch r.URL.Query().Get("after") {
+		case "":
+			page.Records = []Record{testRecord(1, "user.created", monday, nil), testRecord(3, "user.created", monday, nil)}
+			page.NextAfter = 3
+		case "3":
+			page.Records = []Record{testRecord(7, "user.created", monday, nil)}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	source := &AuditSource{URL: server.URL + "/", Client: server.Client()}
+	var got []Record
+	filter := EventFilter{Type: "user.created", Since: monday, Until: monday.AddDate(0, 0, 7)}
+	if err := source.Events(context.Background(), filter, func(record Record) error {
+		got = append(got, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if want := []int64{1, 3, 7}; !reflect.DeepEqual(sequences(got), want) {
+		t.Errorf("got sequences %v want %v", sequences(got), want)
+	}
+	want := []string{
+		"limit=1000&since=2025-01-06T09%3A00%3A00Z&type=user.created&until=2025-01-13T09%3A00%3A00Z",
+		"after=3&limit=1000&since=2025-01-06T09%3A00%3A00Z&type=user.created&until=2025-01-13T09%3A00%3A00Z",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("got queries %v want %v", queries, want)
+	}
+}
+
+func TestAuditSource_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	source := &AuditSource{URL: server.URL, Client: server.Client()}
+	if err := source.Events(context.Background(), EventFilter{}, func(Record) error { return nil }); err == nil {
+		t.Error("got nil error want the failure of the audit service")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	day := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, record := range []Record{
+		testRecord(1, "user.created", day, nil),
+		testRecord(2, "order.placed", day, nil),
+		testRecord(3, "user.created", day.AddDate(0, 0, 1), nil),
+		testRecord(4, "user.created", day.AddDate(0, 0, 2), nil),
+	} {
+		encoder.Encode(record)
+	}
+	file.Close()
+
+	source := &FileSource{Path: path}
+	var got []Record
+	filter := EventFilter{Type: "user.*", Until: day.AddDate(0, 0, 2)}
+	if err := source.Events(context.Background(), filter, func(record Record) error {
+		got = append(got, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if want := []int64{1, 3}; !reflect.DeepEqual(sequences(got), want) {
+		t.Errorf("got sequences %v want %v", sequences(got), want)
+	}
+
+	missing := &FileSource{Path: filepath.Join(t.TempDir(), "missing.jsonl")}
+	if err := missing.Events(context.Background(), EventFilter{}, func(Record) error { return nil }); err == nil {
+		t.Error("got nil error want the missing file reported")
+	}
+}
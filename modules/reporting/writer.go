@@ -0,0 +1,205 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableWriter writes the rows of a report in a file format, as they come
+type TableWriter interface {
+	// WriteRow writes a row, the header row first
+	WriteRow(row []string) error
+	// Close completes the file
+	Close() error
+}
+
+// Format is a file format of reports
+type Format struct {
+	Extension   string
+	ContentType string
+	// New creates a writer of a table called name with columns to w
+	New func(w io.Writer, name string, columns []Column) (TableWriter, error)
+}
+
+// formats are the file formats of reports by name
+var formats = map[string]Format{
+	"csv": {
+		Extension:   ".csv",
+		ContentType: "text/csv; charset=utf-8",
+		New:         newCSVWriter,
+	},
+	"xlsx": {
+		Extension:   ".xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		New:         newXLSXWriter,
+	},
+}
+
+// csvWriter writes reports as CSV
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	header  bool
+}
+
+// newCSVWriter creates a CSV writer to w
+func newCSVWriter(w io.Writer, _ string, columns []Column) (TableWriter, error) {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns}, nil
+}
+
+// WriteRow writes a record. Text cells that a spreadsheet would run as a
+// formula are prefixed with a quote, so opening a report never runs
+// formulas injected through event data.
+func (c *csvWriter) WriteRow(row []string) error {
+	if c.header {
+		for i, cell := range row {
+			if i < len(c.columns) && !c.columns[i].Numeric && cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+				row[i] = "'" + cell
+			}
+		}
+	}
+	c.header = true
+	return c.w.Write(row)
+}
+
+// Close flushes the buffered records
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Parts of the Office Open XML package of a workbook with one worksheet,
+// written before the worksheet
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter writes reports as Excel workbooks. A workbook is a zip archive
+// of XML parts; the worksheet is the last one, so its rows are compressed
+// and written as they come instead of being built in memory.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	columns []Column
+	rows    int
+}
+
+// newXLSXWriter creates an Excel writer to w of a worksheet called name
+func newXLSXWriter(w io.Writer, name string, columns []Column) (TableWriter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writeZipPart(archive, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + xmlEscape(sheetName(name)) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writeZipPart(archive, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{archive: archive, sheet: sheet, columns: columns}, nil
+}
+
+// WriteRow writes a row of the worksheet. Cells of numeric columns are
+// numbers, the others inline strings; the header row is text.
+func (x *xlsxWriter) WriteRow(row []string) error {
+	x.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.rows)
+	for i, cell := range row {
+		ref := columnName(i) + strconv.Itoa(x.rows)
+		if x.rows > 1 && i < len(x.columns) && x.columns[i].Numeric {
+			if _, err := strconv.ParseFloat(cell, 64); err == nil {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, cell)
+				continue
+			}
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(cell))
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+// Close completes the worksheet and the archive
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
+
+// writeZipPart adds a part with content to archive
+func writeZipPart(archive *zip.Writer, name, content string) error {
+	part, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
+
+// columnName returns the spreadsheet name of the column at index i: A, B,
+// ..., Z, AA, AB and so on
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName returns name as a worksheet name: at most 31 characters, none
+// of which Excel forbids
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+// xmlEscape escapes text for XML content and attributes. Characters XML
+// cannot hold are replaced.
+func xmlEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
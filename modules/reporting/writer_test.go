@@ -0,0 +1,126 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testColumns are a text and a numeric column
+var testColumns = []Column{{Name: "name"}, {Name: "count", Numeric: true}}
+
+// writeTable writes rows with the writer of format and returns the file
+func writeTable(t *testing.T, format string, rows ...[]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	table, err := formats[format].New(&buf, "test report", testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := table.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := string(writeTable(t, "csv",
+		[]string{"name", "count"},
+		[]string{"Ada, Countess", "3"},
+		[]string{"=HYPERLINK(\"http://evil\")", "-2"},
+		[]string{"@SUM(A1)", ""},
+	))
+	want := "name,count\n" +
+		"\"Ada, Countess\",3\n" +
+		"\"'=HYPERLINK(\"\"http://evil\"\")\",-2\n" +
+		"'@SUM(A1),\n"
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+// xlsxSheet is the part of a worksheet read by the tests
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	data := writeTable(t, "xlsx",
+		[]string{"name", "count"},
+		[]string{"Ada & <Grace>", "3"},
+		[]string{"Alan", "n/a"},
+	)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("got an invalid zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		parts[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("got parts %v want %s", len(parts), name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="test report"`) {
+		t.Errorf("got workbook %s want the sheet named after the report", parts["xl/workbook.xml"])
+	}
+
+	var sheet xlsxSheet
+	if err := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); err != nil {
+		t.Fatalf("got an invalid worksheet: %v", err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("got %d rows want 3", len(sheet.Rows))
+	}
+	header, ada, alan := sheet.Rows[0], sheet.Rows[1], sheet.Rows[2]
+	if header.Cells[1].Inline != "count" || header.Cells[1].Type != "inlineStr" {
+		t.Errorf("got header %+v want text cells", header)
+	}
+	if ada.R != 2 || ada.Cells[0].Ref != "A2" || ada.Cells[0].Inline != "Ada & <Grace>" {
+		t.Errorf("got row %+v want the escaped name in A2", ada)
+	}
+	if ada.Cells[1].Type != "" || ada.Cells[1].Value != "3" {
+		t.Errorf("got cell %+v want the number 3", ada.Cells[1])
+	}
+	if alan.Cells[1].Type != "inlineStr" || alan.Cells[1].Inline != "n/a" {
+		t.Errorf("got cell %+v want text that is not a number kept as text", alan.Cells[1])
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) got %s want %s", i, got, want)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	if got, want := sheetName("orders/daily: [2025] and a long tail"), "orders_daily_ _2025_ and a long"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diagramClasses are the styles of the nodes of a diagram, by status
+var diagramClasses = []struct {
+	name  string
+	style string
+}{
+	{"pending", "fill:#f5f5f5,stroke:#9e9e9e,color:#616161"},
+	{"running", "fill:#e3f2fd,stroke:#1e88e5"},
+	{"succeeded", "fill:#e8f5e9,stroke:#43a047"},
+	{"failed", "fill:#ffebee,stroke:#e53935"},
+	{"compensating", "fill:#fff8e1,stroke:#ffb300"},
+	{"compensated", "fill:#fff3e0,stroke:#fb8c00"},
+}
+
+// stepClasses maps the statuses of steps to the classes of their nodes
+var stepClasses = map[StepStatus]string{
+	StepPending:            "pending",
+	StepRunning:            "running",
+	StepSucceeded:          "succeeded",
+	StepFailed:             "failed",
+	StepTimedOut:           "failed",
+	StepCompensating:       "compensating",
+	StepCompensated:        "compensated",
+	StepCompensationFailed: "failed",
+}
+
+// sagaClasses maps the statuses of sagas to the class of their outcome node
+var sagaClasses = map[SagaStatus]string{
+	SagaRunning:      "running",
+	SagaCompensating: "compensating",
+	SagaCompleted:    "succeeded",
+	SagaCompensated:  "compensated",
+	SagaFailed:       "failed",
+}
+
+// Diagram renders the state of the saga as a Mermaid flowchart: its steps
+// in order, colored by status, with the compensations sent drawn as dashed
+// arrows back from the step that failed
+func Diagram(saga Saga) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	fmt.Fprintf(&b, "    order([\"order %s\"])\n", saga.Order.ID)
+	previous := "order"
+	for _, step := range saga.Steps {
+		label := fmt.Sprintf("%s<br/>%s: %s", step.Name, step.Participant, step.Status)
+		if step.Reply != "" {
+			label += "<br/>" + step.Reply
+		}
+		fmt.Fprintf(&b, "    %s --> %s[\"%s\"]\n", previous, step.Name, label)
+		previous = step.Name
+	}
+	fmt.Fprintf(&b, "    %s --> outcome([\"%s\"])\n", previous, saga.Status)
+
+	// Compensations run backwards from the step that stopped the saga, the
+	// last one that started
+	var compensated []string
+	from := ""
+	for _, step := range saga.Steps {
+		if step.Status != StepPending && saga.Status != SagaRunning && saga.Status != SagaCompleted {
+			from = step.Name
+		}
+		switch step.Status {
+		case StepCompensating, StepCompensated, StepCompensationFailed:
+			compensated = append(compensated, step.Name)
+		}
+	}
+	for i := len(compensated) - 1; i >= 0 && from != ""; i-- {
+		if compensated[i] == from {
+			continue
+		}
+		fmt.Fprintf(&b, "    %s -. compensate .-> %s\n", from, compensated[i])
+		from = compensated[i]
+	}
+
+	for _, class := range diagramClasses {
+		fmt.Fprintf(&b, "    classDef %s %s\n", class.name, class.style)
+	}
+	for _, step := range saga.Steps {
+		fmt.Fprintf(&b, "    class %s %s\n", step.Name, stepClasses[step.Status])
+	}
+	fmt.Fprintf(&b, "    class outcome %s\n", sagaClasses[saga.Status])
+	return b.String()
+}
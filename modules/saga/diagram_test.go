@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiagram(t *testing.T) {
+	o := newTestOrchestrator()
+	saga := o.run(t, map[string]string{"capture_payment": SimulateTimeout})
+
+	diagram := Diagram(saga)
+	for _, want := range []string{
+		"flowchart LR\n",
+		`order(["order ` + saga.Order.ID + `"])`,
+		`order --> reserve_payment["reserve_payment<br/>payments: compensated<br/>payment.reserved"]`,
+		`capture_payment --> outcome(["compensated"])`,
+		"capture_payment -. compensate .-> reserve_stock\n",
+		"reserve_stock -. compensate .-> reserve_payment\n",
+		"class capture_payment failed\n",
+		"class outcome compensated\n",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("diagram lacks %q:\n%s", want, diagram)
+		}
+	}
+
+	completed := Diagram(o.run(t, nil))
+	if strings.Contains(completed, "-. compensate .->") || !strings.Contains(completed, "class outcome succeeded") {
+		t.Errorf("got diagram of a completed saga:\n%s", completed)
+	}
+}
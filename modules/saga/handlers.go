@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// SagaHandler serves the orders, the state of their sagas and the holdings
+// of the simulated participants
+type SagaHandler struct {
+	orchestrator *Orchestrator
+	simulated    map[string]*SimulatedParticipant
+	mux          *http.ServeMux
+}
+
+// NewSagaHandler creates the handler of the orders, sagas and participants
+// routes
+func NewSagaHandler(orchestrator *Orchestrator, simulated map[string]*SimulatedParticipant) *SagaHandler {
+	h := &SagaHandler{orchestrator: orchestrator, simulated: simulated, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /orders", h.handlePlaceOrder)
+	h.mux.HandleFunc("GET /sagas", h.handleListSagas)
+	h.mux.HandleFunc("GET /sagas/{id}", h.handleGetSaga)
+	h.mux.HandleFunc("GET /sagas/{id}/diagram", h.handleDiagram)
+	h.mux.HandleFunc("GET /participants", h.handleParticipants)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *SagaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handlePlaceOrder starts the saga of an order. It answers before the saga
+// runs; its state is polled at the Location returned.
+func (h *SagaHandler) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
+	var cmd PlaceOrder
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&cmd); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	saga, err := h.orchestrator.Start(r.Context(), cmd)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/sagas/"+saga.ID)
+	httpx.WriteJSON(w, http.StatusAccepted, saga)
+}
+
+// handleListSagas lists the sagas, filtered by the status query parameter
+func (h *SagaHandler) handleListSagas(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.orchestrator.List(SagaStatus(r.URL.Query().Get("status"))))
+}
+
+// handleGetSaga returns the state of a saga
+func (h *SagaHandler) handleGetSaga(w http.ResponseWriter, r *http.Request) {
+	saga, err := h.orchestrator.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, saga)
+}
+
+// handleDiagram returns the state of a saga as a Mermaid flowchart
+func (h *SagaHandler) handleDiagram(w http.ResponseWriter, r *http.Request) {
+	saga, err := h.orchestrator.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(Diagram(saga)))
+}
+
+// handleParticipants returns what each simulated participant holds, by
+// order. Once a saga is compensated, they hold nothing for its order.
+func (h *SagaHandler) handleParticipants(w http.ResponseWriter, r *http.Request) {
+	holdings := make(map[string]map[string]string, len(h.simulated))
+	for name, participant := range h.simulated {
+		holdings[name] = participant.Holdings()
+	}
+	httpx.WriteJSON(w, http.StatusOK, holdings)
+}
+
+// writeError writes the problem matching an orchestrator error
+func (h *SagaHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrSagaNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrBusy):
+		problem.Write(w, r, http.StatusServiceUnavailable, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
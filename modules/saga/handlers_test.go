@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSagaHandler(t *testing.T) {
+	o := newTestOrchestrator()
+	handler := NewSagaHandler(o.Orchestrator, map[string]*SimulatedParticipant{
+		ParticipantPayments:  o.payments,
+		ParticipantInventory: o.inventory,
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders",
+		strings.NewReader(`{"customer_id":"c1","sku":"mug","quantity":1,"amount_cents":900,"simulate":{"reserve_stock":"fail"}}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("got status %d want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	var saga Saga
+	if err := json.NewDecoder(rec.Body).Decode(&saga); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header().Get("Location") != "/sagas/"+saga.ID {
+		t.Errorf("got location %q", rec.Header().Get("Location"))
+	}
+	o.Execute(t.Context(), saga.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"invalid order", http.MethodPost, "/orders", `{"customer_id":"c1"}`, http.StatusUnprocessableEntity, "sku is required"},
+		{"invalid JSON", http.MethodPost, "/orders", `{`, http.StatusBadRequest, "Invalid JSON body"},
+		{"saga", http.MethodGet, "/sagas/" + saga.ID, "", http.StatusOK, `"status":"compensated"`},
+		{"missing saga", http.MethodGet, "/sagas/missing", "", http.StatusNotFound, "saga not found"},
+		{"list", http.MethodGet, "/sagas?status=compensated", "", http.StatusOK, saga.ID},
+		{"diagram", http.MethodGet, "/sagas/" + saga.ID + "/diagram", "", http.StatusOK, "reserve_stock -. compensate .-> reserve_payment"},
+		{"participants", http.MethodGet, "/participants", "", http.StatusOK, `{"inventory":{},"payments":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got status %d %s want %d with %q", rec.Code, rec.Body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort             = "8092"
+	defaultHost             = "localhost"
+	defaultStepTimeout      = 2 * time.Second
+	defaultSimulatedLatency = 300 * time.Millisecond
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+	paymentsURL := os.Getenv("PAYMENTS_URL")
+
+	stepTimeout, latency, err := loadTimings()
+	if err != nil {
+		fatal("Invalid saga configuration", "error", err)
+	}
+
+	// The payments service takes part when PAYMENTS_URL is set; inventory is
+	// always simulated, there is no inventory service
+	ids := uuid.GeneratorFunc(uuid.NewGoogle)
+	simulated := map[string]*SimulatedParticipant{
+		ParticipantInventory: NewSimulatedInventory(latency, ids),
+	}
+	participants := map[string]Participant{ParticipantInventory: simulated[ParticipantInventory]}
+	if paymentsURL != "" {
+		participants[ParticipantPayments] = &HTTPParticipant{URL: paymentsURL}
+	} else {
+		simulated[ParticipantPayments] = NewSimulatedPayments(latency, ids)
+		participants[ParticipantPayments] = simulated[ParticipantPayments]
+	}
+
+	// Saga states publish on a local bus, streamed at GET /events
+	bus := events.NewBus()
+	eventStream := events.NewStream(bus)
+	orchestrator := NewOrchestrator(OrderSteps(), participants, stepTimeout, ids, bus)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go orchestrator.Run(ctx)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewSagaHandler(orchestrator, simulated)
+	mux.Handle("/orders", api)
+	mux.Handle("/sagas", api)
+	mux.Handle("/sagas/", api)
+	mux.Handle("/participants", api)
+	mux.Handle("/events", eventStream)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	server.RegisterOnShutdown(eventStream.Close)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting saga orchestrator", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"step_timeout", stepTimeout, "simulated_latency", latency, "payments_url", paymentsURL)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Saga orchestrator failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down saga orchestrator")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Saga orchestrator forced to shutdown", "error", err)
+	}
+	slog.Info("Saga orchestrator exited")
+}
+
+// loadTimings reads how long a step may wait for its reply from
+// STEP_TIMEOUT, and how long the simulated participants take to handle a
+// command from SIMULATED_LATENCY
+func loadTimings() (stepTimeout, latency time.Duration, err error) {
+	stepTimeout, latency = defaultStepTimeout, defaultSimulatedLatency
+	if value := os.Getenv("STEP_TIMEOUT"); value != "" {
+		stepTimeout, err = time.ParseDuration(value)
+		if err != nil || stepTimeout <= 0 {
+			return 0, 0, fmt.Errorf("STEP_TIMEOUT must be a positive duration, got %q", value)
+		}
+	}
+	if value := os.Getenv("SIMULATED_LATENCY"); value != "" {
+		latency, err = time.ParseDuration(value)
+		if err != nil || latency < 0 {
+			return 0, 0, fmt.Errorf("SIMULATED_LATENCY must be a duration that is not negative, got %q", value)
+		}
+	}
+	if latency >= stepTimeout {
+		return 0, 0, fmt.Errorf("SIMULATED_LATENCY (%s) must be shorter than STEP_TIMEOUT (%s)", latency, stepTimeout)
+	}
+	return stepTimeout, latency, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "saga",
+		"endpoints": map[string]string{
+			"orders":       "/orders",
+			"sagas":        "/sagas",
+			"participants": "/participants",
+			"events":       "/events",
+			"health":       "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadTimings(t *testing.T) {
+	tests := []struct {
+		name        string
+		stepTimeout string
+		latency     string
+		wantTimeout time.Duration
+		wantLatency time.Duration
+		wantErr     bool
+	}{
+		{"defaults", "", "", defaultStepTimeout, defaultSimulatedLatency, false},
+		{"custom", "5s", "0", 5 * time.Second, 0, false},
+		{"zero timeout", "0s", "", 0, 0, true},
+		{"invalid latency", "", "slow", 0, 0, true},
+		{"latency beyond the timeout", "1s", "1s", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STEP_TIMEOUT", tt.stepTimeout)
+			t.Setenv("SIMULATED_LATENCY", tt.latency)
+			stepTimeout, latency, err := loadTimings()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadTimings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if stepTimeout != tt.wantTimeout || latency != tt.wantLatency {
+				t.Errorf("got %v and %v want %v and %v", stepTimeout, latency, tt.wantTimeout, tt.wantLatency)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies the orchestrator as the producer of its events
+const eventSource = "saga-orchestrator"
+
+// Types of the events published by the orchestrator
+const (
+	EventTypeSagaUpdated   = "saga.updated"
+	EventTypeOrderApproved = "order.approved"
+	EventTypeOrderRejected = "order.rejected"
+)
+
+// Participants of the order saga
+const (
+	ParticipantPayments  = "payments"
+	ParticipantInventory = "inventory"
+)
+
+// Compensation retries: compensations must eventually happen, so they are
+// retried before the saga is given up as failed
+const (
+	compensationAttempts     = 3
+	defaultCompensationDelay = 200 * time.Millisecond
+)
+
+// queueSize is how many sagas may wait to start
+const queueSize = 100
+
+// Errors of the orchestrator
+var (
+	ErrSagaNotFound = errors.New("saga not found")
+	ErrBusy         = errors.New("too many sagas waiting to start")
+)
+
+// Step is a step of a saga: a command sent to a participant, the reply
+// meaning it succeeded and the command undoing it
+type Step struct {
+	Name        string
+	Participant string
+	Command     string
+	Success     string
+	// Compensation undoes the step, empty when an earlier compensation
+	// undoes it too. Compensations never fail: any reply means it is done.
+	Compensation string
+}
+
+// OrderSteps are the steps of the order saga. Releasing the payment also
+// refunds it once captured, so the capture needs no compensation of its own.
+func OrderSteps() []Step {
+	return []Step{
+		{Name: "reserve_payment", Participant: ParticipantPayments, Command: "payment.reserve", Success: "payment.reserved", Compensation: "payment.release"},
+		{Name: "reserve_stock", Participant: ParticipantInventory, Command: "inventory.reserve", Success: "inventory.reserved", Compensation: "inventory.release"},
+		{Name: "capture_payment", Participant: ParticipantPayments, Command: "payment.capture", Success: "payment.captured"},
+	}
+}
+
+// commandData is the data of the commands of the order saga, which every
+// participant reads its fields from
+type commandData struct {
+	OrderID     string `json:"order_id"`
+	CustomerID  string `json:"customer_id,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	Quantity    int    `json:"quantity,omitempty"`
+	AmountCents int64  `json:"amount_cents,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Simulate    string `json:"simulate,omitempty"`
+}
+
+// replyData is the part of reply data the orchestrator reads
+type replyData struct {
+	Error string `json:"error"`
+}
+
+// Orchestrator runs sagas: it sends the command of each step in turn and,
+// when one fails or times out, sends the compensations of the steps done so
+// far in reverse order. The state of every saga is kept, and published as
+// saga.updated whenever it changes.
+type Orchestrator struct {
+	mutex             sync.RWMutex
+	sagas             map[string]*Saga
+	steps             []Step
+	participants      map[string]Participant
+	stepTimeout       time.Duration
+	compensationDelay time.Duration
+	queue             chan string
+	ids               uuid.IDGenerator
+	publisher         events.Publisher
+	now               func() time.Time
+}
+
+// NewOrchestrator creates an orchestrator running steps with participants,
+// giving each command stepTimeout to reply
+func NewOrchestrator(steps []Step, participants map[string]Participant, stepTimeout time.Duration, ids uuid.IDGenerator, publisher events.Publisher) *Orchestrator {
+	return &Orchestrator{
+		sagas:             make(map[string]*Saga),
+		steps:             steps,
+		participants:      participants,
+		stepTimeout:       stepTimeout,
+		compensationDelay: defaultCompensationDelay,
+		queue:             make(chan string, queueSize),
+		ids:               ids,
+		publisher:         publisher,
+		now:               func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Start creates the saga of an order and queues it for Run
+func (o *Orchestrator) Start(ctx context.Context, cmd PlaceOrder) (Saga, error) {
+	if err := o.validate(cmd); err != nil {
+		return Saga{}, err
+	}
+	now := o.now()
+	saga := &Saga{
+		ID: o.ids.NewID(),
+		Order: Order{
+			ID:          o.ids.NewID(),
+			CustomerID:  cmd.CustomerID,
+			SKU:         cmd.SKU,
+			Quantity:    cmd.Quantity,
+			AmountCents: cmd.AmountCents,
+		},
+		Status:    SagaRunning,
+		Simulate:  cmd.Simulate,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, step := range o.steps {
+		saga.Steps = append(saga.Steps, StepState{
+			Name:        step.Name,
+			Participant: step.Participant,
+			Status:      StepPending,
+			CommandID:   saga.ID + ":" + step.Name,
+		})
+	}
+
+	o.mutex.Lock()
+	o.sagas[saga.ID] = saga
+	started := saga.clone()
+	o.mutex.Unlock()
+	select {
+	case o.queue <- saga.ID:
+	default:
+		o.mutex.Lock()
+		delete(o.sagas, saga.ID)
+		o.mutex.Unlock()
+		return Saga{}, ErrBusy
+	}
+	o.publish(ctx, EventTypeSagaUpdated, saga.ID, started)
+	return started, nil
+}
+
+// validate checks an order and the steps it simulates failures of
+func (o *Orchestrator) validate(cmd PlaceOrder) error {
+	switch {
+	case cmd.CustomerID == "":
+		return &domain.ValidationError{Field: "customer_id", Message: "is required"}
+	case cmd.SKU == "":
+		return &domain.ValidationError{Field: "sku", Message: "is required"}
+	case cmd.Quantity <= 0:
+		return &domain.ValidationError{Field: "quantity", Message: "must be positive"}
+	case cmd.AmountCents <= 0:
+		return &domain.ValidationError{Field: "amount_cents", Message: "must be positive"}
+	}
+	for name, behaviour := range cmd.Simulate {
+		if !o.hasStep(name) {
+			return &domain.ValidationError{Field: "simulate", Message: fmt.Sprintf("names unknown step %q", name)}
+		}
+		if behaviour != SimulateFail && behaviour != SimulateTimeout {
+			return &domain.ValidationError{Field: "simulate", Message: fmt.Sprintf("must be %s or %s, got %q", SimulateFail, SimulateTimeout, behaviour)}
+		}
+	}
+	return nil
+}
+
+// hasStep reports whether the saga has a step named name
+func (o *Orchestrator) hasStep(name string) bool {
+	for _, step := range o.steps {
+		if step.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the queued sagas, each in its own goroutine, until ctx is
+// done. It returns once the sagas it started are over.
+func (o *Orchestrator) Run(ctx context.Context) {
+	var running sync.WaitGroup
+	defer running.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-o.queue:
+			running.Add(1)
+			go func() {
+				defer running.Done()
+				o.Execute(ctx, id)
+			}()
+		}
+	}
+}
+
+// Execute runs the steps of the saga until one fails or times out, then
+// compensates the steps that may have happened. A saga interrupted by ctx
+// stays where it was.
+func (o *Orchestrator) Execute(ctx context.Context, id string) {
+	saga, ok := o.snapshot(id)
+	if !ok {
+		return
+	}
+	var done []int
+	for i, step := range o.steps {
+		if ctx.Err() != nil {
+			return
+		}
+		o.update(ctx, id, func(saga *Saga) {
+			now := o.now()
+			saga.Steps[i].Status, saga.Steps[i].StartedAt = StepRunning, &now
+		})
+
+		reply, err := o.send(ctx, step.Participant, Command{
+			ID:     saga.Steps[i].CommandID,
+			Type:   step.Command,
+			Source: eventSource,
+		}, commandData{
+			OrderID:     saga.Order.ID,
+			CustomerID:  saga.Order.CustomerID,
+			SKU:         saga.Order.SKU,
+			Quantity:    saga.Order.Quantity,
+			AmountCents: saga.Order.AmountCents,
+			Simulate:    saga.Simulate[step.Name],
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Without a reply, the command may have been handled all the same,
+		// so the step is compensated; a failure reply means it was not
+		var reason string
+		status, happened := StepSucceeded, true
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			status, reason = StepTimedOut, fmt.Sprintf("%s got no reply within %s", step.Name, o.stepTimeout)
+		case err != nil:
+			status, reason = StepFailed, fmt.Sprintf("%s failed: %v", step.Name, err)
+		case reply.Type != step.Success:
+			var data replyData
+			reply.Decode(&data)
+			status, reason, happened = StepFailed, fmt.Sprintf("%s replied %s", step.Name, reply.Type), false
+			if data.Error != "" {
+				reason += ": " + data.Error
+			}
+		}
+		o.update(ctx, id, func(saga *Saga) {
+			now := o.now()
+			saga.Steps[i].Status, saga.Steps[i].Reply, saga.Steps[i].EndedAt = status, reply.Type, &now
+			if status != StepSucceeded {
+				saga.Steps[i].Error = reason
+			}
+		})
+
+		if happened {
+			done = append(done, i)
+		}
+		if status != StepSucceeded {
+			o.compensate(ctx, id, done, reason)
+			return
+		}
+	}
+
+	saga = o.update(ctx, id, func(saga *Saga) { saga.Status = SagaCompleted })
+	o.publish(ctx, EventTypeOrderApproved, saga.Order.ID, saga)
+}
+
+// compensate sends, in reverse order, the compensations of the steps that
+// may have happened, retrying each before giving up on it
+func (o *Orchestrator) compensate(ctx context.Context, id string, done []int, reason string) {
+	saga := o.update(ctx, id, func(saga *Saga) { saga.Status, saga.Reason = SagaCompensating, reason })
+	slog.WarnContext(ctx, "Compensating saga", "saga_id", id, "order_id", saga.Order.ID, "reason", reason)
+
+	compensated := true
+	for j := len(done) - 1; j >= 0; j-- {
+		i := done[j]
+		step := o.steps[i]
+		if step.Compensation == "" {
+			continue
+		}
+		o.update(ctx, id, func(saga *Saga) { saga.Steps[i].Status = StepCompensating })
+
+		var err error
+		for attempt := 1; attempt <= compensationAttempts; attempt++ {
+			_, err = o.send(ctx, step.Participant, Command{
+				ID:     saga.Steps[i].CommandID + ":compensate",
+				Type:   step.Compensation,
+				Source: eventSource,
+			}, commandData{OrderID: saga.Order.ID, Reason: reason})
+			if err == nil || ctx.Err() != nil {
+				break
+			}
+			slog.WarnContext(ctx, "Compensation failed", "saga_id", id, "step", step.Name, "attempt", attempt, "error", err)
+			if attempt < compensationAttempts {
+				select {
+				case <-ctx.Done():
+				case <-time.After(o.compensationDelay << (attempt - 1)):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		status := StepCompensated
+		if err != nil {
+			status, compensated = StepCompensationFailed, false
+		}
+		o.update(ctx, id, func(saga *Saga) {
+			saga.Steps[i].Status = status
+			if err != nil {
+				saga.Steps[i].Error = fmt.Sprintf("compensation failed after %d attempts: %v", compensationAttempts, err)
+			}
+		})
+	}
+
+	status := SagaCompensated
+	if !compensated {
+		status = SagaFailed
+	}
+	saga = o.update(ctx, id, func(saga *Saga) { saga.Status = status })
+	o.publish(ctx, EventTypeOrderRejected, saga.Order.ID, saga)
+}
+
+// send sends a command with data to a participant, waiting up to the step
+// timeout for its reply
+func (o *Orchestrator) send(ctx context.Context, participant string, cmd Command, data commandData) (events.Event, error) {
+	target, ok := o.participants[participant]
+	if !ok {
+		return events.Event{}, fmt.Errorf("no participant %s", participant)
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return events.Event{}, err
+	}
+	cmd.Data = encoded
+
+	ctx, cancel := context.WithTimeout(ctx, o.stepTimeout)
+	defer cancel()
+	return target.Send(ctx, cmd)
+}
+
+// update applies change to the saga and publishes its new state, which it
+// returns
+func (o *Orchestrator) update(ctx context.Context, id string, change func(*Saga)) Saga {
+	o.mutex.Lock()
+	saga := o.sagas[id]
+	change(saga)
+	saga.UpdatedAt = o.now()
+	updated := saga.clone()
+	o.mutex.Unlock()
+
+	o.publish(ctx, EventTypeSagaUpdated, id, updated)
+	return updated
+}
+
+// publish publishes an event about subject carrying the saga
+func (o *Orchestrator) publish(ctx context.Context, eventType, subject string, saga Saga) {
+	event, err := events.New(o.ids.NewID(), eventType, eventSource, subject, saga)
+	if err == nil {
+		err = o.publisher.Publish(ctx, event)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to publish saga event", "event_type", eventType, "saga_id", saga.ID, "error", err)
+	}
+}
+
+// snapshot returns a copy of the saga
+func (o *Orchestrator) snapshot(id string) (Saga, bool) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	saga, ok := o.sagas[id]
+	if !ok {
+		return Saga{}, false
+	}
+	return saga.clone(), true
+}
+
+// Get returns the saga
+func (o *Orchestrator) Get(id string) (Saga, error) {
+	saga, ok := o.snapshot(id)
+	if !ok {
+		return Saga{}, ErrSagaNotFound
+	}
+	return saga, nil
+}
+
+// List returns the sagas with status, every saga when it is empty, oldest
+// first
+func (o *Orchestrator) List(status SagaStatus) []Saga {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	sagas := []Saga{}
+	for _, saga := range o.sagas {
+		if status == "" || saga.Status == status {
+			sagas = append(sagas, saga.clone())
+		}
+	}
+	sort.Slice(sagas, func(i, j int) bool {
+		if !sagas[i].CreatedAt.Equal(sagas[j].CreatedAt) {
+			return sagas[i].CreatedAt.Before(sagas[j].CreatedAt)
+		}
+		return sagas[i].ID < sagas[j].ID
+	})
+	return sagas
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// recordingPublisher records the events published
+type recordingPublisher struct {
+	mutex  sync.Mutex
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event events.Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// types returns the types of the events published other than saga.updated
+func (p *recordingPublisher) types() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var types []string
+	for _, event := range p.events {
+		if event.Type != EventTypeSagaUpdated {
+			types = append(types, event.Type)
+		}
+	}
+	return types
+}
+
+// failingParticipant fails every command
+type failingParticipant struct {
+	mutex sync.Mutex
+	sent  int
+}
+
+func (p *failingParticipant) Send(context.Context, Command) (events.Event, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.sent++
+	return events.Event{}, errors.New("connection refused")
+}
+
+// testOrchestrator wires an orchestrator to simulated participants replying
+// at once, with a short step timeout
+type testOrchestrator struct {
+	*Orchestrator
+	payments  *SimulatedParticipant
+	inventory *SimulatedParticipant
+	published *recordingPublisher
+}
+
+func newTestOrchestrator() testOrchestrator {
+	ids := uuid.NewSequenceGenerator("id-")
+	payments := NewSimulatedPayments(0, ids)
+	inventory := NewSimulatedInventory(0, ids)
+	published := &recordingPublisher{}
+	orchestrator := NewOrchestrator(OrderSteps(), map[string]Participant{
+		ParticipantPayments:  payments,
+		ParticipantInventory: inventory,
+	}, 20*time.Millisecond, ids, published)
+	orchestrator.compensationDelay = time.Millisecond
+	return testOrchestrator{orchestrator, payments, inventory, published}
+}
+
+// run starts and executes the saga of an order simulating simulate
+func (o testOrchestrator) run(t *testing.T, simulate map[string]string) Saga {
+	t.Helper()
+	saga, err := o.Start(context.Background(), PlaceOrder{CustomerID: "c1", SKU: "mug", Quantity: 2, AmountCents: 1800, Simulate: simulate})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	o.Execute(context.Background(), saga.ID)
+	saga, _ = o.Get(saga.ID)
+	return saga
+}
+
+// stepStatuses returns the statuses of the steps of the saga
+func stepStatuses(saga Saga) []StepStatus {
+	var statuses []StepStatus
+	for _, step := range saga.Steps {
+		statuses = append(statuses, step.Status)
+	}
+	return statuses
+}
+
+func TestOrchestrator(t *testing.T) {
+	tests := []struct {
+		name         string
+		simulate     map[string]string
+		wantStatus   SagaStatus
+		wantSteps    []StepStatus
+		wantEvent    string
+		wantPayments string
+		wantStock    string
+	}{
+		{
+			name:         "completed",
+			wantStatus:   SagaCompleted,
+			wantSteps:    []StepStatus{StepSucceeded, StepSucceeded, StepSucceeded},
+			wantEvent:    EventTypeOrderApproved,
+			wantPayments: "payment.captured",
+			wantStock:    "inventory.reserved",
+		},
+		{
+			name:       "declined",
+			simulate:   map[string]string{"reserve_payment": SimulateFail},
+			wantStatus: SagaCompensated,
+			wantSteps:  []StepStatus{StepFailed, StepPending, StepPending},
+			wantEvent:  EventTypeOrderRejected,
+		},
+		{
+			name:       "out of stock",
+			simulate:   map[string]string{"reserve_stock": SimulateFail},
+			wantStatus: SagaCompensated,
+			wantSteps:  []StepStatus{StepCompensated, StepFailed, StepPending},
+			wantEvent:  EventTypeOrderRejected,
+		},
+		{
+			name:       "stock timeout",
+			simulate:   map[string]string{"reserve_stock": SimulateTimeout},
+			wantStatus: SagaCompensated,
+			wantSteps:  []StepStatus{StepCompensated, StepCompensated, StepPending},
+			wantEvent:  EventTypeOrderRejected,
+		},
+		{
+			name:       "capture timeout",
+			simulate:   map[string]string{"capture_payment": SimulateTimeout},
+			wantStatus: SagaCompensated,
+			wantSteps:  []StepStatus{StepCompensated, StepCompensated, StepTimedOut},
+			wantEvent:  EventTypeOrderRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOrchestrator()
+			saga := o.run(t, tt.simulate)
+
+			if saga.Status != tt.wantStatus {
+				t.Errorf("got status %s want %s: %s", saga.Status, tt.wantStatus, saga.Reason)
+			}
+			statuses := stepStatuses(saga)
+			for i := range tt.wantSteps {
+				if statuses[i] != tt.wantSteps[i] {
+					t.Errorf("got steps %v want %v", statuses, tt.wantSteps)
+					break
+				}
+			}
+			if types := o.published.types(); len(types) != 1 || types[0] != tt.wantEvent {
+				t.Errorf("got events %v want %s", types, tt.wantEvent)
+			}
+			// Compensated sagas leave nothing held for the order
+			if got := o.payments.Holdings()[saga.Order.ID]; got != tt.wantPayments {
+				t.Errorf("got payment %q want %q", got, tt.wantPayments)
+			}
+			if got := o.inventory.Holdings()[saga.Order.ID]; got != tt.wantStock {
+				t.Errorf("got stock %q want %q", got, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_CompensationFailed(t *testing.T) {
+	o := newTestOrchestrator()
+	failing := &failingParticipant{}
+	o.participants[ParticipantPayments] = failing
+
+	saga := o.run(t, nil)
+	if saga.Status != SagaFailed || saga.Steps[0].Status != StepCompensationFailed {
+		t.Errorf("got %s with steps %v want failed with the payment compensation failed", saga.Status, stepStatuses(saga))
+	}
+	// The reservation failed and was compensated, each compensation retried
+	if failing.sent != 1+compensationAttempts {
+		t.Errorf("got %d commands sent want %d", failing.sent, 1+compensationAttempts)
+	}
+}
+
+func TestOrchestrator_Start(t *testing.T) {
+	o := newTestOrchestrator()
+	tests := []struct {
+		name string
+		cmd  PlaceOrder
+	}{
+		{"missing customer", PlaceOrder{SKU: "mug", Quantity: 1, AmountCents: 900}},
+		{"zero quantity", PlaceOrder{CustomerID: "c1", SKU: "mug", AmountCents: 900}},
+		{"unknown step", PlaceOrder{CustomerID: "c1", SKU: "mug", Quantity: 1, AmountCents: 900, Simulate: map[string]string{"ship": SimulateFail}}},
+		{"unknown behaviour", PlaceOrder{CustomerID: "c1", SKU: "mug", Quantity: 1, AmountCents: 900, Simulate: map[string]string{"reserve_stock": "explode"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *domain.ValidationError
+			if _, err := o.Start(context.Background(), tt.cmd); !errors.As(err, &validationErr) {
+				t.Errorf("got error %v want a validation error", err)
+			}
+		})
+	}
+
+	saga, err := o.Start(context.Background(), PlaceOrder{CustomerID: "c1", SKU: "mug", Quantity: 1, AmountCents: 900})
+	if err != nil || saga.Status != SagaRunning || len(saga.Steps) != 3 || saga.Steps[0].Status != StepPending {
+		t.Errorf("got %+v, %v want a running saga of 3 pending steps", saga, err)
+	}
+	if sagas := o.List(SagaRunning); len(sagas) != 1 || sagas[0].ID != saga.ID {
+		t.Errorf("got %+v want the running saga", sagas)
+	}
+	if _, err := o.Get("missing"); !errors.Is(err, ErrSagaNotFound) {
+		t.Errorf("got error %v want %v", err, ErrSagaNotFound)
+	}
+}
+
+func TestOrchestrator_Run(t *testing.T) {
+	o := newTestOrchestrator()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		o.Run(ctx)
+		close(stopped)
+	}()
+
+	saga, _ := o.Start(ctx, PlaceOrder{CustomerID: "c1", SKU: "mug", Quantity: 1, AmountCents: 900})
+	deadline := time.Now().Add(5 * time.Second)
+	for saga.Status == SagaRunning && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		saga, _ = o.Get(saga.ID)
+	}
+	if saga.Status != SagaCompleted {
+		t.Errorf("got status %s want %s", saga.Status, SagaCompleted)
+	}
+	cancel()
+	<-stopped
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// ErrUnknownCommand reports a command type a participant does not handle
+var ErrUnknownCommand = errors.New("unknown command type")
+
+// Command asks a participant to do something on behalf of a saga. It has
+// the shape of the commands of the payments service; its ID makes retries
+// safe, as participants handle a command once.
+type Command struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Source string          `json:"source,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Participant handles the commands of a saga, replying to each with an
+// event, failures included
+type Participant interface {
+	Send(ctx context.Context, cmd Command) (events.Event, error)
+}
+
+// HTTPParticipant is a remote participant taking commands at POST /commands,
+// such as the payments service
+type HTTPParticipant struct {
+	// URL is the base URL of the service, e.g. http://localhost:8083
+	URL string
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// Send posts the command and decodes the reply event
+func (p *HTTPParticipant) Send(ctx context.Context, cmd Command) (events.Event, error) {
+	body, err := json.Marshal(cmd)
+	if err != nil {
+		return events.Event{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.URL, "/")+"/commands", bytes.NewReader(body))
+	if err != nil {
+		return events.Event{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return events.Event{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return events.Event{}, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	var reply events.Event
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return events.Event{}, fmt.Errorf("decoding reply: %w", err)
+	}
+	return reply, nil
+}
+
+// Behaviours a simulated participant is asked for by the simulate field of
+// the data of a command
+const (
+	SimulateFail    = "fail"
+	SimulateTimeout = "timeout"
+)
+
+// SimulatedCommand describes how a simulated participant handles a type of
+// command
+type SimulatedCommand struct {
+	// Success is the type of the reply when the command succeeds
+	Success string
+	// Failure is the type of the reply when it fails, empty for commands
+	// that never fail, such as compensations
+	Failure string
+	// Undo commands undo what the previous commands did for the order
+	Undo bool
+}
+
+// simulatedData is the part of command data a simulated participant reads
+type simulatedData struct {
+	OrderID  string `json:"order_id"`
+	Simulate string `json:"simulate"`
+}
+
+// SimulatedReplyData is the payload of the replies of simulated participants
+type SimulatedReplyData struct {
+	OrderID   string `json:"order_id"`
+	CommandID string `json:"command_id"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SimulatedParticipant stands in for a service this repository does not
+// have, such as inventory. It succeeds unless a command asks it to fail or
+// to time out, and keeps what it holds for each order, so compensations can
+// be checked to undo everything.
+type SimulatedParticipant struct {
+	mutex    sync.Mutex
+	name     string
+	commands map[string]SimulatedCommand
+	latency  time.Duration
+	ids      uuid.IDGenerator
+	replies  map[string]events.Event
+	holdings map[string]string
+}
+
+// NewSimulatedParticipant creates the participant name handling commands,
+// each taking latency
+func NewSimulatedParticipant(name string, commands map[string]SimulatedCommand, latency time.Duration, ids uuid.IDGenerator) *SimulatedParticipant {
+	return &SimulatedParticipant{
+		name:     name,
+		commands: commands,
+		latency:  latency,
+		ids:      ids,
+		replies:  make(map[string]events.Event),
+		holdings: make(map[string]string),
+	}
+}
+
+// Send handles the command and returns its reply. A command asked to time
+// out is carried out, but its reply never comes before ctx is done.
+func (p *SimulatedParticipant) Send(ctx context.Context, cmd Command) (events.Event, error) {
+	spec, ok := p.commands[cmd.Type]
+	if !ok {
+		return events.Event{}, fmt.Errorf("%w %s for %s", ErrUnknownCommand, cmd.Type, p.name)
+	}
+	var data simulatedData
+	if err := json.Unmarshal(cmd.Data, &data); err != nil {
+		return events.Event{}, fmt.Errorf("decoding %s data: %w", cmd.Type, err)
+	}
+
+	timer := time.NewTimer(p.latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return events.Event{}, ctx.Err()
+	case <-timer.C:
+	}
+
+	reply, err := p.handle(cmd, spec, data)
+	if err != nil {
+		return events.Event{}, err
+	}
+	if data.Simulate == SimulateTimeout {
+		<-ctx.Done()
+		return events.Event{}, ctx.Err()
+	}
+	return reply, nil
+}
+
+// handle carries out the command, once per command ID
+func (p *SimulatedParticipant) handle(cmd Command, spec SimulatedCommand, data simulatedData) (events.Event, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if reply, ok := p.replies[cmd.ID]; ok {
+		return reply, nil
+	}
+
+	replyData := SimulatedReplyData{OrderID: data.OrderID, CommandID: cmd.ID}
+	replyType := spec.Success
+	switch {
+	case data.Simulate == SimulateFail && spec.Failure != "":
+		replyType, replyData.Error = spec.Failure, "simulated failure"
+	case spec.Undo:
+		delete(p.holdings, data.OrderID)
+	default:
+		p.holdings[data.OrderID] = replyType
+	}
+	reply, err := events.New(p.ids.NewID(), replyType, p.name, data.OrderID, replyData)
+	if err != nil {
+		return events.Event{}, err
+	}
+	p.replies[cmd.ID] = reply
+	return reply, nil
+}
+
+// Holdings returns, for every order the participant holds something for,
+// the type of the reply that last changed it
+func (p *SimulatedParticipant) Holdings() map[string]string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	holdings := make(map[string]string, len(p.holdings))
+	for orderID, state := range p.holdings {
+		holdings[orderID] = state
+	}
+	return holdings
+}
+
+// NewSimulatedPayments creates a participant with the commands and replies
+// of the payments service
+func NewSimulatedPayments(latency time.Duration, ids uuid.IDGenerator) *SimulatedParticipant {
+	return NewSimulatedParticipant("payments", map[string]SimulatedCommand{
+		"payment.reserve": {Success: "payment.reserved", Failure: "payment.declined"},
+		"payment.capture": {Success: "payment.captured", Failure: "payment.capture_failed"},
+		"payment.release": {Success: "payment.released", Undo: true},
+	}, latency, ids)
+}
+
+// NewSimulatedInventory creates an inventory participant reserving stock
+func NewSimulatedInventory(latency time.Duration, ids uuid.IDGenerator) *SimulatedParticipant {
+	return NewSimulatedParticipant("inventory", map[string]SimulatedCommand{
+		"inventory.reserve": {Success: "inventory.reserved", Failure: "inventory.out_of_stock"},
+		"inventory.release": {Success: "inventory.released", Undo: true},
+	}, latency, ids)
+}
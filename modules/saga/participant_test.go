@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// testCommand creates a command for order o1, simulating behaviour
+func testCommand(t *testing.T, id, commandType, behaviour string) Command {
+	t.Helper()
+	data, err := json.Marshal(commandData{OrderID: "o1", Simulate: behaviour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Command{ID: id, Type: commandType, Data: data}
+}
+
+func TestSimulatedParticipant(t *testing.T) {
+	inventory := NewSimulatedInventory(0, uuid.NewSequenceGenerator("id-"))
+	ctx := context.Background()
+
+	reply, err := inventory.Send(ctx, testCommand(t, "c1", "inventory.reserve", ""))
+	if err != nil || reply.Type != "inventory.reserved" || reply.Subject != "o1" {
+		t.Fatalf("got %+v, %v want inventory.reserved for o1", reply, err)
+	}
+	again, _ := inventory.Send(ctx, testCommand(t, "c1", "inventory.reserve", SimulateFail))
+	if again.ID != reply.ID {
+		t.Errorf("got reply %s to a repeated command want the first reply %s", again.ID, reply.ID)
+	}
+	if holdings := inventory.Holdings(); holdings["o1"] != "inventory.reserved" {
+		t.Errorf("got holdings %v want o1 reserved", holdings)
+	}
+
+	failed, _ := inventory.Send(ctx, testCommand(t, "c2", "inventory.reserve", SimulateFail))
+	var data SimulatedReplyData
+	failed.Decode(&data)
+	if failed.Type != "inventory.out_of_stock" || data.Error == "" || data.CommandID != "c2" {
+		t.Errorf("got %s %+v want inventory.out_of_stock with an error", failed.Type, data)
+	}
+
+	if _, err := inventory.Send(ctx, testCommand(t, "c3", "inventory.release", SimulateFail)); err != nil {
+		t.Fatalf("Send() of the release error = %v", err)
+	}
+	if holdings := inventory.Holdings(); len(holdings) != 0 {
+		t.Errorf("got holdings %v want none after the release", holdings)
+	}
+	if _, err := inventory.Send(ctx, testCommand(t, "c4", "payment.reserve", "")); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("got error %v want %v", err, ErrUnknownCommand)
+	}
+}
+
+func TestSimulatedParticipant_Timeout(t *testing.T) {
+	payments := NewSimulatedPayments(0, uuid.NewSequenceGenerator("id-"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := payments.Send(ctx, testCommand(t, "c1", "payment.reserve", SimulateTimeout))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v want %v", err, context.DeadlineExceeded)
+	}
+	// The reservation happened, only its reply was lost
+	if holdings := payments.Holdings(); holdings["o1"] != "payment.reserved" {
+		t.Errorf("got holdings %v want o1 reserved", holdings)
+	}
+}
+
+func TestHTTPParticipant(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmd Command
+		json.NewDecoder(r.Body).Decode(&cmd)
+		if r.URL.Path != "/commands" || cmd.Type != "payment.reserve" {
+			http.Error(w, "unknown command", http.StatusUnprocessableEntity)
+			return
+		}
+		reply, _ := events.New("r1", "payment.reserved", "payment-service", "o1", map[string]string{"command_id": cmd.ID})
+		json.NewEncoder(w).Encode(reply)
+	}))
+	defer server.Close()
+	participant := &HTTPParticipant{URL: server.URL + "/"}
+
+	reply, err := participant.Send(context.Background(), testCommand(t, "c1", "payment.reserve", ""))
+	if err != nil || reply.Type != "payment.reserved" {
+		t.Errorf("got %+v, %v want payment.reserved", reply, err)
+	}
+	if _, err := participant.Send(context.Background(), testCommand(t, "c2", "payment.refund", "")); err == nil {
+		t.Error("Send() of a rejected command succeeded, want an error")
+	}
+}
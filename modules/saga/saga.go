@@ -0,0 +1,90 @@
+package main
+
+import "time"
+
+// SagaStatus is the status of a saga
+type SagaStatus string
+
+// Statuses of a saga
+const (
+	SagaRunning      SagaStatus = "running"
+	SagaCompensating SagaStatus = "compensating"
+	SagaCompleted    SagaStatus = "completed"
+	SagaCompensated  SagaStatus = "compensated"
+	// SagaFailed sagas could not undo every step, and need someone to look
+	SagaFailed SagaStatus = "failed"
+)
+
+// StepStatus is the status of a step of a saga
+type StepStatus string
+
+// Statuses of a step
+const (
+	StepPending   StepStatus = "pending"
+	StepRunning   StepStatus = "running"
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	// StepTimedOut steps got no reply in time: they may or may not have
+	// happened, so they are compensated like succeeded ones
+	StepTimedOut           StepStatus = "timed_out"
+	StepCompensating       StepStatus = "compensating"
+	StepCompensated        StepStatus = "compensated"
+	StepCompensationFailed StepStatus = "compensation_failed"
+)
+
+// PlaceOrder asks to place an order, which the saga turns into a payment
+// and a stock reservation. Simulate asks the simulated participants to fail
+// or time out the steps it names.
+type PlaceOrder struct {
+	CustomerID  string            `json:"customer_id"`
+	SKU         string            `json:"sku"`
+	Quantity    int               `json:"quantity"`
+	AmountCents int64             `json:"amount_cents"`
+	Simulate    map[string]string `json:"simulate,omitempty"`
+}
+
+// Order is the order a saga places
+type Order struct {
+	ID          string `json:"id"`
+	CustomerID  string `json:"customer_id"`
+	SKU         string `json:"sku"`
+	Quantity    int    `json:"quantity"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+// StepState is the state of a step of a saga
+type StepState struct {
+	Name        string     `json:"name"`
+	Participant string     `json:"participant"`
+	Status      StepStatus `json:"status"`
+	CommandID   string     `json:"command_id"`
+	Reply       string     `json:"reply,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+}
+
+// Saga is the state of the saga of an order: where it is, what each step
+// answered and, once over, why it ended the way it did
+type Saga struct {
+	ID        string            `json:"id"`
+	Order     Order             `json:"order"`
+	Status    SagaStatus        `json:"status"`
+	Steps     []StepState       `json:"steps"`
+	Simulate  map[string]string `json:"simulate,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// clone returns a copy of the saga sharing nothing mutable with it
+func (s *Saga) clone() Saga {
+	saga := *s
+	saga.Steps = append([]StepState{}, s.Steps...)
+	return saga
+}
+
+// Ended reports whether the saga is over
+func (s *Saga) Ended() bool {
+	return s.Status != SagaRunning && s.Status != SagaCompensating
+}
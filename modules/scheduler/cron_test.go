@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleNext(t *testing.T) {
+	from := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC) // a Friday
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2025, time.March, 14, 10, 31, 0, 0, time.UTC)},
+		{"daily", "0 8 * * *", time.Date(2025, time.March, 15, 8, 0, 0, 0, time.UTC)},
+		{"later today", "45 10 * * *", time.Date(2025, time.March, 14, 10, 45, 0, 0, time.UTC)},
+		{"step", "*/20 * * * *", time.Date(2025, time.March, 14, 10, 40, 0, 0, time.UTC)},
+		{"range with step", "0 9-17/4 * * *", time.Date(2025, time.March, 14, 13, 0, 0, 0, time.UTC)},
+		{"list", "0 7,22 * * *", time.Date(2025, time.March, 14, 22, 0, 0, 0, time.UTC)},
+		{"weekday name", "0 9 * * mon", time.Date(2025, time.March, 17, 9, 0, 0, 0, time.UTC)},
+		{"weekday range", "0 9 * * mon-fri", time.Date(2025, time.March, 17, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"month name", "0 0 1 jun *", time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 1 * fri", time.Date(2025, time.March, 21, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"macro", "@monthly", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"every", "@every 1h", time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error = %v", tt.expr, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScheduleLocation(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	schedule, err := ParseSchedule("0 8 * * *", location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks go forward on 2025-03-09, 8:00 moves from 13:00 to 12:00 UTC
+	next := schedule.Next(time.Date(2025, time.March, 8, 14, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, time.March, 9, 12, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("got %v want %v", next.UTC(), want)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * * funday",
+		"@fortnightly",
+		"@every soon",
+		"@every 10ms",
+	} {
+		if _, err := ParseSchedule(expr, time.UTC); err == nil {
+			t.Errorf("ParseSchedule(%q) got no error", expr)
+		}
+	}
+}
+
+func TestScheduleNeverMatching(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Errorf("got %v want no run", next)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// JobHandler serves the jobs API of the scheduler
+type JobHandler struct {
+	scheduler *Scheduler
+	mux       *http.ServeMux
+}
+
+// NewJobHandler creates the handler of the jobs routes
+func NewJobHandler(scheduler *Scheduler) *JobHandler {
+	h := &JobHandler{scheduler: scheduler, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /jobs", h.handleListJobs)
+	h.mux.HandleFunc("POST /jobs/{name}/trigger", h.handleTriggerJob)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *JobHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleListJobs lists the jobs with their next and last runs
+func (h *JobHandler) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, h.scheduler.Jobs())
+}
+
+// handleTriggerJob runs a job now and returns its event
+func (h *JobHandler) handleTriggerJob(w http.ResponseWriter, r *http.Request) {
+	event, err := h.scheduler.Trigger(r.Context(), r.PathValue("name"))
+	if errors.Is(err, ErrJobNotFound) {
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, event)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJobHandler(t *testing.T) {
+	scheduler, err := NewScheduler([]JobConfig{{Name: "digest", Schedule: "@daily"}}, time.UTC, &recordingPublisher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewJobHandler(scheduler)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"trigger", http.MethodPost, "/jobs/digest/trigger", http.StatusOK},
+		{"trigger missing", http.MethodPost, "/jobs/missing/trigger", http.StatusNotFound},
+		{"list", http.MethodGet, "/jobs", http.StatusOK},
+		{"wrong method", http.MethodDelete, "/jobs", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	var jobs []JobStatus
+	if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Runs != 1 || jobs[0].EventType != defaultEventType {
+		t.Errorf("got %+v want digest with one run", jobs)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+const (
+	defaultPort          = "8086"
+	defaultHost          = "localhost"
+	defaultSchedulesFile = "schedules.json"
+	defaultTimezone      = "UTC"
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	location, err := time.LoadLocation(config.Getenv("SCHEDULE_TIMEZONE", defaultTimezone))
+	if err != nil {
+		fatal("Invalid SCHEDULE_TIMEZONE", "error", err)
+	}
+	schedulesFile := config.Getenv("SCHEDULES_FILE", defaultSchedulesFile)
+	jobs, err := LoadJobs(schedulesFile, location)
+	if err != nil {
+		fatal("Invalid schedules", "error", err)
+	}
+
+	// Job events publish on a local bus, streamed at GET /events
+	bus := events.NewBus()
+	eventStream := events.NewStream(bus)
+	scheduler, err := NewScheduler(jobs, location, bus)
+	if err != nil {
+		fatal("Invalid schedules", "error", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go scheduler.Run(ctx)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewJobHandler(scheduler)
+	mux.Handle("/jobs", api)
+	mux.Handle("/jobs/", api)
+	mux.Handle("/events", eventStream)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	server.RegisterOnShutdown(eventStream.Close)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting scheduler", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"schedules", schedulesFile, "jobs", len(jobs), "timezone", location.String())
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Scheduler failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down scheduler")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Scheduler forced to shutdown", "error", err)
+	}
+	slog.Info("Scheduler exited")
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "scheduler",
+		"endpoints": map[string]string{
+			"jobs":   "/jobs",
+			"events": "/events",
+			"health": "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// eventSource identifies this service as the producer of its events
+const eventSource = "scheduler"
+
+// defaultEventType is the type of the events of jobs without one
+const defaultEventType = "scheduler.tick"
+
+// ErrJobNotFound reports an unknown job name
+var ErrJobNotFound = errors.New("job not found")
+
+// JobConfig is a job of the schedules file
+type JobConfig struct {
+	Name      string          `json:"name"`
+	Schedule  string          `json:"schedule"`
+	EventType string          `json:"event_type,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// TickData is the payload of the events of jobs: the job, the time it was
+// scheduled at and the data of its configuration
+type TickData struct {
+	Job         string          `json:"job"`
+	ScheduledAt time.Time       `json:"scheduled_at"`
+	Manual      bool            `json:"manual,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// JobStatus describes a job and its runs
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	EventType string     `json:"event_type"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	Runs      int        `json:"runs"`
+}
+
+// job is a scheduled job and its state
+type job struct {
+	JobConfig
+	schedule Schedule
+	next     time.Time
+	lastRun  time.Time
+	runs     int
+}
+
+// LoadJobs reads the jobs of the JSON schedules file at path, evaluating
+// cron expressions in location
+func LoadJobs(path string, location *time.Location) ([]JobConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []JobConfig
+	if err := json.Unmarshal(content, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := newJobs(configs, location); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return configs, nil
+}
+
+// newJobs validates the configurations and creates their jobs
+func newJobs(configs []JobConfig, location *time.Location) ([]*job, error) {
+	jobs := make([]*job, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for i, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("job %d: name is required", i+1)
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("job %q: duplicate name", config.Name)
+		}
+		names[config.Name] = true
+		schedule, err := ParseSchedule(config.Schedule, location)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", config.Name, err)
+		}
+		if config.EventType == "" {
+			config.EventType = defaultEventType
+		}
+		jobs = append(jobs, &job{JobConfig: config, schedule: schedule})
+	}
+	return jobs, nil
+}
+
+// Scheduler publishes the event of each job at the times of its schedule.
+// Runs missed while the process was stopped or late are skipped, not caught
+// up; the event ID is derived from the job and scheduled time, so consumers
+// can tell duplicates apart.
+type Scheduler struct {
+	mutex     sync.Mutex
+	jobs      []*job
+	publisher events.Publisher
+	now       func() time.Time
+}
+
+// NewScheduler creates a scheduler of the configured jobs publishing with publisher
+func NewScheduler(configs []JobConfig, location *time.Location, publisher events.Publisher) (*Scheduler, error) {
+	jobs, err := newJobs(configs, location)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{jobs: jobs, publisher: publisher, now: time.Now}, nil
+}
+
+// Run runs the jobs until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mutex.Lock()
+	now := s.now()
+	for _, j := range s.jobs {
+		j.next = j.schedule.Next(now)
+	}
+	s.mutex.Unlock()
+
+	for {
+		wait, ok := s.untilNext()
+		if !ok {
+			slog.WarnContext(ctx, "No job will run again")
+			<-ctx.Done()
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runDue(ctx)
+		}
+	}
+}
+
+// untilNext returns the time until the earliest run, and false without runs
+func (s *Scheduler) untilNext() (time.Duration, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var earliest time.Time
+	for _, j := range s.jobs {
+		if !j.next.IsZero() && (earliest.IsZero() || j.next.Before(earliest)) {
+			earliest = j.next
+		}
+	}
+	if earliest.IsZero() {
+		return 0, false
+	}
+	return max(earliest.Sub(s.now()), 0), true
+}
+
+// runDue publishes the event of every job whose run is due and schedules
+// its next run
+func (s *Scheduler) runDue(ctx context.Context) {
+	s.mutex.Lock()
+	now := s.now()
+	var due []event
+	for _, j := range s.jobs {
+		if j.next.IsZero() || j.next.After(now) {
+			continue
+		}
+		due = append(due, s.run(j, j.next, false))
+		j.next = j.schedule.Next(now)
+	}
+	s.mutex.Unlock()
+
+	for _, e := range due {
+		s.publish(ctx, e)
+	}
+}
+
+// event is an event of a job waiting to be published
+type event struct {
+	job       string
+	eventType string
+	data      TickData
+}
+
+// run records a run of the job scheduled at scheduledAt and returns its
+// event. The caller holds the mutex.
+func (s *Scheduler) run(j *job, scheduledAt time.Time, manual bool) event {
+	j.lastRun = s.now()
+	j.runs++
+	return event{
+		job:       j.Name,
+		eventType: j.EventType,
+		data:      TickData{Job: j.Name, ScheduledAt: scheduledAt.UTC(), Manual: manual, Data: j.Data},
+	}
+}
+
+// publish publishes the event of a run
+func (s *Scheduler) publish(ctx context.Context, e event) events.Event {
+	id := e.job + "@" + e.data.ScheduledAt.Format(time.RFC3339Nano)
+	published, err := events.New(id, e.eventType, eventSource, e.job, e.data)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create event", "job", e.job, "error", err)
+		return events.Event{}
+	}
+	if err := s.publisher.Publish(ctx, published); err != nil {
+		slog.ErrorContext(ctx, "Failed to publish event", "job", e.job, "error", err)
+	}
+	slog.InfoContext(ctx, "Job ran", "job", e.job, "event_type", e.eventType, "scheduled_at", e.data.ScheduledAt, "manual", e.data.Manual)
+	return published
+}
+
+// Trigger runs the job with name now, outside of its schedule, and returns
+// its event
+func (s *Scheduler) Trigger(ctx context.Context, name string) (events.Event, error) {
+	s.mutex.Lock()
+	var found *job
+	for _, j := range s.jobs {
+		if j.Name == name {
+			found = j
+		}
+	}
+	if found == nil {
+		s.mutex.Unlock()
+		return events.Event{}, fmt.Errorf("%w: %q", ErrJobNotFound, name)
+	}
+	e := s.run(found, s.now(), true)
+	s.mutex.Unlock()
+
+	return s.publish(ctx, e), nil
+}
+
+// Jobs returns the status of every job, in configuration order
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		statuses[i] = JobStatus{Name: j.Name, Schedule: j.Schedule, EventType: j.EventType, Runs: j.runs}
+		if !j.next.IsZero() {
+			next := j.next
+			statuses[i].NextRun = &next
+		}
+		if !j.lastRun.IsZero() {
+			last := j.lastRun
+			statuses[i].LastRun = &last
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// recordingPublisher records the published events
+type recordingPublisher struct {
+	mutex  sync.Mutex
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) published() []events.Event {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]events.Event(nil), p.events...)
+}
+
+func TestSchedulerRun(t *testing.T) {
+	publisher := &recordingPublisher{}
+	scheduler, err := NewScheduler([]JobConfig{
+		{Name: "digest", Schedule: "@daily", EventType: "reminder.daily_digest"},
+		{Name: "fast", Schedule: "@every 1s"},
+	}, time.UTC, publisher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tick faster than the one second minimum of @every
+	scheduler.jobs[1].schedule = everySchedule{interval: 20 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.published()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	published := publisher.published()
+	if len(published) < 3 {
+		t.Fatalf("got %d events want at least 3", len(published))
+	}
+	seen := make(map[string]bool)
+	for _, event := range published {
+		if event.Type != defaultEventType || event.Subject != "fast" || event.Source != eventSource {
+			t.Errorf("got %s from %s about %s want %s from %s about fast", event.Type, event.Source, event.Subject, defaultEventType, eventSource)
+		}
+		if seen[event.ID] {
+			t.Errorf("got duplicate event ID %s", event.ID)
+		}
+		seen[event.ID] = true
+		var data TickData
+		if err := event.Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		if data.Job != "fast" || data.Manual || data.ScheduledAt.IsZero() {
+			t.Errorf("got %+v want a scheduled run of fast", data)
+		}
+	}
+
+	jobs := scheduler.Jobs()
+	if jobs[0].Runs != 0 || jobs[0].LastRun != nil || jobs[0].NextRun == nil {
+		t.Errorf("got %+v want digest waiting for its first run", jobs[0])
+	}
+	if jobs[1].Runs != len(published) || jobs[1].LastRun == nil {
+		t.Errorf("got %+v want %d runs of fast", jobs[1], len(published))
+	}
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	publisher := &recordingPublisher{}
+	scheduler, err := NewScheduler([]JobConfig{
+		{Name: "digest", Schedule: "0 8 * * *", EventType: "reminder.daily_digest", Data: []byte(`{"audience":"all-users"}`)},
+	}, time.UTC, publisher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+
+	event, err := scheduler.Trigger(context.Background(), "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "reminder.daily_digest" || event.ID != "digest@2025-03-14T10:30:00Z" {
+		t.Errorf("got %s %s want reminder.daily_digest digest@2025-03-14T10:30:00Z", event.Type, event.ID)
+	}
+	var data TickData
+	if err := event.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if !data.Manual || !data.ScheduledAt.Equal(now) || string(data.Data) != `{"audience":"all-users"}` {
+		t.Errorf("got %+v want a manual run at %v with the job data", data, now)
+	}
+	if published := publisher.published(); len(published) != 1 || published[0].ID != event.ID {
+		t.Errorf("got %v want the triggered event", published)
+	}
+	if jobs := scheduler.Jobs(); jobs[0].Runs != 1 || !jobs[0].LastRun.Equal(now) {
+		t.Errorf("got %+v want one run at %v", jobs[0], now)
+	}
+
+	if _, err := scheduler.Trigger(context.Background(), "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("got %v want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestNewSchedulerInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []JobConfig
+	}{
+		{"missing name", []JobConfig{{Schedule: "@daily"}}},
+		{"duplicate name", []JobConfig{{Name: "a", Schedule: "@daily"}, {Name: "a", Schedule: "@hourly"}}},
+		{"invalid schedule", []JobConfig{{Name: "a", Schedule: "daily"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewScheduler(tt.configs, time.UTC, &recordingPublisher{}); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestLoadJobs(t *testing.T) {
+	jobs, err := LoadJobs("schedules.json", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 3 || jobs[0].Name != "daily-digest" {
+		t.Errorf("got %+v want the 3 example jobs", jobs)
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid JSON", `{`},
+		{"invalid job", `[{"name":"a","schedule":"sometimes"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "schedules.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadJobs(path, time.UTC); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+
+	if _, err := LoadJobs(filepath.Join(dir, "missing.json"), time.UTC); err == nil {
+		t.Error("got no error for a missing file")
+	}
+}
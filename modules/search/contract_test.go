@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/contract"
+)
+
+// TestEventContracts checks that the fields of the user and order events
+// declared in contracts/ are all the projector needs
+func TestEventContracts(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		producer, docType string
+	}{
+		{"foundation", documentTypeUser},
+		{"orders", documentTypeOrder},
+	} {
+		c, err := contract.Load(filepath.Join("contracts", tt.producer+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, example := range c.Examples() {
+			index := NewMemoryIndex()
+			if err := NewProjector(index, slog.Default()).Handle(ctx, example); err != nil {
+				t.Errorf("Handle(%s) error = %v", example.Type, err)
+				continue
+			}
+			doc, err := index.Get(ctx, documentID(tt.docType, "example"))
+			if example.Type == userDeletedEvent {
+				if err == nil {
+					t.Errorf("after %s got document %s want none", example.Type, doc.ID)
+				}
+				continue
+			}
+			if err != nil || doc.Version != 1 || doc.UpdatedAt.IsZero() || doc.Keywords["status"][0] != "example" {
+				t.Errorf("after %s got %+v, %v want the document of the example", example.Type, doc, err)
+			}
+			if tt.docType == documentTypeOrder && doc.Text["items"] != "example" {
+				t.Errorf("after %s got items %q want the SKU of the example", example.Type, doc.Text["items"])
+			}
+		}
+	}
+}
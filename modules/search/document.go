@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Document types
+const (
+	documentTypeUser  = "user"
+	documentTypeOrder = "order"
+)
+
+// textBoosts are the full text fields of documents, weighted by how much a
+// match in them counts towards the relevance of a document
+var textBoosts = map[string]float64{
+	"name":     2,
+	"email":    1.5,
+	"customer": 1,
+	"items":    1,
+	"reason":   0.5,
+}
+
+// facetFields are the keyword fields whose values are counted in results
+var facetFields = []string{"type", "status"}
+
+// Document is the searchable form of a user or an order. Text fields are
+// analyzed for full text queries; keyword fields hold exact, lower case
+// values for filters and facets. Source is the entity as received, returned
+// in results.
+type Document struct {
+	ID        string              `json:"id"`
+	Type      string              `json:"type"`
+	Version   int64               `json:"version"`
+	UpdatedAt time.Time           `json:"updated_at"`
+	Text      map[string]string   `json:"text"`
+	Keywords  map[string][]string `json:"keywords"`
+	Source    json.RawMessage     `json:"source"`
+}
+
+// documentID returns the ID of the document of the entity id of docType.
+// Users and orders are identified by their own services, so the type keeps
+// their IDs apart.
+func documentID(docType, id string) string {
+	return docType + ":" + id
+}
+
+// userSnapshot is the user carried by the events of the foundation service
+type userSnapshot struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	Status    string    `json:"status"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// orderSnapshot is the order carried by the events of the orders service
+type orderSnapshot struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id"`
+	Items      []struct {
+		SKU string `json:"sku"`
+	} `json:"items"`
+	Status       string    `json:"status"`
+	CancelReason string    `json:"cancel_reason"`
+	Version      int64     `json:"version"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// userDocument returns the document of the user whose snapshot is source
+func userDocument(source json.RawMessage, tenant string) (Document, error) {
+	var user userSnapshot
+	if err := json.Unmarshal(source, &user); err != nil {
+		return Document{}, err
+	}
+	doc := Document{
+		ID:        documentID(documentTypeUser, user.ID),
+		Type:      documentTypeUser,
+		Version:   user.Version,
+		UpdatedAt: user.UpdatedAt,
+		Text:      map[string]string{"name": user.Name, "email": user.Email},
+		Keywords: map[string][]string{
+			"type":   {documentTypeUser},
+			"id":     {keyword(user.ID)},
+			"status": {keyword(user.Status)},
+			"email":  {keyword(user.Email)},
+		},
+		Source: source,
+	}
+	for _, role := range user.Roles {
+		doc.Keywords["role"] = append(doc.Keywords["role"], keyword(role))
+	}
+	if tenant != "" {
+		doc.Keywords["tenant"] = []string{keyword(tenant)}
+	}
+	return doc, nil
+}
+
+// orderDocument returns the document of the order whose snapshot is source.
+// The name and email of its customer are copied in when known, so orders
+// are found by who placed them without a join at query time.
+func orderDocument(source json.RawMessage, tenant string, customer *userSnapshot) (Document, error) {
+	var order orderSnapshot
+	if err := json.Unmarshal(source, &order); err != nil {
+		return Document{}, err
+	}
+	skus := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		skus = append(skus, item.SKU)
+	}
+	doc := Document{
+		ID:        documentID(documentTypeOrder, order.ID),
+		Type:      documentTypeOrder,
+		Version:   order.Version,
+		UpdatedAt: order.UpdatedAt,
+		Text:      map[string]string{"items": strings.Join(skus, " ")},
+		Keywords: map[string][]string{
+			"type":        {documentTypeOrder},
+			"id":          {keyword(order.ID)},
+			"status":      {keyword(order.Status)},
+			"customer_id": {keyword(order.CustomerID)},
+		},
+		Source: source,
+	}
+	for _, sku := range skus {
+		doc.Keywords["sku"] = append(doc.Keywords["sku"], keyword(sku))
+	}
+	if order.CancelReason != "" {
+		doc.Text["reason"] = order.CancelReason
+	}
+	if customer != nil {
+		doc.Text["customer"] = customer.Name
+		doc.Text["email"] = customer.Email
+	}
+	if tenant != "" {
+		doc.Keywords["tenant"] = []string{keyword(tenant)}
+	}
+	return doc, nil
+}
+
+// keyword normalizes the value of a keyword field, which match regardless of case
+func keyword(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// tokenize splits text into the lower case terms of the full text index:
+// runs of letters and digits, so "ada@example.com" is ada, example and com
+func tokenize(text string) []string {
+	var terms []string
+	for _, span := range tokenSpans(text) {
+		terms = append(terms, strings.ToLower(text[span[0]:span[1]]))
+	}
+	return terms
+}
+
+// tokenSpans returns the byte offsets of the start and end of each term of text
+func tokenSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		inTerm := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inTerm && start < 0:
+			start = i
+		case !inTerm && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
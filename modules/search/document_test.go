@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testTime is the time of the entities of the tests
+var testTime = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// testUser returns the snapshot of a user as carried by user events
+func testUser(id, name, email, status string, version int64) json.RawMessage {
+	user, _ := json.Marshal(map[string]interface{}{
+		"id": id, "name": name, "email": email, "roles": []string{"user"}, "status": status,
+		"version": version, "updated_at": testTime.Add(time.Duration(version) * time.Minute),
+	})
+	return user
+}
+
+// testOrder returns the snapshot of an order as carried by order events
+func testOrder(id, customerID, status string, version int64, skus ...string) json.RawMessage {
+	items := make([]map[string]interface{}, 0, len(skus))
+	for _, sku := range skus {
+		items = append(items, map[string]interface{}{"sku": sku, "quantity": 1, "unit_price_cents": 500})
+	}
+	order, _ := json.Marshal(map[string]interface{}{
+		"id": id, "customer_id": customerID, "items": items, "status": status,
+		"version": version, "updated_at": testTime.Add(time.Duration(version) * time.Minute),
+	})
+	return order
+}
+
+func TestUserDocument(t *testing.T) {
+	doc, err := userDocument(testUser("u1", "Ada Lovelace", "Ada@Example.com", "active", 3), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "user:u1" || doc.Type != documentTypeUser || doc.Version != 3 {
+		t.Errorf("got %s %s version %d want user:u1 user version 3", doc.ID, doc.Type, doc.Version)
+	}
+	wantKeywords := map[string][]string{
+		"type": {"user"}, "id": {"u1"}, "status": {"active"}, "email": {"ada@example.com"}, "role": {"user"}, "tenant": {"acme"},
+	}
+	if !reflect.DeepEqual(doc.Keywords, wantKeywords) {
+		t.Errorf("got keywords %v want %v", doc.Keywords, wantKeywords)
+	}
+	if doc.Text["name"] != "Ada Lovelace" || doc.Text["email"] != "Ada@Example.com" {
+		t.Errorf("got text %v want the name and email", doc.Text)
+	}
+}
+
+func TestOrderDocument(t *testing.T) {
+	customer := &userSnapshot{ID: "u1", Name: "Ada Lovelace", Email: "ada@example.com"}
+	doc, err := orderDocument(testOrder("o1", "u1", "placed", 1, "MUG-1", "TEA"), "", customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "order:o1" || doc.Type != documentTypeOrder || doc.Version != 1 {
+		t.Errorf("got %s %s version %d want order:o1 order version 1", doc.ID, doc.Type, doc.Version)
+	}
+	wantText := map[string]string{"items": "MUG-1 TEA", "customer": "Ada Lovelace", "email": "ada@example.com"}
+	if !reflect.DeepEqual(doc.Text, wantText) {
+		t.Errorf("got text %v want %v", doc.Text, wantText)
+	}
+	if got := doc.Keywords["sku"]; !reflect.DeepEqual(got, []string{"mug-1", "tea"}) {
+		t.Errorf("got skus %v want mug-1 and tea", got)
+	}
+
+	doc, _ = orderDocument(testOrder("o1", "u1", "placed", 1), "", nil)
+	if _, ok := doc.Text["customer"]; ok {
+		t.Errorf("got text %v want no customer when unknown", doc.Text)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Ada Lovelace", []string{"ada", "lovelace"}},
+		{"ada@example.com", []string{"ada", "example", "com"}},
+		{"  MUG-1, tea!", []string{"mug", "1", "tea"}},
+		{"Zoë Ünal", []string{"zoë", "ünal"}},
+		{"-- ", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) got %v want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ElasticIndex keeps documents in an Elasticsearch index, through its REST
+// API rather than a client library. Documents are indexed with external
+// versions, so Elasticsearch itself refuses older versions of a document.
+// Searches see documents after the index refreshes, within a second.
+type ElasticIndex struct {
+	// URL is the base URL of the cluster, e.g. http://localhost:9200, with
+	// the credentials of basic authentication if any
+	URL string
+	// Index is the name of the index of the documents
+	Index string
+	// APIKey, when set, authenticates requests instead of basic authentication
+	APIKey string
+	Client *http.Client
+}
+
+// elasticMapping maps text fields to analyzed text, keyword fields to exact
+// keywords, and stores the source without indexing it
+const elasticMapping = `{
+  "mappings": {
+    "dynamic_templates": [
+      {"text": {"path_match": "text.*", "mapping": {"type": "text"}}},
+      {"keywords": {"path_match": "keywords.*", "mapping": {"type": "keyword"}}}
+    ],
+    "properties": {
+      "type": {"type": "keyword"},
+      "version": {"type": "long"},
+      "updated_at": {"type": "date"},
+      "source": {"type": "object", "enabled": false}
+    }
+  }
+}`
+
+// elasticError is the error body of Elasticsearch responses
+type elasticError struct {
+	Error struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// EnsureIndex creates the index with its mapping unless it exists
+func (e *ElasticIndex) EnsureIndex(ctx context.Context) error {
+	status, body, err := e.do(ctx, http.MethodPut, "/"+url.PathEscape(e.Index), []byte(elasticMapping))
+	if err != nil {
+		return err
+	}
+	if status == http.StatusBadRequest {
+		var failure elasticError
+		if json.Unmarshal(body, &failure) == nil && failure.Error.Type == "resource_already_exists_exception" {
+			return nil
+		}
+	}
+	return e.check(status, body, "creating index")
+}
+
+// Put indexes doc at its version. Equal versions are accepted, so the same
+// event applied twice, or a document refreshed at the same version, is not
+// an error.
+func (e *ElasticIndex) Put(ctx context.Context, doc Document) error {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	status, response, err := e.do(ctx, http.MethodPut, e.documentPath(doc.ID, doc.Version), body)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		return ErrStaleVersion
+	}
+	return e.check(status, response, "indexing "+doc.ID)
+}
+
+// Delete removes the document id at version
+func (e *ElasticIndex) Delete(ctx context.Context, id string, version int64) error {
+	status, response, err := e.do(ctx, http.MethodDelete, e.documentPath(id, version), nil)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusConflict:
+		return ErrStaleVersion
+	case http.StatusNotFound:
+		return nil
+	}
+	return e.check(status, response, "deleting "+id)
+}
+
+// Get returns the document id
+func (e *ElasticIndex) Get(ctx context.Context, id string) (Document, error) {
+	status, body, err := e.do(ctx, http.MethodGet, "/"+url.PathEscape(e.Index)+"/_doc/"+url.PathEscape(id), nil)
+	if err != nil {
+		return Document{}, err
+	}
+	if status == http.StatusNotFound {
+		return Document{}, ErrDocumentNotFound
+	}
+	if err := e.check(status, body, "getting "+id); err != nil {
+		return Document{}, err
+	}
+	var response struct {
+		Source Document `json:"_source"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Document{}, fmt.Errorf("elasticsearch: decoding %s: %w", id, err)
+	}
+	return response.Source, nil
+}
+
+// Search runs query as a bool query, with terms aggregations as facets and
+// the highlighting of text fields
+func (e *ElasticIndex) Search(ctx context.Context, query Query) (Results, error) {
+	body, err := json.Marshal(elasticSearchBody(query))
+	if err != nil {
+		return Results{}, err
+	}
+	status, response, err := e.do(ctx, http.MethodPost, "/"+url.PathEscape(e.Index)+"/_search", body)
+	if err != nil {
+		return Results{}, err
+	}
+	if err := e.check(status, response, "searching"); err != nil {
+		return Results{}, err
+	}
+
+	var found struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Score     *float64            `json:"_score"`
+				Source    Document            `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount int    `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := json.Unmarshal(response, &found); err != nil {
+		return Results{}, fmt.Errorf("elasticsearch: decoding search results: %w", err)
+	}
+
+	results := Results{Total: found.Hits.Total.Value, Hits: []Hit{}, Facets: make(map[string][]FacetValue)}
+	for _, hit := range found.Hits.Hits {
+		result := Hit{
+			ID:        hit.Source.ID,
+			Type:      hit.Source.Type,
+			Version:   hit.Source.Version,
+			UpdatedAt: hit.Source.UpdatedAt,
+			Source:    hit.Source.Source,
+		}
+		if hit.Score != nil {
+			result.Score = *hit.Score
+		}
+		for field, fragments := range hit.Highlight {
+			if result.Highlights == nil {
+				result.Highlights = make(map[string]string)
+			}
+			result.Highlights[strings.TrimPrefix(field, "text.")] = strings.Join(fragments, " … ")
+		}
+		results.Hits = append(results.Hits, result)
+	}
+	for _, field := range facetFields {
+		values := []FacetValue{}
+		for _, bucket := range found.Aggregations[field].Buckets {
+			values = append(values, FacetValue{Value: bucket.Key, Count: bucket.DocCount})
+		}
+		results.Facets[field] = values
+	}
+	return results, nil
+}
+
+// elasticSearchBody translates query to the body of a _search request
+func elasticSearchBody(query Query) map[string]interface{} {
+	fields := make([]string, 0, len(textBoosts))
+	for field, boost := range textBoosts {
+		fields = append(fields, fmt.Sprintf("text.%s^%g", field, boost))
+	}
+	sort.Strings(fields)
+
+	clause := func(term Term) map[string]interface{} {
+		match := map[string]interface{}{"query": term.Text, "fields": fields}
+		switch term.Kind {
+		case TermPrefix:
+			match["type"] = "phrase_prefix"
+		case TermFuzzy:
+			match["fuzziness"] = "AUTO"
+		}
+		return map[string]interface{}{"multi_match": match}
+	}
+	terms := func(filters map[string][]string) []interface{} {
+		clauses := []interface{}{}
+		for _, field := range sortedKeys(filters) {
+			clauses = append(clauses, map[string]interface{}{
+				"terms": map[string]interface{}{"keywords." + field: filters[field]},
+			})
+		}
+		return clauses
+	}
+
+	must := []interface{}{}
+	for _, term := range query.Must {
+		must = append(must, clause(term))
+	}
+	mustNot := terms(query.Excludes)
+	for _, term := range query.MustNot {
+		mustNot = append(mustNot, clause(term))
+	}
+
+	var order []interface{}
+	switch query.Sort {
+	case SortUpdated:
+		order = []interface{}{map[string]string{"updated_at": "asc"}}
+	case SortUpdatedDesc:
+		order = []interface{}{map[string]string{"updated_at": "desc"}}
+	default:
+		order = []interface{}{"_score", map[string]string{"updated_at": "desc"}}
+	}
+
+	aggregations := make(map[string]interface{})
+	for _, field := range facetFields {
+		aggregations[field] = map[string]interface{}{
+			"terms": map[string]interface{}{"field": "keywords." + field, "size": 20},
+		}
+	}
+
+	return map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":     must,
+				"filter":   terms(query.Filters),
+				"must_not": mustNot,
+			},
+		},
+		"sort":             order,
+		"from":             query.From,
+		"size":             query.Size,
+		"track_total_hits": true,
+		"aggs":             aggregations,
+		"highlight": map[string]interface{}{
+			"fields":    map[string]interface{}{"text.*": map[string]interface{}{}},
+			"pre_tags":  []string{"<em>"},
+			"post_tags": []string{"</em>"},
+		},
+	}
+}
+
+// documentPath returns the path of the document id written at version
+func (e *ElasticIndex) documentPath(id string, version int64) string {
+	return "/" + url.PathEscape(e.Index) + "/_doc/" + url.PathEscape(id) +
+		"?version_type=external_gte&version=" + strconv.FormatInt(version, 10)
+}
+
+// do sends a request with a JSON body to path and returns the status and
+// body of the response
+func (e *ElasticIndex) do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(e.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("elasticsearch: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.APIKey)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("elasticsearch: reading response: %w", err)
+	}
+	return resp.StatusCode, response, nil
+}
+
+// check returns an error for responses that are not successful
+func (e *ElasticIndex) check(status int, body []byte, action string) error {
+	if status/100 == 2 {
+		return nil
+	}
+	var failure elasticError
+	if json.Unmarshal(body, &failure) == nil && failure.Error.Reason != "" {
+		return fmt.Errorf("elasticsearch: %s: %d %s: %s", action, status, failure.Error.Type, failure.Error.Reason)
+	}
+	return fmt.Errorf("elasticsearch: %s: %d %s", action, status, http.StatusText(status))
+}
+
+// sortedKeys returns the keys of m in order, so requests are deterministic
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
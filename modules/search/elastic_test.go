@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeElasticsearch answers the requests of the index "people" like
+// Elasticsearch, recording them
+type fakeElasticsearch struct {
+	requests []string
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	f.bodies = append(f.bodies, body)
+	f.headers = append(f.headers, r.Header)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method + " " + r.URL.Path {
+	case "PUT /people":
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"type":"resource_already_exists_exception","reason":"index [people] already exists"}}`)
+	case "PUT /people/_doc/user:u1":
+		if r.URL.Query().Get("version") == "1" {
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, `{"error":{"type":"version_conflict_engine_exception","reason":"current version [2] is higher"}}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"result":"created"}`)
+	case "DELETE /people/_doc/user:u9":
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"result":"not_found"}`)
+	case "GET /people/_doc/user:u1":
+		io.WriteString(w, `{"_id":"user:u1","found":true,"_source":{"id":"user:u1","type":"user","version":2,"source":{"id":"u1"}}}`)
+	case "GET /people/_doc/user:u9":
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"_id":"user:u9","found":false}`)
+	case "POST /people/_search":
+		io.WriteString(w, `{
+			"hits": {"total": {"value": 7}, "hits": [
+				{"_id": "user:u1", "_score": 2.5,
+				 "_source": {"id": "user:u1", "type": "user", "version": 2, "source": {"id": "u1"}},
+				 "highlight": {"text.name": ["<em>Ada</em> Lovelace"]}}
+			]},
+			"aggregations": {
+				"type": {"buckets": [{"key": "user", "doc_count": 5}, {"key": "order", "doc_count": 2}]},
+				"status": {"buckets": []}
+			}
+		}`)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"error":{"type":"exception","reason":"unexpected request"}}`)
+	}
+}
+
+// newTestElasticIndex returns an index of a fake cluster
+func newTestElasticIndex(t *testing.T) (*ElasticIndex, *fakeElasticsearch) {
+	t.Helper()
+	fake := &fakeElasticsearch{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return &ElasticIndex{URL: server.URL + "/", Index: "people", APIKey: "secret", Client: server.Client()}, fake
+}
+
+func TestElasticIndex_Documents(t *testing.T) {
+	ctx := context.Background()
+	index, fake := newTestElasticIndex(t)
+
+	if err := index.EnsureIndex(ctx); err != nil {
+		t.Errorf("EnsureIndex() error = %v want an existing index accepted", err)
+	}
+
+	doc, _ := userDocument(testUser("u1", "Ada", "ada@example.com", "active", 2), "")
+	if err := index.Put(ctx, doc); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, want := fake.requests[1], "PUT /people/_doc/user:u1?version_type=external_gte&version=2"; got != want {
+		t.Errorf("got request %q want %q", got, want)
+	}
+	if got := fake.headers[1].Get("Authorization"); got != "ApiKey secret" {
+		t.Errorf("got Authorization %q want the API key", got)
+	}
+	var indexed Document
+	if err := json.Unmarshal(fake.bodies[1], &indexed); err != nil || indexed.Text["name"] != "Ada" {
+		t.Errorf("got body %s want the document", fake.bodies[1])
+	}
+
+	doc.Version = 1
+	if err := index.Put(ctx, doc); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("got error %v want %v on a version conflict", err, ErrStaleVersion)
+	}
+	if err := index.Delete(ctx, "user:u9", 1); err != nil {
+		t.Errorf("got error %v want a missing document deleted without error", err)
+	}
+	if err := index.Delete(ctx, "user:u2", 1); err == nil {
+		t.Error("got nil error want the failure of the cluster")
+	}
+
+	got, err := index.Get(ctx, "user:u1")
+	if err != nil || got.ID != "user:u1" || got.Version != 2 {
+		t.Errorf("got %+v, %v want user:u1 at version 2", got, err)
+	}
+	if _, err := index.Get(ctx, "user:u9"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("got error %v want %v", err, ErrDocumentNotFound)
+	}
+}
+
+func TestElasticIndex_Search(t *testing.T) {
+	index, fake := newTestElasticIndex(t)
+	query, _ := parseQuery("ada* -byron status:active")
+	query.Size = 10
+
+	results, err := index.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := Results{
+		Total: 7,
+		Hits: []Hit{{
+			ID: "user:u1", Type: "user", Score: 2.5, Version: 2,
+			Highlights: map[string]string{"name": "<em>Ada</em> Lovelace"},
+			Source:     json.RawMessage(`{"id": "u1"}`),
+		}},
+		Facets: map[string][]FacetValue{"type": {{"user", 5}, {"order", 2}}, "status": {}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got %+v want %+v", results, want)
+	}
+
+	var body struct {
+		Query struct {
+			Bool struct {
+				Must    []map[string]map[string]interface{} `json:"must"`
+				Filter  []map[string]map[string][]string    `json:"filter"`
+				MustNot []map[string]map[string]interface{} `json:"must_not"`
+			} `json:"bool"`
+		} `json:"query"`
+		Size int                    `json:"size"`
+		Aggs map[string]interface{} `json:"aggs"`
+	}
+	if err := json.Unmarshal(fake.bodies[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	must := body.Query.Bool.Must
+	if len(must) != 1 || must[0]["multi_match"]["query"] != "ada" || must[0]["multi_match"]["type"] != "phrase_prefix" {
+		t.Errorf("got must %v want a phrase prefix match of ada", must)
+	}
+	if filter := body.Query.Bool.Filter; len(filter) != 1 || !reflect.DeepEqual(filter[0]["terms"]["keywords.status"], []string{"active"}) {
+		t.Errorf("got filter %v want the terms of keywords.status", filter)
+	}
+	if mustNot := body.Query.Bool.MustNot; len(mustNot) != 1 || mustNot[0]["multi_match"]["query"] != "byron" {
+		t.Errorf("got must not %v want a match of byron", mustNot)
+	}
+	if body.Size != 10 || len(body.Aggs) != len(facetFields) {
+		t.Errorf("got size %d and aggregations %v want 10 and one per facet field", body.Size, body.Aggs)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// Pagination of search results
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// SearchResponse is the body of GET /search
+type SearchResponse struct {
+	Query   string `json:"query"`
+	Page    int    `json:"page"`
+	PerPage int    `json:"per_page"`
+	Results
+}
+
+// SearchHandler serves queries of the index
+type SearchHandler struct {
+	index Index
+	mux   *http.ServeMux
+}
+
+// NewSearchHandler creates the handler of the search and documents routes
+func NewSearchHandler(index Index) *SearchHandler {
+	h := &SearchHandler{index: index, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /search", h.handleSearch)
+	h.mux.HandleFunc("GET /documents/{id}", h.handleGetDocument)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleSearch handles GET /search?q=&type=&status=&sort=&page=&per_page=
+func (h *SearchHandler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query, err := searchQuery(params)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	results, err := h.index.Search(r.Context(), query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, SearchResponse{
+		Query:   params.Get("q"),
+		Page:    query.From/query.Size + 1,
+		PerPage: query.Size,
+		Results: results,
+	})
+}
+
+// handleGetDocument handles GET /documents/{id}, e.g. /documents/user:42
+func (h *SearchHandler) handleGetDocument(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.index.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, doc)
+}
+
+// searchQuery returns the query of the parameters of GET /search: q in the
+// query language, type and status as filters, sort and pagination
+func searchQuery(params url.Values) (Query, error) {
+	query, err := parseQuery(params.Get("q"))
+	if err != nil {
+		return Query{}, err
+	}
+	for _, field := range []string{"type", "status"} {
+		if value := keyword(params.Get(field)); value != "" {
+			query.Filters[field] = append(query.Filters[field], value)
+		}
+	}
+
+	switch sort := params.Get("sort"); sort {
+	case "":
+	case SortRelevance, SortUpdated, SortUpdatedDesc:
+		query.Sort = sort
+	default:
+		return Query{}, fmt.Errorf("%w: sort must be %s, %s or %s, got %q", ErrInvalidQuery, SortRelevance, SortUpdated, SortUpdatedDesc, sort)
+	}
+
+	page, err := positiveParam(params, "page", 1, 0)
+	if err != nil {
+		return Query{}, err
+	}
+	query.Size, err = positiveParam(params, "per_page", defaultPerPage, maxPerPage)
+	if err != nil {
+		return Query{}, err
+	}
+	query.From = (page - 1) * query.Size
+	return query, nil
+}
+
+// positiveParam returns the positive integer parameter name of params, up
+// to limit unless it is zero, or fallback when it is absent
+func positiveParam(params url.Values, name string, fallback, limit int) (int, error) {
+	value := params.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || (limit > 0 && n > limit) {
+		if limit > 0 {
+			return 0, fmt.Errorf("%w: %s must be between 1 and %d, got %q", ErrInvalidQuery, name, limit, value)
+		}
+		return 0, fmt.Errorf("%w: %s must be a positive integer, got %q", ErrInvalidQuery, name, value)
+	}
+	return n, nil
+}
+
+// writeError writes the problem matching a query error
+func (h *SearchHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidQuery):
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrDocumentNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Search failed", "error", err)
+		problem.Write(w, r, http.StatusBadGateway, "The search backend failed")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// failingIndex is an index whose backend is down
+type failingIndex struct{ Index }
+
+func (failingIndex) Search(context.Context, Query) (Results, error) {
+	return Results{}, errors.New("connection refused")
+}
+
+func TestSearchHandler(t *testing.T) {
+	handler := NewSearchHandler(newTestIndex(t))
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantTotal  int
+		wantHits   int
+	}{
+		{"all", "/search", http.StatusOK, 5, 5},
+		{"query", "/search?q=ada", http.StatusOK, 2, 2},
+		{"type filter", "/search?q=ada&type=User", http.StatusOK, 1, 1},
+		{"status filter", "/search?status=active", http.StatusOK, 2, 2},
+		{"page", "/search?sort=updated_at&page=3&per_page=2", http.StatusOK, 5, 1},
+		{"page beyond the results", "/search?page=9", http.StatusOK, 5, 0},
+		{"invalid query", "/search?q=status:", http.StatusBadRequest, 0, 0},
+		{"invalid sort", "/search?sort=name", http.StatusBadRequest, 0, 0},
+		{"invalid page", "/search?page=0", http.StatusBadRequest, 0, 0},
+		{"too many per page", "/search?per_page=101", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var response SearchResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Total != tt.wantTotal || len(response.Hits) != tt.wantHits {
+				t.Errorf("got %d hits of %d want %d of %d", len(response.Hits), response.Total, tt.wantHits, tt.wantTotal)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?sort=updated_at&page=3&per_page=2", nil))
+	var response SearchResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Page != 3 || response.PerPage != 2 || response.Hits[0].ID != "order:o2" {
+		t.Errorf("got page %d of %d starting with %s want page 3 of 2 starting with order:o2", response.Page, response.PerPage, response.Hits[0].ID)
+	}
+}
+
+func TestSearchHandler_Documents(t *testing.T) {
+	handler := NewSearchHandler(newTestIndex(t))
+	tests := []struct {
+		target     string
+		wantStatus int
+	}{
+		{"/documents/user:u1", http.StatusOK},
+		{"/documents/user:u9", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("GET %s got status %d want %d", tt.target, rec.Code, tt.wantStatus)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(failingIndex{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=ada", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("got status %d want %d when the backend fails", rec.Code, http.StatusBadGateway)
+	}
+}
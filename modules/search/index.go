@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrDocumentNotFound is returned for documents not in the index
+	ErrDocumentNotFound = errors.New("document not found")
+	// ErrStaleVersion is returned when the index holds a newer version of a document
+	ErrStaleVersion = errors.New("stale document version")
+)
+
+// Index stores documents and searches them. Documents carry the version of
+// their entity, so events applied out of order or twice never replace a
+// document with an older one.
+type Index interface {
+	// Put indexes doc, replacing the document with its ID unless the index
+	// holds a newer version, which returns ErrStaleVersion
+	Put(ctx context.Context, doc Document) error
+
+	// Delete removes the document id deleted at version, unless the index
+	// holds a newer version, which returns ErrStaleVersion. Missing
+	// documents are not an error.
+	Delete(ctx context.Context, id string, version int64) error
+
+	// Get returns the document id, or ErrDocumentNotFound
+	Get(ctx context.Context, id string) (Document, error)
+
+	// Search returns the page of the documents found by query
+	Search(ctx context.Context, query Query) (Results, error)
+}
+
+// BM25 parameters: how quickly repeated terms stop adding to the score, and
+// how much long fields are penalized
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// MemoryIndex is an in-process inverted index, scoring documents with BM25
+// like Lucene based engines do. It is lost on restart and rebuilt from the
+// events the service receives.
+type MemoryIndex struct {
+	mutex sync.RWMutex
+	docs  map[string]*memoryDocument
+	// versions keeps the version of deleted documents too
+	versions map[string]int64
+	// postings lists the documents of each term
+	postings map[string]map[string]struct{}
+	// fieldTerms and fieldDocs give the average length of each text field
+	fieldTerms map[string]int
+	fieldDocs  map[string]int
+}
+
+// memoryDocument is a document with the frequency of the terms of each of
+// its text fields
+type memoryDocument struct {
+	Document
+	terms   map[string]map[string]int
+	lengths map[string]int
+}
+
+// NewMemoryIndex creates an empty index
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:       make(map[string]*memoryDocument),
+		versions:   make(map[string]int64),
+		postings:   make(map[string]map[string]struct{}),
+		fieldTerms: make(map[string]int),
+		fieldDocs:  make(map[string]int),
+	}
+}
+
+// Put indexes doc
+func (m *MemoryIndex) Put(_ context.Context, doc Document) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if version, ok := m.versions[doc.ID]; ok && version > doc.Version {
+		return ErrStaleVersion
+	}
+	m.remove(doc.ID)
+
+	indexed := &memoryDocument{
+		Document: doc,
+		terms:    make(map[string]map[string]int),
+		lengths:  make(map[string]int),
+	}
+	for field, text := range doc.Text {
+		terms := tokenize(text)
+		if len(terms) == 0 {
+			continue
+		}
+		frequencies := make(map[string]int)
+		for _, term := range terms {
+			frequencies[term]++
+			if m.postings[term] == nil {
+				m.postings[term] = make(map[string]struct{})
+			}
+			m.postings[term][doc.ID] = struct{}{}
+		}
+		indexed.terms[field] = frequencies
+		indexed.lengths[field] = len(terms)
+		m.fieldTerms[field] += len(terms)
+		m.fieldDocs[field]++
+	}
+	m.docs[doc.ID] = indexed
+	m.versions[doc.ID] = doc.Version
+	return nil
+}
+
+// Delete removes the document id
+func (m *MemoryIndex) Delete(_ context.Context, id string, version int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if current, ok := m.versions[id]; ok && current > version {
+		return ErrStaleVersion
+	}
+	m.remove(id)
+	m.versions[id] = version
+	return nil
+}
+
+// remove takes the document id out of the postings and field lengths
+func (m *MemoryIndex) remove(id string) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for field, frequencies := range doc.terms {
+		for term := range frequencies {
+			delete(m.postings[term], id)
+			if len(m.postings[term]) == 0 {
+				delete(m.postings, term)
+			}
+		}
+		m.fieldTerms[field] -= doc.lengths[field]
+		m.fieldDocs[field]--
+	}
+	delete(m.docs, id)
+}
+
+// Get returns the document id
+func (m *MemoryIndex) Get(_ context.Context, id string) (Document, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	doc, ok := m.docs[id]
+	if !ok {
+		return Document{}, ErrDocumentNotFound
+	}
+	return doc.Document, nil
+}
+
+// Search returns the documents found by query
+func (m *MemoryIndex) Search(_ context.Context, query Query) (Results, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	must := make([]map[string]float64, len(query.Must))
+	for i, term := range query.Must {
+		must[i] = m.expand(term)
+	}
+	mustNot := make([]map[string]float64, len(query.MustNot))
+	for i, term := range query.MustNot {
+		mustNot[i] = m.expand(term)
+	}
+
+	type match struct {
+		doc     *memoryDocument
+		score   float64
+		matched map[string]bool
+	}
+	var matches []match
+	for _, doc := range m.docs {
+		if !matchesFilters(doc.Keywords, query.Filters, query.Excludes) {
+			continue
+		}
+		found := match{doc: doc, matched: make(map[string]bool)}
+		for _, terms := range must {
+			score := m.score(doc, terms, found.matched)
+			if score == 0 {
+				found.score = -1
+				break
+			}
+			found.score += score
+		}
+		if found.score < 0 || slices.ContainsFunc(mustNot, func(terms map[string]float64) bool {
+			return m.score(doc, terms, nil) > 0
+		}) {
+			continue
+		}
+		matches = append(matches, found)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case query.Sort == SortRelevance && a.score != b.score:
+			return a.score > b.score
+		case query.Sort == SortUpdated && !a.doc.UpdatedAt.Equal(b.doc.UpdatedAt):
+			return a.doc.UpdatedAt.Before(b.doc.UpdatedAt)
+		case query.Sort != SortUpdated && !a.doc.UpdatedAt.Equal(b.doc.UpdatedAt):
+			return a.doc.UpdatedAt.After(b.doc.UpdatedAt)
+		}
+		return a.doc.ID < b.doc.ID
+	})
+
+	results := Results{Total: len(matches), Hits: []Hit{}, Facets: make(map[string][]FacetValue)}
+	for _, field := range facetFields {
+		counts := make(map[string]int)
+		for _, found := range matches {
+			for _, value := range found.doc.Keywords[field] {
+				counts[value]++
+			}
+		}
+		results.Facets[field] = facetValues(counts)
+	}
+
+	start := min(query.From, len(matches))
+	end := min(start+query.Size, len(matches))
+	for _, found := range matches[start:end] {
+		results.Hits = append(results.Hits, Hit{
+			ID:         found.doc.ID,
+			Type:       found.doc.Type,
+			Score:      math.Round(found.score*1000) / 1000,
+			Version:    found.doc.Version,
+			UpdatedAt:  found.doc.UpdatedAt,
+			Highlights: highlight(found.doc.Text, found.matched),
+			Source:     found.doc.Source,
+		})
+	}
+	return results, nil
+}
+
+// expand returns the indexed terms matched by term, weighted by how close
+// they are to it
+func (m *MemoryIndex) expand(term Term) map[string]float64 {
+	expanded := make(map[string]float64)
+	switch term.Kind {
+	case TermExact:
+		if _, ok := m.postings[term.Text]; ok {
+			expanded[term.Text] = 1
+		}
+	case TermPrefix:
+		for indexed := range m.postings {
+			if strings.HasPrefix(indexed, term.Text) {
+				expanded[indexed] = 1
+			}
+		}
+	case TermFuzzy:
+		maxEdits := fuzziness(term.Text)
+		for indexed := range m.postings {
+			if edits := editDistance(term.Text, indexed, maxEdits); edits <= maxEdits {
+				expanded[indexed] = 1 / float64(1+edits)
+			}
+		}
+	}
+	return expanded
+}
+
+// score returns the BM25 score of the best of terms in the text fields of
+// doc, zero when none is in them. The terms of doc that matched are added
+// to matched.
+func (m *MemoryIndex) score(doc *memoryDocument, terms map[string]float64, matched map[string]bool) float64 {
+	best := 0.0
+	for term, weight := range terms {
+		found := float64(len(m.postings[term]))
+		idf := math.Log(1 + (float64(len(m.docs))-found+0.5)/(found+0.5))
+		score := 0.0
+		for field, frequencies := range doc.terms {
+			frequency := float64(frequencies[term])
+			if frequency == 0 {
+				continue
+			}
+			averageLength := float64(m.fieldTerms[field]) / float64(m.fieldDocs[field])
+			norm := 1 - bm25B + bm25B*float64(doc.lengths[field])/averageLength
+			score += textBoosts[field] * idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*norm)
+		}
+		if score > 0 && matched != nil {
+			matched[term] = true
+		}
+		best = max(best, score*weight)
+	}
+	return best
+}
+
+// matchesFilters reports whether keywords have one of the values of every
+// field of filters, and none of the values of excludes
+func matchesFilters(keywords, filters, excludes map[string][]string) bool {
+	for field, values := range filters {
+		if !slices.ContainsFunc(values, func(value string) bool { return slices.Contains(keywords[field], value) }) {
+			return false
+		}
+	}
+	for field, values := range excludes {
+		if slices.ContainsFunc(values, func(value string) bool { return slices.Contains(keywords[field], value) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// facetValues returns counts as facet values, the most frequent first
+func facetValues(counts map[string]int) []FacetValue {
+	values := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	return values
+}
+
+// highlight returns the text fields holding matched terms, the terms
+// wrapped in <em>
+func highlight(text map[string]string, matched map[string]bool) map[string]string {
+	highlights := make(map[string]string)
+	for field, value := range text {
+		var b strings.Builder
+		last := 0
+		for _, span := range tokenSpans(value) {
+			if !matched[strings.ToLower(value[span[0]:span[1]])] {
+				continue
+			}
+			b.WriteString(value[last:span[0]])
+			b.WriteString("<em>" + value[span[0]:span[1]] + "</em>")
+			last = span[1]
+		}
+		if last > 0 {
+			b.WriteString(value[last:])
+			highlights[field] = b.String()
+		}
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// fuzziness returns the edits allowed to fuzzy terms, the AUTO fuzziness of
+// Elasticsearch: none up to 2 characters, one up to 5 and two beyond
+func fuzziness(term string) int {
+	switch length := len([]rune(term)); {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the Levenshtein distance of a and b, or maxEdits+1
+// once it is known to exceed maxEdits
+func editDistance(a, b string, maxEdits int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > maxEdits || -diff > maxEdits {
+		return maxEdits + 1
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > maxEdits {
+			return maxEdits + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
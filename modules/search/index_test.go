@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestIndex returns an index of three users and two orders
+func newTestIndex(t *testing.T) *MemoryIndex {
+	t.Helper()
+	index := NewMemoryIndex()
+	ada := &userSnapshot{ID: "u1", Name: "Ada Lovelace", Email: "ada@example.com"}
+	alan := &userSnapshot{ID: "u3", Name: "Alan Turing", Email: "alan@example.com"}
+	var docs []Document
+	for _, user := range []json.RawMessage{
+		testUser("u1", "Ada Lovelace", "ada@example.com", "active", 1),
+		testUser("u2", "Grace Hopper", "grace@navy.mil", "active", 2),
+		testUser("u3", "Alan Turing", "alan@example.com", "suspended", 3),
+	} {
+		doc, err := userDocument(user, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	placed, _ := orderDocument(testOrder("o1", "u1", "placed", 4, "MUG", "TEA"), "", ada)
+	cancelled, _ := orderDocument(testOrder("o2", "u3", "cancelled", 5, "KETTLE"), "", alan)
+	for _, doc := range append(docs, placed, cancelled) {
+		if err := index.Put(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return index
+}
+
+// hitIDs returns the IDs of the hits of results, in order
+func hitIDs(results Results) []string {
+	ids := []string{}
+	for _, hit := range results.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestMemoryIndex_Search(t *testing.T) {
+	index := newTestIndex(t)
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"", []string{"order:o1", "order:o2", "user:u1", "user:u2", "user:u3"}},
+		{"ada", []string{"order:o1", "user:u1"}},
+		{"ADA lovelace", []string{"order:o1", "user:u1"}},
+		{"ada type:user", []string{"user:u1"}},
+		{"lovelase~", []string{"order:o1", "user:u1"}},
+		{"tur*", []string{"order:o2", "user:u3"}},
+		{"example -ada", []string{"order:o2", "user:u3"}},
+		{"status:active", []string{"user:u1", "user:u2"}},
+		{"status:active status:cancelled", []string{"order:o2", "user:u1", "user:u2"}},
+		{"-type:order -status:suspended", []string{"user:u1", "user:u2"}},
+		{"sku:kettle", []string{"order:o2"}},
+		{"mug customer_id:u1", []string{"order:o1"}},
+		{"nobody", []string{}},
+		{"ada nobody", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			query, err := parseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query.Size = 10
+			results, err := index.Search(context.Background(), query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			got := hitIDs(results)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) || results.Total != len(tt.want) {
+				t.Errorf("got %v (total %d) want %v", got, results.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndex_Relevance(t *testing.T) {
+	index := newTestIndex(t)
+	query, _ := parseQuery("ada")
+	query.Size = 10
+	results, _ := index.Search(context.Background(), query)
+
+	// The name of a user weighs more than the customer name of an order
+	if got := hitIDs(results); !reflect.DeepEqual(got, []string{"user:u1", "order:o1"}) {
+		t.Errorf("got %v want the user before the order", got)
+	}
+	if hit := results.Hits[0]; hit.Score <= results.Hits[1].Score {
+		t.Errorf("got scores %v and %v want decreasing scores", hit.Score, results.Hits[1].Score)
+	}
+	want := map[string]string{"name": "<em>Ada</em> Lovelace", "email": "<em>ada</em>@example.com"}
+	if got := results.Hits[0].Highlights; !reflect.DeepEqual(got, want) {
+		t.Errorf("got highlights %v want %v", got, want)
+	}
+}
+
+func TestMemoryIndex_SortAndFacets(t *testing.T) {
+	index := newTestIndex(t)
+	query := Query{Sort: SortUpdated, From: 1, Size: 2}
+	results, _ := index.Search(context.Background(), query)
+	if got := hitIDs(results); !reflect.DeepEqual(got, []string{"user:u2", "user:u3"}) || results.Total != 5 {
+		t.Errorf("got %v (total %d) want the second page of the oldest first", got, results.Total)
+	}
+
+	query.Sort = SortUpdatedDesc
+	results, _ = index.Search(context.Background(), query)
+	if got := hitIDs(results); !reflect.DeepEqual(got, []string{"order:o1", "user:u3"}) {
+		t.Errorf("got %v want the second page of the newest first", got)
+	}
+
+	wantFacets := map[string][]FacetValue{
+		"type":   {{"user", 3}, {"order", 2}},
+		"status": {{"active", 2}, {"cancelled", 1}, {"placed", 1}, {"suspended", 1}},
+	}
+	if !reflect.DeepEqual(results.Facets, wantFacets) {
+		t.Errorf("got facets %v want %v", results.Facets, wantFacets)
+	}
+}
+
+func TestMemoryIndex_Versions(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	put := func(name string, version int64) error {
+		doc, _ := userDocument(testUser("u1", name, "ada@example.com", "active", version), "")
+		return index.Put(ctx, doc)
+	}
+
+	if err := put("Ada", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("Old Ada", 1); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("got error %v want %v for an older version", err, ErrStaleVersion)
+	}
+	if err := put("Ada", 2); err != nil {
+		t.Errorf("got error %v want the same version applied again", err)
+	}
+	if err := index.Delete(ctx, "user:u1", 1); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("got error %v want %v for an older deletion", err, ErrStaleVersion)
+	}
+	if err := index.Delete(ctx, "user:u1", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.Get(ctx, "user:u1"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("got error %v want %v after deletion", err, ErrDocumentNotFound)
+	}
+	// A late event about the user does not bring it back
+	if err := put("Ada", 2); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("got error %v want %v after deletion", err, ErrStaleVersion)
+	}
+
+	query, _ := parseQuery("ada")
+	query.Size = 10
+	if results, _ := index.Search(ctx, query); results.Total != 0 {
+		t.Errorf("got %d hits want the terms of the deleted user removed", results.Total)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		maxEdits int
+		want     int
+	}{
+		{"lovelace", "lovelace", 2, 0},
+		{"lovelase", "lovelace", 2, 1},
+		{"lovlase", "lovelace", 2, 2},
+		{"love", "lovelace", 2, 3},
+		{"zoe", "zoë", 1, 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.maxEdits); got != tt.want {
+			t.Errorf("editDistance(%q, %q) got %d want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+const (
+	defaultPort               = "8093"
+	defaultHost               = "localhost"
+	defaultUserEventsURL      = "http://localhost:8080/events"
+	defaultOrderEventsURL     = "http://localhost:8081/events"
+	defaultElasticsearchURL   = "http://localhost:9200"
+	defaultElasticsearchIndex = "search"
+)
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	userEventsURL, orderEventsURL, err := loadEventStreams()
+	if err != nil {
+		fatal("Invalid event streams", "error", err)
+	}
+	index, err := loadIndex()
+	if err != nil {
+		fatal("Invalid search backend", "error", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if elastic, ok := index.(*ElasticIndex); ok {
+		if err := elastic.EnsureIndex(ctx); err != nil {
+			fatal("Failed to create the Elasticsearch index", "error", err)
+		}
+	}
+
+	// Project the user events of the foundation service and the order
+	// events of the orders service into the index
+	projector := NewProjector(index, logger)
+	users := &events.Subscriber{URL: userEventsURL, Types: userEventTypes}
+	if token := os.Getenv("USER_EVENTS_TOKEN"); token != "" {
+		users.Header = http.Header{"Authorization": {"Bearer " + token}}
+	} else {
+		slog.Warn("USER_EVENTS_TOKEN is not set: the user event stream may refuse the connection")
+	}
+	orders := &events.Subscriber{URL: orderEventsURL, Types: orderEventTypes}
+	go users.Run(ctx, projector.Handle)
+	go orders.Run(ctx, projector.Handle)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewSearchHandler(index)
+	mux.Handle("/search", api)
+	mux.Handle("/documents/", api)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting search service", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"backend", config.Getenv("SEARCH_BACKEND", "memory"), "user_events", userEventsURL, "order_events", orderEventsURL)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Search service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down search service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Search service forced to shutdown", "error", err)
+	}
+	slog.Info("Search service exited")
+}
+
+// loadEventStreams reads the URLs of the user and order event streams from
+// USER_EVENTS_URL and ORDER_EVENTS_URL
+func loadEventStreams() (users, orders string, err error) {
+	users = config.Getenv("USER_EVENTS_URL", defaultUserEventsURL)
+	orders = config.Getenv("ORDER_EVENTS_URL", defaultOrderEventsURL)
+	for key, stream := range map[string]string{"USER_EVENTS_URL": users, "ORDER_EVENTS_URL": orders} {
+		if u, err := url.Parse(stream); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return "", "", fmt.Errorf("%s must be an http(s) URL, got %q", key, stream)
+		}
+	}
+	return users, orders, nil
+}
+
+// loadIndex creates the index selected by SEARCH_BACKEND: memory or elasticsearch
+func loadIndex() (Index, error) {
+	switch backend := config.Getenv("SEARCH_BACKEND", "memory"); backend {
+	case "memory":
+		return NewMemoryIndex(), nil
+	case "elasticsearch":
+		address := config.Getenv("ELASTICSEARCH_URL", defaultElasticsearchURL)
+		if u, err := url.Parse(address); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("ELASTICSEARCH_URL must be an http(s) URL, got %q", address)
+		}
+		return &ElasticIndex{
+			URL:    address,
+			Index:  config.Getenv("ELASTICSEARCH_INDEX", defaultElasticsearchIndex),
+			APIKey: os.Getenv("ELASTICSEARCH_API_KEY"),
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("SEARCH_BACKEND must be 'memory' or 'elasticsearch', got %q", backend)
+	}
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "search",
+		"endpoints": map[string]string{
+			"search":    "/search?q=&type=&status=&sort=&page=&per_page=",
+			"documents": "/documents/{id}",
+			"health":    "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestLoadEventStreams(t *testing.T) {
+	tests := []struct {
+		name       string
+		users      string
+		orders     string
+		wantUsers  string
+		wantOrders string
+		wantErr    bool
+	}{
+		{"defaults", "", "", defaultUserEventsURL, defaultOrderEventsURL, false},
+		{"custom", "https://users.example.com/events", "http://orders:8081/events", "https://users.example.com/events", "http://orders:8081/events", false},
+		{"invalid users", "localhost:8080", "", "", "", true},
+		{"invalid orders", "", "ftp://orders/events", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("USER_EVENTS_URL", tt.users)
+			t.Setenv("ORDER_EVENTS_URL", tt.orders)
+			users, orders, err := loadEventStreams()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadEventStreams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if users != tt.wantUsers || orders != tt.wantOrders {
+				t.Errorf("got %q and %q want %q and %q", users, orders, tt.wantUsers, tt.wantOrders)
+			}
+		})
+	}
+}
+
+func TestLoadIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"default", nil, "memory", false},
+		{"elasticsearch", map[string]string{"SEARCH_BACKEND": "elasticsearch", "ELASTICSEARCH_INDEX": "people"}, "elasticsearch", false},
+		{"invalid url", map[string]string{"SEARCH_BACKEND": "elasticsearch", "ELASTICSEARCH_URL": "localhost:9200"}, "", true},
+		{"unknown", map[string]string{"SEARCH_BACKEND": "bleve"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"SEARCH_BACKEND", "ELASTICSEARCH_URL", "ELASTICSEARCH_INDEX", "ELASTICSEARCH_API_KEY"} {
+				t.Setenv(key, tt.env[key])
+			}
+			index, err := loadIndex()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch index := index.(type) {
+			case *MemoryIndex:
+				if tt.want != "memory" {
+					t.Errorf("got a memory index want %s", tt.want)
+				}
+			case *ElasticIndex:
+				if tt.want != "elasticsearch" || index.URL != defaultElasticsearchURL || index.Index != "people" {
+					t.Errorf("got %+v want an index people of the default cluster", index)
+				}
+			}
+		})
+	}
+}
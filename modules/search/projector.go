@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Event type patterns the projector follows
+var (
+	userEventTypes  = []string{"user.*"}
+	orderEventTypes = []string{"order.*"}
+)
+
+// userDeletedEvent is the type of the event removing a user
+const userDeletedEvent = "user.deleted"
+
+// Projector keeps the index in step with the events of the foundation and
+// orders services: the search projection. It is the only writer of the
+// index, which is a read model that can always be rebuilt from the events.
+type Projector struct {
+	index  Index
+	logger *slog.Logger
+}
+
+// NewProjector creates a projector writing to index
+func NewProjector(index Index, logger *slog.Logger) *Projector {
+	return &Projector{index: index, logger: logger}
+}
+
+// Handle applies a user or order event, as an events.Handler. Events older
+// than the indexed document are ignored.
+func (p *Projector) Handle(ctx context.Context, event events.Event) error {
+	var err error
+	switch {
+	case strings.HasPrefix(event.Type, "user."):
+		err = p.handleUserEvent(ctx, event)
+	case strings.HasPrefix(event.Type, "order."):
+		err = p.handleOrderEvent(ctx, event)
+	default:
+		return nil
+	}
+	if errors.Is(err, ErrStaleVersion) {
+		p.logger.DebugContext(ctx, "Ignored stale event", "event_id", event.ID, "type", event.Type)
+		return nil
+	}
+	return err
+}
+
+// handleUserEvent indexes the user of the event, or removes it, and copies
+// its name and email into its orders
+func (p *Projector) handleUserEvent(ctx context.Context, event events.Event) error {
+	var data struct {
+		User json.RawMessage `json:"user"`
+	}
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	doc, err := userDocument(data.User, event.Tenant)
+	if err != nil {
+		return err
+	}
+
+	if event.Type == userDeletedEvent {
+		// Orders keep the name of their deleted customer
+		return p.index.Delete(ctx, doc.ID, doc.Version)
+	}
+	if err := p.index.Put(ctx, doc); err != nil {
+		return err
+	}
+	return p.refreshOrders(ctx, data.User, event.Tenant)
+}
+
+// handleOrderEvent indexes the order of the event along with its customer
+func (p *Projector) handleOrderEvent(ctx context.Context, event events.Event) error {
+	var data struct {
+		Order json.RawMessage `json:"order"`
+	}
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	var order orderSnapshot
+	if err := json.Unmarshal(data.Order, &order); err != nil {
+		return err
+	}
+	customer, err := p.customer(ctx, order.CustomerID)
+	if err != nil {
+		return err
+	}
+	doc, err := orderDocument(data.Order, event.Tenant, customer)
+	if err != nil {
+		return err
+	}
+	return p.index.Put(ctx, doc)
+}
+
+// customer returns the indexed user with id, nil when the user is not indexed
+func (p *Projector) customer(ctx context.Context, id string) (*userSnapshot, error) {
+	doc, err := p.index.Get(ctx, documentID(documentTypeUser, id))
+	if errors.Is(err, ErrDocumentNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var user userSnapshot
+	if err := json.Unmarshal(doc.Source, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// refreshOrders indexes the orders of the user whose snapshot is source
+// again, with the user's current name and email. Orders keep their version,
+// so a newer version of an order indexed meanwhile is not replaced.
+func (p *Projector) refreshOrders(ctx context.Context, source json.RawMessage, tenant string) error {
+	var user userSnapshot
+	if err := json.Unmarshal(source, &user); err != nil {
+		return err
+	}
+	query := Query{
+		Filters: map[string][]string{"type": {documentTypeOrder}, "customer_id": {keyword(user.ID)}},
+		Sort:    SortUpdated,
+		Size:    maxPerPage,
+	}
+	for {
+		results, err := p.index.Search(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, hit := range results.Hits {
+			doc, err := orderDocument(hit.Source, tenant, &user)
+			if err != nil {
+				return err
+			}
+			if err := p.index.Put(ctx, doc); err != nil && !errors.Is(err, ErrStaleVersion) {
+				return err
+			}
+		}
+		query.From += len(results.Hits)
+		if len(results.Hits) == 0 || query.From >= results.Total {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// event creates an event of eventType carrying the entity source under key
+func event(t *testing.T, eventType, key string, source json.RawMessage) events.Event {
+	t.Helper()
+	e, err := events.New("evt", eventType, "test", "", map[string]json.RawMessage{key: source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestProjector(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	projector := NewProjector(index, slog.Default())
+
+	apply := func(events ...events.Event) {
+		t.Helper()
+		for _, e := range events {
+			if err := projector.Handle(ctx, e); err != nil {
+				t.Fatalf("Handle(%s) error = %v", e.Type, err)
+			}
+		}
+	}
+	customer := func(id string) string {
+		t.Helper()
+		doc, err := index.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc.Text["customer"]
+	}
+
+	// The order arrives before its customer, then follows the renames
+	apply(
+		event(t, "order.placed", "order", testOrder("o1", "u1", "placed", 1, "MUG")),
+		event(t, "user.created", "user", testUser("u1", "Ada", "ada@example.com", "pending", 1)),
+	)
+	if got := customer("order:o1"); got != "Ada" {
+		t.Errorf("got customer %q want the name of the user indexed later", got)
+	}
+
+	apply(
+		event(t, "order.cancelled", "order", testOrder("o1", "u1", "cancelled", 2, "MUG")),
+		event(t, "user.updated", "user", testUser("u1", "Ada Lovelace", "ada@example.com", "pending", 3)),
+		// Redelivered and late events are ignored
+		event(t, "order.placed", "order", testOrder("o1", "u1", "placed", 1, "MUG")),
+		event(t, "user.created", "user", testUser("u1", "Ada", "ada@example.com", "pending", 1)),
+		event(t, "payment.captured", "payment", json.RawMessage(`{}`)),
+	)
+	doc, _ := index.Get(ctx, "order:o1")
+	if doc.Version != 2 || doc.Text["customer"] != "Ada Lovelace" || !strings.Contains(string(doc.Source), `"cancelled"`) {
+		t.Errorf("got order %+v want the cancelled order of Ada Lovelace", doc)
+	}
+	if got := customer("user:u1"); got != "" {
+		t.Errorf("got customer %q on a user", got)
+	}
+	if user, _ := index.Get(ctx, "user:u1"); user.Text["name"] != "Ada Lovelace" {
+		t.Errorf("got name %q want the latest name", user.Text["name"])
+	}
+
+	apply(event(t, "user.deleted", "user", testUser("u1", "Ada Lovelace", "ada@example.com", "pending", 3)))
+	if _, err := index.Get(ctx, "user:u1"); err == nil {
+		t.Error("got the deleted user want it removed")
+	}
+	if got := customer("order:o1"); got != "Ada Lovelace" {
+		t.Errorf("got customer %q want orders to keep the name of their deleted customer", got)
+	}
+}
+
+func TestProjector_InvalidEvent(t *testing.T) {
+	projector := NewProjector(NewMemoryIndex(), slog.Default())
+	e := event(t, "user.created", "user", json.RawMessage(`"ada"`))
+	if err := projector.Handle(context.Background(), e); err == nil {
+		t.Error("got nil error want the invalid user refused")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned for queries that cannot be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// maxQueryClauses bounds the terms and filters of a query
+const maxQueryClauses = 32
+
+// Sort orders of results
+const (
+	SortRelevance   = "relevance"
+	SortUpdated     = "updated_at"
+	SortUpdatedDesc = "-updated_at"
+)
+
+// TermKind tells how a term of a query matches the terms of documents
+type TermKind int
+
+const (
+	// TermExact matches the same term
+	TermExact TermKind = iota
+	// TermPrefix matches the terms starting with the term
+	TermPrefix
+	// TermFuzzy matches the terms within a few typos of the term
+	TermFuzzy
+)
+
+// Term is a full text term of a query
+type Term struct {
+	Text string
+	Kind TermKind
+}
+
+// Query is a parsed search: every Must term matches a text field of the
+// documents found, no MustNot term does, and for every field of Filters
+// one of its values is a value of the document, none of Excludes.
+type Query struct {
+	Must     []Term
+	MustNot  []Term
+	Filters  map[string][]string
+	Excludes map[string][]string
+	Sort     string
+	From     int
+	Size     int
+}
+
+// Results are the documents found by a query, one page of them, with the
+// number of documents found for each value of the facet fields
+type Results struct {
+	Total  int                     `json:"total"`
+	Hits   []Hit                   `json:"hits"`
+	Facets map[string][]FacetValue `json:"facets"`
+}
+
+// Hit is a document found by a query
+type Hit struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Score     float64   `json:"score"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Highlights are the text fields matching the query, their matching
+	// terms wrapped in <em>
+	Highlights map[string]string `json:"highlights,omitempty"`
+	Source     json.RawMessage   `json:"source"`
+}
+
+// FacetValue is a value of a facet field and the number of documents found with it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// parseQuery parses the query language of the q parameter. Words are full
+// text terms that must all match; "ada*" matches terms starting with ada,
+// "ada~" terms within a typo or two of it. "field:value" filters on a
+// keyword field, several values of one field matching any of them. A
+// leading "-" excludes documents matching a term or a filter.
+func parseQuery(q string) (Query, error) {
+	query := Query{
+		Filters:  make(map[string][]string),
+		Excludes: make(map[string][]string),
+		Sort:     SortRelevance,
+	}
+	clauses := 0
+	for _, token := range strings.Fields(q) {
+		exclude := false
+		if rest, ok := strings.CutPrefix(token, "-"); ok && rest != "" {
+			token, exclude = rest, true
+		}
+
+		if field, value, ok := strings.Cut(token, ":"); ok && isFieldName(field) {
+			if value = keyword(value); value == "" {
+				return Query{}, fmt.Errorf("%w: %q has no value", ErrInvalidQuery, token)
+			}
+			filters := query.Filters
+			if exclude {
+				filters = query.Excludes
+			}
+			filters[field] = append(filters[field], value)
+			clauses++
+			continue
+		}
+
+		kind := TermExact
+		if rest, ok := strings.CutSuffix(token, "*"); ok {
+			token, kind = rest, TermPrefix
+		} else if rest, ok := strings.CutSuffix(token, "~"); ok {
+			token, kind = rest, TermFuzzy
+		}
+		// A token such as ada@exa* holds several terms; only the last one
+		// is a prefix
+		words := tokenize(token)
+		for i, word := range words {
+			term := Term{Text: word, Kind: kind}
+			if kind == TermPrefix && i < len(words)-1 {
+				term.Kind = TermExact
+			}
+			if exclude {
+				query.MustNot = append(query.MustNot, term)
+			} else {
+				query.Must = append(query.Must, term)
+			}
+			clauses++
+		}
+	}
+	if clauses > maxQueryClauses {
+		return Query{}, fmt.Errorf("%w: at most %d terms and filters, got %d", ErrInvalidQuery, maxQueryClauses, clauses)
+	}
+	return query, nil
+}
+
+// isFieldName reports whether name is the name of a keyword field: lower
+// case letters and underscores, so times like 10:30 stay text
+func isFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		q            string
+		wantMust     []Term
+		wantMustNot  []Term
+		wantFilters  map[string][]string
+		wantExcludes map[string][]string
+	}{
+		{name: "empty", q: "  "},
+		{name: "terms", q: "Ada LOVELACE", wantMust: []Term{{"ada", TermExact}, {"lovelace", TermExact}}},
+		{name: "prefix", q: "love*", wantMust: []Term{{"love", TermPrefix}}},
+		{name: "prefix of the last term", q: "ada@exa*", wantMust: []Term{{"ada", TermExact}, {"exa", TermPrefix}}},
+		{name: "fuzzy", q: "lovelase~", wantMust: []Term{{"lovelase", TermFuzzy}}},
+		{name: "exclusion", q: "ada -byron", wantMust: []Term{{"ada", TermExact}}, wantMustNot: []Term{{"byron", TermExact}}},
+		{
+			name:        "filters",
+			q:           "status:Active status:pending type:user",
+			wantFilters: map[string][]string{"status": {"active", "pending"}, "type": {"user"}},
+		},
+		{name: "excluded filter", q: "-role:admin", wantExcludes: map[string][]string{"role": {"admin"}}},
+		{name: "time stays text", q: "10:30", wantMust: []Term{{"10", TermExact}, {"30", TermExact}}},
+		{name: "punctuation only", q: "* - ~"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQuery(tt.q)
+			if err != nil {
+				t.Fatalf("parseQuery() error = %v", err)
+			}
+			if tt.wantFilters == nil {
+				tt.wantFilters = map[string][]string{}
+			}
+			if tt.wantExcludes == nil {
+				tt.wantExcludes = map[string][]string{}
+			}
+			if !reflect.DeepEqual(got.Must, tt.wantMust) || !reflect.DeepEqual(got.MustNot, tt.wantMustNot) {
+				t.Errorf("got must %v must not %v want %v and %v", got.Must, got.MustNot, tt.wantMust, tt.wantMustNot)
+			}
+			if !reflect.DeepEqual(got.Filters, tt.wantFilters) || !reflect.DeepEqual(got.Excludes, tt.wantExcludes) {
+				t.Errorf("got filters %v excludes %v want %v and %v", got.Filters, got.Excludes, tt.wantFilters, tt.wantExcludes)
+			}
+			if got.Sort != SortRelevance {
+				t.Errorf("got sort %q want %q", got.Sort, SortRelevance)
+			}
+		})
+	}
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for _, q := range []string{"status:", "-status:", strings.Repeat("a ", maxQueryClauses+1)} {
+		if _, err := parseQuery(q); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("parseQuery(%q) got error %v want %v", q, err, ErrInvalidQuery)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/contract"
+)
+
+// TestPaymentEventsContract checks that the fields of payment events
+// declared in contracts/payments.json are all the shipping service needs,
+// whether the order is named by the subject or the payment
+func TestPaymentEventsContract(t *testing.T) {
+	c, err := contract.Load(filepath.Join("contracts", "payments.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, bySubject := range []bool{true, false} {
+		service, _, _ := newTestService(false, 0)
+		for _, example := range c.Examples() {
+			if !bySubject {
+				example.Subject = ""
+			}
+			if err := service.Handle(ctx, example); err != nil {
+				t.Fatalf("Handle(%s) error = %v", example.Type, err)
+			}
+			if example.Type != EventTypePaymentCaptured {
+				continue
+			}
+			if shipment, err := service.Get("example"); err != nil || shipment.Status != ShipmentStatusCreated {
+				t.Errorf("after %s got %+v, %v want the example order shipped", example.Type, shipment, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/captain-corgi/learning-event-driven/modules/shipping
+
+go 1.24.0
+
+require github.com/captain-corgi/learning-event-driven/pkg v0.0.0-00010101000000-000000000000
+
+replace github.com/captain-corgi/learning-event-driven/pkg => ../../pkg
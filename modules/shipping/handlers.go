@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+)
+
+// shipmentStatuses are the statuses accepted by the status filter
+var shipmentStatuses = []ShipmentStatus{
+	ShipmentStatusAwaiting,
+	ShipmentStatusCreated,
+	ShipmentStatusDelivered,
+	ShipmentStatusCancelled,
+}
+
+// ShipmentHandler serves the HTTP API of the shipping service
+type ShipmentHandler struct {
+	shipments *ShippingService
+	mux       *http.ServeMux
+}
+
+// NewShipmentHandler creates the handler of the shipments routes
+func NewShipmentHandler(shipments *ShippingService) *ShipmentHandler {
+	h := &ShipmentHandler{shipments: shipments, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /shipments", h.handleListShipments)
+	h.mux.HandleFunc("GET /shipments/{order_id}", h.handleGetShipment)
+	h.mux.HandleFunc("POST /shipments/{order_id}/deliver", h.handleDeliverShipment)
+	return h
+}
+
+// ServeHTTP dispatches the request to its route
+func (h *ShipmentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleListShipments lists the shipments, filtered by the status query parameter
+func (h *ShipmentHandler) handleListShipments(w http.ResponseWriter, r *http.Request) {
+	status := ShipmentStatus(r.URL.Query().Get("status"))
+	if status != "" && !validStatus(status) {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid status: "+string(status))
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, h.shipments.List(status))
+}
+
+// handleGetShipment returns the shipment of an order
+func (h *ShipmentHandler) handleGetShipment(w http.ResponseWriter, r *http.Request) {
+	shipment, err := h.shipments.Get(r.PathValue("order_id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, shipment)
+}
+
+// handleDeliverShipment delivers the shipment of an order without waiting
+// for the delivery delay
+func (h *ShipmentHandler) handleDeliverShipment(w http.ResponseWriter, r *http.Request) {
+	shipment, err := h.shipments.Deliver(r.Context(), r.PathValue("order_id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, shipment)
+}
+
+// writeError writes the problem matching a service error
+func (h *ShipmentHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrShipmentNotFound):
+		problem.Write(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidChange):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// validStatus reports whether status is a shipment status
+func validStatus(status ShipmentStatus) bool {
+	for _, s := range shipmentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShipmentHandler(t *testing.T) {
+	service, _, _ := newTestService(false, 0)
+	if err := service.Handle(context.Background(), orderEvent(t, "e1", EventTypePaymentCaptured, "o1")); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewShipmentHandler(service)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"list", http.MethodGet, "/shipments", http.StatusOK},
+		{"list by status", http.MethodGet, "/shipments?status=created", http.StatusOK},
+		{"invalid status", http.MethodGet, "/shipments?status=lost", http.StatusBadRequest},
+		{"get", http.MethodGet, "/shipments/o1", http.StatusOK},
+		{"missing", http.MethodGet, "/shipments/missing", http.StatusNotFound},
+		{"deliver", http.MethodPost, "/shipments/o1/deliver", http.StatusOK},
+		{"deliver twice", http.MethodPost, "/shipments/o1/deliver", http.StatusConflict},
+		{"deliver missing", http.MethodPost, "/shipments/missing/deliver", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
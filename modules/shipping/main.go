@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/config"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/httpx"
+	"github.com/captain-corgi/learning-event-driven/pkg/logging"
+	"github.com/captain-corgi/learning-event-driven/pkg/problem"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+const (
+	defaultPort             = "8087"
+	defaultHost             = "localhost"
+	defaultPaymentEventsURL = "http://localhost:8083/events"
+	defaultDeliveryDelay    = 30 * time.Second
+)
+
+// deliveryCheckInterval is how often shipments are checked for delivery
+const deliveryCheckInterval = time.Second
+
+func main() {
+	// Log structured records, configured by LOG_FORMAT and LOG_LEVEL
+	logger, _, err := logging.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Get configuration from environment variables
+	port := config.Getenv("PORT", defaultPort)
+	host := config.Getenv("HOST", defaultHost)
+
+	paymentEventsURL := config.Getenv("PAYMENT_EVENTS_URL", defaultPaymentEventsURL)
+	if u, err := url.Parse(paymentEventsURL); err != nil || u.Host == "" {
+		fatal("Invalid PAYMENT_EVENTS_URL", "url", paymentEventsURL)
+	}
+	// Without an inventory service, paid orders ship without waiting for stock
+	inventoryEventsURL := os.Getenv("INVENTORY_EVENTS_URL")
+	if u, err := url.Parse(inventoryEventsURL); inventoryEventsURL != "" && (err != nil || u.Host == "") {
+		fatal("Invalid INVENTORY_EVENTS_URL", "url", inventoryEventsURL)
+	}
+	deliveryDelay, err := loadDeliveryDelay()
+	if err != nil {
+		fatal("Invalid delivery configuration", "error", err)
+	}
+
+	// Shipments publish their events on a local bus, streamed at GET /events
+	bus := events.NewBus()
+	eventStream := events.NewStream(bus)
+	shipping := NewShippingService(inventoryEventsURL != "", deliveryDelay, uuid.GeneratorFunc(uuid.NewGoogle), bus)
+
+	// Follow the payment and stock events of the order choreography
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var header http.Header
+	if token := os.Getenv("EVENTS_TOKEN"); token != "" {
+		header = http.Header{"Authorization": {"Bearer " + token}}
+	}
+	payments := &events.Subscriber{URL: paymentEventsURL, Types: []string{EventTypePaymentCaptured, EventTypePaymentRefunded}, Header: header}
+	go payments.Run(ctx, shipping.Handle)
+	if inventoryEventsURL != "" {
+		inventory := &events.Subscriber{URL: inventoryEventsURL, Types: []string{EventTypeStockReserved}, Header: header}
+		go inventory.Run(ctx, shipping.Handle)
+	}
+	go shipping.Run(ctx, deliveryCheckInterval)
+
+	// Setup routes
+	mux := http.NewServeMux()
+	api := NewShipmentHandler(shipping)
+	mux.Handle("/shipments", api)
+	mux.Handle("/shipments/", api)
+	mux.Handle("/events", eventStream)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/", rootHandler)
+
+	// Create server
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Handler:      logging.Middleware(mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	server.RegisterOnShutdown(eventStream.Close)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Starting shipping service", "url", fmt.Sprintf("http://%s:%s", host, port),
+			"payment_events", paymentEventsURL, "inventory_events", inventoryEventsURL, "delivery_delay", deliveryDelay)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Shipping service failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	<-ctx.Done()
+
+	slog.Info("Shutting down shipping service")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal("Shipping service forced to shutdown", "error", err)
+	}
+	slog.Info("Shipping service exited")
+}
+
+// loadDeliveryDelay reads DELIVERY_DELAY, the time shipments take to reach
+// the customer, 0 to deliver them only through the API
+func loadDeliveryDelay() (time.Duration, error) {
+	value := os.Getenv("DELIVERY_DELAY")
+	if value == "" {
+		return defaultDeliveryDelay, nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil || delay < 0 {
+		return 0, fmt.Errorf("DELIVERY_DELAY must be a duration that is not negative, got %q", value)
+	}
+	return delay, nil
+}
+
+// rootHandler describes the service and its endpoints
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		problem.Write(w, r, http.StatusNotFound, "")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "shipping",
+		"endpoints": map[string]string{
+			"shipments": "/shipments",
+			"events":    "/events",
+			"health":    "/health",
+		},
+	})
+}
+
+// healthHandler reports the service healthy
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// fatal logs msg with args as an error and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadDeliveryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", "", defaultDeliveryDelay, false},
+		{"custom", "2m", 2 * time.Minute, false},
+		{"disabled", "0", 0, false},
+		{"negative", "-1s", 0, true},
+		{"invalid", "soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DELIVERY_DELAY", tt.value)
+			delay, err := loadDeliveryDelay()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadDeliveryDelay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if delay != tt.want {
+				t.Errorf("got %v want %v", delay, tt.want)
+			}
+		})
+	}
+}
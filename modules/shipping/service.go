@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// eventSource identifies this service as the producer of its events
+const eventSource = "shipping-service"
+
+// Types of the events consumed by the shipping service
+const (
+	EventTypePaymentCaptured = "payment.captured"
+	EventTypePaymentRefunded = "payment.refunded"
+	EventTypeStockReserved   = "stock.reserved"
+)
+
+// orderEventData is the part of the payload of payment and stock events the
+// shipping service reads when their subject is not the order ID
+type orderEventData struct {
+	OrderID string `json:"order_id"`
+	Payment struct {
+		OrderID string `json:"order_id"`
+	} `json:"payment"`
+}
+
+// ShippingService ships orders once they are paid and in stock, and
+// delivers them after a while. It is a participant of the order
+// choreography: nobody tells it what to do, it reacts to the events of the
+// other services and publishes its own.
+type ShippingService struct {
+	mutex         sync.Mutex
+	shipments     map[string]*Shipment
+	requireStock  bool
+	deliveryDelay time.Duration
+	ids           uuid.IDGenerator
+	publisher     events.Publisher
+	now           func() time.Time
+}
+
+// NewShippingService creates a service shipping paid orders, once their
+// stock is reserved when requireStock is set, and delivering them
+// deliveryDelay after they shipped, never when it is 0
+func NewShippingService(requireStock bool, deliveryDelay time.Duration, ids uuid.IDGenerator, publisher events.Publisher) *ShippingService {
+	return &ShippingService{
+		shipments:     make(map[string]*Shipment),
+		requireStock:  requireStock,
+		deliveryDelay: deliveryDelay,
+		ids:           ids,
+		publisher:     publisher,
+		now:           func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Handle records the payment and stock events of an order, whose ID is the
+// subject of the event, and ships the order once it is ready. A refunded
+// payment cancels a shipment that did not leave yet.
+func (s *ShippingService) Handle(ctx context.Context, event events.Event) error {
+	orderID := event.Subject
+	if orderID == "" {
+		var data orderEventData
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		orderID = data.OrderID
+		if orderID == "" {
+			orderID = data.Payment.OrderID
+		}
+	}
+	if orderID == "" {
+		return fmt.Errorf("%s event %s names no order", event.Type, event.ID)
+	}
+
+	s.mutex.Lock()
+	shipment, ok := s.shipments[orderID]
+	if !ok {
+		if event.Type == EventTypePaymentRefunded {
+			s.mutex.Unlock()
+			return nil
+		}
+		shipment = NewShipment(orderID, s.now())
+		s.shipments[orderID] = shipment
+	}
+
+	switch event.Type {
+	case EventTypePaymentCaptured:
+		shipment.PaymentCaptured = true
+	case EventTypeStockReserved:
+		shipment.StockReserved = true
+	case EventTypePaymentRefunded:
+		err := shipment.Cancel("payment refunded", s.now())
+		status := shipment.Status
+		changes := takeChanges(shipment)
+		s.mutex.Unlock()
+		if err != nil {
+			slog.WarnContext(ctx, "Payment refunded after the order shipped", "order_id", orderID, "status", status)
+		}
+		s.publish(ctx, orderID, changes)
+		return nil
+	default:
+		s.mutex.Unlock()
+		slog.DebugContext(ctx, "Ignoring event", "event_type", event.Type, "event_id", event.ID)
+		return nil
+	}
+	if !shipment.Ready(s.requireStock) {
+		s.mutex.Unlock()
+		return nil
+	}
+	if err := shipment.Ship(s.ids.NewID(), s.now()); err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+	changes := takeChanges(shipment)
+	s.mutex.Unlock()
+
+	s.publish(ctx, orderID, changes)
+	return nil
+}
+
+// Deliver records that the shipment of the order reached the customer
+func (s *ShippingService) Deliver(ctx context.Context, orderID string) (*Shipment, error) {
+	s.mutex.Lock()
+	shipment, ok := s.shipments[orderID]
+	if !ok {
+		s.mutex.Unlock()
+		return nil, ErrShipmentNotFound
+	}
+	if err := shipment.Deliver(s.now()); err != nil {
+		s.mutex.Unlock()
+		return nil, err
+	}
+	changes := takeChanges(shipment)
+	delivered := *shipment
+	s.mutex.Unlock()
+
+	s.publish(ctx, orderID, changes)
+	return &delivered, nil
+}
+
+// DeliverDue delivers the shipments that left at least the delivery delay
+// ago and returns how many were delivered
+func (s *ShippingService) DeliverDue(ctx context.Context) int {
+	if s.deliveryDelay <= 0 {
+		return 0
+	}
+	s.mutex.Lock()
+	var due []string
+	for orderID, shipment := range s.shipments {
+		if shipment.Status == ShipmentStatusCreated && !shipment.ShippedAt.Add(s.deliveryDelay).After(s.now()) {
+			due = append(due, orderID)
+		}
+	}
+	s.mutex.Unlock()
+	sort.Strings(due)
+
+	delivered := 0
+	for _, orderID := range due {
+		if _, err := s.Deliver(ctx, orderID); err != nil {
+			// Delivered through the API in the meantime
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+// Run delivers the due shipments every interval until ctx is done
+func (s *ShippingService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.DeliverDue(ctx)
+		}
+	}
+}
+
+// takeChanges returns the events recorded by the shipment and clears them,
+// so each is published once
+func takeChanges(shipment *Shipment) []domain.DomainEvent {
+	changes := shipment.Changes()
+	shipment.ClearChanges()
+	return changes
+}
+
+// publish publishes the events recorded by the shipment of the order, logging
+// failures: the change is made whether or not its event is delivered
+func (s *ShippingService) publish(ctx context.Context, orderID string, changes []domain.DomainEvent) {
+	for _, change := range changes {
+		event, err := change.Envelope(s.ids.NewID(), eventSource, orderID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to create event", "event_type", change.Type, "order_id", orderID, "error", err)
+			continue
+		}
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "Failed to publish event", "event_type", change.Type, "order_id", orderID, "error", err)
+		}
+		slog.InfoContext(ctx, "Shipment changed", "event_type", change.Type, "order_id", orderID)
+	}
+}
+
+// Get returns the shipment of the order
+func (s *ShippingService) Get(orderID string) (*Shipment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	shipment, ok := s.shipments[orderID]
+	if !ok {
+		return nil, ErrShipmentNotFound
+	}
+	found := *shipment
+	return &found, nil
+}
+
+// List returns the shipments, oldest first, optionally only those with status
+func (s *ShippingService) List(status ShipmentStatus) []Shipment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	shipments := make([]Shipment, 0, len(s.shipments))
+	for _, shipment := range s.shipments {
+		if status == "" || shipment.Status == status {
+			shipments = append(shipments, *shipment)
+		}
+	}
+	sort.Slice(shipments, func(i, j int) bool {
+		if !shipments[i].CreatedAt.Equal(shipments[j].CreatedAt) {
+			return shipments[i].CreatedAt.Before(shipments[j].CreatedAt)
+		}
+		return shipments[i].OrderID < shipments[j].OrderID
+	})
+	return shipments
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+	"github.com/captain-corgi/learning-event-driven/pkg/uuid"
+)
+
+// recordingPublisher records the published events
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+// types returns the types of the published events
+func (p *recordingPublisher) types() []string {
+	types := make([]string, len(p.events))
+	for i, event := range p.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func newTestService(requireStock bool, deliveryDelay time.Duration) (*ShippingService, *recordingPublisher, *time.Time) {
+	publisher := &recordingPublisher{}
+	service := NewShippingService(requireStock, deliveryDelay, uuid.NewSequenceGenerator("id-"), publisher)
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, publisher, &now
+}
+
+// orderEvent creates an event of eventType about the order
+func orderEvent(t *testing.T, id, eventType, orderID string) events.Event {
+	t.Helper()
+	event, err := events.New(id, eventType, "test", orderID, map[string]string{"order_id": orderID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestShippingServiceHandle(t *testing.T) {
+	tests := []struct {
+		name         string
+		requireStock bool
+		events       []string
+		wantStatus   ShipmentStatus
+		wantTypes    []string
+	}{
+		{"payment without inventory", false, []string{EventTypePaymentCaptured}, ShipmentStatusCreated, []string{EventTypeShipmentCreated}},
+		{"payment awaiting stock", true, []string{EventTypePaymentCaptured}, ShipmentStatusAwaiting, []string{}},
+		{"stock awaiting payment", true, []string{EventTypeStockReserved}, ShipmentStatusAwaiting, []string{}},
+		{"stock then payment", true, []string{EventTypeStockReserved, EventTypePaymentCaptured}, ShipmentStatusCreated, []string{EventTypeShipmentCreated}},
+		{"duplicate events ship once", false, []string{EventTypePaymentCaptured, EventTypePaymentCaptured}, ShipmentStatusCreated, []string{EventTypeShipmentCreated}},
+		{"refund before stock", true, []string{EventTypePaymentCaptured, EventTypePaymentRefunded, EventTypeStockReserved}, ShipmentStatusCancelled, []string{EventTypeShipmentCancelled}},
+		{"refund after shipping", false, []string{EventTypePaymentCaptured, EventTypePaymentRefunded}, ShipmentStatusCreated, []string{EventTypeShipmentCreated}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, publisher, _ := newTestService(tt.requireStock, 0)
+			for i, eventType := range tt.events {
+				if err := service.Handle(context.Background(), orderEvent(t, string(rune('a'+i)), eventType, "o1")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			shipment, err := service.Get("o1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shipment.Status != tt.wantStatus {
+				t.Errorf("got status %s want %s", shipment.Status, tt.wantStatus)
+			}
+			if got := publisher.types(); len(got) != len(tt.wantTypes) || (len(got) > 0 && got[0] != tt.wantTypes[0]) {
+				t.Errorf("got events %v want %v", got, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestShippingServiceHandleOrderID(t *testing.T) {
+	service, publisher, _ := newTestService(false, 0)
+
+	// Without a subject, the order ID is read from the payment of the data
+	event, err := events.New("e1", EventTypePaymentCaptured, "payment-service", "", map[string]interface{}{
+		"payment": map[string]string{"order_id": "o1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Handle(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Subject != "o1" {
+		t.Fatalf("got %v want shipment.created about o1", publisher.events)
+	}
+	var data ShipmentEventData
+	if err := publisher.events[0].Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Shipment.TrackingNumber == "" || data.Shipment.Status != ShipmentStatusCreated {
+		t.Errorf("got %+v want a created shipment with a tracking number", data.Shipment)
+	}
+
+	event, _ = events.New("e2", EventTypePaymentCaptured, "payment-service", "", map[string]string{})
+	if err := service.Handle(context.Background(), event); err == nil {
+		t.Error("got no error for an event naming no order")
+	}
+	// A refund of an unknown order creates no shipment
+	if err := service.Handle(context.Background(), orderEvent(t, "e3", EventTypePaymentRefunded, "o2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Get("o2"); !errors.Is(err, ErrShipmentNotFound) {
+		t.Errorf("got %v want %v", err, ErrShipmentNotFound)
+	}
+}
+
+func TestShippingServiceDeliver(t *testing.T) {
+	service, publisher, now := newTestService(false, time.Minute)
+	ctx := context.Background()
+	for _, orderID := range []string{"o1", "o2"} {
+		if err := service.Handle(ctx, orderEvent(t, "paid-"+orderID, EventTypePaymentCaptured, orderID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := service.DeliverDue(ctx); n != 0 {
+		t.Errorf("got %d deliveries want 0 before the delay", n)
+	}
+	if _, err := service.Deliver(ctx, "o1"); err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(time.Minute)
+	if n := service.DeliverDue(ctx); n != 1 {
+		t.Errorf("got %d deliveries want 1", n)
+	}
+	if got := len(service.List(ShipmentStatusDelivered)); got != 2 {
+		t.Errorf("got %d delivered shipments want 2", got)
+	}
+	if got := publisher.types(); len(got) != 4 || got[2] != EventTypeShipmentDelivered || got[3] != EventTypeShipmentDelivered {
+		t.Errorf("got events %v want 2 shipment.created then 2 shipment.delivered", got)
+	}
+
+	if _, err := service.Deliver(ctx, "o1"); !errors.Is(err, ErrInvalidChange) {
+		t.Errorf("got %v want %v", err, ErrInvalidChange)
+	}
+	if _, err := service.Deliver(ctx, "missing"); !errors.Is(err, ErrShipmentNotFound) {
+		t.Errorf("got %v want %v", err, ErrShipmentNotFound)
+	}
+}
+
+func TestShippingServiceDeliverDueDisabled(t *testing.T) {
+	service, _, now := newTestService(false, 0)
+	ctx := context.Background()
+	if err := service.Handle(ctx, orderEvent(t, "e1", EventTypePaymentCaptured, "o1")); err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(24 * time.Hour)
+	if n := service.DeliverDue(ctx); n != 0 {
+		t.Errorf("got %d deliveries want 0 without a delivery delay", n)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/domain"
+)
+
+// ShipmentStatus is the state of a shipment in its lifecycle
+type ShipmentStatus string
+
+const (
+	// ShipmentStatusAwaiting shipments wait for the payment or the stock of their order
+	ShipmentStatusAwaiting ShipmentStatus = "awaiting"
+	// ShipmentStatusCreated shipments left the warehouse
+	ShipmentStatusCreated ShipmentStatus = "created"
+	// ShipmentStatusDelivered shipments reached the customer
+	ShipmentStatusDelivered ShipmentStatus = "delivered"
+	// ShipmentStatusCancelled shipments will never leave, their payment was refunded
+	ShipmentStatusCancelled ShipmentStatus = "cancelled"
+)
+
+// Types of the events recorded by the shipment aggregate
+const (
+	EventTypeShipmentCreated   = "shipment.created"
+	EventTypeShipmentDelivered = "shipment.delivered"
+	EventTypeShipmentCancelled = "shipment.cancelled"
+)
+
+// Errors of the shipment aggregate
+var (
+	ErrShipmentNotFound = errors.New("shipment not found")
+	ErrInvalidChange    = errors.New("invalid shipment status change")
+)
+
+// Shipment is the aggregate root of the shipping service: the parcel of one
+// order, created once its payment is captured and its stock reserved. It is
+// identified by its order. Each status change records an event carrying a
+// snapshot of the shipment.
+type Shipment struct {
+	domain.AggregateRoot
+	OrderID         string         `json:"order_id"`
+	TrackingNumber  string         `json:"tracking_number,omitempty"`
+	Status          ShipmentStatus `json:"status"`
+	PaymentCaptured bool           `json:"payment_captured"`
+	StockReserved   bool           `json:"stock_reserved"`
+	Reason          string         `json:"reason,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	ShippedAt       *time.Time     `json:"shipped_at,omitempty"`
+	DeliveredAt     *time.Time     `json:"delivered_at,omitempty"`
+}
+
+// ShipmentEventData is the payload of shipment events: the shipment after the change
+type ShipmentEventData struct {
+	Shipment Shipment `json:"shipment"`
+}
+
+// NewShipment creates the shipment of an order awaiting its payment and
+// stock. Nothing is recorded until it ships or is cancelled.
+func NewShipment(orderID string, now time.Time) *Shipment {
+	return &Shipment{
+		AggregateRoot: domain.AggregateRoot{ID: orderID},
+		OrderID:       orderID,
+		Status:        ShipmentStatusAwaiting,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Ready reports whether the shipment awaits nothing anymore. Stock is only
+// awaited when requireStock is set, i.e. when an inventory service reports it.
+func (s *Shipment) Ready(requireStock bool) bool {
+	return s.Status == ShipmentStatusAwaiting && s.PaymentCaptured && (s.StockReserved || !requireStock)
+}
+
+// Ship creates the parcel with its tracking number, recording shipment.created
+func (s *Shipment) Ship(trackingNumber string, now time.Time) error {
+	if s.Status != ShipmentStatusAwaiting {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidChange, s.Status, ShipmentStatusCreated)
+	}
+	s.Status = ShipmentStatusCreated
+	s.TrackingNumber = trackingNumber
+	s.ShippedAt = &now
+	s.UpdatedAt = now
+	s.record(EventTypeShipmentCreated)
+	return nil
+}
+
+// Deliver records that the parcel reached the customer, recording
+// shipment.delivered
+func (s *Shipment) Deliver(now time.Time) error {
+	if s.Status != ShipmentStatusCreated {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidChange, s.Status, ShipmentStatusDelivered)
+	}
+	s.Status = ShipmentStatusDelivered
+	s.DeliveredAt = &now
+	s.UpdatedAt = now
+	s.record(EventTypeShipmentDelivered)
+	return nil
+}
+
+// Cancel gives up a shipment that did not leave yet, recording
+// shipment.cancelled
+func (s *Shipment) Cancel(reason string, now time.Time) error {
+	if s.Status != ShipmentStatusAwaiting {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidChange, s.Status, ShipmentStatusCancelled)
+	}
+	s.Status = ShipmentStatusCancelled
+	s.Reason = reason
+	s.UpdatedAt = now
+	s.record(EventTypeShipmentCancelled)
+	return nil
+}
+
+// record records an event of eventType carrying a snapshot of the shipment
+// at the version the event brings it to
+func (s *Shipment) record(eventType string) {
+	snapshot := *s
+	snapshot.ClearChanges()
+	snapshot.Version++
+	s.Record(eventType, ShipmentEventData{Shipment: snapshot})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShipmentLifecycle(t *testing.T) {
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	shipment := NewShipment("o1", now)
+	if shipment.Ready(false) {
+		t.Fatal("got ready without a captured payment")
+	}
+	shipment.PaymentCaptured = true
+	if !shipment.Ready(false) {
+		t.Error("got not ready with the payment captured and no stock required")
+	}
+	if shipment.Ready(true) {
+		t.Error("got ready without the required stock")
+	}
+	shipment.StockReserved = true
+	if !shipment.Ready(true) {
+		t.Error("got not ready with payment and stock")
+	}
+
+	if err := shipment.Deliver(now); !errors.Is(err, ErrInvalidChange) {
+		t.Errorf("got %v want %v delivering before shipping", err, ErrInvalidChange)
+	}
+	if err := shipment.Ship("TRK-1", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if shipment.Status != ShipmentStatusCreated || shipment.TrackingNumber != "TRK-1" || !shipment.ShippedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("got %+v want a created shipment tracked as TRK-1", shipment)
+	}
+	if shipment.Ready(true) {
+		t.Error("got a shipped shipment ready again")
+	}
+	if err := shipment.Cancel("too late", now); !errors.Is(err, ErrInvalidChange) {
+		t.Errorf("got %v want %v cancelling a shipped shipment", err, ErrInvalidChange)
+	}
+	if err := shipment.Deliver(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if shipment.Status != ShipmentStatusDelivered || shipment.Version != 2 || !shipment.UpdatedAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("got %+v want a delivered shipment at version 2", shipment)
+	}
+	changes := shipment.Changes()
+	if len(changes) != 2 || changes[0].Type != EventTypeShipmentCreated || changes[1].Type != EventTypeShipmentDelivered {
+		t.Fatalf("got changes %+v want %s then %s", changes, EventTypeShipmentCreated, EventTypeShipmentDelivered)
+	}
+	if data := changes[0].Data.(ShipmentEventData); data.Shipment.Version != 1 || data.Shipment.Status != ShipmentStatusCreated {
+		t.Errorf("got %+v want a snapshot of the created shipment at version 1", data.Shipment)
+	}
+}
+
+func TestShipmentCancel(t *testing.T) {
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	shipment := NewShipment("o1", now)
+	if err := shipment.Cancel("payment refunded", now); err != nil {
+		t.Fatal(err)
+	}
+	if shipment.Status != ShipmentStatusCancelled || shipment.Reason != "payment refunded" || shipment.Version != 1 {
+		t.Errorf("got %+v want a cancelled shipment at version 1", shipment)
+	}
+	if changes := shipment.Changes(); len(changes) != 1 || changes[0].Type != EventTypeShipmentCancelled {
+		t.Errorf("got changes %+v want %s", changes, EventTypeShipmentCancelled)
+	}
+	if err := shipment.Ship("TRK-1", now); !errors.Is(err, ErrInvalidChange) {
+		t.Errorf("got %v want %v shipping a cancelled shipment", err, ErrInvalidChange)
+	}
+}
@@ -0,0 +1,173 @@
+// Package chaos injects faults into HTTP handlers and event handlers for
+// resilience demos: added latency, errors, and partial failures where the
+// work is done but its caller sees a failure. Faults are drawn at random
+// with configured probabilities or, when allowed, requested per call by the
+// X-Chaos header.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header requests faults for one HTTP request, when Config.HeaderTriggers
+// is set: a comma-separated list of latency=<duration>, error or
+// error=<status>, and partial, such as "latency=2s,error=503".
+const Header = "X-Chaos"
+
+// InjectedHeader lists the faults injected into a response.
+const InjectedHeader = "X-Chaos-Injected"
+
+// ErrInjected is the error of the injected failures.
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config sets the faults to inject. Rates are probabilities between 0 and
+// 1, drawn independently for every call.
+type Config struct {
+	// Latency is added before the call, at LatencyRate.
+	Latency     time.Duration
+	LatencyRate float64
+	// ErrorRate fails calls without running them. HTTP requests are answered
+	// with ErrorStatus, 503 Service Unavailable by default.
+	ErrorRate   float64
+	ErrorStatus int
+	// PartialRate runs calls, then fails them: HTTP responses are cut in the
+	// middle of their body, and event handlers return ErrInjected after
+	// handling the event, so their caller retries work already done.
+	PartialRate float64
+	// HeaderTriggers lets HTTP requests ask for faults with the X-Chaos
+	// header, on top of the random ones.
+	HeaderTriggers bool
+}
+
+// Validate checks that rates are probabilities and the status is an error.
+func (c Config) Validate() error {
+	for name, rate := range map[string]float64{"latency rate": c.LatencyRate, "error rate": c.ErrorRate, "partial rate": c.PartialRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("chaos: %s must be between 0 and 1, got %v", name, rate)
+		}
+	}
+	if c.Latency < 0 {
+		return fmt.Errorf("chaos: latency must not be negative, got %v", c.Latency)
+	}
+	if c.ErrorStatus != 0 && (c.ErrorStatus < 400 || c.ErrorStatus > 599) {
+		return fmt.Errorf("chaos: error status must be between 400 and 599, got %d", c.ErrorStatus)
+	}
+	return nil
+}
+
+// Faults are the faults injected into one call.
+type Faults struct {
+	Latency time.Duration
+	// Status is the status of an HTTP error, or zero for no error.
+	Status  int
+	Partial bool
+}
+
+// None reports whether no fault is injected.
+func (f Faults) None() bool {
+	return f.Latency == 0 && f.Status == 0 && !f.Partial
+}
+
+// String lists the faults, such as "latency=2s,error=503".
+func (f Faults) String() string {
+	var faults []string
+	if f.Latency > 0 {
+		faults = append(faults, "latency="+f.Latency.String())
+	}
+	if f.Status != 0 {
+		faults = append(faults, "error="+strconv.Itoa(f.Status))
+	}
+	if f.Partial {
+		faults = append(faults, "partial")
+	}
+	return strings.Join(faults, ",")
+}
+
+// ParseFaults parses the value of the X-Chaos header.
+func ParseFaults(value string) (Faults, error) {
+	var faults Faults
+	for _, part := range strings.Split(value, ",") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(part), "=")
+		switch {
+		case name == "latency" && hasArg:
+			d, err := time.ParseDuration(arg)
+			if err != nil || d < 0 {
+				return Faults{}, fmt.Errorf("chaos: invalid latency %q", arg)
+			}
+			faults.Latency = d
+		case name == "error" && !hasArg:
+			faults.Status = http.StatusServiceUnavailable
+		case name == "error":
+			status, err := strconv.Atoi(arg)
+			if err != nil || status < 400 || status > 599 {
+				return Faults{}, fmt.Errorf("chaos: invalid error status %q", arg)
+			}
+			faults.Status = status
+		case name == "partial" && !hasArg:
+			faults.Partial = true
+		case name == "":
+		default:
+			return Faults{}, fmt.Errorf("chaos: unknown fault %q, want latency=<duration>, error[=<status>] or partial", part)
+		}
+	}
+	return faults, nil
+}
+
+// Injector draws the faults to inject into calls.
+type Injector struct {
+	config Config
+	// random returns a number in [0, 1), replaced by tests
+	random func() float64
+}
+
+// New creates an injector of the faults of config.
+func New(config Config) (*Injector, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.ErrorStatus == 0 {
+		config.ErrorStatus = http.StatusServiceUnavailable
+	}
+	return &Injector{config: config, random: rand.Float64}, nil
+}
+
+// Draw draws the random faults of a call.
+func (i *Injector) Draw() Faults {
+	var faults Faults
+	if i.config.Latency > 0 && i.hit(i.config.LatencyRate) {
+		faults.Latency = i.config.Latency
+	}
+	if i.hit(i.config.ErrorRate) {
+		faults.Status = i.config.ErrorStatus
+	} else if i.hit(i.config.PartialRate) {
+		faults.Partial = true
+	}
+	return faults
+}
+
+// hit reports whether an event of probability rate happens.
+func (i *Injector) hit(rate float64) bool {
+	return rate > 0 && i.random() < rate
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package chaos
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fixed returns an injector of config drawing the numbers of values in turn
+func fixed(t *testing.T, config Config, values ...float64) *Injector {
+	t.Helper()
+	injector, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	injector.random = func() float64 {
+		value := values[0]
+		values = append(values[1:], value)
+		return value
+	}
+	return injector
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"zero", Config{}, false},
+		{"rates", Config{Latency: time.Second, LatencyRate: 1, ErrorRate: 0.5, PartialRate: 0.1, ErrorStatus: 500}, false},
+		{"rate above 1", Config{ErrorRate: 1.5}, true},
+		{"negative rate", Config{PartialRate: -0.1}, true},
+		{"negative latency", Config{Latency: -time.Second}, true},
+		{"success status", Config{ErrorStatus: 200}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseFaults(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Faults
+		wantErr bool
+	}{
+		{"latency=250ms", Faults{Latency: 250 * time.Millisecond}, false},
+		{"error", Faults{Status: http.StatusServiceUnavailable}, false},
+		{"latency=1s, error=500", Faults{Latency: time.Second, Status: 500}, false},
+		{"partial", Faults{Partial: true}, false},
+		{"latency=soon", Faults{}, true},
+		{"error=200", Faults{}, true},
+		{"explode", Faults{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFaults(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFaults(%q) = %+v, %v want %+v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if got := (Faults{Latency: time.Second, Status: 503, Partial: true}).String(); got != "latency=1s,error=503,partial" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestInjector_Draw(t *testing.T) {
+	config := Config{Latency: time.Second, LatencyRate: 0.5, ErrorRate: 0.2, PartialRate: 0.1, ErrorStatus: 500}
+	tests := []struct {
+		name   string
+		random float64
+		want   Faults
+	}{
+		{"below every rate", 0.05, Faults{Latency: time.Second, Status: 500}},
+		{"latency only", 0.3, Faults{Latency: time.Second}},
+		{"nothing", 0.9, Faults{}},
+	}
+	for _, tt := range tests {
+		if got := fixed(t, config, tt.random).Draw(); got != tt.want {
+			t.Errorf("%s: Draw() = %+v want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// The partial failure is drawn when no error is
+	if got := fixed(t, Config{ErrorRate: 0.2, PartialRate: 0.5}, 0.3).Draw(); !got.Partial || got.Status != 0 {
+		t.Errorf("Draw() = %+v want a partial failure", got)
+	}
+	// Zero rates never inject anything
+	if got := fixed(t, Config{Latency: time.Second}, 0).Draw(); !got.None() {
+		t.Errorf("Draw() = %+v want no fault", got)
+	}
+}
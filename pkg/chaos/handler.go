@@ -0,0 +1,35 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// WrapHandler injects the random faults of injector into the event handler
+// next, such as an events.Handler: latency before handling the event, an
+// ErrInjected instead of handling it, or an ErrInjected after handling it.
+func WrapHandler[E any](injector *Injector, next func(context.Context, E) error) func(context.Context, E) error {
+	return func(ctx context.Context, event E) error {
+		faults := injector.Draw()
+		if faults.None() {
+			return next(ctx, event)
+		}
+
+		slog.DebugContext(ctx, "Injecting faults into an event handler", "faults", faults.String())
+		if err := sleep(ctx, faults.Latency); err != nil {
+			return err
+		}
+		switch {
+		case faults.Status != 0:
+			return ErrInjected
+		case faults.Partial:
+			if err := next(ctx, event); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w after the event was handled", ErrInjected)
+		default:
+			return next(ctx, event)
+		}
+	}
+}
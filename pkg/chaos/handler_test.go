@@ -0,0 +1,54 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+func TestWrapHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		wantHandled bool
+		wantErr     error
+	}{
+		{"no fault", Config{}, true, nil},
+		{"error", Config{ErrorRate: 1}, false, ErrInjected},
+		{"partial", Config{PartialRate: 1}, true, ErrInjected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector, err := New(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handled := false
+			var handler events.Handler = WrapHandler(injector, func(context.Context, events.Event) error {
+				handled = true
+				return nil
+			})
+			if err := handler(context.Background(), events.Event{Type: "user.created"}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v want %v", err, tt.wantErr)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("got handled %v want %v", handled, tt.wantHandled)
+			}
+		})
+	}
+
+	// Latency ends with the context
+	injector, err := New(Config{Latency: time.Hour, LatencyRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler := WrapHandler(injector, events.Handler(func(context.Context, events.Event) error { return nil }))
+	if err := handler(ctx, events.Event{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v want the context error", err)
+	}
+}
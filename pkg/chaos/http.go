@@ -0,0 +1,103 @@
+package chaos
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// Middleware injects faults into the requests served by next. Requests with
+// an invalid X-Chaos header are answered 400 Bad Request when header
+// triggers are enabled. Injected faults are listed in X-Chaos-Injected.
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		faults := i.Draw()
+		if value := r.Header.Get(Header); value != "" && i.config.HeaderTriggers {
+			requested, err := ParseFaults(value)
+			if err != nil {
+				writeProblem(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			faults = merge(faults, requested)
+		}
+		if faults.None() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		slog.DebugContext(r.Context(), "Injecting faults", "faults", faults.String(), "method", r.Method, "path", r.URL.Path)
+		w.Header().Set(InjectedHeader, faults.String())
+		if err := sleep(r.Context(), faults.Latency); err != nil {
+			return
+		}
+		switch {
+		case faults.Status != 0:
+			writeProblem(w, faults.Status, "fault injected by the chaos middleware")
+		case faults.Partial:
+			servePartial(w, r, next)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// merge adds the requested faults to the drawn ones. A requested error
+// replaces a drawn partial failure.
+func merge(drawn, requested Faults) Faults {
+	if requested.Latency > 0 {
+		drawn.Latency = requested.Latency
+	}
+	if requested.Status != 0 {
+		drawn.Status, drawn.Partial = requested.Status, false
+	}
+	if requested.Partial && drawn.Status == 0 {
+		drawn.Partial = true
+	}
+	return drawn
+}
+
+// servePartial serves the request, then sends the response headers and the
+// first half of its body before aborting the connection, as a server
+// crashing while answering would
+func servePartial(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	recorder := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+	next.ServeHTTP(recorder, r)
+
+	body := recorder.body.Bytes()
+	// The announced length makes clients notice the missing half
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(recorder.status)
+	w.Write(body[:len(body)/2])
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// bufferedResponse keeps a response in memory
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+// writeProblem answers with an RFC 7807 problem of status
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"type":"about:blank","title":%q,"status":%d,"detail":%q}`, http.StatusText(status), status, detail)
+}
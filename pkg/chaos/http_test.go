@@ -0,0 +1,92 @@
+package chaos
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hello answers a fixed body
+var hello = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("hello, resilient world"))
+})
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		header       string
+		wantStatus   int
+		wantInjected string
+		wantBody     string
+	}{
+		{"no fault", Config{}, "", http.StatusOK, "", "hello, resilient world"},
+		{"random error", Config{ErrorRate: 1}, "", http.StatusServiceUnavailable, "error=503", "fault injected"},
+		{"header ignored", Config{}, "error=500", http.StatusOK, "", "hello, resilient world"},
+		{"header error", Config{HeaderTriggers: true}, "error=500", http.StatusInternalServerError, "error=500", "fault injected"},
+		{"header latency", Config{HeaderTriggers: true}, "latency=1ms", http.StatusOK, "latency=1ms", "hello, resilient world"},
+		{"invalid header", Config{HeaderTriggers: true}, "explode", http.StatusBadRequest, "", "unknown fault"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector, err := New(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			injector.Middleware(hello).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(InjectedHeader); got != tt.wantInjected {
+				t.Errorf("got %s %q want %q", InjectedHeader, got, tt.wantInjected)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got body %q want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Latency(t *testing.T) {
+	injector := fixed(t, Config{Latency: 50 * time.Millisecond, LatencyRate: 0.5}, 0.1)
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	injector.Middleware(hello).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || rec.Code != http.StatusOK {
+		t.Errorf("got status %d after %v want 200 after the latency", rec.Code, elapsed)
+	}
+}
+
+func TestMiddleware_Partial(t *testing.T) {
+	injector, err := New(Config{PartialRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := false
+	server := httptest.NewServer(injector.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		hello(w, r)
+	})))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("got the complete body %q want the connection cut", body)
+	}
+	if !served || string(body) != "hello, resi" {
+		t.Errorf("got body %q after serving %v want the first half of a served response", body, served)
+	}
+}
@@ -0,0 +1,295 @@
+// Package config loads typed configuration structs from defaults, a YAML or
+// JSON file, environment variables and command-line flags.
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// durationType is the type of time.Duration fields, set from strings like "10s".
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a settable leaf field of a configuration struct.
+type field struct {
+	key    string // dotted key in the config file, e.g. server.port
+	env    string // environment variable, if any
+	def    string // default value, if any
+	secret bool   // redacted by Marshal
+	value  reflect.Value
+}
+
+// Option configures Load.
+type Option func(*options)
+
+type options struct {
+	flags   *flag.FlagSet
+	dotEnv  string
+	ctx     context.Context
+	secrets SecretsProvider
+}
+
+// Load fills the struct pointed to by dst. Every field starts at the value of
+// its default tag, is overwritten by the config file at path, unless path is
+// empty, and then by the environment variable named by its env tag:
+//
+//	type Config struct {
+//		Server struct {
+//			Port int `yaml:"port" env:"PORT" default:"8080"`
+//		} `yaml:"server"`
+//	}
+//
+// Files ending in .json are read as JSON, others as YAML; in both, fields are
+// addressed by their yaml tag and unknown keys are rejected. Supported field
+// types are strings, booleans, integers, floats, time.Duration and string
+// slices, written comma-separated in tags and environment variables. Empty
+// environment variables count as unset. With WithDotEnv, variables missing
+// from the environment are read from a .env file. With WithSecrets, secret
+// fields are then fetched from a secrets provider. With WithFlags,
+// command-line flags override all other layers. Load reports all problems at
+// once.
+func Load(path string, dst interface{}, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	fields, err := structFields(dst)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setString(f.value, f.def); err != nil {
+			errs = append(errs, fmt.Errorf("default of %s: %w", f.key, err))
+		}
+	}
+
+	if path != "" {
+		errs = append(errs, loadFile(path, fields)...)
+	}
+
+	dotEnv := map[string]string{}
+	if o.dotEnv != "" {
+		if dotEnv, err = ReadDotEnv(o.dotEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		name := f.env
+		value := os.Getenv(f.env)
+		if value == "" && dotEnv[f.env] != "" {
+			name = f.env + " in " + o.dotEnv
+			value = dotEnv[f.env]
+		}
+		if value != "" {
+			if err := setString(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+
+	if o.secrets != nil {
+		errs = append(errs, applySecrets(o.ctx, o.secrets, fields)...)
+	}
+
+	if o.flags != nil {
+		errs = append(errs, applyFlags(o.flags, fields)...)
+	}
+	return errors.Join(errs...)
+}
+
+// structFields returns the leaf fields of the struct pointed to by dst.
+func structFields(dst interface{}) ([]field, error) {
+	root := reflect.ValueOf(dst)
+	if root.Kind() != reflect.Pointer || root.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: need a pointer to a struct, got %T", dst)
+	}
+	return collectFields(root.Elem(), ""), nil
+}
+
+// collectFields returns the leaf fields of the struct v, whose keys start with prefix.
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(structField.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(structField.Name)
+		}
+		key := prefix + name
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(value, key+".")...)
+			continue
+		}
+		fields = append(fields, field{
+			key:    key,
+			env:    structField.Tag.Get("env"),
+			def:    structField.Tag.Get("default"),
+			secret: structField.Tag.Get("secret") == "true",
+			value:  value,
+		})
+	}
+	return fields
+}
+
+// loadFile sets the fields found in the config file at path.
+func loadFile(path string, fields []field) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config file: %w", err)}
+	}
+
+	var document map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// Keep numbers as written, so large integers are not rounded through float64
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	} else {
+		err = yaml.Unmarshal(data, &document)
+	}
+	if err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	values := make(map[string]interface{})
+	flatten(document, "", values)
+
+	var errs []error
+	for _, f := range fields {
+		value, ok := values[f.key]
+		if !ok {
+			continue
+		}
+		delete(values, f.key)
+		if err := setFileValue(f.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s in %s: %w", f.key, path, err))
+		}
+	}
+
+	unknown := make([]string, 0, len(values))
+	for key := range values {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("%s in %s: unknown key", key, path))
+	}
+	return errs
+}
+
+// flatten stores the leaves of the nested maps of document in values, under
+// their dotted keys.
+func flatten(document map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range document {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(nested, prefix+key+".", values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+// setFileValue sets v from a value decoded from a config file.
+func setFileValue(v reflect.Value, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		return setString(v, "")
+	case []interface{}:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("got a list, want a single value")
+		}
+		var items []string
+		for _, item := range value {
+			items = append(items, fmt.Sprint(item))
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+		return nil
+	default:
+		return setString(v, fmt.Sprint(value))
+	}
+}
+
+// setString sets v from its string form.
+func setString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, want true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Server struct {
+		Host    string        `yaml:"host" env:"TEST_HOST" default:"localhost"`
+		Port    int           `yaml:"port" env:"TEST_PORT" default:"8080"`
+		Timeout time.Duration `yaml:"timeout" env:"TEST_TIMEOUT" default:"10s"`
+	} `yaml:"server"`
+	Debug   bool     `yaml:"debug" env:"TEST_DEBUG"`
+	Rate    float64  `yaml:"rate" env:"TEST_RATE" default:"1"`
+	Limit   int64    `yaml:"limit" env:"TEST_LIMIT" default:"1048576"`
+	Scopes  []string `yaml:"scopes" env:"TEST_SCOPES" default:"openid,email"`
+	FileKey string   `yaml:"file_key"`
+	Token   string   `yaml:"token" env:"TEST_TOKEN" secret:"true"`
+	ignored string
+}
+
+// writeFile writes content to a file named name in a temporary directory.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := `
+server:
+  host: 0.0.0.0
+  timeout: 30s
+rate: 0.5
+scopes: [openid, profile]
+file_key: from-file
+`
+	jsonFile := `{"server": {"host": "0.0.0.0", "timeout": "30s"}, "rate": 0.5, "limit": 9007199254740993,
+"scopes": ["openid", "profile"], "file_key": "from-file"}`
+
+	tests := []struct {
+		name  string
+		file  string
+		data  string
+		env   map[string]string
+		check func(t *testing.T, got testConfig)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, got testConfig) {
+				if got.Server.Host != "localhost" || got.Server.Port != 8080 || got.Server.Timeout != 10*time.Second {
+					t.Errorf("Load() server = %+v, want the defaults", got.Server)
+				}
+				if got.Debug || got.Rate != 1 || got.Limit != 1048576 || !reflect.DeepEqual(got.Scopes, []string{"openid", "email"}) {
+					t.Errorf("Load() = %+v, want the defaults", got)
+				}
+			},
+		},
+		{
+			name: "yaml file over defaults",
+			file: "config.yaml",
+			data: yamlFile,
+			check: func(t *testing.T, got testConfig) {
+				if got.Server.Host != "0.0.0.0" || got.Server.Port != 8080 || got.Server.Timeout != 30*time.Second {
+					t.Errorf("Load() server = %+v, want host and timeout from the file", got.Server)
+				}
+				if got.Rate != 0.5 || !reflect.DeepEqual(got.Scopes, []string{"openid", "profile"}) || got.FileKey != "from-file" {
+					t.Errorf("Load() = %+v, want values from the file", got)
+				}
+			},
+		},
+		{
+			name: "json file over defaults",
+			file: "config.json",
+			data: jsonFile,
+			check: func(t *testing.T, got testConfig) {
+				if got.Server.Host != "0.0.0.0" || got.Server.Timeout != 30*time.Second || got.Rate != 0.5 {
+					t.Errorf("Load() = %+v, want values from the file", got)
+				}
+				if got.Limit != 9007199254740993 {
+					t.Errorf("Load() limit = %v, want %v", got.Limit, int64(9007199254740993))
+				}
+			},
+		},
+		{
+			name: "env over file",
+			file: "config.yaml",
+			data: yamlFile,
+			env:  map[string]string{"TEST_HOST": "example.com", "TEST_DEBUG": "true", "TEST_SCOPES": "openid, email ,"},
+			check: func(t *testing.T, got testConfig) {
+				if got.Server.Host != "example.com" || !got.Debug || !reflect.DeepEqual(got.Scopes, []string{"openid", "email"}) {
+					t.Errorf("Load() = %+v, want values from the environment", got)
+				}
+				if got.Server.Timeout != 30*time.Second {
+					t.Errorf("Load() timeout = %v, want %v from the file", got.Server.Timeout, 30*time.Second)
+				}
+			},
+		},
+		{
+			name: "empty env is unset",
+			env:  map[string]string{"TEST_PORT": ""},
+			check: func(t *testing.T, got testConfig) {
+				if got.Server.Port != 8080 {
+					t.Errorf("Load() port = %v, want %v", got.Server.Port, 8080)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			path := ""
+			if tt.file != "" {
+				path = writeFile(t, tt.file, tt.data)
+			}
+
+			var got testConfig
+			if err := Load(path, &got); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "invalid env values",
+			env:  map[string]string{"TEST_PORT": "http", "TEST_TIMEOUT": "10", "TEST_DEBUG": "yes"},
+			want: []string{`TEST_PORT: invalid integer "http"`, `TEST_TIMEOUT: invalid duration "10"`, `TEST_DEBUG: invalid boolean "yes"`},
+		},
+		{
+			name: "invalid file values",
+			file: "config.yaml",
+			data: "server:\n  port: http\nrate: [1, 2]\n",
+			want: []string{`server.port in`, `invalid integer "http"`, `rate in`, `got a list`},
+		},
+		{
+			name: "unknown keys",
+			file: "config.yaml",
+			data: "server:\n  hots: 0.0.0.0\n  port:\n    number: 1\nignored: x\n",
+			want: []string{"server.hots in", "server.port.number in", "ignored in", "unknown key"},
+		},
+		{
+			name: "malformed file",
+			file: "config.json",
+			data: `{"server": `,
+			want: []string{"config file"},
+		},
+		{
+			name: "missing file",
+			want: []string{"config file", "no such file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			path := filepath.Join(t.TempDir(), "missing.yaml")
+			if tt.file != "" {
+				path = writeFile(t, tt.file, tt.data)
+			} else if tt.env != nil {
+				path = ""
+			}
+
+			var got testConfig
+			err := Load(path, &got)
+			if err == nil {
+				t.Fatal("Load() error = nil, want an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_NotAStruct(t *testing.T) {
+	var port int
+	if err := Load("", &port); err == nil {
+		t.Error("Load() should fail for a pointer to a non-struct")
+	}
+	if err := Load("", testConfig{}); err == nil {
+		t.Error("Load() should fail for a struct value")
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Changed returns the dotted keys of the fields whose values differ between
+// the structs pointed to by old and updated, which must be of the same type,
+// in field order.
+func Changed(old, updated interface{}) ([]string, error) {
+	if reflect.TypeOf(old) != reflect.TypeOf(updated) {
+		return nil, fmt.Errorf("config: cannot compare %T with %T", old, updated)
+	}
+	oldFields, err := structFields(old)
+	if err != nil {
+		return nil, err
+	}
+	updatedFields, err := structFields(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for i, f := range oldFields {
+		if !reflect.DeepEqual(f.value.Interface(), updatedFields[i].value.Interface()) {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys, nil
+}
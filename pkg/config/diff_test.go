@@ -0,0 +1,41 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChanged(t *testing.T) {
+	var old testConfig
+	if err := Load("", &old); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	updated := old
+	updated.Scopes = append([]string(nil), old.Scopes...)
+	if got, err := Changed(&old, &updated); err != nil || got != nil {
+		t.Errorf("Changed() of equal configs = %v, %v, want nil, nil", got, err)
+	}
+
+	updated.Server.Timeout = time.Minute
+	updated.Scopes = []string{"openid"}
+	updated.Token = "s3cr3t"
+	got, err := Changed(&old, &updated)
+	if err != nil {
+		t.Fatalf("Changed() error = %v", err)
+	}
+	if want := []string{"server.timeout", "scopes", "token"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Changed() = %v, want %v", got, want)
+	}
+}
+
+func TestChanged_Mismatch(t *testing.T) {
+	other := struct{ Port int }{}
+	if _, err := Changed(&testConfig{}, &other); err == nil {
+		t.Error("Changed() should fail for structs of different types")
+	}
+	if _, err := Changed(testConfig{}, testConfig{}); err == nil {
+		t.Error("Changed() should fail for struct values")
+	}
+}
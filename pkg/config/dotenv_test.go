@@ -0,0 +1,82 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDotEnv(t *testing.T) {
+	path := writeFile(t, ".env", `
+# Local development
+TEST_HOST=dev.example.com
+export TEST_PORT = 9000
+TEST_SCOPES=openid,profile # the scopes of the dev client
+TEST_TOKEN="line one\nsay \"hi\""
+TEST_LITERAL='no\nescapes # here'
+TEST_EMPTY=
+`)
+
+	got, err := ReadDotEnv(path)
+	if err != nil {
+		t.Fatalf("ReadDotEnv() error = %v", err)
+	}
+	want := map[string]string{
+		"TEST_HOST":    "dev.example.com",
+		"TEST_PORT":    "9000",
+		"TEST_SCOPES":  "openid,profile",
+		"TEST_TOKEN":   "line one\nsay \"hi\"",
+		"TEST_LITERAL": `no\nescapes # here`,
+		"TEST_EMPTY":   "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDotEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestReadDotEnv_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"missing equals", "TEST_HOST\n", ".env:1: want KEY=value"},
+		{"space in key", "# comment\nTEST HOST=x\n", ".env:2: want KEY=value"},
+		{"unterminated quote", `TEST_TOKEN="secret`, "TEST_TOKEN: unterminated quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadDotEnv(writeFile(t, ".env", tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadDotEnv() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadDotEnv("missing.env"); err == nil {
+		t.Error("ReadDotEnv() error = nil, want an error for a missing file")
+	}
+}
+
+func TestLoad_WithDotEnv(t *testing.T) {
+	t.Setenv("TEST_HOST", "env.example.com")
+	t.Setenv("TEST_PORT", "")
+	path := writeFile(t, ".env", "TEST_HOST=dotenv.example.com\nTEST_PORT=9000\nOTHER_TOOL=ignored\n")
+
+	var got testConfig
+	if err := Load("", &got, WithDotEnv(path)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Server.Host != "env.example.com" {
+		t.Errorf("Load() host = %v, want %v from the environment over the .env file", got.Server.Host, "env.example.com")
+	}
+	if got.Server.Port != 9000 {
+		t.Errorf("Load() port = %v, want %v from the .env file", got.Server.Port, 9000)
+	}
+
+	invalid := writeFile(t, ".env", "TEST_PORT=many\n")
+	err := Load("", &got, WithDotEnv(invalid))
+	if err == nil || !strings.Contains(err.Error(), "TEST_PORT in "+invalid) {
+		t.Errorf("Load() error = %v, want it to name the variable and the .env file", err)
+	}
+}
@@ -0,0 +1,12 @@
+package config
+
+import "os"
+
+// Getenv returns the environment variable named by key, or defaultValue when
+// it is unset or empty.
+func Getenv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestGetenv(t *testing.T) {
+	t.Setenv("CONFIG_TEST_SET", "value")
+	t.Setenv("CONFIG_TEST_EMPTY", "")
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"CONFIG_TEST_SET", "value"},
+		{"CONFIG_TEST_EMPTY", "default"},
+		{"CONFIG_TEST_UNSET", "default"},
+	}
+
+	for _, tt := range tests {
+		if got := Getenv(tt.key, "default"); got != tt.want {
+			t.Errorf("Getenv(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
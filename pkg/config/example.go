@@ -0,0 +1,64 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Example returns an example config file for the type of the struct pointed
+// to by dst, which is left unchanged. Every field is written with its default
+// value and commented with its type and environment variable, e.g.
+//
+//	server:
+//	  port: 8080 # integer, env PORT
+//
+// Secret fields are marked as such. The example can be loaded as is.
+func Example(dst interface{}) ([]byte, error) {
+	if _, err := structFields(dst); err != nil {
+		return nil, err
+	}
+	defaults := reflect.New(reflect.TypeOf(dst).Elem())
+	fields, _ := structFields(defaults.Interface())
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setString(f.value, f.def); err != nil {
+			return nil, err
+		}
+	}
+
+	return encode(document(fields, func(f field, _, value *yaml.Node) {
+		notes := []string{typeName(f.value.Type())}
+		if f.secret {
+			notes = append(notes, "secret")
+		}
+		if f.env != "" {
+			notes = append(notes, "env "+f.env)
+		}
+		value.LineComment = strings.Join(notes, ", ")
+	}))
+}
+
+// typeName describes the type of field values to people editing a config file.
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "unsigned integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "list of " + typeName(t.Elem()) + "s"
+	default:
+		return t.Kind().String()
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExample(t *testing.T) {
+	var dst testConfig
+	dst.Server.Host = "unchanged"
+
+	got, err := Example(&dst)
+	if err != nil {
+		t.Fatalf("Example() error = %v", err)
+	}
+	for _, want := range []string{
+		"server:\n  host: localhost # string, env TEST_HOST\n  port: 8080 # integer, env TEST_PORT\n",
+		"  timeout: 10s # duration, env TEST_TIMEOUT\n",
+		"debug: false # boolean, env TEST_DEBUG\n",
+		"scopes: [openid, email] # list of strings, env TEST_SCOPES\n",
+		"file_key: \"\" # string\n",
+		"token: \"\" # string, secret, env TEST_TOKEN\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Example() =\n%s\nwant it to contain %q", got, want)
+		}
+	}
+	if dst.Server.Host != "unchanged" {
+		t.Errorf("Example() changed dst host to %v", dst.Server.Host)
+	}
+
+	// The example loads as the defaults
+	var loaded, defaults testConfig
+	if err := Load(writeFile(t, "example.yaml", string(got)), &loaded); err != nil {
+		t.Fatalf("Load() of the example error = %v", err)
+	}
+	if err := Load("", &defaults); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, defaults) {
+		t.Errorf("Load() of the example = %+v, want the defaults %+v", loaded, defaults)
+	}
+}
+
+func TestExample_NotAStruct(t *testing.T) {
+	if _, err := Example(testConfig{}); err == nil {
+		t.Error("Example() error = nil, want an error for a non-pointer")
+	}
+}
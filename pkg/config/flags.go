@@ -0,0 +1,79 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// flagValue holds the command-line value of a field until Load applies it.
+type flagValue struct {
+	value  string
+	isBool bool
+	set    bool
+}
+
+// String returns the value given on the command line, or the default.
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+// Set records the value given on the command line.
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	v.set = true
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set by a bare flag, e.g. --server.debug.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// DefineFlags defines a flag on fs for every field of the struct pointed to
+// by dst, named by its dotted key, e.g. --server.port. Values are checked and
+// applied by Load with WithFlags, once fs has been parsed.
+func DefineFlags(fs *flag.FlagSet, dst interface{}) error {
+	fields, err := structFields(dst)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		usage := "sets " + f.key
+		if f.env != "" {
+			usage += ", overriding $" + f.env
+		}
+		fs.Var(&flagValue{value: f.def, isBool: f.value.Kind() == reflect.Bool}, f.key, usage)
+	}
+	return nil
+}
+
+// WithFlags makes Load apply the flags of fs set on the command line, over
+// every other layer. The flags must have been defined by DefineFlags.
+func WithFlags(fs *flag.FlagSet) Option {
+	return func(o *options) {
+		o.flags = fs
+	}
+}
+
+// applyFlags sets the fields whose flag was given on the command line.
+func applyFlags(fs *flag.FlagSet, fields []field) []error {
+	var errs []error
+	for _, f := range fields {
+		fl := fs.Lookup(f.key)
+		if fl == nil {
+			continue
+		}
+		value, ok := fl.Value.(*flagValue)
+		if !ok || !value.set {
+			continue
+		}
+		if err := setString(f.value, value.value); err != nil {
+			errs = append(errs, fmt.Errorf("--%s: %w", f.key, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseFlags defines the flags of testConfig and parses args.
+func parseFlags(t *testing.T, dst *testConfig, args ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := DefineFlags(fs, dst); err != nil {
+		t.Fatalf("DefineFlags() error = %v", err)
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return fs
+}
+
+func TestLoad_WithFlags(t *testing.T) {
+	t.Setenv("TEST_HOST", "env.example.com")
+	t.Setenv("TEST_PORT", "9000")
+	path := writeFile(t, "config.yaml", "server:\n  timeout: 30s\nrate: 0.5\n")
+
+	var got testConfig
+	fs := parseFlags(t, &got, "--server.host=flag.example.com", "--debug", "--scopes", "openid,profile", "--rate", "0.25")
+	if err := Load(path, &got, WithFlags(fs)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got.Server.Host != "flag.example.com" {
+		t.Errorf("Load() host = %v, want %v from the flag over the environment", got.Server.Host, "flag.example.com")
+	}
+	if got.Server.Port != 9000 {
+		t.Errorf("Load() port = %v, want %v from the environment", got.Server.Port, 9000)
+	}
+	if got.Server.Timeout != 30*time.Second {
+		t.Errorf("Load() timeout = %v, want %v from the file", got.Server.Timeout, 30*time.Second)
+	}
+	if got.Rate != 0.25 {
+		t.Errorf("Load() rate = %v, want %v from the flag over the file", got.Rate, 0.25)
+	}
+	if !got.Debug || strings.Join(got.Scopes, ",") != "openid,profile" {
+		t.Errorf("Load() debug = %v and scopes = %v, want true and [openid profile]", got.Debug, got.Scopes)
+	}
+	if got.Limit != 1048576 {
+		t.Errorf("Load() limit = %v, want the default %v", got.Limit, 1048576)
+	}
+}
+
+func TestLoad_WithFlagsInvalid(t *testing.T) {
+	var got testConfig
+	fs := parseFlags(t, &got, "--server.port=http", "--server.timeout=10")
+	err := Load("", &got, WithFlags(fs))
+	if err == nil {
+		t.Fatal("Load() error = nil, want an error")
+	}
+	for _, want := range []string{`--server.port: invalid integer "http"`, `--server.timeout: invalid duration "10"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestDefineFlags(t *testing.T) {
+	var dst testConfig
+	fs := parseFlags(t, &dst)
+
+	port := fs.Lookup("server.port")
+	if port == nil {
+		t.Fatal("DefineFlags() did not define --server.port")
+	}
+	if port.DefValue != "8080" {
+		t.Errorf("--server.port default = %q, want %q", port.DefValue, "8080")
+	}
+	if !strings.Contains(port.Usage, "$TEST_PORT") {
+		t.Errorf("--server.port usage = %q, want it to name $TEST_PORT", port.Usage)
+	}
+	if fs.Lookup("ignored") != nil || fs.Lookup("server") != nil {
+		t.Error("DefineFlags() defined flags for unexported fields or sections")
+	}
+}
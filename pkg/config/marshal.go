@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of secret fields in Marshal's output.
+const redacted = "<redacted>"
+
+// Marshal returns the struct pointed to by src as YAML, in the layout Load
+// reads: keys follow the yaml tags and the order of the fields, and durations
+// are written like "10s". Non-empty fields tagged secret:"true" are written as
+// <redacted>, so the output can be shared.
+func Marshal(src interface{}) ([]byte, error) {
+	fields, err := structFields(src)
+	if err != nil {
+		return nil, err
+	}
+	return encode(document(fields, nil))
+}
+
+// document returns the mapping of fields, nested by section. annotate, when
+// not nil, may comment the key and value nodes of each field.
+func document(fields []field, annotate func(f field, key, value *yaml.Node)) *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := map[string]*yaml.Node{"": root}
+	for _, f := range fields {
+		parent := mappingFor(sections, f.key)
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: f.key[strings.LastIndex(f.key, ".")+1:]}
+		value := valueNode(f)
+		if annotate != nil {
+			annotate(f, key, value)
+		}
+		parent.Content = append(parent.Content, key, value)
+	}
+	return root
+}
+
+// encode returns root as YAML indented by two spaces.
+func encode(root *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return []byte(b.String()), nil
+}
+
+// mappingFor returns the mapping node holding the field with the dotted key,
+// adding the mappings of its sections to sections as they are first seen.
+func mappingFor(sections map[string]*yaml.Node, key string) *yaml.Node {
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return sections[""]
+	}
+	section := key[:i]
+	if node, ok := sections[section]; ok {
+		return node
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	parent := mappingFor(sections, section)
+	parent.Content = append(parent.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: section[strings.LastIndex(section, ".")+1:]},
+		node)
+	sections[section] = node
+	return node
+}
+
+// valueNode returns the YAML node of the value of f.
+func valueNode(f field) *yaml.Node {
+	v := f.value
+	if f.secret && !v.IsZero() {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redacted}
+	}
+	if v.Type() == durationType {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: time.Duration(v.Int()).String()}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatBool(v.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatInt(v.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatUint(v.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())}
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < v.Len(); i++ {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v.Index(i).Interface())})
+		}
+		return node
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v.Interface())}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "s3cr3t")
+
+	var src testConfig
+	if err := Load("", &src); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	data, err := Marshal(&src)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := `server:
+  host: localhost
+  port: 8080
+  timeout: 10s
+debug: false
+rate: 1
+limit: 1048576
+scopes: [openid, email]
+file_key: ""
+token: <redacted>
+`
+	if string(data) != want {
+		t.Errorf("Marshal() = \n%s, want \n%s", data, want)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Error("Marshal() leaks a secret field")
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	t.Setenv("TEST_HOST", "example.com")
+	t.Setenv("TEST_PORT", "9000")
+	t.Setenv("TEST_SCOPES", "openid")
+
+	var src testConfig
+	if err := Load("", &src); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	data, err := Marshal(&src)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	t.Setenv("TEST_HOST", "")
+	t.Setenv("TEST_PORT", "")
+	t.Setenv("TEST_SCOPES", "")
+	var got testConfig
+	if err := Load(writeFile(t, "config.yaml", string(data)), &got); err != nil {
+		t.Fatalf("Load() of the marshaled config error = %v", err)
+	}
+	if !reflect.DeepEqual(got, src) {
+		t.Errorf("Load(Marshal()) = %+v, want %+v", got, src)
+	}
+}
+
+func TestMarshal_NotAStruct(t *testing.T) {
+	if _, err := Marshal(testConfig{}); err == nil {
+		t.Error("Marshal() should fail for a struct value")
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// SecretsProvider fetches secrets, such as passwords and signing keys, from
+// a store outside the configuration. Secret returns false when the store has
+// no secret with the name.
+type SecretsProvider interface {
+	Secret(ctx context.Context, name string) (string, bool, error)
+}
+
+// EnvProvider reads secrets from the environment variables of their name.
+type EnvProvider struct{}
+
+// Secret returns the environment variable name, unless unset or empty.
+func (EnvProvider) Secret(_ context.Context, name string) (string, bool, error) {
+	value := os.Getenv(name)
+	return value, value != "", nil
+}
+
+// WithSecrets makes Load fetch the fields tagged secret:"true" from p, under
+// the name of their env tag, or their dotted key without one. Fetched secrets
+// override the file and the environment; flags still override them.
+func WithSecrets(ctx context.Context, p SecretsProvider) Option {
+	return func(o *options) {
+		o.ctx = ctx
+		o.secrets = p
+	}
+}
+
+// applySecrets sets the secret fields p has a secret for. It stops at the
+// first error, as the store is usually unreachable for all of them.
+func applySecrets(ctx context.Context, p SecretsProvider, fields []field) []error {
+	for _, f := range fields {
+		if !f.secret {
+			continue
+		}
+		name := f.env
+		if name == "" {
+			name = f.key
+		}
+		value, ok, err := p.Secret(ctx, name)
+		if err != nil {
+			return []error{fmt.Errorf("secret %s: %w", name, err)}
+		}
+		if !ok {
+			continue
+		}
+		if err := setString(f.value, value); err != nil {
+			return []error{fmt.Errorf("secret %s: %w", name, err)}
+		}
+	}
+	return nil
+}
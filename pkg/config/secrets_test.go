@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mapProvider serves secrets from a map, or fails with err.
+type mapProvider struct {
+	secrets map[string]string
+	err     error
+	calls   int
+}
+
+func (p *mapProvider) Secret(_ context.Context, name string) (string, bool, error) {
+	p.calls++
+	if p.err != nil {
+		return "", false, p.err
+	}
+	value, ok := p.secrets[name]
+	return value, ok, nil
+}
+
+func TestLoad_WithSecrets(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "from-env")
+	t.Setenv("TEST_HOST", "")
+
+	var got testConfig
+	provider := &mapProvider{secrets: map[string]string{"TEST_TOKEN": "from-provider", "TEST_HOST": "not-a-secret"}}
+	if err := Load("", &got, WithSecrets(context.Background(), provider)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Token != "from-provider" {
+		t.Errorf("Load() token = %v, want %v from the provider over the environment", got.Token, "from-provider")
+	}
+	if got.Server.Host != "localhost" {
+		t.Errorf("Load() host = %v, want %v as only secret fields are fetched", got.Server.Host, "localhost")
+	}
+
+	// Flags still win
+	fs := parseFlags(t, &got, "--token=from-flag")
+	if err := Load("", &got, WithSecrets(context.Background(), provider), WithFlags(fs)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Token != "from-flag" {
+		t.Errorf("Load() token = %v, want %v from the flag", got.Token, "from-flag")
+	}
+
+	// Missing secrets keep the other layers' value
+	if err := Load("", &got, WithSecrets(context.Background(), &mapProvider{})); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Token != "from-env" {
+		t.Errorf("Load() token = %v, want %v from the environment", got.Token, "from-env")
+	}
+}
+
+func TestLoad_WithSecretsError(t *testing.T) {
+	provider := &mapProvider{err: errors.New("connection refused")}
+	var got testConfig
+	err := Load("", &got, WithSecrets(context.Background(), provider))
+	if err == nil || !strings.Contains(err.Error(), "secret TEST_TOKEN: connection refused") {
+		t.Errorf("Load() error = %v, want the provider's error", err)
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("TEST_SECRET", "s3cr3t")
+	t.Setenv("TEST_EMPTY", "")
+
+	value, ok, err := EnvProvider{}.Secret(context.Background(), "TEST_SECRET")
+	if err != nil || !ok || value != "s3cr3t" {
+		t.Errorf("Secret() = %q, %v, %v, want s3cr3t, true, nil", value, ok, err)
+	}
+	if _, ok, _ := (EnvProvider{}).Secret(context.Background(), "TEST_EMPTY"); ok {
+		t.Error("Secret() found an empty variable, want it unset")
+	}
+}
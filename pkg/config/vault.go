@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultProvider reads secrets from a HashiCorp Vault KV secret, whose keys
+// are the secret names. The secret is fetched once, on the first call of
+// Secret, so a configuration is loaded with a single request.
+type VaultProvider struct {
+	address string
+	token   string
+	path    string
+	client  *http.Client
+
+	mutex   sync.Mutex
+	secrets map[string]string
+}
+
+// NewVaultProvider creates a provider reading the KV secret at path, e.g.
+// secret/data/user-service for a version 2 engine mounted at secret/, from
+// the Vault server at address, authenticating with token.
+func NewVaultProvider(address, token, path string) *VaultProvider {
+	return &VaultProvider{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		path:    strings.Trim(path, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Secret returns the value of the key name of the Vault secret.
+func (p *VaultProvider) Secret(ctx context.Context, name string) (string, bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.secrets == nil {
+		secrets, err := p.fetch(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		p.secrets = secrets
+	}
+	value, ok := p.secrets[name]
+	return value, ok, nil
+}
+
+// fetch reads the keys of the Vault secret.
+func (p *VaultProvider) fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/v1/"+p.path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("vault: reading %s: %s: %s", p.path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("vault: reading %s: %w", p.path, err)
+	}
+	// Version 2 engines nest the keys under data, next to the metadata
+	data := body.Data
+	if _, ok := data["metadata"]; ok {
+		if err := json.Unmarshal(data["data"], &data); err != nil {
+			return nil, fmt.Errorf("vault: reading %s: %w", p.path, err)
+		}
+	}
+
+	secrets := make(map[string]string, len(data))
+	for key, raw := range data {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("vault: reading %s: %w", p.path, err)
+		}
+		if s, ok := value.(string); ok {
+			secrets[key] = s
+		} else {
+			secrets[key] = strings.TrimSpace(string(raw))
+		}
+	}
+	return secrets, nil
+}
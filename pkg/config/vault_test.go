@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVaultProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"kv version 2", `{"data": {"data": {"JWT_SECRET": "s3cr3t", "PORT": 5432}, "metadata": {"version": 3}}}`},
+		{"kv version 1", `{"data": {"JWT_SECRET": "s3cr3t", "PORT": 5432}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.URL.Path != "/v1/secret/data/app" || r.Header.Get("X-Vault-Token") != "root" {
+					http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			p := NewVaultProvider(server.URL+"/", "root", "/secret/data/app")
+			value, ok, err := p.Secret(context.Background(), "JWT_SECRET")
+			if err != nil || !ok || value != "s3cr3t" {
+				t.Errorf("Secret() = %q, %v, %v, want s3cr3t, true, nil", value, ok, err)
+			}
+			if value, ok, _ := p.Secret(context.Background(), "PORT"); !ok || value != "5432" {
+				t.Errorf("Secret() = %q, %v, want 5432, true", value, ok)
+			}
+			if _, ok, _ := p.Secret(context.Background(), "MISSING"); ok {
+				t.Error("Secret() found a missing key")
+			}
+			if requests != 1 {
+				t.Errorf("requests = %d, want 1", requests)
+			}
+		})
+	}
+}
+
+func TestVaultProvider_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, _, err := NewVaultProvider(server.URL, "expired", "secret/data/app").Secret(context.Background(), "JWT_SECRET")
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden") || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Secret() error = %v, want the status and Vault's errors", err)
+	}
+}
@@ -0,0 +1,323 @@
+// Package contract checks the events of a producer against the fields its
+// consumers rely on, in the spirit of consumer-driven contracts (Pact).
+//
+// A consumer declares what it reads from the events of a producer in a
+// contract file kept with its own code, modules/<consumer>/contracts/<producer>.json:
+//
+//	{
+//	  "consumer": "orders",
+//	  "producer": "foundation",
+//	  "events": [
+//	    {"types": ["user.created", "user.updated"], "fields": {"data.user.id": "string", "data.user.version": "number"}}
+//	  ]
+//	}
+//
+// Field paths name the fields of the event envelope as sent on the wire,
+// such as subject or tenant, and the payload under data, with dots for
+// nested fields and array indexes. The consumer tests its handlers with
+// the Examples of the contract, so the contract is all it needs; the
+// producer tests the events it publishes with Verify against every contract
+// naming it, so it cannot drop or retype a field a consumer reads.
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Kind is the JSON type of a field.
+type Kind string
+
+// Kinds of fields
+const (
+	String  Kind = "string"
+	Number  Kind = "number"
+	Boolean Kind = "boolean"
+	Object  Kind = "object"
+	Array   Kind = "array"
+	// Timestamp is a string holding an RFC 3339 time.
+	Timestamp Kind = "timestamp"
+)
+
+// kinds are the valid kinds
+var kinds = []Kind{String, Number, Boolean, Object, Array, Timestamp}
+
+// Contract is what a consumer reads from the events of a producer.
+type Contract struct {
+	Consumer string  `json:"consumer"`
+	Producer string  `json:"producer"`
+	Events   []Event `json:"events"`
+}
+
+// Event is the fields a consumer reads from the events of types.
+type Event struct {
+	Types  []string        `json:"types"`
+	Fields map[string]Kind `json:"fields"`
+}
+
+// Load reads the contract file at path and checks it is well formed.
+func Load(path string) (Contract, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Contract{}, err
+	}
+	var c Contract
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return Contract{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return Contract{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// LoadFor reads the contracts of every consumer of producer found in
+// modulesDir, modulesDir/<consumer>/contracts/<producer>.json, sorted by
+// consumer.
+func LoadFor(modulesDir, producer string) ([]Contract, error) {
+	paths, err := filepath.Glob(filepath.Join(modulesDir, "*", "contracts", producer+".json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var contracts []Contract
+	for _, path := range paths {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		consumer := filepath.Base(filepath.Dir(filepath.Dir(path)))
+		if c.Consumer != consumer || c.Producer != producer {
+			return nil, fmt.Errorf("%s: contract of %s with %s, want %s with %s", path, c.Consumer, c.Producer, consumer, producer)
+		}
+		contracts = append(contracts, c)
+	}
+	return contracts, nil
+}
+
+// validate reports the first problem of the contract
+func (c Contract) validate() error {
+	if c.Consumer == "" || c.Producer == "" {
+		return fmt.Errorf("consumer and producer are required")
+	}
+	if len(c.Events) == 0 {
+		return fmt.Errorf("no events")
+	}
+	for i, event := range c.Events {
+		if len(event.Types) == 0 {
+			return fmt.Errorf("events[%d]: no types", i)
+		}
+		if len(event.Fields) == 0 {
+			return fmt.Errorf("events[%d]: no fields", i)
+		}
+		for path, kind := range event.Fields {
+			if !slices.Contains(kinds, kind) {
+				return fmt.Errorf("events[%d]: field %s: unknown kind %q", i, path, kind)
+			}
+		}
+	}
+	return nil
+}
+
+// Types returns the event types the contract covers, sorted.
+func (c Contract) Types() []string {
+	var types []string
+	for _, event := range c.Events {
+		for _, eventType := range event.Types {
+			if !slices.Contains(types, eventType) {
+				types = append(types, eventType)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Fields returns the fields the consumer reads from events of eventType,
+// nil when the contract does not cover it.
+func (c Contract) Fields(eventType string) map[string]Kind {
+	var fields map[string]Kind
+	for _, event := range c.Events {
+		if !slices.Contains(event.Types, eventType) {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]Kind)
+		}
+		for path, kind := range event.Fields {
+			fields[path] = kind
+		}
+	}
+	return fields
+}
+
+// Check returns how event breaks the contract, sorted by field, or nothing
+// when it keeps the contract or is of a type the contract does not cover.
+func (c Contract) Check(event events.Event) []string {
+	fields := c.Fields(event.Type)
+	if fields == nil {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var envelope any
+	json.Unmarshal(data, &envelope)
+
+	var problems []string
+	for _, path := range sortedPaths(fields) {
+		value, ok := lookup(envelope, path)
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s (%s) is missing", path, fields[path]))
+		case !fields[path].matches(value):
+			problems = append(problems, fmt.Sprintf("%s is %s, want %s", path, kindOf(value), fields[path]))
+		}
+	}
+	return problems
+}
+
+// Examples returns an event of each type of the contract holding the
+// fields the consumer reads, with example values, and nothing else: a
+// consumer handling them correctly needs nothing beyond the contract.
+func (c Contract) Examples() []events.Event {
+	var examples []events.Event
+	for _, eventType := range c.Types() {
+		envelope := map[string]any{
+			"type":           eventType,
+			"source":         c.Producer,
+			"schema_version": "1.0.0",
+			"data":           map[string]any{},
+		}
+		fields := c.Fields(eventType)
+		for _, path := range sortedPaths(fields) {
+			set(envelope, strings.Split(path, "."), fields[path].example())
+		}
+		data, _ := json.Marshal(envelope)
+		var event events.Event
+		json.Unmarshal(data, &event)
+		examples = append(examples, event)
+	}
+	return examples
+}
+
+// matches reports whether the decoded JSON value is of kind k
+func (k Kind) matches(value any) bool {
+	if k == Timestamp {
+		s, ok := value.(string)
+		return ok && isTimestamp(s)
+	}
+	return kindOf(value) == k
+}
+
+// example returns an example value of kind k
+func (k Kind) example() any {
+	switch k {
+	case Number:
+		return 1
+	case Boolean:
+		return true
+	case Object:
+		return map[string]any{}
+	case Array:
+		return []any{}
+	case Timestamp:
+		return "2024-01-01T00:00:00Z"
+	default:
+		return "example"
+	}
+}
+
+// kindOf returns the kind of the decoded JSON value
+func kindOf(value any) Kind {
+	switch value.(type) {
+	case string:
+		return String
+	case float64:
+		return Number
+	case bool:
+		return Boolean
+	case map[string]any:
+		return Object
+	case []any:
+		return Array
+	default:
+		return "null"
+	}
+}
+
+// isTimestamp reports whether s is an RFC 3339 time
+func isTimestamp(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+// lookup returns the value at the dotted path in the decoded JSON value
+func lookup(value any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			value = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// set stores value at keys in node, creating the objects and arrays on the
+// way: a numeric key makes an array
+func set(node any, keys []string, value any) any {
+	if len(keys) == 0 {
+		return value
+	}
+	key := keys[0]
+	if i, err := strconv.Atoi(key); err == nil && i >= 0 {
+		array, _ := node.([]any)
+		for len(array) <= i {
+			array = append(array, nil)
+		}
+		array[i] = set(array[i], keys[1:], value)
+		return array
+	}
+	object, ok := node.(map[string]any)
+	if !ok {
+		object = make(map[string]any)
+	}
+	object[key] = set(object[key], keys[1:], value)
+	return object
+}
+
+// sortedPaths returns the paths of fields, sorted
+func sortedPaths(fields map[string]Kind) []string {
+	paths := make([]string, 0, len(fields))
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,193 @@
+package contract
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// modulesDir holds contract files laid out like the modules of the repository
+var modulesDir = filepath.Join("testdata", "modules")
+
+// fakeT records the failures reported to it
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// userEvent creates a user event carrying user
+func userEvent(t *testing.T, id, eventType string, user map[string]any) events.Event {
+	t.Helper()
+	event, err := events.New(id, eventType, "users", "1", map[string]any{"user": user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestLoadFor(t *testing.T) {
+	contracts, err := LoadFor(modulesDir, "users")
+	if err != nil {
+		t.Fatalf("LoadFor() error = %v", err)
+	}
+	var consumers []string
+	for _, c := range contracts {
+		consumers = append(consumers, c.Consumer)
+	}
+	if want := []string{"orders", "search"}; !reflect.DeepEqual(consumers, want) {
+		t.Errorf("LoadFor() consumers = %v, want %v", consumers, want)
+	}
+
+	if got := contracts[0].Types(); !reflect.DeepEqual(got, []string{"user.created", "user.deleted", "user.updated"}) {
+		t.Errorf("Types() = %v", got)
+	}
+	if got := contracts[0].Fields("user.deleted"); !reflect.DeepEqual(got, map[string]Kind{"data.user.id": String}) {
+		t.Errorf("Fields(user.deleted) = %v", got)
+	}
+	if got := contracts[0].Fields("user.suspended"); got != nil {
+		t.Errorf("Fields(user.suspended) = %v, want nil", got)
+	}
+
+	// The misnamed consumer declares a contract for shipping in the directory of another module
+	if _, err := LoadFor(modulesDir, "orders"); err == nil || !strings.Contains(err.Error(), "misnamed") {
+		t.Errorf("LoadFor(orders) error = %v, want the misnamed contract", err)
+	}
+	if contracts, err := LoadFor(modulesDir, "payments"); err != nil || len(contracts) != 0 {
+		t.Errorf("LoadFor(payments) = %v, %v, want none", contracts, err)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, wantErr string
+	}{
+		{"unknown field", `{"consumer":"a","producer":"b","events":[],"extra":1}`, "unknown field"},
+		{"no consumer", `{"producer":"b","events":[{"types":["x"],"fields":{"id":"string"}}]}`, "consumer and producer are required"},
+		{"no events", `{"consumer":"a","producer":"b"}`, "no events"},
+		{"no types", `{"consumer":"a","producer":"b","events":[{"fields":{"id":"string"}}]}`, "events[0]: no types"},
+		{"no fields", `{"consumer":"a","producer":"b","events":[{"types":["x"]}]}`, "events[0]: no fields"},
+		{"unknown kind", `{"consumer":"a","producer":"b","events":[{"types":["x"],"fields":{"id":"text"}}]}`, `unknown kind "text"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			writeFile(t, path, tt.content)
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContract_Check(t *testing.T) {
+	c, err := Load(filepath.Join(modulesDir, "orders", "contracts", "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event events.Event
+		want  []string
+	}{
+		{"kept", userEvent(t, "1", "user.created", map[string]any{"id": "1", "version": 2, "updated_at": updatedAt}), nil},
+		{"other type", userEvent(t, "1", "user.suspended", nil), nil},
+		{"missing field", userEvent(t, "1", "user.updated", map[string]any{"id": "1", "updated_at": updatedAt}),
+			[]string{"data.user.version (number) is missing"}},
+		{"retyped fields", userEvent(t, "1", "user.updated", map[string]any{"id": 1, "version": "2", "updated_at": "yesterday"}),
+			[]string{"data.user.id is number, want string", "data.user.updated_at is string, want timestamp", "data.user.version is string, want number"}},
+		{"null field", userEvent(t, "1", "user.deleted", map[string]any{"id": nil}), []string{"data.user.id is null, want string"}},
+		{"bare event", events.Event{Type: "user.deleted", Data: []byte(`{"user":{"id":"1"}}`)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Check(tt.event); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContract_Examples(t *testing.T) {
+	contracts, err := LoadFor(modulesDir, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range contracts {
+		for _, example := range c.Examples() {
+			if problems := c.Check(example); len(problems) > 0 {
+				t.Errorf("%s example of %s breaks the contract: %v", example.Type, c.Consumer, problems)
+			}
+			if example.Source != "users" {
+				t.Errorf("example.Source = %q, want %q", example.Source, "users")
+			}
+		}
+	}
+
+	search := contracts[1]
+	var data struct {
+		User struct {
+			Roles  []string `json:"roles"`
+			Active bool     `json:"active"`
+			Name   *string  `json:"name"`
+		} `json:"user"`
+	}
+	if err := search.Examples()[0].Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.User.Roles) != 1 || !data.User.Active || data.User.Name != nil {
+		t.Errorf("example data = %+v, want a role, active and nothing else", data.User)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	contracts, err := LoadFor(modulesDir, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := map[string]any{"id": "1", "version": 1, "updated_at": updatedAt, "roles": []string{"viewer"}, "active": true}
+
+	fake := &fakeT{}
+	Verify(fake, contracts, []events.Event{
+		userEvent(t, "1", "user.created", user),
+		userEvent(t, "2", "user.updated", user),
+		userEvent(t, "3", "user.deleted", user),
+	})
+	if len(fake.errors) > 0 {
+		t.Errorf("Verify() errors = %q, want none", fake.errors)
+	}
+
+	fake = &fakeT{}
+	Verify(fake, contracts, []events.Event{
+		userEvent(t, "1", "user.created", map[string]any{"id": "1", "version": 1, "updated_at": updatedAt, "roles": []string{}, "active": true}),
+		userEvent(t, "2", "user.updated", user),
+	})
+	want := []string{
+		"contract: no user.deleted event was published to check against the contract of orders",
+		"contract: user.created event 1 breaks the contract of search:\n\tdata.user.roles.0 (string) is missing",
+	}
+	if !reflect.DeepEqual(fake.errors, want) {
+		t.Errorf("Verify() errors = %q, want %q", fake.errors, want)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+package contract
+
+import (
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// T is the part of testing.TB that verifications report failures to.
+type T interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Verify checks the events a producer published during a test against the
+// contracts of its consumers. Every event type a contract covers must have
+// been published at least once, so the test exercises each of them, and
+// every published event of those types must keep the contract.
+func Verify(t T, contracts []Contract, published []events.Event) {
+	t.Helper()
+	for _, c := range contracts {
+		for _, eventType := range c.Types() {
+			seen := false
+			for _, event := range published {
+				if event.Type != eventType {
+					continue
+				}
+				seen = true
+				if problems := c.Check(event); len(problems) > 0 {
+					t.Errorf("contract: %s event %s breaks the contract of %s:\n\t%s", eventType, event.ID, c.Consumer, strings.Join(problems, "\n\t"))
+				}
+			}
+			if !seen {
+				t.Errorf("contract: no %s event was published to check against the contract of %s", eventType, c.Consumer)
+			}
+		}
+	}
+}
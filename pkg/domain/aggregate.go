@@ -0,0 +1,47 @@
+// Package domain holds the building blocks shared by the aggregates of every
+// module: an aggregate root recording the events it decides, the domain
+// event recorded before it is published, validation errors, and checks of
+// the values commands carry.
+package domain
+
+import "github.com/captain-corgi/learning-event-driven/pkg/events"
+
+// DomainEvent is an event decided by an aggregate, before it is stored or
+// published: its type and its payload.
+type DomainEvent struct {
+	Type string
+	Data interface{}
+}
+
+// Envelope wraps the event in the envelope shared by every service, with the
+// given ID, about subject and published by source.
+func (e DomainEvent) Envelope(id, source, subject string) (events.Event, error) {
+	return events.New(id, e.Type, source, subject, e.Data)
+}
+
+// AggregateRoot is embedded by aggregates to identify them, version them and
+// record the events their commands decide. Each recorded event increments
+// the version, so the version counts the changes of the aggregate.
+type AggregateRoot struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version"`
+
+	changes []DomainEvent
+}
+
+// Record records an event decided by a command and increments the version.
+func (a *AggregateRoot) Record(eventType string, data interface{}) {
+	a.Version++
+	a.changes = append(a.changes, DomainEvent{Type: eventType, Data: data})
+}
+
+// Changes returns the events recorded since the aggregate was loaded or its
+// changes were last cleared, oldest first.
+func (a *AggregateRoot) Changes() []DomainEvent {
+	return append([]DomainEvent(nil), a.changes...)
+}
+
+// ClearChanges forgets the recorded events, once they are stored.
+func (a *AggregateRoot) ClearChanges() {
+	a.changes = nil
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestAggregateRoot(t *testing.T) {
+	type placed struct {
+		Total int `json:"total"`
+	}
+	aggregate := AggregateRoot{ID: "o1", Version: 3}
+	aggregate.Record("order.placed", placed{Total: 42})
+	aggregate.Record("order.cancelled", nil)
+
+	if aggregate.Version != 5 {
+		t.Errorf("Version = %d, want 5 after two recorded events", aggregate.Version)
+	}
+	changes := aggregate.Changes()
+	if len(changes) != 2 || changes[0].Type != "order.placed" || changes[1].Type != "order.cancelled" {
+		t.Fatalf("Changes() = %+v, want both events in order", changes)
+	}
+	changes[0].Type = "changed"
+	if aggregate.Changes()[0].Type != "order.placed" {
+		t.Error("Changes() returned the recorded events, want a copy")
+	}
+
+	aggregate.ClearChanges()
+	if len(aggregate.Changes()) != 0 || aggregate.Version != 5 {
+		t.Errorf("after ClearChanges() changes = %v, version = %d, want none and 5", aggregate.Changes(), aggregate.Version)
+	}
+}
+
+func TestDomainEvent_Envelope(t *testing.T) {
+	event, err := DomainEvent{Type: "order.placed", Data: map[string]int{"total": 42}}.Envelope("e1", "orders", "o1")
+	if err != nil {
+		t.Fatalf("Envelope() error = %v", err)
+	}
+	if event.ID != "e1" || event.Type != "order.placed" || event.Source != "orders" || event.Subject != "o1" {
+		t.Errorf("Envelope() = %+v, want the given attributes", event)
+	}
+	if string(event.Data) != `{"total":42}` {
+		t.Errorf("Envelope() data = %s, want the encoded payload", event.Data)
+	}
+
+	if _, err := (DomainEvent{Type: "order.placed", Data: make(chan int)}).Envelope("e2", "orders", "o1"); err == nil {
+		t.Error("Envelope() error = nil, want an error for data that cannot be encoded")
+	}
+}
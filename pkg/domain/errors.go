@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError reports an invalid field of a command or a request.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// ValidationErrors collects validation errors so every invalid field is
+// reported at once instead of only the first one.
+type ValidationErrors []*ValidationError
+
+// Add records that field is invalid.
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, &ValidationError{Field: field, Message: message})
+}
+
+// Check records err when it is a validation error, such as the result of
+// Required or Positive, and ignores nil.
+func (v *ValidationErrors) Check(err error) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		*v = append(*v, validationErr)
+	}
+}
+
+// Err returns nil when no error was recorded, or the recorded errors. Use
+// errors.As with a *ValidationError to get the first one.
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, err := range v {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the recorded errors, for errors.Is and errors.As.
+func (v ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(v))
+	for i, err := range v {
+		errs[i] = err
+	}
+	return errs
+}
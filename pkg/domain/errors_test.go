@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidationError(t *testing.T) {
+	err := fmt.Errorf("placing order: %w", &ValidationError{Field: "items", Message: "must not be empty"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "items" {
+		t.Fatalf("errors.As() = %v, want the validation error", validationErr)
+	}
+	if got, want := validationErr.Error(), "items must not be empty"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	var errs ValidationErrors
+	if errs.Err() != nil {
+		t.Errorf("Err() = %v, want nil without errors", errs.Err())
+	}
+
+	errs.Add("name", "is required")
+	errs.Check(nil)
+	errs.Check(errors.New("not a validation error"))
+	errs.Check(Positive("price_cents", -1))
+	err := errs.Err()
+	if got, want := err.Error(), "name is required; price_cents must be positive"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "name" {
+		t.Errorf("errors.As() = %v, want the first error", validationErr)
+	}
+}
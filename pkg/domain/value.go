@@ -0,0 +1,35 @@
+package domain
+
+import "fmt"
+
+// Required checks that the value of field is not empty.
+func Required(field, value string) error {
+	if value == "" {
+		return &ValidationError{Field: field, Message: "is required"}
+	}
+	return nil
+}
+
+// MaxLength checks that the value of field is at most max bytes long.
+func MaxLength(field, value string, max int) error {
+	if len(value) > max {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must not exceed %d bytes", max)}
+	}
+	return nil
+}
+
+// Positive checks that the value of field is greater than zero.
+func Positive(field string, value int64) error {
+	if value <= 0 {
+		return &ValidationError{Field: field, Message: "must be positive"}
+	}
+	return nil
+}
+
+// NotNegative checks that the value of field is zero or more.
+func NotNegative(field string, value int64) error {
+	if value < 0 {
+		return &ValidationError{Field: field, Message: "must not be negative"}
+	}
+	return nil
+}
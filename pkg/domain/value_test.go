@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestValueChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"required", Required("name", ""), "name is required"},
+		{"present", Required("name", "Ada"), ""},
+		{"too long", MaxLength("name", "Ada Lovelace", 3), "name must not exceed 3 bytes"},
+		{"short enough", MaxLength("name", "Ada", 3), ""},
+		{"zero", Positive("amount_cents", 0), "amount_cents must be positive"},
+		{"positive", Positive("amount_cents", 1), ""},
+		{"negative", NotNegative("unit_price_cents", -1), "unit_price_cents must not be negative"},
+		{"free", NotNegative("unit_price_cents", 0), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if tt.err != nil {
+				got = tt.err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarkWindow is how many events a transport benchmark keeps in flight,
+// below the buffer of a Stream client so none is dropped
+const benchmarkWindow = streamBufferSize / 2
+
+// transport starts a transport delivering the events published to handler,
+// and returns its publisher
+type transport func(b *testing.B, handler Handler) Publisher
+
+// memoryTransport is the Bus, delivering synchronously in process
+func memoryTransport(b *testing.B, handler Handler) Publisher {
+	bus := NewBus()
+	bus.Subscribe(handler)
+	return bus
+}
+
+// sseTransport is a Stream served over loopback HTTP and followed by a
+// Subscriber, as between the services
+func sseTransport(b *testing.B, handler Handler) Publisher {
+	bus := NewBus()
+	stream := NewStream(bus)
+	server := httptest.NewServer(stream)
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(func() {
+		cancel()
+		stream.Close()
+		server.Close()
+	})
+
+	connected := make(chan struct{})
+	var once sync.Once
+	subscriber := &Subscriber{URL: server.URL, RetryDelay: time.Millisecond}
+	go subscriber.Run(ctx, func(ctx context.Context, event Event) error {
+		if event.Type == "benchmark.ready" {
+			once.Do(func() { close(connected) })
+			return nil
+		}
+		return handler(ctx, event)
+	})
+	// Events published before the subscriber connects are missed
+	for ready := false; !ready; {
+		bus.Publish(ctx, Event{ID: "ready", Type: "benchmark.ready"})
+		select {
+		case <-connected:
+			ready = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return bus
+}
+
+// BenchmarkTransports publishes events through each transport to one
+// consumer, benchmarkWindow at a time, and reports the throughput and the
+// latency from publication to handling. Compare runs with benchstat:
+//
+//	go test -run '^$' -bench Transports -count 10 ./events | tee new.txt
+//	benchstat old.txt new.txt
+func BenchmarkTransports(b *testing.B) {
+	transports := []struct {
+		name  string
+		start transport
+	}{
+		{"memory", memoryTransport},
+		{"sse", sseTransport},
+	}
+	for _, tt := range transports {
+		b.Run(tt.name, func(b *testing.B) {
+			benchmarkTransport(b, tt.start)
+		})
+	}
+}
+
+// benchmarkTransport measures the transport started by start
+func benchmarkTransport(b *testing.B, start transport) {
+	window := make(chan struct{}, benchmarkWindow)
+	var mutex sync.Mutex
+	var latencies []time.Duration
+	publisher := start(b, func(_ context.Context, event Event) error {
+		latency := time.Since(event.Time)
+		mutex.Lock()
+		latencies = append(latencies, latency)
+		mutex.Unlock()
+		<-window
+		return nil
+	})
+	event, err := New("", "benchmark.published", "benchmark", "subject", map[string]string{"payload": "0123456789abcdef"})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	started := time.Now()
+	for i := 0; b.Loop(); i++ {
+		window <- struct{}{}
+		event.ID = fmt.Sprint(i)
+		event.Time = time.Now()
+		if err := publisher.Publish(ctx, event); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// Wait for the events in flight
+	for range benchmarkWindow {
+		window <- struct{}{}
+	}
+	elapsed := time.Since(started)
+	b.StopTimer()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	reportLatencies(b, latencies, elapsed)
+}
+
+// reportLatencies reports the throughput of the handled events and their
+// median and 99th percentile latencies
+func reportLatencies(b *testing.B, latencies []time.Duration, elapsed time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	percentile := func(p int) time.Duration {
+		return latencies[(len(latencies)-1)*p/100]
+	}
+	b.ReportMetric(float64(len(latencies))/elapsed.Seconds(), "events/s")
+	b.ReportMetric(float64(percentile(50).Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(percentile(99).Nanoseconds()), "p99-ns")
+}
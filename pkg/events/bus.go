@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Bus is an in-memory publish/subscribe implementation of Publisher.
+type Bus struct {
+	mutex         sync.RWMutex
+	subscriptions map[int]subscription
+	nextID        int
+}
+
+// subscription is a handler of the events matching its patterns.
+type subscription struct {
+	patterns []string
+	handler  Handler
+}
+
+// NewBus creates a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{subscriptions: make(map[int]subscription)}
+}
+
+// Subscribe registers handler for the events whose type matches one of
+// patterns, see Match, or every event without patterns. It returns a function
+// removing the subscription.
+func (b *Bus) Subscribe(handler Handler, patterns ...string) (unsubscribe func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscriptions[id] = subscription{patterns: patterns, handler: handler}
+
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscriptions, id)
+	}
+}
+
+// Publish delivers the event synchronously to every matching subscriber. A
+// failing subscriber is logged and does not prevent delivery to the others.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mutex.RLock()
+	handlers := make([]Handler, 0, len(b.subscriptions))
+	for _, s := range b.subscriptions {
+		if MatchAny(s.patterns, event.Type) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "Event handler failed", "event_type", event.Type, "event_id", event.ID, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var all, users []string
+	unsubscribe := bus.Subscribe(func(_ context.Context, event Event) error {
+		all = append(all, event.Type)
+		return nil
+	})
+	bus.Subscribe(func(_ context.Context, event Event) error {
+		users = append(users, event.Type)
+		return nil
+	}, "user.*")
+	// A failing subscriber does not stop delivery to the others
+	bus.Subscribe(func(context.Context, Event) error {
+		return errors.New("broken")
+	})
+
+	ctx := context.Background()
+	for _, eventType := range []string{"user.created", "order.created"} {
+		if err := bus.Publish(ctx, Event{ID: eventType, Type: eventType}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	unsubscribe()
+	bus.Publish(ctx, Event{Type: "user.deleted"})
+
+	if len(all) != 2 || all[0] != "user.created" || all[1] != "order.created" {
+		t.Errorf("all = %v, want the events published before unsubscribing", all)
+	}
+	if len(users) != 2 || users[0] != "user.created" || users[1] != "user.deleted" {
+		t.Errorf("users = %v, want the user events only", users)
+	}
+}
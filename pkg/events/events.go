@@ -0,0 +1,92 @@
+// Package events carries domain events between services: an event envelope,
+// an in-memory bus, and a server-sent events stream to publish a bus over
+// HTTP along with the client following it.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is the envelope of a domain event. Its metadata follows the
+// CloudEvents attributes (id, type, source, subject, time), extended with the
+// tenant the event belongs to and, for changes made by a request, the
+// subject who made it and the request ID. It is shared by every service.
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Source        string          `json:"source"`
+	Subject       string          `json:"subject"`
+	Time          time.Time       `json:"time"`
+	SchemaVersion string          `json:"schema_version"`
+	Tenant        string          `json:"tenant,omitempty"`
+	Actor         string          `json:"actor,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// New creates an event of source about subject, carrying data encoded as
+// JSON, with the given ID and the current time.
+func New(id, eventType, source, subject string, data interface{}) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("events: encoding %s data: %w", eventType, err)
+	}
+	return Event{
+		ID:            id,
+		Type:          eventType,
+		Source:        source,
+		Subject:       subject,
+		Time:          time.Now().UTC(),
+		SchemaVersion: "1.0.0",
+		Data:          encoded,
+	}, nil
+}
+
+// Decode decodes the data of the event into dst.
+func (e Event) Decode(dst interface{}) error {
+	if err := json.Unmarshal(e.Data, dst); err != nil {
+		return fmt.Errorf("events: decoding %s data: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Handler processes an event.
+type Handler func(ctx context.Context, event Event) error
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Subscribable delivers events to subscribed handlers, like a Bus.
+type Subscribable interface {
+	Subscribe(handler Handler, patterns ...string) (unsubscribe func())
+}
+
+// Match reports whether the event type matches pattern. Patterns are an
+// exact type, such as user.created, a prefix followed by *, such as user.*,
+// or * for every type.
+func Match(pattern, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
+
+// MatchAny reports whether the event type matches one of patterns, or
+// whether patterns is empty.
+func MatchAny(patterns []string, eventType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if Match(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestNewAndDecode(t *testing.T) {
+	event, err := New("e1", "order.created", "orders", "o1", map[string]int{"total": 42})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if event.ID != "e1" || event.Type != "order.created" || event.Source != "orders" || event.Subject != "o1" {
+		t.Errorf("New() = %+v, want the given attributes", event)
+	}
+	if event.Time.IsZero() || event.SchemaVersion != "1.0.0" {
+		t.Errorf("New() time = %v, schema version = %q, want the current time and 1.0.0", event.Time, event.SchemaVersion)
+	}
+
+	var data struct {
+		Total int `json:"total"`
+	}
+	if err := event.Decode(&data); err != nil || data.Total != 42 {
+		t.Errorf("Decode() = %+v, %v, want total 42", data, err)
+	}
+	if err := event.Decode(&[]string{}); err == nil {
+		t.Error("Decode() error = nil, want an error for the wrong type")
+	}
+
+	if _, err := New("e2", "order.created", "orders", "o1", make(chan int)); err == nil {
+		t.Error("New() error = nil, want an error for data that cannot be encoded")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		eventType string
+		want      bool
+	}{
+		{"user.created", "user.created", true},
+		{"user.created", "user.deleted", false},
+		{"user.*", "user.deleted", true},
+		{"user.*", "order.created", false},
+		{"*", "order.created", true},
+		{"user", "user.created", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.eventType); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.eventType, got, tt.want)
+		}
+	}
+
+	if !MatchAny(nil, "user.created") {
+		t.Error("MatchAny(nil) = false, want true")
+	}
+	if !MatchAny([]string{"order.*", "user.created"}, "user.created") || MatchAny([]string{"order.*"}, "user.created") {
+		t.Error("MatchAny() should match any of the patterns")
+	}
+}
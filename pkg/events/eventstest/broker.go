@@ -0,0 +1,169 @@
+// Package eventstest provides a broker for tests that misbehaves on demand,
+// the way real brokers do: it delays, duplicates, reorders, drops or refuses
+// the events it is scripted to, and delivers the others like events.Bus.
+package eventstest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ErrPublishFailed is returned by Publish for the events scripted to Fail.
+var ErrPublishFailed = errors.New("eventstest: publish failed")
+
+// Fault is what the broker does with a published event.
+type Fault struct {
+	kind  faultKind
+	delay time.Duration
+}
+
+type faultKind int
+
+const (
+	deliver faultKind = iota
+	drop
+	fail
+	duplicate
+	hold
+	delay
+)
+
+var (
+	// Deliver delivers the event once, right away.
+	Deliver = Fault{kind: deliver}
+	// Drop accepts the event and loses it: Publish succeeds and no
+	// subscriber hears of it.
+	Drop = Fault{kind: drop}
+	// Fail refuses the event: Publish returns ErrPublishFailed.
+	Fail = Fault{kind: fail}
+	// Duplicate delivers the event twice.
+	Duplicate = Fault{kind: duplicate}
+	// Hold keeps the event until Release, so the events published meanwhile
+	// overtake it.
+	Hold = Fault{kind: hold}
+)
+
+// Delay delivers the event in the background after d, so the events
+// published meanwhile overtake it. Wait waits for the delayed deliveries.
+func Delay(d time.Duration) Fault {
+	return Fault{kind: delay, delay: d}
+}
+
+// step is a scripted fault for the next event matching pattern.
+type step struct {
+	pattern string
+	fault   Fault
+}
+
+// Broker is an in-memory implementation of events.Publisher whose faults
+// are scripted by the test. Events without a scripted fault are delivered
+// synchronously to the matching subscribers.
+type Broker struct {
+	bus *events.Bus
+
+	mutex     sync.Mutex
+	script    []step
+	held      []events.Event
+	delivered []events.Event
+	pending   sync.WaitGroup
+}
+
+// NewBroker creates a broker without subscribers nor scripted faults.
+func NewBroker() *Broker {
+	return &Broker{bus: events.NewBus()}
+}
+
+// Subscribe registers handler for the events whose type matches one of
+// patterns, like events.Bus.Subscribe.
+func (b *Broker) Subscribe(handler events.Handler, patterns ...string) (unsubscribe func()) {
+	return b.bus.Subscribe(handler, patterns...)
+}
+
+// Script applies faults, in order, to the next events whose type matches
+// pattern, see events.Match. Scripted faults add up: each event takes the
+// first fault whose pattern it matches.
+func (b *Broker) Script(pattern string, faults ...Fault) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, fault := range faults {
+		b.script = append(b.script, step{pattern: pattern, fault: fault})
+	}
+}
+
+// Publish applies the next fault scripted for the event, if any.
+func (b *Broker) Publish(ctx context.Context, event events.Event) error {
+	switch fault := b.next(event.Type); fault.kind {
+	case drop:
+	case fail:
+		return ErrPublishFailed
+	case duplicate:
+		b.deliver(ctx, event)
+		b.deliver(ctx, event)
+	case hold:
+		b.mutex.Lock()
+		b.held = append(b.held, event)
+		b.mutex.Unlock()
+	case delay:
+		b.pending.Add(1)
+		go func() {
+			defer b.pending.Done()
+			time.Sleep(fault.delay)
+			b.deliver(context.WithoutCancel(ctx), event)
+		}()
+	default:
+		b.deliver(ctx, event)
+	}
+	return nil
+}
+
+// Release delivers the held events, oldest first, and returns how many.
+func (b *Broker) Release(ctx context.Context) int {
+	b.mutex.Lock()
+	held := b.held
+	b.held = nil
+	b.mutex.Unlock()
+
+	for _, event := range held {
+		b.deliver(ctx, event)
+	}
+	return len(held)
+}
+
+// Wait waits for the delayed deliveries to be done.
+func (b *Broker) Wait() {
+	b.pending.Wait()
+}
+
+// Delivered returns the events delivered so far, in delivery order, each
+// duplicate included.
+func (b *Broker) Delivered() []events.Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]events.Event(nil), b.delivered...)
+}
+
+// next removes and returns the first fault scripted for eventType, or
+// Deliver.
+func (b *Broker) next(eventType string) Fault {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, s := range b.script {
+		if events.Match(s.pattern, eventType) {
+			b.script = append(b.script[:i], b.script[i+1:]...)
+			return s.fault
+		}
+	}
+	return Deliver
+}
+
+// deliver records the event and publishes it to the subscribers.
+func (b *Broker) deliver(ctx context.Context, event events.Event) {
+	b.mutex.Lock()
+	b.delivered = append(b.delivered, event)
+	b.mutex.Unlock()
+	b.bus.Publish(ctx, event)
+}
@@ -0,0 +1,93 @@
+package eventstest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// ids returns the IDs of events, joined by spaces
+func ids(list []events.Event) string {
+	var names []string
+	for _, event := range list {
+		names = append(names, event.ID)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestBroker(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    func(b *Broker)
+		wantErrs  int
+		wantFirst string
+		want      string
+	}{
+		{"deliver", func(*Broker) {}, 0, "1 2 3", "1 2 3"},
+		{"drop", func(b *Broker) { b.Script("*", Deliver, Drop) }, 0, "1 3", "1 3"},
+		{"fail", func(b *Broker) { b.Script("*", Fail) }, 1, "2 3", "2 3"},
+		{"duplicate", func(b *Broker) { b.Script("*", Duplicate) }, 0, "1 1 2 3", "1 1 2 3"},
+		{"hold", func(b *Broker) { b.Script("*", Hold) }, 0, "2 3", "2 3 1"},
+		{"delay", func(b *Broker) { b.Script("*", Delay(10*time.Millisecond)) }, 0, "2 3", "2 3 1"},
+		{"pattern", func(b *Broker) { b.Script("order.*", Drop) }, 0, "1 2", "1 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			broker := NewBroker()
+			var mutex sync.Mutex
+			var received []events.Event
+			broker.Subscribe(func(_ context.Context, event events.Event) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				received = append(received, event)
+				return nil
+			})
+			tt.script(broker)
+
+			errs := 0
+			for i, eventType := range []string{"user.created", "user.updated", "order.placed"} {
+				if err := broker.Publish(ctx, events.Event{ID: string(rune('1' + i)), Type: eventType}); errors.Is(err, ErrPublishFailed) {
+					errs++
+				}
+			}
+			if errs != tt.wantErrs {
+				t.Errorf("got %d failed publishes want %d", errs, tt.wantErrs)
+			}
+			mutex.Lock()
+			first := ids(received)
+			mutex.Unlock()
+			if first != tt.wantFirst {
+				t.Errorf("got %q delivered right away want %q", first, tt.wantFirst)
+			}
+
+			broker.Release(ctx)
+			broker.Wait()
+			if got := ids(received); got != tt.want {
+				t.Errorf("got %q received want %q", got, tt.want)
+			}
+			if got := ids(broker.Delivered()); got != tt.want {
+				t.Errorf("got %q delivered want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBroker_ScriptIsConsumed(t *testing.T) {
+	broker := NewBroker()
+	broker.Script("user.*", Fail, Fail)
+	ctx := context.Background()
+	for i, wantErr := range []bool{true, true, false} {
+		if err := broker.Publish(ctx, events.Event{Type: "user.created"}); (err != nil) != wantErr {
+			t.Errorf("publish %d got error %v want error %v", i, err, wantErr)
+		}
+	}
+	if got := broker.Release(ctx); got != 0 {
+		t.Errorf("got %d released want 0 without held events", got)
+	}
+}
@@ -0,0 +1,244 @@
+package eventstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Source holds recorded events, such as a Recorder.
+type Source interface {
+	Events() []events.Event
+}
+
+// T is the part of testing.TB that expectations report failures to.
+type T interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Expectation asserts on the events a source recorded when it was created.
+// Failed assertions are reported with t.Errorf, so a test checks every
+// expectation and reads as a list of them:
+//
+//	eventstest.Expect(t, recorder).ToHave("user.created").WithPayloadField("user.email", "ada@example.com")
+//	eventstest.Expect(t, recorder).NotToHave("user.deleted")
+type Expectation struct {
+	t      T
+	events []events.Event
+}
+
+// Expect starts assertions on the events recorded by source so far.
+func Expect(t T, source Source) *Expectation {
+	return &Expectation{t: t, events: source.Events()}
+}
+
+// ToHave asserts that an event whose type matches pattern was recorded, see
+// events.Match. The returned assertion narrows down the matching events.
+func (e *Expectation) ToHave(pattern string) *Assertion {
+	e.t.Helper()
+	a := &Assertion{t: e.t, description: "an event of type " + pattern}
+	for _, event := range e.events {
+		if events.Match(pattern, event.Type) {
+			a.matches = append(a.matches, event)
+		}
+	}
+	if len(a.matches) == 0 {
+		a.fail("got %s", describeTypes(e.events))
+	}
+	return a
+}
+
+// NotToHave asserts that no event whose type matches pattern was recorded.
+func (e *Expectation) NotToHave(pattern string) {
+	e.t.Helper()
+	var found []events.Event
+	for _, event := range e.events {
+		if events.Match(pattern, event.Type) {
+			found = append(found, event)
+		}
+	}
+	if len(found) > 0 {
+		e.t.Errorf("eventstest: expected no event of type %s, got %s", pattern, describeTypes(found))
+	}
+}
+
+// ToHaveInOrder asserts that events matching patterns were recorded in
+// this order, other events being allowed in between.
+func (e *Expectation) ToHaveInOrder(patterns ...string) {
+	e.t.Helper()
+	next := 0
+	for _, event := range e.events {
+		if next < len(patterns) && events.Match(patterns[next], event.Type) {
+			next++
+		}
+	}
+	if next < len(patterns) {
+		e.t.Errorf("eventstest: expected events %s in order, got %s", strings.Join(patterns, ", "), describeTypes(e.events))
+	}
+}
+
+// Assertion narrows down the events matching an expectation. Each method
+// keeps the events that satisfy it and fails the test when none is left;
+// after a failure, the following methods do nothing.
+type Assertion struct {
+	t           T
+	description string
+	matches     []events.Event
+	failed      bool
+}
+
+// WithSubject keeps the events about subject, such as a user ID.
+func (a *Assertion) WithSubject(subject string) *Assertion {
+	a.t.Helper()
+	return a.filter(fmt.Sprintf("with subject %q", subject), func(event events.Event) (bool, string) {
+		return event.Subject == subject, fmt.Sprintf("subject is %q", event.Subject)
+	})
+}
+
+// WithSource keeps the events produced by source, such as "user-service".
+func (a *Assertion) WithSource(source string) *Assertion {
+	a.t.Helper()
+	return a.filter(fmt.Sprintf("with source %q", source), func(event events.Event) (bool, string) {
+		return event.Source == source, fmt.Sprintf("source is %q", event.Source)
+	})
+}
+
+// WithPayloadField keeps the events whose data has the field at path equal
+// to want once both are encoded as JSON. Path names nested fields and array
+// indexes with dots, e.g. "order.items.0.sku".
+func (a *Assertion) WithPayloadField(path string, want any) *Assertion {
+	a.t.Helper()
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		a.fail("cannot encode the value of payload field %s: %v", path, err)
+		return a
+	}
+	var wantValue any
+	json.Unmarshal(wantJSON, &wantValue)
+
+	return a.filter(fmt.Sprintf("with payload field %s = %s", path, wantJSON), func(event events.Event) (bool, string) {
+		got, ok := payloadField(event.Data, path)
+		if !ok {
+			return false, path + " is missing"
+		}
+		gotJSON, _ := json.Marshal(got)
+		return reflect.DeepEqual(got, wantValue), fmt.Sprintf("%s is %s", path, gotJSON)
+	})
+}
+
+// Matching keeps the events for which match is true, described by what,
+// e.g. "placed by a VIP customer", in failure messages.
+func (a *Assertion) Matching(what string, match func(events.Event) bool) *Assertion {
+	a.t.Helper()
+	return a.filter(what, func(event events.Event) (bool, string) {
+		return match(event), "does not match"
+	})
+}
+
+// Times asserts that exactly n events are left.
+func (a *Assertion) Times(n int) *Assertion {
+	a.t.Helper()
+	if !a.failed && len(a.matches) != n {
+		a.failed = true
+		a.t.Errorf("eventstest: expected %s %d times, got %d", a.description, n, len(a.matches))
+	}
+	return a
+}
+
+// Once asserts that exactly one event is left.
+func (a *Assertion) Once() *Assertion {
+	a.t.Helper()
+	return a.Times(1)
+}
+
+// Event returns the first event left, or the zero event after a failure.
+func (a *Assertion) Event() events.Event {
+	if a.failed || len(a.matches) == 0 {
+		return events.Event{}
+	}
+	return a.matches[0]
+}
+
+// Events returns the events left, in the order they were recorded.
+func (a *Assertion) Events() []events.Event {
+	if a.failed {
+		return nil
+	}
+	return append([]events.Event(nil), a.matches...)
+}
+
+// filter keeps the events that satisfy check, which explains why an event
+// does not, and fails listing the explanations when none is left
+func (a *Assertion) filter(description string, check func(events.Event) (bool, string)) *Assertion {
+	a.t.Helper()
+	if a.failed {
+		return a
+	}
+	a.description += " " + description
+	var kept []events.Event
+	var reasons []string
+	for _, event := range a.matches {
+		ok, reason := check(event)
+		if ok {
+			kept = append(kept, event)
+		} else {
+			reasons = append(reasons, fmt.Sprintf("\n\t%s %s: %s", event.Type, event.ID, reason))
+		}
+	}
+	a.matches = kept
+	if len(kept) == 0 {
+		a.fail("got%s", strings.Join(reasons, ""))
+	}
+	return a
+}
+
+// fail reports that the expected events were not found
+func (a *Assertion) fail(format string, args ...any) {
+	a.t.Helper()
+	a.failed = true
+	a.t.Errorf("eventstest: expected %s, %s", a.description, fmt.Sprintf(format, args...))
+}
+
+// payloadField returns the value at path in the JSON data
+func payloadField(data json.RawMessage, path string) (any, bool) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, false
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			value = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// describeTypes lists the types of recorded, for failure messages
+func describeTypes(recorded []events.Event) string {
+	if len(recorded) == 0 {
+		return "no events"
+	}
+	types := make([]string, len(recorded))
+	for i, event := range recorded {
+		types[i] = event.Type
+	}
+	return strings.Join(types, ", ")
+}
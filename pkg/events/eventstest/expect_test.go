@@ -0,0 +1,139 @@
+package eventstest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// fakeT records the failures reported to it
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// recorded returns a recorder holding a user.created, an order.placed and
+// a user.deleted event
+func recorded(t *testing.T) *Recorder {
+	t.Helper()
+	recorder := NewRecorder()
+	bus := events.NewBus()
+	bus.Subscribe(recorder.Handle)
+	for i, e := range []struct {
+		eventType, subject string
+		data               any
+	}{
+		{"user.created", "1", map[string]any{"user": map[string]any{"id": "1", "email": "ada@example.com", "roles": []string{"viewer"}}}},
+		{"order.placed", "7", map[string]any{"order": map[string]any{"id": "7", "total_cents": 3000, "items": []map[string]any{{"sku": "BOOK-1"}}}}},
+		{"user.deleted", "1", map[string]any{"user": map[string]any{"id": "1", "email": "ada@example.com"}}},
+	} {
+		event, err := events.New(fmt.Sprint(i+1), e.eventType, "test", e.subject, e.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bus.Publish(context.Background(), event)
+	}
+	return recorder
+}
+
+func TestExpect(t *testing.T) {
+	tests := []struct {
+		name    string
+		expect  func(e *Expectation)
+		wantErr string
+	}{
+		{"type", func(e *Expectation) { e.ToHave("user.created") }, ""},
+		{"pattern", func(e *Expectation) { e.ToHave("order.*").Once() }, ""},
+		{"missing type", func(e *Expectation) { e.ToHave("user.updated") },
+			"expected an event of type user.updated, got user.created, order.placed, user.deleted"},
+		{"payload field", func(e *Expectation) {
+			e.ToHave("user.created").WithPayloadField("user.email", "ada@example.com").WithSubject("1")
+		}, ""},
+		{"nested payload field", func(e *Expectation) {
+			e.ToHave("order.placed").WithPayloadField("order.items.0.sku", "BOOK-1").WithPayloadField("order.total_cents", 3000)
+		}, ""},
+		{"payload array", func(e *Expectation) { e.ToHave("user.created").WithPayloadField("user.roles", []string{"viewer"}) }, ""},
+		{"wrong payload field", func(e *Expectation) { e.ToHave("user.*").WithPayloadField("user.email", "bob@example.com") },
+			`expected an event of type user.* with payload field user.email = "bob@example.com", got
+	user.created 1: user.email is "ada@example.com"
+	user.deleted 3: user.email is "ada@example.com"`},
+		{"missing payload field", func(e *Expectation) { e.ToHave("user.created").WithPayloadField("user.name", "Ada") },
+			`expected an event of type user.created with payload field user.name = "Ada", got
+	user.created 1: user.name is missing`},
+		{"wrong subject", func(e *Expectation) { e.ToHave("order.placed").WithSubject("8") },
+			`expected an event of type order.placed with subject "8", got
+	order.placed 2: subject is "7"`},
+		{"source", func(e *Expectation) { e.ToHave("user.*").WithSource("test").Times(2) }, ""},
+		{"wrong source", func(e *Expectation) { e.ToHave("order.placed").WithSource("orders") },
+			`expected an event of type order.placed with source "orders", got
+	order.placed 2: source is "test"`},
+		{"matching", func(e *Expectation) {
+			e.ToHave("*").Matching("from test", func(event events.Event) bool { return event.Source == "test" }).Times(3)
+		}, ""},
+		{"times", func(e *Expectation) { e.ToHave("user.*").WithSubject("1").Once() },
+			`expected an event of type user.* with subject "1" 1 times, got 2`},
+		{"only the first failure", func(e *Expectation) { e.ToHave("user.updated").WithSubject("1").Once() },
+			"expected an event of type user.updated, got user.created, order.placed, user.deleted"},
+		{"not to have", func(e *Expectation) { e.NotToHave("user.updated") }, ""},
+		{"not to have present", func(e *Expectation) { e.NotToHave("user.*") },
+			"expected no event of type user.*, got user.created, user.deleted"},
+		{"in order", func(e *Expectation) { e.ToHaveInOrder("user.created", "user.deleted") }, ""},
+		{"out of order", func(e *Expectation) { e.ToHaveInOrder("user.deleted", "order.placed") },
+			"expected events user.deleted, order.placed in order, got user.created, order.placed, user.deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeT{}
+			tt.expect(Expect(fake, recorded(t)))
+
+			got := strings.Join(fake.errors, "\n")
+			want := ""
+			if tt.wantErr != "" {
+				want = "eventstest: " + tt.wantErr
+			}
+			if got != want {
+				t.Errorf("Expect() errors = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAssertion_Event(t *testing.T) {
+	recorder := recorded(t)
+
+	event := Expect(t, recorder).ToHave("user.*").WithSubject("1").Event()
+	if event.ID != "1" {
+		t.Errorf("Event().ID = %q, want %q", event.ID, "1")
+	}
+	if got := Expect(t, recorder).ToHave("user.*").Events(); len(got) != 2 {
+		t.Errorf("len(Events()) = %d, want 2", len(got))
+	}
+
+	fake := &fakeT{}
+	if event := Expect(fake, recorder).ToHave("user.updated").Event(); event.ID != "" {
+		t.Errorf("Event().ID = %q after a failure, want none", event.ID)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	var publisher events.Publisher = recorder
+	publisher.Publish(context.Background(), events.Event{ID: "1", Type: "user.created"})
+	recorder.Handle(context.Background(), events.Event{ID: "2", Type: "user.updated"})
+	if got := ids(recorder.Events()); got != "1 2" {
+		t.Errorf("Events() = %q, want %q", got, "1 2")
+	}
+
+	recorder.Reset()
+	if got := recorder.Events(); len(got) != 0 {
+		t.Errorf("Events() = %v after Reset, want none", got)
+	}
+}
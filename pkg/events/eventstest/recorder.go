@@ -0,0 +1,49 @@
+package eventstest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// Recorder records events for assertions with Expect. It is an
+// events.Publisher for the code under test to publish to, and its Handle
+// method subscribes it to a bus or broker to record what they deliver.
+type Recorder struct {
+	mutex  sync.Mutex
+	events []events.Event
+}
+
+// NewRecorder creates a recorder without events.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Publish records event.
+func (r *Recorder) Publish(_ context.Context, event events.Event) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// Handle records event, so the recorder can subscribe to a bus:
+// bus.Subscribe(recorder.Handle).
+func (r *Recorder) Handle(ctx context.Context, event events.Event) error {
+	return r.Publish(ctx, event)
+}
+
+// Events returns the events recorded so far, in the order they were recorded.
+func (r *Recorder) Events() []events.Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+// Reset forgets the events recorded so far.
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = nil
+}
@@ -0,0 +1,49 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Failure is a scenario failing under a seed, with the trace to reproduce
+// it: running the scenario with the same Config gives the same trace.
+type Failure struct {
+	Seed  uint64
+	Trace []Step
+	Err   error
+}
+
+// Error reports the seed, the error and the trace.
+func (f *Failure) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "simulation: seed %d: %v", f.Seed, f.Err)
+	for _, step := range f.Trace {
+		b.WriteString("\n\t")
+		b.WriteString(step.String())
+	}
+	return b.String()
+}
+
+// Unwrap returns the error of the scenario.
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Explore runs scenario in a simulation of config for each of the seeds
+// config.Seed to config.Seed+seeds-1 and returns a *Failure for the first
+// seed whose scenario returns an error. The scenario sets up its publishers
+// and consumers, runs the simulation and checks the outcome.
+func Explore(config Config, seeds int, scenario func(s *Simulation) error) error {
+	first := config.Seed
+	for i := range seeds {
+		config.Seed = first + uint64(i)
+		s, err := New(config)
+		if err != nil {
+			return err
+		}
+		if err := scenario(s); err != nil {
+			return &Failure{Seed: config.Seed, Trace: s.Trace(), Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+// Package simulation runs publishers and consumers of events on a virtual
+// clock, one step at a time, so that the interleavings behind race
+// conditions are reproduced exactly.
+//
+// A simulation is an events.Publisher. Each event it is published is
+// delivered to every matching subscriber after a latency drawn from a
+// seeded random source, and possibly duplicated, so events overtake each
+// other the way they do on a real broker. Publishers are tasks scheduled on
+// the virtual clock. Run executes the tasks and deliveries in the order of
+// their virtual time, those due at the same time in an order drawn from the
+// seed, in the calling goroutine: the same seed runs the same steps in the
+// same order every time, and Explore runs a scenario over many seeds and
+// reports the first failing one with its trace.
+//
+// The code under test must take its time from Now and its randomness from
+// Rand, and publish from tasks and handlers only, not from goroutines of its
+// own.
+package simulation
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// DefaultStart is the virtual time a simulation starts at, unless
+// configured otherwise.
+var DefaultStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultMaxSteps bounds the steps of a run, unless configured otherwise.
+const DefaultMaxSteps = 100_000
+
+// ErrTooManySteps is returned by Run when the simulation does not settle
+// within the maximum number of steps, e.g. because handlers publish events
+// to each other forever.
+var ErrTooManySteps = errors.New("simulation: too many steps")
+
+// Config sets up a simulation. Every random choice is drawn from Seed.
+type Config struct {
+	Seed uint64
+	// Start is the virtual time the simulation starts at, DefaultStart by
+	// default.
+	Start time.Time
+	// Events are delivered after a latency between MinLatency and
+	// MaxLatency, drawn for every delivery.
+	MinLatency time.Duration
+	MaxLatency time.Duration
+	// DuplicateRate is the probability that a delivery is repeated, as
+	// brokers delivering at least once do.
+	DuplicateRate float64
+	// MaxSteps bounds the steps of a run, DefaultMaxSteps by default.
+	MaxSteps int
+}
+
+// Validate checks that latencies are ordered and the rate is a probability.
+func (c Config) Validate() error {
+	if c.MinLatency < 0 || c.MaxLatency < c.MinLatency {
+		return fmt.Errorf("simulation: latency must be between 0 and max latency, got %v to %v", c.MinLatency, c.MaxLatency)
+	}
+	if c.DuplicateRate < 0 || c.DuplicateRate > 1 {
+		return fmt.Errorf("simulation: duplicate rate must be between 0 and 1, got %v", c.DuplicateRate)
+	}
+	if c.MaxSteps < 0 {
+		return fmt.Errorf("simulation: max steps must not be negative, got %d", c.MaxSteps)
+	}
+	return nil
+}
+
+// Step is a task or a delivery run by a simulation.
+type Step struct {
+	// Elapsed is the virtual time of the step since the start.
+	Elapsed time.Duration
+	// Name is the name of the task or of the subscriber.
+	Name string
+	// Event is the delivered event, the zero event for tasks.
+	Event events.Event
+	// Err is the error returned by the task or the handler.
+	Err error
+}
+
+// String describes the step, such as "+12ms search <- user.updated evt-2".
+func (s Step) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "+%v %s", s.Elapsed, s.Name)
+	if s.Event.Type != "" {
+		fmt.Fprintf(&b, " <- %s %s", s.Event.Type, s.Event.ID)
+	}
+	if s.Err != nil {
+		fmt.Fprintf(&b, ": %v", s.Err)
+	}
+	return b.String()
+}
+
+// subscriber is a consumer of the events matching patterns
+type subscriber struct {
+	name     string
+	handler  events.Handler
+	patterns []string
+}
+
+// Simulation runs tasks and event deliveries on a virtual clock.
+type Simulation struct {
+	config Config
+
+	mutex       sync.Mutex
+	random      *rand.Rand
+	now         time.Time
+	queue       queue
+	scheduled   int
+	subscribers []subscriber
+	trace       []Step
+}
+
+// New creates a simulation of config, at its start time and without tasks.
+func New(config Config) (*Simulation, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Start.IsZero() {
+		config.Start = DefaultStart
+	}
+	if config.MaxSteps == 0 {
+		config.MaxSteps = DefaultMaxSteps
+	}
+	return &Simulation{
+		config: config,
+		random: rand.New(rand.NewPCG(config.Seed, config.Seed)),
+		now:    config.Start,
+	}, nil
+}
+
+// Seed returns the seed of the simulation.
+func (s *Simulation) Seed() uint64 {
+	return s.config.Seed
+}
+
+// Now returns the virtual time. Services take it as their clock, e.g.
+// with an option such as WithClock(s.Now).
+func (s *Simulation) Now() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.now
+}
+
+// Rand returns the random source of the simulation, for the code under test
+// to draw from within tasks and handlers.
+func (s *Simulation) Rand() *rand.Rand {
+	return s.random
+}
+
+// Subscribe registers handler, named name in the trace, for the events whose
+// type matches one of patterns, or every event without patterns.
+func (s *Simulation) Subscribe(name string, handler events.Handler, patterns ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.subscribers = append(s.subscribers, subscriber{name: name, handler: handler, patterns: patterns})
+}
+
+// Publish schedules the delivery of event to each matching subscriber,
+// after a random latency and possibly twice.
+func (s *Simulation) Publish(_ context.Context, event events.Event) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, sub := range s.subscribers {
+		if !events.MatchAny(sub.patterns, event.Type) {
+			continue
+		}
+		deliveries := 1
+		if s.config.DuplicateRate > 0 && s.random.Float64() < s.config.DuplicateRate {
+			deliveries = 2
+		}
+		for range deliveries {
+			s.schedule(s.latency(), sub.name, event, sub.handler)
+		}
+	}
+	return nil
+}
+
+// After schedules task, named name in the trace, to run after d of virtual
+// time. Publishers are tasks, and tasks may schedule other tasks, e.g. to
+// retry or to time out.
+func (s *Simulation) After(d time.Duration, name string, task func(ctx context.Context) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.schedule(d, name, events.Event{}, func(ctx context.Context, _ events.Event) error {
+		return task(ctx)
+	})
+}
+
+// Run runs the scheduled tasks and deliveries until none is left, moving
+// the virtual clock to the time of each.
+func (s *Simulation) Run(ctx context.Context) error {
+	return s.run(ctx, time.Time{})
+}
+
+// RunFor runs the tasks and deliveries due within d of virtual time, then
+// moves the virtual clock to the end of d.
+func (s *Simulation) RunFor(ctx context.Context, d time.Duration) error {
+	until := s.Now().Add(d)
+	if err := s.run(ctx, until); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.now = until
+	s.mutex.Unlock()
+	return nil
+}
+
+// Trace returns the steps run so far, in order.
+func (s *Simulation) Trace() []Step {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Step(nil), s.trace...)
+}
+
+// run runs the steps due until until, or every step when until is zero
+func (s *Simulation) run(ctx context.Context, until time.Time) error {
+	for steps := 0; ; steps++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.mutex.Lock()
+		if s.queue.Len() == 0 || (!until.IsZero() && s.queue[0].at.After(until)) {
+			s.mutex.Unlock()
+			return nil
+		}
+		if steps == s.config.MaxSteps {
+			s.mutex.Unlock()
+			return fmt.Errorf("%w: seed %d ran %d steps", ErrTooManySteps, s.config.Seed, steps)
+		}
+		next := heap.Pop(&s.queue).(*task)
+		s.now = next.at
+		s.mutex.Unlock()
+
+		err := next.handler(ctx, next.event)
+
+		s.mutex.Lock()
+		s.trace = append(s.trace, Step{Elapsed: next.at.Sub(s.config.Start), Name: next.name, Event: next.event, Err: err})
+		s.mutex.Unlock()
+	}
+}
+
+// schedule queues handler to run on event after d. Tasks due at the same
+// time run in the order of a number drawn from the seed. The caller holds
+// the mutex.
+func (s *Simulation) schedule(d time.Duration, name string, event events.Event, handler events.Handler) {
+	s.scheduled++
+	heap.Push(&s.queue, &task{
+		at:       s.now.Add(d),
+		tiebreak: s.random.Uint64(),
+		sequence: s.scheduled,
+		name:     name,
+		event:    event,
+		handler:  handler,
+	})
+}
+
+// latency draws the latency of a delivery. The caller holds the mutex.
+func (s *Simulation) latency() time.Duration {
+	spread := s.config.MaxLatency - s.config.MinLatency
+	if spread == 0 {
+		return s.config.MinLatency
+	}
+	return s.config.MinLatency + time.Duration(s.random.Int64N(int64(spread)+1))
+}
+
+// task is a scheduled task or delivery
+type task struct {
+	at       time.Time
+	tiebreak uint64
+	sequence int
+	name     string
+	event    events.Event
+	handler  events.Handler
+}
+
+// queue is a heap of tasks, the next one due first
+type queue []*task
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	if q[i].tiebreak != q[j].tiebreak {
+		return q[i].tiebreak < q[j].tiebreak
+	}
+	return q[i].sequence < q[j].sequence
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x any) { *q = append(*q, x.(*task)) }
+
+func (q *queue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
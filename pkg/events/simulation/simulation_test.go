@@ -0,0 +1,238 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/captain-corgi/learning-event-driven/pkg/events"
+)
+
+// newSimulation creates a simulation of config or fails the test
+func newSimulation(t *testing.T, config Config) *Simulation {
+	t.Helper()
+	s, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// versionEvent creates a user.updated event of version n
+func versionEvent(n int) events.Event {
+	event, _ := events.New(fmt.Sprintf("evt-%d", n), "user.updated", "test", "ada", map[string]int{"version": n})
+	return event
+}
+
+// publishVersions schedules a publisher of versions 1 to n, one per millisecond
+func publishVersions(s *Simulation, n int) {
+	for i := 1; i <= n; i++ {
+		s.After(time.Duration(i)*time.Millisecond, "publisher", func(ctx context.Context) error {
+			return s.Publish(ctx, versionEvent(i))
+		})
+	}
+}
+
+// traceOf describes the trace of s on one line per step
+func traceOf(s *Simulation) string {
+	var lines []string
+	for _, step := range s.Trace() {
+		lines = append(lines, step.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"negative latency", Config{MinLatency: -time.Second}},
+		{"max below min", Config{MinLatency: time.Second, MaxLatency: time.Millisecond}},
+		{"duplicate rate above 1", Config{DuplicateRate: 1.5}},
+		{"negative max steps", Config{MaxSteps: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Error("got nil error want a validation error")
+			}
+		})
+	}
+}
+
+func TestSimulation_SameSeedSameTrace(t *testing.T) {
+	run := func(seed uint64) string {
+		s := newSimulation(t, Config{Seed: seed, MaxLatency: 20 * time.Millisecond, DuplicateRate: 0.2})
+		for _, name := range []string{"orders", "search"} {
+			s.Subscribe(name, func(context.Context, events.Event) error { return nil })
+		}
+		publishVersions(s, 10)
+		if err := s.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return traceOf(s)
+	}
+
+	if first, second := run(7), run(7); first != second {
+		t.Errorf("seed 7 ran\n%s\nthen\n%s", first, second)
+	}
+	traces := make(map[string]bool)
+	for seed := range uint64(10) {
+		traces[run(seed)] = true
+	}
+	if len(traces) < 2 {
+		t.Error("got the same trace for 10 seeds want the seed to change the interleaving")
+	}
+}
+
+func TestSimulation_VirtualClock(t *testing.T) {
+	s := newSimulation(t, Config{MinLatency: 5 * time.Millisecond, MaxLatency: 5 * time.Millisecond})
+	var handledAt, ranAt time.Time
+	s.Subscribe("consumer", func(context.Context, events.Event) error {
+		handledAt = s.Now()
+		return nil
+	})
+	s.After(time.Hour, "publisher", func(ctx context.Context) error {
+		ranAt = s.Now()
+		return s.Publish(ctx, versionEvent(1))
+	})
+
+	started := time.Now()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Minute {
+		t.Errorf("Run() took %v of real time", elapsed)
+	}
+	if want := DefaultStart.Add(time.Hour); !ranAt.Equal(want) {
+		t.Errorf("got task at %v want %v", ranAt, want)
+	}
+	if want := DefaultStart.Add(time.Hour + 5*time.Millisecond); !handledAt.Equal(want) || !s.Now().Equal(want) {
+		t.Errorf("got event handled at %v, clock at %v want %v", handledAt, s.Now(), want)
+	}
+}
+
+func TestSimulation_RunFor(t *testing.T) {
+	s := newSimulation(t, Config{})
+	var ran []string
+	for _, d := range []time.Duration{time.Second, 3 * time.Second} {
+		s.After(d, d.String(), func(context.Context) error {
+			ran = append(ran, d.String())
+			return nil
+		})
+	}
+
+	if err := s.RunFor(context.Background(), 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ran, []string{"1s"}) || !s.Now().Equal(DefaultStart.Add(2*time.Second)) {
+		t.Errorf("got %v ran, clock at %v want [1s] ran, clock at start+2s", ran, s.Now())
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ran, []string{"1s", "3s"}) {
+		t.Errorf("got %v ran want [1s 3s]", ran)
+	}
+}
+
+func TestSimulation_Subscribe(t *testing.T) {
+	s := newSimulation(t, Config{DuplicateRate: 1})
+	var handled []string
+	s.Subscribe("users", func(_ context.Context, event events.Event) error {
+		handled = append(handled, event.ID)
+		return errors.New("unavailable")
+	}, "user.*")
+	s.Subscribe("orders", func(context.Context, events.Event) error {
+		t.Error("orders heard of a user event")
+		return nil
+	}, "order.*")
+	s.Publish(context.Background(), versionEvent(1))
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(handled, []string{"evt-1", "evt-1"}) {
+		t.Errorf("got %v handled want evt-1 delivered twice", handled)
+	}
+	if got, want := s.Trace()[0].String(), "+0s users <- user.updated evt-1: unavailable"; got != want {
+		t.Errorf("got step %q want %q", got, want)
+	}
+}
+
+func TestSimulation_TooManySteps(t *testing.T) {
+	s := newSimulation(t, Config{MaxSteps: 50})
+	s.Subscribe("echo", func(ctx context.Context, event events.Event) error {
+		return s.Publish(ctx, event)
+	})
+	s.Publish(context.Background(), versionEvent(1))
+
+	if err := s.Run(context.Background()); !errors.Is(err, ErrTooManySteps) {
+		t.Errorf("got %v want %v", err, ErrTooManySteps)
+	}
+	if got := len(s.Trace()); got != 50 {
+		t.Errorf("got %d steps want 50", got)
+	}
+}
+
+func TestExplore(t *testing.T) {
+	config := Config{Seed: 1, MaxLatency: 10 * time.Millisecond}
+
+	// projection keeps the last version it handled, ignoring older ones
+	// when ordered is set
+	projection := func(ordered bool) func(s *Simulation) error {
+		return func(s *Simulation) error {
+			version := 0
+			s.Subscribe("projection", func(_ context.Context, event events.Event) error {
+				var data struct{ Version int }
+				if err := event.Decode(&data); err != nil {
+					return err
+				}
+				if !ordered || data.Version > version {
+					version = data.Version
+				}
+				return nil
+			})
+			publishVersions(s, 5)
+			if err := s.Run(context.Background()); err != nil {
+				return err
+			}
+			if version != 5 {
+				return fmt.Errorf("projection at version %d, want 5", version)
+			}
+			return nil
+		}
+	}
+
+	if err := Explore(config, 100, projection(true)); err != nil {
+		t.Errorf("Explore() = %v, want nil for a projection ignoring stale events", err)
+	}
+
+	err := Explore(config, 100, projection(false))
+	var failure *Failure
+	if !errors.As(err, &failure) {
+		t.Fatalf("got %v want a *Failure for a projection overwritten by stale events", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("seed %d: projection at version", failure.Seed)) {
+		t.Errorf("got %q want the seed and the error", err)
+	}
+
+	// The failing seed reproduces the failure step by step
+	config.Seed = failure.Seed
+	s := newSimulation(t, config)
+	if err := projection(false)(s); err == nil {
+		t.Fatalf("seed %d passed on its own", failure.Seed)
+	}
+	var want []string
+	for _, step := range failure.Trace {
+		want = append(want, step.String())
+	}
+	if got := traceOf(s); got != strings.Join(want, "\n") {
+		t.Errorf("seed %d ran\n%s\nwant\n%s", failure.Seed, got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamBufferSize is how many events a slow stream client may lag behind
+// before events are dropped for it.
+const streamBufferSize = 64
+
+// streamKeepAlive is how often an idle stream sends a comment, so proxies
+// and clients can tell it from a dead connection.
+const streamKeepAlive = 15 * time.Second
+
+// Stream serves the events of a bus as server-sent events. Each
+// event is sent with its ID, its type as the event name and its JSON
+// envelope as data. Clients may filter types with the comma-separated
+// patterns of the types query parameter, e.g. ?types=order.*,user.deleted.
+type Stream struct {
+	bus  Subscribable
+	done chan struct{}
+	once sync.Once
+}
+
+// NewStream creates a stream of the events of bus, a Bus or a type wrapping
+// one.
+func NewStream(bus Subscribable) *Stream {
+	return &Stream{bus: bus, done: make(chan struct{})}
+}
+
+// Close ends every open stream, e.g. before a graceful shutdown, which
+// would otherwise wait for them.
+func (s *Stream) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// ServeHTTP streams events until the client disconnects or the stream is closed.
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var patterns []string
+	for _, pattern := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	events := make(chan Event, streamBufferSize)
+	unsubscribe := s.bus.Subscribe(func(ctx context.Context, event Event) error {
+		select {
+		case events <- event:
+		default:
+			slog.WarnContext(ctx, "Dropping event for slow stream client", "event_type", event.Type, "event_id", event.ID)
+		}
+		return nil
+	}, patterns...)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's WriteTimeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		slog.ErrorContext(r.Context(), "Error clearing write deadline", "error", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Error encoding event", "event_id", event.ID, "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Message is a message of a server-sent event stream.
+type Message struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// ReadSSE calls handle with every message of the server-sent event stream r
+// until r ends or handle fails. Comments are skipped.
+func ReadSSE(r io.Reader, handle func(Message) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var message Message
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			if line != "" {
+				continue // comment
+			}
+			if data != nil || message.Event != "" {
+				message.Data = strings.Join(data, "\n")
+				if err := handle(message); err != nil {
+					return err
+				}
+			}
+			message, data = Message{}, nil
+		case "id":
+			message.ID = value
+		case "event":
+			message.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	bus := NewBus()
+	stream := NewStream(bus)
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?types=order.*")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	ctx := context.Background()
+	bus.Publish(ctx, Event{ID: "e1", Type: "user.created"})
+	bus.Publish(ctx, Event{ID: "e2", Type: "order.created", Data: json.RawMessage(`{"total":42}`)})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	if lines[0] != "id: e2" || lines[1] != "event: order.created" || !strings.Contains(lines[2], `"data":{"total":42}`) {
+		t.Errorf("stream = %q, want the order event only", lines)
+	}
+
+	// Closing the stream ends open responses
+	stream.Close()
+	done := make(chan struct{})
+	go func() {
+		reader.ReadString(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Error("stream still open after Close()")
+	}
+}
+
+func TestStream_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewStream(NewBus()).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	input := ": keep-alive\n\nid: 1\nevent: next\ndata: {\"a\":1}\n\nevent: complete\ndata:\n\ndata: line one\ndata: line two\n\n"
+	var got []Message
+	err := ReadSSE(strings.NewReader(input), func(m Message) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSSE() error = %v", err)
+	}
+	want := []Message{
+		{ID: "1", Event: "next", Data: `{"a":1}`},
+		{Event: "complete"},
+		{Data: "line one\nline two"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ReadSSE() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
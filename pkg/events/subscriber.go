@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default delays between reconnections of a Subscriber.
+const (
+	defaultRetryDelay    = 500 * time.Millisecond
+	defaultMaxRetryDelay = 30 * time.Second
+)
+
+// Subscriber follows the Stream of another service, reconnecting when the
+// connection fails. Events published while it is disconnected are missed,
+// so consumers needing every event must also catch up by other means.
+type Subscriber struct {
+	// URL is the stream, e.g. http://localhost:8081/events
+	URL string
+	// Types are the event type patterns to receive, all events without them
+	Types []string
+	// Header is sent with every request, e.g. an Authorization header
+	Header http.Header
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+	// RetryDelay is the first delay before reconnecting, doubled after every
+	// failure up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+// Run calls handler with every event received until ctx is done. Failing
+// handlers are logged; the event is not delivered again.
+func (s *Subscriber) Run(ctx context.Context, handler Handler) {
+	retryDelay := s.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultRetryDelay
+	}
+	maxRetryDelay := s.MaxRetryDelay
+	if maxRetryDelay <= 0 {
+		maxRetryDelay = defaultMaxRetryDelay
+	}
+
+	delay := retryDelay
+	for {
+		connected, err := s.follow(ctx, handler)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = retryDelay
+		}
+		slog.WarnContext(ctx, "Event stream disconnected, reconnecting", "url", s.URL, "retry_in", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// follow reads the stream until it ends, and reports whether it connected.
+func (s *Subscriber) follow(ctx context.Context, handler Handler) (connected bool, err error) {
+	streamURL, err := url.Parse(s.URL)
+	if err != nil {
+		return false, err
+	}
+	if len(s.Types) > 0 {
+		query := streamURL.Query()
+		query.Set("types", strings.Join(s.Types, ","))
+		streamURL.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	for key, values := range s.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	slog.InfoContext(ctx, "Following event stream", "url", s.URL)
+
+	err = ReadSSE(resp.Body, func(message Message) error {
+		var event Event
+		if err := json.Unmarshal([]byte(message.Data), &event); err != nil {
+			slog.ErrorContext(ctx, "Skipping invalid event", "url", s.URL, "message_id", message.ID, "error", err)
+			return nil
+		}
+		if err := handler(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "Event handler failed", "event_type", event.Type, "event_id", event.ID, "error", err)
+		}
+		return nil
+	})
+	if err == nil {
+		err = io.EOF
+	}
+	return true, err
+}
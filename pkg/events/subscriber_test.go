@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscriber(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := connections.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("types") != "user.*,order.created" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		// The first connection fails, the second streams two events and ends
+		if n == 1 {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: e%d\nevent: user.created\ndata: {\"id\":\"e%d\",\"type\":\"user.created\"}\n\n", n, n)
+		fmt.Fprint(w, "data: not json\n\n")
+		fmt.Fprintf(w, "id: o%d\nevent: order.created\ndata: {\"id\":\"o%d\",\"type\":\"order.created\"}\n\n", n, n)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan Event, 10)
+	subscriber := &Subscriber{
+		URL:        server.URL,
+		Types:      []string{"user.*", "order.created"},
+		Header:     http.Header{"Authorization": {"Bearer token"}},
+		RetryDelay: time.Millisecond,
+	}
+	done := make(chan struct{})
+	go func() {
+		subscriber.Run(ctx, func(_ context.Context, event Event) error {
+			received <- event
+			return nil
+		})
+		close(done)
+	}()
+
+	var ids []string
+	for len(ids) < 3 {
+		select {
+		case event := <-received:
+			ids = append(ids, event.ID)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %v, want events from several connections", ids)
+		}
+	}
+	if ids[0] != "e2" || ids[1] != "o2" || ids[2] != "e3" {
+		t.Errorf("received %v, want [e2 o2 e3]", ids)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Error("Run() did not return after the context was cancelled")
+	}
+}
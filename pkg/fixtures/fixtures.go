@@ -0,0 +1,100 @@
+// Package fixtures loads seed data from JSON or YAML files, typically
+// embedded in the binary with embed.FS. Files are grouped in a directory per
+// environment, such as default/users.yaml and demo/users.yaml, so the seed
+// data is selected at startup without rebuilding.
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvironment is the environment whose files are used when the
+// selected environment has none of its own.
+const DefaultEnvironment = "default"
+
+// extensions are the extensions of fixture files, in the order they are looked up
+var extensions = []string{".yaml", ".yml", ".json"}
+
+// Load decodes the fixture file name of the environment env in fsys, an
+// array of T, e.g. env/users.yaml, falling back to the file of
+// DefaultEnvironment when env has none. Files ending in .json are read as
+// JSON, others as YAML. Both are decoded with the json tags of T, and fields
+// unknown to T are rejected to catch typos.
+func Load[T any](fsys fs.FS, env, name string) ([]T, error) {
+	file, err := find(fsys, env, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+
+	// YAML is converted to JSON, so one set of tags serves both formats
+	if path.Ext(file) != ".json" {
+		var document any
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("fixtures %s: %w", file, err)
+		}
+		if data, err = json.Marshal(document); err != nil {
+			return nil, fmt.Errorf("fixtures %s: %w", file, err)
+		}
+	}
+
+	fixtures := []T{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixtures); err != nil {
+		return nil, fmt.Errorf("fixtures %s: %w", file, err)
+	}
+	if fixtures == nil {
+		// An empty file has no fixtures
+		fixtures = []T{}
+	}
+	return fixtures, nil
+}
+
+// find returns the path of the fixture file name of env or else of the
+// default environment
+func find(fsys fs.FS, env, name string) (string, error) {
+	environments := []string{env}
+	if env != DefaultEnvironment {
+		environments = append(environments, DefaultEnvironment)
+	}
+	for _, dir := range environments {
+		for _, ext := range extensions {
+			file := path.Join(dir, name+ext)
+			if _, err := fs.Stat(fsys, file); err == nil {
+				return file, nil
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("fixtures: %w", err)
+			}
+		}
+	}
+	return "", fmt.Errorf("fixtures: no %s file in %s or %s: %w", name, env, DefaultEnvironment, fs.ErrNotExist)
+}
+
+// Environments returns the names of the environments of fsys, its
+// directories, sorted.
+func Environments(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	var environments []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			environments = append(environments, entry.Name())
+		}
+	}
+	sort.Strings(environments)
+	return environments, nil
+}
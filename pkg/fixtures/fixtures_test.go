@@ -0,0 +1,76 @@
+package fixtures
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type user struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+var files = fstest.MapFS{
+	"default/users.yaml": {Data: []byte("- name: Ada\n  email: ada@example.com\n  roles: [admin]\n")},
+	"demo/users.json":    {Data: []byte(`[{"name":"Ada","email":"ada@example.com"},{"name":"Bob","email":"bob@example.com"}]`)},
+	"empty/users.yml":    {Data: []byte("")},
+	"typo/users.yaml":    {Data: []byte("- name: Ada\n  emial: ada@example.com\n")},
+	"broken/users.yaml":  {Data: []byte("- name: [Ada\n")},
+	"README.md":          {Data: []byte("not an environment")},
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []user
+	}{
+		{"default", []user{{Name: "Ada", Email: "ada@example.com", Roles: []string{"admin"}}}},
+		{"demo", []user{{Name: "Ada", Email: "ada@example.com"}, {Name: "Bob", Email: "bob@example.com"}}},
+		{"empty", []user{}},
+		{"staging", []user{{Name: "Ada", Email: "ada@example.com", Roles: []string{"admin"}}}},
+	}
+	for _, tt := range tests {
+		got, err := Load[user](files, tt.env, "users")
+		if err != nil {
+			t.Fatalf("Load(%q) error = %v", tt.env, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Load(%q) = %+v, want %+v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		env, name, want string
+	}{
+		{"typo", "users", `typo/users.yaml: json: unknown field "emial"`},
+		{"broken", "users", "broken/users.yaml: yaml:"},
+		{"demo", "orders", "no orders file in demo or default"},
+	}
+	for _, tt := range tests {
+		_, err := Load[user](files, tt.env, tt.name)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%q, %q) error = %v, want %q", tt.env, tt.name, err, tt.want)
+		}
+	}
+	if _, err := Load[user](files, "demo", "orders"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	got, err := Environments(files)
+	if err != nil {
+		t.Fatalf("Environments() error = %v", err)
+	}
+	want := []string{"broken", "default", "demo", "empty", "typo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Environments() = %v, want %v", got, want)
+	}
+}
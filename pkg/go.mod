@@ -2,4 +2,12 @@ module github.com/captain-corgi/learning-event-driven/pkg
 
 go 1.24.0
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/bwmarrin/snowflake v0.3.0
+	github.com/google/uuid v1.6.0
+	github.com/matoous/go-nanoid/v2 v2.1.0
+	github.com/oklog/ulid/v2 v2.1.1
+	github.com/rs/xid v1.6.0
+	github.com/segmentio/ksuid v1.0.4
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,170 @@
+// Package golden compares the output of tests with golden files kept under
+// testdata/golden, so that changes to the output are explicit: a test fails
+// with a diff when its output changes, and running the tests with -update
+// rewrites the golden files, which are then reviewed with the change.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Dir is the directory of the golden files, relative to the package under test.
+const Dir = "testdata/golden"
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// Assert compares got with the golden file name, or rewrites the file when
+// the tests run with -update.
+func Assert(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join(Dir, filepath.FromSlash(name))
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run go test -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("output differs from %s (-want +got), run go test -update to accept it:\n%s", path, Diff(string(want), string(got)))
+	}
+}
+
+// AssertJSON compares the JSON document got with the golden file name in
+// its canonical form, see JSON.
+func AssertJSON(t testing.TB, name string, got []byte) {
+	t.Helper()
+	canonical, err := JSON(got)
+	if err != nil {
+		t.Fatalf("golden: %s: %v", name, err)
+	}
+	Assert(t, name, canonical)
+}
+
+// response is the form of an HTTP response in golden files
+type response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+// AssertResponse compares the status, the given headers and the body of
+// resp with the golden file name, and closes the body. JSON bodies are
+// recorded as documents in their canonical form, other bodies as strings.
+// Headers missing from resp are left out.
+func AssertResponse(t testing.TB, name string, resp *http.Response, headers ...string) {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("golden: reading the body: %v", err)
+	}
+
+	recorded := response{Status: resp.StatusCode}
+	for _, header := range headers {
+		if value := resp.Header.Get(header); value != "" {
+			if recorded.Headers == nil {
+				recorded.Headers = make(map[string]string)
+			}
+			recorded.Headers[header] = value
+		}
+	}
+	if len(body) > 0 {
+		if json.Valid(body) {
+			recorded.Body = json.RawMessage(body)
+		} else {
+			recorded.Body = string(body)
+		}
+	}
+
+	data, err := json.Marshal(recorded)
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+	AssertJSON(t, name, data)
+}
+
+// JSON returns the canonical form of the JSON document data: indented by two
+// spaces with sorted object keys and a final newline, so that documents only
+// differing in formatting or key order are equal. Numbers are kept as written.
+func JSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: data after the document")
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Diff returns the lines of want and got, prefixed by "-" when only in want,
+// "+" when only in got and a space when in both.
+func Diff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&diff, " %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			fmt.Fprintf(&diff, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&diff, "+%s\n", b[j])
+			j++
+		}
+	}
+	return diff.String()
+}
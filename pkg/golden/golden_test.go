@@ -0,0 +1,103 @@
+package golden
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB recording the failures of an assertion
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// run runs assert with a recorder and returns the recorded failures
+func run(t *testing.T, assert func(testing.TB)) []string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert(r)
+	}()
+	<-done
+	return r.failures
+}
+
+func TestJSON(t *testing.T) {
+	got, err := JSON([]byte(`{"b":1.50,"a":{"d":[1,2],"c":"<&>"}}`))
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	want := "{\n  \"a\": {\n    \"c\": \"<&>\",\n    \"d\": [\n      1,\n      2\n    ]\n  },\n  \"b\": 1.50\n}\n"
+	if string(got) != want {
+		t.Errorf("JSON() = %q, want %q", got, want)
+	}
+
+	for _, invalid := range []string{``, `{"a":`, `{} {}`} {
+		if _, err := JSON([]byte(invalid)); err == nil {
+			t.Errorf("JSON(%q) error = nil, want an error", invalid)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("a\nb\nc\n", "a\nx\nc\nd\n")
+	want := " a\n-b\n+x\n c\n+d\n"
+	if got != want {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+	if got := Diff("same\n", "same\n"); got != " same\n" {
+		t.Errorf("Diff() = %q, want the unchanged line", got)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	if *update {
+		t.Skip("the golden files of this package are written by hand")
+	}
+
+	if failures := run(t, func(tb testing.TB) { AssertJSON(tb, "example.json", []byte(`{"name":"Ada","id":1}`)) }); len(failures) > 0 {
+		t.Errorf("AssertJSON() failed on an equal document: %v", failures)
+	}
+
+	failures := run(t, func(tb testing.TB) { AssertJSON(tb, "example.json", []byte(`{"id":2,"name":"Ada"}`)) })
+	if len(failures) != 1 || !strings.Contains(failures[0], "-  \"id\": 1,\n+  \"id\": 2,") {
+		t.Errorf("AssertJSON() failures = %q, want a diff of the id", failures)
+	}
+
+	failures = run(t, func(tb testing.TB) { Assert(tb, "missing.json", nil) })
+	if len(failures) != 1 || !strings.Contains(failures[0], "-update") {
+		t.Errorf("Assert() failures = %q, want a hint to create the missing file", failures)
+	}
+}
+
+func TestAssertResponse(t *testing.T) {
+	if *update {
+		t.Skip("the golden files of this package are written by hand")
+	}
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "application/json")
+	rec.Header().Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	rec.WriteHeader(http.StatusCreated)
+	rec.Write([]byte(`{"name":"Ada","id":1}`))
+
+	if failures := run(t, func(tb testing.TB) { AssertResponse(tb, "response.json", rec.Result(), "Content-Type", "Location") }); len(failures) > 0 {
+		t.Errorf("AssertResponse() failures = %q, want none", failures)
+	}
+}
@@ -0,0 +1,118 @@
+// Package logging builds the slog loggers shared by all modules.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Format names a log output format.
+type Format string
+
+const (
+	// FormatText writes key=value lines.
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per record.
+	FormatJSON Format = "json"
+)
+
+// New creates a logger writing records of at least level to w in format.
+// Pass a *slog.LevelVar to change the level while the logger is in use.
+// Attributes stored in the context with ContextWithAttrs are added to every
+// record logged with a context.
+func New(w io.Writer, format Format, level slog.Leveler) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, want text or json", format)
+	}
+	return slog.New(ContextHandler{Handler: handler}), nil
+}
+
+// FromEnv creates a logger writing to stderr, configured by LOG_FORMAT (text
+// or json, default text) and LOG_LEVEL (debug, info, warn or error, default info).
+// Setting the returned level changes the level of the logger at runtime.
+func FromEnv() (*slog.Logger, *slog.LevelVar, error) {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format := Format(strings.ToLower(os.Getenv("LOG_FORMAT")))
+	if format == "" {
+		format = FormatText
+	}
+
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+	logger, err := New(os.Stderr, format, levelVar)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logger, levelVar, nil
+}
+
+// ParseLevel parses a level name. An empty name is the info level.
+func ParseLevel(name string) (slog.Level, error) {
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, want debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+type attrsContextKey struct{}
+
+// ContextWithAttrs returns a copy of ctx carrying attrs, in addition to the
+// attributes it already carries, for every record logged with it.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsContextKey{}).([]slog.Attr)
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+	combined = append(combined, existing...)
+	combined = append(combined, attrs...)
+	return context.WithValue(ctx, attrsContextKey{}, combined)
+}
+
+// ContextHandler adds the attributes stored in the context of a record,
+// such as the request ID or tenant, before passing it to Handler.
+type ContextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the context attributes to r.
+func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsContextKey{}).([]slog.Attr); ok {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a ContextHandler whose handler has attrs.
+func (h ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a ContextHandler whose handler has the group name.
+func (h ContextHandler) WithGroup(name string) slog.Handler {
+	return ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Discard returns a logger dropping every record, for tests and defaults.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		want    string
+		wantErr bool
+	}{
+		{name: "text", format: FormatText, want: "msg=hello"},
+		{name: "json", format: FormatJSON, want: `"msg":"hello"`},
+		{name: "unknown", format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, tt.format, slog.LevelInfo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			logger.Debug("hidden")
+			logger.Info("hello")
+			if got := buf.String(); !strings.Contains(got, tt.want) || strings.Contains(got, "hidden") {
+				t.Errorf("output = %q, want %q and no debug record", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{name: "", want: slog.LevelInfo},
+		{name: "debug", want: slog.LevelDebug},
+		{name: "WARN", want: slog.LevelWarn},
+		{name: "error", want: slog.LevelError},
+		{name: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_LevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	logger, err := New(&buf, FormatText, level)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Debug("before")
+	level.Set(slog.LevelDebug)
+	logger.Debug("after")
+	if got := buf.String(); strings.Contains(got, "before") || !strings.Contains(got, "after") {
+		t.Errorf("output = %q, want only the record logged after lowering the level", got)
+	}
+}
+
+func TestContextWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := ContextWithAttrs(context.Background(), slog.String("request_id", "r-1"))
+	ctx = ContextWithAttrs(ctx, slog.String("tenant", "acme"))
+	logger.With("component", "test").InfoContext(ctx, "hello")
+	logger.Info("without context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2", len(lines))
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("invalid JSON record: %v", err)
+	}
+	for key, want := range map[string]string{"request_id": "r-1", "tenant": "acme", "component": "test"} {
+		if record[key] != want {
+			t.Errorf("%s = %v, want %q", key, record[key], want)
+		}
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("record without context carries context attributes: %s", lines[1])
+	}
+}
@@ -0,0 +1,41 @@
+// Package problem writes the RFC 7807 problem documents the modules answer
+// failed requests with.
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// ContentType is the media type of problem documents.
+const ContentType = "application/problem+json"
+
+// Details is an RFC 7807 problem document.
+type Details struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// Write writes a problem document for status with detail. The instance is the
+// path of r, when r is not nil.
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	problem := Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error("Failed to encode problem", "error", err)
+	}
+}
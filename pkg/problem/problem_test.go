@@ -0,0 +1,47 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    Details
+	}{
+		{
+			name:    "with request",
+			request: httptest.NewRequest(http.MethodGet, "/orders/42", nil),
+			want:    Details{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "order not found", Instance: "/orders/42"},
+		},
+		{
+			name: "without request",
+			want: Details{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "order not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Write(rec, tt.request, http.StatusNotFound, "order not found")
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+			}
+			if got := rec.Header().Get("Content-Type"); got != ContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ContentType)
+			}
+			var got Details
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Write() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
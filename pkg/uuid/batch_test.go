@@ -0,0 +1,58 @@
+package uuid
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewBatch(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{name: "negative", n: -1, want: 0},
+		{name: "empty", n: 0, want: 0},
+		{name: "single", n: 1, want: 1},
+		{name: "many", n: 1000, want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewBatch(tt.n)
+			if len(got) != tt.want {
+				t.Fatalf("NewBatch() length = %v, want %v", len(got), tt.want)
+			}
+
+			seen := make(map[string]bool, len(got))
+			for _, id := range got {
+				u, err := uuid.Parse(id)
+				if err != nil {
+					t.Fatalf("NewBatch() generated invalid UUID: %v", err)
+				}
+				if u.Version() != 4 || u.Variant() != uuid.RFC4122 {
+					t.Errorf("NewBatch() generated %v, want a version 4 RFC 4122 UUID", id)
+				}
+				if seen[id] {
+					t.Errorf("NewBatch() generated duplicate UUIDs: %v", id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func BenchmarkNewBatch(b *testing.B) {
+	for b.Loop() {
+		NewBatch(100)
+	}
+}
+
+func BenchmarkNewGoogle_Loop(b *testing.B) {
+	for b.Loop() {
+		for range 100 {
+			NewGoogle()
+		}
+	}
+}
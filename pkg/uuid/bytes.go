@@ -0,0 +1,38 @@
+package uuid
+
+import (
+	"fmt"
+)
+
+// Bytes returns the 16 bytes of the ID, e.g. to store it in a binary column
+// instead of its 36 character string.
+func (id ID) Bytes() []byte {
+	b := make([]byte, len(id))
+	copy(b, id[:])
+	return b
+}
+
+// FromBytes creates an ID from its 16 bytes, as returned by Bytes.
+func FromBytes(b []byte) (ID, error) {
+	var id ID
+	if len(b) != len(id) {
+		return ID{}, fmt.Errorf("invalid ID length: %d bytes, want %d", len(b), len(id))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (id ID) MarshalBinary() ([]byte, error) {
+	return id.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (id *ID) UnmarshalBinary(data []byte) error {
+	parsed, err := FromBytes(data)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
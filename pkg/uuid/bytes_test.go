@@ -0,0 +1,114 @@
+package uuid
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestID_Bytes(t *testing.T) {
+	id := MustParseID("550e8400-e29b-41d4-a716-446655440000")
+	want := []byte{0x55, 0x0e, 0x84, 0x00, 0xe2, 0x9b, 0x41, 0xd4, 0xa7, 0x16, 0x44, 0x66, 0x55, 0x44, 0x00, 0x00}
+
+	got := id.Bytes()
+	if !bytes.Equal(got, want) {
+		t.Errorf("Bytes() = %x, want %x", got, want)
+	}
+
+	// The returned slice is a copy
+	got[0] = 0
+	if id[0] != 0x55 {
+		t.Error("Bytes() returned a slice sharing the ID's memory")
+	}
+}
+
+func TestFromBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "16 bytes",
+			input: []byte{0x55, 0x0e, 0x84, 0x00, 0xe2, 0x9b, 0x41, 0xd4, 0xa7, 0x16, 0x44, 0x66, 0x55, 0x44, 0x00, 0x00},
+			want:  "550e8400-e29b-41d4-a716-446655440000",
+		},
+		{
+			name:    "empty",
+			input:   nil,
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			input:   make([]byte, 15),
+			wantErr: true,
+		},
+		{
+			name:    "string form",
+			input:   []byte("550e8400-e29b-41d4-a716-446655440000"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromBytes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FromBytes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("FromBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestID_BinaryRoundTrip(t *testing.T) {
+	id := NewID()
+
+	data, err := id.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var got ID
+	if err := got.UnmarshalBinary(data); err != nil || got != id {
+		t.Errorf("UnmarshalBinary() = %v, %v, want %v", got, err, id)
+	}
+	if err := got.UnmarshalBinary([]byte{1}); err == nil {
+		t.Error("UnmarshalBinary() should fail for a short slice")
+	}
+}
+
+// The benchmarks compare storing and loading an ID in its binary and string
+// forms; the stored size is reported as bytes/id.
+
+func BenchmarkID_Bytes(b *testing.B) {
+	id := NewID()
+	for b.Loop() {
+		id.Bytes()
+	}
+	b.ReportMetric(float64(len(id.Bytes())), "bytes/id")
+}
+
+func BenchmarkID_String(b *testing.B) {
+	id := NewID()
+	for b.Loop() {
+		_ = id.String()
+	}
+	b.ReportMetric(float64(len(id.String())), "bytes/id")
+}
+
+func BenchmarkFromBytes(b *testing.B) {
+	data := NewID().Bytes()
+	for b.Loop() {
+		FromBytes(data)
+	}
+}
+
+func BenchmarkParseID(b *testing.B) {
+	s := NewID().String()
+	for b.Loop() {
+		ParseID(s)
+	}
+}
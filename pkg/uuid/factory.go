@@ -0,0 +1,84 @@
+package uuid
+
+import (
+	"fmt"
+)
+
+// Scheme names an ID scheme.
+type Scheme string
+
+// Supported ID schemes.
+const (
+	SchemeUUIDv4    Scheme = "uuidv4"
+	SchemeUUIDv7    Scheme = "uuidv7"
+	SchemeULID      Scheme = "ulid"
+	SchemeKSUID     Scheme = "ksuid"
+	SchemeXID       Scheme = "xid"
+	SchemeSnowflake Scheme = "snowflake"
+	SchemeNanoID    Scheme = "nanoid"
+)
+
+// Schemes lists the supported ID schemes.
+func Schemes() []Scheme {
+	return []Scheme{SchemeUUIDv4, SchemeUUIDv7, SchemeULID, SchemeKSUID, SchemeXID, SchemeSnowflake, SchemeNanoID}
+}
+
+// GeneratorConfig configures the generator created by NewGenerator.
+type GeneratorConfig struct {
+	// Scheme is the ID scheme; empty selects SchemeUUIDv4.
+	Scheme Scheme
+
+	// SnowflakeNode is the node ID of snowflake IDs, between 0 and 1023.
+	SnowflakeNode int64
+
+	// NanoIDAlphabet and NanoIDLength configure NanoIDs; empty values select
+	// NanoIDAlphabet and DefaultNanoIDLength.
+	NanoIDAlphabet string
+	NanoIDLength   int
+
+	// Prefix, when set, prefixes every ID like prefix_id.
+	Prefix string
+}
+
+// NewGenerator creates the generator of the IDs configured by config, e.g.
+// from the configuration of an entity at startup.
+func NewGenerator(config GeneratorConfig) (IDGenerator, error) {
+	generator, err := newSchemeGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Prefix == "" {
+		return generator, nil
+	}
+	return NewPrefixedGenerator(config.Prefix, generator)
+}
+
+// newSchemeGenerator creates the unprefixed generator of config.
+func newSchemeGenerator(config GeneratorConfig) (IDGenerator, error) {
+	switch config.Scheme {
+	case "", SchemeUUIDv4:
+		return GoogleGenerator, nil
+	case SchemeUUIDv7:
+		return GoogleV7Generator, nil
+	case SchemeULID:
+		// IDs made in the same millisecond still sort in creation order
+		return defaultMonotonicULIDs, nil
+	case SchemeKSUID:
+		return KSUIDGenerator, nil
+	case SchemeXID:
+		return XIDGenerator, nil
+	case SchemeSnowflake:
+		return NewSnowflakeGenerator(config.SnowflakeNode)
+	case SchemeNanoID:
+		alphabet, length := config.NanoIDAlphabet, config.NanoIDLength
+		if alphabet == "" {
+			alphabet = NanoIDAlphabet
+		}
+		if length == 0 {
+			length = DefaultNanoIDLength
+		}
+		return NewNanoIDGenerator(alphabet, length)
+	default:
+		return nil, fmt.Errorf("unknown ID scheme %q, want one of %v", config.Scheme, Schemes())
+	}
+}
@@ -0,0 +1,147 @@
+package uuid
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  GeneratorConfig
+		valid   func(string) bool
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			config: GeneratorConfig{},
+			valid:  func(id string) bool { return MustBeVersion(id, 4) == nil },
+		},
+		{
+			name:   "uuidv4",
+			config: GeneratorConfig{Scheme: SchemeUUIDv4},
+			valid:  func(id string) bool { return MustBeVersion(id, 4) == nil },
+		},
+		{
+			name:   "uuidv7",
+			config: GeneratorConfig{Scheme: SchemeUUIDv7},
+			valid:  func(id string) bool { return MustBeVersion(id, 7) == nil },
+		},
+		{
+			name:   "ulid",
+			config: GeneratorConfig{Scheme: SchemeULID},
+			valid:  func(id string) bool { _, err := ParseULID(id); return err == nil },
+		},
+		{
+			name:   "ksuid",
+			config: GeneratorConfig{Scheme: SchemeKSUID},
+			valid:  func(id string) bool { _, err := ParseKSUID(id); return err == nil },
+		},
+		{
+			name:   "xid",
+			config: GeneratorConfig{Scheme: SchemeXID},
+			valid:  func(id string) bool { _, err := ParseXID(id); return err == nil },
+		},
+		{
+			name:   "snowflake",
+			config: GeneratorConfig{Scheme: SchemeSnowflake, SnowflakeNode: 7},
+			valid:  func(id string) bool { _, err := strconv.ParseInt(id, 10, 64); return err == nil },
+		},
+		{
+			name:   "nanoid defaults",
+			config: GeneratorConfig{Scheme: SchemeNanoID},
+			valid:  func(id string) bool { return len(id) == DefaultNanoIDLength },
+		},
+		{
+			name:   "nanoid code",
+			config: GeneratorConfig{Scheme: SchemeNanoID, NanoIDAlphabet: CodeAlphabet, NanoIDLength: 8},
+			valid: func(id string) bool {
+				return len(id) == 8 && strings.Trim(id, CodeAlphabet) == ""
+			},
+		},
+		{
+			name:   "prefixed ulid",
+			config: GeneratorConfig{Scheme: SchemeULID, Prefix: "usr"},
+			valid: func(id string) bool {
+				p, err := ParsePrefixedID(id)
+				if err != nil || p.Prefix != "usr" {
+					return false
+				}
+				_, err = ParseULID(p.ID)
+				return err == nil
+			},
+		},
+		{
+			name:    "unknown scheme",
+			config:  GeneratorConfig{Scheme: "uuidv5"},
+			wantErr: true,
+		},
+		{
+			name:    "snowflake node out of range",
+			config:  GeneratorConfig{Scheme: SchemeSnowflake, SnowflakeNode: 2048},
+			wantErr: true,
+		},
+		{
+			name:    "invalid nanoid alphabet",
+			config:  GeneratorConfig{Scheme: SchemeNanoID, NanoIDAlphabet: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid prefix",
+			config:  GeneratorConfig{Prefix: "Usr"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := NewGenerator(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if id := generator.NewID(); !tt.valid(id) {
+				t.Errorf("NewID() = %v, not an ID of %v", id, tt.config.Scheme)
+			}
+		})
+	}
+}
+
+func TestSchemes(t *testing.T) {
+	// Every listed scheme can be created
+	for _, scheme := range Schemes() {
+		if _, err := NewGenerator(GeneratorConfig{Scheme: scheme}); err != nil {
+			t.Errorf("NewGenerator(%v) error = %v", scheme, err)
+		}
+	}
+}
+
+func TestNewGenerator_MonotonicULIDs(t *testing.T) {
+	generator, err := NewGenerator(GeneratorConfig{Scheme: SchemeULID})
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+	// Unlike ulid.Make, it also keeps the order when the clock goes back
+	if _, ok := generator.(*MonotonicULIDGenerator); !ok {
+		t.Fatalf("NewGenerator() = %T, want a *MonotonicULIDGenerator", generator)
+	}
+
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = generator.NewID()
+	}
+	sameMillisecond := false
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("ID %d = %s, want it after %s", i, ids[i], ids[i-1])
+		}
+		// The first 10 characters encode the millisecond
+		sameMillisecond = sameMillisecond || ids[i][:10] == ids[i-1][:10]
+	}
+	if !sameMillisecond {
+		t.Error("no two IDs were made in the same millisecond")
+	}
+}
@@ -0,0 +1,25 @@
+package uuid
+
+// IDGenerator generates unique IDs. Implementations must be safe for
+// concurrent use.
+type IDGenerator interface {
+	// NewID returns a new unique ID.
+	NewID() string
+}
+
+// GeneratorFunc adapts an ordinary function to the IDGenerator interface.
+type GeneratorFunc func() string
+
+// NewID returns f().
+func (f GeneratorFunc) NewID() string {
+	return f()
+}
+
+// Generators of the supported ID schemes.
+var (
+	GoogleGenerator   IDGenerator = GeneratorFunc(NewGoogle)
+	GoogleV7Generator IDGenerator = GeneratorFunc(NewGoogleV7)
+	ULIDGenerator     IDGenerator = GeneratorFunc(NewULID)
+	KSUIDGenerator    IDGenerator = GeneratorFunc(NewKSUID)
+	XIDGenerator      IDGenerator = GeneratorFunc(NewXID)
+)
@@ -0,0 +1,39 @@
+package uuid
+
+import (
+	"testing"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator IDGenerator
+		parse     func(string) (string, error)
+	}{
+		{name: "google", generator: GoogleGenerator, parse: ParseGoogle},
+		{name: "google v7", generator: GoogleV7Generator, parse: ParseGoogle},
+		{name: "ulid", generator: ULIDGenerator, parse: ParseULID},
+		{name: "ksuid", generator: KSUIDGenerator, parse: ParseKSUID},
+		{name: "xid", generator: XIDGenerator, parse: ParseXID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.generator.NewID()
+			if _, err := tt.parse(first); err != nil {
+				t.Errorf("NewID() generated invalid ID %v: %v", first, err)
+			}
+			if second := tt.generator.NewID(); first == second {
+				t.Errorf("NewID() generated duplicate IDs: %v", first)
+			}
+		})
+	}
+}
+
+func TestGeneratorFunc(t *testing.T) {
+	var generator IDGenerator = GeneratorFunc(func() string { return "fixed" })
+
+	if got := generator.NewID(); got != "fixed" {
+		t.Errorf("NewID() = %v, want %v", got, "fixed")
+	}
+}
@@ -9,6 +9,12 @@ func NewGoogle() string {
 	return uuid.New().String()
 }
 
+// NewGoogleV7 generates a new UUID of version 7, which sorts by creation
+// time to the millisecond.
+func NewGoogleV7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
 // ParseGoogle parses a UUID from a string.
 func ParseGoogle(s string) (string, error) {
 	u, err := uuid.Parse(s)
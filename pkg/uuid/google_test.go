@@ -54,6 +54,23 @@ func TestNewGoogle(t *testing.T) {
 	}
 }
 
+func TestNewGoogleV7(t *testing.T) {
+	got := NewGoogleV7()
+
+	u, err := uuid.Parse(got)
+	if err != nil {
+		t.Fatalf("NewGoogleV7() generated invalid UUID: %v", err)
+	}
+	if u.Version() != 7 {
+		t.Errorf("NewGoogleV7() version = %v, want %v", u.Version(), 7)
+	}
+
+	// UUIDs of version 7 sort by creation time
+	if second := NewGoogleV7(); second <= got {
+		t.Errorf("NewGoogleV7() = %v, want greater than %v", second, got)
+	}
+}
+
 func TestParseGoogle(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,93 @@
+package uuid
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ID is a UUID value. Its text and JSON form is the canonical
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx string; the zero ID is the nil UUID.
+type ID [16]byte
+
+// NewID generates a new random ID.
+func NewID() ID {
+	return ID(uuid.New())
+}
+
+// ParseID parses an ID from a UUID string.
+func ParseID(s string) (ID, error) {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return ID{}, err
+	}
+	return ID(u), nil
+}
+
+// MustParseID parses an ID from a UUID string and panics if there is an error.
+func MustParseID(s string) ID {
+	id, err := ParseID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// String returns the canonical UUID string of the ID.
+func (id ID) String() string {
+	return uuid.UUID(id).String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *ID) UnmarshalText(data []byte) error {
+	parsed, err := ParseID(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid ID %q: %w", data, err)
+	}
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (id ID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A JSON null leaves the ID unchanged.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+	return id.UnmarshalText([]byte(s))
+}
+
+// TypedID is an ID of entities of type T, so that the IDs of different
+// entities cannot be mixed up, e.g. TypedID[User] and TypedID[Order]. It
+// marshals exactly like ID.
+type TypedID[T any] struct {
+	ID
+}
+
+// NewTypedID generates a new random ID of an entity of type T.
+func NewTypedID[T any]() TypedID[T] {
+	return TypedID[T]{ID: NewID()}
+}
+
+// ParseTypedID parses the ID of an entity of type T from a UUID string.
+func ParseTypedID[T any](s string) (TypedID[T], error) {
+	id, err := ParseID(s)
+	if err != nil {
+		return TypedID[T]{}, err
+	}
+	return TypedID[T]{ID: id}, nil
+}
@@ -0,0 +1,188 @@
+package uuid
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "valid UUID",
+			input: "550e8400-e29b-41d4-a716-446655440000",
+			want:  "550e8400-e29b-41d4-a716-446655440000",
+		},
+		{
+			name:  "valid UUID uppercase",
+			input: "550E8400-E29B-41D4-A716-446655440000",
+			want:  "550e8400-e29b-41d4-a716-446655440000", // should normalize to lowercase
+		},
+		{
+			name:  "nil UUID",
+			input: "00000000-0000-0000-0000-000000000000",
+			want:  "00000000-0000-0000-0000-000000000000",
+		},
+		{
+			name:    "empty string",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid characters",
+			input:   "550e8400-e29b-41d4-a716-44665544000g",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("ParseID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewID(t *testing.T) {
+	got := NewID()
+
+	if got == (ID{}) {
+		t.Error("NewID() returned the nil ID")
+	}
+	if second := NewID(); got == second {
+		t.Errorf("NewID() generated duplicate IDs: %v", got)
+	}
+	if parsed := MustParseID(got.String()); parsed != got {
+		t.Errorf("MustParseID(String()) = %v, want %v", parsed, got)
+	}
+}
+
+func TestMustParseID(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("MustParseID() should have panicked for an invalid UUID")
+		}
+	}()
+	MustParseID("invalid-uuid")
+}
+
+func TestID_Stringer(t *testing.T) {
+	id := MustParseID("550e8400-e29b-41d4-a716-446655440000")
+
+	if got := fmt.Sprint(id); got != "550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("fmt.Sprint() = %v, want %v", got, "550e8400-e29b-41d4-a716-446655440000")
+	}
+}
+
+type order struct{}
+
+type entity struct {
+	ID      ID              `json:"id"`
+	OrderID TypedID[order]  `json:"order_id"`
+	Refs    map[ID]string   `json:"refs,omitempty"`
+	Parent  *TypedID[order] `json:"parent,omitempty"`
+}
+
+func TestID_JSON(t *testing.T) {
+	id := MustParseID("550e8400-e29b-41d4-a716-446655440000")
+	orderID, _ := ParseTypedID[order]("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	want := `{"id":"550e8400-e29b-41d4-a716-446655440000","order_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8",` +
+		`"refs":{"550e8400-e29b-41d4-a716-446655440000":"self"}}`
+
+	data, err := json.Marshal(entity{ID: id, OrderID: orderID, Refs: map[ID]string{id: "self"}})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded entity
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.ID != id || decoded.OrderID != orderID || decoded.Refs[id] != "self" || decoded.Parent != nil {
+		t.Errorf("json.Unmarshal() = %+v, want the marshaled entity", decoded)
+	}
+}
+
+func TestID_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    ID
+		wantErr bool
+	}{
+		{
+			name:  "valid UUID",
+			input: `"550e8400-e29b-41d4-a716-446655440000"`,
+			want:  MustParseID("550e8400-e29b-41d4-a716-446655440000"),
+		},
+		{
+			name:  "null",
+			input: `null`,
+		},
+		{
+			name:    "invalid UUID",
+			input:   `"invalid-uuid"`,
+			wantErr: true,
+		},
+		{
+			name:    "not a string",
+			input:   `42`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ID
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("json.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("json.Unmarshal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypedID(t *testing.T) {
+	got := NewTypedID[order]()
+	if got.ID == (ID{}) {
+		t.Error("NewTypedID() returned the nil ID")
+	}
+
+	text, err := got.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() error = %v", err)
+	}
+	var decoded TypedID[order]
+	if err := decoded.UnmarshalText(text); err != nil || decoded != got {
+		t.Errorf("UnmarshalText() = %v, %v, want %v", decoded, err, got)
+	}
+
+	if _, err := ParseTypedID[order]("invalid-uuid"); err == nil {
+		t.Error("ParseTypedID() should fail for an invalid UUID")
+	}
+}
+
+func BenchmarkID_MarshalJSON(b *testing.B) {
+	id := NewID()
+	for b.Loop() {
+		id.MarshalJSON()
+	}
+}
@@ -0,0 +1,45 @@
+package uuid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version returns the version of the ID, e.g. 4 for random UUIDs and 7 for
+// time-ordered ones.
+func (id ID) Version() int {
+	return int(uuid.UUID(id).Version())
+}
+
+// Variant returns the name of the variant (layout) of the ID: RFC4122 for the
+// UUIDs of RFC 4122 and RFC 9562, or Reserved, Microsoft or Future.
+func (id ID) Variant() string {
+	return uuid.UUID(id).Variant().String()
+}
+
+// TimestampOf returns the creation time embedded in a time-based UUID of
+// version 1, 6 or 7, in UTC. IDs of the other versions carry no time.
+func TimestampOf(id ID) (time.Time, error) {
+	u := uuid.UUID(id)
+	if u.Variant() != uuid.RFC4122 {
+		return time.Time{}, fmt.Errorf("UUID %s has no timestamp: variant %s", u, u.Variant())
+	}
+
+	switch u.Version() {
+	case 1, 7:
+		sec, nsec := u.Time().UnixTime()
+		return time.Unix(sec, nsec).UTC(), nil
+	case 6:
+		// The 60-bit timestamp is stored most significant bits first, with
+		// the version in the high nibble of byte 6
+		high := binary.BigEndian.Uint64(u[:8])
+		ticks := high>>16<<12 | high&0xfff
+		sec, nsec := uuid.Time(ticks).UnixTime()
+		return time.Unix(sec, nsec).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("UUID %s has no timestamp: version %d", u, u.Version())
+	}
+}
@@ -0,0 +1,105 @@
+package uuid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The examples of RFC 9562 were all created at 2022-02-22 19:22:22 UTC.
+const (
+	rfcExampleV1 = "c232ab00-9414-11ec-b3c8-9f6bdeced846"
+	rfcExampleV4 = "919108f7-52d1-4320-9bac-f847db4148a8"
+	rfcExampleV6 = "1ec9414c-232a-6b00-b3c8-9f6bdeced846"
+	rfcExampleV7 = "017f22e2-79b0-7cc3-98c4-dc0c0c07398f"
+)
+
+func TestID_Version(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{input: rfcExampleV1, want: 1},
+		{input: rfcExampleV4, want: 4},
+		{input: rfcExampleV6, want: 6},
+		{input: rfcExampleV7, want: 7},
+		{input: "00000000-0000-0000-0000-000000000000", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := MustParseID(tt.input).Version(); got != tt.want {
+				t.Errorf("Version() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestID_Variant(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: rfcExampleV4, want: "RFC4122"},
+		{input: "00000000-0000-0000-0000-000000000000", want: "Reserved"},
+		{input: "00000000-0000-0000-c000-000000000000", want: "Microsoft"},
+		{input: "00000000-0000-0000-e000-000000000000", want: "Future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := MustParseID(tt.input).Variant(); got != tt.want {
+				t.Errorf("Variant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampOf(t *testing.T) {
+	created := time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "version 1", input: rfcExampleV1, want: created},
+		{name: "version 6", input: rfcExampleV6, want: created},
+		{name: "version 7", input: rfcExampleV7, want: created},
+		{name: "version 4", input: rfcExampleV4, wantErr: true},
+		{name: "nil UUID", input: "00000000-0000-0000-0000-000000000000", wantErr: true},
+		{name: "Microsoft variant", input: "c232ab00-9414-11ec-c3c8-9f6bdeced846", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TimestampOf(MustParseID(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TimestampOf() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("TimestampOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampOf_New(t *testing.T) {
+	before := time.Now().Add(-time.Millisecond)
+	v7, err := uuid.NewV7()
+	if err != nil {
+		t.Fatalf("NewV7() error = %v", err)
+	}
+	after := time.Now().Add(time.Millisecond)
+
+	got, err := TimestampOf(ID(v7))
+	if err != nil {
+		t.Fatalf("TimestampOf() error = %v", err)
+	}
+	if got.Before(before) || got.After(after) {
+		t.Errorf("TimestampOf() = %v, want between %v and %v", got, before, after)
+	}
+}
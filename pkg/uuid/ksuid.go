@@ -0,0 +1,55 @@
+package uuid
+
+import (
+	"errors"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// NewKSUID generates a new KSUID: 27 base62 characters that sort by creation
+// time, to the second.
+func NewKSUID() string {
+	return ksuid.New().String()
+}
+
+// errInvalidKSUID is returned for strings that are not base62.
+var errInvalidKSUID = errors.New("invalid KSUID: not a base62 string")
+
+// ParseKSUID parses a KSUID from a string.
+func ParseKSUID(s string) (string, error) {
+	id, err := parseKSUID(s)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// MustParseKSUID parses a KSUID from a string and panics if there is an error.
+func MustParseKSUID(s string) string {
+	id, err := ParseKSUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Timestamp returns the creation time embedded in a KSUID, in UTC.
+func Timestamp(s string) (time.Time, error) {
+	id, err := parseKSUID(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return id.Time().UTC(), nil
+}
+
+// parseKSUID parses a KSUID, rejecting the characters outside of the base62
+// alphabet that ksuid.Parse silently accepts.
+func parseKSUID(s string) (ksuid.KSUID, error) {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+			return ksuid.Nil, errInvalidKSUID
+		}
+	}
+	return ksuid.Parse(s)
+}
@@ -0,0 +1,128 @@
+package uuid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewKSUID(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+	got := NewKSUID()
+	after := time.Now()
+
+	if len(got) != 27 {
+		t.Errorf("NewKSUID() length = %v, want %v", len(got), 27)
+	}
+
+	created, err := Timestamp(got)
+	if err != nil {
+		t.Fatalf("NewKSUID() generated invalid KSUID: %v", err)
+	}
+	if created.Before(before) || created.After(after) {
+		t.Errorf("Timestamp() = %v, want between %v and %v", created, before, after)
+	}
+
+	if second := NewKSUID(); got == second {
+		t.Errorf("NewKSUID() generated duplicate KSUIDs: %v", got)
+	}
+}
+
+func TestParseKSUID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "valid KSUID",
+			input: "0ujtsYcgvSTl8PAuAdqWYSMnLOv",
+			want:  "0ujtsYcgvSTl8PAuAdqWYSMnLOv",
+		},
+		{
+			name:    "empty string",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			input:   "0ujtsYcgvSTl8PAuAdqWYSMnLO",
+			wantErr: true,
+		},
+		{
+			name:    "invalid characters",
+			input:   "0ujtsYcgvSTl8PAuAdqWYSMnL-v",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKSUID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseKSUID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseKSUID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustParseKSUID(t *testing.T) {
+	if got := MustParseKSUID("0ujtsYcgvSTl8PAuAdqWYSMnLOv"); got != "0ujtsYcgvSTl8PAuAdqWYSMnLOv" {
+		t.Errorf("MustParseKSUID() = %v, want %v", got, "0ujtsYcgvSTl8PAuAdqWYSMnLOv")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("MustParseKSUID() should have panicked for an invalid KSUID")
+		}
+	}()
+	MustParseKSUID("invalid-ksuid")
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "valid KSUID",
+			input: "0ujtsYcgvSTl8PAuAdqWYSMnLOv",
+			want:  time.Date(2017, 10, 10, 4, 0, 47, 0, time.UTC),
+		},
+		{
+			name:  "minimum KSUID",
+			input: "000000000000000000000000000",
+			want:  time.Date(2014, 5, 13, 16, 53, 20, 0, time.UTC),
+		},
+		{
+			name:    "invalid KSUID",
+			input:   "invalid-ksuid",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Timestamp(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Timestamp() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Timestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkNewKSUID(b *testing.B) {
+	for b.Loop() {
+		NewKSUID()
+	}
+}
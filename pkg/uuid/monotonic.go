@@ -0,0 +1,67 @@
+package uuid
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// MonotonicULIDGenerator generates ULIDs that strictly increase in the order
+// they are generated, across goroutines. Within a millisecond the random part
+// is incremented instead of drawn anew, and a clock going backwards does not
+// move the timestamp back, so IDs sort exactly like the events they identify.
+type MonotonicULIDGenerator struct {
+	entropy *ulid.MonotonicEntropy
+	now     func() time.Time
+	lastMs  uint64
+	mutex   sync.Mutex
+}
+
+// defaultMonotonicULIDs generates the IDs of NewMonotonicULID.
+var defaultMonotonicULIDs = NewMonotonicULIDGenerator()
+
+// NewMonotonicULID generates a ULID greater than every ULID previously
+// generated by NewMonotonicULID in this process.
+func NewMonotonicULID() string {
+	return defaultMonotonicULIDs.NewID()
+}
+
+// NewMonotonicULIDGenerator creates a generator of strictly increasing ULIDs
+// with cryptographically secure entropy.
+func NewMonotonicULIDGenerator() *MonotonicULIDGenerator {
+	return newMonotonicULIDGenerator(rand.Reader, time.Now)
+}
+
+// newMonotonicULIDGenerator creates a generator reading entropy and the clock now.
+func newMonotonicULIDGenerator(entropy io.Reader, now func() time.Time) *MonotonicULIDGenerator {
+	return &MonotonicULIDGenerator{
+		entropy: ulid.Monotonic(entropy, 0),
+		now:     now,
+	}
+}
+
+// NewID returns a ULID greater than every ULID previously generated by g. It
+// implements IDGenerator.
+func (g *MonotonicULIDGenerator) NewID() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	ms := max(ulid.Timestamp(g.now()), g.lastMs)
+	id, err := ulid.New(ms, g.entropy)
+	if errors.Is(err, ulid.ErrMonotonicOverflow) {
+		// The millisecond ran out of IDs, borrow the next one
+		ms++
+		id, err = ulid.New(ms, g.entropy)
+	}
+	if err != nil {
+		// Only a failing entropy source gets here; crypto/rand never fails
+		panic(err)
+	}
+
+	g.lastMs = ms
+	return id.String()
+}
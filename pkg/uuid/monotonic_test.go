@@ -0,0 +1,111 @@
+package uuid
+
+import (
+	"crypto/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// assertIncreasing fails unless every ID is greater than the one before.
+func assertIncreasing(t *testing.T, ids []string) {
+	t.Helper()
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("ID %d = %v, want greater than %v", i, ids[i], ids[i-1])
+		}
+	}
+}
+
+func TestMonotonicULIDGenerator_SameMillisecond(t *testing.T) {
+	frozen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	generator := newMonotonicULIDGenerator(rand.Reader, func() time.Time { return frozen })
+
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = generator.NewID()
+	}
+
+	assertIncreasing(t, ids)
+	if got := ulid.MustParse(ids[len(ids)-1]).Time(); got != ulid.Timestamp(frozen) {
+		t.Errorf("Time() = %v, want %v", got, ulid.Timestamp(frozen))
+	}
+}
+
+func TestMonotonicULIDGenerator_ClockBackwards(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	generator := newMonotonicULIDGenerator(rand.Reader, func() time.Time { return now })
+
+	first := generator.NewID()
+	now = now.Add(-time.Second)
+	second := generator.NewID()
+
+	assertIncreasing(t, []string{first, second})
+}
+
+// maxFirstEntropy is an entropy source whose first 10 bytes, the entropy of
+// the first ULID, are 0xff, followed by 0x01 bytes.
+type maxFirstEntropy struct {
+	read int
+}
+
+func (e *maxFirstEntropy) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0x01
+		if e.read < 10 {
+			p[i] = 0xff
+		}
+		e.read++
+	}
+	return len(p), nil
+}
+
+func TestMonotonicULIDGenerator_Overflow(t *testing.T) {
+	frozen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Maximal entropy leaves no room to increment within the millisecond
+	generator := newMonotonicULIDGenerator(&maxFirstEntropy{}, func() time.Time { return frozen })
+
+	first := generator.NewID()
+	second := generator.NewID()
+
+	assertIncreasing(t, []string{first, second})
+	if got, want := ulid.MustParse(second).Time(), ulid.Timestamp(frozen)+1; got != want {
+		t.Errorf("Time() = %v, want the next millisecond %v", got, want)
+	}
+}
+
+func TestNewMonotonicULID_Concurrent(t *testing.T) {
+	var mutex sync.Mutex
+	var ids []string
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			own := make([]string, 500)
+			for i := range own {
+				own[i] = NewMonotonicULID()
+			}
+			// Each goroutine sees increasing IDs
+			assertIncreasing(t, own)
+
+			mutex.Lock()
+			ids = append(ids, own...)
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// No two goroutines got the same ID
+	sort.Strings(ids)
+	assertIncreasing(t, ids)
+}
+
+func BenchmarkNewMonotonicULID(b *testing.B) {
+	for b.Loop() {
+		NewMonotonicULID()
+	}
+}
@@ -0,0 +1,62 @@
+package uuid
+
+import (
+	"errors"
+	"strings"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// NanoID alphabets.
+const (
+	// NanoIDAlphabet is the URL-safe default alphabet of NanoIDs.
+	NanoIDAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	// CodeAlphabet has uppercase letters and digits without the look-alikes
+	// 0, O, 1 and I, for codes people read and type, such as invite codes.
+	CodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// DefaultNanoIDLength is the length of NanoIDs created by NewNanoID. With
+// NanoIDAlphabet it has about as many random bits as a UUID v4.
+const DefaultNanoIDLength = 21
+
+// NewNanoID generates a new NanoID of DefaultNanoIDLength characters of
+// NanoIDAlphabet.
+func NewNanoID() string {
+	return gonanoid.Must()
+}
+
+// NanoIDGenerator generates NanoIDs of a configured alphabet and length. It
+// suits short user-facing codes, such as invite codes and share links, rather
+// than primary keys: the shorter the IDs, the likelier a collision.
+type NanoIDGenerator struct {
+	alphabet string
+	length   int
+}
+
+// NewNanoIDGenerator creates a generator of NanoIDs of length characters of
+// alphabet. The alphabet must consist of 2 to 255 distinct ASCII characters.
+func NewNanoIDGenerator(alphabet string, length int) (*NanoIDGenerator, error) {
+	if len(alphabet) < 2 || len(alphabet) > 255 {
+		return nil, errors.New("NanoID alphabet must have 2 to 255 characters")
+	}
+	for i := 0; i < len(alphabet); i++ {
+		if alphabet[i] >= 0x80 {
+			return nil, errors.New("NanoID alphabet must consist of ASCII characters")
+		}
+		if strings.IndexByte(alphabet[i+1:], alphabet[i]) >= 0 {
+			return nil, errors.New("NanoID alphabet must not repeat characters")
+		}
+	}
+	if length <= 0 {
+		return nil, errors.New("NanoID length must be positive")
+	}
+	return &NanoIDGenerator{alphabet: alphabet, length: length}, nil
+}
+
+// NewID returns a new NanoID. It implements IDGenerator.
+func (g *NanoIDGenerator) NewID() string {
+	// The alphabet and length were validated, so generating cannot fail
+	return gonanoid.MustGenerate(g.alphabet, g.length)
+}
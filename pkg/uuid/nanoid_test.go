@@ -0,0 +1,91 @@
+package uuid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewNanoID(t *testing.T) {
+	got := NewNanoID()
+
+	if len(got) != DefaultNanoIDLength {
+		t.Errorf("NewNanoID() length = %v, want %v", len(got), DefaultNanoIDLength)
+	}
+	for _, c := range got {
+		if !strings.ContainsRune(NanoIDAlphabet, c) {
+			t.Errorf("NewNanoID() = %v, contains %q outside of the alphabet", got, c)
+		}
+	}
+	if second := NewNanoID(); got == second {
+		t.Errorf("NewNanoID() generated duplicate IDs: %v", got)
+	}
+}
+
+func TestNewNanoIDGenerator(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		length   int
+		wantErr  bool
+	}{
+		{name: "code alphabet", alphabet: CodeAlphabet, length: 8},
+		{name: "binary alphabet", alphabet: "01", length: 64},
+		{name: "empty alphabet", alphabet: "", length: 8, wantErr: true},
+		{name: "single character", alphabet: "a", length: 8, wantErr: true},
+		{name: "repeated character", alphabet: "abca", length: 8, wantErr: true},
+		{name: "non-ASCII character", alphabet: "abcé", length: 8, wantErr: true},
+		{name: "zero length", alphabet: CodeAlphabet, length: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := NewNanoIDGenerator(tt.alphabet, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewNanoIDGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := generator.NewID()
+			if len(got) != tt.length {
+				t.Errorf("NewID() length = %v, want %v", len(got), tt.length)
+			}
+			for _, c := range got {
+				if !strings.ContainsRune(tt.alphabet, c) {
+					t.Errorf("NewID() = %v, contains %q outside of the alphabet", got, c)
+				}
+			}
+		})
+	}
+}
+
+func TestNanoIDGenerator_IDGenerator(t *testing.T) {
+	generator, err := NewNanoIDGenerator(CodeAlphabet, 10)
+	if err != nil {
+		t.Fatalf("NewNanoIDGenerator() error = %v", err)
+	}
+
+	var ids IDGenerator = generator
+	seen := make(map[string]bool)
+	for range 1000 {
+		id := ids.NewID()
+		if seen[id] {
+			t.Fatalf("NewID() generated duplicate IDs: %v", id)
+		}
+		seen[id] = true
+	}
+}
+
+func BenchmarkNewNanoID(b *testing.B) {
+	for b.Loop() {
+		NewNanoID()
+	}
+}
+
+func BenchmarkNanoIDGenerator_NewID(b *testing.B) {
+	generator, _ := NewNanoIDGenerator(CodeAlphabet, 8)
+	for b.Loop() {
+		generator.NewID()
+	}
+}
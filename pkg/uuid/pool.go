@@ -0,0 +1,96 @@
+package uuid
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Pool keeps up to size pre-generated IDs, refilled in the background, so
+// that taking an ID on a hot path is a channel receive. When the pool is
+// exhausted, NewID falls back to generating the ID directly.
+type Pool struct {
+	ids    chan string
+	source IDGenerator
+	fill   func(n int) []string
+	misses atomic.Int64
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewPool creates a pool of size IDs generated by source and starts refilling it.
+func NewPool(source IDGenerator, size int) *Pool {
+	return newPool(source, size, func(n int) []string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = source.NewID()
+		}
+		return ids
+	})
+}
+
+// NewUUIDPool creates a pool of size random UUIDs, generated in batches by
+// NewBatch, and starts refilling it.
+func NewUUIDPool(size int) *Pool {
+	return newPool(GoogleGenerator, size, NewBatch)
+}
+
+// newPool creates a pool refilled with batches created by fill.
+func newPool(source IDGenerator, size int, fill func(n int) []string) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		ids:    make(chan string, size),
+		source: source,
+		fill:   fill,
+		done:   make(chan struct{}),
+	}
+	go p.refill()
+	return p
+}
+
+// refill keeps the pool full until it is closed. A batch is half the pool,
+// so a drained pool fills up again in two batches.
+func (p *Pool) refill() {
+	batch := max(cap(p.ids)/2, 1)
+	for {
+		for _, id := range p.fill(batch) {
+			// Stop as soon as the pool is closed, rather than when it has space again
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+			select {
+			case p.ids <- id:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// NewID takes an ID from the pool, or generates one when the pool is
+// exhausted or closed. It implements IDGenerator.
+func (p *Pool) NewID() string {
+	select {
+	case id := <-p.ids:
+		return id
+	default:
+		p.misses.Add(1)
+		return p.source.NewID()
+	}
+}
+
+// Misses returns the number of IDs generated directly because the pool was exhausted.
+func (p *Pool) Misses() int64 {
+	return p.misses.Load()
+}
+
+// Close stops refilling the pool. IDs still in the pool are handed out before
+// NewID falls back to generating them.
+func (p *Pool) Close() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
@@ -0,0 +1,115 @@
+package uuid
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFull waits until the pool holds size IDs.
+func waitFull(t *testing.T, p *Pool, size int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(p.ids) < size {
+		if time.Now().After(deadline) {
+			t.Fatalf("pool holds %v IDs after 1s, want %v", len(p.ids), size)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool := NewPool(NewSequenceGenerator("id"), 4)
+	defer pool.Close()
+	waitFull(t, pool, 4)
+
+	// Pooled IDs come in the order the source generated them
+	for _, want := range []string{"id-0001", "id-0002", "id-0003", "id-0004"} {
+		if got := pool.NewID(); got != want {
+			t.Errorf("NewID() = %v, want %v", got, want)
+		}
+	}
+	if got := pool.Misses(); got != 0 {
+		t.Errorf("Misses() = %v, want %v", got, 0)
+	}
+
+	// The pool refills in the background
+	waitFull(t, pool, 4)
+}
+
+func TestPool_Exhausted(t *testing.T) {
+	source := NewSequenceGenerator("id")
+	pool := NewPool(source, 2)
+	waitFull(t, pool, 2)
+	pool.Close()
+
+	if first, second := pool.NewID(), pool.NewID(); first != "id-0001" || second != "id-0002" {
+		t.Errorf("NewID() = %v, %v, want the pooled IDs id-0001, id-0002", first, second)
+	}
+
+	// The refill goroutine may still hand in the ID it was holding, after
+	// that a closed pool falls back to the source
+	seen := map[string]bool{"id-0001": true, "id-0002": true}
+	for range 3 {
+		id := pool.NewID()
+		if seen[id] {
+			t.Errorf("NewID() generated duplicate IDs: %v", id)
+		}
+		seen[id] = true
+	}
+	if got := pool.Misses(); got < 2 {
+		t.Errorf("Misses() = %v, want at least %v", got, 2)
+	}
+
+	// Closing twice is harmless
+	pool.Close()
+}
+
+func TestUUIDPool_Concurrent(t *testing.T) {
+	pool := NewUUIDPool(64)
+	defer pool.Close()
+
+	var mutex sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				id := pool.NewID()
+				if !IsValid(id) {
+					t.Errorf("NewID() = %v, want a valid UUID", id)
+				}
+				mutex.Lock()
+				seen[id] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 4000 {
+		t.Errorf("NewID() generated %v distinct IDs, want %v", len(seen), 4000)
+	}
+}
+
+func BenchmarkUUIDPool_NewID(b *testing.B) {
+	pool := NewUUIDPool(1024)
+	defer pool.Close()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.NewID()
+		}
+	})
+	b.ReportMetric(float64(pool.Misses())/float64(b.N), "misses/op")
+}
+
+func BenchmarkNewGoogle_Parallel(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			NewGoogle()
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package uuid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// prefixSeparator separates the prefix of a PrefixedID from the raw ID.
+const prefixSeparator = "_"
+
+// prefixPattern limits prefixes to short lowercase names such as usr or ord.
+var prefixPattern = regexp.MustCompile(`^[a-z][a-z0-9]{0,15}$`)
+
+// PrefixedID is an ID qualified by the prefix of its entity type, in the
+// form prefix_id, such as usr_01H8XGJWBWBAQ4Z4YDK5QW3M6Y. The prefix makes IDs
+// self-describing in logs and support requests.
+type PrefixedID struct {
+	Prefix string
+	ID     string
+}
+
+// NewPrefixedID creates the PrefixedID of id with prefix. The prefix must be
+// 1 to 16 lowercase letters and digits, starting with a letter.
+func NewPrefixedID(prefix, id string) (PrefixedID, error) {
+	if !prefixPattern.MatchString(prefix) {
+		return PrefixedID{}, fmt.Errorf("invalid ID prefix %q", prefix)
+	}
+	if id == "" {
+		return PrefixedID{}, fmt.Errorf("empty %s ID", prefix)
+	}
+	return PrefixedID{Prefix: prefix, ID: id}, nil
+}
+
+// ParsePrefixedID splits a prefixed ID into its prefix and raw ID. The raw ID
+// is everything after the first underscore, so it may contain underscores itself.
+func ParsePrefixedID(s string) (PrefixedID, error) {
+	prefix, id, found := strings.Cut(s, prefixSeparator)
+	if !found {
+		return PrefixedID{}, fmt.Errorf("invalid prefixed ID %q: missing prefix", s)
+	}
+	return NewPrefixedID(prefix, id)
+}
+
+// String returns the prefixed ID in the form prefix_id.
+func (p PrefixedID) String() string {
+	return p.Prefix + prefixSeparator + p.ID
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p PrefixedID) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *PrefixedID) UnmarshalText(data []byte) error {
+	parsed, err := ParsePrefixedID(string(data))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// PrefixedGenerator generates the IDs of another generator with a prefix.
+type PrefixedGenerator struct {
+	prefix string
+	source IDGenerator
+}
+
+// NewPrefixedGenerator creates a generator of the IDs of source prefixed with prefix.
+func NewPrefixedGenerator(prefix string, source IDGenerator) (*PrefixedGenerator, error) {
+	if !prefixPattern.MatchString(prefix) {
+		return nil, fmt.Errorf("invalid ID prefix %q", prefix)
+	}
+	return &PrefixedGenerator{prefix: prefix, source: source}, nil
+}
+
+// NewID returns a new prefixed ID. It implements IDGenerator.
+func (g *PrefixedGenerator) NewID() string {
+	return g.prefix + prefixSeparator + g.source.NewID()
+}
@@ -0,0 +1,125 @@
+package uuid
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePrefixedID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    PrefixedID
+		wantErr bool
+	}{
+		{
+			name:  "ULID",
+			input: "usr_01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			want:  PrefixedID{Prefix: "usr", ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		},
+		{
+			name:  "raw ID with underscores",
+			input: "inv_a_b-c",
+			want:  PrefixedID{Prefix: "inv", ID: "a_b-c"},
+		},
+		{
+			name:    "missing prefix",
+			input:   "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			wantErr: true,
+		},
+		{
+			name:    "empty prefix",
+			input:   "_01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			wantErr: true,
+		},
+		{
+			name:    "uppercase prefix",
+			input:   "USR_01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			wantErr: true,
+		},
+		{
+			name:    "empty ID",
+			input:   "usr_",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrefixedID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePrefixedID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePrefixedID() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.input {
+				t.Errorf("String() = %v, want %v", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestNewPrefixedID(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		wantErr bool
+	}{
+		{name: "letters", prefix: "usr"},
+		{name: "letters and digits", prefix: "v2key"},
+		{name: "starts with digit", prefix: "2fa", wantErr: true},
+		{name: "contains separator", prefix: "us_r", wantErr: true},
+		{name: "too long", prefix: "abcdefghijklmnopq", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPrefixedID(tt.prefix, "id")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPrefixedID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrefixedID_JSON(t *testing.T) {
+	id := PrefixedID{Prefix: "usr", ID: "user-0001"}
+
+	data, err := json.Marshal(map[string]PrefixedID{"id": id})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if want := `{"id":"usr_user-0001"}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded map[string]PrefixedID
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded["id"] != id {
+		t.Errorf("json.Unmarshal() = %v, %v, want %v", decoded["id"], err, id)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"nope"}`), &decoded); err == nil {
+		t.Error("json.Unmarshal() should fail for an ID without prefix")
+	}
+}
+
+func TestPrefixedGenerator(t *testing.T) {
+	generator, err := NewPrefixedGenerator("usr", NewSequenceGenerator("user"))
+	if err != nil {
+		t.Fatalf("NewPrefixedGenerator() error = %v", err)
+	}
+
+	got := generator.NewID()
+	if got != "usr_user-0001" {
+		t.Errorf("NewID() = %v, want %v", got, "usr_user-0001")
+	}
+	parsed, err := ParsePrefixedID(got)
+	if err != nil || parsed.Prefix != "usr" || parsed.ID != "user-0001" {
+		t.Errorf("ParsePrefixedID(NewID()) = %v, %v", parsed, err)
+	}
+
+	if _, err := NewPrefixedGenerator("Usr", ULIDGenerator); err == nil {
+		t.Error("NewPrefixedGenerator() should fail for an invalid prefix")
+	}
+}
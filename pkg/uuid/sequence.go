@@ -0,0 +1,54 @@
+package uuid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// SequenceGenerator generates the sequential IDs prefix-0001, prefix-0002 and
+// so on. Tests and examples use it for stable output; it is no source of
+// unique IDs across processes.
+type SequenceGenerator struct {
+	prefix string
+	next   atomic.Int64
+}
+
+// NewSequenceGenerator creates a generator of sequential IDs starting at prefix-0001.
+func NewSequenceGenerator(prefix string) *SequenceGenerator {
+	return &SequenceGenerator{prefix: prefix}
+}
+
+// NewID returns the next ID of the sequence. It implements IDGenerator.
+func (g *SequenceGenerator) NewID() string {
+	return fmt.Sprintf("%s-%04d", g.prefix, g.next.Add(1))
+}
+
+// SeededGenerator generates random-looking UUID v4 strings from a seed: two
+// generators created with the same seed generate the same UUIDs. Like
+// SequenceGenerator it is meant for tests, its UUIDs are predictable.
+type SeededGenerator struct {
+	random *rand.ChaCha8
+	mutex  sync.Mutex
+}
+
+// NewSeededGenerator creates a generator of the UUIDs of seed.
+func NewSeededGenerator(seed uint64) *SeededGenerator {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	return &SeededGenerator{random: rand.NewChaCha8(key)}
+}
+
+// NewID returns the next UUID of the seed. It implements IDGenerator.
+func (g *SeededGenerator) NewID() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// Reading from ChaCha8 cannot fail
+	u, _ := uuid.NewRandomFromReader(g.random)
+	return u.String()
+}
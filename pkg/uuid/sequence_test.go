@@ -0,0 +1,74 @@
+package uuid
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSequenceGenerator(t *testing.T) {
+	generator := NewSequenceGenerator("user")
+
+	want := []string{"user-0001", "user-0002", "user-0003"}
+	for _, w := range want {
+		if got := generator.NewID(); got != w {
+			t.Errorf("NewID() = %v, want %v", got, w)
+		}
+	}
+
+	// Sequences are independent of each other
+	if got := NewSequenceGenerator("order").NewID(); got != "order-0001" {
+		t.Errorf("NewID() = %v, want %v", got, "order-0001")
+	}
+}
+
+func TestSequenceGenerator_Concurrent(t *testing.T) {
+	var ids IDGenerator = NewSequenceGenerator("user")
+
+	var mutex sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				id := ids.NewID()
+				mutex.Lock()
+				seen[id] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 1000 {
+		t.Errorf("NewID() generated %v distinct IDs, want %v", len(seen), 1000)
+	}
+	if !seen["user-1000"] {
+		t.Errorf("NewID() did not generate %v", "user-1000")
+	}
+}
+
+func TestSeededGenerator(t *testing.T) {
+	first := NewSeededGenerator(42)
+	second := NewSeededGenerator(42)
+	other := NewSeededGenerator(7)
+
+	previous := ""
+	for range 10 {
+		got := first.NewID()
+		if err := MustBeVersion(got, 4); err != nil {
+			t.Errorf("NewID() = %v, want a UUID v4: %v", got, err)
+		}
+		if want := second.NewID(); got != want {
+			t.Errorf("NewID() = %v, want %v from the same seed", got, want)
+		}
+		if got == other.NewID() {
+			t.Errorf("NewID() = %v from different seeds", got)
+		}
+		if got == previous {
+			t.Errorf("NewID() generated duplicate UUIDs: %v", got)
+		}
+		previous = got
+	}
+}
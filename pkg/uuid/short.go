@@ -0,0 +1,90 @@
+package uuid
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// shortIDLength is the length of every short ID: 22 base62 or base58 digits
+// are enough for 128 bits.
+const shortIDLength = 22
+
+// Alphabets of the short ID encodings. The base62 digits are in ASCII order,
+// so short IDs sort like the UUIDs they encode; the base58 alphabet is
+// Bitcoin's, without the look-alike characters 0, O, I and l.
+const (
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// maxUUID is the largest value of 128 bits.
+var maxUUID = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// EncodeBase62 encodes a UUID string as a 22 character base62 string,
+// suitable for URLs.
+func EncodeBase62(s string) (string, error) {
+	return encodeShort(s, base62Alphabet)
+}
+
+// DecodeBase62 decodes a base62 string created by EncodeBase62 back into the
+// UUID string.
+func DecodeBase62(s string) (string, error) {
+	return decodeShort(s, base62Alphabet, "base62")
+}
+
+// EncodeBase58 encodes a UUID string as a 22 character base58 string, which
+// avoids characters that are easily confused when read by people.
+func EncodeBase58(s string) (string, error) {
+	return encodeShort(s, base58Alphabet)
+}
+
+// DecodeBase58 decodes a base58 string created by EncodeBase58 back into the
+// UUID string.
+func DecodeBase58(s string) (string, error) {
+	return decodeShort(s, base58Alphabet, "base58")
+}
+
+// encodeShort encodes a UUID string in the alphabet, left padded to shortIDLength.
+func encodeShort(s, alphabet string) (string, error) {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	value := new(big.Int).SetBytes(u[:])
+	base := big.NewInt(int64(len(alphabet)))
+	digit := new(big.Int)
+	encoded := make([]byte, shortIDLength)
+	for i := shortIDLength - 1; i >= 0; i-- {
+		value.DivMod(value, base, digit)
+		encoded[i] = alphabet[digit.Int64()]
+	}
+	return string(encoded), nil
+}
+
+// decodeShort decodes a short ID in the alphabet into a UUID string.
+func decodeShort(s, alphabet, name string) (string, error) {
+	if len(s) != shortIDLength {
+		return "", fmt.Errorf("invalid %s ID length: %d", name, len(s))
+	}
+
+	value := new(big.Int)
+	base := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(alphabet, s[i])
+		if digit < 0 {
+			return "", fmt.Errorf("invalid %s ID character: %q", name, s[i])
+		}
+		value.Mul(value, base).Add(value, big.NewInt(int64(digit)))
+	}
+	if value.Cmp(maxUUID) > 0 {
+		return "", fmt.Errorf("invalid %s ID: value exceeds 128 bits", name)
+	}
+
+	var u uuid.UUID
+	value.FillBytes(u[:])
+	return u.String(), nil
+}
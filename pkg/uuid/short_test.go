@@ -0,0 +1,178 @@
+package uuid
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestEncodeBase62(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "nil UUID",
+			input: "00000000-0000-0000-0000-000000000000",
+			want:  "0000000000000000000000",
+		},
+		{
+			name:  "max UUID",
+			input: "ffffffff-ffff-ffff-ffff-ffffffffffff",
+			want:  "7n42DGM5Tflk9n8mt7Fhc7",
+		},
+		{
+			name:  "UUID v4",
+			input: "550e8400-e29b-41d4-a716-446655440000",
+			want:  "2aUyqjCzEIiEcYMKj7TZtw",
+		},
+		{
+			name:    "invalid UUID",
+			input:   "invalid-uuid",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeBase62(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EncodeBase62() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EncodeBase62() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBase62(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "nil UUID",
+			input: "0000000000000000000000",
+			want:  "00000000-0000-0000-0000-000000000000",
+		},
+		{
+			name:  "UUID v4",
+			input: "2aUyqjCzEIiEcYMKj7TZtw",
+			want:  "550e8400-e29b-41d4-a716-446655440000",
+		},
+		{
+			name:    "too short",
+			input:   "2aUyqjCzEIiEcYMKj7TZt",
+			wantErr: true,
+		},
+		{
+			name:    "invalid character",
+			input:   "2aUyqjCzEIiEcYMKj7TZt-",
+			wantErr: true,
+		},
+		{
+			name:    "exceeds 128 bits",
+			input:   "7n42DGM5Tflk9n8mt7Fhc8",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeBase62(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeBase62() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DecodeBase62() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBase58(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "too long", input: "11111111111111111111111", wantErr: true},
+		{name: "look-alike character", input: "111111111111111111111O", wantErr: true},
+		{name: "exceeds 128 bits", input: "zzzzzzzzzzzzzzzzzzzzzz", wantErr: true},
+		{name: "nil UUID", input: "1111111111111111111111"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeBase58(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeBase58() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShortIDRoundTrip(t *testing.T) {
+	encodings := []struct {
+		name   string
+		encode func(string) (string, error)
+		decode func(string) (string, error)
+	}{
+		{name: "base62", encode: EncodeBase62, decode: DecodeBase62},
+		{name: "base58", encode: EncodeBase58, decode: DecodeBase58},
+	}
+	ids := append(NewBatch(100), "00000000-0000-0000-0000-000000000000", "ffffffff-ffff-ffff-ffff-ffffffffffff")
+
+	for _, encoding := range encodings {
+		t.Run(encoding.name, func(t *testing.T) {
+			for _, id := range ids {
+				short, err := encoding.encode(id)
+				if err != nil {
+					t.Fatalf("encode(%v) error = %v", id, err)
+				}
+				if len(short) != 22 {
+					t.Errorf("encode(%v) length = %v, want %v", id, len(short), 22)
+				}
+				if got, err := encoding.decode(short); err != nil || got != id {
+					t.Errorf("decode(%v) = %v, %v, want %v", short, got, err, id)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeBase62_SortOrder(t *testing.T) {
+	ids := NewBatch(100)
+	shorts := make([]string, len(ids))
+	for i, id := range ids {
+		shorts[i], _ = EncodeBase62(id)
+	}
+
+	sort.Strings(ids)
+	sort.Strings(shorts)
+	for i, short := range shorts {
+		if got, _ := DecodeBase62(short); got != ids[i] {
+			t.Fatalf("sorted short ID %d decodes to %v, want %v", i, got, ids[i])
+		}
+	}
+}
+
+func BenchmarkEncodeBase62(b *testing.B) {
+	id := NewGoogle()
+	for b.Loop() {
+		EncodeBase62(id)
+	}
+}
+
+func BenchmarkDecodeBase62(b *testing.B) {
+	short, _ := EncodeBase62(NewGoogle())
+	for b.Loop() {
+		DecodeBase62(short)
+	}
+}
@@ -0,0 +1,27 @@
+package uuid
+
+import (
+	"github.com/bwmarrin/snowflake"
+)
+
+// SnowflakeGenerator generates snowflake IDs: 63-bit integers, rendered in
+// decimal, made of a millisecond timestamp, a node ID and a sequence number.
+// They sort by creation time; every process generating them must use its own
+// node ID to keep them unique.
+type SnowflakeGenerator struct {
+	node *snowflake.Node
+}
+
+// NewSnowflakeGenerator creates a generator of the snowflake IDs of node, between 0 and 1023.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	n, err := snowflake.NewNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return &SnowflakeGenerator{node: n}, nil
+}
+
+// NewID returns a new snowflake ID. It implements IDGenerator.
+func (g *SnowflakeGenerator) NewID() string {
+	return g.node.Generate().String()
+}
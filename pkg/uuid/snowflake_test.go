@@ -0,0 +1,60 @@
+package uuid
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/bwmarrin/snowflake"
+)
+
+func TestNewSnowflakeGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    int64
+		wantErr bool
+	}{
+		{name: "first node", node: 0},
+		{name: "last node", node: 1023},
+		{name: "negative node", node: -1, wantErr: true},
+		{name: "node out of range", node: 1024, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSnowflakeGenerator(tt.node)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSnowflakeGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnowflakeGenerator_NewID(t *testing.T) {
+	generator, err := NewSnowflakeGenerator(42)
+	if err != nil {
+		t.Fatalf("NewSnowflakeGenerator() error = %v", err)
+	}
+
+	previous := int64(0)
+	for range 1000 {
+		got := generator.NewID()
+		value, err := strconv.ParseInt(got, 10, 64)
+		if err != nil {
+			t.Fatalf("NewID() = %v, want a decimal integer", got)
+		}
+		if value <= previous {
+			t.Fatalf("NewID() = %v, want greater than %v", value, previous)
+		}
+		if node := snowflake.ParseInt64(value).Node(); node != 42 {
+			t.Errorf("NewID() node = %v, want %v", node, 42)
+		}
+		previous = value
+	}
+}
+
+func BenchmarkSnowflakeGenerator_NewID(b *testing.B) {
+	generator, _ := NewSnowflakeGenerator(1)
+	for b.Loop() {
+		generator.NewID()
+	}
+}
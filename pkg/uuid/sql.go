@@ -0,0 +1,72 @@
+package uuid
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Value implements driver.Valuer, storing the ID as its UUID string. Use
+// BinaryID to store it in 16 bytes instead.
+func (id ID) Value() (driver.Value, error) {
+	return id.String(), nil
+}
+
+// Scan implements sql.Scanner. It reads UUID strings as well as 16-byte
+// binary values; NULL scans into the nil ID.
+func (id *ID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*id = ID{}
+		return nil
+	case string:
+		return id.UnmarshalText([]byte(src))
+	case []byte:
+		if len(src) == len(id) {
+			copy(id[:], src)
+			return nil
+		}
+		return id.UnmarshalText(src)
+	default:
+		return fmt.Errorf("cannot scan %T into an ID", src)
+	}
+}
+
+// BinaryID is an ID stored as 16 bytes, e.g. in a BLOB or BYTEA column.
+// Convert between the two with BinaryID(id) and ID(binaryID).
+type BinaryID ID
+
+// Value implements driver.Valuer, storing the ID as its 16 bytes.
+func (id BinaryID) Value() (driver.Value, error) {
+	return ID(id).Bytes(), nil
+}
+
+// Scan implements sql.Scanner like ID.Scan.
+func (id *BinaryID) Scan(src interface{}) error {
+	return (*ID)(id).Scan(src)
+}
+
+// String returns the canonical UUID string of the ID.
+func (id BinaryID) String() string {
+	return ID(id).String()
+}
+
+// Value implements driver.Valuer, storing the prefixed ID as its string.
+func (p PrefixedID) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// Scan implements sql.Scanner, parsing a prefixed ID string. NULL scans into
+// the zero PrefixedID.
+func (p *PrefixedID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*p = PrefixedID{}
+		return nil
+	case string:
+		return p.UnmarshalText([]byte(src))
+	case []byte:
+		return p.UnmarshalText(src)
+	default:
+		return fmt.Errorf("cannot scan %T into a PrefixedID", src)
+	}
+}
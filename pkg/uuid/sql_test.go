@@ -0,0 +1,126 @@
+package uuid
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+// The ID types must plug into database/sql queries directly
+var (
+	_ sql.Scanner   = (*ID)(nil)
+	_ driver.Valuer = ID{}
+	_ sql.Scanner   = (*BinaryID)(nil)
+	_ driver.Valuer = BinaryID{}
+	_ sql.Scanner   = (*TypedID[order])(nil)
+	_ driver.Valuer = TypedID[order]{}
+	_ sql.Scanner   = (*PrefixedID)(nil)
+	_ driver.Valuer = PrefixedID{}
+)
+
+func TestID_Value(t *testing.T) {
+	id := MustParseID("550e8400-e29b-41d4-a716-446655440000")
+
+	got, err := id.Value()
+	if err != nil || got != "550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("ID.Value() = %v, %v, want %v", got, err, "550e8400-e29b-41d4-a716-446655440000")
+	}
+
+	got, err = BinaryID(id).Value()
+	if err != nil || !bytes.Equal(got.([]byte), id.Bytes()) {
+		t.Errorf("BinaryID.Value() = %v, %v, want %x", got, err, id.Bytes())
+	}
+}
+
+func TestID_Scan(t *testing.T) {
+	id := MustParseID("550e8400-e29b-41d4-a716-446655440000")
+
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    ID
+		wantErr bool
+	}{
+		{name: "string", src: "550e8400-e29b-41d4-a716-446655440000", want: id},
+		{name: "text bytes", src: []byte("550e8400-e29b-41d4-a716-446655440000"), want: id},
+		{name: "binary bytes", src: id.Bytes(), want: id},
+		{name: "NULL", src: nil, want: ID{}},
+		{name: "invalid string", src: "invalid-uuid", wantErr: true},
+		{name: "short bytes", src: []byte{1, 2, 3}, wantErr: true},
+		{name: "integer", src: int64(42), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewID()
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ID.Scan() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ID.Scan() = %v, want %v", got, tt.want)
+			}
+
+			var binary BinaryID
+			err = binary.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BinaryID.Scan() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && ID(binary) != tt.want {
+				t.Errorf("BinaryID.Scan() = %v, want %v", binary, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypedID_Scan(t *testing.T) {
+	id := NewTypedID[order]()
+	value, err := id.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+
+	var got TypedID[order]
+	if err := got.Scan(value); err != nil || got != id {
+		t.Errorf("Scan() = %v, %v, want %v", got, err, id)
+	}
+}
+
+func TestPrefixedID_SQL(t *testing.T) {
+	id := PrefixedID{Prefix: "usr", ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV"}
+
+	value, err := id.Value()
+	if err != nil || value != "usr_01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Errorf("Value() = %v, %v, want %v", value, err, "usr_01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	}
+
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    PrefixedID
+		wantErr bool
+	}{
+		{name: "string", src: "usr_01ARZ3NDEKTSV4RRFFQ69G5FAV", want: id},
+		{name: "bytes", src: []byte("usr_01ARZ3NDEKTSV4RRFFQ69G5FAV"), want: id},
+		{name: "NULL", src: nil},
+		{name: "missing prefix", src: "01ARZ3NDEKTSV4RRFFQ69G5FAV", wantErr: true},
+		{name: "integer", src: int64(42), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PrefixedID
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
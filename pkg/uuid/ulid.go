@@ -0,0 +1,49 @@
+package uuid
+
+import (
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// NewULID generates a new ULID: 26 Crockford base32 characters that sort by
+// creation time.
+func NewULID() string {
+	return ulid.Make().String()
+}
+
+// ParseULID parses a ULID from a string, in either case.
+func ParseULID(s string) (string, error) {
+	id, err := ulid.ParseStrict(s)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// MustParseULID parses a ULID from a string and panics if there is an error.
+func MustParseULID(s string) string {
+	id, err := ParseULID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// ULIDToUUID converts a ULID to the UUID string form of the same 128 bits.
+func ULIDToUUID(s string) (string, error) {
+	id, err := ulid.ParseStrict(s)
+	if err != nil {
+		return "", err
+	}
+	return uuid.UUID(id).String(), nil
+}
+
+// UUIDToULID converts a UUID to the ULID string form of the same 128 bits.
+// Converting a UUID created by ULIDToUUID returns the original ULID.
+func UUIDToULID(s string) (string, error) {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return ulid.ULID(u).String(), nil
+}
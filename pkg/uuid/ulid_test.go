@@ -0,0 +1,145 @@
+package uuid
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestNewULID(t *testing.T) {
+	got := NewULID()
+
+	if len(got) != 26 {
+		t.Errorf("NewULID() length = %v, want %v", len(got), 26)
+	}
+	if _, err := ulid.ParseStrict(got); err != nil {
+		t.Errorf("NewULID() generated invalid ULID: %v", err)
+	}
+
+	// IDs created later sort after earlier ones
+	second := NewULID()
+	if got == second {
+		t.Errorf("NewULID() generated duplicate ULIDs: %v", got)
+	}
+	if ulid.MustParse(second).Time() < ulid.MustParse(got).Time() {
+		t.Errorf("NewULID() = %v was created before %v", second, got)
+	}
+}
+
+func TestParseULID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "valid ULID",
+			input: "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			want:  "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		},
+		{
+			name:  "lowercase ULID",
+			input: "01arz3ndektsv4rrffq69g5fav",
+			want:  "01ARZ3NDEKTSV4RRFFQ69G5FAV", // should normalize to uppercase
+		},
+		{
+			name:    "empty string",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			input:   "01ARZ3NDEKTSV4RRFFQ69G5FA",
+			wantErr: true,
+		},
+		{
+			name:    "invalid characters",
+			input:   "01ARZ3NDEKTSV4RRFFQ69G5FAU",
+			wantErr: true,
+		},
+		{
+			name:    "overflowing timestamp",
+			input:   "81ARZ3NDEKTSV4RRFFQ69G5FAV",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseULID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseULID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseULID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustParseULID(t *testing.T) {
+	if got := MustParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV"); got != "01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Errorf("MustParseULID() = %v, want %v", got, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("MustParseULID() should have panicked for an invalid ULID")
+		}
+	}()
+	MustParseULID("invalid-ulid")
+}
+
+func TestULIDUUIDConversion(t *testing.T) {
+	tests := []struct {
+		name string
+		ulid string
+		uuid string
+	}{
+		{
+			name: "zero",
+			ulid: "00000000000000000000000000",
+			uuid: "00000000-0000-0000-0000-000000000000",
+		},
+		{
+			name: "spec example",
+			ulid: "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			uuid: "01563e3a-b5d3-d676-4c61-efb99302bd5b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUUID, err := ULIDToUUID(tt.ulid)
+			if err != nil || gotUUID != tt.uuid {
+				t.Errorf("ULIDToUUID() = %v, %v, want %v", gotUUID, err, tt.uuid)
+			}
+			gotULID, err := UUIDToULID(tt.uuid)
+			if err != nil || gotULID != tt.ulid {
+				t.Errorf("UUIDToULID() = %v, %v, want %v", gotULID, err, tt.ulid)
+			}
+		})
+	}
+
+	// A new ULID survives the round trip
+	id := NewULID()
+	asUUID, _ := ULIDToUUID(id)
+	if back, _ := UUIDToULID(asUUID); back != id {
+		t.Errorf("round trip of %v = %v", id, back)
+	}
+
+	if _, err := ULIDToUUID("not-a-ulid"); err == nil {
+		t.Error("ULIDToUUID() should fail for an invalid ULID")
+	}
+	if _, err := UUIDToULID("not-a-uuid"); err == nil {
+		t.Error("UUIDToULID() should fail for an invalid UUID")
+	}
+}
+
+func BenchmarkNewULID(b *testing.B) {
+	for b.Loop() {
+		NewULID()
+	}
+}
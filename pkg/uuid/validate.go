@@ -0,0 +1,48 @@
+package uuid
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned by MustBeVersion.
+var (
+	ErrNilID        = errors.New("nil UUID")
+	ErrWrongVersion = errors.New("wrong UUID version")
+)
+
+// IsNil reports whether s is the nil UUID 00000000-0000-0000-0000-000000000000.
+func IsNil(s string) bool {
+	u, err := uuid.Parse(s)
+	return err == nil && u == uuid.Nil
+}
+
+// IsValid reports whether s is a well-formed UUID string that is not the nil UUID.
+func IsValid(s string) bool {
+	u, err := uuid.Parse(s)
+	return err == nil && u != uuid.Nil
+}
+
+// MustBeVersion checks that s is a UUID of version v, e.g. 4 for random
+// UUIDs. It returns the parse error for malformed strings, ErrNilID for the
+// nil UUID and ErrWrongVersion for UUIDs of another version.
+func MustBeVersion(s string, v int) error {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u == uuid.Nil {
+		return ErrNilID
+	}
+	if got := int(u.Version()); got != v {
+		return fmt.Errorf("%w: version %d, want %d", ErrWrongVersion, got, v)
+	}
+	return nil
+}
+
+// IsNil reports whether the ID is the nil UUID.
+func (id ID) IsNil() bool {
+	return id == ID{}
+}
@@ -0,0 +1,90 @@
+package uuid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsNil(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "nil UUID", input: "00000000-0000-0000-0000-000000000000", want: true},
+		{name: "nil UUID without hyphens", input: "00000000000000000000000000000000", want: true},
+		{name: "UUID v4", input: "550e8400-e29b-41d4-a716-446655440000", want: false},
+		{name: "empty string", input: "", want: false},
+		{name: "malformed", input: "invalid-uuid", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNil(tt.input); got != tt.want {
+				t.Errorf("IsNil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "UUID v4", input: "550e8400-e29b-41d4-a716-446655440000", want: true},
+		{name: "UUID v1 uppercase", input: "6BA7B810-9DAD-11D1-80B4-00C04FD430C8", want: true},
+		{name: "nil UUID", input: "00000000-0000-0000-0000-000000000000", want: false},
+		{name: "empty string", input: "", want: false},
+		{name: "too short", input: "550e8400-e29b-41d4-a716-44665544000", want: false},
+		{name: "invalid characters", input: "550e8400-e29b-41d4-a716-44665544000g", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValid(tt.input); got != tt.want {
+				t.Errorf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustBeVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		version int
+		wantErr error
+		invalid bool
+	}{
+		{name: "UUID v4", input: "550e8400-e29b-41d4-a716-446655440000", version: 4},
+		{name: "UUID v1", input: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", version: 1},
+		{name: "UUID v1 wanted v4", input: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", version: 4, wantErr: ErrWrongVersion},
+		{name: "nil UUID", input: "00000000-0000-0000-0000-000000000000", version: 4, wantErr: ErrNilID},
+		{name: "malformed", input: "invalid-uuid", version: 4, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MustBeVersion(tt.input, tt.version)
+			switch {
+			case tt.invalid:
+				if err == nil {
+					t.Error("MustBeVersion() error = nil, want a parse error")
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("MustBeVersion() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestID_IsNil(t *testing.T) {
+	if !(ID{}).IsNil() {
+		t.Error("IsNil() = false for the zero ID, want true")
+	}
+	if NewID().IsNil() {
+		t.Error("IsNil() = true for a new ID, want false")
+	}
+}
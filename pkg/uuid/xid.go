@@ -0,0 +1,30 @@
+package uuid
+
+import (
+	"github.com/rs/xid"
+)
+
+// NewXID generates a new xid: 20 base32 characters of 12 bytes (a timestamp,
+// machine and process identifiers and a counter) that sort by creation time,
+// to the second. It needs no entropy source and is cheaper than a UUID.
+func NewXID() string {
+	return xid.New().String()
+}
+
+// ParseXID parses an xid from a string.
+func ParseXID(s string) (string, error) {
+	id, err := xid.FromString(s)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// MustParseXID parses an xid from a string and panics if there is an error.
+func MustParseXID(s string) string {
+	id, err := ParseXID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
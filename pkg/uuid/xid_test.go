@@ -0,0 +1,93 @@
+package uuid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+func TestNewXID(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+	got := NewXID()
+	after := time.Now()
+
+	if len(got) != 20 {
+		t.Errorf("NewXID() length = %v, want %v", len(got), 20)
+	}
+
+	id, err := xid.FromString(got)
+	if err != nil {
+		t.Fatalf("NewXID() generated invalid xid: %v", err)
+	}
+	if created := id.Time(); created.Before(before) || created.After(after) {
+		t.Errorf("NewXID() time = %v, want between %v and %v", created, before, after)
+	}
+
+	// The counter makes IDs of the same second increase
+	if second := NewXID(); second <= got {
+		t.Errorf("NewXID() = %v, want greater than %v", second, got)
+	}
+}
+
+func TestParseXID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "valid xid",
+			input: "9m4e2mr0ui3e8a215n4g",
+			want:  "9m4e2mr0ui3e8a215n4g",
+		},
+		{
+			name:    "empty string",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			input:   "9m4e2mr0ui3e8a215n4",
+			wantErr: true,
+		},
+		{
+			name:    "invalid characters",
+			input:   "9m4e2mr0ui3e8a215n4z",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseXID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseXID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseXID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustParseXID(t *testing.T) {
+	if got := MustParseXID("9m4e2mr0ui3e8a215n4g"); got != "9m4e2mr0ui3e8a215n4g" {
+		t.Errorf("MustParseXID() = %v, want %v", got, "9m4e2mr0ui3e8a215n4g")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("MustParseXID() should have panicked for an invalid xid")
+		}
+	}()
+	MustParseXID("invalid-xid")
+}
+
+func BenchmarkNewXID(b *testing.B) {
+	for b.Loop() {
+		NewXID()
+	}
+}